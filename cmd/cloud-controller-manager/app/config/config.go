@@ -62,6 +62,9 @@ type Config struct {
 	SharedInformers informers.SharedInformerFactory
 
 	DynamicReloadingConfig DynamicReloadingConfig
+
+	// EnableCacheDebug enables the debug handler dumping the provider caches.
+	EnableCacheDebug bool
 }
 
 type DynamicReloadingConfig struct {
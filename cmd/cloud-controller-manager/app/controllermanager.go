@@ -19,7 +19,9 @@ package app
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
@@ -27,6 +29,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/uuid"
 	"k8s.io/apimachinery/pkg/util/wait"
@@ -47,6 +50,7 @@ import (
 	cloudcontrollerconfig "sigs.k8s.io/cloud-provider-azure/cmd/cloud-controller-manager/app/config"
 	"sigs.k8s.io/cloud-provider-azure/cmd/cloud-controller-manager/app/dynamic"
 	"sigs.k8s.io/cloud-provider-azure/cmd/cloud-controller-manager/app/options"
+	azcache "sigs.k8s.io/cloud-provider-azure/pkg/cache"
 	"sigs.k8s.io/cloud-provider-azure/pkg/provider"
 	"sigs.k8s.io/cloud-provider-azure/pkg/version"
 	"sigs.k8s.io/cloud-provider-azure/pkg/version/verflag"
@@ -57,6 +61,8 @@ const (
 	ControllerStartJitter = 1.0
 	// ConfigzName is the name used for register cloud-controller manager /configz, same with GroupName.
 	ConfigzName = "cloudcontrollermanager.config.k8s.io"
+	// cacheDebugPath is the path of the handler dumping the provider caches.
+	cacheDebugPath = "/debug/azure-cache"
 )
 
 // NewCloudControllerManagerCommand creates a *cobra.Command object with default parameters
@@ -71,6 +77,9 @@ func NewCloudControllerManagerCommand() *cobra.Command {
 		Long: `The Cloud controller manager is a daemon that embeds the cloud specific control loops shipped with Kubernetes.`,
 		Run: func(cmd *cobra.Command, args []string) {
 			verflag.PrintAndExitIfRequested("Cloud Provider Azure")
+			if s.ValidateCloudConfig {
+				os.Exit(validateCloudConfigFile(cmd.OutOrStdout(), s.KubeCloudShared.CloudProvider.CloudConfigFile))
+			}
 			cliflag.PrintFlags(cmd.Flags())
 
 			c, err := s.Config(KnownControllers(), ControllersDisabledByDefault.List())
@@ -161,6 +170,39 @@ func NewCloudControllerManagerCommand() *cobra.Command {
 	return cmd
 }
 
+// validateCloudConfigFile validates the cloud config file, prints the problems found to out and returns the exit code.
+func validateCloudConfigFile(out io.Writer, cloudConfigFile string) int {
+	if cloudConfigFile == "" {
+		fmt.Fprintln(out, "--cloud-config is required to validate the cloud config")
+		return 1
+	}
+
+	file, err := os.Open(cloudConfigFile)
+	if err != nil {
+		fmt.Fprintf(out, "failed to open the cloud config file: %v\n", err)
+		return 1
+	}
+	defer file.Close()
+
+	config, err := provider.ParseConfig(file)
+	if err != nil {
+		fmt.Fprintf(out, "failed to parse the cloud config file %s: %v\n", cloudConfigFile, err)
+		return 1
+	}
+
+	err = config.Validate()
+	var agg utilerrors.Aggregate
+	if !errors.As(err, &agg) {
+		fmt.Fprintf(out, "the cloud config file %s is valid\n", cloudConfigFile)
+		return 0
+	}
+	fmt.Fprintf(out, "the cloud config file %s is invalid:\n", cloudConfigFile)
+	for _, err := range agg.Errors() {
+		fmt.Fprintf(out, "  - %v\n", err)
+	}
+	return 1
+}
+
 // RunWrapper adapts the ccm boot logic to the leader elector call back function
 func RunWrapper(s *options.CloudControllerManagerOptions, c *cloudcontrollerconfig.Config, h *controllerhealthz.MutableHealthzHandler) func(ctx context.Context) {
 	return func(ctx context.Context) {
@@ -273,26 +315,37 @@ func StartHTTPServer(c *cloudcontrollerconfig.CompletedConfig, stopCh <-chan str
 	}
 
 	healthzHandler := controllerhealthz.NewMutableHealthzHandler(checks...)
+	// The Azure dependencies are only checked for the readiness, so that the failures of Azure don't
+	// restart the controller manager.
+	readyzChecks := append([]healthz.HealthChecker{azureHealthz}, checks...)
 	// Start the controller manager HTTP server
 	if c.SecureServing != nil {
 		unsecuredMux := genericcontrollermanager.NewBaseHandler(&c.ComponentConfig.Generic.Debugging, healthzHandler)
+		if c.EnableCacheDebug {
+			unsecuredMux.Handle(cacheDebugPath, azcache.DebugHandler())
+		}
+		unsecuredMux.Handle(azureHealthzPath, azureHealthz)
 		handler := genericcontrollermanager.BuildHandlerChain(unsecuredMux, &c.Authorization, &c.Authentication)
 		// TODO: handle stoppedCh returned by c.SecureServing.Serve
 		if _, _, err := c.SecureServing.Serve(handler, 0, stopCh); err != nil {
 			return nil, err
 		}
 
-		healthz.InstallReadyzHandler(unsecuredMux, checks...)
+		healthz.InstallReadyzHandler(unsecuredMux, readyzChecks...)
 	}
 	if c.InsecureServing != nil {
 		unsecuredMux := genericcontrollermanager.NewBaseHandler(&c.ComponentConfig.Generic.Debugging, healthzHandler)
+		if c.EnableCacheDebug {
+			unsecuredMux.Handle(cacheDebugPath, azcache.DebugHandler())
+		}
+		unsecuredMux.Handle(azureHealthzPath, azureHealthz)
 		insecureSuperuserAuthn := server.AuthenticationInfo{Authenticator: &server.InsecureSuperuser{}}
 		handler := genericcontrollermanager.BuildHandlerChain(unsecuredMux, nil, &insecureSuperuserAuthn)
 		if err := c.InsecureServing.Serve(handler, 0, stopCh); err != nil {
 			return nil, err
 		}
 
-		healthz.InstallReadyzHandler(unsecuredMux, checks...)
+		healthz.InstallReadyzHandler(unsecuredMux, readyzChecks...)
 	}
 
 	return healthzHandler, nil
@@ -323,6 +376,9 @@ func Run(ctx context.Context, c *cloudcontrollerconfig.CompletedConfig, h *contr
 	if cloud == nil {
 		klog.Fatalf("cloud provider is nil, please check if the --cloud-config is set properly")
 	}
+	if az, ok := cloud.(*provider.Cloud); ok {
+		azureHealthz.setChecker(az.NewDependencyHealthChecker())
+	}
 
 	if !cloud.HasClusterID() {
 		if c.ComponentConfig.KubeCloudShared.AllowUntaggedCloud {
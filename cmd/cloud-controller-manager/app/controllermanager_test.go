@@ -17,11 +17,17 @@ limitations under the License.
 package app
 
 import (
+	"bytes"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/provider"
 )
 
 func TestShouldDisableCloudProvider(t *testing.T) {
@@ -44,3 +50,66 @@ func TestShouldDisableCloudProvider(t *testing.T) {
 	assert.NoError(t, err)
 	assert.True(t, res)
 }
+
+func TestValidateCloudConfigFile(t *testing.T) {
+	for _, tc := range []struct {
+		description      string
+		content          string
+		expectedCode     int
+		expectedMessages []string
+	}{
+		{
+			description:      "valid config",
+			content:          `{"subscriptionId":"00000000-0000-0000-0000-000000000001","vmType":"vmss","loadBalancerSku":"standard"}`,
+			expectedMessages: []string{"is valid"},
+		},
+		{
+			description:  "invalid config",
+			content:      `{"subscriptionId":"subscription","vmType":"vmss","loadBalancerSku":"premium"}`,
+			expectedCode: 1,
+			expectedMessages: []string{
+				"is invalid",
+				"  - subscriptionId subscription is invalid, it should be a GUID\n",
+				"  - loadBalancerSku premium is not supported, supported values are basic and standard\n",
+			},
+		},
+		{
+			description:      "malformed config",
+			content:          `{`,
+			expectedCode:     1,
+			expectedMessages: []string{"failed to parse the cloud config file"},
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			cloudConfigFile := filepath.Join(t.TempDir(), "azure.json")
+			assert.NoError(t, ioutil.WriteFile(cloudConfigFile, []byte(tc.content), 0600))
+
+			out := &bytes.Buffer{}
+			assert.Equal(t, tc.expectedCode, validateCloudConfigFile(out, cloudConfigFile))
+			for _, message := range tc.expectedMessages {
+				assert.Contains(t, out.String(), message)
+			}
+		})
+	}
+
+	out := &bytes.Buffer{}
+	assert.Equal(t, 1, validateCloudConfigFile(out, ""))
+	assert.Contains(t, out.String(), "--cloud-config is required")
+}
+
+func TestAzureHealthzAdaptor(t *testing.T) {
+	adaptor := &azureHealthzAdaptor{}
+	assert.Equal(t, "azure", adaptor.Name())
+	assert.Error(t, adaptor.Check(nil), "the readiness should fail before the cloud provider is initialized")
+
+	recorder := httptest.NewRecorder()
+	adaptor.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, azureHealthzPath, nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+
+	adaptor.setChecker((&provider.Cloud{}).NewDependencyHealthChecker())
+	assert.NoError(t, adaptor.Check(nil))
+
+	recorder = httptest.NewRecorder()
+	adaptor.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, azureHealthzPath, nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
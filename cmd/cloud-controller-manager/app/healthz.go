@@ -0,0 +1,79 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/provider"
+)
+
+// azureHealthzPath is the path of the handler reporting the status of each Azure dependency check.
+const azureHealthzPath = "/healthz/azure"
+
+// azureHealthz is the readiness check of the Azure dependencies. The HTTP server is started before the cloud
+// provider is initialized, so the checker of the cloud provider is set by Run once it is created. With dynamic
+// reloading enabled, Run is called again when the cloud config is updated, which replaces the checker with the
+// one of the re-created cloud provider.
+var azureHealthz = &azureHealthzAdaptor{}
+
+// azureHealthzAdaptor adapts the DependencyHealthChecker of the current cloud provider to the readiness check
+// and the handler of azureHealthzPath. It fails until the cloud provider is initialized.
+type azureHealthzAdaptor struct {
+	lock    sync.RWMutex
+	checker *provider.DependencyHealthChecker
+}
+
+// setChecker sets the DependencyHealthChecker of the current cloud provider.
+func (adaptor *azureHealthzAdaptor) setChecker(checker *provider.DependencyHealthChecker) {
+	adaptor.lock.Lock()
+	defer adaptor.lock.Unlock()
+	adaptor.checker = checker
+}
+
+// getChecker returns the current checker, the checks are run by the caller without holding the lock.
+func (adaptor *azureHealthzAdaptor) getChecker() *provider.DependencyHealthChecker {
+	adaptor.lock.RLock()
+	defer adaptor.lock.RUnlock()
+	return adaptor.checker
+}
+
+// Name implements the healthz.HealthChecker interface.
+func (adaptor *azureHealthzAdaptor) Name() string {
+	return "azure"
+}
+
+// Check implements the healthz.HealthChecker interface.
+func (adaptor *azureHealthzAdaptor) Check(req *http.Request) error {
+	checker := adaptor.getChecker()
+	if checker == nil {
+		return fmt.Errorf("the Azure cloud provider is not initialized yet")
+	}
+	return checker.Check(req)
+}
+
+// ServeHTTP reports the status of each Azure dependency check.
+func (adaptor *azureHealthzAdaptor) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	checker := adaptor.getChecker()
+	if checker == nil {
+		http.Error(w, "the Azure cloud provider is not initialized yet", http.StatusServiceUnavailable)
+		return
+	}
+	checker.ServeHTTP(w, req)
+}
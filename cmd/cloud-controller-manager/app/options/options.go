@@ -82,6 +82,12 @@ type CloudControllerManagerOptions struct {
 	NodeStatusUpdateFrequency metav1.Duration
 
 	DynamicReloading *DynamicReloadingOptions
+
+	// EnableCacheDebug enables the debug handler dumping the provider caches.
+	EnableCacheDebug bool
+
+	// ValidateCloudConfig validates the cloud config file and exits without starting the controllers.
+	ValidateCloudConfig bool
 }
 
 // NewCloudControllerManagerOptions creates a new ExternalCMServer with a default config.
@@ -159,6 +165,8 @@ func (o *CloudControllerManagerOptions) Flags(allControllers, disabledByDefaultC
 	fs.StringVar(&o.Master, "master", o.Master, "The address of the Kubernetes API server (overrides any value in kubeconfig).")
 	fs.StringVar(&o.Kubeconfig, "kubeconfig", o.Kubeconfig, "Path to kubeconfig file with authorization and master location information.")
 	fs.DurationVar(&o.NodeStatusUpdateFrequency.Duration, "node-status-update-frequency", o.NodeStatusUpdateFrequency.Duration, "Specifies how often the controller updates nodes' status.")
+	fs.BoolVar(&o.EnableCacheDebug, "enable-cache-debug", o.EnableCacheDebug, "Enables the /debug/azure-cache handler dumping the keys, ages and TTLs of the provider caches as JSON.")
+	fs.BoolVar(&o.ValidateCloudConfig, "validate-cloud-config", o.ValidateCloudConfig, "Validates the file specified by --cloud-config, prints the problems found and exits. The exit code is 1 if the config is invalid.")
 
 	utilfeature.DefaultMutableFeatureGate.AddFlag(fss.FlagSet("generic"))
 
@@ -238,6 +246,7 @@ func (o *CloudControllerManagerOptions) ApplyTo(c *cloudcontrollerconfig.Config,
 	c.ComponentConfig.Generic.Address = o.InsecureServing.BindAddress.String()
 
 	c.ComponentConfig.NodeStatusUpdateFrequency = o.NodeStatusUpdateFrequency
+	c.EnableCacheDebug = o.EnableCacheDebug
 
 	return nil
 }
@@ -38,6 +38,10 @@ type Config struct {
 	// NodeStatusUpdateFrequency is the frequency at which the controller updates nodes' status
 	NodeStatusUpdateFrequency metav1.Duration
 
+	// NodeSyncPeriod is the period at which the controller syncs nodes' status even if the node
+	// metadata got from the cloud provider is not changed.
+	NodeSyncPeriod metav1.Duration
+
 	SecureServing *apiserver.SecureServingInfo
 	// LoopbackClientConfig is a config for a privileged loopback connection
 	LoopbackClientConfig *restclient.Config
@@ -77,9 +81,18 @@ type Config struct {
 	// If true, the node condition "NodeNetworkUnavailable" would be set to true on initialization.
 	WaitForRoutes bool
 
+	// LeaderElection defines the configuration of leader election client.
+	LeaderElection componentbaseconfig.LeaderElectionConfiguration
+
 	// Specifies if node information is retrieved via IMDS or ARM.
 	UseInstanceMetadata bool
 
+	// EnableScheduledEventsMonitor indicates whether the node should be tainted before it is shut down
+	// by the scheduled events got from IMDS.
+	EnableScheduledEventsMonitor bool
+	// ScheduledEventsPollInterval is the interval to poll the scheduled events from IMDS.
+	ScheduledEventsPollInterval metav1.Duration
+
 	// WindowsService should be set to true if cloud-node-manager is running as a service on Windows.
 	// Its corresponding flag only gets registered in Windows builds
 	WindowsService bool
@@ -19,14 +19,18 @@ package app
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"time"
 
 	"github.com/spf13/cobra"
 
+	"k8s.io/apimachinery/pkg/util/uuid"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apiserver/pkg/server"
 	"k8s.io/apiserver/pkg/server/healthz"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
 	cliflag "k8s.io/component-base/cli/flag"
 	"k8s.io/component-base/cli/globalflag"
 	"k8s.io/component-base/config"
@@ -104,11 +108,27 @@ func Run(c *cloudnodeconfig.Config, stopCh <-chan struct{}) error {
 	klog.Infof("Version: %+v", version.Get())
 	klog.Infof("NodeName: %s", c.NodeName)
 
-	// Start the controller manager HTTP server
+	// Setup any healthz checks we will want to use.
 	var checks []healthz.HealthChecker
+	var electionChecker *leaderelection.HealthzAdaptor
+	if c.LeaderElection.LeaderElect {
+		electionChecker = leaderelection.NewLeaderHealthzAdaptor(time.Second * 20)
+		checks = append(checks, electionChecker)
+	}
+	// The manager is ready after the node informer has synced.
+	nodeInformer := c.SharedInformers.Core().V1().Nodes().Informer()
+	readyChecks := append(checks, healthz.NamedCheck("node-informer-sync", func(_ *http.Request) error {
+		if !nodeInformer.HasSynced() {
+			return fmt.Errorf("the node informer has not synced")
+		}
+		return nil
+	}))
+
+	// Start the controller manager HTTP server
 	healthzHandler := controllerhealthz.NewMutableHealthzHandler(checks...)
 	if c.SecureServing != nil {
 		unsecuredMux := genericcontrollermanager.NewBaseHandler(&config.DebuggingConfiguration{}, healthzHandler)
+		healthz.InstallReadyzHandler(unsecuredMux, readyChecks...)
 		handler := genericcontrollermanager.BuildHandlerChain(unsecuredMux, &c.Authorization, &c.Authentication)
 		// TODO: handle stoppedCh returned by c.SecureServing.Serve
 		if _, _, err := c.SecureServing.Serve(handler, 0, stopCh); err != nil {
@@ -117,6 +137,7 @@ func Run(c *cloudnodeconfig.Config, stopCh <-chan struct{}) error {
 	}
 	if c.InsecureServing != nil {
 		unsecuredMux := genericcontrollermanager.NewBaseHandler(&config.DebuggingConfiguration{}, healthzHandler)
+		healthz.InstallReadyzHandler(unsecuredMux, readyChecks...)
 		insecureSuperuserAuthn := server.AuthenticationInfo{Authenticator: &server.InsecureSuperuser{}}
 		handler := genericcontrollermanager.BuildHandlerChain(unsecuredMux, nil, &insecureSuperuserAuthn)
 		if err := c.InsecureServing.Serve(handler, 0, stopCh); err != nil {
@@ -130,14 +151,67 @@ func Run(c *cloudnodeconfig.Config, stopCh <-chan struct{}) error {
 		}
 	}
 
-	run(context.TODO())
+	if !c.LeaderElection.LeaderElect {
+		run(context.TODO())
+		panic("unreachable")
+	}
+
+	// Identity used to distinguish between multiple cloud node manager instances
+	id, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+	// add a uniquifier so that two processes on the same host don't accidentally both become active
+	id = id + "_" + string(uuid.NewUUID())
+
+	// Lock required for leader election
+	rl, err := resourcelock.NewFromKubeconfig(c.LeaderElection.ResourceLock,
+		c.LeaderElection.ResourceNamespace,
+		c.LeaderElection.ResourceName,
+		resourcelock.ResourceLockConfig{
+			Identity:      id,
+			EventRecorder: c.EventRecorder,
+		},
+		c.Kubeconfig,
+		c.LeaderElection.RenewDeadline.Duration)
+	if err != nil {
+		return fmt.Errorf("error creating lock: %w", err)
+	}
+
+	// Try and become the leader and start cloud node manager loops
+	runWithLeaderElection(context.TODO(), rl, c.LeaderElection, electionChecker, run, func() {
+		klog.Fatalf("leaderelection lost")
+	})
 	panic("unreachable")
 }
 
+// runWithLeaderElection runs the cloud node manager loops only after it becomes the leader, so that only
+// one of the replicas updates the nodes when the manager runs as a Deployment. It blocks until ctx is done.
+func runWithLeaderElection(ctx context.Context, lock resourcelock.Interface, lec config.LeaderElectionConfiguration,
+	watchDog *leaderelection.HealthzAdaptor, run func(context.Context), onStoppedLeading func()) {
+	leaderelection.RunOrDie(ctx, leaderelection.LeaderElectionConfig{
+		Lock:          lock,
+		LeaseDuration: lec.LeaseDuration.Duration,
+		RenewDeadline: lec.RenewDeadline.Duration,
+		RetryPeriod:   lec.RetryPeriod.Duration,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: run,
+			OnStoppedLeading: onStoppedLeading,
+		},
+		WatchDog: watchDog,
+		Name:     "cloud-node-manager",
+	})
+}
+
 // startControllers starts the cloud specific controller loops.
 func startControllers(c *cloudnodeconfig.Config, stopCh <-chan struct{}, healthzHandler *controllerhealthz.MutableHealthzHandler) error {
 	klog.V(1).Infof("Starting cloud-node-manager...")
 
+	var scheduledEventsPollInterval time.Duration
+	if c.EnableScheduledEventsMonitor {
+		scheduledEventsPollInterval = c.ScheduledEventsPollInterval.Duration
+	}
+
 	// Start the CloudNodeController
 	nodeController := nodemanager.NewCloudNodeController(
 		c.NodeName,
@@ -146,7 +220,9 @@ func startControllers(c *cloudnodeconfig.Config, stopCh <-chan struct{}, healthz
 		c.ClientBuilder.ClientOrDie("node-controller"),
 		nodeprovider.NewNodeProvider(c.UseInstanceMetadata, c.CloudConfigFilePath),
 		c.NodeStatusUpdateFrequency.Duration,
-		c.WaitForRoutes)
+		c.WaitForRoutes,
+		scheduledEventsPollInterval,
+		c.NodeSyncPeriod.Duration)
 
 	go nodeController.Run(stopCh)
 
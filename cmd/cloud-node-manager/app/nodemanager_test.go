@@ -0,0 +1,95 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/component-base/config"
+)
+
+func TestRunWithLeaderElection(t *testing.T) {
+	cs := fake.NewSimpleClientset(&v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node0"},
+	})
+	lec := config.LeaderElectionConfiguration{
+		LeaderElect:   true,
+		LeaseDuration: metav1.Duration{Duration: 2 * time.Second},
+		RenewDeadline: metav1.Duration{Duration: time.Second},
+		RetryPeriod:   metav1.Duration{Duration: 100 * time.Millisecond},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var lock sync.Mutex
+	var leaders []string
+	var wg sync.WaitGroup
+	for _, id := range []string{"instance0", "instance1"} {
+		id := id
+		rl := &resourcelock.LeaseLock{
+			LeaseMeta:  metav1.ObjectMeta{Name: "cloud-node-manager", Namespace: metav1.NamespaceSystem},
+			Client:     cs.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{Identity: id},
+		}
+		// Only the leader updates the node.
+		run := func(ctx context.Context) {
+			lock.Lock()
+			leaders = append(leaders, id)
+			lock.Unlock()
+
+			node, err := cs.CoreV1().Nodes().Get(ctx, "node0", metav1.GetOptions{})
+			assert.NoError(t, err)
+			node.Labels = map[string]string{"updated-by": id}
+			_, err = cs.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{})
+			assert.NoError(t, err)
+			<-ctx.Done()
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runWithLeaderElection(ctx, rl, lec, nil, run, func() {})
+		}()
+	}
+
+	err := wait.PollImmediate(100*time.Millisecond, 10*time.Second, func() (bool, error) {
+		node, err := cs.CoreV1().Nodes().Get(context.Background(), "node0", metav1.GetOptions{})
+		return err == nil && node.Labels["updated-by"] != "", nil
+	})
+	assert.NoError(t, err)
+
+	// Wait for a few lease renewals to make sure the other instance doesn't take over.
+	time.Sleep(3 * lec.LeaseDuration.Duration)
+	cancel()
+	wg.Wait()
+
+	assert.Len(t, leaders, 1)
+	node, err := cs.CoreV1().Nodes().Get(context.Background(), "node0", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, leaders[0], node.Labels["updated-by"])
+}
@@ -34,9 +34,11 @@ import (
 	v1core "k8s.io/client-go/kubernetes/typed/core/v1"
 	restclient "k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
 	"k8s.io/client-go/tools/record"
 	cliflag "k8s.io/component-base/cli/flag"
 	componentbaseconfig "k8s.io/component-base/config"
+	componentbaseconfigoptions "k8s.io/component-base/config/options"
 	"k8s.io/controller-manager/pkg/clientbuilder"
 	"k8s.io/klog/v2"
 
@@ -58,6 +60,10 @@ const (
 	CloudControllerManagerPort = 10263
 	// defaultNodeStatusUpdateFrequencyInMinute is the default frequency at which the manager updates nodes' status.
 	defaultNodeStatusUpdateFrequencyInMinute = 5
+	// defaultScheduledEventsPollIntervalInSecond is the default interval at which the manager polls the scheduled events.
+	defaultScheduledEventsPollIntervalInSecond = 10
+	// defaultLeaderElectionResourceName is the default name of the resource object used for the leader election.
+	defaultLeaderElectionResourceName = "cloud-node-manager"
 )
 
 // CloudNodeManagerOptions is the main context object for the controller manager.
@@ -74,6 +80,9 @@ type CloudNodeManagerOptions struct {
 
 	// NodeStatusUpdateFrequency is the frequency at which the manager updates nodes' status
 	NodeStatusUpdateFrequency metav1.Duration
+	// NodeSyncPeriod is the period at which the manager syncs nodes' status even if the node
+	// metadata got from the cloud provider is not changed.
+	NodeSyncPeriod metav1.Duration
 	// minResyncPeriod is the resync period in reflectors; will be random between
 	// minResyncPeriod and 2*minResyncPeriod.
 	MinResyncPeriod metav1.Duration
@@ -83,9 +92,18 @@ type CloudNodeManagerOptions struct {
 	// WaitForRoutes indicates whether the node should wait for routes to be created on Azure.
 	// If true, the node condition "NodeNetworkUnavailable" would be set to true on initialization.
 	WaitForRoutes bool
+	// LeaderElection defines the configuration of leader election client. It should be enabled
+	// when the manager runs as a Deployment instead of a DaemonSet.
+	LeaderElection componentbaseconfig.LeaderElectionConfiguration
 
 	UseInstanceMetadata bool
 
+	// EnableScheduledEventsMonitor indicates whether the node should be tainted before it is shut down
+	// by the scheduled events got from IMDS.
+	EnableScheduledEventsMonitor bool
+	// ScheduledEventsPollInterval is the interval to poll the scheduled events from IMDS.
+	ScheduledEventsPollInterval metav1.Duration
+
 	// WindowsService should be set to true if cloud-node-manager is running as a service on Windows.
 	// Its corresponding flag only gets registered in Windows builds
 	WindowsService bool
@@ -105,6 +123,18 @@ func NewCloudNodeManagerOptions() (*CloudNodeManagerOptions, error) {
 		NodeStatusUpdateFrequency: metav1.Duration{
 			Duration: defaultNodeStatusUpdateFrequencyInMinute * time.Minute,
 		},
+		ScheduledEventsPollInterval: metav1.Duration{
+			Duration: defaultScheduledEventsPollIntervalInSecond * time.Second,
+		},
+		LeaderElection: componentbaseconfig.LeaderElectionConfiguration{
+			LeaderElect:       false,
+			LeaseDuration:     metav1.Duration{Duration: 15 * time.Second},
+			RenewDeadline:     metav1.Duration{Duration: 10 * time.Second},
+			RetryPeriod:       metav1.Duration{Duration: 2 * time.Second},
+			ResourceLock:      resourcelock.LeasesResourceLock,
+			ResourceName:      defaultLeaderElectionResourceName,
+			ResourceNamespace: metav1.NamespaceSystem,
+		},
 	}
 
 	s.Authentication.RemoteKubeConfigFileOptional = true
@@ -126,6 +156,8 @@ func (o *CloudNodeManagerOptions) Flags() cliflag.NamedFlagSets {
 	o.Authentication.AddFlags(fss.FlagSet("authentication"))
 	o.Authorization.AddFlags(fss.FlagSet("authorization"))
 
+	componentbaseconfigoptions.BindLeaderElectionFlags(&o.LeaderElection, fss.FlagSet("leader election"))
+
 	fs := fss.FlagSet("misc")
 	o.addOSFlags(fs)
 
@@ -133,6 +165,7 @@ func (o *CloudNodeManagerOptions) Flags() cliflag.NamedFlagSets {
 	fs.StringVar(&o.Kubeconfig, "kubeconfig", o.Kubeconfig, "Path to kubeconfig file with authorization and master location information.")
 	fs.StringVar(&o.NodeName, "node-name", o.NodeName, "Name of the Node (default is hostname).")
 	fs.DurationVar(&o.NodeStatusUpdateFrequency.Duration, "node-status-update-frequency", o.NodeStatusUpdateFrequency.Duration, "Specifies how often the controller updates nodes' status.")
+	fs.DurationVar(&o.NodeSyncPeriod.Duration, "node-sync-period", o.NodeSyncPeriod.Duration, "Specifies how often the controller syncs nodes' status even if the node metadata got from the cloud provider is not changed. Nodes' status is synced every --node-status-update-frequency if it is zero.")
 	fs.DurationVar(&o.MinResyncPeriod.Duration, "min-resync-period", o.MinResyncPeriod.Duration, "The resync period in reflectors will be random between MinResyncPeriod and 2*MinResyncPeriod.")
 	fs.StringVar(&o.ClientConnection.ContentType, "kube-api-content-type", o.ClientConnection.ContentType, "Content type of requests sent to apiserver.")
 	fs.Float32Var(&o.ClientConnection.QPS, "kube-api-qps", 20, "QPS to use while talking with kubernetes apiserver.")
@@ -140,6 +173,8 @@ func (o *CloudNodeManagerOptions) Flags() cliflag.NamedFlagSets {
 	fs.BoolVar(&o.WaitForRoutes, "wait-routes", false, "Whether the nodes should wait for routes created on Azure route table. It should be set to true when using kubenet plugin.")
 	fs.BoolVar(&o.UseInstanceMetadata, "use-instance-metadata", true, "Should use Instance Metadata Service for fetching node information; if false will use ARM instead.")
 	fs.StringVar(&o.CloudConfigFilePath, "cloud-config", o.CloudConfigFilePath, "The path to the cloud config file to be used when using ARM to fetch node information.")
+	fs.BoolVar(&o.EnableScheduledEventsMonitor, "enable-scheduled-events-monitor", false, "Whether the node should be tainted with node.cloudprovider.kubernetes.io/shutdown before it is shut down by the scheduled events, e.g. Preempt, Reboot or Redeploy.")
+	fs.DurationVar(&o.ScheduledEventsPollInterval.Duration, "scheduled-events-poll-interval", o.ScheduledEventsPollInterval.Duration, "Specifies how often the scheduled events are polled from instance metadata service if --enable-scheduled-events-monitor is set.")
 	return fss
 }
 
@@ -173,6 +208,7 @@ func (o *CloudNodeManagerOptions) ApplyTo(c *cloudnodeconfig.Config, userAgent s
 	c.Kubeconfig.QPS = o.ClientConnection.QPS
 	c.Kubeconfig.Burst = int(o.ClientConnection.Burst)
 	c.WaitForRoutes = o.WaitForRoutes
+	c.LeaderElection = o.LeaderElection
 
 	c.Client, err = clientset.NewForConfig(restclient.AddUserAgent(c.Kubeconfig, userAgent))
 	if err != nil {
@@ -200,8 +236,11 @@ func (o *CloudNodeManagerOptions) ApplyTo(c *cloudnodeconfig.Config, userAgent s
 		options.FieldSelector = fields.OneTermEqualSelector("metadata.name", c.NodeName).String()
 	}))
 	c.NodeStatusUpdateFrequency = o.NodeStatusUpdateFrequency
+	c.NodeSyncPeriod = o.NodeSyncPeriod
 	c.UseInstanceMetadata = o.UseInstanceMetadata
 	c.CloudConfigFilePath = o.CloudConfigFilePath
+	c.EnableScheduledEventsMonitor = o.EnableScheduledEventsMonitor
+	c.ScheduledEventsPollInterval = o.ScheduledEventsPollInterval
 
 	c.WindowsService = o.WindowsService
 
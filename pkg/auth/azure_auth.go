@@ -21,6 +21,8 @@ import (
 	"crypto/x509"
 	"fmt"
 	"io/ioutil"
+	"net/url"
+	"regexp"
 	"strings"
 
 	"github.com/Azure/go-autorest/autorest/adal"
@@ -36,6 +38,9 @@ import (
 var (
 	// ErrorNoAuth indicates that no credentials are provided.
 	ErrorNoAuth = fmt.Errorf("no credentials provided for Azure cloud provider")
+
+	// clientIDRE matches the client IDs of the identities, which are GUIDs.
+	clientIDRE = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
 )
 
 // AzureAuthConfig holds auth related part of cloud config
@@ -52,12 +57,22 @@ type AzureAuthConfig struct {
 	AADClientCertPath string `json:"aadClientCertPath,omitempty" yaml:"aadClientCertPath,omitempty"`
 	// The password of the client certificate for an AAD application with RBAC access to talk to Azure RM APIs
 	AADClientCertPassword string `json:"aadClientCertPassword,omitempty" yaml:"aadClientCertPassword,omitempty" datapolicy:"password"`
+	// The URI of the Key Vault secret of the client certificate, e.g. https://<vault>.vault.azure.net/secrets/<name>.
+	// The certificate is fetched with the managed identity at startup if AADClientCertPath is not set.
+	AADClientCertKeyVaultURI string `json:"aadClientCertKeyVaultURI,omitempty" yaml:"aadClientCertKeyVaultURI,omitempty"`
 	// Use managed service identity for the virtual machine to access Azure ARM APIs
 	UseManagedIdentityExtension bool `json:"useManagedIdentityExtension,omitempty" yaml:"useManagedIdentityExtension,omitempty"`
 	// UserAssignedIdentityID contains the Client ID of the user assigned MSI which is assigned to the underlying VMs. If empty the user assigned identity is not used.
 	// More details of the user assigned identity can be found at: https://docs.microsoft.com/en-us/azure/active-directory/managed-service-identity/overview
 	// For the user assigned identity specified here to be used, the UseManagedIdentityExtension has to be set to true.
 	UserAssignedIdentityID string `json:"userAssignedIdentityID,omitempty" yaml:"userAssignedIdentityID,omitempty"`
+	// Use AAD workload identity to access Azure ARM APIs, which exchanges the federated token in AADFederatedTokenFile
+	// for the access tokens of the AAD application specified by AADClientID and TenantID.
+	// It takes precedence over the managed identity, the client secret and the client certificate.
+	UseFederatedWorkloadIdentityExtension bool `json:"useFederatedWorkloadIdentityExtension,omitempty" yaml:"useFederatedWorkloadIdentityExtension,omitempty"`
+	// The path of the federated token (e.g. the projected service account token) used by the AAD workload identity.
+	// The file is read whenever the access token is refreshed, so the rotated tokens are used without restarts.
+	AADFederatedTokenFile string `json:"aadFederatedTokenFile,omitempty" yaml:"aadFederatedTokenFile,omitempty"`
 	// The ID of the Azure Subscription that the cluster is deployed in
 	SubscriptionID string `json:"subscriptionId,omitempty" yaml:"subscriptionId,omitempty"`
 	// IdentitySystem indicates the identity provider. Relevant only to hybrid clouds (Azure Stack).
@@ -66,6 +81,13 @@ type AzureAuthConfig struct {
 	// ResourceManagerEndpoint is the cloud's resource manager endpoint. If set, cloud provider queries this endpoint
 	// in order to generate an autorest.Environment instance instead of using one of the pre-defined Environments.
 	ResourceManagerEndpoint string `json:"resourceManagerEndpoint,omitempty" yaml:"resourceManagerEndpoint,omitempty"`
+	// CustomCloudEnvironmentFilePath is the path of a JSON file defining the cloud environment, e.g. for Azure Stack Hub
+	// and air-gapped clouds. The fields of the file are the same as autorest.Environment. If set, it takes precedence over
+	// ResourceManagerEndpoint and Cloud.
+	CustomCloudEnvironmentFilePath string `json:"customCloudEnvironmentFilePath,omitempty" yaml:"customCloudEnvironmentFilePath,omitempty"`
+	// DiscoverCloudEnvironmentFromIMDS indicates whether to discover the resource manager endpoint from the instance
+	// metadata service when ResourceManagerEndpoint is not set. The environment is then computed by querying the endpoint.
+	DiscoverCloudEnvironmentFromIMDS bool `json:"discoverCloudEnvironmentFromIMDS,omitempty" yaml:"discoverCloudEnvironmentFromIMDS,omitempty"`
 	// The AAD Tenant ID for the Subscription that the network resources are deployed in
 	NetworkResourceTenantID string `json:"networkResourceTenantID,omitempty" yaml:"networkResourceTenantID,omitempty"`
 	// The ID of the Azure Subscription that the network resources are deployed in
@@ -92,33 +114,14 @@ func GetServicePrincipalToken(config *AzureAuthConfig, env *azure.Environment, r
 		resource = env.ServiceManagementEndpoint
 	}
 
+	if config.UseFederatedWorkloadIdentityExtension {
+		klog.V(2).Infoln("azure: using workload identity extension to retrieve access token")
+		return getFederatedServicePrincipalToken(config, env, tenantID, resource)
+	}
+
 	if config.UseManagedIdentityExtension {
 		klog.V(2).Infoln("azure: using managed identity extension to retrieve access token")
-		msiEndpoint, err := adal.GetMSIVMEndpoint()
-		if err != nil {
-			return nil, fmt.Errorf("error getting the managed service identity endpoint: %w", err)
-		}
-		if len(config.UserAssignedIdentityID) > 0 {
-			klog.V(4).Info("azure: using User Assigned MSI ID to retrieve access token")
-			resourceID, err := azure.ParseResourceID(config.UserAssignedIdentityID)
-			if err == nil &&
-				strings.EqualFold(resourceID.Provider, "Microsoft.ManagedIdentity") &&
-				strings.EqualFold(resourceID.ResourceType, "userAssignedIdentities") {
-				klog.V(4).Info("azure: User Assigned MSI ID is resource ID")
-				return adal.NewServicePrincipalTokenFromMSIWithIdentityResourceID(msiEndpoint,
-					resource,
-					config.UserAssignedIdentityID)
-			}
-
-			klog.V(4).Info("azure: User Assigned MSI ID is client ID. Resource ID parsing error: %+v", err)
-			return adal.NewServicePrincipalTokenFromMSIWithUserAssignedID(msiEndpoint,
-				resource,
-				config.UserAssignedIdentityID)
-		}
-		klog.V(4).Info("azure: using System Assigned MSI to retrieve access token")
-		return adal.NewServicePrincipalTokenFromMSI(
-			msiEndpoint,
-			resource)
+		return getMSIServicePrincipalToken(config, resource)
 	}
 
 	oauthConfig, err := adal.NewOAuthConfigWithAPIVersion(env.ActiveDirectoryEndpoint, tenantID, nil)
@@ -135,15 +138,24 @@ func GetServicePrincipalToken(config *AzureAuthConfig, env *azure.Environment, r
 			resource)
 	}
 
-	if len(config.AADClientCertPath) > 0 && len(config.AADClientCertPassword) > 0 {
+	if len(config.AADClientCertPath) > 0 {
 		klog.V(2).Infoln("azure: using jwt client_assertion (client_cert+client_private_key) to retrieve access token")
-		certData, err := ioutil.ReadFile(config.AADClientCertPath)
+		secret, err := newCertificateFileSecret(config.AADClientCertPath, config.AADClientCertPassword)
 		if err != nil {
-			return nil, fmt.Errorf("reading the client certificate from file %s: %w", config.AADClientCertPath, err)
+			return nil, err
 		}
-		certificate, privateKey, err := decodePkcs12(certData, config.AADClientCertPassword)
+		return adal.NewServicePrincipalTokenWithSecret(
+			*oauthConfig,
+			config.AADClientID,
+			resource,
+			secret)
+	}
+
+	if len(config.AADClientCertKeyVaultURI) > 0 {
+		klog.V(2).Infoln("azure: using jwt client_assertion (client_cert+client_private_key from Key Vault) to retrieve access token")
+		certificate, privateKey, err := getCertificateFromKeyVault(config, env)
 		if err != nil {
-			return nil, fmt.Errorf("decoding the client certificate: %w", err)
+			return nil, err
 		}
 		return adal.NewServicePrincipalTokenFromCertificate(
 			*oauthConfig,
@@ -156,6 +168,120 @@ func GetServicePrincipalToken(config *AzureAuthConfig, env *azure.Environment, r
 	return nil, ErrorNoAuth
 }
 
+// getMSIServicePrincipalToken creates a new service principal token of the managed identity for the resource.
+func getMSIServicePrincipalToken(config *AzureAuthConfig, resource string) (*adal.ServicePrincipalToken, error) {
+	msiEndpoint, err := adal.GetMSIVMEndpoint()
+	if err != nil {
+		return nil, fmt.Errorf("error getting the managed service identity endpoint: %w", err)
+	}
+	if len(config.UserAssignedIdentityID) > 0 {
+		klog.V(4).Info("azure: using User Assigned MSI ID to retrieve access token")
+		isResourceID, err := IsUserAssignedIdentityResourceID(config.UserAssignedIdentityID)
+		if err != nil {
+			return nil, err
+		}
+		if isResourceID {
+			klog.V(4).Info("azure: User Assigned MSI ID is resource ID")
+			return adal.NewServicePrincipalTokenFromMSIWithIdentityResourceID(msiEndpoint,
+				resource,
+				config.UserAssignedIdentityID)
+		}
+
+		klog.V(4).Info("azure: User Assigned MSI ID is client ID")
+		return adal.NewServicePrincipalTokenFromMSIWithUserAssignedID(msiEndpoint,
+			resource,
+			config.UserAssignedIdentityID)
+	}
+	klog.V(4).Info("azure: using System Assigned MSI to retrieve access token")
+	return adal.NewServicePrincipalTokenFromMSI(
+		msiEndpoint,
+		resource)
+}
+
+// IsUserAssignedIdentityResourceID returns true if the user-assigned identity ID is a resource ID, e.g.
+// /subscriptions/<subscription>/resourceGroups/<rg>/providers/Microsoft.ManagedIdentity/userAssignedIdentities/<name>,
+// or false if it is a client ID, which is a GUID. An error is returned if it is neither of them.
+func IsUserAssignedIdentityResourceID(userAssignedIdentityID string) (bool, error) {
+	if strings.HasPrefix(userAssignedIdentityID, "/") {
+		resourceID, err := azure.ParseResourceID(userAssignedIdentityID)
+		if err == nil &&
+			strings.EqualFold(resourceID.Provider, "Microsoft.ManagedIdentity") &&
+			strings.EqualFold(resourceID.ResourceType, "userAssignedIdentities") {
+			return true, nil
+		}
+		return false, fmt.Errorf("userAssignedIdentityID %s is not a resource ID of a user-assigned identity", userAssignedIdentityID)
+	}
+	if !clientIDRE.MatchString(userAssignedIdentityID) {
+		return false, fmt.Errorf("userAssignedIdentityID %s is neither a client ID nor a resource ID of a user-assigned identity", userAssignedIdentityID)
+	}
+	return false, nil
+}
+
+// getFederatedServicePrincipalToken creates a new service principal token which exchanges the federated token
+// in AADFederatedTokenFile for the access tokens.
+func getFederatedServicePrincipalToken(config *AzureAuthConfig, env *azure.Environment, tenantID, resource string) (*adal.ServicePrincipalToken, error) {
+	if strings.EqualFold(config.IdentitySystem, consts.ADFSIdentitySystem) {
+		return nil, fmt.Errorf("workload identity is not supported by the ADFS identity system")
+	}
+	if len(config.AADClientID) == 0 || len(config.AADFederatedTokenFile) == 0 {
+		return nil, fmt.Errorf("both aadClientId and aadFederatedTokenFile should be set when useFederatedWorkloadIdentityExtension is true")
+	}
+
+	// fail fast if the token file is not readable instead of failing in the first refresh
+	if _, err := readFederatedToken(config.AADFederatedTokenFile); err != nil {
+		return nil, err
+	}
+
+	oauthConfig, err := adal.NewOAuthConfigWithAPIVersion(env.ActiveDirectoryEndpoint, tenantID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating the OAuth config: %w", err)
+	}
+
+	return adal.NewServicePrincipalTokenWithSecret(
+		*oauthConfig,
+		config.AADClientID,
+		resource,
+		&federatedTokenFileSecret{tokenFile: config.AADFederatedTokenFile})
+}
+
+// federatedTokenFileSecret implements adal.ServicePrincipalSecret for the federated tokens stored in files.
+// Unlike adal.ServicePrincipalFederatedSecret, the token file is read whenever the access token is refreshed,
+// so that the rotated federated tokens are picked up.
+type federatedTokenFileSecret struct {
+	tokenFile string
+}
+
+// SetAuthenticationValues populates the form submitted to acquire the access token with the federated token.
+func (secret *federatedTokenFileSecret) SetAuthenticationValues(spt *adal.ServicePrincipalToken, v *url.Values) error {
+	token, err := readFederatedToken(secret.tokenFile)
+	if err != nil {
+		return err
+	}
+
+	v.Set("client_assertion", token)
+	v.Set("client_assertion_type", "urn:ietf:params:oauth:client-assertion-type:jwt-bearer")
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (secret federatedTokenFileSecret) MarshalJSON() ([]byte, error) {
+	return nil, fmt.Errorf("marshalling federatedTokenFileSecret is not supported")
+}
+
+// readFederatedToken reads the federated token from the file.
+func readFederatedToken(tokenFile string) (string, error) {
+	data, err := ioutil.ReadFile(tokenFile)
+	if err != nil {
+		return "", fmt.Errorf("reading the federated token from file %s: %w", tokenFile, err)
+	}
+
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("the federated token file %s is empty", tokenFile)
+	}
+	return token, nil
+}
+
 // GetMultiTenantServicePrincipalToken is used when (and only when) NetworkResourceTenantID and NetworkResourceSubscriptionID are specified to have different values than TenantID and SubscriptionID.
 //
 // In that scenario, network resources are deployed in different AAD Tenant and Subscription than those for the cluster,
@@ -185,7 +311,7 @@ func GetMultiTenantServicePrincipalToken(config *AzureAuthConfig, env *azure.Env
 			env.ServiceManagementEndpoint)
 	}
 
-	if len(config.AADClientCertPath) > 0 && len(config.AADClientCertPassword) > 0 {
+	if len(config.AADClientCertPath) > 0 || len(config.AADClientCertKeyVaultURI) > 0 {
 		return nil, fmt.Errorf("AAD Application client certificate authentication is not supported in getting multi-tenant service principal token")
 	}
 
@@ -218,7 +344,7 @@ func GetNetworkResourceServicePrincipalToken(config *AzureAuthConfig, env *azure
 			env.ServiceManagementEndpoint)
 	}
 
-	if len(config.AADClientCertPath) > 0 && len(config.AADClientCertPassword) > 0 {
+	if len(config.AADClientCertPath) > 0 || len(config.AADClientCertKeyVaultURI) > 0 {
 		return nil, fmt.Errorf("AAD Application client certificate authentication is not supported in getting network resources service principal token")
 	}
 
@@ -297,5 +423,9 @@ func (config *AzureAuthConfig) checkConfigWhenNetworkResourceInDifferentTenant()
 		return fmt.Errorf("managed identity is not supported")
 	}
 
+	if config.UseFederatedWorkloadIdentityExtension {
+		return fmt.Errorf("workload identity is not supported")
+	}
+
 	return nil
 }
@@ -17,11 +17,15 @@ limitations under the License.
 package auth
 
 import (
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/Azure/go-autorest/autorest/adal"
 	"github.com/Azure/go-autorest/autorest/azure"
@@ -53,6 +57,14 @@ var (
 			NetworkResourceSubscriptionID: "NetworkResourceSubscriptionID",
 			UseManagedIdentityExtension:   true,
 		},
+		{
+			TenantID:                              "TenantID",
+			AADClientID:                           "AADClientID",
+			NetworkResourceTenantID:               "NetworkResourceTenantID",
+			NetworkResourceSubscriptionID:         "NetworkResourceSubscriptionID",
+			UseFederatedWorkloadIdentityExtension: true,
+			AADFederatedTokenFile:                 "./testdata/token",
+		},
 	}
 
 	// msiEndpointEnv is the environment variable used to store the endpoint in go-autorest/adal library.
@@ -159,6 +171,44 @@ func TestGetServicePrincipalTokenFromMSIWithIdentityResourceID(t *testing.T) {
 	}
 }
 
+func TestIsUserAssignedIdentityResourceID(t *testing.T) {
+	for _, tc := range []struct {
+		description        string
+		id                 string
+		expectedResourceID bool
+		expectedErr        error
+	}{
+		{
+			description: "client ID",
+			id:          "00000000-0000-0000-0000-000000000000",
+		},
+		{
+			description:        "resource ID",
+			id:                 "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/ua",
+			expectedResourceID: true,
+		},
+		{
+			description: "resource ID of another resource type",
+			id:          "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/vm",
+			expectedErr: fmt.Errorf("userAssignedIdentityID /subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/vm is not a resource ID of a user-assigned identity"),
+		},
+		{
+			description: "neither client ID nor resource ID",
+			id:          "ua",
+			expectedErr: fmt.Errorf("userAssignedIdentityID ua is neither a client ID nor a resource ID of a user-assigned identity"),
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			isResourceID, err := IsUserAssignedIdentityResourceID(tc.id)
+			assert.Equal(t, tc.expectedErr, err)
+			assert.Equal(t, tc.expectedResourceID, isResourceID)
+		})
+	}
+
+	_, err := GetServicePrincipalToken(&AzureAuthConfig{UseManagedIdentityExtension: true, UserAssignedIdentityID: "ua"}, &azure.PublicCloud, "")
+	assert.Error(t, err)
+}
+
 func TestGetServicePrincipalTokenFromMSI(t *testing.T) {
 	configs := []*AzureAuthConfig{
 		{
@@ -226,6 +276,142 @@ func TestGetServicePrincipalToken(t *testing.T) {
 	assert.Equal(t, token, spt)
 }
 
+// fakeSTS is a fake AAD token endpoint which records the client assertions in the token requests.
+type fakeSTS struct {
+	lock       sync.Mutex
+	assertions []string
+	forms      []map[string][]string
+}
+
+func (f *fakeSTS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if err := r.ParseForm(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	f.forms = append(f.forms, r.PostForm)
+	f.assertions = append(f.assertions, r.PostForm.Get("client_assertion"))
+	expiresOn := time.Now().Add(time.Hour).Unix()
+	_, _ = w.Write([]byte(fmt.Sprintf(`{"access_token":"token-%d","expires_in":"3600","expires_on":"%d","not_before":"%d","resource":"%s","token_type":"Bearer"}`,
+		len(f.assertions), expiresOn, expiresOn-3600, r.PostForm.Get("resource"))))
+}
+
+func TestGetServicePrincipalTokenFromFederatedToken(t *testing.T) {
+	sts := &fakeSTS{}
+	server := httptest.NewServer(sts)
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	assert.NoError(t, ioutil.WriteFile(tokenFile, []byte("federated-token-1\n"), 0600))
+
+	config := &AzureAuthConfig{
+		TenantID:                              "TenantID",
+		AADClientID:                           "AADClientID",
+		UseFederatedWorkloadIdentityExtension: true,
+		AADFederatedTokenFile:                 tokenFile,
+		// the workload identity should take precedence over the other credentials
+		AADClientSecret:             "AADClientSecret",
+		UseManagedIdentityExtension: true,
+	}
+	env := &azure.Environment{
+		ActiveDirectoryEndpoint:   server.URL + "/",
+		ServiceManagementEndpoint: "https://management.core.windows.net/",
+	}
+
+	token, err := GetServicePrincipalToken(config, env, "")
+	assert.NoError(t, err)
+
+	assert.NoError(t, token.Refresh())
+	assert.Equal(t, "token-1", token.OAuthToken())
+	assert.Equal(t, []string{"federated-token-1"}, sts.assertions)
+	assert.Equal(t, "AADClientID", sts.forms[0]["client_id"][0])
+	assert.Equal(t, "client_credentials", sts.forms[0]["grant_type"][0])
+	assert.Equal(t, "urn:ietf:params:oauth:client-assertion-type:jwt-bearer", sts.forms[0]["client_assertion_type"][0])
+	assert.NotContains(t, sts.forms[0], "client_secret")
+
+	// the rotated federated token should be used in the next refresh without recreating the token
+	assert.NoError(t, ioutil.WriteFile(tokenFile, []byte("federated-token-2"), 0600))
+	assert.NoError(t, token.Refresh())
+	assert.Equal(t, "token-2", token.OAuthToken())
+	assert.Equal(t, []string{"federated-token-1", "federated-token-2"}, sts.assertions)
+
+	// the refresh should fail if the token file becomes unreadable
+	assert.NoError(t, os.Remove(tokenFile))
+	err = token.Refresh()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), tokenFile)
+	assert.Len(t, sts.assertions, 2, "the token request should not be sent without the federated token")
+}
+
+func TestGetServicePrincipalTokenFromFederatedTokenNegative(t *testing.T) {
+	emptyTokenFile := filepath.Join(t.TempDir(), "empty")
+	assert.NoError(t, ioutil.WriteFile(emptyTokenFile, []byte(" \n"), 0600))
+
+	for _, tc := range []struct {
+		desc        string
+		config      *AzureAuthConfig
+		expectedErr string
+	}{
+		{
+			desc: "the token file should be specified",
+			config: &AzureAuthConfig{
+				TenantID:                              "TenantID",
+				AADClientID:                           "AADClientID",
+				UseFederatedWorkloadIdentityExtension: true,
+			},
+			expectedErr: "both aadClientId and aadFederatedTokenFile should be set",
+		},
+		{
+			desc: "the client ID should be specified",
+			config: &AzureAuthConfig{
+				TenantID:                              "TenantID",
+				UseFederatedWorkloadIdentityExtension: true,
+				AADFederatedTokenFile:                 emptyTokenFile,
+			},
+			expectedErr: "both aadClientId and aadFederatedTokenFile should be set",
+		},
+		{
+			desc: "the token file should be readable",
+			config: &AzureAuthConfig{
+				TenantID:                              "TenantID",
+				AADClientID:                           "AADClientID",
+				UseFederatedWorkloadIdentityExtension: true,
+				AADFederatedTokenFile:                 "./testdata/not-exist",
+			},
+			expectedErr: "reading the federated token from file ./testdata/not-exist",
+		},
+		{
+			desc: "the token file should not be empty",
+			config: &AzureAuthConfig{
+				TenantID:                              "TenantID",
+				AADClientID:                           "AADClientID",
+				UseFederatedWorkloadIdentityExtension: true,
+				AADFederatedTokenFile:                 emptyTokenFile,
+			},
+			expectedErr: "is empty",
+		},
+		{
+			desc: "ADFS should not be supported",
+			config: &AzureAuthConfig{
+				TenantID:                              "TenantID",
+				AADClientID:                           "AADClientID",
+				UseFederatedWorkloadIdentityExtension: true,
+				AADFederatedTokenFile:                 emptyTokenFile,
+				IdentitySystem:                        consts.ADFSIdentitySystem,
+			},
+			expectedErr: "not supported by the ADFS identity system",
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			_, err := GetServicePrincipalToken(tc.config, &azure.PublicCloud, "")
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tc.expectedErr)
+		})
+	}
+}
+
 func TestGetMultiTenantServicePrincipalToken(t *testing.T) {
 	config := &AzureAuthConfig{
 		TenantID:                      "TenantID",
@@ -249,25 +435,32 @@ func TestGetMultiTenantServicePrincipalToken(t *testing.T) {
 }
 
 func TestGetServicePrincipalTokenFromCertificate(t *testing.T) {
+	sts := &fakeSTS{}
+	server := httptest.NewServer(sts)
+	defer server.Close()
+
 	config := &AzureAuthConfig{
 		TenantID:              "TenantID",
 		AADClientID:           "AADClientID",
 		AADClientCertPath:     "./testdata/test.pfx",
 		AADClientCertPassword: "id",
 	}
-	env := &azure.PublicCloud
+	env := &azure.Environment{
+		ActiveDirectoryEndpoint:   server.URL + "/",
+		ServiceManagementEndpoint: "https://management.core.windows.net/",
+	}
 	token, err := GetServicePrincipalToken(config, env, "")
 	assert.NoError(t, err)
 
-	oauthConfig, err := adal.NewOAuthConfigWithAPIVersion(env.ActiveDirectoryEndpoint, config.TenantID, nil)
-	assert.NoError(t, err)
 	pfxContent, err := ioutil.ReadFile("./testdata/test.pfx")
 	assert.NoError(t, err)
-	certificate, privateKey, err := decodePkcs12(pfxContent, "id")
+	certificate, _, err := decodePkcs12(pfxContent, "id")
 	assert.NoError(t, err)
-	spt, err := adal.NewServicePrincipalTokenFromCertificate(*oauthConfig, config.AADClientID, certificate, privateKey, env.ServiceManagementEndpoint)
-	assert.NoError(t, err)
-	assert.Equal(t, token, spt)
+
+	assert.NoError(t, token.Refresh())
+	assert.Equal(t, "AADClientID", sts.forms[0]["client_id"][0])
+	assert.Equal(t, "urn:ietf:params:oauth:client-assertion-type:jwt-bearer", sts.forms[0]["client_assertion_type"][0])
+	assert.Equal(t, certificate.Raw, getAssertionCertificate(t, sts.assertions[0]))
 }
 
 func TestGetMultiTenantServicePrincipalTokenNegative(t *testing.T) {
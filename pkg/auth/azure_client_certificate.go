@@ -0,0 +1,265 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// keyVaultAPIVersion is the API version of the Key Vault secrets API.
+	keyVaultAPIVersion = "7.3"
+	// keyVaultRequestTimeout is the timeout of fetching the client certificate from Key Vault.
+	keyVaultRequestTimeout = 30 * time.Second
+	// pemContentType is the content type of the certificates in PEM format stored in Key Vault.
+	pemContentType = "application/x-pem-file"
+)
+
+// decodeClientCertificate decodes a client certificate in either PEM or PKCS#12 format, and checks whether
+// it is valid now. The PEM data should contain the certificate and its unencrypted RSA private key.
+func decodeClientCertificate(data []byte, password string) (*x509.Certificate, *rsa.PrivateKey, error) {
+	var certificate *x509.Certificate
+	var privateKey *rsa.PrivateKey
+	var err error
+	if bytes.Contains(data, []byte("-----BEGIN")) {
+		certificate, privateKey, err = decodePEM(data)
+	} else {
+		certificate, privateKey, err = decodePkcs12(data, password)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	if now.After(certificate.NotAfter) {
+		return nil, nil, fmt.Errorf("the client certificate %q expired at %s", certificate.Subject.CommonName, certificate.NotAfter.UTC().Format(time.RFC3339))
+	}
+	if now.Before(certificate.NotBefore) {
+		return nil, nil, fmt.Errorf("the client certificate %q is not valid until %s", certificate.Subject.CommonName, certificate.NotBefore.UTC().Format(time.RFC3339))
+	}
+	return certificate, privateKey, nil
+}
+
+// decodePEM decodes a PEM client certificate by extracting the first certificate, which is the leaf
+// certificate followed by its chain, and the RSA private key in PKCS#1 or PKCS#8 format.
+func decodePEM(data []byte) (*x509.Certificate, *rsa.PrivateKey, error) {
+	var certificate *x509.Certificate
+	var privateKey *rsa.PrivateKey
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		switch block.Type {
+		case "CERTIFICATE":
+			if certificate != nil {
+				continue
+			}
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, nil, fmt.Errorf("decoding the PEM client certificate: %w", err)
+			}
+			certificate = cert
+		case "RSA PRIVATE KEY":
+			if _, encrypted := block.Headers["Proc-Type"]; encrypted {
+				return nil, nil, fmt.Errorf("decoding the PEM client certificate: encrypted private keys are not supported, please use the PKCS#12 format instead")
+			}
+			key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, nil, fmt.Errorf("decoding the PEM client certificate: %w", err)
+			}
+			privateKey = key
+		case "PRIVATE KEY":
+			key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, nil, fmt.Errorf("decoding the PEM client certificate: %w", err)
+			}
+			rsaKey, isRsaKey := key.(*rsa.PrivateKey)
+			if !isRsaKey {
+				return nil, nil, fmt.Errorf("decoding the PEM client certificate: the private key must be a RSA private key")
+			}
+			privateKey = rsaKey
+		case "ENCRYPTED PRIVATE KEY":
+			return nil, nil, fmt.Errorf("decoding the PEM client certificate: encrypted private keys are not supported, please use the PKCS#12 format instead")
+		}
+	}
+
+	if certificate == nil {
+		return nil, nil, fmt.Errorf("decoding the PEM client certificate: no certificate found")
+	}
+	if privateKey == nil {
+		return nil, nil, fmt.Errorf("decoding the PEM client certificate: no private key found")
+	}
+	if publicKey, ok := certificate.PublicKey.(*rsa.PublicKey); !ok || !publicKey.Equal(&privateKey.PublicKey) {
+		return nil, nil, fmt.Errorf("decoding the PEM client certificate: the private key does not match the certificate")
+	}
+	return certificate, privateKey, nil
+}
+
+// certificateFileSecret implements adal.ServicePrincipalSecret for the client certificates stored in files.
+// Unlike adal.ServicePrincipalCertificateSecret, the file is checked whenever the access token is refreshed,
+// so that the rotated certificates are picked up.
+type certificateFileSecret struct {
+	certPath string
+	password string
+
+	lock     sync.Mutex
+	checksum [sha256.Size]byte
+	secret   *adal.ServicePrincipalCertificateSecret
+}
+
+// newCertificateFileSecret creates a certificateFileSecret, the certificate is loaded to fail fast if it is invalid.
+func newCertificateFileSecret(certPath, password string) (*certificateFileSecret, error) {
+	secret := &certificateFileSecret{certPath: certPath, password: password}
+	if _, err := secret.load(); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// load returns the certificate secret, which is decoded again if the content of the file is changed.
+func (secret *certificateFileSecret) load() (*adal.ServicePrincipalCertificateSecret, error) {
+	secret.lock.Lock()
+	defer secret.lock.Unlock()
+
+	data, err := ioutil.ReadFile(secret.certPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading the client certificate from file %s: %w", secret.certPath, err)
+	}
+
+	checksum := sha256.Sum256(data)
+	if secret.secret != nil && checksum == secret.checksum {
+		return secret.secret, nil
+	}
+
+	certificate, privateKey, err := decodeClientCertificate(data, secret.password)
+	if err != nil {
+		return nil, fmt.Errorf("decoding the client certificate from file %s: %w", secret.certPath, err)
+	}
+	if secret.secret != nil {
+		klog.V(2).Infof("azure: the client certificate in file %s is rotated, the new certificate expires at %s", secret.certPath, certificate.NotAfter.UTC().Format(time.RFC3339))
+	}
+	secret.checksum = checksum
+	secret.secret = &adal.ServicePrincipalCertificateSecret{Certificate: certificate, PrivateKey: privateKey}
+	return secret.secret, nil
+}
+
+// SetAuthenticationValues populates the form submitted to acquire the access token with a JWT signed by the certificate.
+func (secret *certificateFileSecret) SetAuthenticationValues(spt *adal.ServicePrincipalToken, v *url.Values) error {
+	certificateSecret, err := secret.load()
+	if err != nil {
+		return err
+	}
+	return certificateSecret.SetAuthenticationValues(spt, v)
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (secret *certificateFileSecret) MarshalJSON() ([]byte, error) {
+	return nil, fmt.Errorf("marshalling certificateFileSecret is not supported")
+}
+
+var (
+	// keyVaultSender sends the requests of fetching the client certificate from Key Vault, including
+	// the requests of acquiring the access token of Key Vault from the managed identity endpoint.
+	keyVaultSender adal.Sender = &http.Client{Timeout: keyVaultRequestTimeout}
+)
+
+// keyVaultSecret is the secret returned by the Key Vault secrets API. The certificates stored in Key Vault
+// are exposed as secrets, whose values are the base64 encoded PKCS#12 data or the PEM data.
+type keyVaultSecret struct {
+	Value       string `json:"value"`
+	ContentType string `json:"contentType"`
+}
+
+// getCertificateFromKeyVault fetches the client certificate from the Key Vault secret URI, e.g.
+// https://<vault>.vault.azure.net/secrets/<name>[/<version>], with the managed identity.
+func getCertificateFromKeyVault(config *AzureAuthConfig, env *azure.Environment) (*x509.Certificate, *rsa.PrivateKey, error) {
+	keyVaultResource := env.ResourceIdentifiers.KeyVault
+	if keyVaultResource == "" {
+		return nil, nil, fmt.Errorf("the Key Vault resource identifier of cloud %s is unknown", env.Name)
+	}
+
+	spt, err := getMSIServicePrincipalToken(config, keyVaultResource)
+	if err != nil {
+		return nil, nil, err
+	}
+	spt.SetSender(keyVaultSender)
+	if err := spt.Refresh(); err != nil {
+		return nil, nil, fmt.Errorf("acquiring the access token of Key Vault with the managed identity: %w", err)
+	}
+
+	request, err := http.NewRequest(http.MethodGet, config.AADClientCertKeyVaultURI, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating the request to Key Vault: %w", err)
+	}
+	query := request.URL.Query()
+	query.Set("api-version", keyVaultAPIVersion)
+	request.URL.RawQuery = query.Encode()
+	request.Header.Set("Authorization", "Bearer "+spt.OAuthToken())
+
+	response, err := keyVaultSender.Do(request)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetching the client certificate from Key Vault %s: %w", config.AADClientCertKeyVaultURI, err)
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading the client certificate from Key Vault %s: %w", config.AADClientCertKeyVaultURI, err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("fetching the client certificate from Key Vault %s: status code %d, response: %s", config.AADClientCertKeyVaultURI, response.StatusCode, string(body))
+	}
+
+	var secret keyVaultSecret
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return nil, nil, fmt.Errorf("unmarshalling the client certificate from Key Vault %s: %w", config.AADClientCertKeyVaultURI, err)
+	}
+
+	data := []byte(secret.Value)
+	if !strings.EqualFold(secret.ContentType, pemContentType) {
+		if data, err = base64.StdEncoding.DecodeString(secret.Value); err != nil {
+			return nil, nil, fmt.Errorf("decoding the PKCS#12 client certificate from Key Vault %s: %w", config.AADClientCertKeyVaultURI, err)
+		}
+	}
+
+	certificate, privateKey, err := decodeClientCertificate(data, config.AADClientCertPassword)
+	if err != nil {
+		return nil, nil, fmt.Errorf("decoding the client certificate from Key Vault %s: %w", config.AADClientCertKeyVaultURI, err)
+	}
+	return certificate, privateKey, nil
+}
@@ -0,0 +1,335 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/stretchr/testify/assert"
+)
+
+// roundTripperFunc is a http.RoundTripper implemented by a function.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// generateCertificate generates a self-signed certificate and its RSA private key.
+func generateCertificate(t *testing.T, notBefore, notAfter time.Time) (*x509.Certificate, *rsa.PrivateKey) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	assert.NoError(t, err)
+	certificate, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	return certificate, privateKey
+}
+
+// encodePEM encodes the certificate and the private key in PKCS#1 or PKCS#8 format to PEM.
+func encodePEM(t *testing.T, certificate *x509.Certificate, privateKey *rsa.PrivateKey, pkcs8 bool) []byte {
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Raw})
+	if privateKey == nil {
+		return data
+	}
+	if pkcs8 {
+		der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+		assert.NoError(t, err)
+		return append(data, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})...)
+	}
+	return append(data, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})...)
+}
+
+// getAssertionCertificate returns the certificate in the x5c header of the JWT client assertion.
+func getAssertionCertificate(t *testing.T, assertion string) []byte {
+	header, err := base64.RawURLEncoding.DecodeString(strings.Split(assertion, ".")[0])
+	assert.NoError(t, err)
+
+	var jwtHeader struct {
+		X5C []string `json:"x5c"`
+	}
+	assert.NoError(t, json.Unmarshal(header, &jwtHeader))
+	assert.Len(t, jwtHeader.X5C, 1)
+	certificate, err := base64.StdEncoding.DecodeString(jwtHeader.X5C[0])
+	assert.NoError(t, err)
+	return certificate
+}
+
+func TestDecodeClientCertificate(t *testing.T) {
+	now := time.Now()
+	certificate, privateKey := generateCertificate(t, now.Add(-time.Hour), now.Add(time.Hour))
+	_, otherPrivateKey := generateCertificate(t, now.Add(-time.Hour), now.Add(time.Hour))
+	expiredCertificate, expiredPrivateKey := generateCertificate(t, now.Add(-2*time.Hour), now.Add(-time.Hour))
+	futureCertificate, futurePrivateKey := generateCertificate(t, now.Add(time.Hour), now.Add(2*time.Hour))
+	pfx, err := ioutil.ReadFile("./testdata/test.pfx")
+	assert.NoError(t, err)
+	expiredPFX, err := ioutil.ReadFile("./testdata/expired.pfx")
+	assert.NoError(t, err)
+
+	testcases := []struct {
+		description  string
+		data         []byte
+		password     string
+		expectedCert *x509.Certificate
+		expectedErr  string
+	}{
+		{
+			description:  "PEM certificate with PKCS#1 private key should be decoded",
+			data:         encodePEM(t, certificate, privateKey, false),
+			expectedCert: certificate,
+		},
+		{
+			description:  "PEM certificate with PKCS#8 private key should be decoded",
+			data:         encodePEM(t, certificate, privateKey, true),
+			expectedCert: certificate,
+		},
+		{
+			description: "PKCS#12 certificate should be decoded",
+			data:        pfx,
+			password:    "id",
+		},
+		{
+			description: "PKCS#12 certificate with incorrect password should report the format",
+			data:        pfx,
+			password:    "incorrect",
+			expectedErr: "decoding the PKCS#12 client certificate: pkcs12: decryption password incorrect",
+		},
+		{
+			description: "PEM certificate without private key should report the format",
+			data:        encodePEM(t, certificate, nil, false),
+			expectedErr: "decoding the PEM client certificate: no private key found",
+		},
+		{
+			description: "PEM certificate with mismatched private key should report the format",
+			data:        encodePEM(t, certificate, otherPrivateKey, true),
+			expectedErr: "decoding the PEM client certificate: the private key does not match the certificate",
+		},
+		{
+			description: "PEM certificate with encrypted private key should report the format",
+			data: append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Raw}),
+				pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: []byte("encrypted")})...),
+			expectedErr: "decoding the PEM client certificate: encrypted private keys are not supported, please use the PKCS#12 format instead",
+		},
+		{
+			description: "expired PEM certificate should be rejected",
+			data:        encodePEM(t, expiredCertificate, expiredPrivateKey, false),
+			expectedErr: fmt.Sprintf(`the client certificate "test" expired at %s`, expiredCertificate.NotAfter.UTC().Format(time.RFC3339)),
+		},
+		{
+			description: "expired PKCS#12 certificate should be rejected",
+			data:        expiredPFX,
+			password:    "id",
+			expectedErr: `the client certificate "expired" expired at 2021-01-01T00:00:00Z`,
+		},
+		{
+			description: "PEM certificate which is not valid yet should be rejected",
+			data:        encodePEM(t, futureCertificate, futurePrivateKey, false),
+			expectedErr: fmt.Sprintf(`the client certificate "test" is not valid until %s`, futureCertificate.NotBefore.UTC().Format(time.RFC3339)),
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.description, func(t *testing.T) {
+			cert, key, err := decodeClientCertificate(tc.data, tc.password)
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.NotNil(t, key)
+			if tc.expectedCert != nil {
+				assert.Equal(t, tc.expectedCert.Raw, cert.Raw)
+				assert.True(t, privateKey.Equal(key))
+			}
+		})
+	}
+}
+
+func TestGetServicePrincipalTokenFromRotatedCertificate(t *testing.T) {
+	sts := &fakeSTS{}
+	server := httptest.NewServer(sts)
+	defer server.Close()
+
+	now := time.Now()
+	certificate1, privateKey1 := generateCertificate(t, now.Add(-time.Hour), now.Add(time.Hour))
+	certificate2, privateKey2 := generateCertificate(t, now.Add(-time.Hour), now.Add(time.Hour))
+	expiredCertificate, expiredPrivateKey := generateCertificate(t, now.Add(-2*time.Hour), now.Add(-time.Hour))
+
+	certPath := filepath.Join(t.TempDir(), "cert.pem")
+	assert.NoError(t, ioutil.WriteFile(certPath, encodePEM(t, certificate1, privateKey1, false), 0600))
+
+	config := &AzureAuthConfig{
+		TenantID:          "TenantID",
+		AADClientID:       "AADClientID",
+		AADClientCertPath: certPath,
+	}
+	env := &azure.Environment{
+		ActiveDirectoryEndpoint:   server.URL + "/",
+		ServiceManagementEndpoint: "https://management.core.windows.net/",
+	}
+	token, err := GetServicePrincipalToken(config, env, "")
+	assert.NoError(t, err)
+
+	assert.NoError(t, token.Refresh())
+	assert.Equal(t, certificate1.Raw, getAssertionCertificate(t, sts.assertions[0]))
+
+	// the rotated certificate should be used in the next refresh without recreating the token
+	assert.NoError(t, ioutil.WriteFile(certPath, encodePEM(t, certificate2, privateKey2, true), 0600))
+	assert.NoError(t, token.Refresh())
+	assert.Equal(t, certificate2.Raw, getAssertionCertificate(t, sts.assertions[1]))
+
+	// the refresh should fail if the rotated certificate is expired
+	assert.NoError(t, ioutil.WriteFile(certPath, encodePEM(t, expiredCertificate, expiredPrivateKey, false), 0600))
+	err = token.Refresh()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "expired at")
+	assert.Len(t, sts.assertions, 2, "the token request should not be sent with the expired certificate")
+
+	// the token should not be created with the expired certificate
+	_, err = GetServicePrincipalToken(config, env, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), fmt.Sprintf("decoding the client certificate from file %s: the client certificate", certPath))
+}
+
+func TestGetServicePrincipalTokenFromKeyVault(t *testing.T) {
+	now := time.Now()
+	certificate, privateKey := generateCertificate(t, now.Add(-time.Hour), now.Add(time.Hour))
+	pfx, err := ioutil.ReadFile("./testdata/test.pfx")
+	assert.NoError(t, err)
+	pfxCertificate, _, err := decodePkcs12(pfx, "id")
+	assert.NoError(t, err)
+
+	// the managed identity endpoint is faked by serving the requests to it without sending them
+	originalSender := keyVaultSender
+	keyVaultSender = &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		msiEndpoint, err := adal.GetMSIVMEndpoint()
+		assert.NoError(t, err)
+		if !strings.HasPrefix(r.URL.String(), msiEndpoint) {
+			return http.DefaultTransport.RoundTrip(r)
+		}
+
+		assert.Equal(t, "https://vault.azure.net", r.URL.Query().Get("resource"))
+		recorder := httptest.NewRecorder()
+		expiresOn := time.Now().Add(time.Hour).Unix()
+		_, _ = recorder.WriteString(fmt.Sprintf(`{"access_token":"keyvault-token","expires_on":"%d","resource":"https://vault.azure.net","token_type":"Bearer"}`, expiresOn))
+		return recorder.Result(), nil
+	})}
+	defer func() {
+		keyVaultSender = originalSender
+	}()
+
+	sts := &fakeSTS{}
+	stsServer := httptest.NewServer(sts)
+	defer stsServer.Close()
+
+	testcases := []struct {
+		description  string
+		statusCode   int
+		secret       keyVaultSecret
+		password     string
+		expectedCert *x509.Certificate
+		expectedErr  string
+	}{
+		{
+			description:  "PKCS#12 certificate should be fetched from Key Vault",
+			statusCode:   http.StatusOK,
+			secret:       keyVaultSecret{Value: base64.StdEncoding.EncodeToString(pfx), ContentType: "application/x-pkcs12"},
+			password:     "id",
+			expectedCert: pfxCertificate,
+		},
+		{
+			description:  "PEM certificate should be fetched from Key Vault",
+			statusCode:   http.StatusOK,
+			secret:       keyVaultSecret{Value: string(encodePEM(t, certificate, privateKey, true)), ContentType: "application/x-pem-file"},
+			expectedCert: certificate,
+		},
+		{
+			description: "invalid PKCS#12 certificate should report the format",
+			statusCode:  http.StatusOK,
+			secret:      keyVaultSecret{Value: "invalid", ContentType: "application/x-pkcs12"},
+			expectedErr: "decoding the PKCS#12 client certificate from Key Vault",
+		},
+		{
+			description: "failure of Key Vault should be returned",
+			statusCode:  http.StatusForbidden,
+			expectedErr: "status code 403",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.description, func(t *testing.T) {
+			keyVaultServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/secrets/cert", r.URL.Path)
+				assert.Equal(t, keyVaultAPIVersion, r.URL.Query().Get("api-version"))
+				assert.Equal(t, "Bearer keyvault-token", r.Header.Get("Authorization"))
+				w.WriteHeader(tc.statusCode)
+				body, _ := json.Marshal(tc.secret)
+				_, _ = w.Write(body)
+			}))
+			defer keyVaultServer.Close()
+
+			config := &AzureAuthConfig{
+				TenantID:                 "TenantID",
+				AADClientID:              "AADClientID",
+				AADClientCertKeyVaultURI: keyVaultServer.URL + "/secrets/cert",
+				AADClientCertPassword:    tc.password,
+			}
+			env := &azure.Environment{
+				ActiveDirectoryEndpoint:   stsServer.URL + "/",
+				ServiceManagementEndpoint: "https://management.core.windows.net/",
+				ResourceIdentifiers:       azure.ResourceIdentifier{KeyVault: "https://vault.azure.net"},
+			}
+			token, err := GetServicePrincipalToken(config, env, "")
+			if tc.expectedErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectedErr)
+				return
+			}
+
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.NoError(t, token.Refresh())
+			assert.Equal(t, tc.expectedCert.Raw, getAssertionCertificate(t, sts.assertions[len(sts.assertions)-1]))
+		})
+	}
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/azure"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+)
+
+var (
+	// imdsServer is the instance metadata service which the cloud environment is discovered from.
+	imdsServer = consts.ImdsServer
+	// imdsClient is the client of the instance metadata service.
+	imdsClient = &http.Client{Timeout: 10 * time.Second}
+)
+
+// imdsEndpoints is the response of the instance metadata service endpoints API,
+// which lists the resource manager endpoints of the clouds and their locations.
+type imdsEndpoints struct {
+	CloudEndpoint map[string]imdsCloudEndpoint `json:"cloudEndpoint"`
+}
+
+type imdsCloudEndpoint struct {
+	Endpoint  string   `json:"endpoint"`
+	Locations []string `json:"locations"`
+}
+
+// GetAzureEnvironment returns the azure environment of the auth config. The environment is
+// 1. loaded from CustomCloudEnvironmentFilePath if it is set, or
+// 2. computed by querying ResourceManagerEndpoint if it is set, or
+// 3. computed by querying the resource manager endpoint discovered from the instance metadata service
+// if DiscoverCloudEnvironmentFromIMDS is true, or
+// 4. looked up by the name of Cloud from the pre-defined environments.
+func GetAzureEnvironment(config *AzureAuthConfig) (*azure.Environment, error) {
+	if config.CustomCloudEnvironmentFilePath != "" {
+		return environmentFromFile(config.CustomCloudEnvironmentFilePath, config.Cloud)
+	}
+	if config.ResourceManagerEndpoint == "" && config.DiscoverCloudEnvironmentFromIMDS {
+		resourceManagerEndpoint, err := discoverResourceManagerEndpoint()
+		if err != nil {
+			return nil, fmt.Errorf("failed to discover the cloud environment from the instance metadata service: %w", err)
+		}
+		return ParseAzureEnvironment(config.Cloud, resourceManagerEndpoint, config.IdentitySystem)
+	}
+	return ParseAzureEnvironment(config.Cloud, config.ResourceManagerEndpoint, config.IdentitySystem)
+}
+
+// environmentFromFile loads the environment from the JSON file, whose fields are the same as azure.Environment,
+// e.g. resourceManagerEndpoint, activeDirectoryEndpoint and storageEndpointSuffix.
+func environmentFromFile(path, cloudName string) (*azure.Environment, error) {
+	klog.V(4).Infof("Loading environment from file: %s", path)
+	env, err := azure.EnvironmentFromFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the cloud environment from file %s: %w", path, err)
+	}
+	if env.ResourceManagerEndpoint == "" || env.ActiveDirectoryEndpoint == "" {
+		return nil, fmt.Errorf("resourceManagerEndpoint and activeDirectoryEndpoint must be set in the cloud environment file %s", path)
+	}
+
+	if env.Name == "" {
+		env.Name = cloudName
+	}
+	// The token audience and the service management endpoint are the same resource, which the tokens are acquired for.
+	if env.ServiceManagementEndpoint == "" {
+		env.ServiceManagementEndpoint = env.TokenAudience
+	}
+	if env.TokenAudience == "" {
+		env.TokenAudience = env.ServiceManagementEndpoint
+	}
+	if env.ServiceManagementEndpoint == "" {
+		return nil, fmt.Errorf("either serviceManagementEndpoint or tokenAudience must be set in the cloud environment file %s", path)
+	}
+	return &env, nil
+}
+
+// discoverResourceManagerEndpoint returns the resource manager endpoint of the cloud
+// which the location of the VM belongs to from the instance metadata service.
+func discoverResourceManagerEndpoint() (string, error) {
+	location, err := getIMDS(consts.ImdsInstanceURI+"/compute/location", consts.ImdsInstanceAPIVersion, "text")
+	if err != nil {
+		return "", err
+	}
+	body, err := getIMDS(consts.ImdsEndpointsURI, consts.ImdsEndpointsAPIVersion, "json")
+	if err != nil {
+		return "", err
+	}
+
+	var endpoints imdsEndpoints
+	if err := json.Unmarshal([]byte(body), &endpoints); err != nil {
+		return "", fmt.Errorf("failed to unmarshal the endpoints: %w", err)
+	}
+	for name, cloudEndpoint := range endpoints.CloudEndpoint {
+		for _, l := range cloudEndpoint.Locations {
+			if !strings.EqualFold(l, location) {
+				continue
+			}
+			endpoint := cloudEndpoint.Endpoint
+			if !strings.Contains(endpoint, "://") {
+				endpoint = "https://" + endpoint
+			}
+			if !strings.HasSuffix(endpoint, "/") {
+				endpoint += "/"
+			}
+			klog.V(2).Infof("Discovered resource manager endpoint %s of cloud %s for location %s", endpoint, name, location)
+			return endpoint, nil
+		}
+	}
+	return "", fmt.Errorf("no cloud endpoint is found for location %q", location)
+}
+
+// getIMDS returns the response body of the instance metadata service API.
+func getIMDS(uri, apiVersion, format string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, imdsServer+uri, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Add("Metadata", "True")
+	req.Header.Add("User-Agent", "golang/kubernetes-cloud-provider")
+	q := req.URL.Query()
+	q.Add("format", format)
+	q.Add("api-version", apiVersion)
+	req.URL.RawQuery = q.Encode()
+
+	resp, err := imdsClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failure of getting %s from instance metadata service with response %q", uri, resp.Status)
+	}
+	return strings.TrimSpace(string(body)), nil
+}
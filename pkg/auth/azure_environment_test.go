@@ -0,0 +1,211 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/stretchr/testify/assert"
+)
+
+const testEnvironmentFile = `{
+	"name": "AzureStackCloud",
+	"resourceManagerEndpoint": "https://management.local.azurestack.external/",
+	"activeDirectoryEndpoint": "https://login.microsoftonline.com/",
+	"storageEndpointSuffix": "local.azurestack.external",
+	"tokenAudience": "https://management.azurestackci.onmicrosoft.com/"
+}`
+
+// newFakeARM returns a fake resource manager serving the metadata endpoints API.
+func newFakeARM(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/metadata/endpoints", r.URL.Path)
+		_, _ = w.Write([]byte(`{"galleryEndpoint":"https://gallery.local/","graphEndpoint":"https://graph.local/","authentication":{"loginEndpoint":"https://login.local/","audiences":["https://management.local/"]}}`))
+	}))
+}
+
+// newFakeIMDS returns a fake instance metadata service serving the location of the VM and the cloud endpoints.
+func newFakeIMDS(t *testing.T, endpoints string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "True", r.Header.Get("Metadata"))
+		switch r.URL.Path {
+		case "/metadata/instance/compute/location":
+			_, _ = w.Write([]byte("local\n"))
+		case "/metadata/endpoints":
+			_, _ = w.Write([]byte(endpoints))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestGetAzureEnvironmentFromFile(t *testing.T) {
+	for _, tc := range []struct {
+		description string
+		content     string
+		cloud       string
+		expectedErr string
+		check       func(t *testing.T, env *azure.Environment)
+	}{
+		{
+			description: "environment should be loaded from the file",
+			content:     testEnvironmentFile,
+			check: func(t *testing.T, env *azure.Environment) {
+				assert.Equal(t, "AzureStackCloud", env.Name)
+				assert.Equal(t, "https://management.local.azurestack.external/", env.ResourceManagerEndpoint)
+				assert.Equal(t, "https://login.microsoftonline.com/", env.ActiveDirectoryEndpoint)
+				assert.Equal(t, "local.azurestack.external", env.StorageEndpointSuffix)
+				assert.Equal(t, "https://management.azurestackci.onmicrosoft.com/", env.ServiceManagementEndpoint)
+			},
+		},
+		{
+			description: "name and token audience should be defaulted",
+			content:     `{"resourceManagerEndpoint":"https://management.local/","activeDirectoryEndpoint":"https://login.local/","serviceManagementEndpoint":"https://management.local/"}`,
+			cloud:       "AzureStackCloud",
+			check: func(t *testing.T, env *azure.Environment) {
+				assert.Equal(t, "AzureStackCloud", env.Name)
+				assert.Equal(t, "https://management.local/", env.TokenAudience)
+			},
+		},
+		{
+			description: "missing endpoints should be reported",
+			content:     `{"resourceManagerEndpoint":"https://management.local/"}`,
+			expectedErr: "resourceManagerEndpoint and activeDirectoryEndpoint must be set in the cloud environment file",
+		},
+		{
+			description: "missing token audience should be reported",
+			content:     `{"resourceManagerEndpoint":"https://management.local/","activeDirectoryEndpoint":"https://login.local/"}`,
+			expectedErr: "either serviceManagementEndpoint or tokenAudience must be set in the cloud environment file",
+		},
+		{
+			description: "invalid file should be reported",
+			content:     `{`,
+			expectedErr: "failed to load the cloud environment from file",
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "environment.json")
+			assert.NoError(t, ioutil.WriteFile(path, []byte(tc.content), 0600))
+
+			env, err := GetAzureEnvironment(&AzureAuthConfig{
+				Cloud:                          tc.cloud,
+				CustomCloudEnvironmentFilePath: path,
+			})
+			if tc.expectedErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			tc.check(t, env)
+		})
+	}
+}
+
+func TestGetAzureEnvironmentFromIMDS(t *testing.T) {
+	arm := newFakeARM(t)
+	defer arm.Close()
+
+	for _, tc := range []struct {
+		description string
+		endpoints   string
+		expectedErr string
+	}{
+		{
+			description: "resource manager endpoint of the location should be discovered",
+			endpoints:   fmt.Sprintf(`{"cloudEndpoint":{"public":{"endpoint":"management.azure.com","locations":["westus"]},"azureStack":{"endpoint":"%s","locations":["local"]}}}`, arm.URL),
+		},
+		{
+			description: "location without cloud endpoint should be reported",
+			endpoints:   `{"cloudEndpoint":{"public":{"endpoint":"management.azure.com","locations":["westus"]}}}`,
+			expectedErr: `failed to discover the cloud environment from the instance metadata service: no cloud endpoint is found for location "local"`,
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			imds := newFakeIMDS(t, tc.endpoints)
+			defer imds.Close()
+			originalIMDSServer := imdsServer
+			imdsServer = imds.URL
+			defer func() {
+				imdsServer = originalIMDSServer
+			}()
+
+			env, err := GetAzureEnvironment(&AzureAuthConfig{
+				Cloud:                            "AzureStackCloud",
+				DiscoverCloudEnvironmentFromIMDS: true,
+			})
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "AzureStackCloud", env.Name)
+			assert.Equal(t, arm.URL+"/", env.ResourceManagerEndpoint)
+			assert.Equal(t, "https://login.local/", env.ActiveDirectoryEndpoint)
+			assert.Equal(t, "https://management.local/", env.TokenAudience)
+			assert.Equal(t, "https://management.local/", env.ServiceManagementEndpoint)
+		})
+	}
+}
+
+func TestGetAzureEnvironmentPrecedence(t *testing.T) {
+	arm := newFakeARM(t)
+	defer arm.Close()
+	// the instance metadata service should not be queried if the environment is specified explicitly
+	imds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to the instance metadata service: %s", r.URL)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer imds.Close()
+	originalIMDSServer := imdsServer
+	imdsServer = imds.URL
+	defer func() {
+		imdsServer = originalIMDSServer
+	}()
+
+	path := filepath.Join(t.TempDir(), "environment.json")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(testEnvironmentFile), 0600))
+
+	// the file takes precedence over the resource manager endpoint and the discovery
+	env, err := GetAzureEnvironment(&AzureAuthConfig{
+		CustomCloudEnvironmentFilePath:   path,
+		ResourceManagerEndpoint:          arm.URL + "/",
+		DiscoverCloudEnvironmentFromIMDS: true,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://management.local.azurestack.external/", env.ResourceManagerEndpoint)
+
+	// the resource manager endpoint takes precedence over the discovery
+	env, err = GetAzureEnvironment(&AzureAuthConfig{
+		ResourceManagerEndpoint:          arm.URL + "/",
+		DiscoverCloudEnvironmentFromIMDS: true,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, arm.URL+"/", env.ResourceManagerEndpoint)
+	assert.Equal(t, "https://login.local/", env.ActiveDirectoryEndpoint)
+
+	// the pre-defined environment is used without any of them
+	env, err = GetAzureEnvironment(&AzureAuthConfig{Cloud: "AZURECHINACLOUD"})
+	assert.NoError(t, err)
+	assert.Equal(t, "AzureChinaCloud", env.Name)
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"context"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/adal"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/metrics"
+)
+
+const (
+	// tokenRefreshRatio is the ratio of the token lifetime after which the token is refreshed.
+	tokenRefreshRatio = 0.8
+
+	tokenRefreshResultSucceeded = "succeeded"
+	tokenRefreshResultFailed    = "failed"
+)
+
+var (
+	// tokenRefreshRetryInterval is the interval of retrying the failed refreshes.
+	tokenRefreshRetryInterval = 30 * time.Second
+
+	// correlationIDRE matches the correlation ID in the error responses of AAD.
+	correlationIDRE = regexp.MustCompile(`"correlation_id"\s*:\s*"([^"]+)"`)
+)
+
+// TokenRefresher refreshes an access token in the background after 80% of its lifetime elapses, so that the
+// requests never wait for the expired token to be refreshed. It works for all the token flows, e.g. the client
+// secret, the client certificate, the managed identity and the workload identity, since they are refreshed in the
+// same way. The refreshes and the expiry of the token are recorded in the metrics labeled by the name of the token.
+type TokenRefresher struct {
+	name string
+	spt  *adal.ServicePrincipalToken
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// StartTokenRefresher starts refreshing the token in the background until Stop is called.
+// The token is acquired immediately if it has not been acquired yet.
+func StartTokenRefresher(name string, spt *adal.ServicePrincipalToken) *TokenRefresher {
+	refresher := &TokenRefresher{
+		name:   name,
+		spt:    spt,
+		stopCh: make(chan struct{}),
+	}
+	// The callbacks are called after the token is refreshed either by the refresher or on demand.
+	spt.SetRefreshCallbacks([]adal.TokenRefreshCallback{func(token adal.Token) error {
+		metrics.SetTokenExpiry(name, token.Expires())
+		return nil
+	}})
+
+	go refresher.run()
+	return refresher
+}
+
+// Stop stops refreshing the token.
+func (refresher *TokenRefresher) Stop() {
+	refresher.stopOnce.Do(func() {
+		close(refresher.stopCh)
+	})
+}
+
+func (refresher *TokenRefresher) run() {
+	timer := time.NewTimer(nextTokenRefresh(refresher.spt.Token(), time.Now()))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-refresher.stopCh:
+			return
+		case <-timer.C:
+		}
+
+		interval := tokenRefreshRetryInterval
+		if err := refresher.spt.Refresh(); err != nil {
+			metrics.CountTokenRefresh(refresher.name, tokenRefreshResultFailed)
+			klog.Warningf("TokenRefresher: failed to refresh the %s token (AAD correlation ID %q), retrying in %s: %v",
+				refresher.name, getCorrelationID(err), interval, err)
+		} else {
+			metrics.CountTokenRefresh(refresher.name, tokenRefreshResultSucceeded)
+			interval = nextTokenRefresh(refresher.spt.Token(), time.Now())
+			klog.V(4).Infof("TokenRefresher: refreshed the %s token, the next refresh is in %s", refresher.name, interval)
+		}
+		timer.Reset(interval)
+	}
+}
+
+// nextTokenRefresh returns the duration after which the token should be refreshed, which is when 80% of its
+// lifetime elapses. The token is refreshed immediately if it has not been acquired yet.
+func nextTokenRefresh(token adal.Token, now time.Time) time.Duration {
+	if token.IsZero() {
+		return 0
+	}
+
+	expiresOn := token.Expires()
+	lifetime := expiresOn.Sub(now)
+	if expiresIn, err := token.ExpiresIn.Int64(); err == nil && expiresIn > 0 {
+		lifetime = time.Duration(expiresIn) * time.Second
+	}
+	refreshOn := expiresOn.Add(-time.Duration(float64(lifetime) * (1 - tokenRefreshRatio)))
+	if refreshOn.Before(now) {
+		return 0
+	}
+	return refreshOn.Sub(now)
+}
+
+// getCorrelationID returns the correlation ID in the error response of AAD, which is used for troubleshooting
+// with AAD. An empty string is returned if the refresh fails before AAD responds.
+func getCorrelationID(err error) string {
+	matches := correlationIDRE.FindStringSubmatch(err.Error())
+	if len(matches) != 2 {
+		return ""
+	}
+	return matches[1]
+}
+
+// EnsureFresh acquires the token if it has not been acquired yet or it is about to expire.
+func (refresher *TokenRefresher) EnsureFresh(ctx context.Context) error {
+	return refresher.spt.EnsureFreshWithContext(ctx)
+}
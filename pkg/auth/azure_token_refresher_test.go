@@ -0,0 +1,182 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeTokenEndpoint issues the tokens expiring after lifetime for both AAD and the managed identity endpoint,
+// the first failures requests are rejected with a correlation ID.
+type fakeTokenEndpoint struct {
+	lock     sync.Mutex
+	lifetime int64
+	failures int
+	requests int
+}
+
+func (f *fakeTokenEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.requests++
+	if f.requests <= f.failures {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"invalid_client","correlation_id":"00000000-0000-0000-0000-000000000001"}`))
+		return
+	}
+	expiresOn := time.Now().Unix() + f.lifetime
+	_, _ = w.Write([]byte(fmt.Sprintf(`{"access_token":"token-%d","expires_in":"%d","expires_on":"%d","resource":"resource","token_type":"Bearer"}`,
+		f.requests, f.lifetime, expiresOn)))
+}
+
+func (f *fakeTokenEndpoint) getRequests() int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.requests
+}
+
+func TestTokenRefresher(t *testing.T) {
+	originalRetryInterval := tokenRefreshRetryInterval
+	tokenRefreshRetryInterval = 10 * time.Millisecond
+	defer func() {
+		tokenRefreshRetryInterval = originalRetryInterval
+	}()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	assert.NoError(t, ioutil.WriteFile(tokenFile, []byte("federated-token"), 0600))
+
+	testcases := []struct {
+		description string
+		failures    int
+		getToken    func(env *azure.Environment, endpoint string) (*adal.ServicePrincipalToken, error)
+	}{
+		{
+			description: "token of the client secret should be refreshed proactively",
+			getToken: func(env *azure.Environment, endpoint string) (*adal.ServicePrincipalToken, error) {
+				return GetServicePrincipalToken(&AzureAuthConfig{TenantID: "TenantID", AADClientID: "AADClientID", AADClientSecret: "secret"}, env, "resource")
+			},
+		},
+		{
+			description: "token of the workload identity should be refreshed proactively",
+			getToken: func(env *azure.Environment, endpoint string) (*adal.ServicePrincipalToken, error) {
+				return GetServicePrincipalToken(&AzureAuthConfig{
+					TenantID:                              "TenantID",
+					AADClientID:                           "AADClientID",
+					UseFederatedWorkloadIdentityExtension: true,
+					AADFederatedTokenFile:                 tokenFile,
+				}, env, "resource")
+			},
+		},
+		{
+			description: "token of the managed identity should be refreshed proactively",
+			getToken: func(env *azure.Environment, endpoint string) (*adal.ServicePrincipalToken, error) {
+				return adal.NewServicePrincipalTokenFromMSI(endpoint, "resource")
+			},
+		},
+		{
+			description: "failed refreshes should be retried",
+			failures:    2,
+			getToken: func(env *azure.Environment, endpoint string) (*adal.ServicePrincipalToken, error) {
+				return GetServicePrincipalToken(&AzureAuthConfig{TenantID: "TenantID", AADClientID: "AADClientID", AADClientSecret: "secret"}, env, "resource")
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.description, func(t *testing.T) {
+			endpoint := &fakeTokenEndpoint{lifetime: 2, failures: tc.failures}
+			server := httptest.NewServer(endpoint)
+			defer server.Close()
+
+			env := &azure.Environment{ActiveDirectoryEndpoint: server.URL}
+			spt, err := tc.getToken(env, server.URL)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			refresher := StartTokenRefresher("test", spt)
+			defer refresher.Stop()
+
+			// the token is acquired immediately, and refreshed before it expires
+			assert.Eventually(t, func() bool {
+				return spt.OAuthToken() == fmt.Sprintf("token-%d", tc.failures+1)
+			}, 5*time.Second, 10*time.Millisecond)
+			assert.Eventually(t, func() bool {
+				return spt.OAuthToken() == fmt.Sprintf("token-%d", tc.failures+2)
+			}, 5*time.Second, 10*time.Millisecond)
+			assert.False(t, spt.Token().IsExpired(), "the token should be refreshed before it expires")
+
+			refresher.Stop()
+			requests := endpoint.getRequests()
+			time.Sleep(2 * time.Second)
+			assert.Equal(t, requests, endpoint.getRequests(), "the token should not be refreshed after the refresher is stopped")
+		})
+	}
+}
+
+func TestNextTokenRefresh(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	testcases := []struct {
+		description string
+		token       adal.Token
+		expected    time.Duration
+	}{
+		{
+			description: "token not acquired should be refreshed immediately",
+			expected:    0,
+		},
+		{
+			description: "token should be refreshed after 80% of its lifetime",
+			token:       adal.Token{AccessToken: "token", ExpiresIn: "3600", ExpiresOn: "1600003600"},
+			expected:    48 * time.Minute,
+		},
+		{
+			description: "token without expires_in should be refreshed after 80% of its remaining lifetime",
+			token:       adal.Token{AccessToken: "token", ExpiresOn: "1600001000"},
+			expected:    800 * time.Second,
+		},
+		{
+			description: "token which should have been refreshed should be refreshed immediately",
+			token:       adal.Token{AccessToken: "token", ExpiresIn: "3600", ExpiresOn: "1600000100"},
+			expected:    0,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.description, func(t *testing.T) {
+			assert.Equal(t, tc.expected, nextTokenRefresh(tc.token, now))
+		})
+	}
+}
+
+func TestGetCorrelationID(t *testing.T) {
+	err := fmt.Errorf(`adal: Refresh request failed. Status Code = '400'. Response body: {"error":"invalid_client","correlation_id":"b7e9c1d0-1234"} Endpoint https://login.microsoftonline.com/tenant/oauth2/token`)
+	assert.Equal(t, "b7e9c1d0-1234", getCorrelationID(err))
+	assert.Equal(t, "", getCorrelationID(fmt.Errorf("adal: Failed to execute the refresh request")))
+}
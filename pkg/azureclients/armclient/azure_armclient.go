@@ -17,11 +17,20 @@ limitations under the License.
 package armclient
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
 	"fmt"
 	"html"
+	"io/ioutil"
+	"net"
 	"net/http"
+	"net/http/cookiejar"
 	"net/url"
+	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -33,24 +42,141 @@ import (
 	"github.com/Azure/go-autorest/autorest/azure"
 
 	"k8s.io/klog/v2"
+	"sigs.k8s.io/cloud-provider-azure/pkg/log"
+	"sigs.k8s.io/cloud-provider-azure/pkg/metrics"
 	"sigs.k8s.io/cloud-provider-azure/pkg/retry"
 	"sigs.k8s.io/cloud-provider-azure/pkg/version"
 )
 
 var _ Interface = &Client{}
 
+const (
+	provisioningStateFailed = "Failed"
+
+	// resourcesAPIVersion is the API version of the generic resources list API, which supports the changedTime filter.
+	resourcesAPIVersion = "2021-04-01"
+	// providersAPIVersion is the API version of the resource providers API.
+	providersAPIVersion = "2021-04-01"
+
+	// ProviderRegistrationStateRegistered is the registration state of the resource providers registered in the subscription.
+	ProviderRegistrationStateRegistered = "Registered"
+	// ProviderRegistrationStateNotRegistered is the registration state of the resource providers not registered in the subscription.
+	ProviderRegistrationStateNotRegistered = "NotRegistered"
+
+	// DefaultRateLimitRemainingWarningThreshold is the default number of the remaining ARM requests below which warnings are logged.
+	DefaultRateLimitRemainingWarningThreshold = 100
+
+	// dumpRequestLogLevel is the verbosity at which the requests are dumped.
+	dumpRequestLogLevel klog.Level = 10
+)
+
+var (
+	// lowRateLimitRemainingWriteDelay is the delay of the write requests while the remaining ARM writes are low.
+	lowRateLimitRemainingWriteDelay = time.Second
+	// activeAsyncOpMaxAge is the age after which the active async operations are forgotten, e.g. the ones whose
+	// futures are never waited, so that the active async operations don't grow without bound.
+	activeAsyncOpMaxAge = 6 * time.Hour
+
+	// subscriptionIDRE matches the subscription ID in resource IDs and request paths.
+	subscriptionIDRE = regexp.MustCompile(`(?i)^/subscriptions/([^/]+)(?:/|$)`)
+	// changedTimeFilterScopeRE matches the subscriptions and resource groups, whose resources could be filtered by changedTime.
+	changedTimeFilterScopeRE = regexp.MustCompile(`(?i)^/subscriptions/[^/]+(?:/resourceGroups/[^/]+)?/?$`)
+)
+
+// ResourceMetadata is the metadata of a resource returned in the response headers, which could be used
+// to decide whether a cached resource is stale.
+type ResourceMetadata struct {
+	// ETag is the value of the ETag header.
+	ETag string
+	// LastModified is the value of the Last-Modified header, it is zero if the header is absent or invalid.
+	LastModified time.Time
+	// APIVersion is the API version used to get the resource.
+	APIVersion string
+}
+
+// AsyncOpInfo is the information of an async operation started by the client, which could be used to resume
+// polling the operation, e.g. by the next controller instance after a graceful shutdown.
+type AsyncOpInfo struct {
+	// ResourceID is the ID of the resource the operation is on.
+	ResourceID string
+	// Method is the HTTP method of the request that starts the operation.
+	Method string
+	// PollingMethod is the header the operation is polled by.
+	PollingMethod azure.PollingMethodType
+	// PollingURL is the URL the operation is polled by.
+	PollingURL string
+	// StartTime is the time when the operation is started.
+	StartTime time.Time
+}
+
 // Client implements ARM client Interface.
 type Client struct {
 	client           autorest.Client
 	baseURI          string
 	apiVersion       string
 	regionalEndpoint string
+	subscriptionID   string
+
+	// strictSubscriptionValidation rejects the requests to the resources in other subscriptions before sending them.
+	strictSubscriptionValidation bool
+
+	// redactedLogFields are the JSON field paths redacted from the logged request bodies.
+	redactedLogFields []string
+
+	// requestBodyValidators are the validators of the request bodies to put the resources, keyed by the lower case resource types.
+	requestBodyValidators     map[string]RequestBodyValidator
+	requestBodyValidatorsLock sync.RWMutex
+
+	// requestMetricsHook is called with the metrics of each request sent by the client.
+	requestMetricsHook     RequestMetricsHook
+	requestMetricsHookLock sync.RWMutex
+
+	// rateLimitBudget records the remaining ARM requests reported in the responses.
+	rateLimitBudget *azureclients.RateLimitBudget
+	// rateLimitRemainingWarningThreshold is the number of the remaining ARM requests below which warnings are logged.
+	rateLimitRemainingWarningThreshold int
+	// slowDownWritesOnLowRateLimitRemaining delays the writes while the remaining ARM writes are below the threshold.
+	slowDownWritesOnLowRateLimitRemaining bool
+
+	// tenantTokenProvider provides the tokens of the tenants of the requests sent by SendForTenant.
+	tenantTokenProvider azureclients.TenantTokenProvider
+
+	// backoff is the backoff of the retries, it is copied by each request so that it could be updated at runtime.
+	backoff     retry.Backoff
+	backoffLock sync.RWMutex
+
+	// rootCtx is the context from which the contexts of all the requests derive, it is cancelled by CancelAll.
+	rootCtx    context.Context
+	rootCancel context.CancelFunc
+	rootLock   sync.RWMutex
+
+	// activeAsyncOps are the async operations started by PutResourceAsync, PatchResourceAsync and
+	// DeleteResourceAsync that are not waited to complete yet, keyed by their futures.
+	activeAsyncOps     map[*azure.Future]AsyncOpInfo
+	activeAsyncOpsLock sync.Mutex
 }
 
 // New creates a ARM client
 func New(authorizer autorest.Authorizer, clientConfig azureclients.ClientConfig, baseURI, apiVersion string, sendDecoraters ...autorest.SendDecorator) *Client {
 	restClient := autorest.NewClientWithUserAgent(clientConfig.UserAgent)
 	restClient.Authorizer = authorizer
+	if clientConfig.MultiTenantAuthorizer != nil && clientConfig.NetworkResourceSubscriptionID != "" &&
+		!strings.EqualFold(clientConfig.NetworkResourceSubscriptionID, clientConfig.SubscriptionID) {
+		restClient.Authorizer = &crossTenantAuthorizer{
+			authorizer:                    authorizer,
+			multiTenantAuthorizer:         clientConfig.MultiTenantAuthorizer,
+			networkResourceSubscriptionID: clientConfig.NetworkResourceSubscriptionID,
+		}
+	}
+	if clientConfig.TenantTokenProvider != nil {
+		restClient.Authorizer = &tenantAuthorizer{authorizer: restClient.Authorizer}
+	}
+	if clientConfig.ForceHTTP1 || clientConfig.MinTLSVersion != 0 || clientConfig.RootCAs != nil {
+		restClient.Sender = newHTTPClient(clientConfig.ForceHTTP1, clientConfig.MinTLSVersion, clientConfig.RootCAs)
+	}
+	if clientConfig.ReplayResponsesDir != "" {
+		restClient.Sender = &http.Client{Transport: NewReplayTransport(clientConfig.ReplayResponsesDir)}
+	}
 
 	if clientConfig.UserAgent == "" {
 		restClient.UserAgent = GetUserAgent(restClient)
@@ -83,26 +209,93 @@ func New(authorizer autorest.Authorizer, clientConfig azureclients.ClientConfig,
 		backoff.Steps = 1
 	}
 
+	redactedLogFields := clientConfig.RedactedLogFields
+	if len(redactedLogFields) == 0 {
+		redactedLogFields = DefaultRedactedLogFields
+	}
+
+	rateLimitBudget := clientConfig.RateLimitBudget
+	if rateLimitBudget == nil {
+		rateLimitBudget = azureclients.NewRateLimitBudget()
+	}
+	rateLimitRemainingWarningThreshold := clientConfig.RateLimitRemainingWarningThreshold
+	if rateLimitRemainingWarningThreshold == 0 {
+		rateLimitRemainingWarningThreshold = DefaultRateLimitRemainingWarningThreshold
+	}
+
 	url, _ := url.Parse(baseURI)
 
 	client := &Client{
-		client:           restClient,
-		baseURI:          baseURI,
-		apiVersion:       apiVersion,
-		regionalEndpoint: fmt.Sprintf("%s.%s", clientConfig.Location, url.Host),
+		client:            restClient,
+		baseURI:           baseURI,
+		apiVersion:        apiVersion,
+		regionalEndpoint:  fmt.Sprintf("%s.%s", clientConfig.Location, url.Host),
+		subscriptionID:    clientConfig.SubscriptionID,
+		redactedLogFields: redactedLogFields,
+
+		strictSubscriptionValidation: clientConfig.StrictSubscriptionValidation,
+		backoff:                      *backoff,
+
+		rateLimitBudget:                       rateLimitBudget,
+		rateLimitRemainingWarningThreshold:    rateLimitRemainingWarningThreshold,
+		slowDownWritesOnLowRateLimitRemaining: clientConfig.SlowDownWritesOnLowRateLimitRemaining,
+
+		tenantTokenProvider: clientConfig.TenantTokenProvider,
+
+		activeAsyncOps: make(map[*azure.Future]AsyncOpInfo),
 	}
+	client.rootCtx, client.rootCancel = context.WithCancel(context.Background())
 	client.client.Sender = autorest.DecorateSender(client.client,
+		client.doRecordRateLimitRemaining(),
 		autorest.DoCloseIfError(),
-		retry.DoExponentialBackoffRetry(backoff),
+		client.doExponentialBackoffRetry(),
 		DoHackRegionalRetryDecorator(client),
-		DoDumpRequest(10),
+		client.doSlowDownWrites(),
+		DoDumpRequest(dumpRequestLogLevel),
 	)
 
 	client.client.Sender = autorest.DecorateSender(client.client.Sender, sendDecoraters...)
+	if clientConfig.RecordResponsesDir != "" {
+		client.client.Sender = autorest.DecorateSender(client.client.Sender, DoRecordResponses(clientConfig.RecordResponsesDir))
+	}
 
 	return client
 }
 
+// newHTTPClient creates a http client with the same transport settings as the autorest default
+// sender, which attempts HTTP/2. If forceHTTP1 is true, HTTP/2 is disabled on the transport.
+// The handshakes with TLS versions lower than minTLSVersion (TLS 1.2 if it is zero) are rejected,
+// and the server certificates are verified against rootCAs if it is not nil.
+func newHTTPClient(forceHTTP1 bool, minTLSVersion uint16, rootCAs *x509.CertPool) *http.Client {
+	if minTLSVersion == 0 {
+		minTLSVersion = tls.VersionTLS12
+	}
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     !forceHTTP1,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		TLSClientConfig: &tls.Config{
+			MinVersion:    minTLSVersion,
+			Renegotiation: tls.RenegotiateNever,
+			RootCAs:       rootCAs,
+		},
+	}
+	if forceHTTP1 {
+		// A non-nil and empty TLSNextProto disables HTTP/2 on the transport.
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	jar, _ := cookiejar.New(nil)
+	return &http.Client{Jar: jar, Transport: transport}
+}
+
 // GetUserAgent gets the autorest client with a user agent that
 // includes "kubernetes" and the full kubernetes git version string
 // example:
@@ -125,17 +318,165 @@ func NormalizeAzureRegion(name string) string {
 
 // Send sends a http request to ARM service with possible retry to regional ARM endpoint.
 func (c *Client) Send(ctx context.Context, request *http.Request, decorators ...autorest.SendDecorator) (*http.Response, *retry.Error) {
+	if rerr := retry.GetContextError(ctx); rerr != nil {
+		return nil, rerr
+	}
+
+	if c.strictSubscriptionValidation && subscriptionIDRE.MatchString(request.URL.Path) {
+		if err := c.ValidateResourceID(request.URL.Path); err != nil {
+			return nil, retry.NewError(false, err)
+		}
+	}
+
+	request, release, rerr := c.withRootContext(request)
+	if rerr != nil {
+		return nil, rerr
+	}
+
+	// Record the retries of the request, which are accumulated to the stats of the
+	// caller if there is one in the request context.
+	stats := retry.StatsFromContext(request.Context())
+	if stats == nil {
+		stats = &retry.Stats{}
+		request = request.WithContext(retry.WithStats(request.Context(), stats))
+	}
+
+	start := time.Now()
 	response, err := autorest.SendWithSender(
 		c.client,
 		request,
 		decorators...,
 	)
+	// the context of the request is checked before it is released, which cancels it
+	requestCtxErr := request.Context().Err()
+	response = release(response)
+
+	if err != nil {
+		if rerr := retry.GetContextError(ctx); rerr != nil {
+			klog.V(5).InfoS("Send: request is stopped by its context", log.KeysAndValues(ctx, log.AzureResourceIDKey, html.EscapeString(request.URL.Path), "error", ctx.Err())...)
+			return response, rerr.WithStats(stats)
+		}
+		if requestCtxErr != nil {
+			klog.V(5).InfoS("Send: request is cancelled", log.KeysAndValues(ctx, log.AzureResourceIDKey, html.EscapeString(request.URL.Path), "error", requestCtxErr)...)
+			return response, retry.NewError(false, requestCtxErr).WithStats(stats)
+		}
+	}
+
+	statusCode := 0
+	if response != nil {
+		statusCode = response.StatusCode
+	}
+	c.observeRequest(ctx, request, statusCode, time.Since(start))
+
+	if klog.V(5).Enabled() {
+		retries, _ := stats.Get()
+		klog.V(5).InfoS("Send: request is sent", log.KeysAndValues(ctx, log.AzureResourceIDKey, html.EscapeString(request.URL.Path),
+			"method", request.Method, "statusCode", statusCode, "retries", retries, log.DurationMsKey, log.DurationMs(start))...)
+	}
 
 	if response == nil && err == nil {
-		return response, retry.NewError(false, fmt.Errorf("Empty response and no HTTP code"))
+		return response, retry.NewError(false, fmt.Errorf("Empty response and no HTTP code")).WithStats(stats)
+	}
+
+	// Some requests, e.g. the expand queries, could return 204 No Content legitimately. Make sure the
+	// body could always be read so that callers could detect the empty result by IsNoContent.
+	if err == nil && IsNoContent(response) && response.Body == nil {
+		response.Body = http.NoBody
 	}
 
-	return response, retry.GetError(response, err)
+	return response, retry.GetError(response, err).WithStats(stats)
+}
+
+// withRootContext returns a copy of the request whose context is also cancelled by CancelAll. The returned
+// release function should be called with the response once the request is sent to stop watching the root
+// context, the context of the request is cancelled when the response body is closed, or immediately if there
+// is no body, so that the body could still be read after that. An error is returned if the client has been cancelled.
+func (c *Client) withRootContext(request *http.Request) (*http.Request, func(*http.Response) *http.Response, *retry.Error) {
+	c.rootLock.RLock()
+	rootCtx := c.rootCtx
+	c.rootLock.RUnlock()
+
+	if rerr := retry.GetContextError(rootCtx); rerr != nil {
+		return nil, nil, rerr
+	}
+
+	ctx, cancel := context.WithCancel(request.Context())
+	stopCh := make(chan struct{})
+	go func() {
+		select {
+		case <-rootCtx.Done():
+			cancel()
+		case <-ctx.Done():
+		case <-stopCh:
+		}
+	}()
+
+	release := func(response *http.Response) *http.Response {
+		close(stopCh)
+		return retry.CancelOnClose(response, cancel)
+	}
+	return request.WithContext(ctx), release, nil
+}
+
+// CancelAll cancels all the in-flight requests of the client, which return context errors immediately.
+// The subsequent requests fail fast with context errors as well until Reset is called.
+func (c *Client) CancelAll() {
+	c.rootLock.RLock()
+	defer c.rootLock.RUnlock()
+	c.rootCancel()
+}
+
+// Reset makes the client available again after CancelAll is called.
+func (c *Client) Reset() {
+	c.rootLock.Lock()
+	defer c.rootLock.Unlock()
+	if c.rootCtx.Err() != nil {
+		c.rootCtx, c.rootCancel = context.WithCancel(context.Background())
+	}
+}
+
+// ValidateResourceID returns an error if the resource ID is not in the subscription of the client.
+// Any resource ID is valid if the subscription of the client is not configured.
+func (c *Client) ValidateResourceID(resourceID string) error {
+	if c.subscriptionID == "" {
+		return nil
+	}
+
+	matches := subscriptionIDRE.FindStringSubmatch(resourceID)
+	if len(matches) != 2 {
+		return fmt.Errorf("resource ID %q is invalid, it should start with /subscriptions/<subscription ID>", resourceID)
+	}
+	if !strings.EqualFold(matches[1], c.subscriptionID) {
+		return fmt.Errorf("resource ID %q is in subscription %s, which is different from the subscription %s of the client", resourceID, matches[1], c.subscriptionID)
+	}
+
+	return nil
+}
+
+// SendForTenant sends a http request to ARM service in the tenant, e.g. a guest tenant, with possible retry
+// to regional ARM endpoint. The request carries the tenant in the x-ms-client-tenant-id header and is
+// authorized by the token scoped to the tenant, which is provided by the tenant token provider of the client.
+func (c *Client) SendForTenant(ctx context.Context, tenantID string, request *http.Request, decorators ...autorest.SendDecorator) (*http.Response, *retry.Error) {
+	if c.tenantTokenProvider == nil {
+		return nil, retry.NewError(false, fmt.Errorf("failed to send the request to tenant %s: no tenant token provider is configured", tenantID))
+	}
+	tokenProvider, err := c.tenantTokenProvider.GetTokenProvider(tenantID)
+	if err != nil {
+		return nil, retry.NewError(false, fmt.Errorf("failed to get the token provider of tenant %s: %w", tenantID, err))
+	}
+
+	request = request.WithContext(withTenantAuthorizer(request.Context(), autorest.NewBearerAuthorizer(tokenProvider)))
+	if request.Header == nil {
+		request.Header = make(http.Header)
+	}
+	request.Header.Set(clientTenantIDHeader, tenantID)
+	return c.Send(ctx, request, decorators...)
+}
+
+// SendNoRetry sends a http request to ARM service only once without any retries. The GET and PUT
+// requests could be sent without retries as well by passing a context from retry.WithNoRetry.
+func (c *Client) SendNoRetry(ctx context.Context, request *http.Request, decorators ...autorest.SendDecorator) (*http.Response, *retry.Error) {
+	return c.Send(ctx, request.WithContext(retry.WithNoRetry(request.Context())), decorators...)
 }
 
 // PreparePutRequest prepares put request
@@ -203,9 +544,20 @@ func (c *Client) PrepareHeadRequest(ctx context.Context, decorators ...autorest.
 
 // WaitForAsyncOperationCompletion waits for an operation completion
 func (c *Client) WaitForAsyncOperationCompletion(ctx context.Context, future *azure.Future, asyncOperationName string) error {
+	defer c.completeAsyncOperation(ctx, future)
+
+	if locationURL := getLocationPollingURL(future); locationURL != "" {
+		response, err := c.waitForLocationOperation(ctx, future, locationURL, asyncOperationName)
+		c.CloseResponse(ctx, response)
+		return err
+	}
+
 	err := future.WaitForCompletionRef(ctx, c.client)
 	if err != nil {
 		klog.V(5).Infof("Received error in WaitForCompletionRef: '%v'", err)
+		if rerr := retry.GetContextError(ctx); rerr != nil {
+			return rerr.Error()
+		}
 		return err
 	}
 
@@ -224,13 +576,96 @@ func (c *Client) WaitForAsyncOperationCompletion(ctx context.Context, future *az
 
 // WaitForAsyncOperationResult waits for an operation result.
 func (c *Client) WaitForAsyncOperationResult(ctx context.Context, future *azure.Future, asyncOperationName string) (*http.Response, error) {
+	defer c.completeAsyncOperation(ctx, future)
+
+	if locationURL := getLocationPollingURL(future); locationURL != "" {
+		return c.waitForLocationOperation(ctx, future, locationURL, asyncOperationName)
+	}
+
 	if err := future.WaitForCompletionRef(ctx, c.client); err != nil {
 		klog.V(5).Infof("Received error in WaitForAsyncOperationCompletion: '%v'", err)
+		if rerr := retry.GetContextError(ctx); rerr != nil {
+			return nil, rerr.Error()
+		}
 		return nil, err
 	}
 	return future.GetResult(c.client)
 }
 
+// getLocationPollingURL returns the URL in the Location header of the initial response if the operation should
+// be polled by it. azure.Future treats the 200 responses of the Location URL without a body as failures for PUT
+// operations, and reports the operation done without polling if the initial response is 200 OK.
+func getLocationPollingURL(future *azure.Future) string {
+	if future.PollingMethod() != azure.PollingLocation {
+		return ""
+	}
+	return future.PollingURL()
+}
+
+// waitForLocationOperation polls the Location URL until it stops returning 202 Accepted, the interval is
+// the Retry-After header of the polling response or PollingDelay, and the Location header of the polling
+// response replaces the URL if set. The resource is got by the terminal GET for PUT and PATCH operations,
+// and the last polling response is returned for the others.
+func (c *Client) waitForLocationOperation(ctx context.Context, future *azure.Future, locationURL, asyncOperationName string) (*http.Response, error) {
+	response, err := c.pollLocationOperation(ctx, locationURL, asyncOperationName)
+	if err != nil {
+		return response, err
+	}
+
+	initialRequest := future.Response().Request
+	if initialRequest.Method != http.MethodPut && initialRequest.Method != http.MethodPatch {
+		return response, nil
+	}
+	c.CloseResponse(ctx, response)
+
+	request, err := c.prepareRequest(ctx, autorest.AsGet(), autorest.WithBaseURL(initialRequest.URL.String()))
+	if err != nil {
+		klog.V(5).Infof("Received error in %s: resourceURL: %s, error: %s", "get.location.result.prepare", initialRequest.URL.String(), err)
+		return nil, autorest.NewErrorWithError(err, asyncOperationName, "Result", nil, "Failure preparing the terminal request")
+	}
+	response, rerr := c.Send(ctx, request)
+	if rerr != nil {
+		klog.V(5).Infof("Received error in %s: resourceURL: %s, error: %s", "get.location.result.send", initialRequest.URL.String(), rerr.Error())
+		return response, rerr.Error()
+	}
+	return response, nil
+}
+
+// pollLocationOperation polls the Location URL until it stops returning 202 Accepted and returns the last
+// polling response.
+func (c *Client) pollLocationOperation(ctx context.Context, locationURL, asyncOperationName string) (*http.Response, error) {
+	var response *http.Response
+	for {
+		request, err := c.prepareRequest(ctx, autorest.AsGet(), autorest.WithBaseURL(locationURL))
+		if err != nil {
+			klog.V(5).Infof("Received error in %s: locationURL: %s, error: %s", "get.location.prepare", locationURL, err)
+			return nil, autorest.NewErrorWithError(err, asyncOperationName, "Result", nil, "Polling failure")
+		}
+
+		var rerr *retry.Error
+		response, rerr = c.Send(ctx, request)
+		if rerr != nil {
+			klog.V(5).Infof("Received error in %s: locationURL: %s, error: %s", "get.location.send", locationURL, rerr.Error())
+			return response, rerr.Error()
+		}
+		if response.StatusCode != http.StatusAccepted {
+			break
+		}
+
+		if location := response.Header.Get(autorest.HeaderLocation); location != "" {
+			locationURL = location
+		}
+		delay := autorest.GetRetryAfter(response, c.client.PollingDelay)
+		c.CloseResponse(ctx, response)
+		select {
+		case <-ctx.Done():
+			return nil, retry.GetContextError(ctx).Error()
+		case <-time.After(delay):
+		}
+	}
+	return response, nil
+}
+
 // SendAsync send a request and return a future object representing the async result as well as the origin http response
 func (c *Client) SendAsync(ctx context.Context, request *http.Request) (*azure.Future, *http.Response, *retry.Error) {
 	asyncResponse, rerr := c.Send(ctx, request)
@@ -248,14 +683,26 @@ func (c *Client) SendAsync(ctx context.Context, request *http.Request) (*azure.F
 	return &future, asyncResponse, nil
 }
 
-// GetResourceWithExpandQuery get a resource by resource ID with expand
+// GetResourceWithExpandQuery get a resource by resource ID with expand. The 204 No Content response is
+// returned without errors, which could be detected by IsNoContent.
 func (c *Client) GetResourceWithExpandQuery(ctx context.Context, resourceID, expand string) (*http.Response, *retry.Error) {
-	var decorators []autorest.PrepareDecorator
-	if expand != "" {
+	return c.GetResourceWithExpands(ctx, resourceID, []string{expand})
+}
+
+// GetResourceWithExpands get a resource with decorators by resource ID with the expand values joined by commas,
+// e.g. "$expand=instanceView,userData". The empty expand values are ignored.
+func (c *Client) GetResourceWithExpands(ctx context.Context, resourceID string, expands []string, decorators ...autorest.PrepareDecorator) (*http.Response, *retry.Error) {
+	values := make([]string, 0, len(expands))
+	for _, expand := range expands {
+		if expand = strings.TrimSpace(expand); expand != "" {
+			values = append(values, expand)
+		}
+	}
+	if len(values) > 0 {
 		queryParameters := map[string]interface{}{
-			"$expand": autorest.Encode("query", expand),
+			"$expand": autorest.Encode("query", strings.Join(values, ",")),
 		}
-		decorators = append(decorators, autorest.WithQueryParameters(queryParameters))
+		decorators = append([]autorest.PrepareDecorator{autorest.WithQueryParameters(queryParameters)}, decorators...)
 	}
 	return c.GetResource(ctx, resourceID, decorators...)
 }
@@ -299,6 +746,653 @@ func (c *Client) GetResource(ctx context.Context, resourceID string, decorators
 	return c.Send(ctx, request)
 }
 
+// GetResourceByParts get a resource with decorators by the resource group, provider, resource type and name
+// in the subscription of the client. Each part is escaped when composing the resource ID.
+func (c *Client) GetResourceByParts(ctx context.Context, resourceGroup, provider, resourceType, name string, decorators ...autorest.PrepareDecorator) (*http.Response, *retry.Error) {
+	resourceID := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/%s/%s/%s",
+		autorest.Encode("path", c.subscriptionID),
+		autorest.Encode("path", resourceGroup),
+		autorest.Encode("path", provider),
+		autorest.Encode("path", resourceType),
+		autorest.Encode("path", name))
+	return c.GetResource(ctx, resourceID, decorators...)
+}
+
+// GetResourceWithMetadata get a resource with decorators by resource ID, together with the metadata
+// of the resource in the response headers.
+func (c *Client) GetResourceWithMetadata(ctx context.Context, resourceID string, decorators ...autorest.PrepareDecorator) (*http.Response, ResourceMetadata, *retry.Error) {
+	response, rerr := c.GetResource(ctx, resourceID, decorators...)
+	if rerr != nil {
+		return response, ResourceMetadata{}, rerr
+	}
+
+	return response, c.getResourceMetadata(response), nil
+}
+
+// resourceListResult is a page of the resources returned by the generic resources list API.
+type resourceListResult struct {
+	Value    []json.RawMessage `json:"value,omitempty"`
+	NextLink string            `json:"nextLink,omitempty"`
+}
+
+// ListResourcesChangedSince lists the resources in a subscription or resource group whose changedTime is after since.
+// Only the subscriptions and resource groups support the changedTime filter, an error is returned for other resource IDs.
+func (c *Client) ListResourcesChangedSince(ctx context.Context, resourceID string, since time.Time) ([]json.RawMessage, *retry.Error) {
+	if !changedTimeFilterScopeRE.MatchString(resourceID) {
+		return nil, retry.NewError(false, fmt.Errorf("listing resources changed since a timestamp is not supported by %s, only subscriptions and resource groups are supported", resourceID))
+	}
+
+	request, err := c.PrepareGetRequest(ctx,
+		autorest.WithPathParameters("{resourceID}/resources", map[string]interface{}{"resourceID": strings.TrimSuffix(resourceID, "/")}),
+		autorest.WithQueryParameters(map[string]interface{}{
+			"$filter": autorest.Encode("query", fmt.Sprintf("changedTime ge '%s'", since.UTC().Format(time.RFC3339))),
+			"$expand": autorest.Encode("query", "changedTime"),
+		}),
+		withAPIVersion(resourcesAPIVersion),
+	)
+	if err != nil {
+		klog.V(5).Infof("Received error in %s: resourceID: %s, error: %s", "list.prepare", resourceID, err)
+		return nil, retry.NewError(false, err)
+	}
+
+	var resources []json.RawMessage
+	for request != nil {
+		page, rerr := c.listResourcesPage(ctx, request)
+		if rerr != nil {
+			return nil, rerr
+		}
+		resources = append(resources, page.Value...)
+
+		request = nil
+		if page.NextLink != "" {
+			request, err = c.PrepareGetRequest(ctx, autorest.WithBaseURL(page.NextLink))
+			if err != nil {
+				klog.V(5).Infof("Received error in %s: resourceID: %s, error: %s", "list.next.prepare", resourceID, err)
+				return nil, retry.NewError(false, err)
+			}
+		}
+	}
+
+	return resources, nil
+}
+
+// listResourcesPage sends the request and returns a page of the resources listed.
+func (c *Client) listResourcesPage(ctx context.Context, request *http.Request) (resourceListResult, *retry.Error) {
+	result := resourceListResult{}
+	response, rerr := c.Send(ctx, request)
+	defer c.CloseResponse(ctx, response)
+	if rerr != nil {
+		klog.V(5).Infof("Received error in %s: url: %s, error: %s", "list.request", html.EscapeString(request.URL.String()), rerr.Error())
+		return result, rerr
+	}
+
+	err := autorest.Respond(
+		response,
+		azure.WithErrorUnlessStatusCode(http.StatusOK),
+		autorest.ByUnmarshallingJSON(&result))
+	if err != nil {
+		klog.V(5).Infof("Received error in %s: url: %s, error: %s", "list.respond", html.EscapeString(request.URL.String()), err)
+		return result, retry.GetError(response, err)
+	}
+
+	return result, nil
+}
+
+// StreamListResources lists the resources by the list resource ID, e.g. the one returned by GetResourceListID,
+// and calls onItem with each resource following the nextLink. The resources are decoded one by one from the
+// response body instead of buffering the whole page, so that listing thousands of resources doesn't take much
+// memory. Listing stops once onItem returns an error, which is returned.
+func (c *Client) StreamListResources(ctx context.Context, resourceID string, onItem func(json.RawMessage) error) *retry.Error {
+	request, err := c.PrepareGetRequest(ctx,
+		autorest.WithPathParameters("{resourceID}", map[string]interface{}{"resourceID": resourceID}),
+	)
+	if err != nil {
+		klog.V(5).Infof("Received error in %s: resourceID: %s, error: %s", "stream.list.prepare", resourceID, err)
+		return retry.NewError(false, err)
+	}
+
+	for request != nil {
+		nextLink, rerr := c.streamListResourcesPage(ctx, request, onItem)
+		if rerr != nil {
+			return rerr
+		}
+
+		request = nil
+		if nextLink != "" {
+			request, err = c.PrepareGetRequest(ctx, autorest.WithBaseURL(nextLink))
+			if err != nil {
+				klog.V(5).Infof("Received error in %s: resourceID: %s, error: %s", "stream.list.next.prepare", resourceID, err)
+				return retry.NewError(false, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// streamListResourcesPage sends the request, calls onItem with each resource in the value array of the page
+// while decoding it, and returns the nextLink of the page.
+func (c *Client) streamListResourcesPage(ctx context.Context, request *http.Request, onItem func(json.RawMessage) error) (string, *retry.Error) {
+	response, rerr := c.Send(ctx, request)
+	defer c.CloseResponse(ctx, response)
+	if rerr != nil {
+		klog.V(5).Infof("Received error in %s: url: %s, error: %s", "stream.list.request", html.EscapeString(request.URL.String()), rerr.Error())
+		return "", rerr
+	}
+
+	if err := autorest.Respond(response, azure.WithErrorUnlessStatusCode(http.StatusOK)); err != nil {
+		klog.V(5).Infof("Received error in %s: url: %s, error: %s", "stream.list.respond", html.EscapeString(request.URL.String()), err)
+		return "", retry.GetError(response, err)
+	}
+
+	nextLink, callbackErr, err := decodeResourceListPage(json.NewDecoder(response.Body), onItem)
+	if callbackErr != nil {
+		return "", retry.NewError(false, callbackErr)
+	}
+	if err != nil {
+		klog.V(5).Infof("Received error in %s: url: %s, error: %s", "stream.list.decode", html.EscapeString(request.URL.String()), err)
+		return "", retry.NewError(true, fmt.Errorf("failed to decode the resources listed: %w", err))
+	}
+
+	return nextLink, nil
+}
+
+// decodeResourceListPage decodes a page of the resources in the format of {"value": [...], "nextLink": "..."},
+// and calls onItem with each resource in the value array. The error returned by onItem is returned separately
+// from the decoding errors.
+func decodeResourceListPage(decoder *json.Decoder, onItem func(json.RawMessage) error) (nextLink string, callbackErr error, err error) {
+	if err := expectJSONDelim(decoder, '{'); err != nil {
+		return "", nil, err
+	}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", nil, err
+		}
+		switch token {
+		case "value":
+			if err := expectJSONDelim(decoder, '['); err != nil {
+				return "", nil, err
+			}
+			for decoder.More() {
+				var item json.RawMessage
+				if err := decoder.Decode(&item); err != nil {
+					return "", nil, err
+				}
+				if err := onItem(item); err != nil {
+					return "", err, nil
+				}
+			}
+			if err := expectJSONDelim(decoder, ']'); err != nil {
+				return "", nil, err
+			}
+		case "nextLink":
+			if err := decoder.Decode(&nextLink); err != nil {
+				return "", nil, err
+			}
+		default:
+			var ignored json.RawMessage
+			if err := decoder.Decode(&ignored); err != nil {
+				return "", nil, err
+			}
+		}
+	}
+	if err := expectJSONDelim(decoder, '}'); err != nil {
+		return "", nil, err
+	}
+
+	return nextLink, nil, nil
+}
+
+// expectJSONDelim reads the next token and returns an error if it is not the delimiter.
+func expectJSONDelim(decoder *json.Decoder, delim json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("expected %q but got %v", delim, token)
+	}
+	return nil
+}
+
+// QuotaUsage is the current usage and the limit of a quota returned by the usages API of the resource providers.
+type QuotaUsage struct {
+	// Name is the name of the quota, e.g. "cores".
+	Name string
+	// LocalizedName is the localized name of the quota, e.g. "Total Regional vCPUs".
+	LocalizedName string
+	// Unit is the unit of the usage, e.g. "Count".
+	Unit string
+	// CurrentValue is the current usage of the quota.
+	CurrentValue int64
+	// Limit is the limit of the quota.
+	Limit int64
+}
+
+// Remaining returns the remaining quota, which is zero if the usage exceeds the limit.
+func (u QuotaUsage) Remaining() int64 {
+	if u.CurrentValue >= u.Limit {
+		return 0
+	}
+	return u.Limit - u.CurrentValue
+}
+
+// quotaUsage is a usage returned by the usages API of the resource providers.
+type quotaUsage struct {
+	Unit         string `json:"unit,omitempty"`
+	CurrentValue int64  `json:"currentValue"`
+	Limit        int64  `json:"limit"`
+	Name         struct {
+		Value          string `json:"value,omitempty"`
+		LocalizedValue string `json:"localizedValue,omitempty"`
+	} `json:"name"`
+}
+
+// GetQuotaUsage lists the quota usages of the resource provider, e.g. "Microsoft.Compute", in the location
+// of the subscription. The usages API of the resource provider should support the API version of the client.
+func (c *Client) GetQuotaUsage(ctx context.Context, location, provider string) ([]QuotaUsage, *retry.Error) {
+	if location == "" || provider == "" {
+		return nil, retry.NewError(false, fmt.Errorf("both location and provider should be specified to get the quota usage"))
+	}
+
+	resourceID := fmt.Sprintf("/subscriptions/%s/providers/%s/locations/%s/usages",
+		autorest.Encode("path", c.subscriptionID),
+		autorest.Encode("path", provider),
+		autorest.Encode("path", location))
+	request, err := c.PrepareGetRequest(ctx,
+		autorest.WithPath(resourceID),
+		withAPIVersion(c.apiVersion),
+	)
+	if err != nil {
+		klog.V(5).Infof("Received error in %s: resourceID: %s, error: %s", "usage.prepare", resourceID, err)
+		return nil, retry.NewError(false, err)
+	}
+
+	var usages []QuotaUsage
+	for request != nil {
+		page, rerr := c.listResourcesPage(ctx, request)
+		if rerr != nil {
+			return nil, rerr
+		}
+		for _, raw := range page.Value {
+			usage := quotaUsage{}
+			if err := json.Unmarshal(raw, &usage); err != nil {
+				klog.V(5).Infof("Received error in %s: resourceID: %s, error: %s", "usage.unmarshal", resourceID, err)
+				return nil, retry.NewError(false, err)
+			}
+			usages = append(usages, QuotaUsage{
+				Name:          usage.Name.Value,
+				LocalizedName: usage.Name.LocalizedValue,
+				Unit:          usage.Unit,
+				CurrentValue:  usage.CurrentValue,
+				Limit:         usage.Limit,
+			})
+		}
+
+		request = nil
+		if page.NextLink != "" {
+			request, err = c.PrepareGetRequest(ctx, autorest.WithBaseURL(page.NextLink))
+			if err != nil {
+				klog.V(5).Infof("Received error in %s: resourceID: %s, error: %s", "usage.next.prepare", resourceID, err)
+				return nil, retry.NewError(false, err)
+			}
+		}
+	}
+
+	return usages, nil
+}
+
+// GetProviderRegistrationState gets the registration state of the resource provider, e.g. "Microsoft.Network",
+// in the subscription of the client, which is "Registered", "NotRegistered", "Registering" or "Unregistering".
+func (c *Client) GetProviderRegistrationState(ctx context.Context, namespace string) (string, *retry.Error) {
+	if namespace == "" {
+		return "", retry.NewError(false, fmt.Errorf("the namespace of the resource provider should be specified"))
+	}
+
+	resourceID := fmt.Sprintf("/subscriptions/%s/providers/%s",
+		autorest.Encode("path", c.subscriptionID),
+		autorest.Encode("path", namespace))
+	request, err := c.PrepareGetRequest(ctx,
+		autorest.WithPath(resourceID),
+		withAPIVersion(providersAPIVersion),
+	)
+	if err != nil {
+		klog.V(5).Infof("Received error in %s: resourceID: %s, error: %s", "provider.get.prepare", resourceID, err)
+		return "", retry.NewError(false, err)
+	}
+
+	response, rerr := c.Send(ctx, request)
+	defer c.CloseResponse(ctx, response)
+	if rerr != nil {
+		klog.V(5).Infof("Received error in %s: resourceID: %s, error: %s", "provider.get.request", resourceID, rerr.Error())
+		return "", rerr
+	}
+
+	result := struct {
+		RegistrationState string `json:"registrationState"`
+	}{}
+	err = autorest.Respond(
+		response,
+		azure.WithErrorUnlessStatusCode(http.StatusOK),
+		autorest.ByUnmarshallingJSON(&result))
+	if err != nil {
+		klog.V(5).Infof("Received error in %s: resourceID: %s, error: %s", "provider.get.respond", resourceID, err)
+		return "", retry.GetError(response, err)
+	}
+	return result.RegistrationState, nil
+}
+
+// EnsureProviderRegistered registers the resource provider in the subscription of the client if it is not
+// registered, and waits until the registration completes or the timeout expires.
+func (c *Client) EnsureProviderRegistered(ctx context.Context, namespace string, timeout time.Duration) *retry.Error {
+	state, rerr := c.GetProviderRegistrationState(ctx, namespace)
+	if rerr != nil {
+		return rerr
+	}
+	if strings.EqualFold(state, ProviderRegistrationStateRegistered) {
+		return nil
+	}
+
+	klog.V(2).Infof("EnsureProviderRegistered: resource provider %s is in the registration state %s, registering it", namespace, state)
+	resourceID := fmt.Sprintf("/subscriptions/%s/providers/%s/register",
+		autorest.Encode("path", c.subscriptionID),
+		autorest.Encode("path", namespace))
+	request, err := c.PreparePostRequest(ctx,
+		autorest.WithPath(resourceID),
+		withAPIVersion(providersAPIVersion),
+	)
+	if err != nil {
+		klog.V(5).Infof("Received error in %s: resourceID: %s, error: %s", "provider.register.prepare", resourceID, err)
+		return retry.NewError(false, err)
+	}
+
+	response, rerr := c.Send(ctx, request)
+	defer c.CloseResponse(ctx, response)
+	if rerr != nil {
+		klog.V(5).Infof("Received error in %s: resourceID: %s, error: %s", "provider.register.request", resourceID, rerr.Error())
+		return rerr
+	}
+	if err := autorest.Respond(response, azure.WithErrorUnlessStatusCode(http.StatusOK)); err != nil {
+		klog.V(5).Infof("Received error in %s: resourceID: %s, error: %s", "provider.register.respond", resourceID, err)
+		return retry.GetError(response, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for {
+		state, rerr = c.GetProviderRegistrationState(ctx, namespace)
+		if rerr != nil {
+			return rerr
+		}
+		if strings.EqualFold(state, ProviderRegistrationStateRegistered) {
+			return nil
+		}
+
+		klog.V(5).Infof("EnsureProviderRegistered: resource provider %s is in the registration state %s, waiting for %s", namespace, state, ProviderRegistrationStateRegistered)
+		select {
+		case <-ctx.Done():
+			return retry.NewError(false, fmt.Errorf("timed out waiting for resource provider %s to be registered, current state: %s", namespace, state))
+		case <-time.After(c.client.PollingDelay):
+		}
+	}
+}
+
+// getResourceMetadata gets the ResourceMetadata from the response headers.
+func (c *Client) getResourceMetadata(response *http.Response) ResourceMetadata {
+	metadata := ResourceMetadata{
+		APIVersion: c.apiVersion,
+	}
+	if response == nil {
+		return metadata
+	}
+
+	metadata.ETag = response.Header.Get("ETag")
+	if lastModified := response.Header.Get("Last-Modified"); lastModified != "" {
+		t, err := http.ParseTime(lastModified)
+		if err != nil {
+			klog.V(5).Infof("Failed to parse the Last-Modified header %q: %v", lastModified, err)
+		} else {
+			metadata.LastModified = t
+		}
+	}
+	if response.Request != nil && response.Request.URL != nil {
+		if apiVersion := response.Request.URL.Query().Get("api-version"); apiVersion != "" {
+			metadata.APIVersion = apiVersion
+		}
+	}
+	return metadata
+}
+
+// WaitForProvisioningState gets the resource by resource ID on the polling interval until its provisioning
+// state is desiredState. It returns an error immediately if the resource is in the terminal Failed state.
+func (c *Client) WaitForProvisioningState(ctx context.Context, resourceID, desiredState string, timeout time.Duration) *retry.Error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		provisioningState, rerr := c.getProvisioningState(ctx, resourceID)
+		if rerr != nil {
+			return rerr
+		}
+
+		if strings.EqualFold(provisioningState, desiredState) {
+			return nil
+		}
+		if strings.EqualFold(provisioningState, provisioningStateFailed) {
+			return retry.NewError(false, fmt.Errorf("resource %s is in the terminal provisioning state %s", resourceID, provisioningState))
+		}
+
+		klog.V(5).Infof("WaitForProvisioningState: resource %s is in the provisioning state %s, waiting for %s", resourceID, provisioningState, desiredState)
+		select {
+		case <-ctx.Done():
+			return retry.NewError(false, fmt.Errorf("timed out waiting for resource %s to reach the provisioning state %s, current state: %s", resourceID, desiredState, provisioningState))
+		case <-time.After(c.client.PollingDelay):
+		}
+	}
+}
+
+// getProvisioningState gets the provisioning state of the resource by resource ID.
+func (c *Client) getProvisioningState(ctx context.Context, resourceID string) (string, *retry.Error) {
+	response, rerr := c.GetResource(ctx, resourceID)
+	defer c.CloseResponse(ctx, response)
+	if rerr != nil {
+		klog.V(5).Infof("Received error in %s: resourceID: %s, error: %s", "get.provisioningstate.request", resourceID, rerr.Error())
+		return "", rerr
+	}
+
+	result := struct {
+		Properties *struct {
+			ProvisioningState string `json:"provisioningState"`
+		} `json:"properties"`
+	}{}
+	err := autorest.Respond(
+		response,
+		azure.WithErrorUnlessStatusCode(http.StatusOK),
+		autorest.ByUnmarshallingJSON(&result))
+	if err != nil {
+		klog.V(5).Infof("Received error in %s: resourceID: %s, error: %s", "get.provisioningstate.respond", resourceID, err)
+		return "", retry.GetError(response, err)
+	}
+
+	if result.Properties == nil {
+		return "", nil
+	}
+	return result.Properties.ProvisioningState, nil
+}
+
+// GetAsyncOperationStatus gets the status of an async operation by its Azure-AsyncOperation URL,
+// e.g. InProgress, Succeeded, Failed or Canceled. The body of the returned response is still readable.
+func (c *Client) GetAsyncOperationStatus(ctx context.Context, asyncOpURL string) (string, *http.Response, *retry.Error) {
+	request, err := c.prepareRequest(ctx, autorest.AsGet(), autorest.WithBaseURL(asyncOpURL))
+	if err != nil {
+		klog.V(5).Infof("Received error in %s: asyncOpURL: %s, error: %s", "get.asyncoperation.prepare", asyncOpURL, err)
+		return "", nil, retry.NewError(false, err)
+	}
+
+	response, rerr := c.Send(ctx, request)
+	if rerr != nil {
+		klog.V(5).Infof("Received error in %s: asyncOpURL: %s, error: %s", "get.asyncoperation.send", asyncOpURL, rerr.Error())
+		return "", response, rerr
+	}
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		klog.V(5).Infof("Received error in %s: asyncOpURL: %s, error: %s", "get.asyncoperation.respond", asyncOpURL, err)
+		return "", response, retry.GetError(response, err)
+	}
+	response.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	result := struct {
+		Status string `json:"status"`
+	}{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		klog.V(5).Infof("Received error in %s: asyncOpURL: %s, error: %s", "get.asyncoperation.respond", asyncOpURL, err)
+		return "", response, retry.GetError(response, err)
+	}
+	if result.Status == "" {
+		return "", response, retry.NewError(false, fmt.Errorf("status is not found in the response of async operation %s", asyncOpURL))
+	}
+	return result.Status, response, nil
+}
+
+// SetBackoff updates the backoff of the retries of the new requests, the requests in flight keep retrying with the
+// old backoff. The non-retriable errors and the retriable HTTP status codes of the client are kept.
+func (c *Client) SetBackoff(backoff *retry.Backoff) {
+	if backoff == nil {
+		return
+	}
+
+	c.backoffLock.Lock()
+	defer c.backoffLock.Unlock()
+	newBackoff := *backoff
+	newBackoff.NonRetriableErrors = c.backoff.NonRetriableErrors
+	newBackoff.RetriableHTTPStatusCodes = c.backoff.RetriableHTTPStatusCodes
+	if newBackoff.Steps == 0 {
+		// 1 steps means no retry.
+		newBackoff.Steps = 1
+	}
+	c.backoff = newBackoff
+}
+
+// doExponentialBackoffRetry returns a SendDecorator retrying the requests with the current backoff of the client.
+func (c *Client) doExponentialBackoffRetry() autorest.SendDecorator {
+	return func(s autorest.Sender) autorest.Sender {
+		return autorest.SenderFunc(func(r *http.Request) (*http.Response, error) {
+			c.backoffLock.RLock()
+			backoff := c.backoff
+			c.backoffLock.RUnlock()
+			return retry.DoExponentialBackoffRetry(&backoff)(s).Do(r)
+		})
+	}
+}
+
+// doRecordRateLimitRemaining returns a SendDecorator recording the remaining ARM requests reported in the
+// x-ms-ratelimit-remaining-* headers of the responses, and warning when they are below the threshold.
+func (c *Client) doRecordRateLimitRemaining() autorest.SendDecorator {
+	return func(s autorest.Sender) autorest.Sender {
+		return autorest.SenderFunc(func(r *http.Request) (*http.Response, error) {
+			resp, err := s.Do(r)
+			if resp == nil {
+				return resp, err
+			}
+			for rateLimitType, remaining := range parseRateLimitRemaining(resp.Header) {
+				metrics.SetRateLimitRemaining(rateLimitType, remaining)
+				c.rateLimitBudget.Update(rateLimitType, remaining)
+				if remaining < c.rateLimitRemainingWarningThreshold {
+					klog.Warningf("The remaining ARM %s of subscription %s is %d, which is below the threshold %d, the requests may be throttled soon",
+						rateLimitType, c.subscriptionID, remaining, c.rateLimitRemainingWarningThreshold)
+				}
+			}
+			return resp, err
+		})
+	}
+}
+
+// doSlowDownWrites returns a SendDecorator delaying the write requests while the remaining ARM writes are below the
+// threshold, if slowDownWritesOnLowRateLimitRemaining is enabled.
+func (c *Client) doSlowDownWrites() autorest.SendDecorator {
+	return func(s autorest.Sender) autorest.Sender {
+		return autorest.SenderFunc(func(r *http.Request) (*http.Response, error) {
+			if !c.slowDownWritesOnLowRateLimitRemaining || r.Method == http.MethodGet || r.Method == http.MethodHead {
+				return s.Do(r)
+			}
+			if remaining, ok := c.rateLimitBudget.Remaining(azureclients.RateLimitTypeWrites); ok && remaining < c.rateLimitRemainingWarningThreshold {
+				klog.V(4).Infof("Delaying the %s request to %s by %v since the remaining ARM writes is %d", r.Method, r.URL.Path, lowRateLimitRemainingWriteDelay, remaining)
+				select {
+				case <-r.Context().Done():
+					return nil, r.Context().Err()
+				case <-time.After(lowRateLimitRemainingWriteDelay):
+				}
+			}
+			return s.Do(r)
+		})
+	}
+}
+
+// RegisterRequestBodyValidator registers the validator of the request bodies to put the resources of the resource type,
+// e.g. "Microsoft.Network/loadBalancers" or "Microsoft.Network/loadBalancers/backendAddressPools". The registered
+// validator of the same type is replaced, and the validator is removed if it is nil.
+func (c *Client) RegisterRequestBodyValidator(resourceType string, validator RequestBodyValidator) {
+	c.requestBodyValidatorsLock.Lock()
+	defer c.requestBodyValidatorsLock.Unlock()
+
+	if validator == nil {
+		delete(c.requestBodyValidators, strings.ToLower(resourceType))
+		return
+	}
+	if c.requestBodyValidators == nil {
+		c.requestBodyValidators = make(map[string]RequestBodyValidator)
+	}
+	c.requestBodyValidators[strings.ToLower(resourceType)] = validator
+}
+
+// SetRequestMetricsHook sets the hook called with the metrics of each request sent by the client, which carry the
+// source controller and the attribution tags from the request context. The hook is removed if it is nil.
+func (c *Client) SetRequestMetricsHook(hook RequestMetricsHook) {
+	c.requestMetricsHookLock.Lock()
+	defer c.requestMetricsHookLock.Unlock()
+	c.requestMetricsHook = hook
+}
+
+// observeRequest passes the metrics of the request to the request metrics hook if there is one.
+func (c *Client) observeRequest(ctx context.Context, request *http.Request, statusCode int, latency time.Duration) {
+	c.requestMetricsHookLock.RLock()
+	hook := c.requestMetricsHook
+	c.requestMetricsHookLock.RUnlock()
+	if hook == nil {
+		return
+	}
+
+	hook(RequestMetrics{
+		Method:           request.Method,
+		Path:             request.URL.Path,
+		StatusCode:       statusCode,
+		Latency:          latency,
+		SourceController: metrics.SourceFromContext(ctx),
+		Tags:             metrics.AttributionTagsFromContext(ctx),
+	})
+}
+
+// validateRequestBody validates the JSON body of the parameters by the validator registered for the type of the resource.
+func (c *Client) validateRequestBody(resourceID string, parameters interface{}) error {
+	c.requestBodyValidatorsLock.RLock()
+	validator, ok := c.requestBodyValidators[strings.ToLower(getResourceType(resourceID))]
+	c.requestBodyValidatorsLock.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	body, err := json.Marshal(parameters)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the request body of resource %s: %w", resourceID, err)
+	}
+	if err := validator(resourceID, body); err != nil {
+		return fmt.Errorf("the request body of resource %s is invalid: %w", resourceID, err)
+	}
+	return nil
+}
+
 // PutResource puts a resource by resource ID
 func (c *Client) PutResource(ctx context.Context, resourceID string, parameters interface{}, decorators ...autorest.PrepareDecorator) (*http.Response, *retry.Error) {
 	future, rerr := c.PutResourceAsync(ctx, resourceID, parameters, decorators...)
@@ -308,24 +1402,90 @@ func (c *Client) PutResource(ctx context.Context, resourceID string, parameters
 
 	response, err := c.WaitForAsyncOperationResult(ctx, future, "armclient.PutResource")
 	if err != nil {
-		if response != nil {
-			klog.V(5).Infof("Received error in WaitForAsyncOperationResult: '%s', response code %d", err.Error(), response.StatusCode)
-		} else {
-			klog.V(5).Infof("Received error in WaitForAsyncOperationResult: '%s', no response", err.Error())
-		}
+		return nil, c.getPutResourceError(ctx, response, err)
+	}
 
-		retriableErr := retry.GetError(response, err)
-		if !retriableErr.Retriable &&
-			strings.Contains(strings.ToUpper(err.Error()), strings.ToUpper("InternalServerError")) {
-			klog.V(5).Infof("Received InternalServerError in WaitForAsyncOperationResult: '%s', setting error retriable", err.Error())
-			retriableErr.Retriable = true
-		}
-		return nil, retriableErr
+	return response, nil
+}
+
+// getPutResourceError converts the error of waiting for the put operation to a retry.Error.
+func (c *Client) getPutResourceError(ctx context.Context, response *http.Response, err error) *retry.Error {
+	if response != nil {
+		klog.V(5).Infof("Received error in WaitForAsyncOperationResult: '%s', response code %d", err.Error(), response.StatusCode)
+	} else {
+		klog.V(5).Infof("Received error in WaitForAsyncOperationResult: '%s', no response", err.Error())
+	}
+	if rerr := retry.GetContextError(ctx); rerr != nil {
+		return rerr
+	}
+
+	retriableErr := retry.GetError(response, err)
+	if !retriableErr.Retriable &&
+		strings.Contains(strings.ToUpper(err.Error()), strings.ToUpper("InternalServerError")) {
+		klog.V(5).Infof("Received InternalServerError in WaitForAsyncOperationResult: '%s', setting error retriable", err.Error())
+		retriableErr.Retriable = true
+	}
+	return retriableErr
+}
+
+// PutResourceMinimal puts a resource by resource ID with the "Prefer: return=minimal" header, so that the resource
+// is not echoed back in the response body. Different from PutResource, the resource is not got again after the
+// operation completes, and the response of the put request (or of the last polling request of the long running
+// operation) is returned. Some RPs ignore the preference and return the full body anyway, hence callers should
+// neither rely on the body being empty nor on it being present.
+func (c *Client) PutResourceMinimal(ctx context.Context, resourceID string, parameters interface{}, decorators ...autorest.PrepareDecorator) (*http.Response, *retry.Error) {
+	decorators = append(decorators, autorest.WithHeader("Prefer", "return=minimal"))
+	future, response, rerr := c.putResourceAsync(ctx, resourceID, parameters, decorators...)
+	if rerr != nil {
+		c.CloseResponse(ctx, response)
+		return nil, rerr
+	}
+
+	response, err := c.waitForAsyncOperationResponse(ctx, future, response, "armclient.PutResourceMinimal")
+	if err != nil {
+		rerr := c.getPutResourceError(ctx, response, err)
+		c.CloseResponse(ctx, response)
+		return nil, rerr
+	}
+
+	return response, nil
+}
+
+// waitForAsyncOperationResponse waits for the operation to complete and returns its final response without getting
+// the resource again, i.e. the initial response if the operation is not long running, or the last polling response.
+// The initial response is closed if it is not returned.
+func (c *Client) waitForAsyncOperationResponse(ctx context.Context, future *azure.Future, initialResponse *http.Response, asyncOperationName string) (*http.Response, error) {
+	defer c.completeAsyncOperation(ctx, future)
+
+	if locationURL := getLocationPollingURL(future); locationURL != "" {
+		c.CloseResponse(ctx, initialResponse)
+		return c.pollLocationOperation(ctx, locationURL, asyncOperationName)
 	}
 
+	// the future is not polled if the initial response is terminal
+	err := future.WaitForCompletionRef(ctx, c.client)
+	response := future.Response()
+	if response != initialResponse {
+		c.CloseResponse(ctx, initialResponse)
+	}
+	if err != nil {
+		klog.V(5).Infof("Received error in WaitForCompletionRef: '%v'", err)
+		return response, err
+	}
+	// the body of the last polling response has been read by the future, which puts it back for the callers
 	return response, nil
 }
 
+// PutResourceWithETag puts a resource by resource ID only if its ETag matches ifMatch. The stale ETag
+// is rejected with an error whose IsPreconditionFailed() is true.
+func (c *Client) PutResourceWithETag(ctx context.Context, resourceID string, parameters interface{}, ifMatch string, decorators ...autorest.PrepareDecorator) (*http.Response, *retry.Error) {
+	if ifMatch != "" {
+		decorators = append(decorators, autorest.WithHeader("If-Match", autorest.String(ifMatch)))
+	}
+
+	return c.PutResource(ctx, resourceID, parameters, decorators...)
+}
+
 // PutResourcesInBatches is similar with PutResources, but it sends sync request concurrently in batches.
 func (c *Client) PutResourcesInBatches(ctx context.Context, resources map[string]interface{}, batchSize int) map[string]*PutResourcesResponse {
 	if len(resources) == 0 {
@@ -350,7 +1510,17 @@ func (c *Client) PutResourcesInBatches(ctx context.Context, resources map[string
 	wg := sync.WaitGroup{}
 	var responseLock sync.Mutex
 	for resourceID, parameters := range resources {
-		rateLimiter <- struct{}{}
+		select {
+		case rateLimiter <- struct{}{}:
+		case <-ctx.Done():
+			// Don't send the remaining requests once the context is done.
+			responseLock.Lock()
+			responses[resourceID] = &PutResourcesResponse{
+				Error: retry.GetContextError(ctx),
+			}
+			responseLock.Unlock()
+			continue
+		}
 		wg.Add(1)
 		go func(resourceID string, parameters interface{}) {
 			defer wg.Done()
@@ -383,6 +1553,9 @@ func (c *Client) PatchResource(ctx context.Context, resourceID string, parameter
 		} else {
 			klog.V(5).Infof("Received error in WaitForAsyncOperationResult: '%s', no response", err.Error())
 		}
+		if rerr := retry.GetContextError(ctx); rerr != nil {
+			return nil, rerr
+		}
 
 		retriableErr := retry.GetError(response, err)
 		if !retriableErr.Retriable &&
@@ -398,10 +1571,12 @@ func (c *Client) PatchResource(ctx context.Context, resourceID string, parameter
 
 // PatchResourceAsync patches a resource by resource ID asynchronously
 func (c *Client) PatchResourceAsync(ctx context.Context, resourceID string, parameters interface{}, decorators ...autorest.PrepareDecorator) (*azure.Future, *retry.Error) {
-	decorators = append(decorators,
+	// The decorators of the callers are applied after the resource path so that they could also set
+	// the query parameters, e.g. the api-version of the request.
+	decorators = append([]autorest.PrepareDecorator{
 		autorest.WithPathParameters("{resourceID}", map[string]interface{}{"resourceID": resourceID}),
 		autorest.WithJSON(parameters),
-	)
+	}, decorators...)
 
 	request, err := c.PreparePatchRequest(ctx, decorators...)
 	if err != nil {
@@ -415,30 +1590,45 @@ func (c *Client) PatchResourceAsync(ctx context.Context, resourceID string, para
 		klog.V(5).Infof("Received error in %s: resourceID: %s, error: %s", "patch.send", resourceID, clientErr.Error())
 		return nil, clientErr
 	}
+	c.registerAsyncOperation(future, resourceID, http.MethodPatch)
 	return future, clientErr
 }
 
 // PutResourceAsync puts a resource by resource ID in async mode
 func (c *Client) PutResourceAsync(ctx context.Context, resourceID string, parameters interface{}, decorators ...autorest.PrepareDecorator) (*azure.Future, *retry.Error) {
-	decorators = append(decorators,
+	future, resp, rerr := c.putResourceAsync(ctx, resourceID, parameters, decorators...)
+	c.CloseResponse(ctx, resp)
+	return future, rerr
+}
+
+// putResourceAsync is similar with PutResourceAsync, but it returns the initial response without closing it.
+func (c *Client) putResourceAsync(ctx context.Context, resourceID string, parameters interface{}, decorators ...autorest.PrepareDecorator) (*azure.Future, *http.Response, *retry.Error) {
+	if err := c.validateRequestBody(resourceID, parameters); err != nil {
+		klog.V(5).Infof("Received error in %s: resourceID: %s, error: %s", "put.validate", resourceID, err)
+		return nil, nil, retry.NewError(false, err)
+	}
+
+	// The decorators of the callers are applied after the resource path so that they could also set
+	// the query parameters, e.g. the api-version of the request.
+	decorators = append([]autorest.PrepareDecorator{
 		autorest.WithPathParameters("{resourceID}", map[string]interface{}{"resourceID": resourceID}),
 		autorest.WithJSON(parameters),
-	)
+	}, decorators...)
 
 	request, err := c.PreparePutRequest(ctx, decorators...)
 	if err != nil {
 		klog.V(5).Infof("Received error in %s: resourceID: %s, error: %s", "put.prepare", resourceID, err)
-		return nil, retry.NewError(false, err)
+		return nil, nil, retry.NewError(false, err)
 	}
 
 	future, resp, rErr := c.SendAsync(ctx, request)
-	defer c.CloseResponse(ctx, resp)
 	if rErr != nil {
 		klog.V(5).Infof("Received error in %s: resourceID: %s, error: %s", "put.send", resourceID, err)
-		return nil, rErr
+		return nil, resp, rErr
 	}
 
-	return future, nil
+	c.registerAsyncOperation(future, resourceID, http.MethodPut)
+	return future, resp, nil
 }
 
 // PostResource posts a resource by resource ID
@@ -476,8 +1666,12 @@ func (c *Client) DeleteResource(ctx context.Context, resourceID string, decorato
 	if future == nil {
 		return nil
 	}
+	defer c.completeAsyncOperation(ctx, future)
 	if err := future.WaitForCompletionRef(ctx, c.client); err != nil {
-		klog.V(5).Infof("Received error in %s: resourceID: %s, error: %s", "delete.wait", resourceID, clientErr.Error())
+		klog.V(5).Infof("Received error in %s: resourceID: %s, error: %s", "delete.wait", resourceID, err)
+		if rerr := retry.GetContextError(ctx); rerr != nil {
+			return rerr
+		}
 		return retry.NewError(true, err)
 	}
 
@@ -535,9 +1729,101 @@ func (c *Client) DeleteResourceAsync(ctx context.Context, resourceID string, dec
 		return nil, retry.GetError(resp, err)
 	}
 
+	c.registerAsyncOperation(&future, resourceID, http.MethodDelete)
 	return &future, nil
 }
 
+// registerAsyncOperation records the async operation of the future as active until it is waited to complete.
+func (c *Client) registerAsyncOperation(future *azure.Future, resourceID, method string) {
+	if future == nil {
+		return
+	}
+
+	c.activeAsyncOpsLock.Lock()
+	defer c.activeAsyncOpsLock.Unlock()
+	for f, op := range c.activeAsyncOps {
+		if time.Since(op.StartTime) > activeAsyncOpMaxAge {
+			klog.V(4).Infof("Forgetting the %s operation on %s started at %v, which is not waited to complete", op.Method, op.ResourceID, op.StartTime)
+			delete(c.activeAsyncOps, f)
+		}
+	}
+	c.activeAsyncOps[future] = AsyncOpInfo{
+		ResourceID:    resourceID,
+		Method:        method,
+		PollingMethod: future.PollingMethod(),
+		PollingURL:    future.PollingURL(),
+		StartTime:     time.Now(),
+	}
+}
+
+// completeAsyncOperation removes the async operation of the future from the active ones once the wait returns,
+// including when the wait context expires. The operation is only kept if the wait is interrupted by CancelAll,
+// e.g. on shutdown, so that it could be reported as still in flight in ARM. The kept operations and the ones
+// never waited are forgotten after activeAsyncOpMaxAge.
+func (c *Client) completeAsyncOperation(ctx context.Context, future *azure.Future) {
+	if future == nil {
+		return
+	}
+	c.rootLock.RLock()
+	rootCtx := c.rootCtx
+	c.rootLock.RUnlock()
+	if rootCtx.Err() != nil {
+		return
+	}
+
+	c.activeAsyncOpsLock.Lock()
+	defer c.activeAsyncOpsLock.Unlock()
+	delete(c.activeAsyncOps, future)
+}
+
+// ListActiveAsyncOperations returns the async operations started by the client that are not waited to complete,
+// ordered by their start time. It could be called on shutdown after CancelAll to report the operations still in
+// flight, whose polling could be resumed by the next controller instance.
+func (c *Client) ListActiveAsyncOperations() []AsyncOpInfo {
+	c.activeAsyncOpsLock.Lock()
+	defer c.activeAsyncOpsLock.Unlock()
+
+	ops := make([]AsyncOpInfo, 0, len(c.activeAsyncOps))
+	for _, op := range c.activeAsyncOps {
+		ops = append(ops, op)
+	}
+	sort.Slice(ops, func(i, j int) bool {
+		return ops[i].StartTime.Before(ops[j].StartTime)
+	})
+	return ops
+}
+
+// DeleteResourceAsyncAndWait deletes a resource by resource ID and waits for the completion of the async
+// operation, which is polled by the Azure-AsyncOperation or the Location header of the delete response.
+func (c *Client) DeleteResourceAsyncAndWait(ctx context.Context, resourceID string, decorators ...autorest.PrepareDecorator) *retry.Error {
+	future, rerr := c.DeleteResourceAsync(ctx, resourceID, decorators...)
+	if rerr != nil {
+		return rerr
+	}
+	if future == nil {
+		return nil
+	}
+
+	if err := c.WaitForAsyncOperationCompletion(ctx, future, getAsyncOperationName("DeleteResource", future)); err != nil {
+		klog.V(5).Infof("Received error in %s: resourceID: %s, error: %s", "deleteAsync.wait", resourceID, err)
+		if rerr := retry.GetContextError(ctx); rerr != nil {
+			return rerr
+		}
+		return retry.GetError(future.Response(), err)
+	}
+	return nil
+}
+
+// getAsyncOperationName returns the name of the async operation reported in the polling errors, which is
+// suffixed by the header the operation is polled by, e.g. "armclient.DeleteResource.AsyncOperation".
+func getAsyncOperationName(operation string, future *azure.Future) string {
+	name := "armclient." + operation
+	if method := future.PollingMethod(); method != azure.PollingUnknown {
+		name = name + "." + string(method)
+	}
+	return name
+}
+
 // CloseResponse closes a response
 func (c *Client) CloseResponse(ctx context.Context, response *http.Response) {
 	if response != nil && response.Body != nil {
@@ -548,9 +1834,14 @@ func (c *Client) CloseResponse(ctx context.Context, response *http.Response) {
 }
 
 func (c *Client) prepareRequest(ctx context.Context, decorators ...autorest.PrepareDecorator) (*http.Request, error) {
-	decorators = append(
-		decorators,
-		withAPIVersion(c.apiVersion))
+	decorators = append(decorators, withAPIVersion(c.apiVersion))
+	if klog.V(dumpRequestLogLevel).Enabled() {
+		decorators = append(decorators, WithRedactedBodyLogging(c.redactedLogFields))
+	} else {
+		// Skip redacting the bodies since they are not dumped at the current verbosity, and make sure they
+		// are still not dumped if the verbosity is raised before the requests are sent.
+		ctx = context.WithValue(ctx, redactedBodyKey{}, []byte(nil))
+	}
 	preparer := autorest.CreatePreparer(decorators...)
 	return preparer.Prepare((&http.Request{}).WithContext(ctx))
 }
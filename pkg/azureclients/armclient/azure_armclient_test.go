@@ -18,21 +18,35 @@ package armclient
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"runtime"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients"
 
 	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/adal"
 	"github.com/Azure/go-autorest/autorest/azure"
 	"github.com/stretchr/testify/assert"
 
 	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+	"sigs.k8s.io/cloud-provider-azure/pkg/metrics"
 	"sigs.k8s.io/cloud-provider-azure/pkg/retry"
 )
 
@@ -73,6 +87,362 @@ func TestSend(t *testing.T) {
 	assert.Equal(t, 2, count)
 	assert.Equal(t, http.StatusOK, response.StatusCode)
 }
+func TestCancelAll(t *testing.T) {
+	var slow int32 = 1
+	inFlight := make(chan struct{}, 10)
+	unblock := make(chan struct{})
+	defer close(unblock)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&slow) == 1 {
+			inFlight <- struct{}{}
+			select {
+			case <-unblock:
+			case <-r.Context().Done():
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	azConfig := azureclients.ClientConfig{Backoff: &retry.Backoff{Steps: 3}, UserAgent: "test", Location: "eastus"}
+	armClient := New(nil, azConfig, server.URL, "2019-01-01")
+
+	const calls = 5
+	errs := make(chan *retry.Error, calls)
+	for i := 0; i < calls; i++ {
+		go func() {
+			_, rerr := armClient.GetResource(context.Background(), testResourceID)
+			errs <- rerr
+		}()
+	}
+	for i := 0; i < calls; i++ {
+		<-inFlight
+	}
+
+	armClient.CancelAll()
+	for i := 0; i < calls; i++ {
+		select {
+		case rerr := <-errs:
+			assert.NotNil(t, rerr)
+			assert.ErrorIs(t, rerr.Error(), context.Canceled)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("in-flight requests should return after CancelAll")
+		}
+	}
+
+	// Subsequent requests fail fast until Reset.
+	atomic.StoreInt32(&slow, 0)
+	_, rerr := armClient.GetResource(context.Background(), testResourceID)
+	assert.NotNil(t, rerr)
+	assert.ErrorIs(t, rerr.Error(), context.Canceled)
+
+	armClient.Reset()
+	response, rerr := armClient.GetResource(context.Background(), testResourceID)
+	assert.Nil(t, rerr)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+}
+
+func TestWithRootContextRelease(t *testing.T) {
+	azConfig := azureclients.ClientConfig{Backoff: &retry.Backoff{Steps: 1}, UserAgent: "test", Location: "eastus"}
+	armClient := New(nil, azConfig, "http://localhost", "2019-01-01")
+	request, err := http.NewRequest(http.MethodGet, "http://localhost", nil)
+	assert.NoError(t, err)
+
+	// the context of the request is cancelled once the response body is closed
+	rootRequest, release, rerr := armClient.withRootContext(request)
+	assert.Nil(t, rerr)
+	response := release(&http.Response{Body: ioutil.NopCloser(strings.NewReader("body"))})
+	assert.NoError(t, rootRequest.Context().Err())
+	body, err := ioutil.ReadAll(response.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "body", string(body))
+	assert.NoError(t, response.Body.Close())
+	assert.Equal(t, context.Canceled, rootRequest.Context().Err())
+
+	// the context of the request is cancelled immediately if there is no response
+	rootRequest, release, rerr = armClient.withRootContext(request)
+	assert.Nil(t, rerr)
+	assert.Nil(t, release(nil))
+	assert.Equal(t, context.Canceled, rootRequest.Context().Err())
+	assert.NoError(t, request.Context().Err(), "the context of the original request should not be cancelled")
+}
+
+func TestValidateResourceID(t *testing.T) {
+	testcases := []struct {
+		description    string
+		subscriptionID string
+		resourceID     string
+		expectedErr    string
+	}{
+		{
+			description:    "resource ID in the subscription of the client should be valid",
+			subscriptionID: "subscription",
+			resourceID:     testResourceID,
+		},
+		{
+			description:    "subscription should be compared case-insensitively",
+			subscriptionID: "SUBSCRIPTION",
+			resourceID:     testResourceID,
+		},
+		{
+			description:    "subscription ID should be valid",
+			subscriptionID: "subscription",
+			resourceID:     "/subscriptions/subscription",
+		},
+		{
+			description:    "resource ID in another subscription should be invalid",
+			subscriptionID: "subscription",
+			resourceID:     "/subscriptions/other/resourceGroups/rg/providers/Microsoft.Network/publicIPAddresses/testPIP",
+			expectedErr:    "is in subscription other, which is different from the subscription subscription of the client",
+		},
+		{
+			description:    "resource ID without subscription should be invalid",
+			subscriptionID: "subscription",
+			resourceID:     "/resourceGroups/rg/providers/Microsoft.Network/publicIPAddresses/testPIP",
+			expectedErr:    "it should start with /subscriptions/<subscription ID>",
+		},
+		{
+			description: "any resource ID should be valid if the subscription of the client is not configured",
+			resourceID:  "/subscriptions/other/resourceGroups/rg/providers/Microsoft.Network/publicIPAddresses/testPIP",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.description, func(t *testing.T) {
+			armClient := New(nil, azureclients.ClientConfig{SubscriptionID: tc.subscriptionID}, "", "2019-01-01")
+			err := armClient.ValidateResourceID(tc.resourceID)
+			if tc.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestSendStrictSubscriptionValidation(t *testing.T) {
+	testcases := []struct {
+		description   string
+		strict        bool
+		resourceID    string
+		expectedCount int
+	}{
+		{
+			description:   "request in the subscription of the client should be sent in strict mode",
+			strict:        true,
+			resourceID:    testResourceID,
+			expectedCount: 1,
+		},
+		{
+			description: "request in another subscription should be rejected in strict mode",
+			strict:      true,
+			resourceID:  "/subscriptions/other/resourceGroups/rg/providers/Microsoft.Network/publicIPAddresses/testPIP",
+		},
+		{
+			description:   "request in another subscription should be sent if not in strict mode",
+			resourceID:    "/subscriptions/other/resourceGroups/rg/providers/Microsoft.Network/publicIPAddresses/testPIP",
+			expectedCount: 1,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.description, func(t *testing.T) {
+			count := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				count++
+			}))
+			defer server.Close()
+
+			azConfig := azureclients.ClientConfig{SubscriptionID: "subscription", StrictSubscriptionValidation: tc.strict}
+			armClient := New(nil, azConfig, server.URL, "2019-01-01")
+			_, rerr := armClient.GetResource(context.Background(), tc.resourceID)
+			assert.Equal(t, tc.expectedCount, count)
+			if tc.expectedCount == 0 {
+				assert.NotNil(t, rerr)
+				assert.False(t, rerr.Retriable)
+			} else {
+				assert.Nil(t, rerr)
+			}
+		})
+	}
+}
+
+func TestSendCrossTenant(t *testing.T) {
+	authorizer := autorest.NewAPIKeyAuthorizerWithHeaders(map[string]interface{}{"Authorization": "Bearer primary"})
+	multiTenantAuthorizer := autorest.NewAPIKeyAuthorizerWithHeaders(map[string]interface{}{
+		"Authorization":                "Bearer primary",
+		"x-ms-authorization-auxiliary": "Bearer auxiliary",
+	})
+
+	testcases := []struct {
+		description             string
+		subscriptionID          string
+		resourceID              string
+		expectedAuxiliary       string
+		noMultiTenantAuthorizer bool
+	}{
+		{
+			description:    "request in the subscription of the client should not carry the auxiliary token",
+			subscriptionID: "subscription",
+			resourceID:     testResourceID,
+		},
+		{
+			description:       "request in the network resource subscription should carry the auxiliary token",
+			subscriptionID:    "subscription",
+			resourceID:        "/subscriptions/networkSubscription/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet",
+			expectedAuxiliary: "Bearer auxiliary",
+		},
+		{
+			description:    "request in other subscriptions should not carry the auxiliary token",
+			subscriptionID: "subscription",
+			resourceID:     "/subscriptions/other/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet",
+		},
+		{
+			description:    "request of the client in the network resource subscription should not carry the auxiliary token",
+			subscriptionID: "networkSubscription",
+			resourceID:     "/subscriptions/networkSubscription/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet",
+		},
+		{
+			description:             "request should not carry the auxiliary token without the multi-tenant authorizer",
+			subscriptionID:          "subscription",
+			resourceID:              "/subscriptions/networkSubscription/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet",
+			noMultiTenantAuthorizer: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.description, func(t *testing.T) {
+			var header http.Header
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				header = r.Header
+			}))
+			defer server.Close()
+
+			azConfig := azureclients.ClientConfig{
+				SubscriptionID:                tc.subscriptionID,
+				NetworkResourceSubscriptionID: "networkSubscription",
+				MultiTenantAuthorizer:         multiTenantAuthorizer,
+			}
+			if tc.noMultiTenantAuthorizer {
+				azConfig.MultiTenantAuthorizer = nil
+			}
+			armClient := New(authorizer, azConfig, server.URL, "2019-01-01")
+			_, rerr := armClient.GetResource(context.Background(), tc.resourceID)
+			assert.Nil(t, rerr)
+			assert.Equal(t, "Bearer primary", header.Get("Authorization"))
+			assert.Equal(t, tc.expectedAuxiliary, header.Get("x-ms-authorization-auxiliary"))
+		})
+	}
+}
+
+type fakeTenantTokenProvider struct {
+	tokens    map[string]string
+	requested []string
+}
+
+func (p *fakeTenantTokenProvider) GetTokenProvider(tenantID string) (adal.OAuthTokenProvider, error) {
+	p.requested = append(p.requested, tenantID)
+	token, ok := p.tokens[tenantID]
+	if !ok {
+		return nil, fmt.Errorf("unknown tenant %s", tenantID)
+	}
+	return &adal.Token{AccessToken: token}, nil
+}
+
+func TestSendForTenant(t *testing.T) {
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+	}))
+	defer server.Close()
+
+	authorizer := autorest.NewAPIKeyAuthorizerWithHeaders(map[string]interface{}{"Authorization": "Bearer primary"})
+	tokenProvider := &fakeTenantTokenProvider{tokens: map[string]string{"tenant1": "token1", "tenant2": "token2"}}
+	azConfig := azureclients.ClientConfig{TenantTokenProvider: tokenProvider}
+	armClient := New(authorizer, azConfig, server.URL, "2019-01-01")
+
+	request, err := armClient.PrepareGetRequest(context.Background(), autorest.WithPath(testResourceID))
+	assert.NoError(t, err)
+	_, rerr := armClient.SendForTenant(context.Background(), "tenant2", request)
+	assert.Nil(t, rerr)
+	assert.Equal(t, []string{"tenant2"}, tokenProvider.requested)
+	assert.Equal(t, "Bearer token2", header.Get("Authorization"))
+	assert.Equal(t, "tenant2", header.Get("x-ms-client-tenant-id"))
+
+	// The requests sent by Send are still authorized by the primary authorizer.
+	_, rerr = armClient.GetResource(context.Background(), testResourceID)
+	assert.Nil(t, rerr)
+	assert.Equal(t, "Bearer primary", header.Get("Authorization"))
+	assert.Empty(t, header.Get("x-ms-client-tenant-id"))
+
+	request, err = armClient.PrepareGetRequest(context.Background(), autorest.WithPath(testResourceID))
+	assert.NoError(t, err)
+	_, rerr = armClient.SendForTenant(context.Background(), "unknown", request)
+	assert.NotNil(t, rerr)
+	assert.False(t, rerr.Retriable)
+	assert.Equal(t, []string{"tenant2", "unknown"}, tokenProvider.requested)
+
+	armClient = New(authorizer, azureclients.ClientConfig{}, server.URL, "2019-01-01")
+	_, rerr = armClient.SendForTenant(context.Background(), "tenant1", request)
+	assert.NotNil(t, rerr)
+	assert.False(t, rerr.Retriable)
+}
+
+func TestSendNoRetry(t *testing.T) {
+	testcases := []struct {
+		description string
+		statusCode  int
+		send        func(ctx context.Context, armClient *Client, request *http.Request) (*http.Response, *retry.Error)
+	}{
+		{
+			description: "SendNoRetry should send the throttled request only once",
+			statusCode:  http.StatusTooManyRequests,
+			send: func(ctx context.Context, armClient *Client, request *http.Request) (*http.Response, *retry.Error) {
+				return armClient.SendNoRetry(ctx, request)
+			},
+		},
+		{
+			description: "SendNoRetry should send the retriable request only once",
+			statusCode:  http.StatusInternalServerError,
+			send: func(ctx context.Context, armClient *Client, request *http.Request) (*http.Response, *retry.Error) {
+				return armClient.SendNoRetry(ctx, request)
+			},
+		},
+		{
+			description: "GetResource should send the request only once with a no retry context",
+			statusCode:  http.StatusInternalServerError,
+			send: func(ctx context.Context, armClient *Client, request *http.Request) (*http.Response, *retry.Error) {
+				return armClient.GetResource(retry.WithNoRetry(ctx), testResourceID)
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.description, func(t *testing.T) {
+			count := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				count++
+				http.Error(w, "failed", tc.statusCode)
+			}))
+			defer server.Close()
+
+			azConfig := azureclients.ClientConfig{Backoff: &retry.Backoff{Steps: 3}, UserAgent: "test", Location: "eastus"}
+			armClient := New(nil, azConfig, server.URL, "2019-01-01")
+			armClient.client.RetryDuration = time.Millisecond * 1
+
+			ctx := context.Background()
+			request, err := armClient.PrepareGetRequest(ctx, autorest.WithPath(testResourceID))
+			assert.NoError(t, err)
+
+			_, rerr := tc.send(ctx, armClient, request)
+			assert.NotNil(t, rerr)
+			assert.Equal(t, tc.statusCode, rerr.HTTPStatusCode)
+			assert.Equal(t, 1, count)
+		})
+	}
+}
+
 func TestSendFailureRegionalRetry(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		assert.Equal(t, "GET", r.Method)
@@ -139,6 +509,230 @@ func TestSendFailure(t *testing.T) {
 	assert.Equal(t, http.StatusInternalServerError, response.StatusCode)
 }
 
+func TestRecordRateLimitRemaining(t *testing.T) {
+	writesRemaining := int32(1199)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-ms-ratelimit-remaining-subscription-reads", "11999")
+		w.Header().Set("x-ms-ratelimit-remaining-subscription-writes", fmt.Sprint(atomic.AddInt32(&writesRemaining, -1)))
+		w.Header().Set("x-ms-ratelimit-remaining-resource", "Microsoft.Network/PutLoadBalancer3Min;20,Microsoft.Network/PutLoadBalancer30Min;150")
+		_, _ = w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	budget := azureclients.NewRateLimitBudget()
+	azConfig := azureclients.ClientConfig{Backoff: &retry.Backoff{Steps: 1}, UserAgent: "test", Location: "eastus", RateLimitBudget: budget}
+	armClient := New(nil, azConfig, server.URL, "2019-01-01")
+
+	response, rerr := armClient.GetResource(context.Background(), testResourceID)
+	assert.Nil(t, rerr)
+	armClient.CloseResponse(context.Background(), response)
+	response, rerr = armClient.GetResource(context.Background(), testResourceID)
+	assert.Nil(t, rerr)
+	armClient.CloseResponse(context.Background(), response)
+
+	remaining, ok := budget.Remaining(azureclients.RateLimitTypeReads)
+	assert.True(t, ok)
+	assert.Equal(t, 11999, remaining)
+	remaining, ok = budget.Remaining(azureclients.RateLimitTypeWrites)
+	assert.True(t, ok)
+	assert.Equal(t, 1197, remaining)
+	remaining, ok = budget.Remaining(azureclients.RateLimitTypeResource)
+	assert.True(t, ok)
+	assert.Equal(t, 20, remaining)
+}
+
+func TestSlowDownWritesOnLowRateLimitRemaining(t *testing.T) {
+	defer func(delay time.Duration) { lowRateLimitRemainingWriteDelay = delay }(lowRateLimitRemainingWriteDelay)
+	lowRateLimitRemainingWriteDelay = 200 * time.Millisecond
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-ms-ratelimit-remaining-subscription-writes", "5")
+		_, _ = w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	for _, slowDown := range []bool{false, true} {
+		azConfig := azureclients.ClientConfig{
+			Backoff:                               &retry.Backoff{Steps: 1},
+			UserAgent:                             "test",
+			Location:                              "eastus",
+			RateLimitRemainingWarningThreshold:    10,
+			SlowDownWritesOnLowRateLimitRemaining: slowDown,
+		}
+		armClient := New(nil, azConfig, server.URL, "2019-01-01")
+
+		// the first write is not delayed since the remaining writes are unknown
+		start := time.Now()
+		rerr := armClient.DeleteResource(context.Background(), testResourceID)
+		assert.Nil(t, rerr)
+		assert.Less(t, time.Since(start), lowRateLimitRemainingWriteDelay)
+
+		// the reads are never delayed
+		start = time.Now()
+		response, rerr := armClient.GetResource(context.Background(), testResourceID)
+		assert.Nil(t, rerr)
+		armClient.CloseResponse(context.Background(), response)
+		assert.Less(t, time.Since(start), lowRateLimitRemainingWriteDelay)
+
+		start = time.Now()
+		rerr = armClient.DeleteResource(context.Background(), testResourceID)
+		assert.Nil(t, rerr)
+		if slowDown {
+			assert.GreaterOrEqual(t, time.Since(start), lowRateLimitRemainingWriteDelay)
+		} else {
+			assert.Less(t, time.Since(start), lowRateLimitRemainingWriteDelay)
+		}
+	}
+}
+
+func TestSetBackoff(t *testing.T) {
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "failed", http.StatusInternalServerError)
+		count++
+	}))
+	defer server.Close()
+
+	azConfig := azureclients.ClientConfig{Backoff: &retry.Backoff{Steps: 3, NonRetriableErrors: []string{"NotActive"}}, UserAgent: "test", Location: "eastus"}
+	armClient := New(nil, azConfig, server.URL, "2019-01-01")
+	armClient.SetBackoff(&retry.Backoff{Steps: 2})
+	assert.Equal(t, retry.Backoff{Steps: 2, NonRetriableErrors: []string{"NotActive"}}, armClient.backoff)
+
+	ctx := context.Background()
+	request, err := armClient.PrepareGetRequest(ctx, autorest.WithPath("/subscriptions/testid/resourceGroups/testgroup"))
+	assert.NoError(t, err)
+	_, rerr := armClient.Send(ctx, request)
+	assert.NotNil(t, rerr)
+	assert.Equal(t, 2, count)
+
+	// no retries if the steps are not set
+	count = 0
+	armClient.SetBackoff(&retry.Backoff{})
+	request, err = armClient.PrepareGetRequest(ctx, autorest.WithPath("/subscriptions/testid/resourceGroups/testgroup"))
+	assert.NoError(t, err)
+	_, rerr = armClient.Send(ctx, request)
+	assert.NotNil(t, rerr)
+	assert.Equal(t, 1, count)
+}
+
+func TestSendRetryStats(t *testing.T) {
+	failures := 2
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		if count <= failures {
+			http.Error(w, "failed", http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte(`{"a": "b"}`))
+	}))
+	defer server.Close()
+
+	azConfig := azureclients.ClientConfig{Backoff: &retry.Backoff{Steps: 5}, UserAgent: "test", Location: "eastus"}
+	armClient := New(nil, azConfig, server.URL, "2019-01-01")
+
+	// the stats in the context are populated when the request succeeds eventually
+	stats := &retry.Stats{}
+	ctx := retry.WithStats(context.Background(), stats)
+	request, err := armClient.PrepareGetRequest(ctx, autorest.WithPath(testResourceID))
+	assert.NoError(t, err)
+	response, rerr := armClient.Send(ctx, request)
+	assert.Nil(t, rerr)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	retries, _ := stats.Get()
+	assert.Equal(t, failures, retries)
+
+	// the error carries the retries when the retries are exhausted
+	count = 0
+	failures = 5
+	request, err = armClient.PrepareGetRequest(context.Background(), autorest.WithPath(testResourceID))
+	assert.NoError(t, err)
+	_, rerr = armClient.Send(context.Background(), request)
+	assert.NotNil(t, rerr)
+	assert.Equal(t, 5, count)
+	assert.Equal(t, 4, rerr.Retries)
+}
+
+func TestSendWithRequestMetricsHook(t *testing.T) {
+	var headers http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header.Clone()
+		_, _ = w.Write([]byte(`{"a": "b"}`))
+	}))
+	defer server.Close()
+
+	azConfig := azureclients.ClientConfig{Backoff: &retry.Backoff{Steps: 1}, UserAgent: "test", Location: "eastus"}
+	armClient := New(nil, azConfig, server.URL, "2019-01-01")
+	var observed []RequestMetrics
+	armClient.SetRequestMetricsHook(func(m RequestMetrics) {
+		observed = append(observed, m)
+	})
+
+	ctx := metrics.WithSource(context.Background(), metrics.SourceServiceController)
+	ctx = metrics.WithAttributionTags(ctx, map[string]string{"operation": "ensure_loadbalancer"})
+	request, err := armClient.PrepareGetRequest(ctx, autorest.WithPath(testResourceID))
+	assert.NoError(t, err)
+	response, rerr := armClient.Send(ctx, request)
+	assert.Nil(t, rerr)
+	armClient.CloseResponse(ctx, response)
+
+	assert.Len(t, observed, 1)
+	assert.Equal(t, http.MethodGet, observed[0].Method)
+	assert.Equal(t, testResourceID, observed[0].Path)
+	assert.Equal(t, http.StatusOK, observed[0].StatusCode)
+	assert.Equal(t, metrics.SourceServiceController, observed[0].SourceController)
+	assert.Equal(t, map[string]string{"operation": "ensure_loadbalancer"}, observed[0].Tags)
+	assert.Greater(t, observed[0].Latency, time.Duration(0))
+	for key, values := range headers {
+		for _, value := range values {
+			assert.NotContains(t, value, "ensure_loadbalancer", "the attribution tags should not be sent in header %s", key)
+		}
+	}
+
+	// no metrics are observed after the hook is removed
+	armClient.SetRequestMetricsHook(nil)
+	request, err = armClient.PrepareGetRequest(ctx, autorest.WithPath(testResourceID))
+	assert.NoError(t, err)
+	response, rerr = armClient.Send(ctx, request)
+	assert.Nil(t, rerr)
+	armClient.CloseResponse(ctx, response)
+	assert.Len(t, observed, 1)
+}
+
+func TestSendPerTryTimeout(t *testing.T) {
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	azConfig := azureclients.ClientConfig{
+		Backoff: &retry.Backoff{
+			Duration:       10 * time.Millisecond,
+			Factor:         1.0,
+			Steps:          100,
+			PerTryTimeout:  50 * time.Millisecond,
+			OverallTimeout: 500 * time.Millisecond,
+		},
+		UserAgent: "test",
+		Location:  "eastus",
+	}
+	armClient := New(nil, azConfig, server.URL, "2019-01-01")
+
+	start := time.Now()
+	_, rerr := armClient.GetResource(context.Background(), testResourceID)
+	assert.Less(t, time.Since(start), 3*time.Second)
+	assert.NotNil(t, rerr)
+	assert.ErrorIs(t, rerr.Error(), context.DeadlineExceeded)
+	assert.Contains(t, rerr.Error().Error(), "overall timeout 500ms")
+	assert.Greater(t, atomic.LoadInt32(&count), int32(1), "the slow attempts should be cancelled and retried")
+	assert.Greater(t, rerr.Retries, 0)
+}
+
 func TestSendThrottled(t *testing.T) {
 	count := 0
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -298,6 +892,27 @@ func TestGetResource(t *testing.T) {
 				return armClient.GetResourceWithExpandQuery(ctx, testResourceID, "data")
 			},
 		},
+		{
+			description:         "GetResourceWithExpands",
+			expectedURIResource: "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/publicIPAddresses/testPIP?%24expand=instanceView%2CuserData%2Cdata+with+space&api-version=2019-01-01&param1=value1",
+			apiVersion:          "2019-01-01",
+			expectedAPIVersion:  "2019-01-01",
+			params: map[string]interface{}{
+				"param1": "value1",
+			},
+			getResource: func(ctx context.Context, armClient *Client, apiVersion string, params map[string]interface{}) (*http.Response, *retry.Error) {
+				return armClient.GetResourceWithExpands(ctx, testResourceID, []string{"instanceView", "", " userData ", "data with space"}, autorest.WithQueryParameters(params))
+			},
+		},
+		{
+			description:         "GetResourceWithExpands-no-expand",
+			expectedURIResource: "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/publicIPAddresses/testPIP?api-version=2019-01-01",
+			apiVersion:          "2019-01-01",
+			expectedAPIVersion:  "2019-01-01",
+			getResource: func(ctx context.Context, armClient *Client, apiVersion string, params map[string]interface{}) (*http.Response, *retry.Error) {
+				return armClient.GetResourceWithExpands(ctx, testResourceID, []string{""})
+			},
+		},
 		{
 			description:         "GetResourceWithExpandAPIVersionQuery",
 			expectedURIResource: "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/publicIPAddresses/testPIP?%24expand=data&api-version=2019-01-01",
@@ -345,19 +960,549 @@ func TestGetResource(t *testing.T) {
 	}
 }
 
-func TestPutResource(t *testing.T) {
-	handlers := []func(http.ResponseWriter, *http.Request){
-		func(rw http.ResponseWriter, req *http.Request) {
-			assert.Equal(t, "PUT", req.Method)
-			assert.Equal(t, expectedURI, req.URL.String())
-			rw.Header().Set("Azure-AsyncOperation",
-				fmt.Sprintf("http://%s%s", req.Host, operationURI))
-			rw.WriteHeader(http.StatusCreated)
-		},
-
-		func(rw http.ResponseWriter, req *http.Request) {
-			assert.Equal(t, "GET", req.Method)
-			assert.Equal(t, operationURI, req.URL.String())
+func TestGetResourceByParts(t *testing.T) {
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/lb%2Fname%20with%3Fspecial%23chars", r.URL.EscapedPath())
+		assert.Equal(t, "2019-01-01", r.URL.Query().Get("api-version"))
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("{data: testLB}"))
+		count++
+	}))
+	defer server.Close()
+
+	azConfig := azureclients.ClientConfig{Backoff: &retry.Backoff{Steps: 1}, UserAgent: "test", Location: "eastus", SubscriptionID: "subscription"}
+	armClient := New(nil, azConfig, server.URL, "2019-01-01")
+	armClient.client.RetryDuration = time.Millisecond * 1
+
+	response, rerr := armClient.GetResourceByParts(context.Background(), "rg", "Microsoft.Network", "loadBalancers", "lb/name with?special#chars")
+	assert.Nil(t, rerr)
+	assert.NotNil(t, response)
+	byteResponseBody, _ := ioutil.ReadAll(response.Body)
+	assert.Equal(t, "{data: testLB}", string(byteResponseBody))
+	assert.Equal(t, 1, count)
+}
+
+func TestGetResourceNoContent(t *testing.T) {
+	testcases := []struct {
+		description string
+		getResource func(ctx context.Context, armClient *Client) (*http.Response, *retry.Error)
+	}{
+		{
+			description: "GetResource",
+			getResource: func(ctx context.Context, armClient *Client) (*http.Response, *retry.Error) {
+				return armClient.GetResource(ctx, testResourceID)
+			},
+		},
+		{
+			description: "GetResourceWithExpandQuery",
+			getResource: func(ctx context.Context, armClient *Client) (*http.Response, *retry.Error) {
+				return armClient.GetResourceWithExpandQuery(ctx, testResourceID, "data")
+			},
+		},
+		{
+			description: "GetResourceWithExpandAPIVersionQuery",
+			getResource: func(ctx context.Context, armClient *Client) (*http.Response, *retry.Error) {
+				return armClient.GetResourceWithExpandAPIVersionQuery(ctx, testResourceID, "data", "2019-01-01")
+			},
+		},
+		{
+			description: "GetResourceWithMetadata",
+			getResource: func(ctx context.Context, armClient *Client) (*http.Response, *retry.Error) {
+				response, _, rerr := armClient.GetResourceWithMetadata(ctx, testResourceID)
+				return response, rerr
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.description, func(t *testing.T) {
+			count := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "GET", r.Method)
+				w.WriteHeader(http.StatusNoContent)
+				count++
+			}))
+			defer server.Close()
+
+			azConfig := azureclients.ClientConfig{Backoff: &retry.Backoff{Steps: 3}, UserAgent: "test", Location: "eastus"}
+			armClient := New(nil, azConfig, server.URL, "2019-01-01")
+			armClient.client.RetryDuration = time.Millisecond * 1
+
+			response, rerr := tc.getResource(context.Background(), armClient)
+			assert.Nil(t, rerr)
+			assert.NotNil(t, response)
+			assert.Equal(t, http.StatusNoContent, response.StatusCode)
+			assert.True(t, IsNoContent(response))
+			body, err := ioutil.ReadAll(response.Body)
+			assert.NoError(t, err)
+			assert.Empty(t, body)
+			assert.Equal(t, 1, count, "the request should not be retried")
+		})
+	}
+}
+
+func TestSendNoContentWithoutBody(t *testing.T) {
+	azConfig := azureclients.ClientConfig{Backoff: &retry.Backoff{Steps: 1}, UserAgent: "test", Location: "eastus"}
+	armClient := New(nil, azConfig, "http://localhost", "2019-01-01")
+	armClient.client.Sender = autorest.SenderFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusNoContent, Request: r}, nil
+	})
+
+	response, rerr := armClient.GetResourceWithExpandQuery(context.Background(), testResourceID, "data")
+	assert.Nil(t, rerr)
+	assert.True(t, IsNoContent(response))
+	body, err := ioutil.ReadAll(response.Body)
+	assert.NoError(t, err)
+	assert.Empty(t, body)
+}
+
+func TestGetResourceWithMetadata(t *testing.T) {
+	lastModified := time.Date(2022, time.June, 1, 8, 30, 0, 0, time.UTC)
+	testcases := []struct {
+		description      string
+		headers          map[string]string
+		apiVersion       string
+		expectedMetadata ResourceMetadata
+	}{
+		{
+			description: "GetResourceWithMetadata should populate the metadata from the response headers",
+			headers: map[string]string{
+				"ETag":          "W/\"etag1\"",
+				"Last-Modified": lastModified.Format(http.TimeFormat),
+			},
+			expectedMetadata: ResourceMetadata{ETag: "W/\"etag1\"", LastModified: lastModified, APIVersion: "2019-01-01"},
+		},
+		{
+			description:      "GetResourceWithMetadata should ignore the invalid Last-Modified header",
+			headers:          map[string]string{"Last-Modified": "invalid"},
+			expectedMetadata: ResourceMetadata{APIVersion: "2019-01-01"},
+		},
+		{
+			description:      "GetResourceWithMetadata should return the API version used in the request",
+			apiVersion:       "2020-01-01",
+			expectedMetadata: ResourceMetadata{APIVersion: "2020-01-01"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.description, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "GET", r.Method)
+				for k, v := range tc.headers {
+					w.Header().Set(k, v)
+				}
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte("{data: testPIP}"))
+			}))
+			defer server.Close()
+
+			azConfig := azureclients.ClientConfig{Backoff: &retry.Backoff{Steps: 1}, UserAgent: "test", Location: "eastus"}
+			armClient := New(nil, azConfig, server.URL, "2019-01-01")
+			armClient.client.RetryDuration = time.Millisecond * 1
+
+			var decorators []autorest.PrepareDecorator
+			if tc.apiVersion != "" {
+				decorators = append(decorators, autorest.WithQueryParameters(map[string]interface{}{"api-version": tc.apiVersion}))
+			}
+			response, metadata, rerr := armClient.GetResourceWithMetadata(context.Background(), testResourceID, decorators...)
+			assert.Nil(t, rerr)
+			assert.NotNil(t, response)
+			assert.Equal(t, tc.expectedMetadata, metadata)
+		})
+	}
+}
+
+func TestListResourcesChangedSince(t *testing.T) {
+	since := time.Date(2022, 6, 1, 8, 0, 0, 0, time.FixedZone("UTC+8", 8*60*60))
+	count := 0
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "/subscriptions/subscription/resourceGroups/rg/resources", r.URL.Path)
+		assert.Equal(t, "2021-04-01", r.URL.Query().Get("api-version"))
+		count++
+		if r.URL.Query().Get("page") == "2" {
+			_, _ = w.Write([]byte(`{"value":[{"name":"pip2","changedTime":"2022-06-01T02:00:00Z"}]}`))
+			return
+		}
+		assert.Equal(t, "changedTime ge '2022-06-01T00:00:00Z'", r.URL.Query().Get("$filter"))
+		assert.Equal(t, "changedTime", r.URL.Query().Get("$expand"))
+		_, _ = w.Write([]byte(fmt.Sprintf(`{"value":[{"name":"pip1","changedTime":"2022-06-01T01:00:00Z"}],"nextLink":"%s%s"}`,
+			server.URL, "/subscriptions/subscription/resourceGroups/rg/resources?api-version=2021-04-01&page=2")))
+	}))
+	defer server.Close()
+
+	azConfig := azureclients.ClientConfig{Backoff: &retry.Backoff{Steps: 1}, UserAgent: "test", Location: "eastus"}
+	armClient := New(nil, azConfig, server.URL, "2019-01-01")
+	armClient.client.RetryDuration = time.Millisecond * 1
+
+	resources, rerr := armClient.ListResourcesChangedSince(context.Background(), "/subscriptions/subscription/resourceGroups/rg", since)
+	assert.Nil(t, rerr)
+	assert.Equal(t, 2, count)
+	assert.Len(t, resources, 2)
+	assert.JSONEq(t, `{"name":"pip1","changedTime":"2022-06-01T01:00:00Z"}`, string(resources[0]))
+	assert.JSONEq(t, `{"name":"pip2","changedTime":"2022-06-01T02:00:00Z"}`, string(resources[1]))
+
+	_, rerr = armClient.ListResourcesChangedSince(context.Background(), testResourceID, since)
+	assert.NotNil(t, rerr)
+	assert.Contains(t, rerr.Error().Error(), "is not supported")
+	assert.Equal(t, 2, count, "the unsupported request should not be sent")
+}
+
+func TestStreamListResources(t *testing.T) {
+	count := 0
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/networkInterfaces", r.URL.Path)
+		count++
+		if r.URL.Query().Get("page") == "2" {
+			_, _ = w.Write([]byte(`{"value":[{"name":"nic3"}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(fmt.Sprintf(`{"value":[{"name":"nic1"},{"name":"nic2","properties":{"ipConfigurations":[]}}],"unknown":{"key":["value"]},"nextLink":"%s%s"}`,
+			server.URL, "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/networkInterfaces?api-version=2019-01-01&page=2")))
+	}))
+	defer server.Close()
+
+	azConfig := azureclients.ClientConfig{Backoff: &retry.Backoff{Steps: 1}, UserAgent: "test", Location: "eastus"}
+	armClient := New(nil, azConfig, server.URL, "2019-01-01")
+	armClient.client.RetryDuration = time.Millisecond * 1
+	resourceID := GetResourceListID("subscription", "rg", "Microsoft.Network/networkInterfaces")
+
+	var resources []string
+	rerr := armClient.StreamListResources(context.Background(), resourceID, func(item json.RawMessage) error {
+		resources = append(resources, string(item))
+		return nil
+	})
+	assert.Nil(t, rerr)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, []string{`{"name":"nic1"}`, `{"name":"nic2","properties":{"ipConfigurations":[]}}`, `{"name":"nic3"}`}, resources)
+
+	resources = nil
+	rerr = armClient.StreamListResources(context.Background(), resourceID, func(item json.RawMessage) error {
+		resources = append(resources, string(item))
+		return fmt.Errorf("stop")
+	})
+	assert.NotNil(t, rerr)
+	assert.Equal(t, "stop", rerr.RawError.Error())
+	assert.Len(t, resources, 1, "listing should stop once the callback returns an error")
+	assert.Equal(t, 3, count, "the next page should not be requested")
+}
+
+func TestStreamListResourcesMemory(t *testing.T) {
+	const (
+		items     = 100000
+		padding   = 400
+		maxGrowth = 16 << 20
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pad := strings.Repeat("x", padding)
+		_, _ = w.Write([]byte(`{"value":[`))
+		for i := 0; i < items; i++ {
+			if i > 0 {
+				_, _ = w.Write([]byte(","))
+			}
+			_, _ = fmt.Fprintf(w, `{"name":"nic%d","padding":"%s"}`, i, pad)
+		}
+		_, _ = w.Write([]byte(`]}`))
+	}))
+	defer server.Close()
+
+	azConfig := azureclients.ClientConfig{Backoff: &retry.Backoff{Steps: 1}, UserAgent: "test", Location: "eastus"}
+	armClient := New(nil, azConfig, server.URL, "2019-01-01")
+	armClient.client.RetryDuration = time.Millisecond * 1
+
+	var stats runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&stats)
+	baseline := stats.HeapAlloc
+	var peak uint64
+	count := 0
+	rerr := armClient.StreamListResources(context.Background(), "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/networkInterfaces", func(item json.RawMessage) error {
+		count++
+		if count%1000 == 0 {
+			runtime.ReadMemStats(&stats)
+			if stats.HeapAlloc > peak {
+				peak = stats.HeapAlloc
+			}
+		}
+		return nil
+	})
+	assert.Nil(t, rerr)
+	assert.Equal(t, items, count)
+	// The response is about 40MB, which would be buffered as a whole by decoding the page at once.
+	assert.Less(t, peak, baseline+maxGrowth, "the memory should not grow with the number of the resources")
+}
+
+func TestGetQuotaUsage(t *testing.T) {
+	count := 0
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "/subscriptions/subscription/providers/Microsoft.Compute/locations/eastus/usages", r.URL.Path)
+		assert.Equal(t, "2019-01-01", r.URL.Query().Get("api-version"))
+		count++
+		if r.URL.Query().Get("page") == "2" {
+			_, _ = w.Write([]byte(`{"value":[{"unit":"Count","currentValue":120,"limit":100,"name":{"value":"standardDSv3Family","localizedValue":"Standard DSv3 Family vCPUs"}}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(fmt.Sprintf(`{"value":[{"unit":"Count","currentValue":8,"limit":350,"name":{"value":"cores","localizedValue":"Total Regional vCPUs"}}],"nextLink":"%s%s"}`,
+			server.URL, "/subscriptions/subscription/providers/Microsoft.Compute/locations/eastus/usages?api-version=2019-01-01&page=2")))
+	}))
+	defer server.Close()
+
+	azConfig := azureclients.ClientConfig{Backoff: &retry.Backoff{Steps: 1}, UserAgent: "test", Location: "eastus", SubscriptionID: "subscription"}
+	armClient := New(nil, azConfig, server.URL, "2019-01-01")
+	armClient.client.RetryDuration = time.Millisecond * 1
+
+	usages, rerr := armClient.GetQuotaUsage(context.Background(), "eastus", "Microsoft.Compute")
+	assert.Nil(t, rerr)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, []QuotaUsage{
+		{
+			Name:          "cores",
+			LocalizedName: "Total Regional vCPUs",
+			Unit:          "Count",
+			CurrentValue:  8,
+			Limit:         350,
+		},
+		{
+			Name:          "standardDSv3Family",
+			LocalizedName: "Standard DSv3 Family vCPUs",
+			Unit:          "Count",
+			CurrentValue:  120,
+			Limit:         100,
+		},
+	}, usages)
+	assert.Equal(t, int64(342), usages[0].Remaining())
+	assert.Equal(t, int64(0), usages[1].Remaining(), "the remaining quota should not be negative")
+
+	_, rerr = armClient.GetQuotaUsage(context.Background(), "", "Microsoft.Compute")
+	assert.NotNil(t, rerr)
+	assert.Equal(t, 2, count, "the invalid request should not be sent")
+}
+
+func TestGetProviderRegistrationState(t *testing.T) {
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "/subscriptions/subscription/providers/Microsoft.Network", r.URL.Path)
+		assert.Equal(t, providersAPIVersion, r.URL.Query().Get("api-version"))
+		count++
+		_, _ = w.Write([]byte(`{"id":"/subscriptions/subscription/providers/Microsoft.Network","namespace":"Microsoft.Network","registrationState":"NotRegistered","resourceTypes":[]}`))
+	}))
+	defer server.Close()
+
+	azConfig := azureclients.ClientConfig{Backoff: &retry.Backoff{Steps: 1}, UserAgent: "test", Location: "eastus", SubscriptionID: "subscription"}
+	armClient := New(nil, azConfig, server.URL, "2019-01-01")
+	armClient.client.RetryDuration = time.Millisecond * 1
+
+	state, rerr := armClient.GetProviderRegistrationState(context.Background(), "Microsoft.Network")
+	assert.Nil(t, rerr)
+	assert.Equal(t, ProviderRegistrationStateNotRegistered, state)
+	assert.Equal(t, 1, count)
+
+	_, rerr = armClient.GetProviderRegistrationState(context.Background(), "")
+	assert.NotNil(t, rerr)
+	assert.Equal(t, 1, count, "the invalid request should not be sent")
+}
+
+func TestEnsureProviderRegistered(t *testing.T) {
+	testcases := []struct {
+		description      string
+		states           []string
+		timeout          time.Duration
+		expectedRegister bool
+		expectedErr      bool
+	}{
+		{
+			description: "EnsureProviderRegistered should not register the registered resource provider",
+			states:      []string{"Registered"},
+			timeout:     time.Minute,
+		},
+		{
+			description:      "EnsureProviderRegistered should register the resource provider and wait until it is registered",
+			states:           []string{"NotRegistered", "Registering", "Registering", "Registered"},
+			timeout:          time.Minute,
+			expectedRegister: true,
+		},
+		{
+			description:      "EnsureProviderRegistered should return the error after timeout",
+			states:           []string{"NotRegistered", "Registering"},
+			timeout:          50 * time.Millisecond,
+			expectedRegister: true,
+			expectedErr:      true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.description, func(t *testing.T) {
+			count := 0
+			registered := false
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, providersAPIVersion, r.URL.Query().Get("api-version"))
+				if r.Method == "POST" {
+					assert.Equal(t, "/subscriptions/subscription/providers/Microsoft.Network/register", r.URL.Path)
+					registered = true
+					_, _ = w.Write([]byte(`{"namespace":"Microsoft.Network","registrationState":"Registering"}`))
+					return
+				}
+
+				assert.Equal(t, "/subscriptions/subscription/providers/Microsoft.Network", r.URL.Path)
+				state := tc.states[len(tc.states)-1]
+				if count < len(tc.states) {
+					state = tc.states[count]
+				}
+				count++
+				_, _ = w.Write([]byte(fmt.Sprintf(`{"namespace":"Microsoft.Network","registrationState":"%s"}`, state)))
+			}))
+			defer server.Close()
+
+			azConfig := azureclients.ClientConfig{Backoff: &retry.Backoff{Steps: 1}, UserAgent: "test", Location: "eastus", SubscriptionID: "subscription"}
+			armClient := New(nil, azConfig, server.URL, "2019-01-01")
+			armClient.client.RetryDuration = time.Millisecond * 1
+			armClient.client.PollingDelay = time.Millisecond * 10
+
+			rerr := armClient.EnsureProviderRegistered(context.Background(), "Microsoft.Network", tc.timeout)
+			assert.Equal(t, tc.expectedErr, rerr != nil)
+			assert.Equal(t, tc.expectedRegister, registered)
+			if !tc.expectedErr {
+				assert.Equal(t, len(tc.states), count)
+			}
+		})
+	}
+}
+
+func TestWaitForProvisioningState(t *testing.T) {
+	testcases := []struct {
+		description   string
+		states        []string
+		timeout       time.Duration
+		expectedCount int
+		expectedErr   bool
+	}{
+		{
+			description:   "WaitForProvisioningState should return when the desired state is reached",
+			states:        []string{"Updating", "Updating", "Succeeded"},
+			timeout:       time.Minute,
+			expectedCount: 3,
+		},
+		{
+			description:   "WaitForProvisioningState should return the error on the Failed state without waiting",
+			states:        []string{"Updating", "Failed", "Succeeded"},
+			timeout:       time.Minute,
+			expectedCount: 2,
+			expectedErr:   true,
+		},
+		{
+			description:   "WaitForProvisioningState should return the error after timeout",
+			states:        []string{"Updating"},
+			timeout:       50 * time.Millisecond,
+			expectedErr:   true,
+			expectedCount: -1,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.description, func(t *testing.T) {
+			count := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "GET", r.Method)
+				state := tc.states[len(tc.states)-1]
+				if count < len(tc.states) {
+					state = tc.states[count]
+				}
+				count++
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte(fmt.Sprintf(`{"properties":{"provisioningState":"%s"}}`, state)))
+			}))
+			defer server.Close()
+
+			azConfig := azureclients.ClientConfig{Backoff: &retry.Backoff{Steps: 1}, UserAgent: "test", Location: "eastus"}
+			armClient := New(nil, azConfig, server.URL, "2019-01-01")
+			armClient.client.RetryDuration = time.Millisecond * 1
+			armClient.client.PollingDelay = time.Millisecond * 10
+
+			rerr := armClient.WaitForProvisioningState(context.Background(), testResourceID, "Succeeded", tc.timeout)
+			assert.Equal(t, tc.expectedErr, rerr != nil)
+			if tc.expectedCount >= 0 {
+				assert.Equal(t, tc.expectedCount, count)
+			}
+		})
+	}
+}
+
+func TestGetAsyncOperationStatus(t *testing.T) {
+	testcases := []struct {
+		description    string
+		body           string
+		expectedStatus string
+		expectedErr    bool
+	}{
+		{
+			description:    "GetAsyncOperationStatus should return InProgress status",
+			body:           `{"status":"InProgress"}`,
+			expectedStatus: "InProgress",
+		},
+		{
+			description:    "GetAsyncOperationStatus should return Succeeded status",
+			body:           `{"status":"Succeeded"}`,
+			expectedStatus: "Succeeded",
+		},
+		{
+			description:    "GetAsyncOperationStatus should return Failed status",
+			body:           `{"status":"Failed","error":{"code":"InternalError","message":"internal error"}}`,
+			expectedStatus: "Failed",
+		},
+		{
+			description: "GetAsyncOperationStatus should return an error if the status is not found",
+			body:        `{}`,
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.description, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "GET", r.Method)
+				assert.Equal(t, "/operations/op?api-version=2019-01-01", r.URL.String())
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+
+			azConfig := azureclients.ClientConfig{Backoff: &retry.Backoff{Steps: 1}, UserAgent: "test", Location: "eastus"}
+			armClient := New(nil, azConfig, server.URL, "2019-01-01")
+			armClient.client.RetryDuration = time.Millisecond * 1
+
+			status, response, rerr := armClient.GetAsyncOperationStatus(context.Background(), server.URL+"/operations/op?api-version=2019-01-01")
+			assert.Equal(t, tc.expectedErr, rerr != nil)
+			assert.Equal(t, tc.expectedStatus, status)
+			if assert.NotNil(t, response) {
+				body, err := ioutil.ReadAll(response.Body)
+				assert.NoError(t, err)
+				assert.Equal(t, tc.body, string(body))
+			}
+		})
+	}
+}
+
+func TestPutResource(t *testing.T) {
+	handlers := []func(http.ResponseWriter, *http.Request){
+		func(rw http.ResponseWriter, req *http.Request) {
+			assert.Equal(t, "PUT", req.Method)
+			assert.Equal(t, expectedURI, req.URL.String())
+			rw.Header().Set("Azure-AsyncOperation",
+				fmt.Sprintf("http://%s%s", req.Host, operationURI))
+			rw.WriteHeader(http.StatusCreated)
+		},
+
+		func(rw http.ResponseWriter, req *http.Request) {
+			assert.Equal(t, "GET", req.Method)
+			assert.Equal(t, operationURI, req.URL.String())
 
 			rw.WriteHeader(http.StatusOK)
 			_, _ = rw.Write([]byte(`{"error":{"code":"InternalServerError"},"status":"Failed"}`))
@@ -385,6 +1530,169 @@ func TestPutResource(t *testing.T) {
 	assert.Equal(t, true, rerr.Retriable)
 }
 
+func TestPutAndPatchResourceWithAPIVersionQuery(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/publicIPAddresses/testPIP", req.URL.Path)
+		assert.Equal(t, "2022-07-02", req.URL.Query().Get("api-version"))
+		rw.WriteHeader(http.StatusOK)
+		_, _ = rw.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	azConfig := azureclients.ClientConfig{Backoff: &retry.Backoff{Steps: 1}, UserAgent: "test", Location: "eastus"}
+	armClient := New(nil, azConfig, server.URL, "2019-01-01")
+	armClient.client.RetryDuration = time.Millisecond * 1
+
+	decorator := autorest.WithQueryParameters(map[string]interface{}{"api-version": "2022-07-02"})
+	response, rerr := armClient.PutResource(context.Background(), testResourceID, nil, decorator)
+	assert.Nil(t, rerr)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	response, rerr = armClient.PatchResource(context.Background(), testResourceID, nil, decorator)
+	assert.Nil(t, rerr)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+}
+
+func TestPutResourceWithETag(t *testing.T) {
+	testcases := []struct {
+		description   string
+		currentETag   string
+		ifMatch       string
+		expectedCount int
+		expectedErr   bool
+	}{
+		{
+			description:   "PutResourceWithETag should put the resource if the ETag matches",
+			currentETag:   "etag1",
+			ifMatch:       "etag1",
+			expectedCount: 3,
+		},
+		{
+			description:   "PutResourceWithETag should return the precondition failed error if the ETag is stale",
+			currentETag:   "etag2",
+			ifMatch:       "etag1",
+			expectedCount: 1,
+			expectedErr:   true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.description, func(t *testing.T) {
+			count := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				count++
+				if r.Method == "GET" {
+					w.WriteHeader(http.StatusOK)
+					_, _ = w.Write([]byte(`{"status":"Succeeded"}`))
+					return
+				}
+
+				assert.Equal(t, "PUT", r.Method)
+				assert.Equal(t, tc.ifMatch, r.Header.Get("If-Match"))
+				if r.Header.Get("If-Match") != tc.currentETag {
+					w.WriteHeader(http.StatusPreconditionFailed)
+					_, _ = w.Write([]byte(`{"error":{"code":"PreconditionFailed"}}`))
+					return
+				}
+				w.Header().Set("Azure-AsyncOperation", fmt.Sprintf("http://%s%s", r.Host, operationURI))
+				w.WriteHeader(http.StatusCreated)
+			}))
+			defer server.Close()
+
+			azConfig := azureclients.ClientConfig{Backoff: &retry.Backoff{Steps: 1}, UserAgent: "test", Location: "eastus"}
+			armClient := New(nil, azConfig, server.URL, "2019-01-01")
+			armClient.client.RetryDuration = time.Millisecond * 1
+
+			response, rerr := armClient.PutResourceWithETag(context.Background(), testResourceID, nil, tc.ifMatch)
+			defer armClient.CloseResponse(context.Background(), response)
+			assert.Equal(t, tc.expectedCount, count)
+			assert.Equal(t, tc.expectedErr, rerr != nil)
+			if tc.expectedErr {
+				assert.True(t, rerr.IsPreconditionFailed())
+				assert.False(t, rerr.Retriable)
+			} else {
+				assert.Equal(t, http.StatusOK, response.StatusCode)
+			}
+		})
+	}
+}
+
+func TestPutResourceMinimal(t *testing.T) {
+	testcases := []struct {
+		description        string
+		statusCode         int
+		pollingHeader      string
+		responseBody       string
+		pollingBody        string
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			description:        "PutResourceMinimal should return the response without body if the preference is applied",
+			statusCode:         http.StatusNoContent,
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			description:        "PutResourceMinimal should return the full body if the preference is ignored",
+			statusCode:         http.StatusOK,
+			responseBody:       `{"name":"testPIP"}`,
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `{"name":"testPIP"}`,
+		},
+		{
+			description:        "PutResourceMinimal should not get the resource after the long running operation completes",
+			statusCode:         http.StatusCreated,
+			pollingHeader:      "Azure-AsyncOperation",
+			pollingBody:        `{"status":"Succeeded"}`,
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `{"status":"Succeeded"}`,
+		},
+		{
+			description:        "PutResourceMinimal should return the final polling response of the Location long running operation",
+			statusCode:         http.StatusAccepted,
+			pollingHeader:      autorest.HeaderLocation,
+			pollingBody:        `{"name":"testPIP"}`,
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `{"name":"testPIP"}`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.description, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == "GET" {
+					assert.Equal(t, operationURI, r.URL.RequestURI(), "the resource should not be got again")
+					_, _ = w.Write([]byte(tc.pollingBody))
+					return
+				}
+
+				assert.Equal(t, "PUT", r.Method)
+				assert.Equal(t, "return=minimal", r.Header.Get("Prefer"))
+				if tc.pollingHeader != "" {
+					w.Header().Set(tc.pollingHeader, fmt.Sprintf("http://%s%s", r.Host, operationURI))
+				}
+				w.WriteHeader(tc.statusCode)
+				_, _ = w.Write([]byte(tc.responseBody))
+			}))
+			defer server.Close()
+
+			azConfig := azureclients.ClientConfig{Backoff: &retry.Backoff{Steps: 1}, UserAgent: "test", Location: "eastus"}
+			armClient := New(nil, azConfig, server.URL, "2019-01-01")
+			armClient.client.RetryDuration = time.Millisecond * 1
+			armClient.client.PollingDelay = time.Millisecond
+
+			response, rerr := armClient.PutResourceMinimal(context.Background(), testResourceID, nil)
+			defer armClient.CloseResponse(context.Background(), response)
+			assert.Nil(t, rerr)
+			assert.Equal(t, tc.expectedStatusCode, response.StatusCode)
+
+			body, err := ioutil.ReadAll(response.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedBody, string(body))
+			assert.Empty(t, armClient.ListActiveAsyncOperations())
+		})
+	}
+}
+
 func TestResourceAction(t *testing.T) {
 	for _, tc := range []struct {
 		description string
@@ -475,6 +1783,148 @@ func TestResourceAction(t *testing.T) {
 	}
 }
 
+func TestDeleteResourceAsyncAndWait(t *testing.T) {
+	for _, tc := range []struct {
+		description    string
+		finalStatus    string
+		expectedPolls  int
+		expectedErrMsg string
+	}{
+		{
+			description:   "should wait until the async operation succeeds",
+			finalStatus:   `{"status":"Succeeded"}`,
+			expectedPolls: 3,
+		},
+		{
+			description:    "should return the error of the failed async operation",
+			finalStatus:    `{"error":{"code":"InternalServerError"},"status":"Failed"}`,
+			expectedPolls:  3,
+			expectedErrMsg: "InternalServerError",
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			polls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				if req.Method == http.MethodDelete {
+					assert.Equal(t, testResourceID, req.URL.Path)
+					rw.Header().Set("Azure-AsyncOperation", fmt.Sprintf("http://%s%s", req.Host, operationURI))
+					rw.WriteHeader(http.StatusAccepted)
+					return
+				}
+
+				assert.Equal(t, http.MethodGet, req.Method)
+				assert.Equal(t, operationURI, req.URL.String())
+				polls++
+				rw.WriteHeader(http.StatusOK)
+				if polls < tc.expectedPolls {
+					_, _ = rw.Write([]byte(`{"status":"InProgress"}`))
+					return
+				}
+				_, _ = rw.Write([]byte(tc.finalStatus))
+			}))
+			defer server.Close()
+
+			azConfig := azureclients.ClientConfig{Backoff: &retry.Backoff{Steps: 1}, UserAgent: "test", Location: "eastus"}
+			armClient := New(nil, azConfig, server.URL, "2019-01-01")
+			armClient.client.PollingDelay = time.Millisecond
+
+			rerr := armClient.DeleteResourceAsyncAndWait(context.Background(), testResourceID)
+			assert.Equal(t, tc.expectedPolls, polls)
+			if tc.expectedErrMsg == "" {
+				assert.Nil(t, rerr)
+				return
+			}
+			assert.NotNil(t, rerr)
+			assert.Contains(t, rerr.Error().Error(), tc.expectedErrMsg)
+		})
+	}
+}
+
+func TestListActiveAsyncOperations(t *testing.T) {
+	var completed int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodDelete {
+			rw.Header().Set("Azure-AsyncOperation", fmt.Sprintf("http://%s%s", req.Host, operationURI))
+			rw.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		rw.WriteHeader(http.StatusOK)
+		if atomic.LoadInt32(&completed) == 0 {
+			_, _ = rw.Write([]byte(`{"status":"InProgress"}`))
+			return
+		}
+		_, _ = rw.Write([]byte(`{"status":"Succeeded"}`))
+	}))
+	defer server.Close()
+
+	azConfig := azureclients.ClientConfig{Backoff: &retry.Backoff{Steps: 1}, UserAgent: "test", Location: "eastus"}
+	armClient := New(nil, azConfig, server.URL, "2019-01-01")
+	armClient.client.PollingDelay = time.Millisecond
+	assert.Empty(t, armClient.ListActiveAsyncOperations())
+
+	future, rerr := armClient.DeleteResourceAsync(context.Background(), testResourceID)
+	assert.Nil(t, rerr)
+	ops := armClient.ListActiveAsyncOperations()
+	assert.Len(t, ops, 1)
+	assert.Equal(t, testResourceID, ops[0].ResourceID)
+	assert.Equal(t, http.MethodDelete, ops[0].Method)
+	assert.Equal(t, azure.PollingAsyncOperation, ops[0].PollingMethod)
+	assert.Equal(t, server.URL+operationURI, ops[0].PollingURL)
+
+	// The operation is kept as in flight if the wait is interrupted by CancelAll.
+	armClient.CancelAll()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.Error(t, armClient.WaitForAsyncOperationCompletion(ctx, future, "test"))
+	assert.Len(t, armClient.ListActiveAsyncOperations(), 1)
+	armClient.Reset()
+
+	atomic.StoreInt32(&completed, 1)
+	assert.NoError(t, armClient.WaitForAsyncOperationCompletion(context.Background(), future, "test"))
+	assert.Empty(t, armClient.ListActiveAsyncOperations())
+}
+
+func TestListActiveAsyncOperationsWaitTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodDelete {
+			rw.Header().Set("Azure-AsyncOperation", fmt.Sprintf("http://%s%s", req.Host, operationURI))
+			rw.WriteHeader(http.StatusAccepted)
+			return
+		}
+		rw.WriteHeader(http.StatusOK)
+		_, _ = rw.Write([]byte(`{"status":"InProgress"}`))
+	}))
+	defer server.Close()
+
+	azConfig := azureclients.ClientConfig{Backoff: &retry.Backoff{Steps: 1}, UserAgent: "test", Location: "eastus"}
+	armClient := New(nil, azConfig, server.URL, "2019-01-01")
+	armClient.client.PollingDelay = time.Millisecond
+
+	// The operation is forgotten once the wait times out.
+	future, rerr := armClient.DeleteResourceAsync(context.Background(), testResourceID)
+	assert.Nil(t, rerr)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.Error(t, armClient.WaitForAsyncOperationCompletion(ctx, future, "test"))
+	assert.Empty(t, armClient.ListActiveAsyncOperations())
+
+	// The operations never waited are forgotten after activeAsyncOpMaxAge.
+	_, rerr = armClient.DeleteResourceAsync(context.Background(), testResourceID)
+	assert.Nil(t, rerr)
+	armClient.activeAsyncOpsLock.Lock()
+	for f, op := range armClient.activeAsyncOps {
+		op.StartTime = op.StartTime.Add(-activeAsyncOpMaxAge - time.Minute)
+		armClient.activeAsyncOps[f] = op
+	}
+	armClient.activeAsyncOpsLock.Unlock()
+	_, rerr = armClient.DeleteResourceAsync(context.Background(), testResourceID)
+	assert.Nil(t, rerr)
+	ops := armClient.ListActiveAsyncOperations()
+	assert.Len(t, ops, 1)
+	assert.WithinDuration(t, time.Now(), ops[0].StartTime, time.Minute)
+}
+
 func TestPatchResource(t *testing.T) {
 	handlers := []func(http.ResponseWriter, *http.Request){
 		func(rw http.ResponseWriter, req *http.Request) {
@@ -606,3 +2056,370 @@ func TestGetResourceID(t *testing.T) {
 		})
 	}
 }
+
+func TestContextCanceled(t *testing.T) {
+	for _, tc := range []struct {
+		description string
+		handler     http.HandlerFunc
+		action      func(ctx context.Context, armClient *Client) error
+	}{
+		{
+			description: "retry loop of Send",
+			handler: func(rw http.ResponseWriter, req *http.Request) {
+				http.Error(rw, "failed", http.StatusInternalServerError)
+			},
+			action: func(ctx context.Context, armClient *Client) error {
+				_, rerr := armClient.GetResource(ctx, testResourceID)
+				return rerr.Error()
+			},
+		},
+		{
+			description: "polling of PutResource",
+			handler: func(rw http.ResponseWriter, req *http.Request) {
+				if req.Method == "PUT" {
+					rw.Header().Set("Azure-AsyncOperation",
+						fmt.Sprintf("http://%s%s", req.Host, operationURI))
+					rw.WriteHeader(http.StatusCreated)
+					return
+				}
+				rw.WriteHeader(http.StatusOK)
+				_, _ = rw.Write([]byte(`{"status":"InProgress"}`))
+			},
+			action: func(ctx context.Context, armClient *Client) error {
+				_, rerr := armClient.PutResource(ctx, testResourceID, nil)
+				return rerr.Error()
+			},
+		},
+		{
+			description: "WaitForAsyncOperationCompletion",
+			handler: func(rw http.ResponseWriter, req *http.Request) {
+				if req.Method == "PUT" {
+					rw.Header().Set("Azure-AsyncOperation",
+						fmt.Sprintf("http://%s%s", req.Host, operationURI))
+					rw.WriteHeader(http.StatusCreated)
+					return
+				}
+				rw.WriteHeader(http.StatusOK)
+				_, _ = rw.Write([]byte(`{"status":"InProgress"}`))
+			},
+			action: func(ctx context.Context, armClient *Client) error {
+				future, rerr := armClient.PutResourceAsync(ctx, testResourceID, nil)
+				if rerr != nil {
+					return rerr.Error()
+				}
+				return armClient.WaitForAsyncOperationCompletion(ctx, future, "test")
+			},
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			server := httptest.NewServer(tc.handler)
+			defer server.Close()
+
+			azConfig := azureclients.ClientConfig{Backoff: &retry.Backoff{Steps: 10, Duration: time.Minute}, UserAgent: "test", Location: "eastus"}
+			armClient := New(nil, azConfig, server.URL, "2019-01-01")
+			armClient.client.PollingDelay = time.Minute
+
+			ctx, cancel := context.WithCancel(context.Background())
+			go func() {
+				time.Sleep(100 * time.Millisecond)
+				cancel()
+			}()
+
+			start := time.Now()
+			err := tc.action(ctx, armClient)
+			assert.Less(t, time.Since(start), 5*time.Second)
+			assert.ErrorIs(t, err, context.Canceled)
+			assert.False(t, retry.IsErrorRetriable(err))
+		})
+	}
+}
+
+func TestPutResourcesInBatchesContextCanceled(t *testing.T) {
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	azConfig := azureclients.ClientConfig{Backoff: &retry.Backoff{Steps: 1}, UserAgent: "test", Location: "eastus"}
+	armClient := New(nil, azConfig, server.URL, "2019-01-01")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	resources := map[string]interface{}{
+		testResourceID:       nil,
+		testResourceID + "2": nil,
+		testResourceID + "3": nil,
+	}
+	responses := armClient.PutResourcesInBatches(ctx, resources, 1)
+	assert.Equal(t, 0, count)
+	assert.Equal(t, len(resources), len(responses))
+	for resourceID, response := range responses {
+		assert.NotNil(t, response.Error, resourceID)
+		assert.False(t, response.Error.Retriable, resourceID)
+		assert.ErrorIs(t, response.Error.RawError, context.Canceled, resourceID)
+	}
+}
+
+func TestNewHTTPClientProtocol(t *testing.T) {
+	testcases := []struct {
+		description      string
+		forceHTTP1       bool
+		serverHTTP2      bool
+		expectedProtocol string
+	}{
+		{
+			description:      "HTTP/2 should be negotiated with a h2-capable server",
+			serverHTTP2:      true,
+			expectedProtocol: "HTTP/2.0",
+		},
+		{
+			description:      "HTTP/1.1 should be negotiated with a h2-capable server if HTTP/1.1 is forced",
+			forceHTTP1:       true,
+			serverHTTP2:      true,
+			expectedProtocol: "HTTP/1.1",
+		},
+		{
+			description:      "HTTP/1.1 should be negotiated with a h1-only server",
+			expectedProtocol: "HTTP/1.1",
+		},
+		{
+			description:      "HTTP/1.1 should be negotiated with a h1-only server if HTTP/1.1 is forced",
+			forceHTTP1:       true,
+			expectedProtocol: "HTTP/1.1",
+		},
+	}
+
+	for _, tc := range testcases {
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		server.EnableHTTP2 = tc.serverHTTP2
+		server.StartTLS()
+
+		client := newHTTPClient(tc.forceHTTP1, 0, nil)
+		transport, ok := client.Transport.(*http.Transport)
+		assert.True(t, ok, tc.description)
+		transport.TLSClientConfig.RootCAs = server.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+
+		resp, err := client.Get(server.URL)
+		assert.NoError(t, err, tc.description)
+		assert.Equal(t, tc.expectedProtocol, resp.Proto, tc.description)
+		resp.Body.Close()
+		server.Close()
+	}
+}
+
+// newTestCA creates a self-signed CA and a server certificate for 127.0.0.1 signed by it.
+func newTestCA(t *testing.T) (*x509.Certificate, tls.Certificate) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	assert.NoError(t, err)
+	caCert, err := x509.ParseCertificate(caDER)
+	assert.NoError(t, err)
+
+	serverKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	serverTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	serverDER, err := x509.CreateCertificate(rand.Reader, serverTemplate, caCert, &serverKey.PublicKey, caKey)
+	assert.NoError(t, err)
+
+	return caCert, tls.Certificate{Certificate: [][]byte{serverDER}, PrivateKey: serverKey}
+}
+
+func TestNewHTTPClientTLS(t *testing.T) {
+	caCert, serverCert := newTestCA(t)
+	otherCACert, _ := newTestCA(t)
+	pinnedCAs := x509.NewCertPool()
+	pinnedCAs.AddCert(caCert)
+	otherCAs := x509.NewCertPool()
+	otherCAs.AddCert(otherCACert)
+
+	testcases := []struct {
+		description      string
+		serverMaxVersion uint16
+		minTLSVersion    uint16
+		rootCAs          *x509.CertPool
+		expectErr        bool
+	}{
+		{
+			description: "the server certificate signed by the pinned CA should be accepted",
+			rootCAs:     pinnedCAs,
+		},
+		{
+			description: "the server certificate not signed by the pinned CA should be rejected",
+			rootCAs:     otherCAs,
+			expectErr:   true,
+		},
+		{
+			description:      "TLS 1.1 should be rejected by default",
+			serverMaxVersion: tls.VersionTLS11,
+			rootCAs:          pinnedCAs,
+			expectErr:        true,
+		},
+		{
+			description:      "TLS 1.2 should be rejected if the minimum version is TLS 1.3",
+			serverMaxVersion: tls.VersionTLS12,
+			minTLSVersion:    tls.VersionTLS13,
+			rootCAs:          pinnedCAs,
+			expectErr:        true,
+		},
+		{
+			description:      "TLS 1.3 should be accepted if the minimum version is TLS 1.3",
+			serverMaxVersion: tls.VersionTLS13,
+			minTLSVersion:    tls.VersionTLS13,
+			rootCAs:          pinnedCAs,
+		},
+	}
+
+	for _, tc := range testcases {
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		server.TLS = &tls.Config{
+			Certificates: []tls.Certificate{serverCert},
+			MinVersion:   tls.VersionTLS10,
+			MaxVersion:   tls.VersionTLS13,
+		}
+		if tc.serverMaxVersion != 0 {
+			server.TLS.MaxVersion = tc.serverMaxVersion
+		}
+		server.StartTLS()
+
+		client := newHTTPClient(false, tc.minTLSVersion, tc.rootCAs)
+		resp, err := client.Get(server.URL)
+		if tc.expectErr {
+			assert.Error(t, err, tc.description)
+		} else {
+			assert.NoError(t, err, tc.description)
+			assert.Equal(t, http.StatusOK, resp.StatusCode, tc.description)
+			resp.Body.Close()
+		}
+		server.Close()
+	}
+}
+
+func TestPutResourceWithLocationPolling(t *testing.T) {
+	const locationURI = "/operations/op"
+	testcases := []struct {
+		description      string
+		finalPollingCode int
+		expectedRequests []string
+		expectedErr      bool
+	}{
+		{
+			description:      "PutResource should follow the Location header and get the resource at the end",
+			finalPollingCode: http.StatusOK,
+			expectedRequests: []string{"PUT " + testResourceID, "GET " + locationURI, "GET " + locationURI, "GET " + locationURI, "GET " + testResourceID},
+		},
+		{
+			description:      "PutResource should return the error of the Location polling",
+			finalPollingCode: http.StatusConflict,
+			expectedRequests: []string{"PUT " + testResourceID, "GET " + locationURI, "GET " + locationURI, "GET " + locationURI},
+			expectedErr:      true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.description, func(t *testing.T) {
+			var requests []string
+			pollingCount := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, r.Method+" "+r.URL.Path)
+				switch {
+				case r.Method == http.MethodPut:
+					w.Header().Set("Location", fmt.Sprintf("http://%s%s", r.Host, locationURI))
+					w.WriteHeader(http.StatusCreated)
+				case r.URL.Path == locationURI:
+					pollingCount++
+					if pollingCount < 3 {
+						w.WriteHeader(http.StatusAccepted)
+						return
+					}
+					w.WriteHeader(tc.finalPollingCode)
+				default:
+					_, _ = w.Write([]byte(`{"name":"testPIP","properties":{"provisioningState":"Succeeded"}}`))
+				}
+			}))
+			defer server.Close()
+
+			azConfig := azureclients.ClientConfig{Backoff: &retry.Backoff{Steps: 1}, UserAgent: "test", Location: "eastus"}
+			armClient := New(nil, azConfig, server.URL, "2019-01-01")
+			armClient.client.RetryDuration = time.Millisecond * 1
+			armClient.client.PollingDelay = time.Millisecond * 10
+
+			response, rerr := armClient.PutResource(context.Background(), testResourceID, nil)
+			assert.Equal(t, tc.expectedRequests, requests)
+			if tc.expectedErr {
+				assert.NotNil(t, rerr)
+				return
+			}
+			assert.Nil(t, rerr)
+			body, err := ioutil.ReadAll(response.Body)
+			assert.NoError(t, err)
+			assert.Contains(t, string(body), `"provisioningState":"Succeeded"`)
+		})
+	}
+}
+
+func TestPutResourceWithRequestBodyValidator(t *testing.T) {
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			count++
+		}
+		_, _ = w.Write([]byte(`{"name":"testPIP","location":"eastus"}`))
+	}))
+	defer server.Close()
+
+	azConfig := azureclients.ClientConfig{Backoff: &retry.Backoff{Steps: 1}, UserAgent: "test", Location: "eastus"}
+	armClient := New(nil, azConfig, server.URL, "2019-01-01")
+	armClient.client.RetryDuration = time.Millisecond * 1
+	armClient.RegisterRequestBodyValidator("microsoft.network/publicIPAddresses", RequiredFieldsValidator("location", "properties.publicIPAllocationMethod"))
+
+	ctx := context.Background()
+	_, rerr := armClient.PutResource(ctx, testResourceID, map[string]interface{}{"location": "eastus"})
+	assert.NotNil(t, rerr)
+	assert.False(t, rerr.Retriable)
+	assert.Equal(t, fmt.Sprintf("the request body of resource %s is invalid: required fields [properties.publicIPAllocationMethod] are missing", testResourceID), rerr.RawError.Error())
+	assert.Equal(t, 0, count, "the invalid request should not be sent")
+
+	response, rerr := armClient.PutResource(ctx, testResourceID, map[string]interface{}{
+		"location":   "eastus",
+		"properties": map[string]interface{}{"publicIPAllocationMethod": "Static"},
+	})
+	assert.Nil(t, rerr)
+	armClient.CloseResponse(ctx, response)
+	assert.Equal(t, 1, count)
+
+	// the validators of the other resource types are not applied
+	_, rerr = armClient.PutResource(ctx, "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/lb", map[string]interface{}{})
+	assert.Nil(t, rerr)
+	assert.Equal(t, 2, count)
+
+	// the validator could be removed
+	armClient.RegisterRequestBodyValidator("Microsoft.Network/publicIPAddresses", nil)
+	_, rerr = armClient.PutResource(ctx, testResourceID, map[string]interface{}{"location": "eastus"})
+	assert.Nil(t, rerr)
+	assert.Equal(t, 3, count)
+}
@@ -18,7 +18,9 @@ package armclient
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
+	"time"
 
 	"github.com/Azure/go-autorest/autorest"
 	"github.com/Azure/go-autorest/autorest/azure"
@@ -38,6 +40,24 @@ type Interface interface {
 	// Send sends a http request to ARM service with possible retry to regional ARM endpoint.
 	Send(ctx context.Context, request *http.Request, decorators ...autorest.SendDecorator) (*http.Response, *retry.Error)
 
+	// SendForTenant sends a http request to ARM service in the tenant, which is authorized by the token scoped to the tenant.
+	SendForTenant(ctx context.Context, tenantID string, request *http.Request, decorators ...autorest.SendDecorator) (*http.Response, *retry.Error)
+
+	// SendNoRetry sends a http request to ARM service only once without any retries.
+	SendNoRetry(ctx context.Context, request *http.Request, decorators ...autorest.SendDecorator) (*http.Response, *retry.Error)
+
+	// ValidateResourceID returns an error if the resource ID is not in the subscription of the client.
+	ValidateResourceID(resourceID string) error
+
+	// CancelAll cancels all the in-flight requests, the subsequent requests fail fast until Reset is called.
+	CancelAll()
+
+	// Reset makes the client available again after CancelAll is called.
+	Reset()
+
+	// ListActiveAsyncOperations returns the async operations started by the client that are not waited to complete.
+	ListActiveAsyncOperations() []AsyncOpInfo
+
 	// PreparePutRequest prepares put request
 	PreparePutRequest(ctx context.Context, decorators ...autorest.PrepareDecorator) (*http.Request, error)
 
@@ -62,9 +82,21 @@ type Interface interface {
 	// SendAsync send a request and return a future object representing the async result as well as the origin http response
 	SendAsync(ctx context.Context, request *http.Request) (*azure.Future, *http.Response, *retry.Error)
 
+	// WaitForProvisioningState waits until the provisioning state of a resource is desiredState or Failed.
+	WaitForProvisioningState(ctx context.Context, resourceID, desiredState string, timeout time.Duration) *retry.Error
+
+	// GetAsyncOperationStatus gets the status of an async operation by its Azure-AsyncOperation URL
+	GetAsyncOperationStatus(ctx context.Context, asyncOpURL string) (status string, resp *http.Response, err *retry.Error)
+
 	// PutResource puts a resource by resource ID
 	PutResource(ctx context.Context, resourceID string, parameters interface{}, decorators ...autorest.PrepareDecorator) (*http.Response, *retry.Error)
 
+	// PutResourceMinimal puts a resource by resource ID without the resource echoed back in the response body
+	PutResourceMinimal(ctx context.Context, resourceID string, parameters interface{}, decorators ...autorest.PrepareDecorator) (*http.Response, *retry.Error)
+
+	// PutResourceWithETag puts a resource by resource ID if its ETag matches ifMatch
+	PutResourceWithETag(ctx context.Context, resourceID string, parameters interface{}, ifMatch string, decorators ...autorest.PrepareDecorator) (*http.Response, *retry.Error)
+
 	// PutResourceAsync puts a resource by resource ID in async mode
 	PutResourceAsync(ctx context.Context, resourceID string, parameters interface{}, decorators ...autorest.PrepareDecorator) (*azure.Future, *retry.Error)
 
@@ -80,15 +112,43 @@ type Interface interface {
 	// HeadResource heads a resource by resource ID
 	HeadResource(ctx context.Context, resourceID string) (*http.Response, *retry.Error)
 
-	// GetResourceWithExpandQuery get a resource by resource ID with expand
+	// GetResourceWithExpandQuery get a resource by resource ID with expand. The 204 No Content response is
+	// returned without errors, which could be detected by IsNoContent.
 	GetResourceWithExpandQuery(ctx context.Context, resourceID, expand string) (*http.Response, *retry.Error)
 
+	// GetResourceWithExpands get a resource with decorators by resource ID with multiple expand values, e.g. "instanceView" and "userData".
+	GetResourceWithExpands(ctx context.Context, resourceID string, expands []string, decorators ...autorest.PrepareDecorator) (*http.Response, *retry.Error)
+
 	// GetResourceWithExpandAPIVersionQuery get a resource by resource ID with expand and API version.
 	GetResourceWithExpandAPIVersionQuery(ctx context.Context, resourceID, expand, apiVersion string) (*http.Response, *retry.Error)
 
 	// GetResource get a resource with decorators by resource ID
 	GetResource(ctx context.Context, resourceID string, decorators ...autorest.PrepareDecorator) (*http.Response, *retry.Error)
 
+	// GetResourceByParts get a resource with decorators by the resource group, provider, resource type and name
+	// in the subscription of the client, e.g. "rg", "Microsoft.Network", "loadBalancers" and "lb".
+	GetResourceByParts(ctx context.Context, resourceGroup, provider, resourceType, name string, decorators ...autorest.PrepareDecorator) (*http.Response, *retry.Error)
+
+	// GetResourceWithMetadata get a resource with decorators by resource ID, together with the ETag,
+	// Last-Modified and API version of the resource
+	GetResourceWithMetadata(ctx context.Context, resourceID string, decorators ...autorest.PrepareDecorator) (*http.Response, ResourceMetadata, *retry.Error)
+
+	// ListResourcesChangedSince lists the resources in a subscription or resource group whose changedTime is after since.
+	ListResourcesChangedSince(ctx context.Context, resourceID string, since time.Time) ([]json.RawMessage, *retry.Error)
+
+	// StreamListResources lists the resources by the list resource ID and calls onItem with each resource decoded
+	// from the responses one by one, following the nextLink. Listing stops once onItem returns an error.
+	StreamListResources(ctx context.Context, resourceID string, onItem func(json.RawMessage) error) *retry.Error
+
+	// GetQuotaUsage lists the quota usages of the resource provider, e.g. "Microsoft.Compute", in the location.
+	GetQuotaUsage(ctx context.Context, location, provider string) ([]QuotaUsage, *retry.Error)
+
+	// GetProviderRegistrationState gets the registration state of the resource provider, e.g. "Microsoft.Network", in the subscription.
+	GetProviderRegistrationState(ctx context.Context, namespace string) (string, *retry.Error)
+
+	// EnsureProviderRegistered registers the resource provider in the subscription if it is not registered, and waits until it is registered.
+	EnsureProviderRegistered(ctx context.Context, namespace string, timeout time.Duration) *retry.Error
+
 	// PostResource posts a resource by resource ID
 	PostResource(ctx context.Context, resourceID, action string, parameters interface{}, queryParameters map[string]interface{}) (*http.Response, *retry.Error)
 
@@ -98,6 +158,21 @@ type Interface interface {
 	// DeleteResourceAsync delete a resource by resource ID and returns a future representing the async result
 	DeleteResourceAsync(ctx context.Context, resourceID string, decorators ...autorest.PrepareDecorator) (*azure.Future, *retry.Error)
 
+	// DeleteResourceAsyncAndWait deletes a resource by resource ID and waits for the completion of the async operation
+	DeleteResourceAsyncAndWait(ctx context.Context, resourceID string, decorators ...autorest.PrepareDecorator) *retry.Error
+
+	// RegisterRequestBodyValidator registers the validator of the request bodies to put the resources of the resource type,
+	// e.g. "Microsoft.Network/loadBalancers". PutResource returns a non-retriable error without sending the request if
+	// the validator fails.
+	RegisterRequestBodyValidator(resourceType string, validator RequestBodyValidator)
+
+	// SetRequestMetricsHook sets the hook called with the metrics of each request, which carry the source controller
+	// and the attribution tags from the request context.
+	SetRequestMetricsHook(hook RequestMetricsHook)
+
+	// SetBackoff updates the backoff of the retries of the new requests.
+	SetBackoff(backoff *retry.Backoff)
+
 	// CloseResponse closes a response
 	CloseResponse(ctx context.Context, response *http.Response)
 }
@@ -23,8 +23,10 @@ package mockarmclient
 
 import (
 	context "context"
+	json "encoding/json"
 	http "net/http"
 	reflect "reflect"
+	time "time"
 
 	autorest "github.com/Azure/go-autorest/autorest"
 	azure "github.com/Azure/go-autorest/autorest/azure"
@@ -56,6 +58,18 @@ func (m *MockInterface) EXPECT() *MockInterfaceMockRecorder {
 	return m.recorder
 }
 
+// CancelAll mocks base method.
+func (m *MockInterface) CancelAll() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "CancelAll")
+}
+
+// CancelAll indicates an expected call of CancelAll.
+func (mr *MockInterfaceMockRecorder) CancelAll() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelAll", reflect.TypeOf((*MockInterface)(nil).CancelAll))
+}
+
 // CloseResponse mocks base method.
 func (m *MockInterface) CloseResponse(ctx context.Context, response *http.Response) {
 	m.ctrl.T.Helper()
@@ -107,6 +121,85 @@ func (mr *MockInterfaceMockRecorder) DeleteResourceAsync(ctx, resourceID interfa
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteResourceAsync", reflect.TypeOf((*MockInterface)(nil).DeleteResourceAsync), varargs...)
 }
 
+// DeleteResourceAsyncAndWait mocks base method.
+func (m *MockInterface) DeleteResourceAsyncAndWait(ctx context.Context, resourceID string, decorators ...autorest.PrepareDecorator) *retry.Error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, resourceID}
+	for _, a := range decorators {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DeleteResourceAsyncAndWait", varargs...)
+	ret0, _ := ret[0].(*retry.Error)
+	return ret0
+}
+
+// DeleteResourceAsyncAndWait indicates an expected call of DeleteResourceAsyncAndWait.
+func (mr *MockInterfaceMockRecorder) DeleteResourceAsyncAndWait(ctx, resourceID interface{}, decorators ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, resourceID}, decorators...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteResourceAsyncAndWait", reflect.TypeOf((*MockInterface)(nil).DeleteResourceAsyncAndWait), varargs...)
+}
+
+// EnsureProviderRegistered mocks base method.
+func (m *MockInterface) EnsureProviderRegistered(ctx context.Context, namespace string, timeout time.Duration) *retry.Error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnsureProviderRegistered", ctx, namespace, timeout)
+	ret0, _ := ret[0].(*retry.Error)
+	return ret0
+}
+
+// EnsureProviderRegistered indicates an expected call of EnsureProviderRegistered.
+func (mr *MockInterfaceMockRecorder) EnsureProviderRegistered(ctx, namespace, timeout interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureProviderRegistered", reflect.TypeOf((*MockInterface)(nil).EnsureProviderRegistered), ctx, namespace, timeout)
+}
+
+// GetAsyncOperationStatus mocks base method.
+func (m *MockInterface) GetAsyncOperationStatus(ctx context.Context, asyncOpURL string) (string, *http.Response, *retry.Error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAsyncOperationStatus", ctx, asyncOpURL)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(*http.Response)
+	ret2, _ := ret[2].(*retry.Error)
+	return ret0, ret1, ret2
+}
+
+// GetAsyncOperationStatus indicates an expected call of GetAsyncOperationStatus.
+func (mr *MockInterfaceMockRecorder) GetAsyncOperationStatus(ctx, asyncOpURL interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAsyncOperationStatus", reflect.TypeOf((*MockInterface)(nil).GetAsyncOperationStatus), ctx, asyncOpURL)
+}
+
+// GetProviderRegistrationState mocks base method.
+func (m *MockInterface) GetProviderRegistrationState(ctx context.Context, namespace string) (string, *retry.Error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProviderRegistrationState", ctx, namespace)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(*retry.Error)
+	return ret0, ret1
+}
+
+// GetProviderRegistrationState indicates an expected call of GetProviderRegistrationState.
+func (mr *MockInterfaceMockRecorder) GetProviderRegistrationState(ctx, namespace interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProviderRegistrationState", reflect.TypeOf((*MockInterface)(nil).GetProviderRegistrationState), ctx, namespace)
+}
+
+// GetQuotaUsage mocks base method.
+func (m *MockInterface) GetQuotaUsage(ctx context.Context, location, provider string) ([]armclient.QuotaUsage, *retry.Error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetQuotaUsage", ctx, location, provider)
+	ret0, _ := ret[0].([]armclient.QuotaUsage)
+	ret1, _ := ret[1].(*retry.Error)
+	return ret0, ret1
+}
+
+// GetQuotaUsage indicates an expected call of GetQuotaUsage.
+func (mr *MockInterfaceMockRecorder) GetQuotaUsage(ctx, location, provider interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetQuotaUsage", reflect.TypeOf((*MockInterface)(nil).GetQuotaUsage), ctx, location, provider)
+}
+
 // GetResource mocks base method.
 func (m *MockInterface) GetResource(ctx context.Context, resourceID string, decorators ...autorest.PrepareDecorator) (*http.Response, *retry.Error) {
 	m.ctrl.T.Helper()
@@ -127,6 +220,26 @@ func (mr *MockInterfaceMockRecorder) GetResource(ctx, resourceID interface{}, de
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetResource", reflect.TypeOf((*MockInterface)(nil).GetResource), varargs...)
 }
 
+// GetResourceByParts mocks base method.
+func (m *MockInterface) GetResourceByParts(ctx context.Context, resourceGroup, provider, resourceType, name string, decorators ...autorest.PrepareDecorator) (*http.Response, *retry.Error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, resourceGroup, provider, resourceType, name}
+	for _, a := range decorators {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetResourceByParts", varargs...)
+	ret0, _ := ret[0].(*http.Response)
+	ret1, _ := ret[1].(*retry.Error)
+	return ret0, ret1
+}
+
+// GetResourceByParts indicates an expected call of GetResourceByParts.
+func (mr *MockInterfaceMockRecorder) GetResourceByParts(ctx, resourceGroup, provider, resourceType, name interface{}, decorators ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, resourceGroup, provider, resourceType, name}, decorators...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetResourceByParts", reflect.TypeOf((*MockInterface)(nil).GetResourceByParts), varargs...)
+}
+
 // GetResourceWithExpandAPIVersionQuery mocks base method.
 func (m *MockInterface) GetResourceWithExpandAPIVersionQuery(ctx context.Context, resourceID, expand, apiVersion string) (*http.Response, *retry.Error) {
 	m.ctrl.T.Helper()
@@ -157,6 +270,47 @@ func (mr *MockInterfaceMockRecorder) GetResourceWithExpandQuery(ctx, resourceID,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetResourceWithExpandQuery", reflect.TypeOf((*MockInterface)(nil).GetResourceWithExpandQuery), ctx, resourceID, expand)
 }
 
+// GetResourceWithExpands mocks base method.
+func (m *MockInterface) GetResourceWithExpands(ctx context.Context, resourceID string, expands []string, decorators ...autorest.PrepareDecorator) (*http.Response, *retry.Error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, resourceID, expands}
+	for _, a := range decorators {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetResourceWithExpands", varargs...)
+	ret0, _ := ret[0].(*http.Response)
+	ret1, _ := ret[1].(*retry.Error)
+	return ret0, ret1
+}
+
+// GetResourceWithExpands indicates an expected call of GetResourceWithExpands.
+func (mr *MockInterfaceMockRecorder) GetResourceWithExpands(ctx, resourceID, expands interface{}, decorators ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, resourceID, expands}, decorators...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetResourceWithExpands", reflect.TypeOf((*MockInterface)(nil).GetResourceWithExpands), varargs...)
+}
+
+// GetResourceWithMetadata mocks base method.
+func (m *MockInterface) GetResourceWithMetadata(ctx context.Context, resourceID string, decorators ...autorest.PrepareDecorator) (*http.Response, armclient.ResourceMetadata, *retry.Error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, resourceID}
+	for _, a := range decorators {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetResourceWithMetadata", varargs...)
+	ret0, _ := ret[0].(*http.Response)
+	ret1, _ := ret[1].(armclient.ResourceMetadata)
+	ret2, _ := ret[2].(*retry.Error)
+	return ret0, ret1, ret2
+}
+
+// GetResourceWithMetadata indicates an expected call of GetResourceWithMetadata.
+func (mr *MockInterfaceMockRecorder) GetResourceWithMetadata(ctx, resourceID interface{}, decorators ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, resourceID}, decorators...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetResourceWithMetadata", reflect.TypeOf((*MockInterface)(nil).GetResourceWithMetadata), varargs...)
+}
+
 // HeadResource mocks base method.
 func (m *MockInterface) HeadResource(ctx context.Context, resourceID string) (*http.Response, *retry.Error) {
 	m.ctrl.T.Helper()
@@ -172,6 +326,35 @@ func (mr *MockInterfaceMockRecorder) HeadResource(ctx, resourceID interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HeadResource", reflect.TypeOf((*MockInterface)(nil).HeadResource), ctx, resourceID)
 }
 
+// ListActiveAsyncOperations mocks base method.
+func (m *MockInterface) ListActiveAsyncOperations() []armclient.AsyncOpInfo {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListActiveAsyncOperations")
+	ret0, _ := ret[0].([]armclient.AsyncOpInfo)
+	return ret0
+}
+
+// ListActiveAsyncOperations indicates an expected call of ListActiveAsyncOperations.
+func (mr *MockInterfaceMockRecorder) ListActiveAsyncOperations() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListActiveAsyncOperations", reflect.TypeOf((*MockInterface)(nil).ListActiveAsyncOperations))
+}
+
+// ListResourcesChangedSince mocks base method.
+func (m *MockInterface) ListResourcesChangedSince(ctx context.Context, resourceID string, since time.Time) ([]json.RawMessage, *retry.Error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListResourcesChangedSince", ctx, resourceID, since)
+	ret0, _ := ret[0].([]json.RawMessage)
+	ret1, _ := ret[1].(*retry.Error)
+	return ret0, ret1
+}
+
+// ListResourcesChangedSince indicates an expected call of ListResourcesChangedSince.
+func (mr *MockInterfaceMockRecorder) ListResourcesChangedSince(ctx, resourceID, since interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListResourcesChangedSince", reflect.TypeOf((*MockInterface)(nil).ListResourcesChangedSince), ctx, resourceID, since)
+}
+
 // PatchResource mocks base method.
 func (m *MockInterface) PatchResource(ctx context.Context, resourceID string, parameters interface{}, decorators ...autorest.PrepareDecorator) (*http.Response, *retry.Error) {
 	m.ctrl.T.Helper()
@@ -367,6 +550,46 @@ func (mr *MockInterfaceMockRecorder) PutResourceAsync(ctx, resourceID, parameter
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutResourceAsync", reflect.TypeOf((*MockInterface)(nil).PutResourceAsync), varargs...)
 }
 
+// PutResourceMinimal mocks base method.
+func (m *MockInterface) PutResourceMinimal(ctx context.Context, resourceID string, parameters interface{}, decorators ...autorest.PrepareDecorator) (*http.Response, *retry.Error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, resourceID, parameters}
+	for _, a := range decorators {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "PutResourceMinimal", varargs...)
+	ret0, _ := ret[0].(*http.Response)
+	ret1, _ := ret[1].(*retry.Error)
+	return ret0, ret1
+}
+
+// PutResourceMinimal indicates an expected call of PutResourceMinimal.
+func (mr *MockInterfaceMockRecorder) PutResourceMinimal(ctx, resourceID, parameters interface{}, decorators ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, resourceID, parameters}, decorators...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutResourceMinimal", reflect.TypeOf((*MockInterface)(nil).PutResourceMinimal), varargs...)
+}
+
+// PutResourceWithETag mocks base method.
+func (m *MockInterface) PutResourceWithETag(ctx context.Context, resourceID string, parameters interface{}, ifMatch string, decorators ...autorest.PrepareDecorator) (*http.Response, *retry.Error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, resourceID, parameters, ifMatch}
+	for _, a := range decorators {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "PutResourceWithETag", varargs...)
+	ret0, _ := ret[0].(*http.Response)
+	ret1, _ := ret[1].(*retry.Error)
+	return ret0, ret1
+}
+
+// PutResourceWithETag indicates an expected call of PutResourceWithETag.
+func (mr *MockInterfaceMockRecorder) PutResourceWithETag(ctx, resourceID, parameters, ifMatch interface{}, decorators ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, resourceID, parameters, ifMatch}, decorators...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutResourceWithETag", reflect.TypeOf((*MockInterface)(nil).PutResourceWithETag), varargs...)
+}
+
 // PutResourcesInBatches mocks base method.
 func (m *MockInterface) PutResourcesInBatches(ctx context.Context, resources map[string]interface{}, batchSize int) map[string]*armclient.PutResourcesResponse {
 	m.ctrl.T.Helper()
@@ -381,6 +604,30 @@ func (mr *MockInterfaceMockRecorder) PutResourcesInBatches(ctx, resources, batch
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutResourcesInBatches", reflect.TypeOf((*MockInterface)(nil).PutResourcesInBatches), ctx, resources, batchSize)
 }
 
+// RegisterRequestBodyValidator mocks base method.
+func (m *MockInterface) RegisterRequestBodyValidator(resourceType string, validator armclient.RequestBodyValidator) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RegisterRequestBodyValidator", resourceType, validator)
+}
+
+// RegisterRequestBodyValidator indicates an expected call of RegisterRequestBodyValidator.
+func (mr *MockInterfaceMockRecorder) RegisterRequestBodyValidator(resourceType, validator interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterRequestBodyValidator", reflect.TypeOf((*MockInterface)(nil).RegisterRequestBodyValidator), resourceType, validator)
+}
+
+// Reset mocks base method.
+func (m *MockInterface) Reset() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Reset")
+}
+
+// Reset indicates an expected call of Reset.
+func (mr *MockInterfaceMockRecorder) Reset() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reset", reflect.TypeOf((*MockInterface)(nil).Reset))
+}
+
 // Send mocks base method.
 func (m *MockInterface) Send(ctx context.Context, request *http.Request, decorators ...autorest.SendDecorator) (*http.Response, *retry.Error) {
 	m.ctrl.T.Helper()
@@ -417,6 +664,98 @@ func (mr *MockInterfaceMockRecorder) SendAsync(ctx, request interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendAsync", reflect.TypeOf((*MockInterface)(nil).SendAsync), ctx, request)
 }
 
+// SendForTenant mocks base method.
+func (m *MockInterface) SendForTenant(ctx context.Context, tenantID string, request *http.Request, decorators ...autorest.SendDecorator) (*http.Response, *retry.Error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, tenantID, request}
+	for _, a := range decorators {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "SendForTenant", varargs...)
+	ret0, _ := ret[0].(*http.Response)
+	ret1, _ := ret[1].(*retry.Error)
+	return ret0, ret1
+}
+
+// SendForTenant indicates an expected call of SendForTenant.
+func (mr *MockInterfaceMockRecorder) SendForTenant(ctx, tenantID, request interface{}, decorators ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, tenantID, request}, decorators...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendForTenant", reflect.TypeOf((*MockInterface)(nil).SendForTenant), varargs...)
+}
+
+// SendNoRetry mocks base method.
+func (m *MockInterface) SendNoRetry(ctx context.Context, request *http.Request, decorators ...autorest.SendDecorator) (*http.Response, *retry.Error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, request}
+	for _, a := range decorators {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "SendNoRetry", varargs...)
+	ret0, _ := ret[0].(*http.Response)
+	ret1, _ := ret[1].(*retry.Error)
+	return ret0, ret1
+}
+
+// SendNoRetry indicates an expected call of SendNoRetry.
+func (mr *MockInterfaceMockRecorder) SendNoRetry(ctx, request interface{}, decorators ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, request}, decorators...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendNoRetry", reflect.TypeOf((*MockInterface)(nil).SendNoRetry), varargs...)
+}
+
+// SetBackoff mocks base method.
+func (m *MockInterface) SetBackoff(backoff *retry.Backoff) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetBackoff", backoff)
+}
+
+// SetBackoff indicates an expected call of SetBackoff.
+func (mr *MockInterfaceMockRecorder) SetBackoff(backoff interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetBackoff", reflect.TypeOf((*MockInterface)(nil).SetBackoff), backoff)
+}
+
+// SetRequestMetricsHook mocks base method.
+func (m *MockInterface) SetRequestMetricsHook(hook armclient.RequestMetricsHook) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetRequestMetricsHook", hook)
+}
+
+// SetRequestMetricsHook indicates an expected call of SetRequestMetricsHook.
+func (mr *MockInterfaceMockRecorder) SetRequestMetricsHook(hook interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRequestMetricsHook", reflect.TypeOf((*MockInterface)(nil).SetRequestMetricsHook), hook)
+}
+
+// StreamListResources mocks base method.
+func (m *MockInterface) StreamListResources(ctx context.Context, resourceID string, onItem func(json.RawMessage) error) *retry.Error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StreamListResources", ctx, resourceID, onItem)
+	ret0, _ := ret[0].(*retry.Error)
+	return ret0
+}
+
+// StreamListResources indicates an expected call of StreamListResources.
+func (mr *MockInterfaceMockRecorder) StreamListResources(ctx, resourceID, onItem interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamListResources", reflect.TypeOf((*MockInterface)(nil).StreamListResources), ctx, resourceID, onItem)
+}
+
+// ValidateResourceID mocks base method.
+func (m *MockInterface) ValidateResourceID(resourceID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateResourceID", resourceID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ValidateResourceID indicates an expected call of ValidateResourceID.
+func (mr *MockInterfaceMockRecorder) ValidateResourceID(resourceID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateResourceID", reflect.TypeOf((*MockInterface)(nil).ValidateResourceID), resourceID)
+}
+
 // WaitForAsyncOperationCompletion mocks base method.
 func (m *MockInterface) WaitForAsyncOperationCompletion(ctx context.Context, future *azure.Future, asyncOperationName string) error {
 	m.ctrl.T.Helper()
@@ -445,3 +784,17 @@ func (mr *MockInterfaceMockRecorder) WaitForAsyncOperationResult(ctx, future, as
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitForAsyncOperationResult", reflect.TypeOf((*MockInterface)(nil).WaitForAsyncOperationResult), ctx, future, asyncOperationName)
 }
+
+// WaitForProvisioningState mocks base method.
+func (m *MockInterface) WaitForProvisioningState(ctx context.Context, resourceID, desiredState string, timeout time.Duration) *retry.Error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WaitForProvisioningState", ctx, resourceID, desiredState, timeout)
+	ret0, _ := ret[0].(*retry.Error)
+	return ret0
+}
+
+// WaitForProvisioningState indicates an expected call of WaitForProvisioningState.
+func (mr *MockInterfaceMockRecorder) WaitForProvisioningState(ctx, resourceID, desiredState, timeout interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitForProvisioningState", reflect.TypeOf((*MockInterface)(nil).WaitForProvisioningState), ctx, resourceID, desiredState, timeout)
+}
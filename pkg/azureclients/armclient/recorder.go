@@ -0,0 +1,173 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package armclient
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/Azure/go-autorest/autorest"
+
+	"k8s.io/klog/v2"
+)
+
+// RecordedExchange is a request sent to ARM and its response, which is recorded by DoRecordResponses
+// and replayed by ReplayTransport.
+type RecordedExchange struct {
+	// Method is the HTTP method of the request.
+	Method string `json:"method"`
+	// URL is the path and the query of the request, the host is omitted so that the exchange could be
+	// replayed against any endpoint.
+	URL string `json:"url"`
+	// RequestBody is the body of the request.
+	RequestBody string `json:"requestBody,omitempty"`
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int `json:"statusCode"`
+	// Header is the header of the response.
+	Header http.Header `json:"header,omitempty"`
+	// Body is the body of the response.
+	Body string `json:"body,omitempty"`
+}
+
+// DoRecordResponses returns a SendDecorator which writes each request and its response as a RecordedExchange
+// in JSON to the directory, keyed by the hash of the method, the path, the query and the body of the request.
+// The exchanges of the same requests are overwritten by the last ones. Failures of recording are logged
+// without failing the requests.
+func DoRecordResponses(dir string) autorest.SendDecorator {
+	return func(s autorest.Sender) autorest.Sender {
+		return autorest.SenderFunc(func(request *http.Request) (*http.Response, error) {
+			requestBody, err := readRequestBody(request)
+			if err != nil {
+				return nil, err
+			}
+
+			response, err := s.Do(request)
+			if err != nil || response == nil {
+				return response, err
+			}
+
+			var responseBody []byte
+			if response.Body != nil {
+				responseBody, err = ioutil.ReadAll(response.Body)
+				response.Body.Close()
+				if err != nil {
+					return response, err
+				}
+				response.Body = ioutil.NopCloser(bytes.NewReader(responseBody))
+			}
+
+			exchange := RecordedExchange{
+				Method:      request.Method,
+				URL:         request.URL.RequestURI(),
+				RequestBody: string(requestBody),
+				StatusCode:  response.StatusCode,
+				Header:      response.Header,
+				Body:        string(responseBody),
+			}
+			if err := writeRecordedExchange(dir, exchange); err != nil {
+				klog.Errorf("Failed to record the response of %s %s: %v", request.Method, request.URL.Path, err)
+			}
+			return response, nil
+		})
+	}
+}
+
+// ReplayTransport is a http.RoundTripper which replays the responses recorded by DoRecordResponses in the
+// directory without sending the requests. The requests without recorded responses fail.
+type ReplayTransport struct {
+	dir string
+}
+
+// NewReplayTransport creates a ReplayTransport replaying the responses recorded in the directory.
+func NewReplayTransport(dir string) *ReplayTransport {
+	return &ReplayTransport{dir: dir}
+}
+
+// RoundTrip returns the recorded response of the request.
+func (t *ReplayTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	requestBody, err := readRequestBody(request)
+	if err != nil {
+		return nil, err
+	}
+
+	path := recordedExchangePath(t.dir, request.Method, request.URL.RequestURI(), requestBody)
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the recorded response of %s %s: %w", request.Method, request.URL.RequestURI(), err)
+	}
+	var exchange RecordedExchange
+	if err := json.Unmarshal(content, &exchange); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the recorded response %s: %w", path, err)
+	}
+
+	header := exchange.Header
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", exchange.StatusCode, http.StatusText(exchange.StatusCode)),
+		StatusCode:    exchange.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader([]byte(exchange.Body))),
+		ContentLength: int64(len(exchange.Body)),
+		Request:       request,
+	}, nil
+}
+
+// readRequestBody reads the body of the request and restores it so that the request could still be sent.
+func readRequestBody(request *http.Request) ([]byte, error) {
+	if request.Body == nil || request.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := ioutil.ReadAll(request.Body)
+	request.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the request body: %w", err)
+	}
+	request.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// writeRecordedExchange writes the exchange to the file keyed by the hash of its request in the directory.
+func writeRecordedExchange(dir string, exchange RecordedExchange) error {
+	content, err := json.MarshalIndent(exchange, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(recordedExchangePath(dir, exchange.Method, exchange.URL, []byte(exchange.RequestBody)), content, 0600)
+}
+
+// recordedExchangePath returns the path of the file recording the exchange of the request in the directory.
+func recordedExchangePath(dir, method, url string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + "\n" + url + "\n"))
+	hash.Write(body)
+	return filepath.Join(dir, hex.EncodeToString(hash.Sum(nil))+".json")
+}
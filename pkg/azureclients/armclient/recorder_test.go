@@ -0,0 +1,71 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package armclient
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients"
+	"sigs.k8s.io/cloud-provider-azure/pkg/retry"
+)
+
+func TestRecordAndReplayResponses(t *testing.T) {
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		w.Header().Set("ETag", `"etag"`)
+		_, _ = w.Write([]byte(`{"name": "testPIP"}`))
+	}))
+
+	dir := t.TempDir()
+	ctx := context.Background()
+	recordConfig := azureclients.ClientConfig{Backoff: &retry.Backoff{Steps: 1}, UserAgent: "test", Location: "eastus", RecordResponsesDir: dir}
+	recordClient := New(nil, recordConfig, server.URL, "2019-01-01")
+	response, rerr := recordClient.GetResource(ctx, testResourceID)
+	assert.Nil(t, rerr)
+	body, err := ioutil.ReadAll(response.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name": "testPIP"}`, string(body), "the response body should still be readable after it is recorded")
+	assert.Equal(t, 1, count)
+	server.Close()
+
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	// the recorded response is replayed although the server is closed
+	replayConfig := azureclients.ClientConfig{Backoff: &retry.Backoff{Steps: 1}, UserAgent: "test", Location: "eastus", ReplayResponsesDir: dir}
+	replayClient := New(nil, replayConfig, server.URL, "2019-01-01")
+	response, rerr = replayClient.GetResource(ctx, testResourceID)
+	assert.Nil(t, rerr)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, `"etag"`, response.Header.Get("ETag"))
+	body, err = ioutil.ReadAll(response.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name": "testPIP"}`, string(body))
+	assert.Equal(t, 1, count)
+
+	// the requests without recorded responses fail
+	_, rerr = replayClient.GetResource(ctx, "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/publicIPAddresses/otherPIP")
+	assert.NotNil(t, rerr)
+}
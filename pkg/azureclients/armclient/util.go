@@ -18,18 +18,23 @@ package armclient
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"html"
 	"io/ioutil"
 	"net/http"
 	"net/http/httputil"
+	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/Azure/go-autorest/autorest"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/util/flowcontrol"
 	"k8s.io/klog/v2"
+	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients"
 	"sigs.k8s.io/cloud-provider-azure/pkg/metrics"
 	"sigs.k8s.io/cloud-provider-azure/pkg/retry"
 )
@@ -80,11 +85,16 @@ func DoDumpRequest(v klog.Level) autorest.SendDecorator {
 	return func(s autorest.Sender) autorest.Sender {
 
 		return autorest.SenderFunc(func(request *http.Request) (*http.Response, error) {
-			if request != nil {
-				requestDump, err := httputil.DumpRequest(request, true)
+			if request != nil && klog.V(v).Enabled() {
+				// Dump the redacted body instead of the real one if it has been prepared by WithRedactedBodyLogging.
+				redactedBody, isRedacted := request.Context().Value(redactedBodyKey{}).([]byte)
+				requestDump, err := httputil.DumpRequest(request, !isRedacted)
 				if err != nil {
 					klog.Errorf("Failed to dump request: %v", err)
 				} else {
+					if isRedacted {
+						requestDump = append(requestDump, redactedBody...)
+					}
 					klog.V(v).Infof("Dumping request: %s", string(requestDump))
 				}
 			}
@@ -93,6 +103,166 @@ func DoDumpRequest(v klog.Level) autorest.SendDecorator {
 	}
 }
 
+// DefaultRedactedLogFields are the JSON fields redacted from the logged request bodies by default.
+var DefaultRedactedLogFields = []string{"adminPassword", "secret"}
+
+// redactedValue replaces the values of the redacted fields.
+const redactedValue = "***"
+
+type redactedBodyKey struct{}
+
+// WithRedactedBodyLogging returns a PrepareDecorator which attaches a copy of the JSON request body
+// with the given fields redacted to the request context, so that DoDumpRequest logs the copy instead
+// of the body to be sent. A field path either matches the full path of a field, e.g.
+// "properties.osProfile.adminPassword", or matches the field at any depth if it contains no dot.
+// Fields are matched case-insensitively. The body to be sent is not changed.
+func WithRedactedBodyLogging(fieldPaths []string) autorest.PrepareDecorator {
+	return func(p autorest.Preparer) autorest.Preparer {
+		return autorest.PreparerFunc(func(r *http.Request) (*http.Request, error) {
+			r, err := p.Prepare(r)
+			if err != nil || r.Body == nil || len(fieldPaths) == 0 {
+				return r, err
+			}
+
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				return r, fmt.Errorf("failed to read the request body: %w", err)
+			}
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+			var content interface{}
+			if err := json.Unmarshal(body, &content); err != nil {
+				// Not a JSON body, nothing to redact.
+				return r, nil
+			}
+			redactedBody, err := json.Marshal(redactFields(content, "", fieldPaths))
+			if err != nil {
+				return r, nil
+			}
+			return r.WithContext(context.WithValue(r.Context(), redactedBodyKey{}, redactedBody)), nil
+		})
+	}
+}
+
+// redactFields replaces the values of the fields matching fieldPaths with redactedValue.
+func redactFields(content interface{}, path string, fieldPaths []string) interface{} {
+	switch v := content.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			fieldPath := key
+			if path != "" {
+				fieldPath = path + "." + key
+			}
+			if isRedactedField(key, fieldPath, fieldPaths) {
+				v[key] = redactedValue
+				continue
+			}
+			v[key] = redactFields(value, fieldPath, fieldPaths)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = redactFields(v[i], path, fieldPaths)
+		}
+	}
+	return content
+}
+
+func isRedactedField(key, fieldPath string, fieldPaths []string) bool {
+	for _, redacted := range fieldPaths {
+		if strings.EqualFold(redacted, fieldPath) ||
+			(!strings.Contains(redacted, ".") && strings.EqualFold(redacted, key)) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequestMetrics is the measurement of a request sent to ARM, including the retries, which is passed to the
+// RequestMetricsHook of the client.
+type RequestMetrics struct {
+	// Method is the HTTP method of the request.
+	Method string
+	// Path is the URL path of the request, e.g. the resource ID.
+	Path string
+	// StatusCode is the HTTP status code of the response, it is zero if there is no response.
+	StatusCode int
+	// Latency is the time taken to send the request and get the response.
+	Latency time.Duration
+	// SourceController is the controller from which the request originates, e.g. serviceController.
+	SourceController string
+	// Tags are the attribution tags carried by the context of the request, which are not sent to ARM.
+	Tags map[string]string
+}
+
+// RequestMetricsHook is called with the metrics of each request sent by the client, e.g. to attribute the ARM
+// call volume and latency to the controllers and operations.
+type RequestMetricsHook func(RequestMetrics)
+
+// RequestBodyValidator validates the JSON body of the request to put a resource before it is sent,
+// so that the malformed resources are reported without a round trip to ARM.
+type RequestBodyValidator func(resourceID string, body []byte) error
+
+// RequiredFieldsValidator returns a RequestBodyValidator which checks that the fields exist in the request body
+// and are not null. The fields are the full paths matched case-insensitively, e.g. "properties.frontendIPConfigurations".
+func RequiredFieldsValidator(fieldPaths ...string) RequestBodyValidator {
+	return func(resourceID string, body []byte) error {
+		var content interface{}
+		if err := json.Unmarshal(body, &content); err != nil {
+			return fmt.Errorf("failed to unmarshal the request body: %w", err)
+		}
+
+		var missing []string
+		for _, fieldPath := range fieldPaths {
+			value := content
+			for _, key := range strings.Split(fieldPath, ".") {
+				value = getFieldIgnoreCase(value, key)
+			}
+			if value == nil {
+				missing = append(missing, fieldPath)
+			}
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("required fields %v are missing", missing)
+		}
+		return nil
+	}
+}
+
+// getFieldIgnoreCase returns the value of the key in the JSON object, or nil if the content is not an object.
+func getFieldIgnoreCase(content interface{}, key string) interface{} {
+	object, ok := content.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	if value, ok := object[key]; ok {
+		return value
+	}
+	for k, value := range object {
+		if strings.EqualFold(k, key) {
+			return value
+		}
+	}
+	return nil
+}
+
+// getResourceType returns the type of the resource ID, e.g. "Microsoft.Network/loadBalancers" for the load balancers and
+// "Microsoft.Network/loadBalancers/backendAddressPools" for their backend pools. It returns "" for the IDs without providers.
+func getResourceType(resourceID string) string {
+	index := strings.LastIndex(strings.ToLower(resourceID), "/providers/")
+	if index < 0 {
+		return ""
+	}
+	parts := strings.Split(strings.Trim(resourceID[index+len("/providers/"):], "/"), "/")
+	if len(parts) < 2 {
+		return ""
+	}
+	resourceType := parts[0] + "/" + parts[1]
+	for i := 3; i < len(parts); i += 2 {
+		resourceType += "/" + parts[i]
+	}
+	return resourceType
+}
+
 func WithMetricsSendDecoratorWrapper(prefix, request, resourceGroup, subscriptionID, source string, factory func(mc *metrics.MetricContext) []autorest.SendDecorator) autorest.SendDecorator {
 	mc := metrics.NewMetricContext(prefix, request, resourceGroup, subscriptionID, source)
 	if factory != nil {
@@ -112,7 +282,7 @@ func DoHackRegionalRetryDecorator(c *Client) autorest.SendDecorator {
 				klog.V(2).Infof("response is empty")
 				return response, rerr
 			}
-			if rerr == nil || response.StatusCode == http.StatusNotFound || c.regionalEndpoint == "" {
+			if rerr == nil || response.StatusCode == http.StatusNotFound || c.regionalEndpoint == "" || retry.IsNoRetry(request.Context()) {
 				return response, rerr
 			}
 			// Hack: retry the regional ARM endpoint in case of ARM traffic split and arm resource group replication is too slow
@@ -167,3 +337,217 @@ func DoHackRegionalRetryDecorator(c *Client) autorest.SendDecorator {
 		})
 	}
 }
+
+// crossTenantAuthorizer authorizes the requests to the resources in the network resource subscription by
+// the multi-tenant authorizer, which attaches the auxiliary token of the network resource tenant, and the
+// other requests by the primary authorizer. The tokens are acquired and refreshed by the authorizers.
+type crossTenantAuthorizer struct {
+	authorizer                    autorest.Authorizer
+	multiTenantAuthorizer         autorest.Authorizer
+	networkResourceSubscriptionID string
+}
+
+// WithAuthorization returns a PrepareDecorator which chooses the authorizer by the subscription in the request path.
+func (a *crossTenantAuthorizer) WithAuthorization() autorest.PrepareDecorator {
+	return func(p autorest.Preparer) autorest.Preparer {
+		return autorest.PreparerFunc(func(r *http.Request) (*http.Request, error) {
+			r, err := p.Prepare(r)
+			if err != nil {
+				return r, err
+			}
+
+			var authorizer autorest.Authorizer = autorest.NullAuthorizer{}
+			if a.authorizer != nil {
+				authorizer = a.authorizer
+			}
+			if matches := subscriptionIDRE.FindStringSubmatch(r.URL.Path); len(matches) == 2 &&
+				strings.EqualFold(matches[1], a.networkResourceSubscriptionID) {
+				authorizer = a.multiTenantAuthorizer
+			}
+			return autorest.CreatePreparer(authorizer.WithAuthorization()).Prepare(r)
+		})
+	}
+}
+
+// tenantAuthorizerKey is the context key of the authorizer of the tenant of the request.
+type tenantAuthorizerKey struct{}
+
+// withTenantAuthorizer returns a copy of the context carrying the authorizer of the tenant of the request.
+func withTenantAuthorizer(ctx context.Context, authorizer autorest.Authorizer) context.Context {
+	return context.WithValue(ctx, tenantAuthorizerKey{}, authorizer)
+}
+
+// tenantAuthorizer authorizes the requests sent by SendForTenant by the authorizers of their tenants in the
+// request contexts, and the other requests by the primary authorizer.
+type tenantAuthorizer struct {
+	authorizer autorest.Authorizer
+}
+
+// WithAuthorization returns a PrepareDecorator which chooses the authorizer by the request context.
+func (a *tenantAuthorizer) WithAuthorization() autorest.PrepareDecorator {
+	return func(p autorest.Preparer) autorest.Preparer {
+		return autorest.PreparerFunc(func(r *http.Request) (*http.Request, error) {
+			r, err := p.Prepare(r)
+			if err != nil {
+				return r, err
+			}
+
+			var authorizer autorest.Authorizer = autorest.NullAuthorizer{}
+			if a.authorizer != nil {
+				authorizer = a.authorizer
+			}
+			if tenantAuthorizer, ok := r.Context().Value(tenantAuthorizerKey{}).(autorest.Authorizer); ok {
+				authorizer = tenantAuthorizer
+			}
+			return autorest.CreatePreparer(authorizer.WithAuthorization()).Prepare(r)
+		})
+	}
+}
+
+// IsNoContent returns true if the response is a successful response without content, e.g. the expand query
+// returns 204 No Content when there is nothing to expand. Callers should not unmarshal the body of such responses.
+func IsNoContent(response *http.Response) bool {
+	return response != nil && response.StatusCode == http.StatusNoContent
+}
+
+// DefaultDiffIgnoredFields are the read-only JSON fields populated by the server, which are ignored by DiffResources by default.
+var DefaultDiffIgnoredFields = []string{"provisioningState", "etag", "resourceGuid"}
+
+// DiffResources returns a readable diff of two JSON resource bodies, one line per changed field path, e.g.
+// "~ properties.idleTimeoutInMinutes: 4 -> 30", "+ tags.foo: \"bar\"" or "- zones: [\"1\"]". The fields
+// matching ignoredFields are skipped, which are matched in the same way as WithRedactedBodyLogging, and
+// DefaultDiffIgnoredFields is used if no field is given. An empty string is returned if nothing is changed.
+func DiffResources(current, desired []byte, ignoredFields ...string) (string, error) {
+	if len(ignoredFields) == 0 {
+		ignoredFields = DefaultDiffIgnoredFields
+	}
+
+	var currentContent, desiredContent interface{}
+	if err := json.Unmarshal(current, &currentContent); err != nil {
+		return "", fmt.Errorf("failed to unmarshal the current resource: %w", err)
+	}
+	if err := json.Unmarshal(desired, &desiredContent); err != nil {
+		return "", fmt.Errorf("failed to unmarshal the desired resource: %w", err)
+	}
+
+	var lines []string
+	diffFields(currentContent, desiredContent, "", "", ignoredFields, &lines)
+	return strings.Join(lines, "\n"), nil
+}
+
+// diffFields appends the diff of the two JSON values to lines. path is the field path with the array indexes
+// for display, and fieldPath is the one without the indexes for matching the ignored fields.
+func diffFields(current, desired interface{}, path, fieldPath string, ignoredFields []string, lines *[]string) {
+	switch currentValue := current.(type) {
+	case map[string]interface{}:
+		desiredValue, ok := desired.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := sets.NewString()
+		for key := range currentValue {
+			keys.Insert(key)
+		}
+		for key := range desiredValue {
+			keys.Insert(key)
+		}
+		for _, key := range keys.List() {
+			childPath, childFieldPath := key, key
+			if path != "" {
+				childPath, childFieldPath = path+"."+key, fieldPath+"."+key
+			}
+			if isRedactedField(key, childFieldPath, ignoredFields) {
+				continue
+			}
+			currentChild, inCurrent := currentValue[key]
+			desiredChild, inDesired := desiredValue[key]
+			switch {
+			case !inCurrent:
+				*lines = append(*lines, fmt.Sprintf("+ %s: %s", childPath, marshalDiffValue(desiredChild)))
+			case !inDesired:
+				*lines = append(*lines, fmt.Sprintf("- %s: %s", childPath, marshalDiffValue(currentChild)))
+			default:
+				diffFields(currentChild, desiredChild, childPath, childFieldPath, ignoredFields, lines)
+			}
+		}
+		return
+	case []interface{}:
+		desiredValue, ok := desired.([]interface{})
+		if !ok {
+			break
+		}
+		for i := 0; i < len(currentValue) || i < len(desiredValue); i++ {
+			childPath := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(currentValue):
+				*lines = append(*lines, fmt.Sprintf("+ %s: %s", childPath, marshalDiffValue(desiredValue[i])))
+			case i >= len(desiredValue):
+				*lines = append(*lines, fmt.Sprintf("- %s: %s", childPath, marshalDiffValue(currentValue[i])))
+			default:
+				diffFields(currentValue[i], desiredValue[i], childPath, fieldPath, ignoredFields, lines)
+			}
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(current, desired) {
+		*lines = append(*lines, fmt.Sprintf("~ %s: %s -> %s", path, marshalDiffValue(current), marshalDiffValue(desired)))
+	}
+}
+
+func marshalDiffValue(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+const (
+	rateLimitRemainingReadsHeader    = "x-ms-ratelimit-remaining-subscription-reads"
+	rateLimitRemainingWritesHeader   = "x-ms-ratelimit-remaining-subscription-writes"
+	rateLimitRemainingResourceHeader = "x-ms-ratelimit-remaining-resource"
+
+	// clientTenantIDHeader is the header of the tenant of the cross-tenant requests.
+	clientTenantIDHeader = "x-ms-client-tenant-id"
+)
+
+// parseRateLimitRemaining parses the numbers of the remaining ARM requests in the x-ms-ratelimit-remaining-* headers,
+// keyed by the rate limit types. The resource header lists the remaining requests of the throttling policies of the
+// resource provider, e.g. "Microsoft.Compute/HighCostGet3Min;107,Microsoft.Compute/HighCostGet30Min;587", and the
+// lowest one is returned. The malformed headers are ignored.
+func parseRateLimitRemaining(header http.Header) map[string]int {
+	result := make(map[string]int)
+	for rateLimitType, name := range map[string]string{
+		azureclients.RateLimitTypeReads:  rateLimitRemainingReadsHeader,
+		azureclients.RateLimitTypeWrites: rateLimitRemainingWritesHeader,
+	} {
+		if value := header.Get(name); value != "" {
+			remaining, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				klog.V(4).Infof("parseRateLimitRemaining: ignoring the malformed header %s: %q", name, value)
+				continue
+			}
+			result[rateLimitType] = remaining
+		}
+	}
+
+	if value := header.Get(rateLimitRemainingResourceHeader); value != "" {
+		lowest, found := 0, false
+		for _, policy := range strings.Split(value, ",") {
+			parts := strings.Split(policy, ";")
+			remaining, err := strconv.Atoi(strings.TrimSpace(parts[len(parts)-1]))
+			if len(parts) != 2 || err != nil {
+				klog.V(4).Infof("parseRateLimitRemaining: ignoring the malformed policy %q in header %s", policy, rateLimitRemainingResourceHeader)
+				continue
+			}
+			if !found || remaining < lowest {
+				lowest, found = remaining, true
+			}
+		}
+		if found {
+			result[azureclients.RateLimitTypeResource] = lowest
+		}
+	}
+	return result
+}
@@ -0,0 +1,247 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package armclient
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients"
+	"sigs.k8s.io/cloud-provider-azure/pkg/retry"
+)
+
+func TestRedactedBodyLogging(t *testing.T) {
+	var logs bytes.Buffer
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	klog.InitFlags(flags)
+	assert.NoError(t, flags.Set("v", "10"))
+	assert.NoError(t, flags.Set("logtostderr", "false"))
+	klog.SetOutput(&logs)
+	defer func() {
+		_ = flags.Set("v", "0")
+		_ = flags.Set("logtostderr", "true")
+		klog.SetOutput(os.Stderr)
+	}()
+
+	var sentBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sentBody, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	azConfig := azureclients.ClientConfig{Backoff: &retry.Backoff{Steps: 1}, UserAgent: "test", Location: "eastus"}
+	armClient := New(nil, azConfig, server.URL, "2019-01-01")
+	parameters := map[string]interface{}{
+		"properties": map[string]interface{}{
+			"osProfile": map[string]interface{}{
+				"adminUsername": "azureuser",
+				"adminPassword": "p@ssw0rd",
+			},
+			"sslCertificates": []interface{}{
+				map[string]interface{}{"secret": "tls-key"},
+			},
+		},
+	}
+	_, rerr := armClient.PutResourceAsync(context.Background(), testResourceID, parameters)
+	assert.Nil(t, rerr)
+	klog.Flush()
+
+	assert.Contains(t, string(sentBody), `"adminPassword":"p@ssw0rd"`)
+	assert.Contains(t, string(sentBody), `"secret":"tls-key"`)
+	assert.Contains(t, logs.String(), `"adminPassword":"***"`)
+	assert.Contains(t, logs.String(), `"secret":"***"`)
+	assert.Contains(t, logs.String(), `"adminUsername":"azureuser"`)
+	assert.NotContains(t, logs.String(), "p@ssw0rd")
+	assert.NotContains(t, logs.String(), "tls-key")
+}
+
+func TestRedactedBodyLoggingSkippedWhenDumpDisabled(t *testing.T) {
+	azConfig := azureclients.ClientConfig{Backoff: &retry.Backoff{Steps: 1}, UserAgent: "test", Location: "eastus"}
+	armClient := New(nil, azConfig, "http://localhost", "2019-01-01")
+	parameters := map[string]interface{}{"properties": map[string]interface{}{"secret": "tls-key"}}
+
+	request, err := armClient.PreparePutRequest(context.Background(), autorest.WithPathParameters("{resourceID}", map[string]interface{}{"resourceID": testResourceID}), autorest.WithJSON(parameters))
+	assert.NoError(t, err)
+	redactedBody, isRedacted := request.Context().Value(redactedBodyKey{}).([]byte)
+	assert.True(t, isRedacted, "the body should never be dumped without redaction")
+	assert.Empty(t, redactedBody, "the body should not be redacted when the dump is disabled")
+	body, err := ioutil.ReadAll(request.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"properties":{"secret":"tls-key"}}`, string(body))
+}
+
+func TestRedactFields(t *testing.T) {
+	for _, tc := range []struct {
+		description string
+		fieldPaths  []string
+		expected    map[string]interface{}
+	}{
+		{
+			description: "field name should match fields at any depth",
+			fieldPaths:  []string{"password"},
+			expected: map[string]interface{}{
+				"password": "***",
+				"properties": map[string]interface{}{
+					"password": "***",
+				},
+			},
+		},
+		{
+			description: "full field path should only match the field itself",
+			fieldPaths:  []string{"Properties.Password"},
+			expected: map[string]interface{}{
+				"password": "top",
+				"properties": map[string]interface{}{
+					"password": "***",
+				},
+			},
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			content := map[string]interface{}{
+				"password": "top",
+				"properties": map[string]interface{}{
+					"password": "nested",
+				},
+			}
+			assert.Equal(t, tc.expected, redactFields(content, "", tc.fieldPaths))
+		})
+	}
+}
+
+func TestIsNoContent(t *testing.T) {
+	assert.False(t, IsNoContent(nil))
+	assert.False(t, IsNoContent(&http.Response{StatusCode: http.StatusOK}))
+	assert.True(t, IsNoContent(&http.Response{StatusCode: http.StatusNoContent}))
+}
+
+func TestDiffResources(t *testing.T) {
+	current := `{"name":"pip","etag":"W/\"1\"","tags":{"a":"1","b":"2"},"zones":["1"],"properties":{"provisioningState":"Succeeded","idleTimeoutInMinutes":4,"ipTags":[{"tag":"x"}]}}`
+	for _, tc := range []struct {
+		description   string
+		desired       string
+		ignoredFields []string
+		expected      string
+		expectedErr   string
+	}{
+		{
+			description: "unchanged resources should have no diff",
+			desired:     current,
+		},
+		{
+			description: "read-only fields should be ignored by default",
+			desired:     `{"name":"pip","tags":{"a":"1","b":"2"},"zones":["1"],"properties":{"idleTimeoutInMinutes":4,"ipTags":[{"tag":"x"}]}}`,
+		},
+		{
+			description: "added fields should be reported",
+			desired:     `{"name":"pip","etag":"W/\"1\"","tags":{"a":"1","b":"2","c":"3"},"zones":["1","2"],"properties":{"provisioningState":"Succeeded","idleTimeoutInMinutes":4,"ipTags":[{"tag":"x"}],"dnsSettings":{"domainNameLabel":"foo"}}}`,
+			expected: `+ properties.dnsSettings: {"domainNameLabel":"foo"}
++ tags.c: "3"
++ zones[1]: "2"`,
+		},
+		{
+			description: "removed fields should be reported",
+			desired:     `{"name":"pip","tags":{"a":"1"},"properties":{"idleTimeoutInMinutes":4,"ipTags":[]}}`,
+			expected: `- properties.ipTags[0]: {"tag":"x"}
+- tags.b: "2"
+- zones: ["1"]`,
+		},
+		{
+			description: "changed fields should be reported",
+			desired:     `{"name":"pip","tags":{"a":"1","b":"3"},"zones":"1","properties":{"idleTimeoutInMinutes":30,"ipTags":[{"tag":"y"}]}}`,
+			expected: `~ properties.idleTimeoutInMinutes: 4 -> 30
+~ properties.ipTags[0].tag: "x" -> "y"
+~ tags.b: "2" -> "3"
+~ zones: ["1"] -> "1"`,
+		},
+		{
+			description:   "given ignored fields should replace the default ones",
+			desired:       `{"name":"pip","tags":{"a":"1","b":"3"},"zones":["1"],"properties":{"idleTimeoutInMinutes":30,"ipTags":[{"tag":"y"}]}}`,
+			ignoredFields: []string{"tags", "properties.ipTags.tag"},
+			expected: `- etag: "W/\"1\""
+~ properties.idleTimeoutInMinutes: 4 -> 30
+- properties.provisioningState: "Succeeded"`,
+		},
+		{
+			description: "invalid JSON should be reported",
+			desired:     `{`,
+			expectedErr: "failed to unmarshal the desired resource: unexpected end of JSON input",
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			diff, err := DiffResources([]byte(current), []byte(tc.desired), tc.ignoredFields...)
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, diff)
+		})
+	}
+}
+
+func TestGetResourceType(t *testing.T) {
+	for resourceID, expected := range map[string]string{
+		"/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/lb":                                                                "Microsoft.Network/loadBalancers",
+		"/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/lb/backendAddressPools/pool":                                       "Microsoft.Network/loadBalancers/backendAddressPools",
+		"/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/providers/Microsoft.Insights/diagnosticSettings/ds": "Microsoft.Insights/diagnosticSettings",
+		"/subscriptions/sub/resourceGroups/rg": "",
+	} {
+		assert.Equal(t, expected, getResourceType(resourceID), resourceID)
+	}
+}
+
+func TestRequiredFieldsValidator(t *testing.T) {
+	validator := RequiredFieldsValidator("location", "properties.frontendIPConfigurations")
+
+	assert.NoError(t, validator("lb", []byte(`{"location":"eastus","Properties":{"frontendIPConfigurations":[]}}`)))
+	assert.EqualError(t, validator("lb", []byte(`{"properties":{"frontendIPConfigurations":null}}`)), "required fields [location properties.frontendIPConfigurations] are missing")
+	assert.EqualError(t, validator("lb", []byte(`{"location":"eastus","properties":"invalid"}`)), "required fields [properties.frontendIPConfigurations] are missing")
+	assert.Error(t, validator("lb", []byte(`{`)))
+}
+
+func TestParseRateLimitRemaining(t *testing.T) {
+	header := http.Header{}
+	assert.Empty(t, parseRateLimitRemaining(header))
+
+	header.Set("x-ms-ratelimit-remaining-subscription-reads", "11999")
+	header.Set("x-ms-ratelimit-remaining-subscription-writes", " 1199 ")
+	header.Set("x-ms-ratelimit-remaining-resource", "Microsoft.Compute/HighCostGet3Min;107,Microsoft.Compute/HighCostGet30Min;587")
+	assert.Equal(t, map[string]int{
+		azureclients.RateLimitTypeReads:    11999,
+		azureclients.RateLimitTypeWrites:   1199,
+		azureclients.RateLimitTypeResource: 107,
+	}, parseRateLimitRemaining(header))
+
+	header.Set("x-ms-ratelimit-remaining-subscription-reads", "invalid")
+	header.Set("x-ms-ratelimit-remaining-resource", "Microsoft.Compute/HighCostGet3Min,Microsoft.Compute/HighCostGet30Min;587")
+	assert.Equal(t, map[string]int{
+		azureclients.RateLimitTypeWrites:   1199,
+		azureclients.RateLimitTypeResource: 587,
+	}, parseRateLimitRemaining(header))
+}
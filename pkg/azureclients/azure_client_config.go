@@ -17,9 +17,15 @@ limitations under the License.
 package azureclients
 
 import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/adal"
 	"k8s.io/client-go/util/flowcontrol"
 
 	"sigs.k8s.io/cloud-provider-azure/pkg/retry"
@@ -38,6 +44,71 @@ type ClientConfig struct {
 	Backoff                 *retry.Backoff
 	UserAgent               string
 	DisableAzureStackCloud  bool
+	// RedactedLogFields are the JSON field paths redacted from the request bodies
+	// before they are logged, e.g. "adminPassword" or "properties.osProfile.adminPassword".
+	// armclient.DefaultRedactedLogFields is used if it is empty.
+	RedactedLogFields []string
+	// ForceHTTP1 disables HTTP/2 and forces HTTP/1.1 on the transport. It is useful behind
+	// proxies or firewalls that stall HTTP/2 streams, at the cost of opening one connection
+	// per in-flight request instead of multiplexing the requests over a single connection.
+	ForceHTTP1 bool
+	// StrictSubscriptionValidation rejects the requests to the resources in subscriptions other than
+	// SubscriptionID before they are sent, instead of failing with confusing authorization errors.
+	// It shouldn't be set on the clients operating resources in multiple subscriptions.
+	StrictSubscriptionValidation bool
+	// MinTLSVersion is the minimum TLS version accepted in the handshakes with ARM, e.g. tls.VersionTLS13.
+	// tls.VersionTLS12 is used if it is zero.
+	MinTLSVersion uint16
+	// RootCAs pins the certificate authorities used to verify the ARM server certificates.
+	// The host's root CA set is used if it is nil.
+	RootCAs *x509.CertPool
+	// InvalidationRegistry is notified by the clients after they mutate the resources,
+	// so that the cached resources could be invalidated. It is shared by the copies of the config.
+	InvalidationRegistry *InvalidationRegistry
+	// NetworkResourceSubscriptionID is the subscription of the network resources in another AAD tenant.
+	// The requests to the resources in it are authorized by MultiTenantAuthorizer if it is different
+	// from SubscriptionID.
+	NetworkResourceSubscriptionID string
+	// MultiTenantAuthorizer attaches the auxiliary token of the network resource tenant to the requests
+	// in the x-ms-authorization-auxiliary header, which is required by ARM for cross-tenant requests.
+	MultiTenantAuthorizer autorest.Authorizer
+	// RateLimitBudget records the remaining ARM requests reported in the responses. It is shared by the copies
+	// of the config, and the clients create their own ones if it is nil.
+	RateLimitBudget *RateLimitBudget
+	// RateLimitRemainingWarningThreshold is the number of the remaining ARM requests below which warnings are logged.
+	// armclient.DefaultRateLimitRemainingWarningThreshold is used if it is zero.
+	RateLimitRemainingWarningThreshold int
+	// SlowDownWritesOnLowRateLimitRemaining delays the write requests while the remaining ARM writes are below
+	// RateLimitRemainingWarningThreshold, so that the subscription is less likely to be throttled.
+	SlowDownWritesOnLowRateLimitRemaining bool
+	// TenantTokenProvider provides the tokens scoped to the tenants of the cross-tenant requests, e.g. the
+	// requests to the resources in the guest tenants. The requests to other tenants fail if it is nil.
+	TenantTokenProvider TenantTokenProvider
+	// RecordResponsesDir is the directory where the requests and their responses are recorded in JSON, e.g. to build
+	// the fixtures of the tests from the real ARM interactions. Nothing is recorded if it is empty.
+	RecordResponsesDir string
+	// ReplayResponsesDir is the directory of the responses recorded by RecordResponsesDir, which are replayed
+	// instead of sending the requests to ARM. It is used in the tests only.
+	ReplayResponsesDir string
+}
+
+// TenantTokenProvider provides the OAuth tokens scoped to the tenants.
+type TenantTokenProvider interface {
+	// GetTokenProvider returns the OAuth token provider of the tenant.
+	GetTokenProvider(tenantID string) (adal.OAuthTokenProvider, error)
+}
+
+// TenantTokenProviders is a TenantTokenProvider keyed by the tenant IDs.
+type TenantTokenProviders map[string]adal.OAuthTokenProvider
+
+// GetTokenProvider returns the OAuth token provider of the tenant, or an error if there is none.
+func (p TenantTokenProviders) GetTokenProvider(tenantID string) (adal.OAuthTokenProvider, error) {
+	for id, tokenProvider := range p {
+		if strings.EqualFold(id, tenantID) {
+			return tokenProvider, nil
+		}
+	}
+	return nil, fmt.Errorf("no token provider for tenant %s", tenantID)
 }
 
 // WithRateLimiter returns a new ClientConfig with rateLimitConfig set.
@@ -81,7 +152,14 @@ func RateLimitEnabled(config *RateLimitConfig) bool {
 }
 
 // NewRateLimiter creates new read and write flowcontrol.RateLimiter from RateLimitConfig.
+// The rate limiters are ReloadableRateLimiters, which could be updated by SetRateLimits.
 func NewRateLimiter(config *RateLimitConfig) (flowcontrol.RateLimiter, flowcontrol.RateLimiter) {
+	readLimiter, writeLimiter := newTokenBucketRateLimiters(config)
+	return NewReloadableRateLimiter(readLimiter), NewReloadableRateLimiter(writeLimiter)
+}
+
+// newTokenBucketRateLimiters creates the read and write token bucket rate limiters from RateLimitConfig.
+func newTokenBucketRateLimiters(config *RateLimitConfig) (flowcontrol.RateLimiter, flowcontrol.RateLimiter) {
 	readLimiter := flowcontrol.NewFakeAlwaysRateLimiter()
 	writeLimiter := flowcontrol.NewFakeAlwaysRateLimiter()
 
@@ -97,3 +175,82 @@ func NewRateLimiter(config *RateLimitConfig) (flowcontrol.RateLimiter, flowcontr
 
 	return readLimiter, writeLimiter
 }
+
+// SetRateLimits replaces the underlying rate limiters of the read and write ReloadableRateLimiters with the ones
+// created from RateLimitConfig. The rate limiters of other types, e.g. the fake ones in tests, are not changed.
+func SetRateLimits(config *RateLimitConfig, readLimiter, writeLimiter flowcontrol.RateLimiter) {
+	newReadLimiter, newWriteLimiter := newTokenBucketRateLimiters(config)
+	if limiter, ok := readLimiter.(*ReloadableRateLimiter); ok {
+		limiter.Set(newReadLimiter)
+	}
+	if limiter, ok := writeLimiter.(*ReloadableRateLimiter); ok {
+		limiter.Set(newWriteLimiter)
+	}
+}
+
+// RateLimitsSetter is implemented by the clients whose rate limits could be updated at runtime.
+type RateLimitsSetter interface {
+	// SetRateLimits replaces the rate limiters of the client with the ones created from RateLimitConfig.
+	SetRateLimits(config *RateLimitConfig)
+}
+
+// BackoffSetter is implemented by the clients whose backoff could be updated at runtime.
+type BackoffSetter interface {
+	// SetBackoff updates the backoff of the retries of the new requests.
+	SetBackoff(backoff *retry.Backoff)
+}
+
+// rateLimiterHolder holds a flowcontrol.RateLimiter, so that the rate limiters of
+// different types could be stored in the same atomic.Value.
+type rateLimiterHolder struct {
+	flowcontrol.RateLimiter
+}
+
+// ReloadableRateLimiter is a flowcontrol.RateLimiter whose underlying rate limiter could be swapped atomically.
+// The calls after the swap go to the new rate limiter, while the calls waiting for the old one, e.g. Accept
+// and Wait, are not affected.
+type ReloadableRateLimiter struct {
+	limiter atomic.Value
+}
+
+// NewReloadableRateLimiter creates a new ReloadableRateLimiter with the initial rate limiter.
+func NewReloadableRateLimiter(limiter flowcontrol.RateLimiter) *ReloadableRateLimiter {
+	r := &ReloadableRateLimiter{}
+	r.Set(limiter)
+	return r
+}
+
+// Set swaps the underlying rate limiter.
+func (r *ReloadableRateLimiter) Set(limiter flowcontrol.RateLimiter) {
+	r.limiter.Store(rateLimiterHolder{RateLimiter: limiter})
+}
+
+// get returns the current underlying rate limiter.
+func (r *ReloadableRateLimiter) get() flowcontrol.RateLimiter {
+	return r.limiter.Load().(rateLimiterHolder).RateLimiter
+}
+
+// TryAccept returns true if a token is taken immediately from the current rate limiter.
+func (r *ReloadableRateLimiter) TryAccept() bool {
+	return r.get().TryAccept()
+}
+
+// Accept returns once a token becomes available in the current rate limiter.
+func (r *ReloadableRateLimiter) Accept() {
+	r.get().Accept()
+}
+
+// Wait returns nil if a token is taken from the current rate limiter before the Context is done.
+func (r *ReloadableRateLimiter) Wait(ctx context.Context) error {
+	return r.get().Wait(ctx)
+}
+
+// Stop stops the current rate limiter.
+func (r *ReloadableRateLimiter) Stop() {
+	r.get().Stop()
+}
+
+// QPS returns the QPS of the current rate limiter.
+func (r *ReloadableRateLimiter) QPS() float32 {
+	return r.get().QPS()
+}
@@ -17,8 +17,11 @@ limitations under the License.
 package azureclients
 
 import (
+	"context"
 	"testing"
+	"time"
 
+	"github.com/Azure/go-autorest/autorest/adal"
 	"github.com/stretchr/testify/assert"
 	"k8s.io/client-go/util/flowcontrol"
 )
@@ -32,6 +35,18 @@ func TestWithRateLimiter(t *testing.T) {
 	assert.Nil(t, config.RateLimitConfig)
 }
 
+func TestTenantTokenProviders(t *testing.T) {
+	token := &adal.Token{AccessToken: "token"}
+	providers := TenantTokenProviders{"Tenant": token}
+
+	tokenProvider, err := providers.GetTokenProvider("tenant")
+	assert.NoError(t, err)
+	assert.Equal(t, token, tokenProvider)
+
+	_, err = providers.GetTokenProvider("other")
+	assert.EqualError(t, err, "no token provider for tenant other")
+}
+
 func TestRateLimitEnabled(t *testing.T) {
 	assert.Equal(t, false, RateLimitEnabled(nil))
 	config := &RateLimitConfig{}
@@ -43,15 +58,15 @@ func TestRateLimitEnabled(t *testing.T) {
 func TestNewRateLimiter(t *testing.T) {
 	fakeRateLimiter := flowcontrol.NewFakeAlwaysRateLimiter()
 	readLimiter, writeLimiter := NewRateLimiter(nil)
-	assert.Equal(t, readLimiter, fakeRateLimiter)
-	assert.Equal(t, writeLimiter, fakeRateLimiter)
+	assert.Equal(t, readLimiter.(*ReloadableRateLimiter).get(), fakeRateLimiter)
+	assert.Equal(t, writeLimiter.(*ReloadableRateLimiter).get(), fakeRateLimiter)
 
 	rateLimitConfig := &RateLimitConfig{
 		CloudProviderRateLimit: false,
 	}
 	readLimiter, writeLimiter = NewRateLimiter(rateLimitConfig)
-	assert.Equal(t, readLimiter, fakeRateLimiter)
-	assert.Equal(t, writeLimiter, fakeRateLimiter)
+	assert.Equal(t, readLimiter.(*ReloadableRateLimiter).get(), fakeRateLimiter)
+	assert.Equal(t, writeLimiter.(*ReloadableRateLimiter).get(), fakeRateLimiter)
 
 	rateLimitConfig = &RateLimitConfig{
 		CloudProviderRateLimit:            true,
@@ -61,6 +76,112 @@ func TestNewRateLimiter(t *testing.T) {
 		CloudProviderRateLimitBucketWrite: 3,
 	}
 	readLimiter, writeLimiter = NewRateLimiter(rateLimitConfig)
-	assert.Equal(t, flowcontrol.NewTokenBucketRateLimiter(3, 10), readLimiter)
-	assert.Equal(t, flowcontrol.NewTokenBucketRateLimiter(1, 3), writeLimiter)
+	assert.Equal(t, flowcontrol.NewTokenBucketRateLimiter(3, 10), readLimiter.(*ReloadableRateLimiter).get())
+	assert.Equal(t, flowcontrol.NewTokenBucketRateLimiter(1, 3), writeLimiter.(*ReloadableRateLimiter).get())
+}
+
+// waitSignalingRateLimiter signals on waiting when Wait is called on the wrapped rate limiter.
+type waitSignalingRateLimiter struct {
+	flowcontrol.RateLimiter
+	waiting chan struct{}
+}
+
+func (l *waitSignalingRateLimiter) Wait(ctx context.Context) error {
+	close(l.waiting)
+	return l.RateLimiter.Wait(ctx)
+}
+
+func TestSetRateLimits(t *testing.T) {
+	oldLimiter := &waitSignalingRateLimiter{
+		RateLimiter: flowcontrol.NewTokenBucketRateLimiter(0.001, 1),
+		waiting:     make(chan struct{}),
+	}
+	readLimiter := flowcontrol.RateLimiter(NewReloadableRateLimiter(oldLimiter))
+	writeLimiter := flowcontrol.RateLimiter(NewReloadableRateLimiter(flowcontrol.NewTokenBucketRateLimiter(0.001, 1)))
+	assert.True(t, readLimiter.TryAccept())
+	assert.False(t, readLimiter.TryAccept())
+
+	// wait for the old rate limiter until the limits are updated
+	ctx, cancel := context.WithCancel(context.Background())
+	waitErr := make(chan error)
+	go func() {
+		waitErr <- readLimiter.Wait(ctx)
+	}()
+	<-oldLimiter.waiting
+
+	SetRateLimits(&RateLimitConfig{
+		CloudProviderRateLimit:            true,
+		CloudProviderRateLimitQPS:         0.002,
+		CloudProviderRateLimitBucket:      3,
+		CloudProviderRateLimitQPSWrite:    0.003,
+		CloudProviderRateLimitBucketWrite: 2,
+	}, readLimiter, writeLimiter)
+	assert.Equal(t, float32(0.002), readLimiter.QPS())
+	assert.Equal(t, float32(0.003), writeLimiter.QPS())
+
+	// the new calls should use the new limits
+	for i := 0; i < 3; i++ {
+		assert.True(t, readLimiter.TryAccept())
+	}
+	assert.False(t, readLimiter.TryAccept())
+	for i := 0; i < 2; i++ {
+		assert.True(t, writeLimiter.TryAccept())
+	}
+	assert.False(t, writeLimiter.TryAccept())
+
+	// the in-flight wait should stay on the old rate limiter
+	select {
+	case err := <-waitErr:
+		t.Fatalf("unexpected return of the in-flight wait: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	cancel()
+	assert.Error(t, <-waitErr)
+
+	// the rate limits could be disabled
+	SetRateLimits(nil, readLimiter, writeLimiter)
+	assert.Equal(t, flowcontrol.NewFakeAlwaysRateLimiter(), readLimiter.(*ReloadableRateLimiter).get())
+
+	// the rate limiters of other types are not changed
+	fakeRateLimiter := flowcontrol.NewFakeNeverRateLimiter()
+	SetRateLimits(nil, fakeRateLimiter, fakeRateLimiter)
+	assert.False(t, fakeRateLimiter.TryAccept())
+}
+
+func TestInvalidationRegistry(t *testing.T) {
+	var nilRegistry *InvalidationRegistry
+	assert.NotPanics(t, func() { nilRegistry.Invalidate("Microsoft.Network/loadBalancers", "lb") })
+
+	registry := NewInvalidationRegistry()
+	var invalidated []string
+	registry.Register("Microsoft.Network/loadBalancers", func(resourceID string) {
+		invalidated = append(invalidated, "lb:"+resourceID)
+	})
+	registry.Register("microsoft.network/LOADBALANCERS", func(resourceID string) {
+		invalidated = append(invalidated, "lb2:"+resourceID)
+	})
+	registry.Register("Microsoft.Network/networkSecurityGroups", func(resourceID string) {
+		invalidated = append(invalidated, "nsg:"+resourceID)
+	})
+
+	registry.Invalidate("Microsoft.Network/loadBalancers", "id")
+	assert.Equal(t, []string{"lb:id", "lb2:id"}, invalidated)
+	registry.Invalidate("Microsoft.Network/networkInterfaces", "id")
+	assert.Equal(t, []string{"lb:id", "lb2:id"}, invalidated)
+}
+
+func TestRateLimitBudget(t *testing.T) {
+	var nilBudget *RateLimitBudget
+	assert.NotPanics(t, func() { nilBudget.Update(RateLimitTypeWrites, 1) })
+	_, ok := nilBudget.Remaining(RateLimitTypeWrites)
+	assert.False(t, ok)
+
+	budget := NewRateLimitBudget()
+	_, ok = budget.Remaining(RateLimitTypeWrites)
+	assert.False(t, ok)
+	budget.Update(RateLimitTypeWrites, 1199)
+	budget.Update(RateLimitTypeWrites, 1198)
+	remaining, ok := budget.Remaining(RateLimitTypeWrites)
+	assert.True(t, ok)
+	assert.Equal(t, 1198, remaining)
 }
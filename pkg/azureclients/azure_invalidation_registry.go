@@ -0,0 +1,65 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azureclients
+
+import (
+	"strings"
+	"sync"
+)
+
+// Invalidator invalidates the cached data of a resource after the resource is mutated.
+type Invalidator func(resourceID string)
+
+// InvalidationRegistry dispatches the mutations of the resources made by the clients
+// to the invalidators registered for the resource types.
+type InvalidationRegistry struct {
+	lock sync.RWMutex
+	// invalidators are keyed by the lower-cased resource types, e.g. "microsoft.network/loadbalancers".
+	invalidators map[string][]Invalidator
+}
+
+// NewInvalidationRegistry creates a new InvalidationRegistry.
+func NewInvalidationRegistry() *InvalidationRegistry {
+	return &InvalidationRegistry{
+		invalidators: make(map[string][]Invalidator),
+	}
+}
+
+// Register registers the invalidator for the resource type, e.g. "Microsoft.Network/loadBalancers".
+func (r *InvalidationRegistry) Register(resourceType string, invalidator Invalidator) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	key := strings.ToLower(resourceType)
+	r.invalidators[key] = append(r.invalidators[key], invalidator)
+}
+
+// Invalidate calls the invalidators registered for the resource type with the resource ID.
+// It is a no-op on a nil InvalidationRegistry so that the clients could call it unconditionally.
+func (r *InvalidationRegistry) Invalidate(resourceType, resourceID string) {
+	if r == nil {
+		return
+	}
+
+	r.lock.RLock()
+	invalidators := r.invalidators[strings.ToLower(resourceType)]
+	r.lock.RUnlock()
+
+	for _, invalidate := range invalidators {
+		invalidate(resourceID)
+	}
+}
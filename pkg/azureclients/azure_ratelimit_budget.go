@@ -0,0 +1,71 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azureclients
+
+import (
+	"sync"
+)
+
+const (
+	// RateLimitTypeReads is the type of the remaining subscription reads reported by ARM.
+	RateLimitTypeReads = "reads"
+	// RateLimitTypeWrites is the type of the remaining subscription writes reported by ARM.
+	RateLimitTypeWrites = "writes"
+	// RateLimitTypeResource is the type of the remaining requests of the resource provider throttling policies reported by ARM.
+	RateLimitTypeResource = "resource"
+)
+
+// RateLimitBudget records the most recent numbers of the remaining requests reported by ARM in the
+// x-ms-ratelimit-remaining-* response headers, so that the throttling could be anticipated before it happens.
+// It is shared by the copies of the config.
+type RateLimitBudget struct {
+	lock sync.RWMutex
+	// remaining is keyed by the rate limit types, e.g. RateLimitTypeWrites.
+	remaining map[string]int
+}
+
+// NewRateLimitBudget creates a new RateLimitBudget.
+func NewRateLimitBudget() *RateLimitBudget {
+	return &RateLimitBudget{
+		remaining: make(map[string]int),
+	}
+}
+
+// Update records the number of the remaining requests of the rate limit type.
+// It is a no-op on a nil RateLimitBudget so that the clients could call it unconditionally.
+func (b *RateLimitBudget) Update(rateLimitType string, remaining int) {
+	if b == nil {
+		return
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.remaining[rateLimitType] = remaining
+}
+
+// Remaining returns the most recent number of the remaining requests of the rate limit type,
+// and whether it has been reported.
+func (b *RateLimitBudget) Remaining(rateLimitType string) (int, bool) {
+	if b == nil {
+		return 0, false
+	}
+
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	remaining, ok := b.remaining[rateLimitType]
+	return remaining, ok
+}
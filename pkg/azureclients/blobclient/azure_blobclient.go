@@ -48,7 +48,7 @@ func (c *Client) CreateContainer(ctx context.Context, resourceGroupName, account
 	if resourceGroupName == "" || accountName == "" || containerName == "" {
 		return fmt.Errorf("empty value in resourceGroupName(%s), accountName(%s), containerName(%s)", resourceGroupName, accountName, containerName)
 	}
-	mc := metrics.NewMetricContext("blob_container", "create", resourceGroupName, c.subscriptionID, "")
+	mc := metrics.NewMetricContext("blob_container", "create", resourceGroupName, c.subscriptionID, "").WithContext(ctx)
 	_, err := c.blobContainersClient.Create(ctx, resourceGroupName, accountName, containerName, blobContainer)
 	var rerr *retry.Error
 	if err != nil {
@@ -65,7 +65,7 @@ func (c *Client) DeleteContainer(ctx context.Context, resourceGroupName, account
 	if resourceGroupName == "" || accountName == "" || containerName == "" {
 		return fmt.Errorf("empty value in resourceGroupName(%s), accountName(%s), containerName(%s)", resourceGroupName, accountName, containerName)
 	}
-	mc := metrics.NewMetricContext("blob_container", "delete", resourceGroupName, c.subscriptionID, "")
+	mc := metrics.NewMetricContext("blob_container", "delete", resourceGroupName, c.subscriptionID, "").WithContext(ctx)
 	_, err := c.blobContainersClient.Delete(ctx, resourceGroupName, accountName, containerName)
 	var rerr *retry.Error
 	if err != nil {
@@ -82,7 +82,7 @@ func (c *Client) GetContainer(ctx context.Context, resourceGroupName, accountNam
 	if resourceGroupName == "" || accountName == "" || containerName == "" {
 		return storage.BlobContainer{}, fmt.Errorf("empty value in resourceGroupName(%s), accountName(%s), containerName(%s)", resourceGroupName, accountName, containerName)
 	}
-	mc := metrics.NewMetricContext("blob_container", "get", resourceGroupName, c.subscriptionID, "")
+	mc := metrics.NewMetricContext("blob_container", "get", resourceGroupName, c.subscriptionID, "").WithContext(ctx)
 	blobContainer, err := c.blobContainersClient.Get(ctx, resourceGroupName, accountName, containerName)
 	var rerr *retry.Error
 	if err != nil {
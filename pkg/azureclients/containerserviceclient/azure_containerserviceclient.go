@@ -79,9 +79,20 @@ func New(config *azclients.ClientConfig) *Client {
 	return client
 }
 
+// SetRateLimits replaces the rate limiters of the client with the ones created from the config.
+// The requests waiting for the old rate limiters are not affected.
+func (c *Client) SetRateLimits(config *azclients.RateLimitConfig) {
+	azclients.SetRateLimits(config, c.rateLimiterReader, c.rateLimiterWriter)
+}
+
+// SetBackoff updates the backoff of the retries of the new requests.
+func (c *Client) SetBackoff(backoff *retry.Backoff) {
+	c.armClient.SetBackoff(backoff)
+}
+
 // Get gets a ManagedCluster.
 func (c *Client) Get(ctx context.Context, resourceGroupName string, managedClusterName string) (containerservice.ManagedCluster, *retry.Error) {
-	mc := metrics.NewMetricContext("managed_clusters", "get", resourceGroupName, c.subscriptionID, "")
+	mc := metrics.NewMetricContext("managed_clusters", "get", resourceGroupName, c.subscriptionID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterReader.TryAccept() {
@@ -142,7 +153,7 @@ func (c *Client) getManagedCluster(ctx context.Context, resourceGroupName string
 
 // List gets a list of ManagedClusters in the resource group.
 func (c *Client) List(ctx context.Context, resourceGroupName string) ([]containerservice.ManagedCluster, *retry.Error) {
-	mc := metrics.NewMetricContext("managed_clusters", "list", resourceGroupName, c.subscriptionID, "")
+	mc := metrics.NewMetricContext("managed_clusters", "list", resourceGroupName, c.subscriptionID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterReader.TryAccept() {
@@ -301,7 +312,7 @@ func (page ManagedClusterResultPage) Values() []containerservice.ManagedCluster
 
 // CreateOrUpdate creates or updates a ManagedCluster.
 func (c *Client) CreateOrUpdate(ctx context.Context, resourceGroupName string, managedClusterName string, parameters containerservice.ManagedCluster, etag string) *retry.Error {
-	mc := metrics.NewMetricContext("managed_clusters", "create_or_update", resourceGroupName, c.subscriptionID, "")
+	mc := metrics.NewMetricContext("managed_clusters", "create_or_update", resourceGroupName, c.subscriptionID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterWriter.TryAccept() {
@@ -373,7 +384,7 @@ func (c *Client) createOrUpdateResponder(resp *http.Response) (*containerservice
 
 // Delete deletes a ManagedCluster by name.
 func (c *Client) Delete(ctx context.Context, resourceGroupName string, managedClusterName string) *retry.Error {
-	mc := metrics.NewMetricContext("managed_clusters", "delete", resourceGroupName, c.subscriptionID, "")
+	mc := metrics.NewMetricContext("managed_clusters", "delete", resourceGroupName, c.subscriptionID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterWriter.TryAccept() {
@@ -79,9 +79,20 @@ func New(config *azclients.ClientConfig) *Client {
 	return client
 }
 
+// SetRateLimits replaces the rate limiters of the client with the ones created from the config.
+// The requests waiting for the old rate limiters are not affected.
+func (c *Client) SetRateLimits(config *azclients.RateLimitConfig) {
+	azclients.SetRateLimits(config, c.rateLimiterReader, c.rateLimiterWriter)
+}
+
+// SetBackoff updates the backoff of the retries of the new requests.
+func (c *Client) SetBackoff(backoff *retry.Backoff) {
+	c.armClient.SetBackoff(backoff)
+}
+
 // Get gets a deployment
 func (c *Client) Get(ctx context.Context, resourceGroupName string, deploymentName string) (resources.DeploymentExtended, *retry.Error) {
-	mc := metrics.NewMetricContext("deployments", "get", resourceGroupName, c.subscriptionID, "")
+	mc := metrics.NewMetricContext("deployments", "get", resourceGroupName, c.subscriptionID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterReader.TryAccept() {
@@ -142,7 +153,7 @@ func (c *Client) getDeployment(ctx context.Context, resourceGroupName string, de
 
 // List gets a list of deployments in the resource group.
 func (c *Client) List(ctx context.Context, resourceGroupName string) ([]resources.DeploymentExtended, *retry.Error) {
-	mc := metrics.NewMetricContext("deployments", "list", resourceGroupName, c.subscriptionID, "")
+	mc := metrics.NewMetricContext("deployments", "list", resourceGroupName, c.subscriptionID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterReader.TryAccept() {
@@ -301,7 +312,7 @@ func (page DeploymentResultPage) Values() []resources.DeploymentExtended {
 
 // CreateOrUpdate creates or updates a deployment.
 func (c *Client) CreateOrUpdate(ctx context.Context, resourceGroupName string, deploymentName string, parameters resources.Deployment, etag string) *retry.Error {
-	mc := metrics.NewMetricContext("deployments", "create_or_update", resourceGroupName, c.subscriptionID, "")
+	mc := metrics.NewMetricContext("deployments", "create_or_update", resourceGroupName, c.subscriptionID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterWriter.TryAccept() {
@@ -372,7 +383,7 @@ func (c *Client) createOrUpdateResponder(resp *http.Response) (*resources.Deploy
 
 // Delete deletes a deployment by name.
 func (c *Client) Delete(ctx context.Context, resourceGroupName string, deploymentName string) *retry.Error {
-	mc := metrics.NewMetricContext("deployments", "delete", resourceGroupName, c.subscriptionID, "")
+	mc := metrics.NewMetricContext("deployments", "delete", resourceGroupName, c.subscriptionID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterWriter.TryAccept() {
@@ -415,7 +426,7 @@ func (c *Client) deleteDeployment(ctx context.Context, resourceGroupName string,
 
 // ExportTemplate exports the template used for specified deployment
 func (c *Client) ExportTemplate(ctx context.Context, resourceGroupName string, deploymentName string) (result resources.DeploymentExportResult, rerr *retry.Error) {
-	mc := metrics.NewMetricContext("deployments", "export_template", resourceGroupName, c.subscriptionID, "")
+	mc := metrics.NewMetricContext("deployments", "export_template", resourceGroupName, c.subscriptionID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterWriter.TryAccept() {
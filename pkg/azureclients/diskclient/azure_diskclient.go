@@ -38,7 +38,12 @@ import (
 
 var _ Interface = &Client{}
 
-const diskResourceType = "Microsoft.Compute/disks"
+const (
+	diskResourceType = "Microsoft.Compute/disks"
+
+	// diskStorageAccountTypesPremiumV2LRS is the Premium SSD v2 disk type, which is not defined in the compute API version of the SDK.
+	diskStorageAccountTypesPremiumV2LRS compute.DiskStorageAccountTypes = "PremiumV2_LRS"
+)
 
 // Client implements Disk client Interface.
 type Client struct {
@@ -89,12 +94,23 @@ func New(config *azclients.ClientConfig) *Client {
 	return client
 }
 
+// SetRateLimits replaces the rate limiters of the client with the ones created from the config.
+// The requests waiting for the old rate limiters are not affected.
+func (c *Client) SetRateLimits(config *azclients.RateLimitConfig) {
+	azclients.SetRateLimits(config, c.rateLimiterReader, c.rateLimiterWriter)
+}
+
+// SetBackoff updates the backoff of the retries of the new requests.
+func (c *Client) SetBackoff(backoff *retry.Backoff) {
+	c.armClient.SetBackoff(backoff)
+}
+
 // Get gets a Disk.
 func (c *Client) Get(ctx context.Context, subsID, resourceGroupName, diskName string) (compute.Disk, *retry.Error) {
 	if subsID == "" {
 		subsID = c.subscriptionID
 	}
-	mc := metrics.NewMetricContext("disks", "get", resourceGroupName, subsID, "")
+	mc := metrics.NewMetricContext("disks", "get", resourceGroupName, subsID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterReader.TryAccept() {
@@ -158,7 +174,7 @@ func (c *Client) CreateOrUpdate(ctx context.Context, subsID, resourceGroupName,
 	if subsID == "" {
 		subsID = c.subscriptionID
 	}
-	mc := metrics.NewMetricContext("disks", "create_or_update", resourceGroupName, subsID, "")
+	mc := metrics.NewMetricContext("disks", "create_or_update", resourceGroupName, subsID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterWriter.TryAccept() {
@@ -196,7 +212,12 @@ func (c *Client) createOrUpdateDisk(ctx context.Context, subsID, resourceGroupNa
 		diskName,
 	)
 
-	response, rerr := c.armClient.PutResource(ctx, resourceID, diskParameter)
+	decorators := []autorest.PrepareDecorator{}
+	if diskParameter.Sku != nil && diskParameter.Sku.Name == diskStorageAccountTypesPremiumV2LRS {
+		decorators = append(decorators, withPremiumV2APIVersion())
+	}
+
+	response, rerr := c.armClient.PutResource(ctx, resourceID, diskParameter, decorators...)
 	defer c.armClient.CloseResponse(ctx, response)
 	if rerr != nil {
 		klog.V(5).Infof("Received error in %s: resourceID: %s, error: %s", "disk.put.request", resourceID, rerr.Error())
@@ -229,7 +250,7 @@ func (c *Client) Update(ctx context.Context, subsID, resourceGroupName, diskName
 	if subsID == "" {
 		subsID = c.subscriptionID
 	}
-	mc := metrics.NewMetricContext("disks", "update", resourceGroupName, subsID, "")
+	mc := metrics.NewMetricContext("disks", "update", resourceGroupName, subsID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterWriter.TryAccept() {
@@ -267,7 +288,14 @@ func (c *Client) updateDisk(ctx context.Context, subsID, resourceGroupName, disk
 		diskName,
 	)
 
-	response, rerr := c.armClient.PatchResource(ctx, resourceID, diskParameter)
+	decorators := []autorest.PrepareDecorator{}
+	if (diskParameter.Sku != nil && diskParameter.Sku.Name == diskStorageAccountTypesPremiumV2LRS) ||
+		(diskParameter.DiskUpdateProperties != nil &&
+			(diskParameter.DiskIOPSReadWrite != nil || diskParameter.DiskMBpsReadWrite != nil)) {
+		decorators = append(decorators, withPremiumV2APIVersion())
+	}
+
+	response, rerr := c.armClient.PatchResource(ctx, resourceID, diskParameter, decorators...)
 	defer c.armClient.CloseResponse(ctx, response)
 	if rerr != nil {
 		klog.V(5).Infof("Received error in %s: resourceID: %s, error: %s", "disk.put.request", resourceID, rerr.Error())
@@ -295,12 +323,19 @@ func (c *Client) updateResponder(resp *http.Response) (*compute.Disk, *retry.Err
 	return result, retry.GetError(resp, err)
 }
 
+// withPremiumV2APIVersion sends the request in PremiumV2APIVersion instead of the API version of the client.
+func withPremiumV2APIVersion() autorest.PrepareDecorator {
+	return autorest.WithQueryParameters(map[string]interface{}{
+		"api-version": PremiumV2APIVersion,
+	})
+}
+
 // Delete deletes a Disk by name.
 func (c *Client) Delete(ctx context.Context, subsID, resourceGroupName, diskName string) *retry.Error {
 	if subsID == "" {
 		subsID = c.subscriptionID
 	}
-	mc := metrics.NewMetricContext("disks", "delete", resourceGroupName, subsID, "")
+	mc := metrics.NewMetricContext("disks", "delete", resourceGroupName, subsID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterWriter.TryAccept() {
@@ -215,6 +215,95 @@ func TestUpdate(t *testing.T) {
 	assert.Equal(t, throttleErr, rerr)
 }
 
+func TestPremiumV2APIVersion(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	premiumV2Disk := getTestDisk("disk1")
+	premiumV2Disk.Sku = &compute.DiskSku{Name: diskStorageAccountTypesPremiumV2LRS}
+	premiumDisk := getTestDisk("disk1")
+	premiumDisk.Sku = &compute.DiskSku{Name: compute.DiskStorageAccountTypesPremiumLRS}
+
+	testCases := []struct {
+		description        string
+		disk               *compute.Disk
+		diskUpdate         *compute.DiskUpdate
+		expectedAPIVersion string
+	}{
+		{
+			description:        "CreateOrUpdate should send the PremiumV2 API version for PremiumV2_LRS disks",
+			disk:               &premiumV2Disk,
+			expectedAPIVersion: PremiumV2APIVersion,
+		},
+		{
+			description:        "CreateOrUpdate should send the API version of the client for other disks",
+			disk:               &premiumDisk,
+			expectedAPIVersion: APIVersion,
+		},
+		{
+			description: "Update should send the PremiumV2 API version when updating the IOPS",
+			diskUpdate: &compute.DiskUpdate{
+				DiskUpdateProperties: &compute.DiskUpdateProperties{DiskIOPSReadWrite: to.Int64Ptr(4000)},
+			},
+			expectedAPIVersion: PremiumV2APIVersion,
+		},
+		{
+			description: "Update should send the PremiumV2 API version when updating the throughput",
+			diskUpdate: &compute.DiskUpdate{
+				DiskUpdateProperties: &compute.DiskUpdateProperties{DiskMBpsReadWrite: to.Int64Ptr(200)},
+			},
+			expectedAPIVersion: PremiumV2APIVersion,
+		},
+		{
+			description:        "Update should send the API version of the client when resizing the disk",
+			diskUpdate:         func() *compute.DiskUpdate { u := getTestDiskUpdate(); return &u }(),
+			expectedAPIVersion: APIVersion,
+		},
+	}
+
+	for _, test := range testCases {
+		armClient := mockarmclient.NewMockInterface(ctrl)
+		response := &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(""))),
+		}
+		var apiVersion string
+		recordAPIVersion := func(ctx context.Context, resourceID string, parameters interface{}, decorators ...autorest.PrepareDecorator) (*http.Response, *retry.Error) {
+			apiVersion = getRequestAPIVersion(t, decorators...)
+			return response, nil
+		}
+		armClient.EXPECT().PutResource(gomock.Any(), testResourceID, gomock.Any(), gomock.Any()).DoAndReturn(recordAPIVersion).AnyTimes()
+		armClient.EXPECT().PatchResource(gomock.Any(), testResourceID, gomock.Any(), gomock.Any()).DoAndReturn(recordAPIVersion).AnyTimes()
+		armClient.EXPECT().PutResource(gomock.Any(), testResourceID, gomock.Any()).DoAndReturn(recordAPIVersion).AnyTimes()
+		armClient.EXPECT().PatchResource(gomock.Any(), testResourceID, gomock.Any()).DoAndReturn(recordAPIVersion).AnyTimes()
+		armClient.EXPECT().CloseResponse(gomock.Any(), gomock.Any()).Times(1)
+
+		diskClient := getTestDiskClient(armClient)
+		var rerr *retry.Error
+		if test.disk != nil {
+			rerr = diskClient.CreateOrUpdate(context.TODO(), "", "rg", "disk1", *test.disk)
+		} else {
+			rerr = diskClient.Update(context.TODO(), "", "rg", "disk1", *test.diskUpdate)
+		}
+		assert.Nil(t, rerr, test.description)
+		assert.Equal(t, test.expectedAPIVersion, apiVersion, test.description)
+	}
+}
+
+// getRequestAPIVersion returns the api-version set by the decorators, or the API version of the client if they set none.
+func getRequestAPIVersion(t *testing.T, decorators ...autorest.PrepareDecorator) string {
+	decorators = append([]autorest.PrepareDecorator{
+		autorest.WithBaseURL("https://management.azure.com"),
+		autorest.WithPath(testResourceID),
+	}, decorators...)
+	request, err := autorest.CreatePreparer(decorators...).Prepare(&http.Request{})
+	assert.NoError(t, err)
+	if apiVersion := request.URL.Query().Get("api-version"); apiVersion != "" {
+		return apiVersion
+	}
+	return APIVersion
+}
+
 func getTestDiskUpdate() compute.DiskUpdate {
 	return compute.DiskUpdate{
 		DiskUpdateProperties: &compute.DiskUpdateProperties{
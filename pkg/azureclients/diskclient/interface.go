@@ -27,6 +27,9 @@ import (
 const (
 	// APIVersion is the API version for compute.
 	APIVersion = "2021-04-01"
+	// PremiumV2APIVersion is the API version for the requests creating PremiumV2_LRS disks or updating the
+	// provisioned IOPS and throughput of the disks, which is only sent on those requests.
+	PremiumV2APIVersion = "2022-07-02"
 	// AzureStackCloudAPIVersion is the API version for Azure Stack
 	AzureStackCloudAPIVersion = "2019-03-01"
 	// AzureStackCloudName is the cloud name of Azure Stack
@@ -56,6 +56,9 @@ type Client struct {
 	RetryAfterReader time.Time
 	RetryAfterWriter time.Time
 
+	// invalidationRegistry is notified after the resources are mutated.
+	invalidationRegistry *azclients.InvalidationRegistry
+
 	computeAPIVersion string
 }
 
@@ -92,14 +95,26 @@ func New(config *azclients.ClientConfig) *Client {
 		cloudName:              config.CloudName,
 		disableAzureStackCloud: config.DisableAzureStackCloud,
 		computeAPIVersion:      computeAPIVersion,
+		invalidationRegistry:   config.InvalidationRegistry,
 	}
 
 	return client
 }
 
+// SetRateLimits replaces the rate limiters of the client with the ones created from the config.
+// The requests waiting for the old rate limiters are not affected.
+func (c *Client) SetRateLimits(config *azclients.RateLimitConfig) {
+	azclients.SetRateLimits(config, c.rateLimiterReader, c.rateLimiterWriter)
+}
+
+// SetBackoff updates the backoff of the retries of the new requests.
+func (c *Client) SetBackoff(backoff *retry.Backoff) {
+	c.armClient.SetBackoff(backoff)
+}
+
 // Get gets a network.Interface.
 func (c *Client) Get(ctx context.Context, resourceGroupName string, networkInterfaceName string, expand string) (network.Interface, *retry.Error) {
-	mc := metrics.NewMetricContext("interfaces", "get", resourceGroupName, c.subscriptionID, "")
+	mc := metrics.NewMetricContext("interfaces", "get", resourceGroupName, c.subscriptionID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterReader.TryAccept() {
@@ -160,7 +175,7 @@ func (c *Client) getNetworkInterface(ctx context.Context, resourceGroupName stri
 
 // GetVirtualMachineScaleSetNetworkInterface gets a network.Interface of VMSS VM.
 func (c *Client) GetVirtualMachineScaleSetNetworkInterface(ctx context.Context, resourceGroupName string, virtualMachineScaleSetName string, virtualmachineIndex string, networkInterfaceName string, expand string) (network.Interface, *retry.Error) {
-	mc := metrics.NewMetricContext("interfaces", "get_vmss_nic", resourceGroupName, c.subscriptionID, "")
+	mc := metrics.NewMetricContext("interfaces", "get_vmss_nic", resourceGroupName, c.subscriptionID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterReader.TryAccept() {
@@ -221,9 +236,78 @@ func (c *Client) getVMSSNetworkInterface(ctx context.Context, resourceGroupName
 	return result, nil
 }
 
+// List gets a list of network.Interface in the resource group.
+func (c *Client) List(ctx context.Context, resourceGroupName string) ([]network.Interface, *retry.Error) {
+	mc := metrics.NewMetricContext("interfaces", "list", resourceGroupName, c.subscriptionID, "").WithContext(ctx)
+
+	// Report errors if the client is rate limited.
+	if !c.rateLimiterReader.TryAccept() {
+		mc.RateLimitedCount()
+		return nil, retry.GetRateLimitError(false, "NicList")
+	}
+
+	// Report errors if the client is throttled.
+	if c.RetryAfterReader.After(time.Now()) {
+		mc.ThrottledCount()
+		rerr := retry.GetThrottlingError("NicList", "client throttled", c.RetryAfterReader)
+		return nil, rerr
+	}
+
+	result, rerr := c.listNetworkInterface(ctx, resourceGroupName)
+	mc.Observe(rerr)
+	if rerr != nil {
+		if rerr.IsThrottled() {
+			// Update RetryAfterReader so that no more requests would be sent until RetryAfter expires.
+			c.RetryAfterReader = rerr.RetryAfter
+		}
+
+		return result, rerr
+	}
+
+	return result, nil
+}
+
+// listNetworkInterface gets a list of network.Interface in the resource group.
+func (c *Client) listNetworkInterface(ctx context.Context, resourceGroupName string) ([]network.Interface, *retry.Error) {
+	resourceID := armclient.GetResourceListID(c.subscriptionID, resourceGroupName, netInterfaceResourceType)
+	result := make([]network.Interface, 0)
+	page := &InterfaceListResultPage{}
+	page.fn = c.listNextResults
+
+	resp, rerr := c.armClient.GetResource(ctx, resourceID)
+	defer c.armClient.CloseResponse(ctx, resp)
+	if rerr != nil {
+		klog.V(5).Infof("Received error in %s: resourceID: %s, error: %s", "nic.list.request", resourceID, rerr.Error())
+		return result, rerr
+	}
+
+	var err error
+	page.ilr, err = c.listResponder(resp)
+	if err != nil {
+		klog.V(5).Infof("Received error in %s: resourceID: %s, error: %s", "nic.list.respond", resourceID, err)
+		return result, retry.GetError(resp, err)
+	}
+
+	for {
+		result = append(result, page.Values()...)
+
+		// Abort the loop when there's no nextLink in the response.
+		if to.String(page.Response().NextLink) == "" {
+			break
+		}
+
+		if err = page.NextWithContext(ctx); err != nil {
+			klog.V(5).Infof("Received error in %s: resourceID: %s, error: %s", "nic.list.next", resourceID, err)
+			return result, retry.GetError(page.Response().Response.Response, err)
+		}
+	}
+
+	return result, nil
+}
+
 // CreateOrUpdate creates or updates a network.Interface.
 func (c *Client) CreateOrUpdate(ctx context.Context, resourceGroupName string, networkInterfaceName string, parameters network.Interface) *retry.Error {
-	mc := metrics.NewMetricContext("interfaces", "create_or_update", resourceGroupName, c.subscriptionID, "")
+	mc := metrics.NewMetricContext("interfaces", "create_or_update", resourceGroupName, c.subscriptionID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterWriter.TryAccept() {
@@ -272,6 +356,9 @@ func (c *Client) createOrUpdateInterface(ctx context.Context, resourceGroupName
 		return rerr
 	}
 
+	// Invalidate the cached Interface right after it is mutated.
+	c.invalidationRegistry.Invalidate(netInterfaceResourceType, resourceID)
+
 	if response != nil && response.StatusCode != http.StatusNoContent {
 		_, rerr = c.createOrUpdateResponder(response)
 		if rerr != nil {
@@ -295,7 +382,7 @@ func (c *Client) createOrUpdateResponder(resp *http.Response) (*network.Interfac
 
 // Delete deletes a network interface by name.
 func (c *Client) Delete(ctx context.Context, resourceGroupName string, networkInterfaceName string) *retry.Error {
-	mc := metrics.NewMetricContext("interfaces", "delete", resourceGroupName, c.subscriptionID, "")
+	mc := metrics.NewMetricContext("interfaces", "delete", resourceGroupName, c.subscriptionID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterWriter.TryAccept() {
@@ -335,3 +422,93 @@ func (c *Client) deleteInterface(ctx context.Context, resourceGroupName string,
 
 	return c.armClient.DeleteResource(ctx, resourceID)
 }
+
+func (c *Client) listResponder(resp *http.Response) (result network.InterfaceListResult, err error) {
+	err = autorest.Respond(
+		resp,
+		autorest.ByIgnoring(),
+		azure.WithErrorUnlessStatusCode(http.StatusOK),
+		autorest.ByUnmarshallingJSON(&result))
+	result.Response = autorest.Response{Response: resp}
+	return
+}
+
+// interfaceListResultPreparer prepares a request to retrieve the next set of results.
+// It returns nil if no more results exist.
+func (c *Client) interfaceListResultPreparer(ctx context.Context, lr network.InterfaceListResult) (*http.Request, error) {
+	if lr.NextLink == nil || len(to.String(lr.NextLink)) < 1 {
+		return nil, nil
+	}
+
+	decorators := []autorest.PrepareDecorator{
+		autorest.WithBaseURL(to.String(lr.NextLink)),
+	}
+	return c.armClient.PrepareGetRequest(ctx, decorators...)
+}
+
+// listNextResults retrieves the next set of results, if any.
+func (c *Client) listNextResults(ctx context.Context, lastResults network.InterfaceListResult) (result network.InterfaceListResult, err error) {
+	req, err := c.interfaceListResultPreparer(ctx, lastResults)
+	if err != nil {
+		return result, autorest.NewErrorWithError(err, "interfaceclient", "listNextResults", nil, "Failure preparing next results request")
+	}
+	if req == nil {
+		return
+	}
+
+	resp, rerr := c.armClient.Send(ctx, req)
+	defer c.armClient.CloseResponse(ctx, resp)
+	if rerr != nil {
+		result.Response = autorest.Response{Response: resp}
+		return result, autorest.NewErrorWithError(rerr.Error(), "interfaceclient", "listNextResults", resp, "Failure sending next results request")
+	}
+
+	result, err = c.listResponder(resp)
+	if err != nil {
+		err = autorest.NewErrorWithError(err, "interfaceclient", "listNextResults", resp, "Failure responding to next results request")
+	}
+
+	return
+}
+
+// InterfaceListResultPage contains a page of Interface values.
+type InterfaceListResultPage struct {
+	fn  func(context.Context, network.InterfaceListResult) (network.InterfaceListResult, error)
+	ilr network.InterfaceListResult
+}
+
+// NextWithContext advances to the next page of values.  If there was an error making
+// the request the page does not advance and the error is returned.
+func (page *InterfaceListResultPage) NextWithContext(ctx context.Context) (err error) {
+	next, err := page.fn(ctx, page.ilr)
+	if err != nil {
+		return err
+	}
+	page.ilr = next
+	return nil
+}
+
+// Next advances to the next page of values.  If there was an error making
+// the request the page does not advance and the error is returned.
+// Deprecated: Use NextWithContext() instead.
+func (page *InterfaceListResultPage) Next() error {
+	return page.NextWithContext(context.Background())
+}
+
+// NotDone returns true if the page enumeration should be started or is not yet complete.
+func (page InterfaceListResultPage) NotDone() bool {
+	return !page.ilr.IsEmpty()
+}
+
+// Response returns the raw server response from the last page request.
+func (page InterfaceListResultPage) Response() network.InterfaceListResult {
+	return page.ilr
+}
+
+// Values returns the slice of values for the current page or nil if there are no values.
+func (page InterfaceListResultPage) Values() []network.Interface {
+	if page.ilr.IsEmpty() {
+		return nil
+	}
+	return *page.ilr.Value
+}
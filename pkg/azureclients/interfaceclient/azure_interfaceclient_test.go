@@ -19,6 +19,7 @@ package interfaceclient
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -37,7 +38,10 @@ import (
 	"sigs.k8s.io/cloud-provider-azure/pkg/retry"
 )
 
-const testResourceID = "/subscriptions/subscriptionID/resourceGroups/rg/providers/Microsoft.Network/networkInterfaces/nic1"
+const (
+	testResourceID   = "/subscriptions/subscriptionID/resourceGroups/rg/providers/Microsoft.Network/networkInterfaces/nic1"
+	resourceIDPrefix = "/subscriptions/subscriptionID/resourceGroups/rg/providers/Microsoft.Network/networkInterfaces"
+)
 
 func TestNew(t *testing.T) {
 	config := &azclients.ClientConfig{
@@ -223,6 +227,120 @@ func TestCreateOrUpdate(t *testing.T) {
 	assert.Equal(t, noContentErr, rerr)
 }
 
+func TestCreateOrUpdateInvalidatesCache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testInterface := getTestInterface("nic1")
+	armClient := mockarmclient.NewMockInterface(ctrl)
+	response := &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(bytes.NewReader([]byte(""))),
+	}
+	armClient.EXPECT().PutResource(gomock.Any(), to.String(testInterface.ID), testInterface, gomock.Any()).Return(response, nil).Times(1)
+	armClient.EXPECT().CloseResponse(gomock.Any(), gomock.Any()).Times(1)
+
+	var invalidated []string
+	nicClient := getTestInterfaceClient(armClient)
+	nicClient.invalidationRegistry = azclients.NewInvalidationRegistry()
+	nicClient.invalidationRegistry.Register(netInterfaceResourceType, func(resourceID string) {
+		invalidated = append(invalidated, resourceID)
+	})
+	rerr := nicClient.CreateOrUpdate(context.TODO(), "rg", "nic1", testInterface)
+	assert.Nil(t, rerr)
+	assert.Equal(t, []string{testResourceID}, invalidated)
+}
+
+func TestList(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	armClient := mockarmclient.NewMockInterface(ctrl)
+	nicList := []network.Interface{getTestInterface("interface1"), getTestInterface("interface2"), getTestInterface("interface3")}
+	responseBody, err := json.Marshal(network.InterfaceListResult{Value: &nicList})
+	assert.NoError(t, err)
+	armClient.EXPECT().GetResource(gomock.Any(), resourceIDPrefix).Return(
+		&http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader(responseBody)),
+		}, nil).Times(1)
+	armClient.EXPECT().CloseResponse(gomock.Any(), gomock.Any()).Times(1)
+
+	nicClient := getTestInterfaceClient(armClient)
+	result, rerr := nicClient.List(context.TODO(), "rg")
+	assert.Nil(t, rerr)
+	assert.Equal(t, 3, len(result))
+}
+
+func TestListWithNextPage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	armClient := mockarmclient.NewMockInterface(ctrl)
+	nicList := []network.Interface{getTestInterface("interface1"), getTestInterface("interface2"), getTestInterface("interface3")}
+	// NextLink is read-only and is not marshaled by network.InterfaceListResult.
+	partialResponse, err := json.Marshal(map[string]interface{}{"value": nicList, "nextLink": "nextLink"})
+	assert.NoError(t, err)
+	pagedResponse, err := json.Marshal(network.InterfaceListResult{Value: &nicList})
+	assert.NoError(t, err)
+	armClient.EXPECT().PrepareGetRequest(gomock.Any(), gomock.Any()).Return(&http.Request{}, nil)
+	armClient.EXPECT().Send(gomock.Any(), gomock.Any()).Return(
+		&http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader(pagedResponse)),
+		}, nil)
+	armClient.EXPECT().GetResource(gomock.Any(), resourceIDPrefix).Return(
+		&http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader(partialResponse)),
+		}, nil).Times(1)
+	armClient.EXPECT().CloseResponse(gomock.Any(), gomock.Any()).Times(2)
+
+	nicClient := getTestInterfaceClient(armClient)
+	result, rerr := nicClient.List(context.TODO(), "rg")
+	assert.Nil(t, rerr)
+	assert.Equal(t, 6, len(result))
+}
+
+func TestListInternalError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	response := &http.Response{
+		StatusCode: http.StatusInternalServerError,
+		Body:       ioutil.NopCloser(bytes.NewReader([]byte("{}"))),
+	}
+	armClient := mockarmclient.NewMockInterface(ctrl)
+	armClient.EXPECT().GetResource(gomock.Any(), resourceIDPrefix).Return(response, nil).Times(1)
+	armClient.EXPECT().CloseResponse(gomock.Any(), gomock.Any()).Times(1)
+
+	nicClient := getTestInterfaceClient(armClient)
+	result, rerr := nicClient.List(context.TODO(), "rg")
+	assert.Equal(t, []network.Interface{}, result)
+	assert.NotNil(t, rerr)
+	assert.Equal(t, http.StatusInternalServerError, rerr.HTTPStatusCode)
+}
+
+func TestListThrottle(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	throttleErr := &retry.Error{
+		HTTPStatusCode: http.StatusTooManyRequests,
+		RawError:       fmt.Errorf("error"),
+		Retriable:      true,
+		RetryAfter:     time.Unix(100, 0),
+	}
+	armClient := mockarmclient.NewMockInterface(ctrl)
+	armClient.EXPECT().GetResource(gomock.Any(), resourceIDPrefix).Return(&http.Response{}, throttleErr).Times(1)
+	armClient.EXPECT().CloseResponse(gomock.Any(), gomock.Any()).Times(1)
+
+	nicClient := getTestInterfaceClient(armClient)
+	result, rerr := nicClient.List(context.TODO(), "rg")
+	assert.Empty(t, result)
+	assert.Equal(t, throttleErr, rerr)
+}
+
 func TestDelete(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -45,6 +45,9 @@ type Interface interface {
 	// Get gets a network.Interface.
 	Get(ctx context.Context, resourceGroupName string, networkInterfaceName string, expand string) (result network.Interface, rerr *retry.Error)
 
+	// List gets a list of network.Interface in the resource group.
+	List(ctx context.Context, resourceGroupName string) (result []network.Interface, rerr *retry.Error)
+
 	// GetVirtualMachineScaleSetNetworkInterface gets a network.Interface of VMSS VM.
 	GetVirtualMachineScaleSetNetworkInterface(ctx context.Context, resourceGroupName string, virtualMachineScaleSetName string, virtualmachineIndex string, networkInterfaceName string, expand string) (result network.Interface, rerr *retry.Error)
 
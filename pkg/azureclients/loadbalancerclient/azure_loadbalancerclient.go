@@ -53,6 +53,9 @@ type Client struct {
 	// ARM throttling configures.
 	RetryAfterReader time.Time
 	RetryAfterWriter time.Time
+
+	// invalidationRegistry is notified after the resources are mutated.
+	invalidationRegistry *azclients.InvalidationRegistry
 }
 
 // New creates a new LoadBalancer client with ratelimiting.
@@ -76,19 +79,31 @@ func New(config *azclients.ClientConfig) *Client {
 	}
 
 	client := &Client{
-		armClient:         armClient,
-		rateLimiterReader: rateLimiterReader,
-		rateLimiterWriter: rateLimiterWriter,
-		subscriptionID:    config.SubscriptionID,
-		cloudName:         config.CloudName,
+		armClient:            armClient,
+		rateLimiterReader:    rateLimiterReader,
+		rateLimiterWriter:    rateLimiterWriter,
+		subscriptionID:       config.SubscriptionID,
+		cloudName:            config.CloudName,
+		invalidationRegistry: config.InvalidationRegistry,
 	}
 
 	return client
 }
 
+// SetRateLimits replaces the rate limiters of the client with the ones created from the config.
+// The requests waiting for the old rate limiters are not affected.
+func (c *Client) SetRateLimits(config *azclients.RateLimitConfig) {
+	azclients.SetRateLimits(config, c.rateLimiterReader, c.rateLimiterWriter)
+}
+
+// SetBackoff updates the backoff of the retries of the new requests.
+func (c *Client) SetBackoff(backoff *retry.Backoff) {
+	c.armClient.SetBackoff(backoff)
+}
+
 // Get gets a LoadBalancer.
 func (c *Client) Get(ctx context.Context, resourceGroupName string, loadBalancerName string, expand string) (network.LoadBalancer, *retry.Error) {
-	mc := metrics.NewMetricContext("load_balancers", "get", resourceGroupName, c.subscriptionID, "")
+	mc := metrics.NewMetricContext("load_balancers", "get", resourceGroupName, c.subscriptionID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterReader.TryAccept() {
@@ -149,7 +164,7 @@ func (c *Client) getLB(ctx context.Context, resourceGroupName string, loadBalanc
 
 // List gets a list of LoadBalancer in the resource group.
 func (c *Client) List(ctx context.Context, resourceGroupName string) ([]network.LoadBalancer, *retry.Error) {
-	mc := metrics.NewMetricContext("load_balancers", "list", resourceGroupName, c.subscriptionID, "")
+	mc := metrics.NewMetricContext("load_balancers", "list", resourceGroupName, c.subscriptionID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterReader.TryAccept() {
@@ -218,7 +233,7 @@ func (c *Client) listLB(ctx context.Context, resourceGroupName string) ([]networ
 
 // CreateOrUpdate creates or updates a LoadBalancer.
 func (c *Client) CreateOrUpdate(ctx context.Context, resourceGroupName string, loadBalancerName string, parameters network.LoadBalancer, etag string) *retry.Error {
-	mc := metrics.NewMetricContext("load_balancers", "create_or_update", resourceGroupName, c.subscriptionID, "")
+	mc := metrics.NewMetricContext("load_balancers", "create_or_update", resourceGroupName, c.subscriptionID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterWriter.TryAccept() {
@@ -267,6 +282,9 @@ func (c *Client) createOrUpdateLB(ctx context.Context, resourceGroupName string,
 		return rerr
 	}
 
+	// Invalidate the cached LoadBalancer right after it is mutated.
+	c.invalidationRegistry.Invalidate(lbResourceType, resourceID)
+
 	if response != nil && response.StatusCode != http.StatusNoContent {
 		_, rerr = c.createOrUpdateResponder(response)
 		if rerr != nil {
@@ -290,7 +308,7 @@ func (c *Client) createOrUpdateResponder(resp *http.Response) (*network.LoadBala
 
 // Delete deletes a LoadBalancer by name.
 func (c *Client) Delete(ctx context.Context, resourceGroupName string, loadBalancerName string) *retry.Error {
-	mc := metrics.NewMetricContext("load_balancers", "delete", resourceGroupName, c.subscriptionID, "")
+	mc := metrics.NewMetricContext("load_balancers", "delete", resourceGroupName, c.subscriptionID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterWriter.TryAccept() {
@@ -328,7 +346,11 @@ func (c *Client) deleteLB(ctx context.Context, resourceGroupName string, loadBal
 		loadBalancerName,
 	)
 
-	return c.armClient.DeleteResource(ctx, resourceID)
+	rerr := c.armClient.DeleteResource(ctx, resourceID)
+	if rerr == nil {
+		c.invalidationRegistry.Invalidate(lbResourceType, resourceID)
+	}
+	return rerr
 }
 
 func (c *Client) listResponder(resp *http.Response) (result network.LoadBalancerListResult, err error) {
@@ -423,7 +445,7 @@ func (page LoadBalancerListResultPage) Values() []network.LoadBalancer {
 
 // CreateOrUpdateBackendPools creates or updates a LoadBalancer backend pool.
 func (c *Client) CreateOrUpdateBackendPools(ctx context.Context, resourceGroupName string, loadBalancerName string, backendPoolName string, parameters network.BackendAddressPool, etag string) *retry.Error {
-	mc := metrics.NewMetricContext("load_balancers", "create_or_update_backend_pools", resourceGroupName, c.subscriptionID, "")
+	mc := metrics.NewMetricContext("load_balancers", "create_or_update_backend_pools", resourceGroupName, c.subscriptionID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterWriter.TryAccept() {
@@ -474,6 +496,9 @@ func (c *Client) createOrUpdateLBBackendPool(ctx context.Context, resourceGroupN
 		return rerr
 	}
 
+	// Invalidate the cached LoadBalancer right after its backend pool is mutated.
+	c.invalidationRegistry.Invalidate(lbResourceType, armclient.GetResourceID(c.subscriptionID, resourceGroupName, lbResourceType, loadBalancerName))
+
 	if response != nil && response.StatusCode != http.StatusNoContent {
 		_, rerr = c.createOrUpdateBackendPoolResponder(response)
 		if rerr != nil {
@@ -487,7 +512,7 @@ func (c *Client) createOrUpdateLBBackendPool(ctx context.Context, resourceGroupN
 
 // DeleteLBBackendPool deletes a LoadBalancer backend pool by name.
 func (c *Client) DeleteLBBackendPool(ctx context.Context, resourceGroupName, loadBalancerName, backendPoolName string) *retry.Error {
-	mc := metrics.NewMetricContext("load_balancers", "delete_backend_pool", resourceGroupName, c.subscriptionID, "")
+	mc := metrics.NewMetricContext("load_balancers", "delete_backend_pool", resourceGroupName, c.subscriptionID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterWriter.TryAccept() {
@@ -525,7 +550,11 @@ func (c *Client) deleteLBBackendPool(ctx context.Context, resourceGroupName, loa
 		"backendAddressPools",
 		backendPoolName,
 	)
-	return c.armClient.DeleteResource(ctx, resourceID)
+	rerr := c.armClient.DeleteResource(ctx, resourceID)
+	if rerr == nil {
+		c.invalidationRegistry.Invalidate(lbResourceType, armclient.GetResourceID(c.subscriptionID, resourceGroupName, lbResourceType, loadBalancerName))
+	}
+	return rerr
 }
 
 func (c *Client) createOrUpdateBackendPoolResponder(resp *http.Response) (*network.BackendAddressPool, *retry.Error) {
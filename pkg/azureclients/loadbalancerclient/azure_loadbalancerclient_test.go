@@ -32,9 +32,14 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/component-base/metrics/testutil"
+
 	azclients "sigs.k8s.io/cloud-provider-azure/pkg/azureclients"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/armclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/armclient/mockarmclient"
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+	"sigs.k8s.io/cloud-provider-azure/pkg/metrics"
 	"sigs.k8s.io/cloud-provider-azure/pkg/retry"
 )
 
@@ -149,6 +154,62 @@ func TestGetThrottle(t *testing.T) {
 	assert.Equal(t, throttleErr, rerr)
 }
 
+func TestGetMetricSource(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	armClient := mockarmclient.NewMockInterface(ctrl)
+	armClient.EXPECT().GetResourceWithExpandQuery(gomock.Any(), testResourceID, "").Return(&http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(bytes.NewReader([]byte("{}"))),
+	}, nil).Times(1)
+	armClient.EXPECT().CloseResponse(gomock.Any(), gomock.Any()).Times(1)
+
+	lbClient := getTestLoadBalancerClient(armClient)
+	_, rerr := lbClient.Get(metrics.WithSource(context.TODO(), metrics.SourceServiceController), "rg", "lb1", "")
+	assert.Nil(t, rerr)
+
+	vec, err := testutil.GetHistogramVecFromGatherer(legacyregistry.DefaultGatherer, consts.AzureMetricsNamespace+"_api_request_duration_seconds",
+		map[string]string{"request": "load_balancers_get", "source_controller": metrics.SourceServiceController})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), vec.GetAggregatedSampleCount())
+}
+
+func TestSetRateLimits(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	armClient := mockarmclient.NewMockInterface(ctrl)
+	armClient.EXPECT().GetResourceWithExpandQuery(gomock.Any(), testResourceID, "").Return(&http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(bytes.NewReader([]byte("{}"))),
+	}, nil).Times(1)
+	armClient.EXPECT().CloseResponse(gomock.Any(), gomock.Any()).Times(1)
+
+	lbClient := getTestLoadBalancerClient(armClient)
+	lbClient.SetRateLimits(&azclients.RateLimitConfig{
+		CloudProviderRateLimit:       true,
+		CloudProviderRateLimitQPS:    0.001,
+		CloudProviderRateLimitBucket: 1,
+	})
+	_, rerr := lbClient.Get(context.TODO(), "rg", "lb1", "")
+	assert.Nil(t, rerr)
+	_, rerr = lbClient.Get(context.TODO(), "rg", "lb1", "")
+	assert.Equal(t, retry.GetRateLimitError(false, "LBGet"), rerr)
+}
+
+func TestSetBackoff(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	backoff := &retry.Backoff{Steps: 3, Duration: time.Second}
+	armClient := mockarmclient.NewMockInterface(ctrl)
+	armClient.EXPECT().SetBackoff(backoff).Times(1)
+
+	lbClient := getTestLoadBalancerClient(armClient)
+	lbClient.SetBackoff(backoff)
+}
+
 func TestList(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -297,6 +358,43 @@ func TestCreateOrUpdateBackendPools(t *testing.T) {
 	assert.Nil(t, rerr)
 }
 
+func TestCreateOrUpdateInvalidatesCache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	lb := getTestLoadBalancer("lb1")
+	backendAddressPool := getTestBackendAddressPool("lb1", "backendAddressPool1")
+	armClient := mockarmclient.NewMockInterface(ctrl)
+	response := &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(bytes.NewReader([]byte(""))),
+	}
+	armClient.EXPECT().PutResource(gomock.Any(), to.String(lb.ID), lb, gomock.Any()).Return(response, nil).Times(1)
+	armClient.EXPECT().PutResource(gomock.Any(), to.String(backendAddressPool.ID), backendAddressPool, gomock.Any()).Return(response, nil).Times(1)
+	armClient.EXPECT().PutResource(gomock.Any(), to.String(lb.ID), lb, gomock.Any()).Return(nil, retry.NewError(false, fmt.Errorf("failed"))).Times(1)
+	armClient.EXPECT().CloseResponse(gomock.Any(), gomock.Any()).Times(3)
+	armClient.EXPECT().DeleteResource(gomock.Any(), to.String(lb.ID)).Return(nil).Times(1)
+
+	var invalidated []string
+	lbClient := getTestLoadBalancerClient(armClient)
+	lbClient.invalidationRegistry = azclients.NewInvalidationRegistry()
+	lbClient.invalidationRegistry.Register(lbResourceType, func(resourceID string) {
+		invalidated = append(invalidated, resourceID)
+	})
+
+	rerr := lbClient.CreateOrUpdate(context.TODO(), "rg", "lb1", lb, "")
+	assert.Nil(t, rerr)
+	rerr = lbClient.CreateOrUpdateBackendPools(context.TODO(), "rg", "lb1", "backendAddressPool1", backendAddressPool, "")
+	assert.Nil(t, rerr)
+	rerr = lbClient.CreateOrUpdate(context.TODO(), "rg", "lb1", lb, "")
+	assert.NotNil(t, rerr)
+	rerr = lbClient.Delete(context.TODO(), "rg", "lb1")
+	assert.Nil(t, rerr)
+
+	// the failed PUT should not invalidate the cache.
+	assert.Equal(t, []string{testResourceID, testResourceID, testResourceID}, invalidated)
+}
+
 func TestDelete(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
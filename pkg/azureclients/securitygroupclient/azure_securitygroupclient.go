@@ -53,6 +53,9 @@ type Client struct {
 	// ARM throttling configures.
 	RetryAfterReader time.Time
 	RetryAfterWriter time.Time
+
+	// invalidationRegistry is notified after the resources are mutated.
+	invalidationRegistry *azclients.InvalidationRegistry
 }
 
 // New creates a new SecurityGroup client with ratelimiting.
@@ -76,19 +79,31 @@ func New(config *azclients.ClientConfig) *Client {
 	}
 
 	client := &Client{
-		armClient:         armClient,
-		rateLimiterReader: rateLimiterReader,
-		rateLimiterWriter: rateLimiterWriter,
-		subscriptionID:    config.SubscriptionID,
-		cloudName:         config.CloudName,
+		armClient:            armClient,
+		rateLimiterReader:    rateLimiterReader,
+		rateLimiterWriter:    rateLimiterWriter,
+		subscriptionID:       config.SubscriptionID,
+		cloudName:            config.CloudName,
+		invalidationRegistry: config.InvalidationRegistry,
 	}
 
 	return client
 }
 
+// SetRateLimits replaces the rate limiters of the client with the ones created from the config.
+// The requests waiting for the old rate limiters are not affected.
+func (c *Client) SetRateLimits(config *azclients.RateLimitConfig) {
+	azclients.SetRateLimits(config, c.rateLimiterReader, c.rateLimiterWriter)
+}
+
+// SetBackoff updates the backoff of the retries of the new requests.
+func (c *Client) SetBackoff(backoff *retry.Backoff) {
+	c.armClient.SetBackoff(backoff)
+}
+
 // Get gets a SecurityGroup.
 func (c *Client) Get(ctx context.Context, resourceGroupName string, networkSecurityGroupName string, expand string) (network.SecurityGroup, *retry.Error) {
-	mc := metrics.NewMetricContext("security_groups", "get", resourceGroupName, c.subscriptionID, "")
+	mc := metrics.NewMetricContext("security_groups", "get", resourceGroupName, c.subscriptionID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterReader.TryAccept() {
@@ -149,7 +164,7 @@ func (c *Client) getSecurityGroup(ctx context.Context, resourceGroupName string,
 
 // List gets a list of SecurityGroups in the resource group.
 func (c *Client) List(ctx context.Context, resourceGroupName string) ([]network.SecurityGroup, *retry.Error) {
-	mc := metrics.NewMetricContext("security_groups", "list", resourceGroupName, c.subscriptionID, "")
+	mc := metrics.NewMetricContext("security_groups", "list", resourceGroupName, c.subscriptionID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterReader.TryAccept() {
@@ -218,7 +233,7 @@ func (c *Client) listSecurityGroup(ctx context.Context, resourceGroupName string
 
 // CreateOrUpdate creates or updates a SecurityGroup.
 func (c *Client) CreateOrUpdate(ctx context.Context, resourceGroupName string, networkSecurityGroupName string, parameters network.SecurityGroup, etag string) *retry.Error {
-	mc := metrics.NewMetricContext("security_groups", "create_or_update", resourceGroupName, c.subscriptionID, "")
+	mc := metrics.NewMetricContext("security_groups", "create_or_update", resourceGroupName, c.subscriptionID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterWriter.TryAccept() {
@@ -267,6 +282,9 @@ func (c *Client) createOrUpdateNSG(ctx context.Context, resourceGroupName string
 		return rerr
 	}
 
+	// Invalidate the cached SecurityGroup right after it is mutated.
+	c.invalidationRegistry.Invalidate(nsgResourceType, resourceID)
+
 	if response != nil && response.StatusCode != http.StatusNoContent {
 		_, rerr = c.createOrUpdateResponder(response)
 		if rerr != nil {
@@ -290,7 +308,7 @@ func (c *Client) createOrUpdateResponder(resp *http.Response) (*network.Security
 
 // Delete deletes a SecurityGroup by name.
 func (c *Client) Delete(ctx context.Context, resourceGroupName string, networkSecurityGroupName string) *retry.Error {
-	mc := metrics.NewMetricContext("security_groups", "delete", resourceGroupName, c.subscriptionID, "")
+	mc := metrics.NewMetricContext("security_groups", "delete", resourceGroupName, c.subscriptionID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterWriter.TryAccept() {
@@ -456,6 +456,30 @@ func TestCreateOrUpdate(t *testing.T) {
 	assert.Nil(t, rerr)
 }
 
+func TestCreateOrUpdateInvalidatesCache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	nsg := getTestSecurityGroup("nsg1")
+	armClient := mockarmclient.NewMockInterface(ctrl)
+	response := &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(bytes.NewReader([]byte(""))),
+	}
+	armClient.EXPECT().PutResource(gomock.Any(), to.String(nsg.ID), nsg, gomock.Any()).Return(response, nil).Times(1)
+	armClient.EXPECT().CloseResponse(gomock.Any(), gomock.Any()).Times(1)
+
+	var invalidated []string
+	nsgClient := getTestSecurityGroupClient(armClient)
+	nsgClient.invalidationRegistry = azclients.NewInvalidationRegistry()
+	nsgClient.invalidationRegistry.Register(nsgResourceType, func(resourceID string) {
+		invalidated = append(invalidated, resourceID)
+	})
+	rerr := nsgClient.CreateOrUpdate(context.TODO(), "rg", "nsg1", nsg, "*")
+	assert.Nil(t, rerr)
+	assert.Equal(t, []string{testResourceID}, invalidated)
+}
+
 func TestCreateOrUpdateWithCreateOrUpdateResponderError(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -86,12 +86,23 @@ func New(config *azclients.ClientConfig) *Client {
 	return client
 }
 
+// SetRateLimits replaces the rate limiters of the client with the ones created from the config.
+// The requests waiting for the old rate limiters are not affected.
+func (c *Client) SetRateLimits(config *azclients.RateLimitConfig) {
+	azclients.SetRateLimits(config, c.rateLimiterReader, c.rateLimiterWriter)
+}
+
+// SetBackoff updates the backoff of the retries of the new requests.
+func (c *Client) SetBackoff(backoff *retry.Backoff) {
+	c.armClient.SetBackoff(backoff)
+}
+
 // Get gets a Snapshot.
 func (c *Client) Get(ctx context.Context, subsID, resourceGroupName, snapshotName string) (compute.Snapshot, *retry.Error) {
 	if subsID == "" {
 		subsID = c.subscriptionID
 	}
-	mc := metrics.NewMetricContext("snapshot", "get", resourceGroupName, subsID, "")
+	mc := metrics.NewMetricContext("snapshot", "get", resourceGroupName, subsID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterReader.TryAccept() {
@@ -155,7 +166,7 @@ func (c *Client) Delete(ctx context.Context, subsID, resourceGroupName, snapshot
 	if subsID == "" {
 		subsID = c.subscriptionID
 	}
-	mc := metrics.NewMetricContext("snapshot", "delete", resourceGroupName, subsID, "")
+	mc := metrics.NewMetricContext("snapshot", "delete", resourceGroupName, subsID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterWriter.TryAccept() {
@@ -201,7 +212,7 @@ func (c *Client) CreateOrUpdate(ctx context.Context, subsID, resourceGroupName,
 	if subsID == "" {
 		subsID = c.subscriptionID
 	}
-	mc := metrics.NewMetricContext("snapshot", "create_or_update", resourceGroupName, subsID, "")
+	mc := metrics.NewMetricContext("snapshot", "create_or_update", resourceGroupName, subsID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterWriter.TryAccept() {
@@ -272,7 +283,7 @@ func (c *Client) ListByResourceGroup(ctx context.Context, subsID, resourceGroupN
 	if subsID == "" {
 		subsID = c.subscriptionID
 	}
-	mc := metrics.NewMetricContext("snapshot", "list_by_resource_group", resourceGroupName, subsID, "")
+	mc := metrics.NewMetricContext("snapshot", "list_by_resource_group", resourceGroupName, subsID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterReader.TryAccept() {
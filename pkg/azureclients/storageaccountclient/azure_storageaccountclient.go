@@ -86,12 +86,23 @@ func New(config *azclients.ClientConfig) *Client {
 	return client
 }
 
+// SetRateLimits replaces the rate limiters of the client with the ones created from the config.
+// The requests waiting for the old rate limiters are not affected.
+func (c *Client) SetRateLimits(config *azclients.RateLimitConfig) {
+	azclients.SetRateLimits(config, c.rateLimiterReader, c.rateLimiterWriter)
+}
+
+// SetBackoff updates the backoff of the retries of the new requests.
+func (c *Client) SetBackoff(backoff *retry.Backoff) {
+	c.armClient.SetBackoff(backoff)
+}
+
 // GetProperties gets properties of the StorageAccount.
 func (c *Client) GetProperties(ctx context.Context, subsID, resourceGroupName, accountName string) (storage.Account, *retry.Error) {
 	if subsID == "" {
 		subsID = c.subscriptionID
 	}
-	mc := metrics.NewMetricContext("storage_account", "get", resourceGroupName, subsID, "")
+	mc := metrics.NewMetricContext("storage_account", "get", resourceGroupName, subsID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterReader.TryAccept() {
@@ -155,7 +166,7 @@ func (c *Client) ListKeys(ctx context.Context, subsID, resourceGroupName, accoun
 	if subsID == "" {
 		subsID = c.subscriptionID
 	}
-	mc := metrics.NewMetricContext("storage_account", "list_keys", resourceGroupName, subsID, "")
+	mc := metrics.NewMetricContext("storage_account", "list_keys", resourceGroupName, subsID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterReader.TryAccept() {
@@ -219,7 +230,7 @@ func (c *Client) Create(ctx context.Context, subsID, resourceGroupName, accountN
 	if subsID == "" {
 		subsID = c.subscriptionID
 	}
-	mc := metrics.NewMetricContext("storage_account", "create", resourceGroupName, subsID, "")
+	mc := metrics.NewMetricContext("storage_account", "create", resourceGroupName, subsID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterWriter.TryAccept() {
@@ -288,7 +299,7 @@ func (c *Client) Update(ctx context.Context, subsID, resourceGroupName, accountN
 	if subsID == "" {
 		subsID = c.subscriptionID
 	}
-	mc := metrics.NewMetricContext("storage_account", "update", resourceGroupName, subsID, "")
+	mc := metrics.NewMetricContext("storage_account", "update", resourceGroupName, subsID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterWriter.TryAccept() {
@@ -357,7 +368,7 @@ func (c *Client) Delete(ctx context.Context, subsID, resourceGroupName, accountN
 	if subsID == "" {
 		subsID = c.subscriptionID
 	}
-	mc := metrics.NewMetricContext("storage_account", "delete", resourceGroupName, subsID, "")
+	mc := metrics.NewMetricContext("storage_account", "delete", resourceGroupName, subsID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterWriter.TryAccept() {
@@ -403,7 +414,7 @@ func (c *Client) ListByResourceGroup(ctx context.Context, subsID, resourceGroupN
 	if subsID == "" {
 		subsID = c.subscriptionID
 	}
-	mc := metrics.NewMetricContext("storage_account", "list_by_resource_group", resourceGroupName, subsID, "")
+	mc := metrics.NewMetricContext("storage_account", "list_by_resource_group", resourceGroupName, subsID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterReader.TryAccept() {
@@ -86,9 +86,20 @@ func New(config *azclients.ClientConfig) *Client {
 	return client
 }
 
+// SetRateLimits replaces the rate limiters of the client with the ones created from the config.
+// The requests waiting for the old rate limiters are not affected.
+func (c *Client) SetRateLimits(config *azclients.RateLimitConfig) {
+	azclients.SetRateLimits(config, c.rateLimiterReader, c.rateLimiterWriter)
+}
+
+// SetBackoff updates the backoff of the retries of the new requests.
+func (c *Client) SetBackoff(backoff *retry.Backoff) {
+	c.armClient.SetBackoff(backoff)
+}
+
 // Get gets a Subnet.
 func (c *Client) Get(ctx context.Context, resourceGroupName string, virtualNetworkName string, subnetName string, expand string) (network.Subnet, *retry.Error) {
-	mc := metrics.NewMetricContext("subnets", "get", resourceGroupName, c.subscriptionID, "")
+	mc := metrics.NewMetricContext("subnets", "get", resourceGroupName, c.subscriptionID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterReader.TryAccept() {
@@ -151,7 +162,7 @@ func (c *Client) getSubnet(ctx context.Context, resourceGroupName string, virtua
 
 // List gets a list of Subnets in the VNet.
 func (c *Client) List(ctx context.Context, resourceGroupName string, virtualNetworkName string) ([]network.Subnet, *retry.Error) {
-	mc := metrics.NewMetricContext("subnets", "list", resourceGroupName, c.subscriptionID, "")
+	mc := metrics.NewMetricContext("subnets", "list", resourceGroupName, c.subscriptionID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterReader.TryAccept() {
@@ -226,7 +237,7 @@ func (c *Client) listSubnet(ctx context.Context, resourceGroupName string, virtu
 
 // CreateOrUpdate creates or updates a Subnet.
 func (c *Client) CreateOrUpdate(ctx context.Context, resourceGroupName string, virtualNetworkName string, subnetName string, subnetParameters network.Subnet) *retry.Error {
-	mc := metrics.NewMetricContext("subnets", "create_or_update", resourceGroupName, c.subscriptionID, "")
+	mc := metrics.NewMetricContext("subnets", "create_or_update", resourceGroupName, c.subscriptionID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterWriter.TryAccept() {
@@ -295,7 +306,7 @@ func (c *Client) createOrUpdateResponder(resp *http.Response) (*network.Subnet,
 
 // Delete deletes a Subnet by name.
 func (c *Client) Delete(ctx context.Context, resourceGroupName string, virtualNetworkName string, subnetName string) *retry.Error {
-	mc := metrics.NewMetricContext("subnets", "delete", resourceGroupName, c.subscriptionID, "")
+	mc := metrics.NewMetricContext("subnets", "delete", resourceGroupName, c.subscriptionID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterWriter.TryAccept() {
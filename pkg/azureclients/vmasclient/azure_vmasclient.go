@@ -86,9 +86,20 @@ func New(config *azclients.ClientConfig) *Client {
 	return client
 }
 
+// SetRateLimits replaces the rate limiters of the client with the ones created from the config.
+// The requests waiting for the old rate limiters are not affected.
+func (c *Client) SetRateLimits(config *azclients.RateLimitConfig) {
+	azclients.SetRateLimits(config, c.rateLimiterReader, c.rateLimiterWriter)
+}
+
+// SetBackoff updates the backoff of the retries of the new requests.
+func (c *Client) SetBackoff(backoff *retry.Backoff) {
+	c.armClient.SetBackoff(backoff)
+}
+
 // Get gets a AvailabilitySet.
 func (c *Client) Get(ctx context.Context, resourceGroupName string, vmasName string) (compute.AvailabilitySet, *retry.Error) {
-	mc := metrics.NewMetricContext("vmas", "get", resourceGroupName, c.subscriptionID, "")
+	mc := metrics.NewMetricContext("vmas", "get", resourceGroupName, c.subscriptionID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterReader.TryAccept() {
@@ -149,7 +160,7 @@ func (c *Client) getVMAS(ctx context.Context, resourceGroupName string, vmasName
 
 // List gets a list of AvailabilitySets in the resource group.
 func (c *Client) List(ctx context.Context, resourceGroupName string) ([]compute.AvailabilitySet, *retry.Error) {
-	mc := metrics.NewMetricContext("vmas", "list", resourceGroupName, c.subscriptionID, "")
+	mc := metrics.NewMetricContext("vmas", "list", resourceGroupName, c.subscriptionID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterReader.TryAccept() {
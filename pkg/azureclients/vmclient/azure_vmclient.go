@@ -86,9 +86,20 @@ func New(config *azclients.ClientConfig) *Client {
 	return client
 }
 
+// SetRateLimits replaces the rate limiters of the client with the ones created from the config.
+// The requests waiting for the old rate limiters are not affected.
+func (c *Client) SetRateLimits(config *azclients.RateLimitConfig) {
+	azclients.SetRateLimits(config, c.rateLimiterReader, c.rateLimiterWriter)
+}
+
+// SetBackoff updates the backoff of the retries of the new requests.
+func (c *Client) SetBackoff(backoff *retry.Backoff) {
+	c.armClient.SetBackoff(backoff)
+}
+
 // Get gets a VirtualMachine.
 func (c *Client) Get(ctx context.Context, resourceGroupName string, VMName string, expand compute.InstanceViewTypes) (compute.VirtualMachine, *retry.Error) {
-	mc := metrics.NewMetricContext("vm", "get", resourceGroupName, c.subscriptionID, "")
+	mc := metrics.NewMetricContext("vm", "get", resourceGroupName, c.subscriptionID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterReader.TryAccept() {
@@ -149,7 +160,7 @@ func (c *Client) getVM(ctx context.Context, resourceGroupName string, VMName str
 
 // List gets a list of VirtualMachine in the resourceGroupName.
 func (c *Client) List(ctx context.Context, resourceGroupName string) ([]compute.VirtualMachine, *retry.Error) {
-	mc := metrics.NewMetricContext("vm", "list", resourceGroupName, c.subscriptionID, "")
+	mc := metrics.NewMetricContext("vm", "list", resourceGroupName, c.subscriptionID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterReader.TryAccept() {
@@ -219,7 +230,7 @@ func (c *Client) listVM(ctx context.Context, resourceGroupName string) ([]comput
 
 // Update updates a VirtualMachine.
 func (c *Client) Update(ctx context.Context, resourceGroupName string, VMName string, parameters compute.VirtualMachineUpdate, source string) *retry.Error {
-	mc := metrics.NewMetricContext("vm", "update", resourceGroupName, c.subscriptionID, source)
+	mc := metrics.NewMetricContext("vm", "update", resourceGroupName, c.subscriptionID, source).WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterWriter.TryAccept() {
@@ -250,7 +261,7 @@ func (c *Client) Update(ctx context.Context, resourceGroupName string, VMName st
 
 // UpdateAsync updates a VirtualMachine asynchronously
 func (c *Client) UpdateAsync(ctx context.Context, resourceGroupName string, VMName string, parameters compute.VirtualMachineUpdate, source string) (*azure.Future, *retry.Error) {
-	mc := metrics.NewMetricContext("vm", "updateasync", resourceGroupName, c.subscriptionID, source)
+	mc := metrics.NewMetricContext("vm", "updateasync", resourceGroupName, c.subscriptionID, source).WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterWriter.TryAccept() {
@@ -288,7 +299,7 @@ func (c *Client) UpdateAsync(ctx context.Context, resourceGroupName string, VMNa
 
 // WaitForUpdateResult waits for the response of the update request
 func (c *Client) WaitForUpdateResult(ctx context.Context, future *azure.Future, resourceGroupName, source string) *retry.Error {
-	mc := metrics.NewMetricContext("vm", "wait_for_update_result", resourceGroupName, c.subscriptionID, source)
+	mc := metrics.NewMetricContext("vm", "wait_for_update_result", resourceGroupName, c.subscriptionID, source).WithContext(ctx)
 	response, err := c.armClient.WaitForAsyncOperationResult(ctx, future, "VMWaitForUpdateResult")
 	mc.Observe(retry.NewErrorOrNil(false, err))
 
@@ -435,7 +446,7 @@ func (page VirtualMachineListResultPage) Values() []compute.VirtualMachine {
 
 // CreateOrUpdate creates or updates a VirtualMachine.
 func (c *Client) CreateOrUpdate(ctx context.Context, resourceGroupName string, VMName string, parameters compute.VirtualMachine, source string) *retry.Error {
-	mc := metrics.NewMetricContext("vm", "create_or_update", resourceGroupName, c.subscriptionID, source)
+	mc := metrics.NewMetricContext("vm", "create_or_update", resourceGroupName, c.subscriptionID, source).WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterWriter.TryAccept() {
@@ -504,7 +515,7 @@ func (c *Client) createOrUpdateResponder(resp *http.Response) (*compute.VirtualM
 
 // Delete deletes a VirtualMachine.
 func (c *Client) Delete(ctx context.Context, resourceGroupName string, VMName string) *retry.Error {
-	mc := metrics.NewMetricContext("vm", "delete", resourceGroupName, c.subscriptionID, "")
+	mc := metrics.NewMetricContext("vm", "delete", resourceGroupName, c.subscriptionID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterWriter.TryAccept() {
@@ -53,6 +53,9 @@ type Client struct {
 	// ARM throttling configures.
 	RetryAfterReader time.Time
 	RetryAfterWriter time.Time
+
+	// invalidationRegistry is notified after the resources are mutated.
+	invalidationRegistry *azclients.InvalidationRegistry
 }
 
 // New creates a new VMSS client with ratelimiting.
@@ -76,19 +79,34 @@ func New(config *azclients.ClientConfig) *Client {
 	}
 
 	client := &Client{
-		armClient:         armClient,
-		rateLimiterReader: rateLimiterReader,
-		rateLimiterWriter: rateLimiterWriter,
-		subscriptionID:    config.SubscriptionID,
-		cloudName:         config.CloudName,
+		armClient:            armClient,
+		rateLimiterReader:    rateLimiterReader,
+		rateLimiterWriter:    rateLimiterWriter,
+		subscriptionID:       config.SubscriptionID,
+		cloudName:            config.CloudName,
+		invalidationRegistry: config.InvalidationRegistry,
 	}
 
 	return client
 }
 
+// SetRateLimits replaces the rate limiters of the client with the ones created from the config.
+// The requests waiting for the old rate limiters are not affected.
+func (c *Client) SetRateLimits(config *azclients.RateLimitConfig) {
+	azclients.SetRateLimits(config, c.rateLimiterReader, c.rateLimiterWriter)
+}
+
+// SetBackoff updates the backoff of the retries of the new requests.
+func (c *Client) SetBackoff(backoff *retry.Backoff) {
+	c.armClient.SetBackoff(backoff)
+}
+
 // Get gets a VirtualMachineScaleSet.
-func (c *Client) Get(ctx context.Context, resourceGroupName string, VMScaleSetName string) (compute.VirtualMachineScaleSet, *retry.Error) {
-	mc := metrics.NewMetricContext("vmss", "get", resourceGroupName, c.subscriptionID, "")
+func (c *Client) Get(ctx context.Context, subsID, resourceGroupName string, VMScaleSetName string) (compute.VirtualMachineScaleSet, *retry.Error) {
+	if subsID == "" {
+		subsID = c.subscriptionID
+	}
+	mc := metrics.NewMetricContext("vmss", "get", resourceGroupName, subsID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterReader.TryAccept() {
@@ -103,7 +121,7 @@ func (c *Client) Get(ctx context.Context, resourceGroupName string, VMScaleSetNa
 		return compute.VirtualMachineScaleSet{}, rerr
 	}
 
-	result, rerr := c.getVMSS(ctx, resourceGroupName, VMScaleSetName)
+	result, rerr := c.getVMSS(ctx, subsID, resourceGroupName, VMScaleSetName)
 	mc.Observe(rerr)
 	if rerr != nil {
 		if rerr.IsThrottled() {
@@ -118,9 +136,9 @@ func (c *Client) Get(ctx context.Context, resourceGroupName string, VMScaleSetNa
 }
 
 // getVMSS gets a VirtualMachineScaleSet.
-func (c *Client) getVMSS(ctx context.Context, resourceGroupName string, VMScaleSetName string) (compute.VirtualMachineScaleSet, *retry.Error) {
+func (c *Client) getVMSS(ctx context.Context, subsID, resourceGroupName string, VMScaleSetName string) (compute.VirtualMachineScaleSet, *retry.Error) {
 	resourceID := armclient.GetResourceID(
-		c.subscriptionID,
+		subsID,
 		resourceGroupName,
 		vmssResourceType,
 		VMScaleSetName,
@@ -148,8 +166,11 @@ func (c *Client) getVMSS(ctx context.Context, resourceGroupName string, VMScaleS
 }
 
 // List gets a list of VirtualMachineScaleSets in the resource group.
-func (c *Client) List(ctx context.Context, resourceGroupName string) ([]compute.VirtualMachineScaleSet, *retry.Error) {
-	mc := metrics.NewMetricContext("vmss", "list", resourceGroupName, c.subscriptionID, "")
+func (c *Client) List(ctx context.Context, subsID, resourceGroupName string) ([]compute.VirtualMachineScaleSet, *retry.Error) {
+	if subsID == "" {
+		subsID = c.subscriptionID
+	}
+	mc := metrics.NewMetricContext("vmss", "list", resourceGroupName, subsID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterReader.TryAccept() {
@@ -164,7 +185,7 @@ func (c *Client) List(ctx context.Context, resourceGroupName string) ([]compute.
 		return nil, rerr
 	}
 
-	result, rerr := c.listVMSS(ctx, resourceGroupName)
+	result, rerr := c.listVMSS(ctx, subsID, resourceGroupName)
 	mc.Observe(rerr)
 	if rerr != nil {
 		if rerr.IsThrottled() {
@@ -179,9 +200,9 @@ func (c *Client) List(ctx context.Context, resourceGroupName string) ([]compute.
 }
 
 // listVMSS gets a list of VirtualMachineScaleSets in the resource group.
-func (c *Client) listVMSS(ctx context.Context, resourceGroupName string) ([]compute.VirtualMachineScaleSet, *retry.Error) {
+func (c *Client) listVMSS(ctx context.Context, subsID, resourceGroupName string) ([]compute.VirtualMachineScaleSet, *retry.Error) {
 	resourceID := armclient.GetResourceListID(
-		c.subscriptionID,
+		subsID,
 		resourceGroupName,
 		vmssResourceType,
 	)
@@ -221,8 +242,11 @@ func (c *Client) listVMSS(ctx context.Context, resourceGroupName string) ([]comp
 }
 
 // CreateOrUpdate creates or updates a VirtualMachineScaleSet.
-func (c *Client) CreateOrUpdate(ctx context.Context, resourceGroupName string, VMScaleSetName string, parameters compute.VirtualMachineScaleSet) *retry.Error {
-	mc := metrics.NewMetricContext("vmss", "create_or_update", resourceGroupName, c.subscriptionID, "")
+func (c *Client) CreateOrUpdate(ctx context.Context, subsID, resourceGroupName string, VMScaleSetName string, parameters compute.VirtualMachineScaleSet) *retry.Error {
+	if subsID == "" {
+		subsID = c.subscriptionID
+	}
+	mc := metrics.NewMetricContext("vmss", "create_or_update", resourceGroupName, subsID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterWriter.TryAccept() {
@@ -237,7 +261,7 @@ func (c *Client) CreateOrUpdate(ctx context.Context, resourceGroupName string, V
 		return rerr
 	}
 
-	rerr := c.createOrUpdateVMSS(ctx, resourceGroupName, VMScaleSetName, parameters)
+	rerr := c.createOrUpdateVMSS(ctx, subsID, resourceGroupName, VMScaleSetName, parameters)
 	mc.Observe(rerr)
 	if rerr != nil {
 		if rerr.IsThrottled() {
@@ -252,8 +276,11 @@ func (c *Client) CreateOrUpdate(ctx context.Context, resourceGroupName string, V
 }
 
 // CreateOrUpdateAsync sends the request to arm client and DO NOT wait for the response
-func (c *Client) CreateOrUpdateAsync(ctx context.Context, resourceGroupName string, VMScaleSetName string, parameters compute.VirtualMachineScaleSet) (*azure.Future, *retry.Error) {
-	mc := metrics.NewMetricContext("vmss", "create_or_update_async", resourceGroupName, c.subscriptionID, "")
+func (c *Client) CreateOrUpdateAsync(ctx context.Context, subsID, resourceGroupName string, VMScaleSetName string, parameters compute.VirtualMachineScaleSet) (*azure.Future, *retry.Error) {
+	if subsID == "" {
+		subsID = c.subscriptionID
+	}
+	mc := metrics.NewMetricContext("vmss", "create_or_update_async", resourceGroupName, subsID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterWriter.TryAccept() {
@@ -269,7 +296,7 @@ func (c *Client) CreateOrUpdateAsync(ctx context.Context, resourceGroupName stri
 	}
 
 	resourceID := armclient.GetResourceID(
-		c.subscriptionID,
+		subsID,
 		resourceGroupName,
 		vmssResourceType,
 		VMScaleSetName,
@@ -311,16 +338,16 @@ func (c *Client) WaitForStartInstancesResult(ctx context.Context, future *azure.
 
 // WaitForAsyncOperationResult waits for the response of the request
 func (c *Client) WaitForAsyncOperationResult(ctx context.Context, future *azure.Future, resourceGroupName, request, asycOpName string) (*http.Response, error) {
-	mc := metrics.NewMetricContext("vmss", request, resourceGroupName, c.subscriptionID, "")
+	mc := metrics.NewMetricContext("vmss", request, resourceGroupName, c.subscriptionID, "").WithContext(ctx)
 	res, err := c.armClient.WaitForAsyncOperationResult(ctx, future, asycOpName)
 	mc.Observe(retry.NewErrorOrNil(false, err))
 	return res, err
 }
 
 // createOrUpdateVMSS creates or updates a VirtualMachineScaleSet.
-func (c *Client) createOrUpdateVMSS(ctx context.Context, resourceGroupName string, VMScaleSetName string, parameters compute.VirtualMachineScaleSet) *retry.Error {
+func (c *Client) createOrUpdateVMSS(ctx context.Context, subsID, resourceGroupName string, VMScaleSetName string, parameters compute.VirtualMachineScaleSet) *retry.Error {
 	resourceID := armclient.GetResourceID(
-		c.subscriptionID,
+		subsID,
 		resourceGroupName,
 		vmssResourceType,
 		VMScaleSetName,
@@ -332,6 +359,9 @@ func (c *Client) createOrUpdateVMSS(ctx context.Context, resourceGroupName strin
 		return rerr
 	}
 
+	// Invalidate the cached VirtualMachineScaleSet right after it is mutated.
+	c.invalidationRegistry.Invalidate(vmssResourceType, resourceID)
+
 	if response != nil && response.StatusCode != http.StatusNoContent {
 		_, rerr = c.createOrUpdateResponder(response)
 		if rerr != nil {
@@ -444,8 +474,11 @@ func (page VirtualMachineScaleSetListResultPage) Values() []compute.VirtualMachi
 }
 
 // DeleteInstances deletes the instances for a VirtualMachineScaleSet.
-func (c *Client) DeleteInstances(ctx context.Context, resourceGroupName string, vmScaleSetName string, vmInstanceIDs compute.VirtualMachineScaleSetVMInstanceRequiredIDs) *retry.Error {
-	mc := metrics.NewMetricContext("vmss", "delete_instances", resourceGroupName, c.subscriptionID, "")
+func (c *Client) DeleteInstances(ctx context.Context, subsID, resourceGroupName string, vmScaleSetName string, vmInstanceIDs compute.VirtualMachineScaleSetVMInstanceRequiredIDs) *retry.Error {
+	if subsID == "" {
+		subsID = c.subscriptionID
+	}
+	mc := metrics.NewMetricContext("vmss", "delete_instances", resourceGroupName, subsID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterWriter.TryAccept() {
@@ -460,7 +493,7 @@ func (c *Client) DeleteInstances(ctx context.Context, resourceGroupName string,
 		return rerr
 	}
 
-	rerr := c.deleteVMSSInstances(ctx, resourceGroupName, vmScaleSetName, vmInstanceIDs)
+	rerr := c.deleteVMSSInstances(ctx, subsID, resourceGroupName, vmScaleSetName, vmInstanceIDs)
 	mc.Observe(rerr)
 	if rerr != nil {
 		if rerr.IsThrottled() {
@@ -475,8 +508,11 @@ func (c *Client) DeleteInstances(ctx context.Context, resourceGroupName string,
 }
 
 // DeleteInstancesAsync sends the delete request to ARM client and DOEST NOT wait on the future
-func (c *Client) DeleteInstancesAsync(ctx context.Context, resourceGroupName string, vmScaleSetName string, vmInstanceIDs compute.VirtualMachineScaleSetVMInstanceRequiredIDs, forceDelete bool) (*azure.Future, *retry.Error) {
-	mc := metrics.NewMetricContext("vmss", "delete_instances_async", resourceGroupName, c.subscriptionID, "")
+func (c *Client) DeleteInstancesAsync(ctx context.Context, subsID, resourceGroupName string, vmScaleSetName string, vmInstanceIDs compute.VirtualMachineScaleSetVMInstanceRequiredIDs, forceDelete bool) (*azure.Future, *retry.Error) {
+	if subsID == "" {
+		subsID = c.subscriptionID
+	}
+	mc := metrics.NewMetricContext("vmss", "delete_instances_async", resourceGroupName, subsID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterWriter.TryAccept() {
@@ -492,7 +528,7 @@ func (c *Client) DeleteInstancesAsync(ctx context.Context, resourceGroupName str
 	}
 
 	resourceID := armclient.GetResourceID(
-		c.subscriptionID,
+		subsID,
 		resourceGroupName,
 		vmssResourceType,
 		vmScaleSetName,
@@ -530,8 +566,11 @@ func (c *Client) DeleteInstancesAsync(ctx context.Context, resourceGroupName str
 }
 
 // DeallocateInstancesAsync sends the deallocate request to ARM client and DOEST NOT wait on the future
-func (c *Client) DeallocateInstancesAsync(ctx context.Context, resourceGroupName string, vmScaleSetName string, vmInstanceIDs compute.VirtualMachineScaleSetVMInstanceRequiredIDs) (*azure.Future, *retry.Error) {
-	mc := metrics.NewMetricContext("vmss", "deallocate_instances_async", resourceGroupName, c.subscriptionID, "")
+func (c *Client) DeallocateInstancesAsync(ctx context.Context, subsID, resourceGroupName string, vmScaleSetName string, vmInstanceIDs compute.VirtualMachineScaleSetVMInstanceRequiredIDs) (*azure.Future, *retry.Error) {
+	if subsID == "" {
+		subsID = c.subscriptionID
+	}
+	mc := metrics.NewMetricContext("vmss", "deallocate_instances_async", resourceGroupName, subsID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterWriter.TryAccept() {
@@ -547,7 +586,7 @@ func (c *Client) DeallocateInstancesAsync(ctx context.Context, resourceGroupName
 	}
 
 	resourceID := armclient.GetResourceID(
-		c.subscriptionID,
+		subsID,
 		resourceGroupName,
 		vmssResourceType,
 		vmScaleSetName,
@@ -579,8 +618,11 @@ func (c *Client) DeallocateInstancesAsync(ctx context.Context, resourceGroupName
 }
 
 // StartInstancesAsync sends the start request to ARM client and DOEST NOT wait on the future
-func (c *Client) StartInstancesAsync(ctx context.Context, resourceGroupName string, vmScaleSetName string, vmInstanceIDs compute.VirtualMachineScaleSetVMInstanceRequiredIDs) (*azure.Future, *retry.Error) {
-	mc := metrics.NewMetricContext("vmss", "start_instances_async", resourceGroupName, c.subscriptionID, "")
+func (c *Client) StartInstancesAsync(ctx context.Context, subsID, resourceGroupName string, vmScaleSetName string, vmInstanceIDs compute.VirtualMachineScaleSetVMInstanceRequiredIDs) (*azure.Future, *retry.Error) {
+	if subsID == "" {
+		subsID = c.subscriptionID
+	}
+	mc := metrics.NewMetricContext("vmss", "start_instances_async", resourceGroupName, subsID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterWriter.TryAccept() {
@@ -596,7 +638,7 @@ func (c *Client) StartInstancesAsync(ctx context.Context, resourceGroupName stri
 	}
 
 	resourceID := armclient.GetResourceID(
-		c.subscriptionID,
+		subsID,
 		resourceGroupName,
 		vmssResourceType,
 		vmScaleSetName,
@@ -628,9 +670,9 @@ func (c *Client) StartInstancesAsync(ctx context.Context, resourceGroupName stri
 }
 
 // deleteVMSSInstances deletes the instances for a VirtualMachineScaleSet.
-func (c *Client) deleteVMSSInstances(ctx context.Context, resourceGroupName string, vmScaleSetName string, vmInstanceIDs compute.VirtualMachineScaleSetVMInstanceRequiredIDs) *retry.Error {
+func (c *Client) deleteVMSSInstances(ctx context.Context, subsID, resourceGroupName string, vmScaleSetName string, vmInstanceIDs compute.VirtualMachineScaleSetVMInstanceRequiredIDs) *retry.Error {
 	resourceID := armclient.GetResourceID(
-		c.subscriptionID,
+		subsID,
 		resourceGroupName,
 		vmssResourceType,
 		vmScaleSetName,
@@ -104,7 +104,7 @@ func TestGet(t *testing.T) {
 
 	expected := compute.VirtualMachineScaleSet{Response: autorest.Response{Response: response}}
 	vmssClient := getTestVMSSClient(armClient)
-	result, rerr := vmssClient.Get(context.TODO(), "rg", "vmss1")
+	result, rerr := vmssClient.Get(context.TODO(), "", "rg", "vmss1")
 	assert.Equal(t, expected, result)
 	assert.Nil(t, rerr)
 }
@@ -121,7 +121,7 @@ func TestGetNeverRateLimiter(t *testing.T) {
 	armClient := mockarmclient.NewMockInterface(ctrl)
 	vmssClient := getTestVMSSClientWithNeverRateLimiter(armClient)
 	expected := compute.VirtualMachineScaleSet{}
-	result, rerr := vmssClient.Get(context.TODO(), "rg", "vmss1")
+	result, rerr := vmssClient.Get(context.TODO(), "", "rg", "vmss1")
 	assert.Equal(t, expected, result)
 	assert.Equal(t, vmssGetErr, rerr)
 }
@@ -139,7 +139,7 @@ func TestGetRetryAfterReader(t *testing.T) {
 	armClient := mockarmclient.NewMockInterface(ctrl)
 	vmssClient := getTestVMSSClientWithRetryAfterReader(armClient)
 	expected := compute.VirtualMachineScaleSet{}
-	result, rerr := vmssClient.Get(context.TODO(), "rg", "vmss1")
+	result, rerr := vmssClient.Get(context.TODO(), "", "rg", "vmss1")
 	assert.Equal(t, expected, result)
 	assert.Equal(t, vmssGetErr, rerr)
 }
@@ -158,7 +158,7 @@ func TestGetNotFound(t *testing.T) {
 
 	vmssClient := getTestVMSSClient(armClient)
 	expectedVMSS := compute.VirtualMachineScaleSet{Response: autorest.Response{}}
-	result, rerr := vmssClient.Get(context.TODO(), "rg", "vmss1")
+	result, rerr := vmssClient.Get(context.TODO(), "", "rg", "vmss1")
 	assert.Equal(t, expectedVMSS, result)
 	assert.NotNil(t, rerr)
 	assert.Equal(t, http.StatusNotFound, rerr.HTTPStatusCode)
@@ -178,7 +178,7 @@ func TestGetInternalError(t *testing.T) {
 
 	vmssClient := getTestVMSSClient(armClient)
 	expectedVMSS := compute.VirtualMachineScaleSet{Response: autorest.Response{}}
-	result, rerr := vmssClient.Get(context.TODO(), "rg", "vmss1")
+	result, rerr := vmssClient.Get(context.TODO(), "", "rg", "vmss1")
 	assert.Equal(t, expectedVMSS, result)
 	assert.NotNil(t, rerr)
 	assert.Equal(t, http.StatusInternalServerError, rerr.HTTPStatusCode)
@@ -203,7 +203,7 @@ func TestGetThrottle(t *testing.T) {
 	armClient.EXPECT().CloseResponse(gomock.Any(), gomock.Any()).Times(1)
 
 	vmssClient := getTestVMSSClient(armClient)
-	result, rerr := vmssClient.Get(context.TODO(), "rg", "vmss1")
+	result, rerr := vmssClient.Get(context.TODO(), "", "rg", "vmss1")
 	assert.Empty(t, result)
 	assert.Equal(t, throttleErr, rerr)
 }
@@ -224,7 +224,7 @@ func TestList(t *testing.T) {
 	armClient.EXPECT().CloseResponse(gomock.Any(), gomock.Any()).Times(1)
 
 	vmssClient := getTestVMSSClient(armClient)
-	result, rerr := vmssClient.List(context.TODO(), "rg")
+	result, rerr := vmssClient.List(context.TODO(), "", "rg")
 	assert.Nil(t, rerr)
 	assert.Equal(t, 3, len(result))
 }
@@ -243,7 +243,7 @@ func TestListNotFound(t *testing.T) {
 
 	vmssClient := getTestVMSSClient(armClient)
 	expected := []compute.VirtualMachineScaleSet{}
-	result, rerr := vmssClient.List(context.TODO(), "rg")
+	result, rerr := vmssClient.List(context.TODO(), "", "rg")
 	assert.Equal(t, expected, result)
 	assert.NotNil(t, rerr)
 	assert.Equal(t, http.StatusNotFound, rerr.HTTPStatusCode)
@@ -263,7 +263,7 @@ func TestListInternalError(t *testing.T) {
 
 	vmssClient := getTestVMSSClient(armClient)
 	expected := []compute.VirtualMachineScaleSet{}
-	result, rerr := vmssClient.List(context.TODO(), "rg")
+	result, rerr := vmssClient.List(context.TODO(), "", "rg")
 	assert.Equal(t, expected, result)
 	assert.NotNil(t, rerr)
 	assert.Equal(t, http.StatusInternalServerError, rerr.HTTPStatusCode)
@@ -287,7 +287,7 @@ func TestListThrottle(t *testing.T) {
 	armClient.EXPECT().GetResource(gomock.Any(), testResourcePrefix).Return(response, throttleErr).Times(1)
 	armClient.EXPECT().CloseResponse(gomock.Any(), gomock.Any()).Times(1)
 	vmssClient := getTestVMSSClient(armClient)
-	result, rerr := vmssClient.List(context.TODO(), "rg")
+	result, rerr := vmssClient.List(context.TODO(), "", "rg")
 	assert.Empty(t, result)
 	assert.NotNil(t, rerr)
 	assert.Equal(t, throttleErr, rerr)
@@ -308,7 +308,7 @@ func TestListWithListResponderError(t *testing.T) {
 		}, nil).Times(1)
 	armClient.EXPECT().CloseResponse(gomock.Any(), gomock.Any()).Times(1)
 	vmssClient := getTestVMSSClient(armClient)
-	result, rerr := vmssClient.List(context.TODO(), "rg")
+	result, rerr := vmssClient.List(context.TODO(), "", "rg")
 	assert.NotNil(t, rerr)
 	assert.Equal(t, 0, len(result))
 }
@@ -336,7 +336,7 @@ func TestListWithNextPage(t *testing.T) {
 		}, nil).Times(1)
 	armClient.EXPECT().CloseResponse(gomock.Any(), gomock.Any()).Times(2)
 	vmssClient := getTestVMSSClient(armClient)
-	result, rerr := vmssClient.List(context.TODO(), "rg")
+	result, rerr := vmssClient.List(context.TODO(), "", "rg")
 	assert.Nil(t, rerr)
 	assert.Equal(t, 6, len(result))
 }
@@ -352,7 +352,7 @@ func TestListNeverRateLimiter(t *testing.T) {
 
 	armClient := mockarmclient.NewMockInterface(ctrl)
 	vmssClient := getTestVMSSClientWithNeverRateLimiter(armClient)
-	result, rerr := vmssClient.List(context.TODO(), "rg")
+	result, rerr := vmssClient.List(context.TODO(), "", "rg")
 	assert.Equal(t, 0, len(result))
 	assert.NotNil(t, rerr)
 	assert.Equal(t, vmssListErr, rerr)
@@ -370,7 +370,7 @@ func TestListRetryAfterReader(t *testing.T) {
 
 	armClient := mockarmclient.NewMockInterface(ctrl)
 	vmssClient := getTestVMSSClientWithRetryAfterReader(armClient)
-	result, rerr := vmssClient.List(context.TODO(), "rg")
+	result, rerr := vmssClient.List(context.TODO(), "", "rg")
 	assert.Equal(t, 0, len(result))
 	assert.NotNil(t, rerr)
 	assert.Equal(t, vmssListErr, rerr)
@@ -508,7 +508,7 @@ func TestCreateOrUpdate(t *testing.T) {
 	armClient.EXPECT().CloseResponse(gomock.Any(), gomock.Any()).Times(1)
 
 	vmssClient := getTestVMSSClient(armClient)
-	rerr := vmssClient.CreateOrUpdate(context.TODO(), "rg", "vmss1", vmss)
+	rerr := vmssClient.CreateOrUpdate(context.TODO(), "", "rg", "vmss1", vmss)
 	assert.Nil(t, rerr)
 }
 
@@ -525,7 +525,7 @@ func TestCreateOrUpdateWithCreateOrUpdateResponderError(t *testing.T) {
 	armClient.EXPECT().CloseResponse(gomock.Any(), gomock.Any()).Times(1)
 
 	vmssClient := getTestVMSSClient(armClient)
-	rerr := vmssClient.CreateOrUpdate(context.TODO(), "rg", "vmss1", vmss)
+	rerr := vmssClient.CreateOrUpdate(context.TODO(), "", "rg", "vmss1", vmss)
 	assert.NotNil(t, rerr)
 }
 
@@ -538,7 +538,7 @@ func TestCreateOrUpdateNeverRateLimiter(t *testing.T) {
 	armClient := mockarmclient.NewMockInterface(ctrl)
 	vmssClient := getTestVMSSClientWithNeverRateLimiter(armClient)
 	vmss := getTestVMSS("vmss1")
-	rerr := vmssClient.CreateOrUpdate(context.TODO(), "rg", "vmss1", vmss)
+	rerr := vmssClient.CreateOrUpdate(context.TODO(), "", "rg", "vmss1", vmss)
 	assert.NotNil(t, rerr)
 	assert.Equal(t, vmssCreateOrUpdateErr, rerr)
 }
@@ -552,7 +552,7 @@ func TestCreateOrUpdateRetryAfterReader(t *testing.T) {
 	vmss := getTestVMSS("vmss1")
 	armClient := mockarmclient.NewMockInterface(ctrl)
 	vmssClient := getTestVMSSClientWithRetryAfterReader(armClient)
-	rerr := vmssClient.CreateOrUpdate(context.TODO(), "rg", "vmss1", vmss)
+	rerr := vmssClient.CreateOrUpdate(context.TODO(), "", "rg", "vmss1", vmss)
 	assert.NotNil(t, rerr)
 	assert.Equal(t, vmssCreateOrUpdateErr, rerr)
 }
@@ -578,7 +578,7 @@ func TestCreateOrUpdateThrottle(t *testing.T) {
 	armClient.EXPECT().CloseResponse(gomock.Any(), gomock.Any()).Times(1)
 
 	vmssClient := getTestVMSSClient(armClient)
-	rerr := vmssClient.CreateOrUpdate(context.TODO(), "rg", "vmss1", vmss)
+	rerr := vmssClient.CreateOrUpdate(context.TODO(), "", "rg", "vmss1", vmss)
 	assert.NotNil(t, rerr)
 	assert.Equal(t, throttleErr, rerr)
 }
@@ -593,12 +593,12 @@ func TestCreateOrUpdateAsync(t *testing.T) {
 
 	armClient.EXPECT().PutResourceAsync(gomock.Any(), to.String(vmss.ID), vmss).Return(future, nil).Times(1)
 	vmssClient := getTestVMSSClient(armClient)
-	_, rerr := vmssClient.CreateOrUpdateAsync(context.TODO(), "rg", "vmss1", vmss)
+	_, rerr := vmssClient.CreateOrUpdateAsync(context.TODO(), "", "rg", "vmss1", vmss)
 	assert.Nil(t, rerr)
 
 	retryErr := &retry.Error{RawError: fmt.Errorf("error")}
 	armClient.EXPECT().PutResourceAsync(gomock.Any(), to.String(vmss.ID), vmss).Return(future, retryErr).Times(1)
-	_, rerr = vmssClient.CreateOrUpdateAsync(context.TODO(), "rg", "vmss1", vmss)
+	_, rerr = vmssClient.CreateOrUpdateAsync(context.TODO(), "", "rg", "vmss1", vmss)
 	assert.Equal(t, retryErr, rerr)
 }
 
@@ -614,7 +614,7 @@ func TestCreateOrUpdateAsyncNeverRateLimiter(t *testing.T) {
 	armClient := mockarmclient.NewMockInterface(ctrl)
 	vmssClient := getTestVMSSClientWithNeverRateLimiter(armClient)
 	vmss := getTestVMSS("vmss1")
-	_, rerr := vmssClient.CreateOrUpdateAsync(context.TODO(), "rg", "vmss1", vmss)
+	_, rerr := vmssClient.CreateOrUpdateAsync(context.TODO(), "", "rg", "vmss1", vmss)
 	assert.NotNil(t, rerr)
 	assert.Equal(t, vmssCreateOrUpdateAsyncErr, rerr)
 }
@@ -632,7 +632,7 @@ func TestCreateOrUpdateAsyncRetryAfterReader(t *testing.T) {
 	vmss := getTestVMSS("vmss1")
 	armClient := mockarmclient.NewMockInterface(ctrl)
 	vmssClient := getTestVMSSClientWithRetryAfterReader(armClient)
-	_, rerr := vmssClient.CreateOrUpdateAsync(context.TODO(), "rg", "vmss1", vmss)
+	_, rerr := vmssClient.CreateOrUpdateAsync(context.TODO(), "", "rg", "vmss1", vmss)
 	assert.NotNil(t, rerr)
 	assert.Equal(t, vmssCreateOrUpdateAsyncErr, rerr)
 }
@@ -654,7 +654,7 @@ func TestCreateOrUpdateAsyncThrottle(t *testing.T) {
 	armClient.EXPECT().PutResourceAsync(gomock.Any(), to.String(vmss.ID), vmss).Return(future, throttleErr).Times(1)
 
 	vmssClient := getTestVMSSClient(armClient)
-	_, rerr := vmssClient.CreateOrUpdateAsync(context.TODO(), "rg", "vmss1", vmss)
+	_, rerr := vmssClient.CreateOrUpdateAsync(context.TODO(), "", "rg", "vmss1", vmss)
 	assert.NotNil(t, rerr)
 	assert.Equal(t, throttleErr, rerr)
 }
@@ -694,7 +694,7 @@ func TestDeleteInstances(t *testing.T) {
 	armClient.EXPECT().WaitForAsyncOperationCompletion(gomock.Any(), gomock.Any(), "vmssclient.DeleteInstances").Return(nil).Times(1)
 
 	client := getTestVMSSClient(armClient)
-	rerr := client.DeleteInstances(context.TODO(), "rg", "vmss1", vmInstanceIDs)
+	rerr := client.DeleteInstances(context.TODO(), "", "rg", "vmss1", vmInstanceIDs)
 	assert.Nil(t, rerr)
 }
 
@@ -712,7 +712,7 @@ func TestDeleteInstancesNeverRateLimiter(t *testing.T) {
 
 	armClient := mockarmclient.NewMockInterface(ctrl)
 	vmssClient := getTestVMSSClientWithNeverRateLimiter(armClient)
-	rerr := vmssClient.DeleteInstances(context.TODO(), "rg", "vmss1", vmInstanceIDs)
+	rerr := vmssClient.DeleteInstances(context.TODO(), "", "rg", "vmss1", vmInstanceIDs)
 	assert.NotNil(t, rerr)
 	assert.Equal(t, vmssDeleteInstancesErr, rerr)
 }
@@ -732,7 +732,7 @@ func TestDeleteInstancesRetryAfterReader(t *testing.T) {
 
 	armClient := mockarmclient.NewMockInterface(ctrl)
 	vmssClient := getTestVMSSClientWithRetryAfterReader(armClient)
-	rerr := vmssClient.DeleteInstances(context.TODO(), "rg", "vmss1", vmInstanceIDs)
+	rerr := vmssClient.DeleteInstances(context.TODO(), "", "rg", "vmss1", vmInstanceIDs)
 	assert.NotNil(t, rerr)
 	assert.Equal(t, vmssDeleteInstancesErr, rerr)
 }
@@ -761,7 +761,7 @@ func TestDeleteInstancesThrottle(t *testing.T) {
 	armClient.EXPECT().CloseResponse(gomock.Any(), gomock.Any()).Times(1)
 
 	vmssClient := getTestVMSSClient(armClient)
-	rerr := vmssClient.DeleteInstances(context.TODO(), "rg", "vmss1", vmInstanceIDs)
+	rerr := vmssClient.DeleteInstances(context.TODO(), "", "rg", "vmss1", vmInstanceIDs)
 	assert.NotNil(t, rerr)
 	assert.Equal(t, throttleErr, rerr)
 }
@@ -791,7 +791,7 @@ func TestDeleteInstancesWaitError(t *testing.T) {
 	armClient.EXPECT().WaitForAsyncOperationCompletion(gomock.Any(), gomock.Any(), "vmssclient.DeleteInstances").Return(err).Times(1)
 
 	vmssClient := getTestVMSSClient(armClient)
-	rerr := vmssClient.DeleteInstances(context.TODO(), "rg", "vmss1", vmInstanceIDs)
+	rerr := vmssClient.DeleteInstances(context.TODO(), "", "rg", "vmss1", vmInstanceIDs)
 	assert.NotNil(t, rerr)
 	assert.Equal(t, vmssDeleteInstancesErr, rerr)
 }
@@ -814,14 +814,14 @@ func TestDeleteInstancesAsync(t *testing.T) {
 	armClient.EXPECT().CloseResponse(gomock.Any(), gomock.Any()).Times(1)
 
 	vmssClient := getTestVMSSClient(armClient)
-	future, rerr := vmssClient.DeleteInstancesAsync(context.TODO(), "rg", "vmss1", vmInstanceIDs, false)
+	future, rerr := vmssClient.DeleteInstancesAsync(context.TODO(), "", "rg", "vmss1", vmInstanceIDs, false)
 	assert.Nil(t, rerr)
 	assert.Equal(t, future.Status(), "Succeeded")
 
 	// with force delete
 	armClient.EXPECT().PostResource(gomock.Any(), to.String(vmss.ID), "delete", vmInstanceIDs, map[string]interface{}{"forceDeletion": true}).Return(response, nil).Times(1)
 	armClient.EXPECT().CloseResponse(gomock.Any(), gomock.Any()).Times(1)
-	future, rerr = vmssClient.DeleteInstancesAsync(context.TODO(), "rg", "vmss1", vmInstanceIDs, true)
+	future, rerr = vmssClient.DeleteInstancesAsync(context.TODO(), "", "rg", "vmss1", vmInstanceIDs, true)
 	assert.Nil(t, rerr)
 	assert.Equal(t, future.Status(), "Succeeded")
 
@@ -829,7 +829,7 @@ func TestDeleteInstancesAsync(t *testing.T) {
 	retryErr := &retry.Error{RawError: fmt.Errorf("error")}
 	armClient.EXPECT().PostResource(gomock.Any(), to.String(vmss.ID), "delete", vmInstanceIDs, gomock.Any()).Return(&http.Response{StatusCode: http.StatusBadRequest}, retryErr).Times(1)
 	armClient.EXPECT().CloseResponse(gomock.Any(), gomock.Any()).Times(1)
-	_, rerr = vmssClient.DeleteInstancesAsync(context.TODO(), "rg", "vmss1", vmInstanceIDs, false)
+	_, rerr = vmssClient.DeleteInstancesAsync(context.TODO(), "", "rg", "vmss1", vmInstanceIDs, false)
 	assert.Equal(t, retryErr, rerr)
 }
 
@@ -39,25 +39,25 @@ const (
 // Don't forget to run "hack/update-mock-clients.sh" command to generate the mock client.
 type Interface interface {
 	// Get gets a VirtualMachineScaleSet.
-	Get(ctx context.Context, resourceGroupName string, VMScaleSetName string) (result compute.VirtualMachineScaleSet, rerr *retry.Error)
+	Get(ctx context.Context, subsID, resourceGroupName string, VMScaleSetName string) (result compute.VirtualMachineScaleSet, rerr *retry.Error)
 
 	// List gets a list of VirtualMachineScaleSets in the resource group.
-	List(ctx context.Context, resourceGroupName string) (result []compute.VirtualMachineScaleSet, rerr *retry.Error)
+	List(ctx context.Context, subsID, resourceGroupName string) (result []compute.VirtualMachineScaleSet, rerr *retry.Error)
 
 	// CreateOrUpdate creates or updates a VirtualMachineScaleSet.
-	CreateOrUpdate(ctx context.Context, resourceGroupName string, VMScaleSetName string, parameters compute.VirtualMachineScaleSet) *retry.Error
+	CreateOrUpdate(ctx context.Context, subsID, resourceGroupName string, VMScaleSetName string, parameters compute.VirtualMachineScaleSet) *retry.Error
 
 	// CreateOrUpdateSync sends the request to arm client and DO NOT wait for the response
-	CreateOrUpdateAsync(ctx context.Context, resourceGroupName string, VMScaleSetName string, parameters compute.VirtualMachineScaleSet) (*azure.Future, *retry.Error)
+	CreateOrUpdateAsync(ctx context.Context, subsID, resourceGroupName string, VMScaleSetName string, parameters compute.VirtualMachineScaleSet) (*azure.Future, *retry.Error)
 
 	// WaitForAsyncOperationResult waits for the response of the request
 	WaitForAsyncOperationResult(ctx context.Context, future *azure.Future, resourceGroupName, request, asyncOpName string) (*http.Response, error)
 
 	// DeleteInstances deletes the instances for a VirtualMachineScaleSet.
-	DeleteInstances(ctx context.Context, resourceGroupName string, vmScaleSetName string, vmInstanceIDs compute.VirtualMachineScaleSetVMInstanceRequiredIDs) *retry.Error
+	DeleteInstances(ctx context.Context, subsID, resourceGroupName string, vmScaleSetName string, vmInstanceIDs compute.VirtualMachineScaleSetVMInstanceRequiredIDs) *retry.Error
 
 	// DeleteInstancesAsync sends the delete request to the ARM client and DOEST NOT wait on the future
-	DeleteInstancesAsync(ctx context.Context, resourceGroupName string, vmScaleSetName string, vmInstanceIDs compute.VirtualMachineScaleSetVMInstanceRequiredIDs, forceDelete bool) (*azure.Future, *retry.Error)
+	DeleteInstancesAsync(ctx context.Context, subsID, resourceGroupName string, vmScaleSetName string, vmInstanceIDs compute.VirtualMachineScaleSetVMInstanceRequiredIDs, forceDelete bool) (*azure.Future, *retry.Error)
 
 	// WaitForCreateOrUpdateResult waits for the response of the create or update request
 	WaitForCreateOrUpdateResult(ctx context.Context, future *azure.Future, resourceGroupName string) (*http.Response, error)
@@ -66,13 +66,13 @@ type Interface interface {
 	WaitForDeleteInstancesResult(ctx context.Context, future *azure.Future, resourceGroupName string) (*http.Response, error)
 
 	// DeallocateInstances sends the deallocate request to the ARM client and DOEST NOT wait on the future
-	DeallocateInstancesAsync(ctx context.Context, resourceGroupName string, vmScaleSetName string, vmInstanceIDs compute.VirtualMachineScaleSetVMInstanceRequiredIDs) (*azure.Future, *retry.Error)
+	DeallocateInstancesAsync(ctx context.Context, subsID, resourceGroupName string, vmScaleSetName string, vmInstanceIDs compute.VirtualMachineScaleSetVMInstanceRequiredIDs) (*azure.Future, *retry.Error)
 
 	// WaitForDeallocateInstancesResult waits for the response of the deallocate instances request
 	WaitForDeallocateInstancesResult(ctx context.Context, future *azure.Future, resourceGroupName string) (*http.Response, error)
 
 	// StartInstancesAsync starts the instances for a VirtualMachineScaleSet.
-	StartInstancesAsync(ctx context.Context, resourceGroupName string, vmScaleSetName string, vmInstanceIDs compute.VirtualMachineScaleSetVMInstanceRequiredIDs) (*azure.Future, *retry.Error)
+	StartInstancesAsync(ctx context.Context, subsID, resourceGroupName string, vmScaleSetName string, vmInstanceIDs compute.VirtualMachineScaleSetVMInstanceRequiredIDs) (*azure.Future, *retry.Error)
 
 	// WaitForStartInstancesResult waits for the response of the start instances request
 	WaitForStartInstancesResult(ctx context.Context, future *azure.Future, resourceGroupName string) (*http.Response, error)
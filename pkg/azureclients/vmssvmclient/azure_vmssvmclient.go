@@ -40,8 +40,9 @@ import (
 var _ Interface = &Client{}
 
 const (
-	vmssResourceType = "Microsoft.Compute/virtualMachineScaleSets"
-	vmResourceType   = "virtualMachines"
+	vmssResourceType   = "Microsoft.Compute/virtualMachineScaleSets"
+	vmResourceType     = "virtualMachines"
+	vmssVMResourceType = vmssResourceType + "/" + vmResourceType
 )
 
 // Client implements VMSS client Interface.
@@ -57,6 +58,9 @@ type Client struct {
 	// ARM throttling configures.
 	RetryAfterReader time.Time
 	RetryAfterWriter time.Time
+
+	// invalidationRegistry is notified after the resources are mutated.
+	invalidationRegistry *azclients.InvalidationRegistry
 }
 
 // New creates a new vmssVM client with ratelimiting.
@@ -80,19 +84,34 @@ func New(config *azclients.ClientConfig) *Client {
 	}
 
 	client := &Client{
-		armClient:         armClient,
-		rateLimiterReader: rateLimiterReader,
-		rateLimiterWriter: rateLimiterWriter,
-		subscriptionID:    config.SubscriptionID,
-		cloudName:         config.CloudName,
+		armClient:            armClient,
+		rateLimiterReader:    rateLimiterReader,
+		rateLimiterWriter:    rateLimiterWriter,
+		subscriptionID:       config.SubscriptionID,
+		cloudName:            config.CloudName,
+		invalidationRegistry: config.InvalidationRegistry,
 	}
 
 	return client
 }
 
+// SetRateLimits replaces the rate limiters of the client with the ones created from the config.
+// The requests waiting for the old rate limiters are not affected.
+func (c *Client) SetRateLimits(config *azclients.RateLimitConfig) {
+	azclients.SetRateLimits(config, c.rateLimiterReader, c.rateLimiterWriter)
+}
+
+// SetBackoff updates the backoff of the retries of the new requests.
+func (c *Client) SetBackoff(backoff *retry.Backoff) {
+	c.armClient.SetBackoff(backoff)
+}
+
 // Get gets a VirtualMachineScaleSetVM.
-func (c *Client) Get(ctx context.Context, resourceGroupName string, VMScaleSetName string, instanceID string, expand compute.InstanceViewTypes) (compute.VirtualMachineScaleSetVM, *retry.Error) {
-	mc := metrics.NewMetricContext("vmssvm", "get", resourceGroupName, c.subscriptionID, "")
+func (c *Client) Get(ctx context.Context, subsID, resourceGroupName string, VMScaleSetName string, instanceID string, expand compute.InstanceViewTypes) (compute.VirtualMachineScaleSetVM, *retry.Error) {
+	if subsID == "" {
+		subsID = c.subscriptionID
+	}
+	mc := metrics.NewMetricContext("vmssvm", "get", resourceGroupName, subsID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterReader.TryAccept() {
@@ -107,7 +126,7 @@ func (c *Client) Get(ctx context.Context, resourceGroupName string, VMScaleSetNa
 		return compute.VirtualMachineScaleSetVM{}, rerr
 	}
 
-	result, rerr := c.getVMSSVM(ctx, resourceGroupName, VMScaleSetName, instanceID, expand)
+	result, rerr := c.getVMSSVM(ctx, subsID, resourceGroupName, VMScaleSetName, instanceID, expand)
 	mc.Observe(rerr)
 	if rerr != nil {
 		if rerr.IsThrottled() {
@@ -122,9 +141,9 @@ func (c *Client) Get(ctx context.Context, resourceGroupName string, VMScaleSetNa
 }
 
 // getVMSSVM gets a VirtualMachineScaleSetVM.
-func (c *Client) getVMSSVM(ctx context.Context, resourceGroupName string, VMScaleSetName string, instanceID string, expand compute.InstanceViewTypes) (compute.VirtualMachineScaleSetVM, *retry.Error) {
+func (c *Client) getVMSSVM(ctx context.Context, subsID, resourceGroupName string, VMScaleSetName string, instanceID string, expand compute.InstanceViewTypes) (compute.VirtualMachineScaleSetVM, *retry.Error) {
 	resourceID := armclient.GetChildResourceID(
-		c.subscriptionID,
+		subsID,
 		resourceGroupName,
 		vmssResourceType,
 		VMScaleSetName,
@@ -154,8 +173,11 @@ func (c *Client) getVMSSVM(ctx context.Context, resourceGroupName string, VMScal
 }
 
 // List gets a list of VirtualMachineScaleSetVMs in the virtualMachineScaleSet.
-func (c *Client) List(ctx context.Context, resourceGroupName string, virtualMachineScaleSetName string, expand string) ([]compute.VirtualMachineScaleSetVM, *retry.Error) {
-	mc := metrics.NewMetricContext("vmssvm", "list", resourceGroupName, c.subscriptionID, "")
+func (c *Client) List(ctx context.Context, subsID, resourceGroupName string, virtualMachineScaleSetName string, expand string) ([]compute.VirtualMachineScaleSetVM, *retry.Error) {
+	if subsID == "" {
+		subsID = c.subscriptionID
+	}
+	mc := metrics.NewMetricContext("vmssvm", "list", resourceGroupName, subsID, "").WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterReader.TryAccept() {
@@ -170,7 +192,7 @@ func (c *Client) List(ctx context.Context, resourceGroupName string, virtualMach
 		return nil, rerr
 	}
 
-	result, rerr := c.listVMSSVM(ctx, resourceGroupName, virtualMachineScaleSetName, expand)
+	result, rerr := c.listVMSSVM(ctx, subsID, resourceGroupName, virtualMachineScaleSetName, expand)
 	mc.Observe(rerr)
 	if rerr != nil {
 		if rerr.IsThrottled() {
@@ -185,9 +207,9 @@ func (c *Client) List(ctx context.Context, resourceGroupName string, virtualMach
 }
 
 // listVMSSVM gets a list of VirtualMachineScaleSetVMs in the virtualMachineScaleSet.
-func (c *Client) listVMSSVM(ctx context.Context, resourceGroupName string, virtualMachineScaleSetName string, expand string) ([]compute.VirtualMachineScaleSetVM, *retry.Error) {
+func (c *Client) listVMSSVM(ctx context.Context, subsID, resourceGroupName string, virtualMachineScaleSetName string, expand string) ([]compute.VirtualMachineScaleSetVM, *retry.Error) {
 	resourceID := armclient.GetChildResourcesListID(
-		c.subscriptionID,
+		subsID,
 		resourceGroupName,
 		vmssResourceType,
 		virtualMachineScaleSetName,
@@ -230,8 +252,11 @@ func (c *Client) listVMSSVM(ctx context.Context, resourceGroupName string, virtu
 }
 
 // Update updates a VirtualMachineScaleSetVM.
-func (c *Client) Update(ctx context.Context, resourceGroupName string, VMScaleSetName string, instanceID string, parameters compute.VirtualMachineScaleSetVM, source string) *retry.Error {
-	mc := metrics.NewMetricContext("vmssvm", "update", resourceGroupName, c.subscriptionID, source)
+func (c *Client) Update(ctx context.Context, subsID, resourceGroupName string, VMScaleSetName string, instanceID string, parameters compute.VirtualMachineScaleSetVM, source string) *retry.Error {
+	if subsID == "" {
+		subsID = c.subscriptionID
+	}
+	mc := metrics.NewMetricContext("vmssvm", "update", resourceGroupName, subsID, source).WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterWriter.TryAccept() {
@@ -246,7 +271,7 @@ func (c *Client) Update(ctx context.Context, resourceGroupName string, VMScaleSe
 		return rerr
 	}
 
-	rerr := c.updateVMSSVM(ctx, resourceGroupName, VMScaleSetName, instanceID, parameters)
+	rerr := c.updateVMSSVM(ctx, subsID, resourceGroupName, VMScaleSetName, instanceID, parameters)
 	mc.Observe(rerr)
 	if rerr != nil {
 		if rerr.IsThrottled() {
@@ -261,8 +286,11 @@ func (c *Client) Update(ctx context.Context, resourceGroupName string, VMScaleSe
 }
 
 // UpdateAsync updates a VirtualMachineScaleSetVM asynchronously
-func (c *Client) UpdateAsync(ctx context.Context, resourceGroupName string, VMScaleSetName string, instanceID string, parameters compute.VirtualMachineScaleSetVM, source string) (*azure.Future, *retry.Error) {
-	mc := metrics.NewMetricContext("vmssvm", "updateasync", resourceGroupName, c.subscriptionID, source)
+func (c *Client) UpdateAsync(ctx context.Context, subsID, resourceGroupName string, VMScaleSetName string, instanceID string, parameters compute.VirtualMachineScaleSetVM, source string) (*azure.Future, *retry.Error) {
+	if subsID == "" {
+		subsID = c.subscriptionID
+	}
+	mc := metrics.NewMetricContext("vmssvm", "updateasync", resourceGroupName, subsID, source).WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterWriter.TryAccept() {
@@ -278,7 +306,7 @@ func (c *Client) UpdateAsync(ctx context.Context, resourceGroupName string, VMSc
 	}
 
 	resourceID := armclient.GetChildResourceID(
-		c.subscriptionID,
+		subsID,
 		resourceGroupName,
 		vmssResourceType,
 		VMScaleSetName,
@@ -302,7 +330,7 @@ func (c *Client) UpdateAsync(ctx context.Context, resourceGroupName string, VMSc
 
 // WaitForUpdateResult waits for the response of the update request
 func (c *Client) WaitForUpdateResult(ctx context.Context, future *azure.Future, resourceGroupName, source string) *retry.Error {
-	mc := metrics.NewMetricContext("vmss", "wait_for_update_result", resourceGroupName, c.subscriptionID, source)
+	mc := metrics.NewMetricContext("vmss", "wait_for_update_result", resourceGroupName, c.subscriptionID, source).WithContext(ctx)
 	response, err := c.armClient.WaitForAsyncOperationResult(ctx, future, "VMSSWaitForUpdateResult")
 	mc.Observe(retry.NewErrorOrNil(false, err))
 	if err != nil {
@@ -317,9 +345,9 @@ func (c *Client) WaitForUpdateResult(ctx context.Context, future *azure.Future,
 }
 
 // updateVMSSVM updates a VirtualMachineScaleSetVM.
-func (c *Client) updateVMSSVM(ctx context.Context, resourceGroupName string, VMScaleSetName string, instanceID string, parameters compute.VirtualMachineScaleSetVM) *retry.Error {
+func (c *Client) updateVMSSVM(ctx context.Context, subsID, resourceGroupName string, VMScaleSetName string, instanceID string, parameters compute.VirtualMachineScaleSetVM) *retry.Error {
 	resourceID := armclient.GetChildResourceID(
-		c.subscriptionID,
+		subsID,
 		resourceGroupName,
 		vmssResourceType,
 		VMScaleSetName,
@@ -334,6 +362,9 @@ func (c *Client) updateVMSSVM(ctx context.Context, resourceGroupName string, VMS
 		return rerr
 	}
 
+	// Invalidate the cached VirtualMachineScaleSetVM right after it is mutated.
+	c.invalidationRegistry.Invalidate(vmssVMResourceType, resourceID)
+
 	if response != nil && response.StatusCode != http.StatusNoContent {
 		_, rerr = c.updateResponder(response)
 		if rerr != nil {
@@ -448,8 +479,11 @@ func (page VirtualMachineScaleSetVMListResultPage) Values() []compute.VirtualMac
 // UpdateVMs updates a list of VirtualMachineScaleSetVM from map[instanceID]compute.VirtualMachineScaleSetVM.
 // If the batch size > 0, it will send sync requests concurrently in batches, or it will send sync requests in sequence.
 // No matter what the batch size is, it will process the async requests concurrently in one single batch.
-func (c *Client) UpdateVMs(ctx context.Context, resourceGroupName string, VMScaleSetName string, instances map[string]compute.VirtualMachineScaleSetVM, source string, batchSize int) *retry.Error {
-	mc := metrics.NewMetricContext("vmssvm", "update_vms", resourceGroupName, c.subscriptionID, source)
+func (c *Client) UpdateVMs(ctx context.Context, subsID, resourceGroupName string, VMScaleSetName string, instances map[string]compute.VirtualMachineScaleSetVM, source string, batchSize int) *retry.Error {
+	if subsID == "" {
+		subsID = c.subscriptionID
+	}
+	mc := metrics.NewMetricContext("vmssvm", "update_vms", resourceGroupName, subsID, source).WithContext(ctx)
 
 	// Report errors if the client is rate limited.
 	if !c.rateLimiterWriter.TryAccept() {
@@ -464,7 +498,7 @@ func (c *Client) UpdateVMs(ctx context.Context, resourceGroupName string, VMScal
 		return rerr
 	}
 
-	rerr := c.updateVMSSVMs(ctx, resourceGroupName, VMScaleSetName, instances, batchSize)
+	rerr := c.updateVMSSVMs(ctx, subsID, resourceGroupName, VMScaleSetName, instances, batchSize)
 	mc.Observe(rerr)
 	if rerr != nil {
 		if rerr.IsThrottled() {
@@ -479,11 +513,11 @@ func (c *Client) UpdateVMs(ctx context.Context, resourceGroupName string, VMScal
 }
 
 // updateVMSSVMs updates a list of VirtualMachineScaleSetVM from map[instanceID]compute.VirtualMachineScaleSetVM.
-func (c *Client) updateVMSSVMs(ctx context.Context, resourceGroupName string, VMScaleSetName string, instances map[string]compute.VirtualMachineScaleSetVM, batchSize int) *retry.Error {
+func (c *Client) updateVMSSVMs(ctx context.Context, subsID, resourceGroupName string, VMScaleSetName string, instances map[string]compute.VirtualMachineScaleSetVM, batchSize int) *retry.Error {
 	resources := make(map[string]interface{})
 	for instanceID, parameter := range instances {
 		resourceID := armclient.GetChildResourceID(
-			c.subscriptionID,
+			subsID,
 			resourceGroupName,
 			vmssResourceType,
 			VMScaleSetName,
@@ -507,6 +541,9 @@ func (c *Client) updateVMSSVMs(ctx context.Context, resourceGroupName string, VM
 			continue
 		}
 
+		// Invalidate the cached VirtualMachineScaleSetVM right after it is mutated.
+		c.invalidationRegistry.Invalidate(vmssVMResourceType, resourceID)
+
 		if resp.Response != nil && resp.Response.StatusCode != http.StatusNoContent {
 			_, rerr := c.updateResponder(resp.Response)
 			if rerr != nil {
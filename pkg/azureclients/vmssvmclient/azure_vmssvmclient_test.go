@@ -104,7 +104,7 @@ func TestGet(t *testing.T) {
 
 	expected := compute.VirtualMachineScaleSetVM{Response: autorest.Response{Response: response}}
 	vmssvmClient := getTestVMSSVMClient(armClient)
-	result, rerr := vmssvmClient.Get(context.TODO(), "rg", "vmss1", "0", "InstanceView")
+	result, rerr := vmssvmClient.Get(context.TODO(), "", "rg", "vmss1", "0", "InstanceView")
 	assert.Equal(t, expected, result)
 	assert.Nil(t, rerr)
 }
@@ -121,7 +121,7 @@ func TestGetNeverRateLimiter(t *testing.T) {
 	armClient := mockarmclient.NewMockInterface(ctrl)
 	vmssvmClient := getTestVMSSVMClientWithNeverRateLimiter(armClient)
 	expected := compute.VirtualMachineScaleSetVM{}
-	result, rerr := vmssvmClient.Get(context.TODO(), "rg", "vmss1", "0", "InstanceView")
+	result, rerr := vmssvmClient.Get(context.TODO(), "", "rg", "vmss1", "0", "InstanceView")
 	assert.Equal(t, expected, result)
 	assert.Equal(t, vmssvmGetErr, rerr)
 }
@@ -139,7 +139,7 @@ func TestGetRetryAfterReader(t *testing.T) {
 	armClient := mockarmclient.NewMockInterface(ctrl)
 	vmssvmClient := getTestVMSSVMClientWithRetryAfterReader(armClient)
 	expected := compute.VirtualMachineScaleSetVM{}
-	result, rerr := vmssvmClient.Get(context.TODO(), "rg", "vmss1", "0", "InstanceView")
+	result, rerr := vmssvmClient.Get(context.TODO(), "", "rg", "vmss1", "0", "InstanceView")
 	assert.Equal(t, expected, result)
 	assert.Equal(t, vmssvmGetErr, rerr)
 }
@@ -158,7 +158,7 @@ func TestGetNotFound(t *testing.T) {
 
 	vmssClient := getTestVMSSVMClient(armClient)
 	expectedVM := compute.VirtualMachineScaleSetVM{Response: autorest.Response{}}
-	result, rerr := vmssClient.Get(context.TODO(), "rg", "vmss1", "0", "InstanceView")
+	result, rerr := vmssClient.Get(context.TODO(), "", "rg", "vmss1", "0", "InstanceView")
 	assert.Equal(t, expectedVM, result)
 	assert.NotNil(t, rerr)
 	assert.Equal(t, http.StatusNotFound, rerr.HTTPStatusCode)
@@ -179,7 +179,7 @@ func TestGetInternalError(t *testing.T) {
 
 	vmssClient := getTestVMSSVMClient(armClient)
 	expectedVM := compute.VirtualMachineScaleSetVM{Response: autorest.Response{}}
-	result, rerr := vmssClient.Get(context.TODO(), "rg", "vmss1", "1", "InstanceView")
+	result, rerr := vmssClient.Get(context.TODO(), "", "rg", "vmss1", "1", "InstanceView")
 	assert.Equal(t, expectedVM, result)
 	assert.NotNil(t, rerr)
 	assert.Equal(t, http.StatusInternalServerError, rerr.HTTPStatusCode)
@@ -204,7 +204,7 @@ func TestGetThrottle(t *testing.T) {
 	armClient.EXPECT().CloseResponse(gomock.Any(), gomock.Any()).Times(1)
 
 	vmssvmClient := getTestVMSSVMClient(armClient)
-	result, rerr := vmssvmClient.Get(context.TODO(), "rg", "vmss1", "0", "InstanceView")
+	result, rerr := vmssvmClient.Get(context.TODO(), "", "rg", "vmss1", "0", "InstanceView")
 	assert.Empty(t, result)
 	assert.Equal(t, throttleErr, rerr)
 }
@@ -225,7 +225,7 @@ func TestList(t *testing.T) {
 	armClient.EXPECT().CloseResponse(gomock.Any(), gomock.Any()).Times(1)
 
 	vmssClient := getTestVMSSVMClient(armClient)
-	result, rerr := vmssClient.List(context.TODO(), "rg", "vmss1", "InstanceView")
+	result, rerr := vmssClient.List(context.TODO(), "", "rg", "vmss1", "InstanceView")
 	assert.Nil(t, rerr)
 	assert.Equal(t, 3, len(result))
 }
@@ -244,7 +244,7 @@ func TestListNotFound(t *testing.T) {
 
 	vmssvmClient := getTestVMSSVMClient(armClient)
 	expected := []compute.VirtualMachineScaleSetVM{}
-	result, rerr := vmssvmClient.List(context.TODO(), "rg", "vmss1", "InstanceView")
+	result, rerr := vmssvmClient.List(context.TODO(), "", "rg", "vmss1", "InstanceView")
 	assert.Equal(t, expected, result)
 	assert.NotNil(t, rerr)
 	assert.Equal(t, http.StatusNotFound, rerr.HTTPStatusCode)
@@ -264,7 +264,7 @@ func TestListInternalError(t *testing.T) {
 
 	vmssvmClient := getTestVMSSVMClient(armClient)
 	expected := []compute.VirtualMachineScaleSetVM{}
-	result, rerr := vmssvmClient.List(context.TODO(), "rg", "vmss1", "InstanceView")
+	result, rerr := vmssvmClient.List(context.TODO(), "", "rg", "vmss1", "InstanceView")
 	assert.Equal(t, expected, result)
 	assert.NotNil(t, rerr)
 	assert.Equal(t, http.StatusInternalServerError, rerr.HTTPStatusCode)
@@ -288,7 +288,7 @@ func TestListThrottle(t *testing.T) {
 	armClient.EXPECT().GetResourceWithExpandQuery(gomock.Any(), testResourcePrefix, "InstanceView").Return(response, throttleErr).Times(1)
 	armClient.EXPECT().CloseResponse(gomock.Any(), gomock.Any()).Times(1)
 	vmssvmClient := getTestVMSSVMClient(armClient)
-	result, rerr := vmssvmClient.List(context.TODO(), "rg", "vmss1", "InstanceView")
+	result, rerr := vmssvmClient.List(context.TODO(), "", "rg", "vmss1", "InstanceView")
 	assert.Empty(t, result)
 	assert.NotNil(t, rerr)
 	assert.Equal(t, throttleErr, rerr)
@@ -309,7 +309,7 @@ func TestListWithListResponderError(t *testing.T) {
 		}, nil).Times(1)
 	armClient.EXPECT().CloseResponse(gomock.Any(), gomock.Any()).Times(1)
 	vmssvmClient := getTestVMSSVMClient(armClient)
-	result, rerr := vmssvmClient.List(context.TODO(), "rg", "vmss1", "InstanceView")
+	result, rerr := vmssvmClient.List(context.TODO(), "", "rg", "vmss1", "InstanceView")
 	assert.NotNil(t, rerr)
 	assert.Equal(t, 0, len(result))
 }
@@ -337,7 +337,7 @@ func TestListWithNextPage(t *testing.T) {
 		}, nil).Times(1)
 	armClient.EXPECT().CloseResponse(gomock.Any(), gomock.Any()).Times(2)
 	vmssvmClient := getTestVMSSVMClient(armClient)
-	result, rerr := vmssvmClient.List(context.TODO(), "rg", "vmss1", "InstanceView")
+	result, rerr := vmssvmClient.List(context.TODO(), "", "rg", "vmss1", "InstanceView")
 	assert.Nil(t, rerr)
 	assert.Equal(t, 6, len(result))
 }
@@ -353,7 +353,7 @@ func TestListNeverRateLimiter(t *testing.T) {
 
 	armClient := mockarmclient.NewMockInterface(ctrl)
 	vmssvmClient := getTestVMSSVMClientWithNeverRateLimiter(armClient)
-	result, rerr := vmssvmClient.List(context.TODO(), "rg", "vmss1", "InstanceView")
+	result, rerr := vmssvmClient.List(context.TODO(), "", "rg", "vmss1", "InstanceView")
 	assert.Equal(t, 0, len(result))
 	assert.NotNil(t, rerr)
 	assert.Equal(t, vmssvmListErr, rerr)
@@ -371,7 +371,7 @@ func TestListRetryAfterReader(t *testing.T) {
 
 	armClient := mockarmclient.NewMockInterface(ctrl)
 	vmssvmClient := getTestVMSSVMClientWithRetryAfterReader(armClient)
-	result, rerr := vmssvmClient.List(context.TODO(), "rg", "vmss1", "InstanceView")
+	result, rerr := vmssvmClient.List(context.TODO(), "", "rg", "vmss1", "InstanceView")
 	assert.Equal(t, 0, len(result))
 	assert.NotNil(t, rerr)
 	assert.Equal(t, vmssvmListErr, rerr)
@@ -509,7 +509,7 @@ func TestUpdate(t *testing.T) {
 	armClient.EXPECT().CloseResponse(gomock.Any(), gomock.Any()).Times(1)
 
 	vmssClient := getTestVMSSVMClient(armClient)
-	rerr := vmssClient.Update(context.TODO(), "rg", "vmss1", "0", vmssVM, "test")
+	rerr := vmssClient.Update(context.TODO(), "", "rg", "vmss1", "0", vmssVM, "test")
 	assert.Nil(t, rerr)
 }
 
@@ -522,7 +522,7 @@ func TestUpdateAsync(t *testing.T) {
 	armClient.EXPECT().PutResourceAsync(gomock.Any(), to.String(vmssVM.ID), vmssVM).Return(nil, nil).Times(1)
 
 	vmssClient := getTestVMSSVMClient(armClient)
-	future, rerr := vmssClient.UpdateAsync(context.TODO(), "rg", "vmss1", "0", vmssVM, "test")
+	future, rerr := vmssClient.UpdateAsync(context.TODO(), "", "rg", "vmss1", "0", vmssVM, "test")
 	assert.Nil(t, rerr)
 	assert.Nil(t, future)
 }
@@ -593,7 +593,7 @@ func TestUpdateWithUpdateResponderError(t *testing.T) {
 	armClient.EXPECT().CloseResponse(gomock.Any(), gomock.Any()).Times(1)
 
 	vmssvmClient := getTestVMSSVMClient(armClient)
-	rerr := vmssvmClient.Update(context.TODO(), "rg", "vmss1", "0", vmssVM, "test")
+	rerr := vmssvmClient.Update(context.TODO(), "", "rg", "vmss1", "0", vmssVM, "test")
 	assert.NotNil(t, rerr)
 }
 
@@ -609,7 +609,7 @@ func TestUpdateNeverRateLimiter(t *testing.T) {
 	armClient := mockarmclient.NewMockInterface(ctrl)
 	vmssvmClient := getTestVMSSVMClientWithNeverRateLimiter(armClient)
 	vmssVM := getTestVMSSVM("vmss1", "0")
-	rerr := vmssvmClient.Update(context.TODO(), "rg", "vmss1", "0", vmssVM, "test")
+	rerr := vmssvmClient.Update(context.TODO(), "", "rg", "vmss1", "0", vmssVM, "test")
 	assert.NotNil(t, rerr)
 	assert.Equal(t, vmssvmUpdateErr, rerr)
 }
@@ -627,7 +627,7 @@ func TestUpdateRetryAfterReader(t *testing.T) {
 	vmssVM := getTestVMSSVM("vmss1", "0")
 	armClient := mockarmclient.NewMockInterface(ctrl)
 	vmClient := getTestVMSSVMClientWithRetryAfterReader(armClient)
-	rerr := vmClient.Update(context.TODO(), "rg", "vmss1", "0", vmssVM, "test")
+	rerr := vmClient.Update(context.TODO(), "", "rg", "vmss1", "0", vmssVM, "test")
 	assert.NotNil(t, rerr)
 	assert.Equal(t, vmssvmUpdateErr, rerr)
 }
@@ -653,7 +653,7 @@ func TestUpdateThrottle(t *testing.T) {
 	armClient.EXPECT().CloseResponse(gomock.Any(), gomock.Any()).Times(1)
 
 	vmssvmClient := getTestVMSSVMClient(armClient)
-	rerr := vmssvmClient.Update(context.TODO(), "rg", "vmss1", "0", vmssVM, "test")
+	rerr := vmssvmClient.Update(context.TODO(), "", "rg", "vmss1", "0", vmssVM, "test")
 	assert.NotNil(t, rerr)
 	assert.Equal(t, throttleErr, rerr)
 }
@@ -690,10 +690,59 @@ func TestUpdateVMs(t *testing.T) {
 	armClient.EXPECT().CloseResponse(gomock.Any(), gomock.Any()).Times(2)
 
 	vmssvmClient := getTestVMSSVMClient(armClient)
-	rerr := vmssvmClient.UpdateVMs(context.TODO(), "rg", "vmss1", instances, "test", 0)
+	rerr := vmssvmClient.UpdateVMs(context.TODO(), "", "rg", "vmss1", instances, "test", 0)
 	assert.Nil(t, rerr)
 }
 
+func TestUpdateInvalidatesCache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	vmssVM := getTestVMSSVM("vmss1", "0")
+	vmssVM1 := getTestVMSSVM("vmss1", "1")
+	vmssVM2 := getTestVMSSVM("vmss1", "2")
+	instances := map[string]compute.VirtualMachineScaleSetVM{
+		"1": vmssVM1,
+		"2": vmssVM2,
+	}
+	testvmssVMs := map[string]interface{}{
+		to.String(vmssVM1.ID): vmssVM1,
+		to.String(vmssVM2.ID): vmssVM2,
+	}
+	response := &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(bytes.NewReader([]byte(""))),
+	}
+	responses := map[string]*armclient.PutResourcesResponse{
+		to.String(vmssVM1.ID): {
+			Response: response,
+		},
+		to.String(vmssVM2.ID): {
+			Error: retry.NewError(false, fmt.Errorf("failed")),
+		},
+	}
+
+	armClient := mockarmclient.NewMockInterface(ctrl)
+	armClient.EXPECT().PutResource(gomock.Any(), to.String(vmssVM.ID), vmssVM).Return(response, nil).Times(1)
+	armClient.EXPECT().PutResourcesInBatches(gomock.Any(), testvmssVMs, 0).Return(responses).Times(1)
+	armClient.EXPECT().CloseResponse(gomock.Any(), gomock.Any()).AnyTimes()
+
+	var invalidated []string
+	vmssvmClient := getTestVMSSVMClient(armClient)
+	vmssvmClient.invalidationRegistry = azclients.NewInvalidationRegistry()
+	vmssvmClient.invalidationRegistry.Register(vmssVMResourceType, func(resourceID string) {
+		invalidated = append(invalidated, resourceID)
+	})
+
+	rerr := vmssvmClient.Update(context.TODO(), "", "rg", "vmss1", "0", vmssVM, "test")
+	assert.Nil(t, rerr)
+	rerr = vmssvmClient.UpdateVMs(context.TODO(), "", "rg", "vmss1", instances, "test", 0)
+	assert.NotNil(t, rerr)
+
+	// only the successfully updated VMs should be invalidated.
+	assert.Equal(t, []string{testResourceID, to.String(vmssVM1.ID)}, invalidated)
+}
+
 func TestUpdateVMsWithUpdateVMsResponderError(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -719,7 +768,7 @@ func TestUpdateVMsWithUpdateVMsResponderError(t *testing.T) {
 	armClient.EXPECT().CloseResponse(gomock.Any(), gomock.Any()).Times(1)
 
 	vmssvmClient := getTestVMSSVMClient(armClient)
-	rerr := vmssvmClient.UpdateVMs(context.TODO(), "rg", "vmss1", instances, "test", 0)
+	rerr := vmssvmClient.UpdateVMs(context.TODO(), "", "rg", "vmss1", instances, "test", 0)
 	assert.NotNil(t, rerr)
 }
 
@@ -735,7 +784,7 @@ func TestUpdateVMsNeverRateLimiter(t *testing.T) {
 
 	armClient := mockarmclient.NewMockInterface(ctrl)
 	vmssvmClient := getTestVMSSVMClientWithNeverRateLimiter(armClient)
-	rerr := vmssvmClient.UpdateVMs(context.TODO(), "rg", "vmss1", instances, "test", 0)
+	rerr := vmssvmClient.UpdateVMs(context.TODO(), "", "rg", "vmss1", instances, "test", 0)
 	assert.NotNil(t, rerr)
 	assert.Equal(t, vmssvmUpdateVMsErr, rerr)
 }
@@ -753,7 +802,7 @@ func TestUpdateVMsRetryAfterReader(t *testing.T) {
 
 	armClient := mockarmclient.NewMockInterface(ctrl)
 	vmClient := getTestVMSSVMClientWithRetryAfterReader(armClient)
-	rerr := vmClient.UpdateVMs(context.TODO(), "rg", "vmss1", instances, "test", 0)
+	rerr := vmClient.UpdateVMs(context.TODO(), "", "rg", "vmss1", instances, "test", 0)
 	assert.NotNil(t, rerr)
 	assert.Equal(t, vmssvmUpdateVMsErr, rerr)
 }
@@ -790,7 +839,7 @@ func TestUpdateVMsThrottle(t *testing.T) {
 	armClient.EXPECT().CloseResponse(gomock.Any(), gomock.Any()).Times(1)
 
 	vmssvmClient := getTestVMSSVMClient(armClient)
-	rerr := vmssvmClient.UpdateVMs(context.TODO(), "rg", "vmss1", instances, "test", 0)
+	rerr := vmssvmClient.UpdateVMs(context.TODO(), "", "rg", "vmss1", instances, "test", 0)
 	assert.NotNil(t, rerr)
 	assert.EqualError(t, throttleErr.Error(), rerr.RawError.Error())
 }
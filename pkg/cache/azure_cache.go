@@ -17,11 +17,19 @@ limitations under the License.
 package cache
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"sort"
 	"sync"
 	"time"
 
+	"golang.org/x/sync/singleflight"
+
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/metrics"
 )
 
 // AzureCacheReadType defines the read type for cache data
@@ -41,6 +49,33 @@ const (
 	CacheReadTypeForceRefresh
 )
 
+const (
+	// unnamedCacheName is the name of the caches created without names.
+	unnamedCacheName = "unnamed"
+
+	// cacheResultHit is the result of the requests served by the cached data.
+	cacheResultHit = "hit"
+	// cacheResultNegativeHit is the result of the requests served by the cached not found results.
+	cacheResultNegativeHit = "negative_hit"
+	// cacheResultMiss is the result of the requests whose data is not cached yet.
+	cacheResultMiss = "miss"
+	// cacheResultExpired is the result of the requests whose cached data has expired.
+	cacheResultExpired = "expired"
+	// cacheResultForceRefresh is the result of the requests which force refresh the cached data.
+	cacheResultForceRefresh = "force_refresh"
+	// cacheResultEviction is the result of the requests which delete the cached data.
+	cacheResultEviction = "eviction"
+)
+
+var (
+	// namedCaches are the caches created with names, which are dumped by DebugHandler.
+	namedCaches     = map[string]*TimedCache{}
+	namedCachesLock sync.RWMutex
+)
+
+// notFound is the sentinel cached for the keys whose getter returns nil data when negative caching is enabled.
+type notFound struct{}
+
 // GetFunc defines a getter function for timedCache.
 type GetFunc func(key string) (interface{}, error)
 
@@ -53,6 +88,9 @@ type AzureCacheEntry struct {
 	Lock sync.Mutex
 	// time when entry was fetched and created
 	CreatedOn time.Time
+
+	// refreshing indicates whether the data is being fetched by the getter.
+	refreshing bool
 }
 
 // cacheKeyFunc defines the key function required in TTLStore.
@@ -65,23 +103,49 @@ type TimedCache struct {
 	Store  cache.Store
 	Lock   sync.Mutex
 	Getter GetFunc
-	TTL    time.Duration
+	// TTL is the TTL of the cached data. The caching is disabled if it is zero,
+	// i.e. all the reads force refreshing the data by the getter.
+	TTL time.Duration
+	// Name is the name of the cache used in the metrics and debug dumps.
+	Name string
+	// NegativeTTL is the TTL of the not found results, which are returned when the getter returns nil data.
+	// The not found results are not cached if it is zero. It should be shorter than TTL since the missing
+	// resources may be created by other clients, and the write paths should delete the keys they create.
+	NegativeTTL time.Duration
+
+	// group deduplicates the concurrent getter calls of the same key.
+	group singleflight.Group
 }
 
-// NewTimedcache creates a new TimedCache.
+// NewTimedcache creates a new TimedCache without a name.
 func NewTimedcache(ttl time.Duration, getter GetFunc) (*TimedCache, error) {
+	return NewTimedcacheWithName("", ttl, getter)
+}
+
+// NewTimedcacheWithName creates a new TimedCache with the name used in the metrics and debug dumps.
+// The named cache replaces the one with the same name created before in the debug dumps.
+func NewTimedcacheWithName(name string, ttl time.Duration, getter GetFunc) (*TimedCache, error) {
 	if getter == nil {
 		return nil, fmt.Errorf("getter is not provided")
 	}
 
-	return &TimedCache{
+	t := &TimedCache{
 		Getter: getter,
 		// switch to using NewStore instead of NewTTLStore so that we can
 		// reuse entries for calls that are fine with reading expired/stalled data.
 		// with NewTTLStore, entries are not returned if they have already expired.
 		Store: cache.NewStore(cacheKeyFunc),
 		TTL:   ttl,
-	}, nil
+		Name:  name,
+	}
+	if name == "" {
+		t.Name = unnamedCacheName
+	} else {
+		namedCachesLock.Lock()
+		namedCaches[name] = t
+		namedCachesLock.Unlock()
+	}
+	return t, nil
 }
 
 // getInternal returns AzureCacheEntry by key. If the key is not cached yet,
@@ -127,28 +191,80 @@ func (t *TimedCache) Get(key string, crt AzureCacheReadType) (interface{}, error
 		return nil, err
 	}
 
+	if data, cached := t.getCached(entry, crt); cached {
+		return data, nil
+	}
+
+	// Data is not cached yet, cache data is expired or requested force refresh
+	// cache it by getter. The concurrent gets of the same key share the same
+	// getter call and result to ensure they don't result in multiple ARM calls.
+	data, err, _ := t.group.Do(key, func() (interface{}, error) {
+		return t.refresh(key, entry)
+	})
+	return data, err
+}
+
+// getCached returns the cached data of the entry if it could be used for the read type.
+func (t *TimedCache) getCached(entry *AzureCacheEntry, crt AzureCacheReadType) (interface{}, bool) {
 	entry.Lock.Lock()
 	defer entry.Lock.Unlock()
 
+	// the caching is disabled, so always refresh the data
+	if t.TTL == 0 {
+		crt = CacheReadTypeForceRefresh
+	}
+
 	// entry exists and if cache is not force refreshed
-	if entry.Data != nil && crt != CacheReadTypeForceRefresh {
+	result := cacheResultMiss
+	if _, ok := entry.Data.(notFound); ok && crt != CacheReadTypeForceRefresh {
+		// the not found result is returned until it expires even for unsafe reads
+		if time.Since(entry.CreatedOn) < t.NegativeTTL {
+			metrics.CountCacheRequest(t.Name, cacheResultNegativeHit)
+			return nil, true
+		}
+		result = cacheResultExpired
+	} else if entry.Data != nil && crt != CacheReadTypeForceRefresh {
 		// allow unsafe read, so return data even if expired
 		if crt == CacheReadTypeUnsafe {
-			return entry.Data, nil
+			metrics.CountCacheRequest(t.Name, cacheResultHit)
+			return entry.Data, true
 		}
 		// if cached data is not expired, return cached data
 		if crt == CacheReadTypeDefault && time.Since(entry.CreatedOn) < t.TTL {
-			return entry.Data, nil
+			metrics.CountCacheRequest(t.Name, cacheResultHit)
+			return entry.Data, true
 		}
+		result = cacheResultExpired
+	} else if crt == CacheReadTypeForceRefresh {
+		result = cacheResultForceRefresh
 	}
-	// Data is not cached yet, cache data is expired or requested force refresh
-	// cache it by getter. entry is locked before getting to ensure concurrent
-	// gets don't result in multiple ARM calls.
+	metrics.CountCacheRequest(t.Name, result)
+	return nil, false
+}
+
+// refresh fetches the data of the key by getter and caches it in the entry.
+// Errors are not cached so that the next get would call the getter again.
+func (t *TimedCache) refresh(key string, entry *AzureCacheEntry) (interface{}, error) {
+	entry.Lock.Lock()
+	entry.refreshing = true
+	entry.Lock.Unlock()
+
 	data, err := t.Getter(key)
+
+	entry.Lock.Lock()
+	defer entry.Lock.Unlock()
+	entry.refreshing = false
 	if err != nil {
 		return nil, err
 	}
 
+	// cache the not found result if negative caching is enabled
+	if data == nil && t.NegativeTTL > 0 {
+		entry.Data = notFound{}
+		entry.CreatedOn = time.Now().UTC()
+		return nil, nil
+	}
+
 	// set the data in cache and also set the last update time
 	// to now as the data was recently fetched
 	entry.Data = data
@@ -157,8 +273,11 @@ func (t *TimedCache) Get(key string, crt AzureCacheReadType) (interface{}, error
 	return entry.Data, nil
 }
 
-// Delete removes an item from the cache.
+// Delete removes an item from the cache. The getter call in flight is forgotten, so that the next get
+// refetches the data instead of sharing the result fetched before the deletion.
 func (t *TimedCache) Delete(key string) error {
+	metrics.CountCacheRequest(t.Name, cacheResultEviction)
+	t.group.Forget(key)
 	return t.Store.Delete(&AzureCacheEntry{
 		Key: key,
 	})
@@ -173,3 +292,75 @@ func (t *TimedCache) Set(key string, data interface{}) {
 		CreatedOn: time.Now().UTC(),
 	})
 }
+
+// CacheEntryDump is the debug information of a cache entry.
+type CacheEntryDump struct {
+	Key string `json:"key"`
+	// Age is the time since the data of the entry was fetched, it is empty if the data is not fetched yet.
+	Age string `json:"age,omitempty"`
+	// Expired indicates whether the data of the entry has expired.
+	Expired bool `json:"expired"`
+	// Refreshing indicates whether the data of the entry is being fetched, the age is not available then.
+	Refreshing bool `json:"refreshing,omitempty"`
+	// NotFound indicates whether the entry is a cached not found result.
+	NotFound bool `json:"notFound,omitempty"`
+}
+
+// CacheDump is the debug information of a cache.
+type CacheDump struct {
+	Name    string           `json:"name"`
+	TTL     string           `json:"ttl"`
+	Entries []CacheEntryDump `json:"entries"`
+}
+
+// Dump returns the debug information of the cache entries sorted by their keys.
+func (t *TimedCache) Dump() CacheDump {
+	dump := CacheDump{
+		Name:    t.Name,
+		TTL:     t.TTL.String(),
+		Entries: make([]CacheEntryDump, 0),
+	}
+	for _, obj := range t.Store.List() {
+		entry := obj.(*AzureCacheEntry)
+		entryDump := CacheEntryDump{Key: entry.Key}
+		entry.Lock.Lock()
+		if entry.refreshing {
+			entryDump.Refreshing = true
+		} else if entry.Data != nil {
+			ttl := t.TTL
+			if _, ok := entry.Data.(notFound); ok {
+				ttl = t.NegativeTTL
+				entryDump.NotFound = true
+			}
+			age := time.Since(entry.CreatedOn)
+			entryDump.Age = age.Round(time.Second).String()
+			entryDump.Expired = age >= ttl
+		}
+		entry.Lock.Unlock()
+		dump.Entries = append(dump.Entries, entryDump)
+	}
+	sort.Slice(dump.Entries, func(i, j int) bool {
+		return dump.Entries[i].Key < dump.Entries[j].Key
+	})
+	return dump
+}
+
+// DebugHandler returns a http handler which dumps the keys, ages and TTLs of the named caches as JSON.
+func DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		namedCachesLock.RLock()
+		dumps := make([]CacheDump, 0, len(namedCaches))
+		for _, t := range namedCaches {
+			dumps = append(dumps, t.Dump())
+		}
+		namedCachesLock.RUnlock()
+		sort.Slice(dumps, func(i, j int) bool {
+			return dumps[i].Name < dumps[j].Name
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(dumps); err != nil {
+			klog.Errorf("failed to encode the cache dumps: %v", err)
+		}
+	})
+}
@@ -17,12 +17,18 @@ limitations under the License.
 package cache
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+
+	"k8s.io/component-base/metrics/legacyregistry"
 )
 
 const (
@@ -220,3 +226,335 @@ func TestCacheForceRefresh(t *testing.T) {
 	assert.Equal(t, 2, dataSource.called)
 	assert.Equal(t, val, v, "should refetch unexpired data as forced refresh")
 }
+
+func getCacheRequestCount(t *testing.T, name, result string) float64 {
+	families, err := legacyregistry.DefaultGatherer.Gather()
+	assert.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != "cloudprovider_azure_cache_request_count" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["cache"] == name && labels["result"] == result {
+				return metric.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+func TestCacheRequestMetrics(t *testing.T) {
+	dataSource := &fakeDataSource{
+		data: map[string]*fakeDataObj{
+			testKey: {},
+		},
+	}
+	cache, err := NewTimedcacheWithName("metrics_test", fakeCacheTTL, dataSource.get)
+	assert.NoError(t, err)
+
+	_, err = cache.Get(testKey, CacheReadTypeDefault)
+	assert.NoError(t, err)
+	_, err = cache.Get(testKey, CacheReadTypeDefault)
+	assert.NoError(t, err)
+	_, err = cache.Get(testKey, CacheReadTypeUnsafe)
+	assert.NoError(t, err)
+	_, err = cache.Get(testKey, CacheReadTypeForceRefresh)
+	assert.NoError(t, err)
+
+	cache.TTL = time.Nanosecond
+	_, err = cache.Get(testKey, CacheReadTypeDefault)
+	assert.NoError(t, err)
+
+	err = cache.Delete(testKey)
+	assert.NoError(t, err)
+
+	assert.Equal(t, float64(1), getCacheRequestCount(t, "metrics_test", cacheResultMiss))
+	assert.Equal(t, float64(2), getCacheRequestCount(t, "metrics_test", cacheResultHit))
+	assert.Equal(t, float64(1), getCacheRequestCount(t, "metrics_test", cacheResultForceRefresh))
+	assert.Equal(t, float64(1), getCacheRequestCount(t, "metrics_test", cacheResultExpired))
+	assert.Equal(t, float64(1), getCacheRequestCount(t, "metrics_test", cacheResultEviction))
+	assert.Equal(t, 3, dataSource.called)
+}
+
+func TestCacheDump(t *testing.T) {
+	dataSource, cache := newFakeCache(t)
+	dataSource.set(map[string]*fakeDataObj{
+		"key2":  {},
+		testKey: {},
+	})
+
+	_, err := cache.Get("key2", CacheReadTypeDefault)
+	assert.NoError(t, err)
+	_, err = cache.Get(testKey, CacheReadTypeDefault)
+	assert.NoError(t, err)
+	cache.Set("key3", &fakeDataObj{})
+	entry, err := cache.getInternal("key3")
+	assert.NoError(t, err)
+	entry.CreatedOn = time.Now().UTC().Add(-fakeCacheTTL)
+	entry, err = cache.getInternal("key4")
+	assert.NoError(t, err)
+	entry.refreshing = true
+
+	dump := cache.Dump()
+	assert.Equal(t, unnamedCacheName, dump.Name)
+	assert.Equal(t, fakeCacheTTL.String(), dump.TTL)
+	assert.Equal(t, []CacheEntryDump{
+		{Key: testKey, Age: "0s"},
+		{Key: "key2", Age: "0s"},
+		{Key: "key3", Age: fakeCacheTTL.String(), Expired: true},
+		{Key: "key4", Refreshing: true},
+	}, dump.Entries)
+}
+
+func TestDebugHandler(t *testing.T) {
+	dataSource := &fakeDataSource{
+		data: map[string]*fakeDataObj{
+			testKey: {},
+		},
+	}
+	cache, err := NewTimedcacheWithName("debug_test", fakeCacheTTL, dataSource.get)
+	assert.NoError(t, err)
+	_, err = cache.Get(testKey, CacheReadTypeDefault)
+	assert.NoError(t, err)
+	_, err = NewTimedcache(fakeCacheTTL, dataSource.get)
+	assert.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	DebugHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/azure-cache", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+	var dumps []CacheDump
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &dumps))
+	found := false
+	for _, dump := range dumps {
+		assert.NotEqual(t, unnamedCacheName, dump.Name, "unnamed caches should not be dumped")
+		if dump.Name == "debug_test" {
+			found = true
+			assert.Equal(t, []CacheEntryDump{{Key: testKey, Age: "0s"}}, dump.Entries)
+		}
+	}
+	assert.True(t, found, "named cache should be dumped")
+}
+
+func TestCacheNegativeCaching(t *testing.T) {
+	val := &fakeDataObj{}
+	dataSource, cache := newFakeCache(t)
+	cache.NegativeTTL = fakeCacheTTL
+
+	v, err := cache.Get(testKey, CacheReadTypeDefault)
+	assert.NoError(t, err)
+	assert.Nil(t, v)
+	assert.Equal(t, 1, dataSource.called)
+
+	for _, crt := range []AzureCacheReadType{CacheReadTypeDefault, CacheReadTypeUnsafe} {
+		v, err = cache.Get(testKey, crt)
+		assert.NoError(t, err)
+		assert.Nil(t, v, "should return nil for the cached not found result")
+		assert.Equal(t, 1, dataSource.called, "should not call getter for the cached not found result")
+	}
+
+	dump := cache.Dump()
+	assert.Len(t, dump.Entries, 1)
+	assert.True(t, dump.Entries[0].NotFound)
+	assert.False(t, dump.Entries[0].Expired)
+
+	dataSource.set(map[string]*fakeDataObj{
+		testKey: val,
+	})
+	v, err = cache.Get(testKey, CacheReadTypeForceRefresh)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, dataSource.called)
+	assert.Equal(t, val, v, "should refetch the cached not found result as forced refresh")
+}
+
+func TestCacheNegativeCachingExpired(t *testing.T) {
+	val := &fakeDataObj{}
+	dataSource, cache := newFakeCache(t)
+	cache.NegativeTTL = fakeCacheTTL / 2
+
+	v, err := cache.Get(testKey, CacheReadTypeDefault)
+	assert.NoError(t, err)
+	assert.Nil(t, v)
+
+	dataSource.set(map[string]*fakeDataObj{
+		testKey: val,
+	})
+	time.Sleep(fakeCacheTTL / 2)
+	v, err = cache.Get(testKey, CacheReadTypeUnsafe)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, dataSource.called)
+	assert.Equal(t, val, v, "should refetch the expired not found result even for unsafe read")
+}
+
+func TestCacheNegativeCachingInvalidated(t *testing.T) {
+	val := &fakeDataObj{}
+	dataSource, cache := newFakeCache(t)
+	cache.NegativeTTL = fakeCacheTTL
+
+	v, err := cache.Get(testKey, CacheReadTypeDefault)
+	assert.NoError(t, err)
+	assert.Nil(t, v)
+
+	// the resource is created and the write path invalidates the key
+	dataSource.set(map[string]*fakeDataObj{
+		testKey: val,
+	})
+	err = cache.Delete(testKey)
+	assert.NoError(t, err)
+
+	v, err = cache.Get(testKey, CacheReadTypeDefault)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, dataSource.called)
+	assert.Equal(t, val, v, "should refetch the invalidated not found result")
+}
+
+func TestCacheNegativeCachingDisabled(t *testing.T) {
+	dataSource, cache := newFakeCache(t)
+
+	for i := 1; i <= 2; i++ {
+		v, err := cache.Get(testKey, CacheReadTypeDefault)
+		assert.NoError(t, err)
+		assert.Nil(t, v)
+		assert.Equal(t, i, dataSource.called, "should not cache the not found result")
+	}
+}
+
+func TestCacheDisabledWithZeroTTL(t *testing.T) {
+	dataSource, cache := newFakeCache(t)
+	cache.TTL = 0
+	val := &fakeDataObj{}
+	dataSource.set(map[string]*fakeDataObj{testKey: val})
+
+	for i, crt := range []AzureCacheReadType{CacheReadTypeDefault, CacheReadTypeUnsafe, CacheReadTypeForceRefresh} {
+		v, err := cache.Get(testKey, crt)
+		assert.NoError(t, err)
+		assert.Equal(t, val, v)
+		assert.Equal(t, i+1, dataSource.called, "should always refresh the data when the caching is disabled")
+	}
+}
+
+func TestCacheConcurrentGetSingleflight(t *testing.T) {
+	for _, tc := range []struct {
+		desc        string
+		crt         AzureCacheReadType
+		getterErr   error
+		expectedErr bool
+	}{
+		{
+			desc: "concurrent misses should share one getter call",
+			crt:  CacheReadTypeDefault,
+		},
+		{
+			desc: "concurrent force refreshes should share one getter call",
+			crt:  CacheReadTypeForceRefresh,
+		},
+		{
+			desc:        "concurrent misses should share the getter error",
+			crt:         CacheReadTypeDefault,
+			getterErr:   fmt.Errorf("getter error"),
+			expectedErr: true,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			val := &fakeDataObj{}
+			var called int32
+			release := make(chan struct{})
+			getter := func(key string) (interface{}, error) {
+				atomic.AddInt32(&called, 1)
+				<-release
+				if tc.getterErr != nil {
+					return nil, tc.getterErr
+				}
+				return val, nil
+			}
+			cache, err := NewTimedcache(fakeCacheTTL, getter)
+			assert.NoError(t, err)
+
+			const goroutines = 100
+			var started, wg sync.WaitGroup
+			results := make(chan interface{}, goroutines)
+			errs := make(chan error, goroutines)
+			started.Add(goroutines)
+			for i := 0; i < goroutines; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					started.Done()
+					v, err := cache.Get(testKey, tc.crt)
+					results <- v
+					errs <- err
+				}()
+			}
+			started.Wait()
+			// wait for the goroutines to join the in-flight getter call
+			time.Sleep(100 * time.Millisecond)
+			close(release)
+			wg.Wait()
+			close(results)
+			close(errs)
+
+			assert.Equal(t, int32(1), atomic.LoadInt32(&called), "getter should be called exactly once")
+			for err := range errs {
+				assert.Equal(t, tc.expectedErr, err != nil)
+			}
+			for v := range results {
+				if tc.expectedErr {
+					assert.Nil(t, v)
+				} else {
+					assert.Equal(t, val, v)
+				}
+			}
+
+			// the error should not be cached
+			if tc.expectedErr {
+				_, err = cache.Get(testKey, CacheReadTypeDefault)
+				assert.Error(t, err)
+				assert.Equal(t, int32(2), atomic.LoadInt32(&called))
+			}
+		})
+	}
+}
+
+func TestCacheDeleteWhileGetInFlight(t *testing.T) {
+	oldVal, newVal := &fakeDataObj{}, &fakeDataObj{}
+	var called int32
+	entered, release := make(chan struct{}), make(chan struct{})
+	getter := func(key string) (interface{}, error) {
+		if atomic.AddInt32(&called, 1) == 1 {
+			close(entered)
+			<-release
+			return oldVal, nil
+		}
+		return newVal, nil
+	}
+	cache, err := NewTimedcache(fakeCacheTTL, getter)
+	assert.NoError(t, err)
+
+	oldResult := make(chan interface{})
+	go func() {
+		v, _ := cache.Get(testKey, CacheReadTypeDefault)
+		oldResult <- v
+	}()
+	<-entered
+
+	// the get after the deletion should not join the getter call started before it
+	assert.NoError(t, cache.Delete(testKey))
+	v, err := cache.Get(testKey, CacheReadTypeDefault)
+	assert.NoError(t, err)
+	assert.Same(t, newVal, v)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&called))
+
+	close(release)
+	assert.Same(t, oldVal, <-oldResult)
+
+	// the stale result should not be cached in the new entry
+	v, err = cache.Get(testKey, CacheReadTypeDefault)
+	assert.NoError(t, err)
+	assert.Same(t, newVal, v)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&called))
+}
@@ -42,6 +42,12 @@ const (
 	LabelFailureDomainBetaRegion = "failure-domain.beta.kubernetes.io/region"
 	// LabelPlatformSubFaultDomain is the label key of platformSubFaultDomain
 	LabelPlatformSubFaultDomain = "topology.kubernetes.azure.com/sub-fault-domain"
+	// LabelExtendedLocation is the label key of the extended location (e.g. the edge zone) of the node
+	LabelExtendedLocation = "topology.kubernetes.azure.com/extended-location"
+	// LabelVMSSName is the label key of the name of the VMSS the node belongs to
+	LabelVMSSName = "kubernetes.azure.com/vmss-name"
+	// LabelAgentPool is the label key of the agent pool the node belongs to
+	LabelAgentPool = "kubernetes.azure.com/agentpool"
 
 	// ADFSIdentitySystem is the override value for tenantID on Azure Stack clouds.
 	ADFSIdentitySystem = "adfs"
@@ -112,6 +118,8 @@ const (
 
 	// VirtualMachineScaleSetsDeallocating indicates VMSS instances are in Deallocating state.
 	VirtualMachineScaleSetsDeallocating = "Deallocating"
+	// VMSSIDTemplate is the vmss ID template
+	VMSSIDTemplate = "/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/virtualMachineScaleSets/%s"
 	// VmssMachineIDTemplate is the vmss manchine ID template
 	VmssMachineIDTemplate = "/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/virtualMachineScaleSets/%s/virtualMachines/%s"
 	// VMSetCIDRIPV4TagKey specifies the node ipv4 CIDR mask of the instances on the VMSS or VMAS
@@ -145,6 +153,10 @@ const (
 	VMSSVirtualMachinesCacheTTLDefaultInSeconds = 600
 	// VMASCacheTTLDefaultInSeconds is the TTL of the vmas cache
 	VMASCacheTTLDefaultInSeconds = 600
+	// VMASNICCacheTTLDefaultInSeconds is the TTL of the cache of the availability set VMs and their primary NICs
+	VMASNICCacheTTLDefaultInSeconds = 30
+	// NegativeCacheTTLDefaultInSeconds is the max TTL of the cached not found results of the caches opting in negative caching
+	NegativeCacheTTLDefaultInSeconds = 30
 
 	// ZoneFetchingInterval defines the interval of performing zoneClient.GetZones
 	ZoneFetchingInterval = 30 * time.Minute
@@ -168,6 +180,8 @@ const (
 	BackoffDurationDefault = 5 // in seconds
 	// BackoffJitterDefault is the default value of the backoff jitter
 	BackoffJitterDefault = 1.0
+	// EnsureHostsInPoolConcurrencyDefault is the default number of scale sets updated concurrently by EnsureHostsInPool
+	EnsureHostsInPoolConcurrencyDefault = 4
 )
 
 // load balancer
@@ -270,6 +284,21 @@ const (
 	// `/healthz` would be configured by default.
 	ServiceAnnotationLoadBalancerHealthProbeRequestPath = "service.beta.kubernetes.io/azure-load-balancer-health-probe-request-path"
 
+	// ServiceAnnotationHealthProbePort overrides the port of the health probe of the service with the external traffic
+	// policy Local, which targets the health check node port served by kube-proxy by default. It is useful when the
+	// node health is served on a different port, e.g. by the kube-proxy replacement of Cilium. The port must not be
+	// one of the node ports allocated to the service.
+	ServiceAnnotationHealthProbePort = "service.beta.kubernetes.io/azure-health-probe-port"
+
+	// ServiceAnnotationHealthProbeProtocol overrides the protocol of the health probe of the service with the external
+	// traffic policy Local. The supported values are Http, Https (standard load balancer only) and Tcp. If not set,
+	// Http would be used by default.
+	ServiceAnnotationHealthProbeProtocol = "service.beta.kubernetes.io/azure-health-probe-protocol"
+
+	// ServiceAnnotationHealthProbeRequestPath overrides the request path of the Http and Https health probe of the
+	// service with the external traffic policy Local. If not set, the health check path of kube-proxy would be used.
+	ServiceAnnotationHealthProbeRequestPath = "service.beta.kubernetes.io/azure-health-probe-request-path"
+
 	// ServiceAnnotationAzurePIPTags determines what tags should be applied to the public IP of the service. The cluster name
 	// and service names tags (which is managed by controller manager itself) would keep unchanged. The supported format
 	// is `a=b,c=d,...`. After updated, the old user-assigned tags would not be replaced by the new ones.
@@ -360,8 +389,16 @@ const (
 	ImdsServer = "http://169.254.169.254"
 	// ImdsInstanceURI is the imds instance uri
 	ImdsInstanceURI = "/metadata/instance"
+	// ImdsEndpointsAPIVersion is the imds endpoints api version
+	ImdsEndpointsAPIVersion = "2020-06-01"
+	// ImdsEndpointsURI is the imds uri of the endpoints of the clouds
+	ImdsEndpointsURI = "/metadata/endpoints"
 	// ImdsLoadBalancerURI is the imds load balancer uri
 	ImdsLoadBalancerURI = "/metadata/loadbalancer"
+	// ImdsScheduledEventsAPIVersion is the imds scheduled events api version
+	ImdsScheduledEventsAPIVersion = "2020-07-01"
+	// ImdsScheduledEventsURI is the imds scheduled events uri
+	ImdsScheduledEventsURI = "/metadata/scheduledevents"
 )
 
 // routes
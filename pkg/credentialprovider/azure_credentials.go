@@ -185,7 +185,7 @@ func parseConfig(configReader io.Reader) (*auth.AzureAuthConfig, *azure.Environm
 		return nil, nil, err
 	}
 
-	environment, err := auth.ParseAzureEnvironment(config.Cloud, config.ResourceManagerEndpoint, config.IdentitySystem)
+	environment, err := auth.GetAzureEnvironment(&config)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -0,0 +1,92 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package log
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/uuid"
+)
+
+const (
+	// ServiceKey is the key of the namespaced name of the service being reconciled.
+	ServiceKey = "service"
+	// OperationIDKey is the key of the ID shared by the log lines of an operation.
+	OperationIDKey = "operationID"
+	// AzureResourceIDKey is the key of the ID of the Azure resource being requested.
+	AzureResourceIDKey = "azureResourceID"
+	// DurationMsKey is the key of the duration in milliseconds of an operation or request.
+	DurationMsKey = "durationMs"
+)
+
+// operationKey is the context key of the operation.
+type operationKey struct{}
+
+// operation is an invocation of the cloud provider, e.g. EnsureLoadBalancer, whose log lines are correlated by its ID.
+type operation struct {
+	id      string
+	service string
+}
+
+// NewOperationContext returns a copy of ctx carrying a new operation with a random ID for the service. The
+// service is optional, e.g. for the operations of the routes. If ctx already carries an operation, e.g. in the
+// nested calls, ctx is returned as is so that the operation ID is kept.
+func NewOperationContext(ctx context.Context, service string) context.Context {
+	if _, ok := ctx.Value(operationKey{}).(*operation); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, operationKey{}, &operation{
+		id:      string(uuid.NewUUID()),
+		service: service,
+	})
+}
+
+// OperationID returns the ID of the operation carried by ctx, it is empty if there is no operation.
+func OperationID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if op, ok := ctx.Value(operationKey{}).(*operation); ok {
+		return op.id
+	}
+	return ""
+}
+
+// KeysAndValues returns the keys and values of the operation carried by ctx followed by keysAndValues,
+// which are passed to klog.InfoS or klog.ErrorS.
+func KeysAndValues(ctx context.Context, keysAndValues ...interface{}) []interface{} {
+	var op *operation
+	if ctx != nil {
+		op, _ = ctx.Value(operationKey{}).(*operation)
+	}
+	if op == nil {
+		return keysAndValues
+	}
+
+	result := make([]interface{}, 0, len(keysAndValues)+4)
+	if op.service != "" {
+		result = append(result, ServiceKey, op.service)
+	}
+	result = append(result, OperationIDKey, op.id)
+	return append(result, keysAndValues...)
+}
+
+// DurationMs returns the milliseconds elapsed since start.
+func DurationMs(start time.Time) int64 {
+	return time.Since(start).Milliseconds()
+}
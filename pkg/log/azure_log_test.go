@@ -0,0 +1,53 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package log
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewOperationContext(t *testing.T) {
+	ctx := NewOperationContext(context.Background(), "default/svc")
+	operationID := OperationID(ctx)
+	assert.NotEmpty(t, operationID)
+
+	nested := func(ctx context.Context) string {
+		return OperationID(NewOperationContext(ctx, "default/svc"))
+	}
+	assert.Equal(t, operationID, nested(ctx), "nested calls should keep the operation ID")
+	assert.Equal(t, operationID, nested(context.WithValue(ctx, struct{}{}, "value")), "derived contexts should keep the operation ID")
+	assert.NotEqual(t, operationID, OperationID(NewOperationContext(context.Background(), "default/svc")))
+	assert.Empty(t, OperationID(context.Background()))
+}
+
+func TestKeysAndValues(t *testing.T) {
+	assert.Equal(t, []interface{}{"key", "value"}, KeysAndValues(context.Background(), "key", "value"))
+
+	ctx := NewOperationContext(context.Background(), "default/svc")
+	assert.Equal(t, []interface{}{ServiceKey, "default/svc", OperationIDKey, OperationID(ctx), "key", "value"}, KeysAndValues(ctx, "key", "value"))
+
+	ctx = NewOperationContext(context.Background(), "")
+	assert.Equal(t, []interface{}{OperationIDKey, OperationID(ctx)}, KeysAndValues(ctx))
+}
+
+func TestDurationMs(t *testing.T) {
+	assert.GreaterOrEqual(t, DurationMs(time.Now().Add(-time.Second)), int64(1000))
+}
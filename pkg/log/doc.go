@@ -0,0 +1,23 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package log provides the helpers of the structured logging which correlate the log lines of an operation.
+//
+// An operation is created by NewOperationContext once per entry point, e.g. EnsureLoadBalancer, and is
+// carried by the context to the nested calls. KeysAndValues prefixes the key/value pairs of a log line
+// with the "service" and "operationID" of the operation, so every line of the operation could be found
+// by its operationID.
+package log // import "sigs.k8s.io/cloud-provider-azure/pkg/log"
@@ -17,9 +17,12 @@ limitations under the License.
 package metrics
 
 import (
+	"context"
 	"strings"
+	"sync"
 	"time"
 
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/component-base/metrics"
 	"k8s.io/component-base/metrics/legacyregistry"
 	"k8s.io/klog/v2"
@@ -28,16 +31,59 @@ import (
 	"sigs.k8s.io/cloud-provider-azure/pkg/retry"
 )
 
+const (
+	// SourceServiceController is the source of the operations of the service controller.
+	SourceServiceController = "serviceController"
+	// SourceRouteController is the source of the operations of the route controller.
+	SourceRouteController = "routeController"
+	// SourceNodeController is the source of the operations of the cloud node controllers.
+	SourceNodeController = "nodeController"
+	// SourceNodeIPAMController is the source of the operations of the node IPAM controller.
+	SourceNodeIPAMController = "nodeIPAMController"
+	// SourceUnknown is the source of the operations whose sources are not set or not allowed.
+	SourceUnknown = "unknown"
+
+	// ServiceReconcileResultSucceeded is the result of the succeeded reconciliations of the LoadBalancer services.
+	ServiceReconcileResultSucceeded = "succeeded"
+	// ServiceReconcileResultFailed is the result of the failed reconciliations of the LoadBalancer services.
+	ServiceReconcileResultFailed = "failed"
+)
+
 var (
 	metricLabels = []string{
-		"request",         // API function that is being invoked
-		"resource_group",  // Resource group of the resource being monitored
-		"subscription_id", // Subscription ID of the resource being monitored
-		"source",          // Operation source(optional)
+		"request",           // API function that is being invoked
+		"resource_group",    // Resource group of the resource being monitored
+		"subscription_id",   // Subscription ID of the resource being monitored
+		"source",            // Operation source(optional)
+		"source_controller", // Controller from which the operation originates, e.g. serviceController
 	}
 
+	// allowedSources are the allowed values of the source_controller label, which keep the cardinality bounded.
+	allowedSources = sets.NewString(
+		SourceServiceController,
+		SourceRouteController,
+		SourceNodeController,
+		SourceNodeIPAMController,
+	)
+
 	apiMetrics       = registerAPIMetrics(metricLabels...)
 	operationMetrics = registerOperationMetrics(metricLabels...)
+
+	skippedVMSSVMCount = registerSkippedVMSSVMMetrics()
+
+	nodeSyncCount = registerNodeSyncMetrics()
+
+	cacheRequestCount = registerCacheMetrics()
+
+	tokenRefreshCount, tokenExpiry = registerTokenMetrics()
+
+	rateLimitRemaining = registerRateLimitRemainingMetrics()
+
+	serviceReconcileMetrics = registerServiceReconcileMetrics()
+
+	// failingServices are the namespaced names of the LoadBalancer services whose last reconciliations failed.
+	failingServices     = sets.NewString()
+	failingServicesLock sync.Mutex
 )
 
 // apiCallMetrics is the metrics measuring the performance of a single API call
@@ -49,6 +95,13 @@ type apiCallMetrics struct {
 	throttledCount   *metrics.CounterVec
 }
 
+// serviceReconcileCallMetrics is the metrics measuring the reconciliations of the LoadBalancer services.
+type serviceReconcileCallMetrics struct {
+	latency *metrics.HistogramVec
+	count   *metrics.CounterVec
+	failing *metrics.Gauge
+}
+
 // operationCallMetrics is the metrics measuring the performance of a whole operation
 // e.g., the create / update / delete process of a loadbalancer or route.
 type operationCallMetrics struct {
@@ -56,6 +109,61 @@ type operationCallMetrics struct {
 	operationFailureCount *metrics.CounterVec
 }
 
+// sourceKey is the context key of the source controller of the operations.
+type sourceKey struct{}
+
+// WithSource returns a copy of ctx carrying the source controller of the operations, e.g. SourceServiceController.
+// The metrics of the API calls with the context are labeled with the source controller.
+func WithSource(ctx context.Context, source string) context.Context {
+	return context.WithValue(ctx, sourceKey{}, source)
+}
+
+// SourceFromContext returns the source controller carried by ctx. SourceUnknown is returned if the source
+// is not set or not in the allow-list.
+func SourceFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return SourceUnknown
+	}
+	source, ok := ctx.Value(sourceKey{}).(string)
+	if !ok || !allowedSources.Has(source) {
+		return SourceUnknown
+	}
+	return source
+}
+
+// attributionTagsKey is the context key of the attribution tags of the operations.
+type attributionTagsKey struct{}
+
+// WithAttributionTags returns a copy of ctx carrying the attribution tags of the operations, e.g. the operation
+// name, merged with the tags already carried by ctx. The tags are only passed to the request metrics hooks to
+// attribute the API calls, they are never sent to ARM.
+func WithAttributionTags(ctx context.Context, tags map[string]string) context.Context {
+	merged := AttributionTagsFromContext(ctx)
+	if merged == nil {
+		merged = make(map[string]string, len(tags))
+	}
+	for key, value := range tags {
+		merged[key] = value
+	}
+	return context.WithValue(ctx, attributionTagsKey{}, merged)
+}
+
+// AttributionTagsFromContext returns a copy of the attribution tags carried by ctx, or nil if there are none.
+func AttributionTagsFromContext(ctx context.Context) map[string]string {
+	if ctx == nil {
+		return nil
+	}
+	tags, ok := ctx.Value(attributionTagsKey{}).(map[string]string)
+	if !ok {
+		return nil
+	}
+	copied := make(map[string]string, len(tags))
+	for key, value := range tags {
+		copied[key] = value
+	}
+	return copied
+}
+
 // MetricContext indicates the context for Azure client metrics.
 type MetricContext struct {
 	start      time.Time
@@ -66,10 +174,16 @@ type MetricContext struct {
 func NewMetricContext(prefix, request, resourceGroup, subscriptionID, source string) *MetricContext {
 	return &MetricContext{
 		start:      time.Now(),
-		attributes: []string{prefix + "_" + request, strings.ToLower(resourceGroup), subscriptionID, source},
+		attributes: []string{prefix + "_" + request, strings.ToLower(resourceGroup), subscriptionID, source, SourceUnknown},
 	}
 }
 
+// WithContext sets the source controller label of the metrics to the source carried by ctx.
+func (mc *MetricContext) WithContext(ctx context.Context) *MetricContext {
+	mc.attributes[len(mc.attributes)-1] = SourceFromContext(ctx)
+	return mc
+}
+
 // RateLimitedCount records the metrics for rate limited request count.
 func (mc *MetricContext) RateLimitedCount() {
 	apiMetrics.rateLimitedCount.WithLabelValues(mc.attributes...).Inc()
@@ -117,6 +231,60 @@ func (mc *MetricContext) CountFailedOperation() {
 	operationMetrics.operationFailureCount.WithLabelValues(mc.attributes...).Inc()
 }
 
+// CountSkippedVMSSVM increases the number of VMSS VMs skipped by the operation because of their provisioning states.
+func CountSkippedVMSSVM(operation, provisioningState string) {
+	skippedVMSSVMCount.WithLabelValues(operation, provisioningState).Inc()
+}
+
+// CountNodeSync increases the number of node syncs of the cloud node manager by their results, e.g. updated or skipped.
+func CountNodeSync(result string) {
+	nodeSyncCount.WithLabelValues(result).Inc()
+}
+
+// CountCacheRequest increases the number of requests to the cache by their results, e.g. hit, miss or expired.
+func CountCacheRequest(cacheName, result string) {
+	cacheRequestCount.WithLabelValues(cacheName, result).Inc()
+}
+
+// CountTokenRefresh increases the number of refreshes of the access token by their results, e.g. succeeded or failed.
+func CountTokenRefresh(token, result string) {
+	tokenRefreshCount.WithLabelValues(token, result).Inc()
+}
+
+// SetTokenExpiry records the time when the current access token expires.
+func SetTokenExpiry(token string, expiresOn time.Time) {
+	tokenExpiry.WithLabelValues(token).Set(float64(expiresOn.Unix()))
+}
+
+// SetRateLimitRemaining records the number of the remaining ARM requests of the rate limit type, e.g. reads or writes.
+func SetRateLimitRemaining(rateLimitType string, remaining int) {
+	rateLimitRemaining.WithLabelValues(rateLimitType).Set(float64(remaining))
+}
+
+// ObserveServiceReconcile observes the latency and the result of the reconciliation of a LoadBalancer service,
+// e.g. ensure_loadbalancer, and updates the number of the services currently failing to be reconciled.
+func ObserveServiceReconcile(operation, serviceName string, isInternal bool, start time.Time, isOperationSucceeded bool) {
+	lbType := "external"
+	if isInternal {
+		lbType = "internal"
+	}
+	result := ServiceReconcileResultSucceeded
+	if !isOperationSucceeded {
+		result = ServiceReconcileResultFailed
+	}
+	serviceReconcileMetrics.latency.WithLabelValues(operation, result, lbType).Observe(time.Since(start).Seconds())
+	serviceReconcileMetrics.count.WithLabelValues(operation, result, lbType).Inc()
+
+	failingServicesLock.Lock()
+	defer failingServicesLock.Unlock()
+	if isOperationSucceeded {
+		failingServices.Delete(serviceName)
+	} else {
+		failingServices.Insert(serviceName)
+	}
+	serviceReconcileMetrics.failing.Set(float64(failingServices.Len()))
+}
+
 // registerAPIMetrics registers the API metrics.
 func registerAPIMetrics(attributes ...string) *apiCallMetrics {
 	metrics := &apiCallMetrics{
@@ -196,3 +364,140 @@ func registerOperationMetrics(attributes ...string) *operationCallMetrics {
 
 	return metrics
 }
+
+// registerSkippedVMSSVMMetrics registers the metrics of VMSS VMs skipped because of their provisioning states.
+func registerSkippedVMSSVMMetrics() *metrics.CounterVec {
+	skippedCount := metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      consts.AzureMetricsNamespace,
+			Name:           "vmss_vm_skipped_count",
+			Help:           "Number of VMSS VMs skipped because of their provisioning states",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"operation", "provisioning_state"},
+	)
+
+	legacyregistry.MustRegister(skippedCount)
+
+	return skippedCount
+}
+
+// registerNodeSyncMetrics registers the metrics of node syncs of the cloud node manager.
+func registerNodeSyncMetrics() *metrics.CounterVec {
+	syncCount := metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      consts.AzureMetricsNamespace,
+			Name:           "node_sync_count",
+			Help:           "Number of node syncs of the cloud node manager by their results",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"result"},
+	)
+
+	legacyregistry.MustRegister(syncCount)
+
+	return syncCount
+}
+
+// registerCacheMetrics registers the metrics of the requests to the caches.
+func registerCacheMetrics() *metrics.CounterVec {
+	requestCount := metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      consts.AzureMetricsNamespace,
+			Name:           "cache_request_count",
+			Help:           "Number of requests to the caches by their results",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"cache", "result"},
+	)
+
+	legacyregistry.MustRegister(requestCount)
+
+	return requestCount
+}
+
+// registerTokenMetrics registers the metrics of the access tokens.
+func registerTokenMetrics() (*metrics.CounterVec, *metrics.GaugeVec) {
+	refreshCount := metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      consts.AzureMetricsNamespace,
+			Name:           "token_refresh_total",
+			Help:           "Number of refreshes of the access tokens by their results",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"token", "result"},
+	)
+	expiry := metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Namespace:      consts.AzureMetricsNamespace,
+			Name:           "token_expiry_seconds",
+			Help:           "Unix time in seconds when the current access tokens expire",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"token"},
+	)
+
+	legacyregistry.MustRegister(refreshCount)
+	legacyregistry.MustRegister(expiry)
+
+	return refreshCount, expiry
+}
+
+// registerRateLimitRemainingMetrics registers the metrics of the remaining ARM requests.
+func registerRateLimitRemainingMetrics() *metrics.GaugeVec {
+	remaining := metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Namespace:      consts.AzureMetricsNamespace,
+			Name:           "api_ratelimit_remaining",
+			Help:           "Number of the remaining ARM requests reported in the x-ms-ratelimit-remaining headers",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"type"},
+	)
+
+	legacyregistry.MustRegister(remaining)
+
+	return remaining
+}
+
+// registerServiceReconcileMetrics registers the metrics of the reconciliations of the LoadBalancer services.
+// They are registered to the legacy registry, which is served by both the cloud-controller-manager
+// and the kube-controller-manager.
+func registerServiceReconcileMetrics() *serviceReconcileCallMetrics {
+	attributes := []string{"operation", "result", "lb_type"}
+	metrics := &serviceReconcileCallMetrics{
+		latency: metrics.NewHistogramVec(
+			&metrics.HistogramOpts{
+				Namespace:      consts.AzureMetricsNamespace,
+				Name:           "service_reconcile_duration_seconds",
+				Help:           "Latency of the reconciliations of the LoadBalancer services",
+				Buckets:        []float64{0.1, 0.5, 1, 5, 10, 30, 60, 120, 300, 600, 1200},
+				StabilityLevel: metrics.ALPHA,
+			},
+			attributes,
+		),
+		count: metrics.NewCounterVec(
+			&metrics.CounterOpts{
+				Namespace:      consts.AzureMetricsNamespace,
+				Name:           "service_reconcile_total",
+				Help:           "Number of the reconciliations of the LoadBalancer services by their results",
+				StabilityLevel: metrics.ALPHA,
+			},
+			attributes,
+		),
+		failing: metrics.NewGauge(
+			&metrics.GaugeOpts{
+				Namespace:      consts.AzureMetricsNamespace,
+				Name:           "service_reconcile_failing",
+				Help:           "Number of the LoadBalancer services whose last reconciliations failed",
+				StabilityLevel: metrics.ALPHA,
+			},
+		),
+	}
+
+	legacyregistry.MustRegister(metrics.latency)
+	legacyregistry.MustRegister(metrics.count)
+	legacyregistry.MustRegister(metrics.failing)
+
+	return metrics
+}
@@ -17,9 +17,17 @@ limitations under the License.
 package metrics
 
 import (
+	"context"
+	"fmt"
+	"net/http"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+
+	"k8s.io/component-base/metrics/testutil"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/retry"
 )
 
 func TestAzureMetricLabelCardinality(t *testing.T) {
@@ -37,3 +45,145 @@ func TestAzureMetricLabelPrefix(t *testing.T) {
 	}
 	assert.True(t, found, "request label must be prefixed")
 }
+
+func TestSourceFromContext(t *testing.T) {
+	assert.Equal(t, SourceUnknown, SourceFromContext(context.Background()))
+	assert.Equal(t, SourceServiceController, SourceFromContext(WithSource(context.Background(), SourceServiceController)))
+	assert.Equal(t, SourceRouteController, SourceFromContext(WithSource(context.Background(), SourceRouteController)))
+	assert.Equal(t, SourceUnknown, SourceFromContext(WithSource(context.Background(), "someController")), "sources not in the allow-list should be unknown")
+}
+
+func TestAttributionTagsFromContext(t *testing.T) {
+	assert.Nil(t, AttributionTagsFromContext(context.Background()))
+
+	ctx := WithAttributionTags(context.Background(), map[string]string{"operation": "ensure_loadbalancer", "service": "default/svc"})
+	ctx = WithAttributionTags(ctx, map[string]string{"operation": "reconcile_lb"})
+	tags := AttributionTagsFromContext(ctx)
+	assert.Equal(t, map[string]string{"operation": "reconcile_lb", "service": "default/svc"}, tags)
+
+	tags["service"] = "changed"
+	assert.Equal(t, "default/svc", AttributionTagsFromContext(ctx)["service"], "the tags in the context should not be changed by the callers")
+}
+
+func TestObserveWithSource(t *testing.T) {
+	ctx := WithSource(context.Background(), SourceServiceController)
+	mc := NewMetricContext("test_source", "get", "RG", "subscription_id", "").WithContext(ctx)
+	mc.Observe(nil)
+	mc = NewMetricContext("test_source", "get", "RG", "subscription_id", "").WithContext(ctx)
+	mc.Observe(&retry.Error{
+		HTTPStatusCode: http.StatusConflict,
+		RawError:       fmt.Errorf(`{"error":{"code":"AnotherOperationInProgress","message":"conflict"}}`),
+	})
+	NewMetricContext("test_source", "get", "RG", "subscription_id", "").Observe(nil)
+
+	count, err := testutil.GetCounterMetricValue(apiMetrics.errors.WithLabelValues("test_source_get", "rg", "subscription_id", "", SourceServiceController, "AnotherOperationInProgress"))
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1), count)
+
+	samples, err := testutil.GetHistogramMetricCount(apiMetrics.latency.WithLabelValues("test_source_get", "rg", "subscription_id", "", SourceServiceController))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), samples)
+	samples, err = testutil.GetHistogramMetricCount(apiMetrics.latency.WithLabelValues("test_source_get", "rg", "subscription_id", "", SourceUnknown))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), samples)
+}
+
+func TestCountSkippedVMSSVM(t *testing.T) {
+	CountSkippedVMSSVM("operation", "Deleting")
+	CountSkippedVMSSVM("operation", "Deleting")
+	CountSkippedVMSSVM("operation", "Failed")
+
+	count, err := testutil.GetCounterMetricValue(skippedVMSSVMCount.WithLabelValues("operation", "Deleting"))
+	assert.NoError(t, err)
+	assert.Equal(t, float64(2), count)
+	count, err = testutil.GetCounterMetricValue(skippedVMSSVMCount.WithLabelValues("operation", "Failed"))
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1), count)
+}
+
+func TestCountNodeSync(t *testing.T) {
+	CountNodeSync("updated")
+	CountNodeSync("skipped")
+	CountNodeSync("skipped")
+
+	count, err := testutil.GetCounterMetricValue(nodeSyncCount.WithLabelValues("updated"))
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1), count)
+	count, err = testutil.GetCounterMetricValue(nodeSyncCount.WithLabelValues("skipped"))
+	assert.NoError(t, err)
+	assert.Equal(t, float64(2), count)
+}
+
+func TestCountCacheRequest(t *testing.T) {
+	CountCacheRequest("cache", "hit")
+	CountCacheRequest("cache", "hit")
+	CountCacheRequest("cache", "miss")
+
+	count, err := testutil.GetCounterMetricValue(cacheRequestCount.WithLabelValues("cache", "hit"))
+	assert.NoError(t, err)
+	assert.Equal(t, float64(2), count)
+	count, err = testutil.GetCounterMetricValue(cacheRequestCount.WithLabelValues("cache", "miss"))
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1), count)
+}
+
+func TestTokenMetrics(t *testing.T) {
+	CountTokenRefresh("primary", "succeeded")
+	CountTokenRefresh("primary", "failed")
+	CountTokenRefresh("primary", "failed")
+	expiresOn := time.Unix(1700000000, 0)
+	SetTokenExpiry("primary", expiresOn)
+
+	count, err := testutil.GetCounterMetricValue(tokenRefreshCount.WithLabelValues("primary", "succeeded"))
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1), count)
+	count, err = testutil.GetCounterMetricValue(tokenRefreshCount.WithLabelValues("primary", "failed"))
+	assert.NoError(t, err)
+	assert.Equal(t, float64(2), count)
+	expiry, err := testutil.GetGaugeMetricValue(tokenExpiry.WithLabelValues("primary"))
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1700000000), expiry)
+}
+
+func TestSetRateLimitRemaining(t *testing.T) {
+	SetRateLimitRemaining("writes", 1199)
+	SetRateLimitRemaining("writes", 1198)
+	SetRateLimitRemaining("reads", 11999)
+
+	remaining, err := testutil.GetGaugeMetricValue(rateLimitRemaining.WithLabelValues("writes"))
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1198), remaining)
+	remaining, err = testutil.GetGaugeMetricValue(rateLimitRemaining.WithLabelValues("reads"))
+	assert.NoError(t, err)
+	assert.Equal(t, float64(11999), remaining)
+}
+
+func TestObserveServiceReconcile(t *testing.T) {
+	start := time.Now()
+	ObserveServiceReconcile("ensure_loadbalancer", "default/svc1", false, start, false)
+	ObserveServiceReconcile("ensure_loadbalancer", "default/svc2", true, start, false)
+	ObserveServiceReconcile("ensure_loadbalancer", "default/svc2", true, start, false)
+
+	count, err := testutil.GetCounterMetricValue(serviceReconcileMetrics.count.WithLabelValues("ensure_loadbalancer", ServiceReconcileResultFailed, "external"))
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1), count)
+	count, err = testutil.GetCounterMetricValue(serviceReconcileMetrics.count.WithLabelValues("ensure_loadbalancer", ServiceReconcileResultFailed, "internal"))
+	assert.NoError(t, err)
+	assert.Equal(t, float64(2), count)
+	samples, err := testutil.GetHistogramMetricCount(serviceReconcileMetrics.latency.WithLabelValues("ensure_loadbalancer", ServiceReconcileResultFailed, "internal"))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), samples)
+	failing, err := testutil.GetGaugeMetricValue(serviceReconcileMetrics.failing)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(2), failing, "each failing service should be counted once")
+
+	ObserveServiceReconcile("update_loadbalancer", "default/svc1", false, start, true)
+	ObserveServiceReconcile("ensure_loadbalancer_deleted", "default/svc2", true, start, true)
+
+	count, err = testutil.GetCounterMetricValue(serviceReconcileMetrics.count.WithLabelValues("update_loadbalancer", ServiceReconcileResultSucceeded, "external"))
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1), count)
+	failing, err = testutil.GetGaugeMetricValue(serviceReconcileMetrics.failing)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(0), failing)
+}
@@ -29,6 +29,7 @@ import (
 )
 
 // IMDSNodeProvider implements nodemanager.NodeProvider.
+// It gets the node information of the local instance from IMDS only, so that it doesn't require Azure credentials.
 type IMDSNodeProvider struct {
 	azure *azureprovider.Cloud
 }
@@ -50,13 +51,13 @@ func NewIMDSNodeProvider() *IMDSNodeProvider {
 
 // NodeAddresses returns the addresses of the specified instance.
 func (np *IMDSNodeProvider) NodeAddresses(ctx context.Context, name types.NodeName) ([]v1.NodeAddress, error) {
-	return np.azure.NodeAddresses(ctx, name)
+	return np.azure.LocalInstanceNodeAddresses(name)
 }
 
 // InstanceID returns the cloud provider ID of the specified instance.
 // Note that if the instance does not exist or is no longer running, we must return ("", cloudprovider.InstanceNotFound)
 func (np *IMDSNodeProvider) InstanceID(ctx context.Context, name types.NodeName) (string, error) {
-	instanceID, err := np.azure.InstanceID(ctx, name)
+	instanceID, err := np.azure.LocalInstanceID()
 	if err != nil {
 		return "", err
 	}
@@ -69,7 +70,7 @@ func (np *IMDSNodeProvider) InstanceID(ctx context.Context, name types.NodeName)
 // (Implementer Note): This is used by kubelet. Kubelet will label the node. Real log from kubelet:
 //       Adding node label from cloud provider: beta.kubernetes.io/instance-type=[value]
 func (np *IMDSNodeProvider) InstanceType(ctx context.Context, name types.NodeName) (string, error) {
-	return np.azure.InstanceType(ctx, name)
+	return np.azure.LocalInstanceType()
 }
 
 // GetZone returns the Zone containing the current failure zone and locality region that the program is running in
@@ -83,3 +84,13 @@ func (np *IMDSNodeProvider) GetZone(ctx context.Context, name types.NodeName) (c
 func (np *IMDSNodeProvider) GetPlatformSubFaultDomain() (string, error) {
 	return np.azure.GetPlatformSubFaultDomain()
 }
+
+// GetExtendedLocation returns the name of the extended location (e.g. the edge zone) of the instance if set.
+func (np *IMDSNodeProvider) GetExtendedLocation() (string, error) {
+	return np.azure.GetExtendedLocation()
+}
+
+// GetScheduledShutdownEvent returns the type of the scheduled event from IMDS which would shut down the instance.
+func (np *IMDSNodeProvider) GetScheduledShutdownEvent() (string, error) {
+	return np.azure.LocalInstanceScheduledShutdownEvent()
+}
@@ -0,0 +1,250 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package node
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	cloudprovider "k8s.io/cloud-provider"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+	azureprovider "sigs.k8s.io/cloud-provider-azure/pkg/provider"
+)
+
+const (
+	vmssMetadata = `{
+	"compute": {
+		"name": "vmss_0",
+		"vmScaleSetName": "vmss",
+		"vmSize": "Standard_D2s_v3",
+		"osType": "Linux",
+		"location": "westus",
+		"zone": "1",
+		"platformFaultDomain": "0",
+		"resourceGroupName": "RG",
+		"subscriptionId": "subscription"
+	},
+	"network": {
+		"interface": [{
+			"ipv4": {"ipAddress": [{"privateIpAddress": "10.0.0.4", "publicIpAddress": ""}]},
+			"ipv6": {"ipAddress": []}
+		}]
+	}
+}`
+	standaloneMetadata = `{
+	"compute": {
+		"name": "vm1",
+		"vmSize": "Standard_D4s_v3",
+		"osType": "Linux",
+		"location": "westus",
+		"platformFaultDomain": "2",
+		"resourceGroupName": "rg",
+		"subscriptionId": "subscription"
+	},
+	"network": {
+		"interface": [{
+			"ipv4": {"ipAddress": [{"privateIpAddress": "10.0.0.5", "publicIpAddress": "1.2.3.4"}]},
+			"ipv6": {"ipAddress": []}
+		}]
+	}
+}`
+	edgeZoneMetadata = `{
+	"compute": {
+		"name": "vm2",
+		"vmSize": "Standard_D2s_v3",
+		"osType": "Linux",
+		"location": "westus",
+		"platformFaultDomain": "0",
+		"resourceGroupName": "rg",
+		"subscriptionId": "subscription",
+		"extendedLocation": {"type": "EdgeZone", "name": "microsoftlosangeles1"}
+	},
+	"network": {
+		"interface": [{
+			"ipv4": {"ipAddress": [{"privateIpAddress": "10.0.0.7", "publicIpAddress": ""}]},
+			"ipv6": {"ipAddress": []}
+		}]
+	}
+}`
+	windowsMetadata = `{
+	"compute": {
+		"name": "akswindowsnodepool1",
+		"vmSize": "Standard_D8s_v3",
+		"osType": "Windows",
+		"location": "westus",
+		"zone": "3",
+		"platformFaultDomain": "0",
+		"resourceGroupName": "rg",
+		"subscriptionId": "subscription"
+	},
+	"network": {
+		"interface": [{
+			"ipv4": {"ipAddress": [{"privateIpAddress": "10.0.0.6", "publicIpAddress": ""}]},
+			"ipv6": {"ipAddress": []}
+		}]
+	}
+}`
+)
+
+func TestIMDSNodeProvider(t *testing.T) {
+	testCases := []struct {
+		desc                     string
+		nodeName                 types.NodeName
+		metadata                 string
+		transientFailures        int
+		expectedProviderID       string
+		expectedInstanceType     string
+		expectedZone             cloudprovider.Zone
+		expectedNodeAddresses    []v1.NodeAddress
+		expectedExtendedLocation string
+	}{
+		{
+			desc:                 "node information of vmss instance shall be got from IMDS",
+			nodeName:             "vmss000000",
+			metadata:             vmssMetadata,
+			expectedProviderID:   "azure:///subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0",
+			expectedInstanceType: "Standard_D2s_v3",
+			expectedZone:         cloudprovider.Zone{FailureDomain: "westus-1", Region: "westus"},
+			expectedNodeAddresses: []v1.NodeAddress{
+				{Type: v1.NodeHostName, Address: "vmss000000"},
+				{Type: v1.NodeInternalIP, Address: "10.0.0.4"},
+			},
+		},
+		{
+			desc:                 "node information of standalone instance shall be got from IMDS",
+			nodeName:             "vm1",
+			metadata:             standaloneMetadata,
+			expectedProviderID:   "azure:///subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/vm1",
+			expectedInstanceType: "Standard_D4s_v3",
+			expectedZone:         cloudprovider.Zone{FailureDomain: "2", Region: "westus"},
+			expectedNodeAddresses: []v1.NodeAddress{
+				{Type: v1.NodeHostName, Address: "vm1"},
+				{Type: v1.NodeInternalIP, Address: "10.0.0.5"},
+				{Type: v1.NodeExternalIP, Address: "1.2.3.4"},
+			},
+		},
+		{
+			desc:                 "extended location of edge zone instance shall be got from IMDS",
+			nodeName:             "vm2",
+			metadata:             edgeZoneMetadata,
+			expectedProviderID:   "azure:///subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/vm2",
+			expectedInstanceType: "Standard_D2s_v3",
+			expectedZone:         cloudprovider.Zone{FailureDomain: "0", Region: "westus"},
+			expectedNodeAddresses: []v1.NodeAddress{
+				{Type: v1.NodeHostName, Address: "vm2"},
+				{Type: v1.NodeInternalIP, Address: "10.0.0.7"},
+			},
+			expectedExtendedLocation: "microsoftlosangeles1",
+		},
+		{
+			desc:                 "providerID of windows instance shall be composed with the VM name instead of the truncated hostname",
+			nodeName:             "akswindowsnode",
+			metadata:             windowsMetadata,
+			expectedProviderID:   "azure:///subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/akswindowsnodepool1",
+			expectedInstanceType: "Standard_D8s_v3",
+			expectedZone:         cloudprovider.Zone{FailureDomain: "westus-3", Region: "westus"},
+			expectedNodeAddresses: []v1.NodeAddress{
+				{Type: v1.NodeHostName, Address: "akswindowsnode"},
+				{Type: v1.NodeInternalIP, Address: "10.0.0.6"},
+			},
+		},
+		{
+			desc:                 "transient failures of IMDS shall be retried",
+			nodeName:             "vm1",
+			metadata:             standaloneMetadata,
+			transientFailures:    1,
+			expectedProviderID:   "azure:///subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/vm1",
+			expectedInstanceType: "Standard_D4s_v3",
+			expectedZone:         cloudprovider.Zone{FailureDomain: "2", Region: "westus"},
+			expectedNodeAddresses: []v1.NodeAddress{
+				{Type: v1.NodeHostName, Address: "vm1"},
+				{Type: v1.NodeInternalIP, Address: "10.0.0.5"},
+				{Type: v1.NodeExternalIP, Address: "1.2.3.4"},
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			failures := 0
+			mux := http.NewServeMux()
+			mux.HandleFunc(consts.ImdsInstanceURI, func(w http.ResponseWriter, r *http.Request) {
+				if failures < test.transientFailures {
+					failures++
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				_, _ = w.Write([]byte(test.metadata))
+			})
+			mux.HandleFunc(consts.ImdsLoadBalancerURI, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			})
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			np := newTestIMDSNodeProvider(t, server.URL)
+			ctx := context.Background()
+
+			providerID, err := np.InstanceID(ctx, test.nodeName)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedProviderID, providerID)
+
+			instanceType, err := np.InstanceType(ctx, test.nodeName)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedInstanceType, instanceType)
+
+			zone, err := np.GetZone(ctx, test.nodeName)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedZone, zone)
+
+			addresses, err := np.NodeAddresses(ctx, test.nodeName)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedNodeAddresses, addresses)
+
+			extendedLocation, err := np.GetExtendedLocation()
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedExtendedLocation, extendedLocation)
+		})
+	}
+}
+
+func TestIMDSNodeProviderUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	server.Close()
+
+	np := newTestIMDSNodeProvider(t, server.URL)
+	_, err := np.InstanceID(context.Background(), "vm1")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "is unreachable")
+}
+
+func newTestIMDSNodeProvider(t *testing.T, imdsServer string) *IMDSNodeProvider {
+	np := NewIMDSNodeProvider()
+	metadata, err := azureprovider.NewInstanceMetadataService(imdsServer)
+	assert.NoError(t, err)
+	np.azure.Metadata = metadata
+	return np
+}
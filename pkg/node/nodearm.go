@@ -102,3 +102,13 @@ func (np *ARMNodeProvider) GetZone(ctx context.Context, name types.NodeName) (cl
 func (np *ARMNodeProvider) GetPlatformSubFaultDomain() (string, error) {
 	return "", nil
 }
+
+// GetExtendedLocation returns the name of the extended location (e.g. the edge zone) of the instance if set.
+func (np *ARMNodeProvider) GetExtendedLocation() (string, error) {
+	return np.azure.GetExtendedLocation()
+}
+
+// GetScheduledShutdownEvent returns the type of the scheduled event from IMDS which would shut down the instance.
+func (np *ARMNodeProvider) GetScheduledShutdownEvent() (string, error) {
+	return np.azure.LocalInstanceScheduledShutdownEvent()
+}
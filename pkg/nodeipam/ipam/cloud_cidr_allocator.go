@@ -17,6 +17,7 @@ limitations under the License.
 package ipam
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"net"
@@ -41,6 +42,7 @@ import (
 	netutils "k8s.io/utils/net"
 
 	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+	"sigs.k8s.io/cloud-provider-azure/pkg/metrics"
 	"sigs.k8s.io/cloud-provider-azure/pkg/nodeipam/ipam/cidrset"
 	providerazure "sigs.k8s.io/cloud-provider-azure/pkg/provider"
 	nodeutil "sigs.k8s.io/cloud-provider-azure/pkg/util/controller/node"
@@ -233,7 +235,10 @@ func (ca *cloudCIDRAllocator) updateNodeSubnetMaskSizes(nodeName, providerID str
 		klog.Warningf("updateNodeSubnetMaskSizes(%s): empty providerID", providerID)
 	}
 
+	ctx := metrics.WithSource(context.Background(), metrics.SourceNodeIPAMController)
+	mc := metrics.NewMetricContext("nodeipam", "get_node_cidr_masks", ca.cloud.ResourceGroup, ca.cloud.SubscriptionID, nodeName).WithContext(ctx)
 	ipv4Mask, ipv6Mask, err := ca.cloud.VMSet.GetNodeCIDRMasksByProviderID(providerID)
+	mc.ObserveOperationWithResult(err == nil)
 	if err != nil {
 		klog.Warningf("updateNodeSubnetMaskSizes(%s): cannot get node subnet mask size by providerID: %v", providerID, err)
 	}
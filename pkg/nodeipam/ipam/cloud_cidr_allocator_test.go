@@ -188,7 +188,7 @@ func TestUpdateNodeSubnetMaskSizes(t *testing.T) {
 	}{
 		{
 			description: "updateNodeSubnetMaskSizes should put the correct mask sizes on the map",
-			providerID:  "azure:///subscriptions/sub/resourceGroups/rg1/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0",
+			providerID:  "azure:///subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0",
 			tags: map[string]*string{
 				consts.VMSetCIDRIPV4TagKey: to.StringPtr("25"),
 				consts.VMSetCIDRIPV6TagKey: to.StringPtr("65"),
@@ -206,7 +206,7 @@ func TestUpdateNodeSubnetMaskSizes(t *testing.T) {
 		},
 		{
 			description: "updateNodeSubnetMaskSizes should report an error if the ipv4 mask is smaller than the cluster mask",
-			providerID:  "azure:///subscriptions/sub/resourceGroups/rg1/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0",
+			providerID:  "azure:///subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0",
 			tags: map[string]*string{
 				consts.VMSetCIDRIPV4TagKey: to.StringPtr("15"),
 				consts.VMSetCIDRIPV6TagKey: to.StringPtr("65"),
@@ -216,7 +216,7 @@ func TestUpdateNodeSubnetMaskSizes(t *testing.T) {
 		},
 		{
 			description: "updateNodeSubnetMaskSizes should report an error if the ipv6 mask is smaller than the cluster mask",
-			providerID:  "azure:///subscriptions/sub/resourceGroups/rg1/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0",
+			providerID:  "azure:///subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0",
 			tags: map[string]*string{
 				consts.VMSetCIDRIPV4TagKey: to.StringPtr("25"),
 				consts.VMSetCIDRIPV6TagKey: to.StringPtr("45"),
@@ -235,7 +235,7 @@ func TestUpdateNodeSubnetMaskSizes(t *testing.T) {
 				Tags: tc.tags,
 			}
 			mockVMSSClient := ss.VirtualMachineScaleSetsClient.(*mockvmssclient.MockInterface)
-			mockVMSSClient.EXPECT().List(gomock.Any(), cloud.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).MaxTimes(1)
+			mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), cloud.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).MaxTimes(1)
 			cloud.VMSet = ss
 
 			clusterCIDRs := func() []*net.IPNet {
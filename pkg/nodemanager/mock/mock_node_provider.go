@@ -49,6 +49,21 @@ func (m *NodeProvider) EXPECT() *NodeProviderMockRecorder {
 	return m.recorder
 }
 
+// GetExtendedLocation mocks base method.
+func (m *NodeProvider) GetExtendedLocation() (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExtendedLocation")
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetExtendedLocation indicates an expected call of GetExtendedLocation.
+func (mr *NodeProviderMockRecorder) GetExtendedLocation() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExtendedLocation", reflect.TypeOf((*NodeProvider)(nil).GetExtendedLocation))
+}
+
 // GetPlatformSubFaultDomain mocks base method.
 func (m *NodeProvider) GetPlatformSubFaultDomain() (string, error) {
 	m.ctrl.T.Helper()
@@ -64,6 +79,21 @@ func (mr *NodeProviderMockRecorder) GetPlatformSubFaultDomain() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPlatformSubFaultDomain", reflect.TypeOf((*NodeProvider)(nil).GetPlatformSubFaultDomain))
 }
 
+// GetScheduledShutdownEvent mocks base method.
+func (m *NodeProvider) GetScheduledShutdownEvent() (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetScheduledShutdownEvent")
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetScheduledShutdownEvent indicates an expected call of GetScheduledShutdownEvent.
+func (mr *NodeProviderMockRecorder) GetScheduledShutdownEvent() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetScheduledShutdownEvent", reflect.TypeOf((*NodeProvider)(nil).GetScheduledShutdownEvent))
+}
+
 // GetZone mocks base method.
 func (m *NodeProvider) GetZone(arg0 context.Context, arg1 types.NodeName) (cloudprovider.Zone, error) {
 	m.ctrl.T.Helper()
@@ -21,6 +21,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -46,6 +49,7 @@ import (
 	"k8s.io/klog/v2"
 
 	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+	"sigs.k8s.io/cloud-provider-azure/pkg/metrics"
 )
 
 // NodeProvider defines the interfaces for node provider.
@@ -60,6 +64,11 @@ type NodeProvider interface {
 	GetZone(ctx context.Context, name types.NodeName) (cloudprovider.Zone, error)
 	// GetPlatformSubFaultDomain returns the PlatformSubFaultDomain from IMDS if set.
 	GetPlatformSubFaultDomain() (string, error)
+	// GetExtendedLocation returns the name of the extended location (e.g. the edge zone) of the instance if set.
+	GetExtendedLocation() (string, error)
+	// GetScheduledShutdownEvent returns the type of the scheduled event which would shut down the instance,
+	// e.g. Preempt, Reboot or Redeploy. Empty string is returned if there isn't any.
+	GetScheduledShutdownEvent() (string, error)
 }
 
 // labelReconcileInfo lists Node labels to reconcile, and how to reconcile them.
@@ -99,6 +108,31 @@ var labelReconcileInfo = []struct {
 	},
 }
 
+const (
+	// nodeSyncResultUpdated is the result of the node sync which updates the node status.
+	nodeSyncResultUpdated = "updated"
+	// nodeSyncResultSkipped is the result of the node sync which is skipped because the node metadata is not changed.
+	nodeSyncResultSkipped = "skipped"
+)
+
+var (
+	// vmssNameRE matches the VMSS name in the provider ID of the VMSS instances.
+	vmssNameRE = regexp.MustCompile(`(?i)/providers/Microsoft\.Compute/virtualMachineScaleSets/([^/]+)/virtualMachines/`)
+	// vmNameRE matches the VM name in the provider ID of the standalone VMs.
+	vmNameRE = regexp.MustCompile(`(?i)/providers/Microsoft\.Compute/virtualMachines/([^/]+)$`)
+	// agentPoolVMSSNameRE matches the agent pool in the VMSS name of AKS and aks-engine, e.g. aks-agentpool-12345678-vmss.
+	agentPoolVMSSNameRE = regexp.MustCompile(`^(?:aks|k8s)-([a-z0-9]+)-\d+-vmss$`)
+	// agentPoolVMNameRE matches the agent pool in the standalone VM name of AKS and aks-engine, e.g. aks-agentpool-12345678-0.
+	agentPoolVMNameRE = regexp.MustCompile(`^(?:aks|k8s)-([a-z0-9]+)-\d+-\d+$`)
+)
+
+// shutdownTaint is the taint applied to the node going to be shut down, it is the same as
+// the one applied by the cloud node lifecycle controller to the shutdown nodes.
+var shutdownTaint = &v1.Taint{
+	Key:    cloudproviderapi.TaintNodeShutdown,
+	Effect: v1.TaintEffectNoSchedule,
+}
+
 // UpdateNodeSpecBackoff is the back configure for node update.
 var UpdateNodeSpecBackoff = wait.Backoff{
 	Steps:    20,
@@ -122,6 +156,27 @@ type CloudNodeController struct {
 	recorder      record.EventRecorder
 
 	nodeStatusUpdateFrequency time.Duration
+	// scheduledEventsPollInterval is the interval to poll the scheduled events of the instance,
+	// the scheduled events are not monitored if it is zero.
+	scheduledEventsPollInterval time.Duration
+
+	// nodeForceSyncPeriods is the number of status update periods after which the node status is synced
+	// even if the node metadata got from the cloud provider is not changed. The node status is synced in
+	// every period if it is not larger than 1.
+	nodeForceSyncPeriods int
+	// lastNodeMetadataHash is the hash of the node metadata at the last successful sync.
+	lastNodeMetadataHash string
+	// skippedNodeSyncs is the number of syncs skipped since the last sync.
+	skippedNodeSyncs int
+}
+
+// nodeMetadata is the node metadata got from the cloud provider, the node status is
+// only synced when it is changed.
+type nodeMetadata struct {
+	Addresses      []v1.NodeAddress   `json:"addresses,omitempty"`
+	InstanceType   string             `json:"instanceType,omitempty"`
+	Zone           cloudprovider.Zone `json:"zone"`
+	TopologyLabels map[string]string  `json:"topologyLabels,omitempty"`
 }
 
 // NewCloudNodeController creates a CloudNodeController object
@@ -131,7 +186,9 @@ func NewCloudNodeController(
 	kubeClient clientset.Interface,
 	nodeProvider NodeProvider,
 	nodeStatusUpdateFrequency time.Duration,
-	waitForRoutes bool) *CloudNodeController {
+	waitForRoutes bool,
+	scheduledEventsPollInterval time.Duration,
+	nodeSyncPeriod time.Duration) *CloudNodeController {
 
 	eventBroadcaster := record.NewBroadcaster()
 	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: "cloud-node-controller"})
@@ -151,6 +208,11 @@ func NewCloudNodeController(
 		nodeProvider:              nodeProvider,
 		waitForRoutes:             waitForRoutes,
 		nodeStatusUpdateFrequency: nodeStatusUpdateFrequency,
+
+		scheduledEventsPollInterval: scheduledEventsPollInterval,
+	}
+	if nodeStatusUpdateFrequency > 0 {
+		cnc.nodeForceSyncPeriods = int(nodeSyncPeriod / nodeStatusUpdateFrequency)
 	}
 
 	// Use shared informer to listen to add/update of nodes. Note that any nodes
@@ -173,10 +235,52 @@ func (cnc *CloudNodeController) Run(stopCh <-chan struct{}) {
 	// of O(num_nodes) per cycle. These functions are justified here because these events fire
 	// very infrequently. DO NOT MODIFY this to perform frequent operations.
 
+	// Start a loop to periodically taint the node before it is shut down by the scheduled events
+	if cnc.scheduledEventsPollInterval > 0 {
+		go wait.Until(func() { cnc.reconcileShutdownTaint(context.TODO()) }, cnc.scheduledEventsPollInterval, stopCh)
+	}
+
 	// Start a loop to periodically update the node addresses obtained from the cloud
 	wait.Until(func() { cnc.UpdateNodeStatus(context.TODO()) }, cnc.nodeStatusUpdateFrequency, stopCh)
 }
 
+// reconcileShutdownTaint applies the shutdown taint to the node if it is going to be shut down by a scheduled
+// event, e.g. preemption or maintenance, so that the workloads could be moved away before the node goes down.
+// The taint is removed after the scheduled event is cancelled or completed.
+func (cnc *CloudNodeController) reconcileShutdownTaint(ctx context.Context) {
+	node, err := cnc.nodeInformer.Lister().Get(cnc.nodeName)
+	if err != nil {
+		// If node not found, just ignore it.
+		if apierrors.IsNotFound(err) {
+			return
+		}
+
+		klog.Errorf("Error getting node %q from informer, err: %v", cnc.nodeName, err)
+		return
+	}
+
+	eventType, err := cnc.nodeProvider.GetScheduledShutdownEvent()
+	if err != nil {
+		klog.Errorf("Error getting scheduled events for node %q, err: %v", node.Name, err)
+		return
+	}
+
+	tainted := hasShutdownTaint(node.Spec.Taints)
+	if eventType != "" && !tainted {
+		klog.Infof("Node %q is going to be shut down by scheduled event %s, applying taint %s", node.Name, eventType, shutdownTaint.Key)
+		if err := cloudnodeutil.AddOrUpdateTaintOnNode(cnc.kubeClient, node.Name, shutdownTaint); err != nil {
+			klog.Errorf("Error applying shutdown taint to node %q, err: %v", node.Name, err)
+			return
+		}
+		cnc.recorder.Eventf(node, v1.EventTypeWarning, "ScheduledShutdown", "Node %s is going to be shut down by scheduled event %s", node.Name, eventType)
+	} else if eventType == "" && tainted {
+		klog.Infof("Node %q is not going to be shut down by scheduled events, removing taint %s", node.Name, shutdownTaint.Key)
+		if err := cloudnodeutil.RemoveTaintOffNode(cnc.kubeClient, node.Name, node, shutdownTaint); err != nil {
+			klog.Errorf("Error removing shutdown taint from node %q, err: %v", node.Name, err)
+		}
+	}
+}
+
 // UpdateNodeStatus updates the node status, such as node addresses
 func (cnc *CloudNodeController) UpdateNodeStatus(ctx context.Context) {
 	node, err := cnc.nodeInformer.Lister().Get(cnc.nodeName)
@@ -190,15 +294,95 @@ func (cnc *CloudNodeController) UpdateNodeStatus(ctx context.Context) {
 		return
 	}
 
+	metadataHash, skip := cnc.shouldSkipNodeSync(ctx, node)
+	if skip {
+		cnc.skippedNodeSyncs++
+		metrics.CountNodeSync(nodeSyncResultSkipped)
+		klog.V(4).Infof("Skipping status sync for node %q since its metadata is not changed", node.Name)
+		return
+	}
+
+	synced := true
 	err = cnc.updateNodeAddress(ctx, node)
 	if err != nil {
+		synced = false
 		klog.Errorf("Error reconciling node address for node %q, err: %v", node.Name, err)
 	}
 
 	err = cnc.reconcileNodeLabels(node)
 	if err != nil {
+		synced = false
 		klog.Errorf("Error reconciling node labels for node %q, err: %v", node.Name, err)
 	}
+
+	err = cnc.reconcileTopologyLabels(ctx, node)
+	if err != nil {
+		synced = false
+		klog.Errorf("Error reconciling topology labels for node %q, err: %v", node.Name, err)
+	}
+
+	// Sync the node status again in the next period if any of the above failed.
+	cnc.lastNodeMetadataHash = ""
+	if synced {
+		cnc.lastNodeMetadataHash = metadataHash
+	}
+	cnc.skippedNodeSyncs = 0
+	metrics.CountNodeSync(nodeSyncResultUpdated)
+}
+
+// shouldSkipNodeSync returns the hash of the node metadata got from the cloud provider and whether the node
+// status sync could be skipped because the metadata is not changed since the last sync. The sync is never
+// skipped for more than nodeForceSyncPeriods-1 periods in a row so that the changes made by others, e.g.
+// the removed labels, are reconciled eventually.
+func (cnc *CloudNodeController) shouldSkipNodeSync(ctx context.Context, node *v1.Node) (string, bool) {
+	if cnc.nodeForceSyncPeriods <= 1 {
+		return "", false
+	}
+
+	// The node which is not initialized yet is not processed by the sync.
+	if GetCloudTaint(node.Spec.Taints) != nil {
+		return "", false
+	}
+
+	metadataHash, err := cnc.getNodeMetadataHash(ctx, node)
+	if err != nil {
+		klog.Warningf("Error getting metadata of node %q, err: %v, will sync its status", node.Name, err)
+		return "", false
+	}
+
+	if metadataHash != cnc.lastNodeMetadataHash || cnc.skippedNodeSyncs+1 >= cnc.nodeForceSyncPeriods {
+		return metadataHash, false
+	}
+	return metadataHash, true
+}
+
+// getNodeMetadataHash returns the hash of the node metadata got from the cloud provider.
+func (cnc *CloudNodeController) getNodeMetadataHash(ctx context.Context, node *v1.Node) (string, error) {
+	var metadata nodeMetadata
+	var err error
+	if metadata.Addresses, err = cnc.getNodeAddressesByName(ctx, node); err != nil {
+		return "", fmt.Errorf("failed to get node addresses: %w", err)
+	}
+	if metadata.InstanceType, err = cnc.getInstanceTypeByName(ctx, node); err != nil {
+		return "", fmt.Errorf("failed to get instance type: %w", err)
+	}
+	if metadata.Zone, err = cnc.getZoneByName(ctx, node); err != nil {
+		return "", fmt.Errorf("failed to get zone: %w", err)
+	}
+	if node.Spec.ProviderID != "" {
+		if metadata.TopologyLabels, err = cnc.getTopologyLabels(node.Spec.ProviderID); err != nil {
+			return "", fmt.Errorf("failed to get topology labels: %w", err)
+		}
+	}
+
+	// The keys of the maps are sorted by json.Marshal, so the hash is stable.
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return "", err
+	}
+	hash := fnv.New64a()
+	_, _ = hash.Write(data)
+	return strconv.FormatUint(hash.Sum64(), 16), nil
 }
 
 // reconcileNodeLabels reconciles node labels transitioning from beta to GA
@@ -241,6 +425,51 @@ func (cnc *CloudNodeController) reconcileNodeLabels(node *v1.Node) error {
 	return nil
 }
 
+// reconcileTopologyLabels adds the topology labels got from the cloud provider if they are missing,
+// e.g. they are removed by users or the node is initialized by an older version of the node manager.
+func (cnc *CloudNodeController) reconcileTopologyLabels(ctx context.Context, node *v1.Node) error {
+	if node.Spec.ProviderID == "" {
+		// The node hasn't been initialized yet.
+		return nil
+	}
+
+	topologyLabels, err := cnc.getTopologyLabels(node.Spec.ProviderID)
+	if err != nil {
+		return err
+	}
+
+	_, regionExists := node.Labels[v1.LabelZoneRegion]
+	_, stableRegionExists := node.Labels[v1.LabelZoneRegionStable]
+	if !regionExists && !stableRegionExists {
+		zone, err := cnc.getZoneByName(ctx, node)
+		if err != nil {
+			return fmt.Errorf("failed to get zone from cloud provider: %w", err)
+		}
+		if zone.Region != "" {
+			topologyLabels[v1.LabelZoneRegion] = zone.Region
+			topologyLabels[v1.LabelZoneRegionStable] = zone.Region
+		}
+	}
+
+	labelsToUpdate := map[string]string{}
+	for key, value := range topologyLabels {
+		if _, ok := node.Labels[key]; !ok {
+			klog.V(2).Infof("Adding missing node label from cloud provider: %s=%s", key, value)
+			labelsToUpdate[key] = value
+		}
+	}
+
+	if len(labelsToUpdate) == 0 {
+		return nil
+	}
+
+	if !cloudnodeutil.AddOrUpdateLabelsOnNode(cnc.kubeClient, labelsToUpdate, node) {
+		return fmt.Errorf("failed update labels for node %+v", node)
+	}
+
+	return nil
+}
+
 // UpdateNodeAddress updates the nodeAddress of a single node
 func (cnc *CloudNodeController) updateNodeAddress(ctx context.Context, node *v1.Node) error {
 	// Do not process nodes that are still tainted
@@ -433,8 +662,10 @@ func (cnc *CloudNodeController) initializeNode(ctx context.Context, node *v1.Nod
 func (cnc *CloudNodeController) getNodeModifiersFromCloudProvider(ctx context.Context, node *v1.Node) ([]nodeModifier, error) {
 	var nodeModifiers []nodeModifier
 
-	if node.Spec.ProviderID == "" {
-		providerID, err := cnc.nodeProvider.InstanceID(ctx, types.NodeName(node.Name))
+	providerID := node.Spec.ProviderID
+	if providerID == "" {
+		var err error
+		providerID, err = cnc.nodeProvider.InstanceID(ctx, types.NodeName(node.Name))
 		if err == nil {
 			nodeModifiers = append(nodeModifiers, func(n *v1.Node) {
 				if n.Spec.ProviderID == "" {
@@ -516,9 +747,74 @@ func (cnc *CloudNodeController) getNodeModifiersFromCloudProvider(ctx context.Co
 		})
 	}
 
+	topologyLabels, err := cnc.getTopologyLabels(providerID)
+	if err != nil {
+		return nil, err
+	}
+	if len(topologyLabels) > 0 {
+		for key, value := range topologyLabels {
+			klog.V(2).Infof("Adding node label from cloud provider: %s=%s", key, value)
+		}
+		nodeModifiers = append(nodeModifiers, func(n *v1.Node) {
+			if n.Labels == nil {
+				n.Labels = map[string]string{}
+			}
+			for key, value := range topologyLabels {
+				// Keep the labels set by others, e.g. the agent pool label set by kubelet.
+				if _, ok := n.Labels[key]; !ok {
+					n.Labels[key] = value
+				}
+			}
+		})
+	}
+
 	return nodeModifiers, nil
 }
 
+// getTopologyLabels returns the labels of the extended location of the node, and the VMSS and
+// agent pool derived from the provider ID of the node.
+func (cnc *CloudNodeController) getTopologyLabels(providerID string) (map[string]string, error) {
+	labels := map[string]string{}
+
+	extendedLocation, err := cnc.getExtendedLocation()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get extended location: %w", err)
+	}
+	if extendedLocation != "" {
+		labels[consts.LabelExtendedLocation] = extendedLocation
+	}
+
+	vmssName, agentPool := getVMSSNameAndAgentPool(providerID)
+	if vmssName != "" {
+		labels[consts.LabelVMSSName] = vmssName
+	}
+	if agentPool != "" {
+		labels[consts.LabelAgentPool] = agentPool
+	}
+
+	return labels, nil
+}
+
+// getVMSSNameAndAgentPool returns the VMSS name and the agent pool derived from the provider ID.
+// The agent pool is only derived from the VMSS or VM names following the naming convention of AKS and aks-engine.
+func getVMSSNameAndAgentPool(providerID string) (string, string) {
+	if matches := vmssNameRE.FindStringSubmatch(providerID); len(matches) == 2 {
+		vmssName := matches[1]
+		if poolMatches := agentPoolVMSSNameRE.FindStringSubmatch(strings.ToLower(vmssName)); len(poolMatches) == 2 {
+			return vmssName, poolMatches[1]
+		}
+		return vmssName, ""
+	}
+
+	if matches := vmNameRE.FindStringSubmatch(providerID); len(matches) == 2 {
+		if poolMatches := agentPoolVMNameRE.FindStringSubmatch(strings.ToLower(matches[1])); len(poolMatches) == 2 {
+			return "", poolMatches[1]
+		}
+	}
+
+	return "", ""
+}
+
 func GetCloudTaint(taints []v1.Taint) *v1.Taint {
 	for _, taint := range taints {
 		if taint.Key == cloudproviderapi.TaintExternalCloudProvider {
@@ -528,6 +824,15 @@ func GetCloudTaint(taints []v1.Taint) *v1.Taint {
 	return nil
 }
 
+func hasShutdownTaint(taints []v1.Taint) bool {
+	for i := range taints {
+		if taints[i].MatchTaint(shutdownTaint) {
+			return true
+		}
+	}
+	return false
+}
+
 func excludeCloudTaint(taints []v1.Taint) []v1.Taint {
 	newTaints := []v1.Taint{}
 	for _, taint := range taints {
@@ -630,6 +935,14 @@ func (cnc *CloudNodeController) getPlatformSubFaultDomain() (string, error) {
 	return subFD, nil
 }
 
+func (cnc *CloudNodeController) getExtendedLocation() (string, error) {
+	extendedLocation, err := cnc.nodeProvider.GetExtendedLocation()
+	if err != nil {
+		return "", fmt.Errorf("cnc.getExtendedLocation: %w", err)
+	}
+	return extendedLocation, nil
+}
+
 func (cnc *CloudNodeController) updateNetworkingCondition(node *v1.Node, networkReady bool) error {
 	_, condition := nodeutil.GetNodeCondition(&(node.Status), v1.NodeNetworkUnavailable)
 	if networkReady && condition != nil && condition.Status == v1.ConditionFalse {
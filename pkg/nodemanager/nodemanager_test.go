@@ -183,6 +183,7 @@ func TestNodeInitialized(t *testing.T) {
 		},
 	}, nil).AnyTimes()
 	mockNP.EXPECT().GetPlatformSubFaultDomain().Return("1", nil)
+	mockNP.EXPECT().GetExtendedLocation().Return("", nil)
 
 	cloudNodeController := NewCloudNodeController(
 		"node0",
@@ -190,7 +191,9 @@ func TestNodeInitialized(t *testing.T) {
 		fnh,
 		mockNP,
 		time.Second,
-		false)
+		false,
+		0,
+		0)
 
 	cloudNodeController.AddCloudNode(ctx, fnh.Existing[0])
 
@@ -260,6 +263,7 @@ func TestUpdateCloudNode(t *testing.T) {
 		},
 	}, nil).AnyTimes()
 	mockNP.EXPECT().GetPlatformSubFaultDomain().Return("1", nil)
+	mockNP.EXPECT().GetExtendedLocation().Return("", nil)
 
 	eventBroadcaster := record.NewBroadcaster()
 	cloudNodeController := NewCloudNodeController(
@@ -268,7 +272,9 @@ func TestUpdateCloudNode(t *testing.T) {
 		fnh,
 		mockNP,
 		time.Second,
-		true)
+		true,
+		0,
+		0)
 	eventBroadcaster.StartLogging(klog.Infof)
 
 	cloudNodeController.UpdateCloudNode(ctx, fnh.Existing[0], fnh.Existing[0])
@@ -319,7 +325,9 @@ func TestNodeIgnored(t *testing.T) {
 		fnh,
 		mockNP,
 		time.Second,
-		false)
+		false,
+		0,
+		0)
 	eventBroadcaster.StartLogging(klog.Infof)
 
 	cloudNodeController.AddCloudNode(context.TODO(), fnh.Existing[0])
@@ -390,6 +398,7 @@ func TestZoneInitialized(t *testing.T) {
 		},
 	}, nil).AnyTimes()
 	mockNP.EXPECT().GetPlatformSubFaultDomain().Return("", nil)
+	mockNP.EXPECT().GetExtendedLocation().Return("", nil)
 
 	eventBroadcaster := record.NewBroadcaster()
 	cloudNodeController := &CloudNodeController{
@@ -485,6 +494,7 @@ func TestAddCloudNode(t *testing.T) {
 		},
 	}, nil).AnyTimes()
 	mockNP.EXPECT().GetPlatformSubFaultDomain().Return("", nil)
+	mockNP.EXPECT().GetExtendedLocation().Return("", nil)
 
 	factory := informers.NewSharedInformerFactory(fnh, 0)
 	nodeInformer := factory.Core().V1().Nodes()
@@ -495,7 +505,9 @@ func TestAddCloudNode(t *testing.T) {
 		fnh,
 		mockNP,
 		time.Second,
-		false)
+		false,
+		0,
+		0)
 	factory.Start(ctx.Done())
 	cache.WaitForCacheSync(ctx.Done(), nodeInformer.Informer().HasSynced)
 
@@ -554,7 +566,9 @@ func TestUpdateNodeAddresses(t *testing.T) {
 		fnh,
 		mockNP,
 		time.Second,
-		false)
+		false,
+		0,
+		0)
 	factory.Start(ctx.Done())
 	cache.WaitForCacheSync(ctx.Done(), nodeInformer.Informer().HasSynced)
 
@@ -574,6 +588,91 @@ func TestUpdateNodeAddresses(t *testing.T) {
 	assert.Equal(t, 2, len(updatedNodes[0].Status.Addresses), "Node Addresses not correctly updated")
 }
 
+func TestUpdateNodeStatusSkipsUnchangedNode(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fnh := &testutil.FakeNodeHandler{
+		Existing: []*v1.Node{
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "node0",
+					CreationTimestamp: metav1.Date(2012, 1, 1, 0, 0, 0, 0, time.UTC),
+					Labels:            map[string]string{},
+				},
+			},
+		},
+		Clientset:      fake.NewSimpleClientset(&v1.PodList{}),
+		DeleteWaitChan: make(chan struct{}),
+	}
+
+	mockNP := mocknodeprovider.NewMockNodeProvider(ctrl)
+	factory := informers.NewSharedInformerFactory(fnh, 0)
+	nodeInformer := factory.Core().V1().Nodes()
+
+	cloudNodeController := NewCloudNodeController(
+		"node0",
+		nodeInformer,
+		fnh,
+		mockNP,
+		time.Second,
+		false,
+		0,
+		3*time.Second)
+	factory.Start(ctx.Done())
+	cache.WaitForCacheSync(ctx.Done(), nodeInformer.Informer().HasSynced)
+	assert.Equal(t, 3, cloudNodeController.nodeForceSyncPeriods)
+	requestCount := fnh.RequestCount
+
+	internalIP := "10.0.0.1"
+	mockNP.EXPECT().InstanceID(gomock.Any(), types.NodeName("node0")).Return("node0", nil).AnyTimes()
+	mockNP.EXPECT().InstanceType(gomock.Any(), types.NodeName("node0")).Return("Standard_D2_v3", nil).AnyTimes()
+	mockNP.EXPECT().GetZone(gomock.Any(), types.NodeName("node0")).Return(cloudprovider.Zone{
+		Region:        "eastus",
+		FailureDomain: "eastus-1",
+	}, nil).AnyTimes()
+	mockNP.EXPECT().NodeAddresses(gomock.Any(), types.NodeName("node0")).DoAndReturn(func(context.Context, types.NodeName) ([]v1.NodeAddress, error) {
+		return []v1.NodeAddress{
+			{
+				Type:    v1.NodeHostName,
+				Address: "node0.cloud.internal",
+			},
+			{
+				Type:    v1.NodeInternalIP,
+				Address: internalIP,
+			},
+		}, nil
+	}).AnyTimes()
+
+	// The first sync patches the node addresses.
+	cloudNodeController.UpdateNodeStatus(ctx)
+	assert.Equal(t, requestCount+1, fnh.RequestCount)
+	assert.NotEmpty(t, cloudNodeController.lastNodeMetadataHash)
+
+	// No patch is issued when the metadata is stable.
+	cloudNodeController.UpdateNodeStatus(ctx)
+	assert.Equal(t, requestCount+1, fnh.RequestCount)
+	assert.Equal(t, 1, cloudNodeController.skippedNodeSyncs)
+
+	// The node addresses are patched when the address is changed.
+	internalIP = "10.0.0.2"
+	cloudNodeController.UpdateNodeStatus(ctx)
+	assert.Equal(t, requestCount+2, fnh.RequestCount)
+	assert.Equal(t, 0, cloudNodeController.skippedNodeSyncs)
+	updatedNodes := fnh.GetUpdatedNodesCopy()
+	assert.Equal(t, "10.0.0.2", updatedNodes[0].Status.Addresses[1].Address)
+
+	// The node status is synced after nodeForceSyncPeriods periods even if the metadata is stable.
+	cloudNodeController.UpdateNodeStatus(ctx)
+	cloudNodeController.UpdateNodeStatus(ctx)
+	assert.Equal(t, 2, cloudNodeController.skippedNodeSyncs)
+	assert.Equal(t, requestCount+2, fnh.RequestCount)
+	cloudNodeController.UpdateNodeStatus(ctx)
+	assert.Equal(t, 0, cloudNodeController.skippedNodeSyncs)
+}
+
 // This test checks that a node with the external cloud provider taint is cloudprovider initialized and
 // and the provided node ip is validated with the cloudprovider and nodeAddresses are updated from the cloudprovider
 func TestNodeProvidedIPAddresses(t *testing.T) {
@@ -647,6 +746,7 @@ func TestNodeProvidedIPAddresses(t *testing.T) {
 		},
 	}, nil).AnyTimes()
 	mockNP.EXPECT().GetPlatformSubFaultDomain().Return("", nil)
+	mockNP.EXPECT().GetExtendedLocation().Return("", nil)
 
 	eventBroadcaster := record.NewBroadcaster()
 	cloudNodeController := NewCloudNodeController(
@@ -655,7 +755,9 @@ func TestNodeProvidedIPAddresses(t *testing.T) {
 		fnh,
 		mockNP,
 		time.Second,
-		false)
+		false,
+		0,
+		0)
 	eventBroadcaster.StartLogging(klog.Infof)
 
 	cloudNodeController.AddCloudNode(context.TODO(), fnh.Existing[0])
@@ -785,6 +887,307 @@ func Test_reconcileNodeLabels(t *testing.T) {
 }
 
 // Tests that node address changes are detected correctly
+func TestNodeInitializedWithTopologyLabels(t *testing.T) {
+	testcases := []struct {
+		desc             string
+		providerID       string
+		extendedLocation string
+		expectedLabels   map[string]string
+		unexpectedLabels []string
+	}{
+		{
+			desc:             "edge zone VMSS instance should be labeled with the extended location, the VMSS and the agent pool",
+			providerID:       "azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/aks-agentpool-12345678-vmss/virtualMachines/0",
+			extendedLocation: "microsoftlosangeles1",
+			expectedLabels: map[string]string{
+				v1.LabelZoneRegionStable:     "eastus",
+				consts.LabelExtendedLocation: "microsoftlosangeles1",
+				consts.LabelVMSSName:         "aks-agentpool-12345678-vmss",
+				consts.LabelAgentPool:        "agentpool",
+			},
+		},
+		{
+			desc:       "regular standalone VM should be labeled without the extended location and the VMSS",
+			providerID: "azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/aks-nodepool1-12345678-0",
+			expectedLabels: map[string]string{
+				v1.LabelZoneRegionStable: "eastus",
+				consts.LabelAgentPool:    "nodepool1",
+			},
+			unexpectedLabels: []string{consts.LabelExtendedLocation, consts.LabelVMSSName},
+		},
+	}
+
+	for _, test := range testcases {
+		t.Run(test.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			fnh := &testutil.FakeNodeHandler{
+				Existing: []*v1.Node{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:              "node0",
+							CreationTimestamp: metav1.Date(2012, 1, 1, 0, 0, 0, 0, time.UTC),
+						},
+						Spec: v1.NodeSpec{
+							Taints: []v1.Taint{
+								{
+									Key:    cloudproviderapi.TaintExternalCloudProvider,
+									Value:  "true",
+									Effect: v1.TaintEffectNoSchedule,
+								},
+							},
+						},
+					},
+				},
+				Clientset:      fake.NewSimpleClientset(&v1.PodList{}),
+				DeleteWaitChan: make(chan struct{}),
+			}
+
+			ctx := context.TODO()
+			factory := informers.NewSharedInformerFactory(fnh, 0)
+			mockNP := mocknodeprovider.NewMockNodeProvider(ctrl)
+			mockNP.EXPECT().InstanceID(ctx, types.NodeName("node0")).Return(test.providerID, nil)
+			mockNP.EXPECT().InstanceType(ctx, types.NodeName("node0")).Return("Standard_D2_v3", nil)
+			mockNP.EXPECT().GetZone(ctx, gomock.Any()).Return(cloudprovider.Zone{Region: "eastus"}, nil)
+			mockNP.EXPECT().NodeAddresses(ctx, types.NodeName("node0")).Return([]v1.NodeAddress{
+				{
+					Type:    v1.NodeInternalIP,
+					Address: "10.0.0.1",
+				},
+			}, nil).AnyTimes()
+			mockNP.EXPECT().GetPlatformSubFaultDomain().Return("", nil)
+			mockNP.EXPECT().GetExtendedLocation().Return(test.extendedLocation, nil)
+
+			cloudNodeController := NewCloudNodeController(
+				"node0",
+				factory.Core().V1().Nodes(),
+				fnh,
+				mockNP,
+				time.Second,
+				false,
+				0,
+				0)
+
+			cloudNodeController.AddCloudNode(ctx, fnh.Existing[0])
+
+			assert.Equal(t, 1, len(fnh.UpdatedNodes), "Node was not updated")
+			for key, value := range test.expectedLabels {
+				assert.Equal(t, value, fnh.UpdatedNodes[0].Labels[key], "Node label %s not set correctly", key)
+			}
+			for _, key := range test.unexpectedLabels {
+				assert.NotContains(t, fnh.UpdatedNodes[0].Labels, key)
+			}
+		})
+	}
+}
+
+func Test_reconcileTopologyLabels(t *testing.T) {
+	providerID := "azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/aks-agentpool-12345678-vmss/virtualMachines/0"
+	testcases := []struct {
+		name           string
+		providerID     string
+		labels         map[string]string
+		expectGetZone  bool
+		expectedLabels map[string]string
+	}{
+		{
+			name:           "uninitialized node shouldn't be reconciled",
+			labels:         map[string]string{},
+			expectedLabels: map[string]string{},
+		},
+		{
+			name:          "missing labels should be added",
+			providerID:    providerID,
+			labels:        map[string]string{},
+			expectGetZone: true,
+			expectedLabels: map[string]string{
+				v1.LabelZoneRegion:           "eastus",
+				v1.LabelZoneRegionStable:     "eastus",
+				consts.LabelExtendedLocation: "microsoftlosangeles1",
+				consts.LabelVMSSName:         "aks-agentpool-12345678-vmss",
+				consts.LabelAgentPool:        "agentpool",
+			},
+		},
+		{
+			name:       "existing labels shouldn't be changed",
+			providerID: providerID,
+			labels: map[string]string{
+				v1.LabelZoneRegionStable: "eastus",
+				consts.LabelAgentPool:    "pool",
+			},
+			expectedLabels: map[string]string{
+				v1.LabelZoneRegionStable:     "eastus",
+				consts.LabelExtendedLocation: "microsoftlosangeles1",
+				consts.LabelVMSSName:         "aks-agentpool-12345678-vmss",
+				consts.LabelAgentPool:        "pool",
+			},
+		},
+	}
+
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			testNode := &v1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "node01",
+					Labels: test.labels,
+				},
+				Spec: v1.NodeSpec{
+					ProviderID: test.providerID,
+				},
+			}
+
+			clientset := fake.NewSimpleClientset(testNode)
+			mockNP := mocknodeprovider.NewMockNodeProvider(ctrl)
+			if test.providerID != "" {
+				mockNP.EXPECT().GetExtendedLocation().Return("microsoftlosangeles1", nil)
+			}
+			if test.expectGetZone {
+				mockNP.EXPECT().GetZone(gomock.Any(), types.NodeName("node01")).Return(cloudprovider.Zone{Region: "eastus"}, nil)
+			}
+
+			cnc := &CloudNodeController{
+				kubeClient:   clientset,
+				nodeProvider: mockNP,
+			}
+
+			err := cnc.reconcileTopologyLabels(context.TODO(), testNode)
+			assert.NoError(t, err)
+
+			actualNode, err := clientset.CoreV1().Nodes().Get(context.TODO(), "node01", metav1.GetOptions{})
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedLabels, actualNode.Labels)
+		})
+	}
+}
+
+func Test_reconcileShutdownTaint(t *testing.T) {
+	testcases := []struct {
+		name            string
+		taints          []v1.Taint
+		eventType       string
+		eventErr        error
+		expectedTainted bool
+	}{
+		{
+			name:            "node should be tainted if it is going to be preempted",
+			eventType:       "Preempt",
+			expectedTainted: true,
+		},
+		{
+			name:            "node should be tainted if it is going to be rebooted",
+			eventType:       "Reboot",
+			expectedTainted: true,
+		},
+		{
+			name:            "node should be tainted if it is going to be redeployed",
+			eventType:       "Redeploy",
+			expectedTainted: true,
+		},
+		{
+			name:            "tainted node should be kept tainted if the event is not completed",
+			taints:          []v1.Taint{*shutdownTaint},
+			eventType:       "Redeploy",
+			expectedTainted: true,
+		},
+		{
+			name:   "taint should be removed if the event is cancelled or completed",
+			taints: []v1.Taint{*shutdownTaint},
+		},
+		{
+			name: "node shouldn't be tainted if there is no scheduled events",
+		},
+		{
+			name:            "taint shouldn't be changed if failed to get the scheduled events",
+			taints:          []v1.Taint{*shutdownTaint},
+			eventErr:        errors.New("error"),
+			expectedTainted: true,
+		},
+	}
+
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			testNode := &v1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "node01",
+				},
+				Spec: v1.NodeSpec{
+					Taints: test.taints,
+				},
+			}
+
+			clientset := fake.NewSimpleClientset(testNode)
+			factory := informers.NewSharedInformerFactory(clientset, 0)
+			mockNP := mocknodeprovider.NewMockNodeProvider(ctrl)
+			mockNP.EXPECT().GetScheduledShutdownEvent().Return(test.eventType, test.eventErr)
+
+			cnc := &CloudNodeController{
+				nodeName:     "node01",
+				kubeClient:   clientset,
+				nodeInformer: factory.Core().V1().Nodes(),
+				nodeProvider: mockNP,
+				recorder:     record.NewFakeRecorder(10),
+			}
+
+			// activate node informer
+			factory.Core().V1().Nodes().Informer()
+			factory.Start(nil)
+			factory.WaitForCacheSync(nil)
+
+			cnc.reconcileShutdownTaint(context.TODO())
+
+			actualNode, err := clientset.CoreV1().Nodes().Get(context.TODO(), "node01", metav1.GetOptions{})
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedTainted, hasShutdownTaint(actualNode.Spec.Taints))
+		})
+	}
+}
+
+func Test_getVMSSNameAndAgentPool(t *testing.T) {
+	testcases := []struct {
+		providerID        string
+		expectedVMSSName  string
+		expectedAgentPool string
+	}{
+		{
+			providerID:        "azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/aks-agentpool-12345678-vmss/virtualMachines/0",
+			expectedVMSSName:  "aks-agentpool-12345678-vmss",
+			expectedAgentPool: "agentpool",
+		},
+		{
+			providerID:        "azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/k8s-pool1-12345678-vmss/virtualMachines/k8s-pool1-12345678-vmss_1",
+			expectedVMSSName:  "k8s-pool1-12345678-vmss",
+			expectedAgentPool: "pool1",
+		},
+		{
+			providerID:       "azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/my-vmss/virtualMachines/0",
+			expectedVMSSName: "my-vmss",
+		},
+		{
+			providerID:        "azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/aks-nodepool1-12345678-0",
+			expectedAgentPool: "nodepool1",
+		},
+		{
+			providerID: "azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/my-vm",
+		},
+		{
+			providerID: "",
+		},
+	}
+
+	for _, test := range testcases {
+		vmssName, agentPool := getVMSSNameAndAgentPool(test.providerID)
+		assert.Equal(t, test.expectedVMSSName, vmssName, test.providerID)
+		assert.Equal(t, test.expectedAgentPool, agentPool, test.providerID)
+	}
+}
+
 func TestNodeAddressesChangeDetected(t *testing.T) {
 	addressSet1 := []v1.NodeAddress{
 		{
@@ -1044,6 +1447,7 @@ func TestNodeProviderID(t *testing.T) {
 		},
 	}, nil).AnyTimes()
 	mockNP.EXPECT().GetPlatformSubFaultDomain().Return("", nil).AnyTimes()
+	mockNP.EXPECT().GetExtendedLocation().Return("", nil).AnyTimes()
 
 	eventBroadcaster := record.NewBroadcaster()
 	cloudNodeController := &CloudNodeController{
@@ -1130,6 +1534,7 @@ func TestNodeProviderIDAlreadySet(t *testing.T) {
 		},
 	}, nil).AnyTimes()
 	mockNP.EXPECT().GetPlatformSubFaultDomain().Return("", nil).AnyTimes()
+	mockNP.EXPECT().GetExtendedLocation().Return("", nil).AnyTimes()
 
 	eventBroadcaster := record.NewBroadcaster()
 	cloudNodeController := &CloudNodeController{
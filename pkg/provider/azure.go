@@ -17,12 +17,13 @@ limitations under the License.
 package provider
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
-	"os"
 	"strings"
 	"sync"
 	"time"
@@ -214,6 +215,8 @@ type Config struct {
 	CloudProviderBackoffRetries int `json:"cloudProviderBackoffRetries,omitempty" yaml:"cloudProviderBackoffRetries,omitempty"`
 	// Backoff duration
 	CloudProviderBackoffDuration int `json:"cloudProviderBackoffDuration,omitempty" yaml:"cloudProviderBackoffDuration,omitempty"`
+	// Backoff cap, the maximum interval in seconds between the retries. The intervals are not limited if not set.
+	CloudProviderBackoffCap int `json:"cloudProviderBackoffCap,omitempty" yaml:"cloudProviderBackoffCap,omitempty"`
 	// AvailabilitySetNodesCacheTTLInSeconds sets the Cache TTL for availabilitySetNodesCache
 	// if not set, will use default value
 	AvailabilitySetNodesCacheTTLInSeconds int `json:"availabilitySetNodesCacheTTLInSeconds,omitempty" yaml:"availabilitySetNodesCacheTTLInSeconds,omitempty"`
@@ -235,6 +238,9 @@ type Config struct {
 	AvailabilitySetsCacheTTLInSeconds int `json:"availabilitySetsCacheTTLInSeconds,omitempty" yaml:"availabilitySetsCacheTTLInSeconds,omitempty"`
 	// PublicIPCacheTTLInSeconds sets the cache TTL for public ip
 	PublicIPCacheTTLInSeconds int `json:"publicIPCacheTTLInSeconds,omitempty" yaml:"publicIPCacheTTLInSeconds,omitempty"`
+	// CacheTTLs maps the cache names to the cache TTLs in seconds, which take precedence over the TTLs
+	// configured above. The caching is disabled if the TTL is 0. Supported cache names are listed by ListCacheNames.
+	CacheTTLs map[string]int `json:"cacheTTLs,omitempty" yaml:"cacheTTLs,omitempty"`
 	// RouteUpdateWaitingInSeconds is the delay time for waiting route updates to take effect. This waiting delay is added
 	// because the routes are not taken effect when the async route updating operation returns success. Default is 30 seconds.
 	RouteUpdateWaitingInSeconds int `json:"routeUpdateWaitingInSeconds,omitempty" yaml:"routeUpdateWaitingInSeconds,omitempty"`
@@ -249,6 +255,37 @@ type Config struct {
 	// PutVMSSVMBatchSize defines how many requests the client send concurrently when putting the VMSS VMs.
 	// If it is smaller than or equal to zero, the request will be sent one by one in sequence (default).
 	PutVMSSVMBatchSize int `json:"putVMSSVMBatchSize" yaml:"putVMSSVMBatchSize"`
+	// EnsureHostsInPoolConcurrency defines how many scale sets are updated concurrently when ensuring the hosts
+	// in the load balancer backend pool. If it is smaller than or equal to zero, the default value 4 will be used.
+	EnsureHostsInPoolConcurrency int `json:"ensureHostsInPoolConcurrency,omitempty" yaml:"ensureHostsInPoolConcurrency,omitempty"`
+	// ForceHTTP1 disables HTTP/2 for the requests sent to ARM, which could be stalled by some proxies or firewalls.
+	ForceHTTP1 bool `json:"forceHTTP1,omitempty" yaml:"forceHTTP1,omitempty"`
+	// ArmRateLimitRemainingWarningThreshold is the number of the remaining ARM requests reported in the
+	// x-ms-ratelimit-remaining headers below which warnings are logged. Default is 100.
+	ArmRateLimitRemainingWarningThreshold int `json:"armRateLimitRemainingWarningThreshold,omitempty" yaml:"armRateLimitRemainingWarningThreshold,omitempty"`
+	// SlowDownWritesOnLowArmRateLimitRemaining delays the write requests to ARM while the remaining ARM writes are
+	// below ArmRateLimitRemainingWarningThreshold, so that the subscription is less likely to be throttled.
+	SlowDownWritesOnLowArmRateLimitRemaining bool `json:"slowDownWritesOnLowArmRateLimitRemaining,omitempty" yaml:"slowDownWritesOnLowArmRateLimitRemaining,omitempty"`
+	// DefaultDiskEncryptionSetID is the disk encryption set used to encrypt the managed disks with customer-managed keys
+	// if DiskEncryptionSetID is not specified in the disk options.
+	DefaultDiskEncryptionSetID string `json:"defaultDiskEncryptionSetID,omitempty" yaml:"defaultDiskEncryptionSetID,omitempty"`
+	// DefaultDiskNetworkAccessPolicy is the network access policy of the managed disks if NetworkAccessPolicy is not
+	// specified in the disk options, possible values are AllowAll, AllowPrivate and DenyAll.
+	DefaultDiskNetworkAccessPolicy string `json:"defaultDiskNetworkAccessPolicy,omitempty" yaml:"defaultDiskNetworkAccessPolicy,omitempty"`
+	// DefaultDiskAccessID is the disk access resource ID used with the AllowPrivate DefaultDiskNetworkAccessPolicy.
+	DefaultDiskAccessID string `json:"defaultDiskAccessID,omitempty" yaml:"defaultDiskAccessID,omitempty"`
+	// EnforceDiskNetworkAccessPolicy updates the network access policy of a created disk if it differs from the
+	// requested one. By default, the difference is only logged.
+	EnforceDiskNetworkAccessPolicy bool `json:"enforceDiskNetworkAccessPolicy,omitempty" yaml:"enforceDiskNetworkAccessPolicy,omitempty"`
+	// DiskLunStartIndex is the lowest LUN assigned to the attached data disks. The LUNs below it are reserved,
+	// e.g. by the data disks of the VM image. Default is 0.
+	DiskLunStartIndex int32 `json:"diskLunStartIndex,omitempty" yaml:"diskLunStartIndex,omitempty"`
+	// DiskOpBatchWindowInMilliseconds is the duration to wait for the concurrent attach or detach requests on a node
+	// to be coalesced into one VM update. 0 disables the wait. Default is 200 milliseconds.
+	DiskOpBatchWindowInMilliseconds *int `json:"diskOpBatchWindowInMilliseconds,omitempty" yaml:"diskOpBatchWindowInMilliseconds,omitempty"`
+	// PrimaryIPFamily is the IP family of the node addresses reported first on dual-stack clusters,
+	// possible values are IPv4 and IPv6. Default is IPv4.
+	PrimaryIPFamily string `json:"primaryIPFamily,omitempty" yaml:"primaryIPFamily,omitempty"`
 	// PrivateLinkServiceResourceGroup determines the specific resource group of the private link services user want to use
 	PrivateLinkServiceResourceGroup string `json:"privateLinkServiceResourceGroup,omitempty" yaml:"privateLinkServiceResourceGroup,omitempty"`
 }
@@ -312,6 +349,11 @@ type Cloud struct {
 
 	// ipv6DualStack allows overriding for unit testing.  It's normally initialized from featuregates
 	ipv6DualStackEnabled bool
+	// the IPv6 addresses of the local instance last got from IMDS, which are kept for a grace period
+	// in case IMDS omits them momentarily
+	localIPv6Addresses     []v1.NodeAddress
+	localIPv6AddressesTime time.Time
+	localIPv6AddressesLock sync.Mutex
 	// isSHaredLoadBalancerSynced indicates if the reconcileSharedLoadBalancer has been run
 	isSharedLoadBalancerSynced bool
 	// Lock for access to node caches, includes nodeZones, nodeResourceGroups, and unmanagedNodes.
@@ -323,6 +365,9 @@ type Cloud struct {
 	nodeZones map[string]sets.String
 	// nodeResourceGroups holds nodes external resource groups
 	nodeResourceGroups map[string]string
+	// nodeProviderIDs holds the providerIDs of nodes, which are used to discover
+	// the subscriptions and resource groups of the node pools.
+	nodeProviderIDs map[string]string
 	// unmanagedNodes holds a list of nodes not managed by Azure cloud provider.
 	unmanagedNodes sets.String
 	// excludeLoadBalancerNodes holds a list of nodes that should be excluded from LoadBalancer.
@@ -340,6 +385,11 @@ type Cloud struct {
 	regionZonesMap   map[string][]string
 	refreshZonesLock sync.RWMutex
 
+	// backoffLock guards ResourceRequestBackoff and the backoff configs, which could be reloaded at runtime.
+	backoffLock sync.RWMutex
+	// rateLimitLock guards CloudProviderRateLimitConfig, which could be reloaded at runtime.
+	rateLimitLock sync.RWMutex
+
 	KubeClient       clientset.Interface
 	eventBroadcaster record.EventBroadcaster
 	eventRecorder    record.EventRecorder
@@ -353,6 +403,20 @@ type Cloud struct {
 	// use LB frontEndIpConfiguration ID as the key and search for PLS attached to the frontEnd
 	plsCache *azcache.TimedCache
 
+	// invalidationRegistry invalidates the caches after the resources are mutated by the Azure clients.
+	invalidationRegistry *azclients.InvalidationRegistry
+
+	// authorizer, multiTenantAuthorizer and networkResourceAuthorizer are shared by the Azure clients
+	// and swapped when the credentials in the cloud config file are rotated.
+	authorizer                *reloadableAuthorizer
+	multiTenantAuthorizer     *reloadableAuthorizer
+	networkResourceAuthorizer *reloadableAuthorizer
+	configFileReloader        *configFileReloader
+	// tokenRefreshers refresh the tokens of the authorizers proactively.
+	tokenRefreshers []*auth.TokenRefresher
+	// tokenRefreshersLock guards tokenRefreshers, which are replaced when the credentials are reloaded.
+	tokenRefreshersLock sync.RWMutex
+
 	*ManagedDiskController
 	*controllerCommon
 }
@@ -391,15 +455,18 @@ func NewCloudFromConfigFile(configFilePath string, calFromCCM bool) (cloudprovid
 	)
 
 	if configFilePath != "" {
-		var config *os.File
-		config, err = os.Open(configFilePath)
+		var config []byte
+		config, err = ioutil.ReadFile(configFilePath)
 		if err != nil {
 			klog.Fatalf("Couldn't open cloud provider configuration %s: %#v",
 				configFilePath, err)
 		}
 
-		defer config.Close()
-		cloud, err = NewCloud(config, calFromCCM)
+		cloud, err = NewCloud(bytes.NewReader(config), calFromCCM)
+		if err == nil && calFromCCM {
+			// reload the rotated credentials from the mounted cloud config file without restarts
+			err = cloud.(*Cloud).startConfigFileReloader(configFilePath, config, configFileReloadInterval, wait.NeverStop)
+		}
 	} else {
 		// Pass explicit nil so plugins can actually check for nil. See
 		// "Why is my nil error value not equal to nil?" in golang.org/doc/faq.
@@ -439,6 +506,7 @@ func NewCloudFromSecret(clientBuilder cloudprovider.ControllerClientBuilder, sec
 		nodeNames:                sets.NewString(),
 		nodeZones:                map[string]sets.String{},
 		nodeResourceGroups:       map[string]string{},
+		nodeProviderIDs:          map[string]string{},
 		unmanagedNodes:           sets.NewString(),
 		routeCIDRs:               map[string]string{},
 		excludeLoadBalancerNodes: sets.NewString(),
@@ -471,6 +539,7 @@ func NewCloudWithoutFeatureGates(configReader io.Reader, callFromCCM bool) (*Clo
 		nodeNames:                sets.NewString(),
 		nodeZones:                map[string]sets.String{},
 		nodeResourceGroups:       map[string]string{},
+		nodeProviderIDs:          map[string]string{},
 		unmanagedNodes:           sets.NewString(),
 		routeCIDRs:               map[string]string{},
 		excludeLoadBalancerNodes: sets.NewString(),
@@ -504,6 +573,10 @@ func (az *Cloud) InitializeCloudFromConfig(config *Config, fromSecret, callFromC
 		config.PrivateLinkServiceResourceGroup = config.ResourceGroup
 	}
 
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
 	if config.VMType == "" {
 		// default to standard vmType if not set.
 		config.VMType = consts.VMTypeStandard
@@ -513,38 +586,18 @@ func (az *Cloud) InitializeCloudFromConfig(config *Config, fromSecret, callFromC
 		config.RouteUpdateWaitingInSeconds = defaultRouteUpdateWaitingInSeconds
 	}
 
-	if config.DisableAvailabilitySetNodes && config.VMType != consts.VMTypeVMSS {
-		return fmt.Errorf("disableAvailabilitySetNodes %v is only supported when vmType is 'vmss'", config.DisableAvailabilitySetNodes)
-	}
-
 	if config.CloudConfigType == "" {
 		// The default cloud config type is cloudConfigTypeMerge.
 		config.CloudConfigType = cloudConfigTypeMerge
-	} else {
-		supportedCloudConfigTypes := sets.NewString(
-			string(cloudConfigTypeMerge),
-			string(cloudConfigTypeFile),
-			string(cloudConfigTypeSecret))
-		if !supportedCloudConfigTypes.Has(string(config.CloudConfigType)) {
-			return fmt.Errorf("cloudConfigType %v is not supported, supported values are %v", config.CloudConfigType, supportedCloudConfigTypes.List())
-		}
 	}
 
 	if config.LoadBalancerBackendPoolConfigurationType == "" ||
 		// TODO(nilo19): support pod IP mode in the future
 		strings.EqualFold(config.LoadBalancerBackendPoolConfigurationType, consts.LoadBalancerBackendPoolConfigurationTypePODIP) {
 		config.LoadBalancerBackendPoolConfigurationType = consts.LoadBalancerBackendPoolConfigurationTypeNodeIPConfiguration
-	} else {
-		supportedLoadBalancerBackendPoolConfigurationTypes := sets.NewString(
-			strings.ToLower(consts.LoadBalancerBackendPoolConfigurationTypeNodeIPConfiguration),
-			strings.ToLower(consts.LoadBalancerBackendPoolConfigurationTypeNodeIP),
-			strings.ToLower(consts.LoadBalancerBackendPoolConfigurationTypePODIP))
-		if !supportedLoadBalancerBackendPoolConfigurationTypes.Has(strings.ToLower(config.LoadBalancerBackendPoolConfigurationType)) {
-			return fmt.Errorf("loadBalancerBackendPoolConfigurationType %s is not supported, supported values are %v", config.LoadBalancerBackendPoolConfigurationType, supportedLoadBalancerBackendPoolConfigurationTypes.List())
-		}
 	}
 
-	env, err := auth.ParseAzureEnvironment(config.Cloud, config.ResourceManagerEndpoint, config.IdentitySystem)
+	env, err := auth.GetAzureEnvironment(&config.AzureAuthConfig)
 	if err != nil {
 		return err
 	}
@@ -600,6 +653,15 @@ func (az *Cloud) InitializeCloudFromConfig(config *Config, fromSecret, callFromC
 		return err
 	}
 
+	// The misconfigured managed identity is reported at startup instead of the 403 errors of the later requests
+	if config.UseManagedIdentityExtension && callFromCCM {
+		go func() {
+			if err := az.validateManagedIdentity(context.Background(), servicePrincipalToken); err != nil {
+				klog.Warningf("InitializeCloudFromConfig: %v", err)
+			}
+		}()
+	}
+
 	if az.MaximumLoadBalancerRuleCount == 0 {
 		az.MaximumLoadBalancerRuleCount = consts.MaximumLoadBalancerRuleCount
 	}
@@ -666,6 +728,13 @@ func (az *Cloud) getPutVMSSVMBatchSize() int {
 	return az.PutVMSSVMBatchSize
 }
 
+func (az *Cloud) getEnsureHostsInPoolConcurrency() int {
+	if az.EnsureHostsInPoolConcurrency <= 0 {
+		return consts.EnsureHostsInPoolConcurrencyDefault
+	}
+	return az.EnsureHostsInPoolConcurrency
+}
+
 func (az *Cloud) initCaches() (err error) {
 	az.vmCache, err = az.newVMCache()
 	if err != nil {
@@ -697,6 +766,7 @@ func (az *Cloud) initCaches() (err error) {
 		return err
 	}
 
+	az.registerCacheInvalidators()
 	return nil
 }
 
@@ -735,9 +805,42 @@ func (az *Cloud) configureMultiTenantClients(servicePrincipalToken *adal.Service
 	}
 
 	az.configAzureClients(servicePrincipalToken, multiTenantServicePrincipalToken, networkResourceServicePrincipalToken)
+	az.startTokenRefreshers(servicePrincipalToken, multiTenantServicePrincipalToken, networkResourceServicePrincipalToken)
 	return nil
 }
 
+// startTokenRefreshers starts refreshing the tokens proactively in the background,
+// and stops refreshing the previous ones, e.g. the tokens before the credentials are rotated.
+func (az *Cloud) startTokenRefreshers(
+	servicePrincipalToken *adal.ServicePrincipalToken,
+	multiTenantServicePrincipalToken *adal.MultiTenantServicePrincipalToken,
+	networkResourceServicePrincipalToken *adal.ServicePrincipalToken) {
+	az.tokenRefreshersLock.Lock()
+	defer az.tokenRefreshersLock.Unlock()
+	for _, refresher := range az.tokenRefreshers {
+		refresher.Stop()
+	}
+
+	refreshers := []*auth.TokenRefresher{auth.StartTokenRefresher("primary", servicePrincipalToken)}
+	if multiTenantServicePrincipalToken != nil {
+		refreshers = append(refreshers, auth.StartTokenRefresher("multi_tenant_primary", multiTenantServicePrincipalToken.PrimaryToken))
+		for _, auxiliaryToken := range multiTenantServicePrincipalToken.AuxiliaryTokens {
+			refreshers = append(refreshers, auth.StartTokenRefresher("multi_tenant_auxiliary", auxiliaryToken))
+		}
+	}
+	if networkResourceServicePrincipalToken != nil {
+		refreshers = append(refreshers, auth.StartTokenRefresher("network_resource", networkResourceServicePrincipalToken))
+	}
+	az.tokenRefreshers = refreshers
+}
+
+// getTokenRefreshers returns a snapshot of the current token refreshers.
+func (az *Cloud) getTokenRefreshers() []*auth.TokenRefresher {
+	az.tokenRefreshersLock.RLock()
+	defer az.tokenRefreshersLock.RUnlock()
+	return append([]*auth.TokenRefresher{}, az.tokenRefreshers...)
+}
+
 func (az *Cloud) setCloudProviderBackoffDefaults(config *Config) wait.Backoff {
 	// Conditionally configure resource request backoff
 	resourceRequestBackoff := wait.Backoff{
@@ -783,6 +886,16 @@ func (az *Cloud) configAzureClients(
 	multiTenantServicePrincipalToken *adal.MultiTenantServicePrincipalToken,
 	networkResourceServicePrincipalToken *adal.ServicePrincipalToken) {
 	azClientConfig := az.getAzureClientConfig(servicePrincipalToken)
+	az.invalidationRegistry = azclients.NewInvalidationRegistry()
+	azClientConfig.InvalidationRegistry = az.invalidationRegistry
+	az.authorizer = newReloadableAuthorizer(azClientConfig.Authorizer)
+	azClientConfig.Authorizer = az.authorizer
+	if multiTenantServicePrincipalToken != nil {
+		az.multiTenantAuthorizer = newReloadableAuthorizer(autorest.NewMultiTenantServicePrincipalTokenAuthorizer(multiTenantServicePrincipalToken))
+		// The requests to the network resources in another AAD Tenant carry the auxiliary token of that tenant
+		azClientConfig.MultiTenantAuthorizer = az.multiTenantAuthorizer
+		azClientConfig.NetworkResourceSubscriptionID = az.Config.NetworkResourceSubscriptionID
+	}
 
 	// Prepare AzureClientConfig for all azure clients
 	interfaceClientConfig := azClientConfig.WithRateLimiter(az.Config.InterfaceRateLimit)
@@ -817,7 +930,7 @@ func (az *Cloud) configAzureClients(
 
 	// If uses network resources in different AAD Tenant, update Authorizer for VM/VMSS/VMAS client config
 	if multiTenantServicePrincipalToken != nil {
-		multiTenantServicePrincipalTokenAuthorizer := autorest.NewMultiTenantServicePrincipalTokenAuthorizer(multiTenantServicePrincipalToken)
+		multiTenantServicePrincipalTokenAuthorizer := az.multiTenantAuthorizer
 		vmClientConfig.Authorizer = multiTenantServicePrincipalTokenAuthorizer
 		vmssClientConfig.Authorizer = multiTenantServicePrincipalTokenAuthorizer
 		vmssVMClientConfig.Authorizer = multiTenantServicePrincipalTokenAuthorizer
@@ -826,7 +939,8 @@ func (az *Cloud) configAzureClients(
 
 	// If uses network resources in different AAD Tenant, update SubscriptionID and Authorizer for network resources client config
 	if networkResourceServicePrincipalToken != nil {
-		networkResourceServicePrincipalTokenAuthorizer := autorest.NewBearerAuthorizer(networkResourceServicePrincipalToken)
+		az.networkResourceAuthorizer = newReloadableAuthorizer(autorest.NewBearerAuthorizer(networkResourceServicePrincipalToken))
+		networkResourceServicePrincipalTokenAuthorizer := az.networkResourceAuthorizer
 		routeClientConfig.Authorizer = networkResourceServicePrincipalTokenAuthorizer
 		subnetClientConfig.Authorizer = networkResourceServicePrincipalTokenAuthorizer
 		routeTableClientConfig.Authorizer = networkResourceServicePrincipalTokenAuthorizer
@@ -883,15 +997,15 @@ func (az *Cloud) getAzureClientConfig(servicePrincipalToken *adal.ServicePrincip
 		Backoff:                 &retry.Backoff{Steps: 1},
 		DisableAzureStackCloud:  az.Config.DisableAzureStackCloud,
 		UserAgent:               az.Config.UserAgent,
+		ForceHTTP1:              az.Config.ForceHTTP1,
+
+		RateLimitBudget:                       azclients.NewRateLimitBudget(),
+		RateLimitRemainingWarningThreshold:    az.Config.ArmRateLimitRemainingWarningThreshold,
+		SlowDownWritesOnLowRateLimitRemaining: az.Config.SlowDownWritesOnLowArmRateLimitRemaining,
 	}
 
 	if az.Config.CloudProviderBackoff {
-		azClientConfig.Backoff = &retry.Backoff{
-			Steps:    az.Config.CloudProviderBackoffRetries,
-			Factor:   az.Config.CloudProviderBackoffExponent,
-			Duration: time.Duration(az.Config.CloudProviderBackoffDuration) * time.Second,
-			Jitter:   az.Config.CloudProviderBackoffJitter,
-		}
+		azClientConfig.Backoff = newClientBackoff(&az.Config)
 	}
 
 	if az.Config.HasExtendedLocation() {
@@ -904,6 +1018,17 @@ func (az *Cloud) getAzureClientConfig(servicePrincipalToken *adal.ServicePrincip
 	return azClientConfig
 }
 
+// newClientBackoff returns the backoff of the Azure clients from the backoff configs.
+func newClientBackoff(config *Config) *retry.Backoff {
+	return &retry.Backoff{
+		Steps:    config.CloudProviderBackoffRetries,
+		Factor:   config.CloudProviderBackoffExponent,
+		Duration: time.Duration(config.CloudProviderBackoffDuration) * time.Second,
+		Jitter:   config.CloudProviderBackoffJitter,
+		Cap:      time.Duration(config.CloudProviderBackoffCap) * time.Second,
+	}
+}
+
 // ParseConfig returns a parsed configuration for an Azure cloudprovider config file
 func ParseConfig(configReader io.Reader) (*Config, error) {
 	var config Config
@@ -984,17 +1109,21 @@ func (az *Cloud) ProviderName() string {
 	return consts.CloudProviderName
 }
 
-func initDiskControllers(az *Cloud) error {
-	// Common controller contains the function
-	// needed by both blob disk and managed disk controllers
-
+// newDiskOpRateLimiter creates the rate limiter of the attach/detach disk operations from the rate limit configs.
+func newDiskOpRateLimiter(config *CloudProviderRateLimitConfig) flowcontrol.RateLimiter {
 	qps := float32(defaultAtachDetachDiskQPS)
 	bucket := defaultAtachDetachDiskBucket
-	if az.Config.AttachDetachDiskRateLimit != nil {
-		qps = az.Config.AttachDetachDiskRateLimit.CloudProviderRateLimitQPSWrite
-		bucket = az.Config.AttachDetachDiskRateLimit.CloudProviderRateLimitBucketWrite
+	if config.AttachDetachDiskRateLimit != nil {
+		qps = config.AttachDetachDiskRateLimit.CloudProviderRateLimitQPSWrite
+		bucket = config.AttachDetachDiskRateLimit.CloudProviderRateLimitBucketWrite
 	}
 	klog.V(2).Infof("attach/detach disk operation rate limit QPS: %f, Bucket: %d", qps, bucket)
+	return flowcontrol.NewTokenBucketRateLimiter(qps, bucket)
+}
+
+func initDiskControllers(az *Cloud) error {
+	// Common controller contains the function
+	// needed by both blob disk and managed disk controllers
 
 	common := &controllerCommon{
 		location:              az.Location,
@@ -1003,7 +1132,11 @@ func initDiskControllers(az *Cloud) error {
 		subscriptionID:        az.SubscriptionID,
 		cloud:                 az,
 		lockMap:               newLockMap(),
-		diskOpRateLimiter:     flowcontrol.NewTokenBucketRateLimiter(qps, bucket),
+		diskOpRateLimiter:     azclients.NewReloadableRateLimiter(newDiskOpRateLimiter(&az.Config.CloudProviderRateLimitConfig)),
+		diskOpBatchWindow:     defaultDiskOpBatchWindow,
+	}
+	if az.DiskOpBatchWindowInMilliseconds != nil {
+		common.diskOpBatchWindow = time.Duration(*az.DiskOpBatchWindowInMilliseconds) * time.Millisecond
 	}
 
 	if az.HasExtendedLocation() {
@@ -1079,6 +1212,9 @@ func (az *Cloud) updateNodeCaches(prevNode, newNode *v1.Node) {
 			delete(az.nodeResourceGroups, prevNode.ObjectMeta.Name)
 		}
 
+		// Remove from nodeProviderIDs cache.
+		delete(az.nodeProviderIDs, prevNode.ObjectMeta.Name)
+
 		managed, ok := prevNode.ObjectMeta.Labels[consts.ManagedByAzureLabel]
 		isNodeManagedByCloudProvider := !ok || !strings.EqualFold(managed, consts.NotManagedByAzureLabelValue)
 
@@ -1121,6 +1257,11 @@ func (az *Cloud) updateNodeCaches(prevNode, newNode *v1.Node) {
 			az.nodeResourceGroups[newNode.ObjectMeta.Name] = strings.ToLower(newRG)
 		}
 
+		// Add to nodeProviderIDs cache.
+		if newNode.Spec.ProviderID != "" {
+			az.nodeProviderIDs[newNode.ObjectMeta.Name] = newNode.Spec.ProviderID
+		}
+
 		_, hasExcludeBalancerLabel := newNode.ObjectMeta.Labels[v1.LabelNodeExcludeBalancers]
 		managed, ok := newNode.ObjectMeta.Labels[consts.ManagedByAzureLabel]
 		isNodeManagedByCloudProvider := !ok || !strings.EqualFold(managed, consts.NotManagedByAzureLabelValue)
@@ -1249,6 +1390,43 @@ func (az *Cloud) GetResourceGroups() (sets.String, error) {
 	return resourceGroups, nil
 }
 
+// GetSubscriptionResourceGroups returns the resource groups that all nodes are running on, grouped by
+// (lower-cased) subscription ID. Besides the configured subscription and resource groups, the subscriptions
+// and resource groups of the node pools are discovered from the nodes' providerIDs.
+func (az *Cloud) GetSubscriptionResourceGroups() (map[string]sets.String, error) {
+	resourceGroups, err := az.GetResourceGroups()
+	if err != nil {
+		return nil, err
+	}
+	subscriptionResourceGroups := map[string]sets.String{
+		strings.ToLower(az.SubscriptionID): sets.NewString(),
+	}
+	for _, resourceGroup := range resourceGroups.List() {
+		subscriptionResourceGroups[strings.ToLower(az.SubscriptionID)].Insert(strings.ToLower(resourceGroup))
+	}
+
+	// Kubelet won't set az.nodeInformerSynced, always return configured subscription and resourceGroup.
+	if az.nodeInformerSynced == nil {
+		return subscriptionResourceGroups, nil
+	}
+
+	az.nodeCachesLock.RLock()
+	defer az.nodeCachesLock.RUnlock()
+	for _, providerID := range az.nodeProviderIDs {
+		subscriptionID, resourceGroup, err := extractSubscriptionAndResourceGroupByProviderID(providerID)
+		if err != nil {
+			continue
+		}
+
+		if subscriptionResourceGroups[subscriptionID] == nil {
+			subscriptionResourceGroups[subscriptionID] = sets.NewString()
+		}
+		subscriptionResourceGroups[subscriptionID].Insert(resourceGroup)
+	}
+
+	return subscriptionResourceGroups, nil
+}
+
 // GetUnmanagedNodes returns a list of nodes not managed by Azure cloud provider (e.g. on-prem nodes).
 func (az *Cloud) GetUnmanagedNodes() (sets.String, error) {
 	// Kubelet won't set az.nodeInformerSynced, always return nil.
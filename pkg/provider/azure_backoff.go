@@ -17,6 +17,7 @@ limitations under the License.
 package provider
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -50,6 +51,8 @@ var (
 // This is to make sure that the requested command executes
 // at least once
 func (az *Cloud) RequestBackoff() (resourceRequestBackoff wait.Backoff) {
+	az.backoffLock.RLock()
+	defer az.backoffLock.RUnlock()
 	if az.CloudProviderBackoff {
 		return az.ResourceRequestBackoff
 	}
@@ -148,15 +151,11 @@ func (az *Cloud) GetIPForMachineWithRetry(name types.NodeName) (string, string,
 }
 
 // CreateOrUpdateSecurityGroup invokes az.SecurityGroupsClient.CreateOrUpdate with exponential backoff retry
-func (az *Cloud) CreateOrUpdateSecurityGroup(sg network.SecurityGroup) error {
-	ctx, cancel := getContextWithCancel()
-	defer cancel()
-
+func (az *Cloud) CreateOrUpdateSecurityGroup(ctx context.Context, sg network.SecurityGroup) error {
 	rerr := az.SecurityGroupsClient.CreateOrUpdate(ctx, az.SecurityGroupResourceGroup, *sg.Name, sg, to.String(sg.Etag))
 	klog.V(10).Infof("SecurityGroupsClient.CreateOrUpdate(%s): end", *sg.Name)
 	if rerr == nil {
-		// Invalidate the cache right after updating
-		_ = az.nsgCache.Delete(*sg.Name)
+		// The cache is invalidated by the client right after updating.
 		return nil
 	}
 
@@ -206,18 +205,14 @@ func cleanupSubnetInFrontendIPConfigurations(lb *network.LoadBalancer) network.L
 }
 
 // CreateOrUpdateLB invokes az.LoadBalancerClient.CreateOrUpdate with exponential backoff retry
-func (az *Cloud) CreateOrUpdateLB(service *v1.Service, lb network.LoadBalancer) error {
-	ctx, cancel := getContextWithCancel()
-	defer cancel()
-
+func (az *Cloud) CreateOrUpdateLB(ctx context.Context, service *v1.Service, lb network.LoadBalancer) error {
 	lb = cleanupSubnetInFrontendIPConfigurations(&lb)
 
 	rgName := az.getLoadBalancerResourceGroup()
 	rerr := az.LoadBalancerClient.CreateOrUpdate(ctx, rgName, to.String(lb.Name), lb, to.String(lb.Etag))
 	klog.V(10).Infof("LoadBalancerClient.CreateOrUpdate(%s): end", *lb.Name)
 	if rerr == nil {
-		// Invalidate the cache right after updating
-		_ = az.lbCache.Delete(*lb.Name)
+		// The cache is invalidated by the client right after updating.
 		return nil
 	}
 
@@ -252,7 +247,7 @@ func (az *Cloud) CreateOrUpdateLB(service *v1.Service, lb network.LoadBalancer)
 			return rerr.Error()
 		}
 		// Perform a dummy update to fix the provisioning state
-		err = az.CreateOrUpdatePIP(service, pipRG, pip)
+		err = az.CreateOrUpdatePIP(ctx, service, pipRG, pip)
 		if err != nil {
 			klog.Errorf("Failed to update the public IP %s in resource group %s: %v", pipName, pipRG, err)
 			return rerr.Error()
@@ -265,15 +260,11 @@ func (az *Cloud) CreateOrUpdateLB(service *v1.Service, lb network.LoadBalancer)
 	return rerr.Error()
 }
 
-func (az *Cloud) CreateOrUpdateLBBackendPool(lbName string, backendPool network.BackendAddressPool) error {
-	ctx, cancel := getContextWithCancel()
-	defer cancel()
-
+func (az *Cloud) CreateOrUpdateLBBackendPool(ctx context.Context, lbName string, backendPool network.BackendAddressPool) error {
 	klog.V(4).Infof("CreateOrUpdateLBBackendPool: updating backend pool %s in LB %s", to.String(backendPool.Name), lbName)
 	rerr := az.LoadBalancerClient.CreateOrUpdateBackendPools(ctx, az.getLoadBalancerResourceGroup(), lbName, to.String(backendPool.Name), backendPool, to.String(backendPool.Etag))
 	if rerr == nil {
-		// Invalidate the cache right after updating
-		_ = az.lbCache.Delete(lbName)
+		// The cache is invalidated by the client right after updating.
 		return nil
 	}
 
@@ -293,15 +284,11 @@ func (az *Cloud) CreateOrUpdateLBBackendPool(lbName string, backendPool network.
 	return rerr.Error()
 }
 
-func (az *Cloud) DeleteLBBackendPool(lbName, backendPoolName string) error {
-	ctx, cancel := getContextWithCancel()
-	defer cancel()
-
+func (az *Cloud) DeleteLBBackendPool(ctx context.Context, lbName, backendPoolName string) error {
 	klog.V(4).Infof("DeleteLBBackendPool: deleting backend pool %s in LB %s", backendPoolName, lbName)
 	rerr := az.LoadBalancerClient.DeleteLBBackendPool(ctx, az.getLoadBalancerResourceGroup(), lbName, backendPoolName)
 	if rerr == nil {
-		// Invalidate the cache right after updating
-		_ = az.lbCache.Delete(lbName)
+		// The cache is invalidated by the client right after updating.
 		return nil
 	}
 
@@ -323,8 +310,8 @@ func (az *Cloud) DeleteLBBackendPool(lbName, backendPoolName string) error {
 
 // ListManagedLBs invokes az.LoadBalancerClient.List and filter out
 // those that are not managed by cloud provider azure or not associated to a managed VMSet.
-func (az *Cloud) ListManagedLBs(service *v1.Service, nodes []*v1.Node, clusterName string) ([]network.LoadBalancer, error) {
-	allLBs, err := az.ListLB(service)
+func (az *Cloud) ListManagedLBs(ctx context.Context, service *v1.Service, nodes []*v1.Node, clusterName string) ([]network.LoadBalancer, error) {
+	allLBs, err := az.ListLB(ctx, service)
 	if err != nil {
 		return nil, err
 	}
@@ -367,10 +354,7 @@ func (az *Cloud) ListManagedLBs(service *v1.Service, nodes []*v1.Node, clusterNa
 }
 
 // ListLB invokes az.LoadBalancerClient.List with exponential backoff retry
-func (az *Cloud) ListLB(service *v1.Service) ([]network.LoadBalancer, error) {
-	ctx, cancel := getContextWithCancel()
-	defer cancel()
-
+func (az *Cloud) ListLB(ctx context.Context, service *v1.Service) ([]network.LoadBalancer, error) {
 	rgName := az.getLoadBalancerResourceGroup()
 	allLBs, rerr := az.LoadBalancerClient.List(ctx, rgName)
 	if rerr != nil {
@@ -386,10 +370,7 @@ func (az *Cloud) ListLB(service *v1.Service) ([]network.LoadBalancer, error) {
 }
 
 // ListPIP list the PIP resources in the given resource group
-func (az *Cloud) ListPIP(service *v1.Service, pipResourceGroup string) ([]network.PublicIPAddress, error) {
-	ctx, cancel := getContextWithCancel()
-	defer cancel()
-
+func (az *Cloud) ListPIP(ctx context.Context, service *v1.Service, pipResourceGroup string) ([]network.PublicIPAddress, error) {
 	allPIPs, rerr := az.PublicIPAddressesClient.List(ctx, pipResourceGroup)
 	if rerr != nil {
 		if rerr.IsNotFound() {
@@ -405,10 +386,7 @@ func (az *Cloud) ListPIP(service *v1.Service, pipResourceGroup string) ([]networ
 }
 
 // CreateOrUpdatePIP invokes az.PublicIPAddressesClient.CreateOrUpdate with exponential backoff retry
-func (az *Cloud) CreateOrUpdatePIP(service *v1.Service, pipResourceGroup string, pip network.PublicIPAddress) error {
-	ctx, cancel := getContextWithCancel()
-	defer cancel()
-
+func (az *Cloud) CreateOrUpdatePIP(ctx context.Context, service *v1.Service, pipResourceGroup string, pip network.PublicIPAddress) error {
 	rerr := az.PublicIPAddressesClient.CreateOrUpdate(ctx, pipResourceGroup, to.String(pip.Name), pip)
 	klog.V(10).Infof("PublicIPAddressesClient.CreateOrUpdate(%s, %s): end", pipResourceGroup, to.String(pip.Name))
 	if rerr == nil {
@@ -438,10 +416,7 @@ func (az *Cloud) CreateOrUpdatePIP(service *v1.Service, pipResourceGroup string,
 }
 
 // CreateOrUpdateInterface invokes az.InterfacesClient.CreateOrUpdate with exponential backoff retry
-func (az *Cloud) CreateOrUpdateInterface(service *v1.Service, nic network.Interface) error {
-	ctx, cancel := getContextWithCancel()
-	defer cancel()
-
+func (az *Cloud) CreateOrUpdateInterface(ctx context.Context, service *v1.Service, nic network.Interface) error {
 	rerr := az.InterfacesClient.CreateOrUpdate(ctx, az.ResourceGroup, *nic.Name, nic)
 	klog.V(10).Infof("InterfacesClient.CreateOrUpdate(%s): end", *nic.Name)
 	if rerr != nil {
@@ -454,10 +429,7 @@ func (az *Cloud) CreateOrUpdateInterface(service *v1.Service, nic network.Interf
 }
 
 // DeletePublicIP invokes az.PublicIPAddressesClient.Delete with exponential backoff retry
-func (az *Cloud) DeletePublicIP(service *v1.Service, pipResourceGroup string, pipName string) error {
-	ctx, cancel := getContextWithCancel()
-	defer cancel()
-
+func (az *Cloud) DeletePublicIP(ctx context.Context, service *v1.Service, pipResourceGroup string, pipName string) error {
 	rerr := az.PublicIPAddressesClient.Delete(ctx, pipResourceGroup, pipName)
 	if rerr != nil {
 		klog.Errorf("PublicIPAddressesClient.Delete(%s) failed: %s", pipName, rerr.Error().Error())
@@ -476,15 +448,11 @@ func (az *Cloud) DeletePublicIP(service *v1.Service, pipResourceGroup string, pi
 }
 
 // DeleteLB invokes az.LoadBalancerClient.Delete with exponential backoff retry
-func (az *Cloud) DeleteLB(service *v1.Service, lbName string) *retry.Error {
-	ctx, cancel := getContextWithCancel()
-	defer cancel()
-
+func (az *Cloud) DeleteLB(ctx context.Context, service *v1.Service, lbName string) *retry.Error {
 	rgName := az.getLoadBalancerResourceGroup()
 	rerr := az.LoadBalancerClient.Delete(ctx, rgName, lbName)
 	if rerr == nil {
-		// Invalidate the cache right after updating
-		_ = az.lbCache.Delete(lbName)
+		// The cache is invalidated by the client right after deleting.
 		return nil
 	}
 
@@ -494,10 +462,7 @@ func (az *Cloud) DeleteLB(service *v1.Service, lbName string) *retry.Error {
 }
 
 // CreateOrUpdateRouteTable invokes az.RouteTablesClient.CreateOrUpdate with exponential backoff retry
-func (az *Cloud) CreateOrUpdateRouteTable(routeTable network.RouteTable) error {
-	ctx, cancel := getContextWithCancel()
-	defer cancel()
-
+func (az *Cloud) CreateOrUpdateRouteTable(ctx context.Context, routeTable network.RouteTable) error {
 	rerr := az.RouteTablesClient.CreateOrUpdate(ctx, az.RouteTableResourceGroup, az.RouteTableName, routeTable, to.String(routeTable.Etag))
 	if rerr == nil {
 		// Invalidate the cache right after updating
@@ -523,10 +488,7 @@ func (az *Cloud) CreateOrUpdateRouteTable(routeTable network.RouteTable) error {
 }
 
 // CreateOrUpdateRoute invokes az.RoutesClient.CreateOrUpdate with exponential backoff retry
-func (az *Cloud) CreateOrUpdateRoute(route network.Route) error {
-	ctx, cancel := getContextWithCancel()
-	defer cancel()
-
+func (az *Cloud) CreateOrUpdateRoute(ctx context.Context, route network.Route) error {
 	rerr := az.RoutesClient.CreateOrUpdate(ctx, az.RouteTableResourceGroup, az.RouteTableName, *route.Name, route, to.String(route.Etag))
 	klog.V(10).Infof("RoutesClient.CreateOrUpdate(%s): end", *route.Name)
 	if rerr == nil {
@@ -547,10 +509,7 @@ func (az *Cloud) CreateOrUpdateRoute(route network.Route) error {
 }
 
 // DeleteRouteWithName invokes az.RoutesClient.CreateOrUpdate with exponential backoff retry
-func (az *Cloud) DeleteRouteWithName(routeName string) error {
-	ctx, cancel := getContextWithCancel()
-	defer cancel()
-
+func (az *Cloud) DeleteRouteWithName(ctx context.Context, routeName string) error {
 	rerr := az.RoutesClient.Delete(ctx, az.RouteTableResourceGroup, az.RouteTableName, routeName)
 	klog.V(10).Infof("RoutesClient.Delete(%s,%s): end", az.RouteTableName, routeName)
 	if rerr == nil {
@@ -562,14 +521,11 @@ func (az *Cloud) DeleteRouteWithName(routeName string) error {
 }
 
 // CreateOrUpdateVMSS invokes az.VirtualMachineScaleSetsClient.Update().
-func (az *Cloud) CreateOrUpdateVMSS(resourceGroupName string, VMScaleSetName string, parameters compute.VirtualMachineScaleSet) *retry.Error {
-	ctx, cancel := getContextWithCancel()
-	defer cancel()
-
+func (az *Cloud) CreateOrUpdateVMSS(ctx context.Context, subscriptionID, resourceGroupName string, VMScaleSetName string, parameters compute.VirtualMachineScaleSet) *retry.Error {
 	// When vmss is being deleted, CreateOrUpdate API would report "the vmss is being deleted" error.
 	// Since it is being deleted, we shouldn't send more CreateOrUpdate requests for it.
 	klog.V(3).Infof("CreateOrUpdateVMSS: verify the status of the vmss being created or updated")
-	vmss, rerr := az.VirtualMachineScaleSetsClient.Get(ctx, resourceGroupName, VMScaleSetName)
+	vmss, rerr := az.VirtualMachineScaleSetsClient.Get(ctx, subscriptionID, resourceGroupName, VMScaleSetName)
 	if rerr != nil {
 		klog.Errorf("CreateOrUpdateVMSS: error getting vmss(%s): %v", VMScaleSetName, rerr)
 		return rerr
@@ -579,7 +535,7 @@ func (az *Cloud) CreateOrUpdateVMSS(resourceGroupName string, VMScaleSetName str
 		return nil
 	}
 
-	rerr = az.VirtualMachineScaleSetsClient.CreateOrUpdate(ctx, resourceGroupName, VMScaleSetName, parameters)
+	rerr = az.VirtualMachineScaleSetsClient.CreateOrUpdate(ctx, subscriptionID, resourceGroupName, VMScaleSetName, parameters)
 	klog.V(10).Infof("UpdateVmssVMWithRetry: VirtualMachineScaleSetsClient.CreateOrUpdate(%s): end", VMScaleSetName)
 	if rerr != nil {
 		klog.Errorf("CreateOrUpdateVMSS: error CreateOrUpdate vmss(%s): %v", VMScaleSetName, rerr)
@@ -589,10 +545,7 @@ func (az *Cloud) CreateOrUpdateVMSS(resourceGroupName string, VMScaleSetName str
 	return nil
 }
 
-func (az *Cloud) CreateOrUpdatePLS(service *v1.Service, pls network.PrivateLinkService) error {
-	ctx, cancel := getContextWithCancel()
-	defer cancel()
-
+func (az *Cloud) CreateOrUpdatePLS(ctx context.Context, service *v1.Service, pls network.PrivateLinkService) error {
 	rerr := az.PrivateLinkServiceClient.CreateOrUpdate(ctx, az.PrivateLinkServiceResourceGroup, to.String(pls.Name), pls, to.String(pls.Etag))
 	if rerr == nil {
 		// Invalidate the cache right after updating
@@ -618,10 +571,7 @@ func (az *Cloud) CreateOrUpdatePLS(service *v1.Service, pls network.PrivateLinkS
 }
 
 // DeletePLS invokes az.PrivateLinkServiceClient.Delete with exponential backoff retry
-func (az *Cloud) DeletePLS(service *v1.Service, plsName string, plsLBFrontendID string) *retry.Error {
-	ctx, cancel := getContextWithCancel()
-	defer cancel()
-
+func (az *Cloud) DeletePLS(ctx context.Context, service *v1.Service, plsName string, plsLBFrontendID string) *retry.Error {
 	rerr := az.PrivateLinkServiceClient.Delete(ctx, az.PrivateLinkServiceResourceGroup, plsName)
 	if rerr == nil {
 		// Invalidate the cache right after deleting
@@ -635,10 +585,7 @@ func (az *Cloud) DeletePLS(service *v1.Service, plsName string, plsLBFrontendID
 }
 
 // DeletePEConn invokes az.PrivateLinkServiceClient.DeletePEConnection with exponential backoff retry
-func (az *Cloud) DeletePEConn(service *v1.Service, plsName string, peConnName string) *retry.Error {
-	ctx, cancel := getContextWithCancel()
-	defer cancel()
-
+func (az *Cloud) DeletePEConn(ctx context.Context, service *v1.Service, plsName string, peConnName string) *retry.Error {
 	rerr := az.PrivateLinkServiceClient.DeletePEConnection(ctx, az.PrivateLinkServiceResourceGroup, plsName, peConnName)
 	if rerr == nil {
 		return nil
@@ -650,10 +597,7 @@ func (az *Cloud) DeletePEConn(service *v1.Service, plsName string, peConnName st
 }
 
 // CreateOrUpdateSubnet invokes az.SubnetClient.CreateOrUpdate with exponential backoff retry
-func (az *Cloud) CreateOrUpdateSubnet(service *v1.Service, subnet network.Subnet) error {
-	ctx, cancel := getContextWithCancel()
-	defer cancel()
-
+func (az *Cloud) CreateOrUpdateSubnet(ctx context.Context, service *v1.Service, subnet network.Subnet) error {
 	var rg string
 	if len(az.VnetResourceGroup) > 0 {
 		rg = az.VnetResourceGroup
@@ -17,6 +17,7 @@ limitations under the License.
 package provider
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -230,7 +231,7 @@ func TestCreateOrUpdateSecurityGroupCanceled(t *testing.T) {
 	})
 	mockSGClient.EXPECT().Get(gomock.Any(), az.ResourceGroup, "sg", gomock.Any()).Return(network.SecurityGroup{}, nil)
 
-	err := az.CreateOrUpdateSecurityGroup(network.SecurityGroup{Name: to.StringPtr("sg")})
+	err := az.CreateOrUpdateSecurityGroup(context.TODO(), network.SecurityGroup{Name: to.StringPtr("sg")})
 	assert.EqualError(t, fmt.Errorf("Retriable: false, RetryAfter: 0s, HTTPStatusCode: 0, RawError: %w", fmt.Errorf("canceledandsupersededduetoanotheroperation")), err.Error())
 
 	// security group should be removed from cache if the operation is canceled
@@ -280,7 +281,7 @@ func TestCreateOrUpdateLB(t *testing.T) {
 			},
 		}, nil).AnyTimes()
 
-		err := az.CreateOrUpdateLB(&v1.Service{}, network.LoadBalancer{
+		err := az.CreateOrUpdateLB(context.TODO(), &v1.Service{}, network.LoadBalancer{
 			Name: to.StringPtr("lb"),
 			Etag: to.StringPtr("etag"),
 		})
@@ -344,7 +345,7 @@ func TestListAgentPoolLBs(t *testing.T) {
 		mockVMSet.EXPECT().GetPrimaryVMSetName().Return("vmas-0").AnyTimes()
 		az.VMSet = mockVMSet
 
-		lbs, err := az.ListManagedLBs(&v1.Service{}, []*v1.Node{}, "kubernetes")
+		lbs, err := az.ListManagedLBs(context.TODO(), &v1.Service{}, []*v1.Node{}, "kubernetes")
 		assert.Equal(t, test.expectedErr, err)
 		assert.Equal(t, test.expectedLBs, lbs)
 	}
@@ -372,7 +373,7 @@ func TestListPIP(t *testing.T) {
 		mockPIPClient := az.PublicIPAddressesClient.(*mockpublicipclient.MockInterface)
 		mockPIPClient.EXPECT().List(gomock.Any(), az.ResourceGroup).Return(nil, test.clientErr)
 
-		pips, err := az.ListPIP(&v1.Service{}, az.ResourceGroup)
+		pips, err := az.ListPIP(context.TODO(), &v1.Service{}, az.ResourceGroup)
 		assert.Equal(t, test.expectedErr, err)
 		assert.Empty(t, pips)
 	}
@@ -414,7 +415,7 @@ func TestCreateOrUpdatePIP(t *testing.T) {
 			mockPIPClient.EXPECT().Get(gomock.Any(), az.ResourceGroup, "nic", gomock.Any()).Return(network.PublicIPAddress{}, nil)
 		}
 
-		err := az.CreateOrUpdatePIP(&v1.Service{}, az.ResourceGroup, network.PublicIPAddress{Name: to.StringPtr("nic")})
+		err := az.CreateOrUpdatePIP(context.TODO(), &v1.Service{}, az.ResourceGroup, network.PublicIPAddress{Name: to.StringPtr("nic")})
 		assert.EqualError(t, test.expectedErr, err.Error())
 
 		cachedPIP, err := az.pipCache.Get(az.getPIPCacheKey(az.ResourceGroup, "nic"), cache.CacheReadTypeDefault)
@@ -427,6 +428,35 @@ func TestCreateOrUpdatePIP(t *testing.T) {
 	}
 }
 
+func TestCreateOrUpdatePIPInvalidatesNotFoundCache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	az := GetTestCloud(ctrl)
+	pip := network.PublicIPAddress{Name: to.StringPtr("pip")}
+	mockPIPClient := az.PublicIPAddressesClient.(*mockpublicipclient.MockInterface)
+	gomock.InOrder(
+		mockPIPClient.EXPECT().Get(gomock.Any(), az.ResourceGroup, "pip", gomock.Any()).Return(network.PublicIPAddress{}, &retry.Error{HTTPStatusCode: http.StatusNotFound}).Times(1),
+		mockPIPClient.EXPECT().CreateOrUpdate(gomock.Any(), az.ResourceGroup, "pip", gomock.Any()).Return(nil).Times(1),
+		mockPIPClient.EXPECT().Get(gomock.Any(), az.ResourceGroup, "pip", gomock.Any()).Return(pip, nil).Times(1),
+	)
+
+	// the not found result should be cached
+	for i := 0; i < 2; i++ {
+		_, existing, err := az.getPublicIPAddress(az.ResourceGroup, "pip", cache.CacheReadTypeDefault)
+		assert.NoError(t, err)
+		assert.False(t, existing)
+	}
+
+	err := az.CreateOrUpdatePIP(context.TODO(), &v1.Service{}, az.ResourceGroup, pip)
+	assert.NoError(t, err)
+
+	cachedPIP, existing, err := az.getPublicIPAddress(az.ResourceGroup, "pip", cache.CacheReadTypeDefault)
+	assert.NoError(t, err)
+	assert.True(t, existing)
+	assert.Equal(t, pip, cachedPIP)
+}
+
 func TestCreateOrUpdateInterface(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -435,7 +465,7 @@ func TestCreateOrUpdateInterface(t *testing.T) {
 	mockInterfaceClient := az.InterfacesClient.(*mockinterfaceclient.MockInterface)
 	mockInterfaceClient.EXPECT().CreateOrUpdate(gomock.Any(), az.ResourceGroup, "nic", gomock.Any()).Return(&retry.Error{HTTPStatusCode: http.StatusInternalServerError})
 
-	err := az.CreateOrUpdateInterface(&v1.Service{}, network.Interface{Name: to.StringPtr("nic")})
+	err := az.CreateOrUpdateInterface(context.TODO(), &v1.Service{}, network.Interface{Name: to.StringPtr("nic")})
 	assert.EqualError(t, fmt.Errorf("Retriable: false, RetryAfter: 0s, HTTPStatusCode: 500, RawError: %w", error(nil)), err.Error())
 }
 
@@ -447,7 +477,7 @@ func TestDeletePublicIP(t *testing.T) {
 	mockPIPClient := az.PublicIPAddressesClient.(*mockpublicipclient.MockInterface)
 	mockPIPClient.EXPECT().Delete(gomock.Any(), az.ResourceGroup, "pip").Return(&retry.Error{HTTPStatusCode: http.StatusInternalServerError})
 
-	err := az.DeletePublicIP(&v1.Service{}, az.ResourceGroup, "pip")
+	err := az.DeletePublicIP(context.TODO(), &v1.Service{}, az.ResourceGroup, "pip")
 	assert.EqualError(t, fmt.Errorf("Retriable: false, RetryAfter: 0s, HTTPStatusCode: 500, RawError: %w", error(nil)), err.Error())
 }
 
@@ -459,7 +489,7 @@ func TestDeleteLB(t *testing.T) {
 	mockLBClient := az.LoadBalancerClient.(*mockloadbalancerclient.MockInterface)
 	mockLBClient.EXPECT().Delete(gomock.Any(), az.ResourceGroup, "lb").Return(&retry.Error{HTTPStatusCode: http.StatusInternalServerError})
 
-	err := az.DeleteLB(&v1.Service{}, "lb")
+	err := az.DeleteLB(context.TODO(), &v1.Service{}, "lb")
 	assert.EqualError(t, fmt.Errorf("Retriable: false, RetryAfter: 0s, HTTPStatusCode: 500, RawError: %w", error(nil)), fmt.Sprintf("%s", err.Error()))
 }
 
@@ -489,7 +519,7 @@ func TestCreateOrUpdateRouteTable(t *testing.T) {
 		mockRTClient.EXPECT().CreateOrUpdate(gomock.Any(), az.ResourceGroup, gomock.Any(), gomock.Any(), gomock.Any()).Return(test.clientErr)
 		mockRTClient.EXPECT().Get(gomock.Any(), az.ResourceGroup, "rt", gomock.Any()).Return(network.RouteTable{}, nil)
 
-		err := az.CreateOrUpdateRouteTable(network.RouteTable{
+		err := az.CreateOrUpdateRouteTable(context.TODO(), network.RouteTable{
 			Name: to.StringPtr("rt"),
 			Etag: to.StringPtr("etag"),
 		})
@@ -534,7 +564,7 @@ func TestCreateOrUpdateRoute(t *testing.T) {
 		mockRTableClient := az.RouteTablesClient.(*mockroutetableclient.MockInterface)
 		mockRTableClient.EXPECT().Get(gomock.Any(), az.ResourceGroup, "rt", gomock.Any()).Return(network.RouteTable{}, nil)
 
-		err := az.CreateOrUpdateRoute(network.Route{
+		err := az.CreateOrUpdateRoute(context.TODO(), network.Route{
 			Name: to.StringPtr("rt"),
 			Etag: to.StringPtr("etag"),
 		})
@@ -572,7 +602,7 @@ func TestDeleteRouteWithName(t *testing.T) {
 		mockRTClient := az.RoutesClient.(*mockrouteclient.MockInterface)
 		mockRTClient.EXPECT().Delete(gomock.Any(), az.ResourceGroup, "rt", "rt").Return(test.clientErr)
 
-		err := az.DeleteRouteWithName("rt")
+		err := az.DeleteRouteWithName(context.TODO(), "rt")
 		if test.expectedErr != nil {
 			assert.EqualError(t, test.expectedErr, err.Error())
 		}
@@ -613,11 +643,20 @@ func TestCreateOrUpdateVMSS(t *testing.T) {
 		az := GetTestCloud(ctrl)
 
 		mockVMSSClient := az.VirtualMachineScaleSetsClient.(*mockvmssclient.MockInterface)
-		mockVMSSClient.EXPECT().Get(gomock.Any(), az.ResourceGroup, testVMSSName).Return(test.vmss, test.clientErr)
+		mockVMSSClient.EXPECT().Get(gomock.Any(), "subscription2", az.ResourceGroup, testVMSSName).Return(test.vmss, test.clientErr)
 
-		err := az.CreateOrUpdateVMSS(az.ResourceGroup, testVMSSName, compute.VirtualMachineScaleSet{})
+		err := az.CreateOrUpdateVMSS(context.TODO(), "subscription2", az.ResourceGroup, testVMSSName, compute.VirtualMachineScaleSet{})
 		assert.Equal(t, test.expectedErr, err)
 	}
+
+	// the VMSS is updated in the given subscription
+	az := GetTestCloud(ctrl)
+	mockVMSSClient := az.VirtualMachineScaleSetsClient.(*mockvmssclient.MockInterface)
+	mockVMSSClient.EXPECT().Get(gomock.Any(), "subscription2", az.ResourceGroup, testVMSSName).Return(compute.VirtualMachineScaleSet{
+		VirtualMachineScaleSetProperties: &compute.VirtualMachineScaleSetProperties{},
+	}, nil)
+	mockVMSSClient.EXPECT().CreateOrUpdate(gomock.Any(), "subscription2", az.ResourceGroup, testVMSSName, gomock.Any()).Return(nil)
+	assert.Nil(t, az.CreateOrUpdateVMSS(context.TODO(), "subscription2", az.ResourceGroup, testVMSSName, compute.VirtualMachineScaleSet{}))
 }
 
 func TestRequestBackoff(t *testing.T) {
@@ -659,7 +698,7 @@ func TestCreateOrUpdateLBBackendPool(t *testing.T) {
 		lbClient.EXPECT().CreateOrUpdateBackendPools(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(tc.createOrUpdateErr)
 		az.LoadBalancerClient = lbClient
 
-		err := az.CreateOrUpdateLBBackendPool("kubernetes", network.BackendAddressPool{})
+		err := az.CreateOrUpdateLBBackendPool(context.TODO(), "kubernetes", network.BackendAddressPool{})
 		assert.Equal(t, tc.expectedErr, err != nil)
 	}
 }
@@ -690,7 +729,7 @@ func TestDeleteLBBackendPool(t *testing.T) {
 		lbClient.EXPECT().DeleteLBBackendPool(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(tc.deleteErr)
 		az.LoadBalancerClient = lbClient
 
-		err := az.DeleteLBBackendPool("kubernetes", "kubernetes")
+		err := az.DeleteLBBackendPool(context.TODO(), "kubernetes", "kubernetes")
 		assert.Equal(t, tc.expectedErr, err != nil)
 	}
 }
@@ -17,8 +17,15 @@ limitations under the License.
 package provider
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/klog/v2"
@@ -36,8 +43,17 @@ const (
 	cloudConfigTypeFile   cloudConfigType = "file"
 	cloudConfigTypeSecret cloudConfigType = "secret"
 	cloudConfigTypeMerge  cloudConfigType = "merge"
+
+	// configEmptyValue is the value in the secret which sets the field to empty explicitly,
+	// since the empty strings in the secret do not override the values from the file.
+	configEmptyValue = "<empty>"
 )
 
+// configEnvPrefix is the prefix of the environment variables overriding the cloud config. It is specific to the
+// cloud provider so that the generic variables, e.g. AZURE_TENANT_ID injected by the workload identity webhook or
+// ARM_SUBSCRIPTION_ID exported for Terraform, never override the cloud config.
+const configEnvPrefix = "CLOUD_PROVIDER_AZURE_"
+
 // InitializeCloudFromSecret initializes Azure cloud provider from Kubernetes secret.
 func (az *Cloud) InitializeCloudFromSecret() error {
 	config, err := az.GetConfigFromSecret()
@@ -51,6 +67,7 @@ func (az *Cloud) InitializeCloudFromSecret() error {
 		return nil
 	}
 
+	klog.V(2).Infof("InitializeCloudFromSecret: the effective cloud config is %s", redactedConfig(config))
 	if err := az.InitializeCloudFromConfig(config, true, true); err != nil {
 		klog.Errorf("Failed to initialize Azure cloud provider: %v", err)
 		return fmt.Errorf("InitializeCloudFromSecret: failed to initialize Azure cloud provider: %w", err)
@@ -75,16 +92,213 @@ func (az *Cloud) GetConfigFromSecret() (*Config, error) {
 		return nil, fmt.Errorf("cloud-config is not set in the secret (%s/%s)", az.SecretNamespace, az.SecretName)
 	}
 
-	config := Config{}
+	base := &Config{}
 	if az.Config.CloudConfigType == "" || az.Config.CloudConfigType == cloudConfigTypeMerge {
 		// Merge cloud config, set default value to existing config.
-		config = az.Config
+		base = &az.Config
 	}
 
-	err = yaml.Unmarshal(cloudConfigData, &config)
+	config, err := mergeConfig(base, cloudConfigData)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse Azure cloud-config: %w", err)
 	}
 
-	return &config, nil
+	if err := applyConfigEnvOverrides(config, os.LookupEnv); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// mergeConfig merges the cloud config data from the secret into a copy of the base config. The values in the data
+// override the base values field by field, and the nested objects, e.g. the rate limit configs of the clients and
+// the maps, are merged recursively. The empty strings in the data are ignored unless they are set to configEmptyValue.
+func mergeConfig(base *Config, data []byte) (*Config, error) {
+	baseJSON, err := json.Marshal(base)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := decodeConfigObject(baseJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	overrideJSON, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	override, err := decodeConfigObject(overrideJSON)
+	if err != nil {
+		return nil, err
+	}
+	mergeConfigObjects(merged, override)
+
+	mergedJSON, err := json.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	config := &Config{}
+	if err := json.Unmarshal(mergedJSON, config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// decodeConfigObject decodes the JSON object with the numbers kept as they are, so that the integers are not
+// converted to floats which could not be decoded to the integer fields again.
+func decodeConfigObject(data []byte) (map[string]interface{}, error) {
+	object := map[string]interface{}{}
+	if len(bytes.TrimSpace(data)) == 0 || bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return object, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&object); err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+// mergeConfigObjects merges the override object into the base object. The keys are matched case-insensitively
+// as the JSON decoder does, and the base keys are kept so that the overridden values are not shadowed.
+func mergeConfigObjects(base, override map[string]interface{}) {
+	for overrideKey, overrideValue := range override {
+		key := overrideKey
+		for baseKey := range base {
+			if strings.EqualFold(baseKey, overrideKey) {
+				key = baseKey
+				break
+			}
+		}
+
+		switch value := overrideValue.(type) {
+		case string:
+			if value == "" {
+				continue
+			}
+			if value == configEmptyValue {
+				delete(base, key)
+				continue
+			}
+		case map[string]interface{}:
+			if baseObject, ok := base[key].(map[string]interface{}); ok {
+				mergeConfigObjects(baseObject, value)
+				continue
+			}
+		}
+		base[key] = overrideValue
+	}
+}
+
+// applyConfigEnvOverrides overrides the scalar fields of the cloud config by the environment variables named by
+// the JSON names of the fields in upper snake case with configEnvPrefix, e.g. CLOUD_PROVIDER_AZURE_RESOURCE_GROUP
+// overrides resourceGroup. They are meant for debugging and take precedence over both the file and the secret.
+func applyConfigEnvOverrides(config *Config, lookupEnv func(string) (string, bool)) error {
+	var apply func(value reflect.Value) error
+	apply = func(value reflect.Value) error {
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				if err := apply(value.Field(i)); err != nil {
+					return err
+				}
+				continue
+			}
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if !field.IsExported() || name == "" {
+				continue
+			}
+
+			envName := configEnvPrefix + toUpperSnakeCase(name)
+			envValue, ok := lookupEnv(envName)
+			if !ok {
+				continue
+			}
+			applied, err := setScalarValue(value.Field(i), envValue)
+			if err != nil {
+				return fmt.Errorf("failed to override cloud config %s by environment variable %s: %w", name, envName, err)
+			}
+			if applied {
+				klog.Infof("cloud config %s is overridden by environment variable %s", name, envName)
+			}
+		}
+		return nil
+	}
+	return apply(reflect.ValueOf(config).Elem())
+}
+
+// setScalarValue parses the string into the string, boolean or numeric value, or the pointer to it.
+// It returns false if the value is not a scalar.
+func setScalarValue(value reflect.Value, s string) (bool, error) {
+	if value.Kind() == reflect.Ptr {
+		elem := reflect.New(value.Type().Elem())
+		applied, err := setScalarValue(elem.Elem(), s)
+		if applied && err == nil {
+			value.Set(elem)
+		}
+		return applied, err
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return true, err
+		}
+		value.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, value.Type().Bits())
+		if err != nil {
+			return true, err
+		}
+		value.SetInt(i)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, value.Type().Bits())
+		if err != nil {
+			return true, err
+		}
+		value.SetFloat(f)
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// toUpperSnakeCase converts the camel case names to upper snake case, e.g. aadClientId to AAD_CLIENT_ID
+// and cloudProviderRateLimitQPS to CLOUD_PROVIDER_RATE_LIMIT_QPS.
+func toUpperSnakeCase(name string) string {
+	var builder strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) &&
+			(!unicode.IsUpper(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			builder.WriteRune('_')
+		}
+		builder.WriteRune(unicode.ToUpper(r))
+	}
+	return builder.String()
+}
+
+// redactedConfig returns the cloud config in JSON with the secrets and passwords redacted for logging.
+func redactedConfig(config *Config) string {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Sprintf("<failed to marshal the cloud config: %v>", err)
+	}
+	object, err := decodeConfigObject(data)
+	if err != nil {
+		return fmt.Sprintf("<failed to marshal the cloud config: %v>", err)
+	}
+	for key, value := range object {
+		lowerKey := strings.ToLower(key)
+		if (strings.Contains(lowerKey, "secret") || strings.Contains(lowerKey, "password")) && value != "" {
+			object[key] = "REDACTED"
+		}
+	}
+	data, err = json.Marshal(object)
+	if err != nil {
+		return fmt.Sprintf("<failed to marshal the cloud config: %v>", err)
+	}
+	return string(data)
 }
@@ -0,0 +1,429 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/adal"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/auth"
+	azclients "sigs.k8s.io/cloud-provider-azure/pkg/azureclients"
+	"sigs.k8s.io/cloud-provider-azure/pkg/retry"
+)
+
+const (
+	// configFileReloadInterval is the interval of checking whether the cloud config file is changed.
+	configFileReloadInterval = 30 * time.Second
+)
+
+var (
+	// credentialConfigFields are the cloud config fields of the credentials, which are reloaded without restarts.
+	credentialConfigFields = sets.NewString(
+		"tenantId",
+		"aadClientId",
+		"aadClientSecret",
+		"aadClientCertPath",
+		"aadClientCertPassword",
+		"aadClientCertKeyVaultURI",
+		"useManagedIdentityExtension",
+		"userAssignedIdentityID",
+		"useFederatedWorkloadIdentityExtension",
+		"aadFederatedTokenFile",
+	)
+	// rateLimitConfigFields are the cloud config fields of the rate limits, which are reloaded without restarts.
+	rateLimitConfigFields = configFieldNames(reflect.TypeOf(CloudProviderRateLimitConfig{}))
+	// backoffConfigFields are the cloud config fields of the backoff, which are reloaded without restarts.
+	backoffConfigFields = sets.NewString(
+		"cloudProviderBackoff",
+		"cloudProviderBackoffRetries",
+		"cloudProviderBackoffExponent",
+		"cloudProviderBackoffDuration",
+		"cloudProviderBackoffJitter",
+		"cloudProviderBackoffCap",
+	)
+)
+
+// authorizerHolder holds an autorest.Authorizer, so that the authorizers of
+// different types could be stored in the same atomic.Value.
+type authorizerHolder struct {
+	autorest.Authorizer
+}
+
+// reloadableAuthorizer is an autorest.Authorizer whose underlying authorizer could be swapped atomically.
+// The Azure clients get the authorizer every time a request is sent, so they pick up the rotated credentials
+// without being rebuilt, while the requests in flight finish with the old credentials.
+type reloadableAuthorizer struct {
+	authorizer atomic.Value
+}
+
+// newReloadableAuthorizer creates a new reloadableAuthorizer with the initial authorizer.
+func newReloadableAuthorizer(authorizer autorest.Authorizer) *reloadableAuthorizer {
+	a := &reloadableAuthorizer{}
+	a.set(authorizer)
+	return a
+}
+
+// WithAuthorization returns the PrepareDecorator of the current authorizer.
+func (a *reloadableAuthorizer) WithAuthorization() autorest.PrepareDecorator {
+	return a.authorizer.Load().(authorizerHolder).WithAuthorization()
+}
+
+// set swaps the underlying authorizer.
+func (a *reloadableAuthorizer) set(authorizer autorest.Authorizer) {
+	a.authorizer.Store(authorizerHolder{Authorizer: authorizer})
+}
+
+// configFileReloader holds the state of the cloud config file whose credentials are reloaded when it changes.
+type configFileReloader struct {
+	// lock serializes the reloads.
+	lock sync.Mutex
+	path string
+	// checksum is the checksum of the file content loaded last time.
+	checksum [sha256.Size]byte
+	// config is the config parsed from the file content loaded last time, without any defaults.
+	config *Config
+}
+
+// startConfigFileReloader starts reloading the credentials from the cloud config file periodically,
+// data is the content of the file which the Cloud is initialized from.
+func (az *Cloud) startConfigFileReloader(path string, data []byte, period time.Duration, stopCh <-chan struct{}) error {
+	if az.authorizer == nil {
+		klog.V(2).Infof("startConfigFileReloader: skip reloading cloud config file %s since no credentials are provided", path)
+		return nil
+	}
+
+	config, err := ParseConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to parse cloud config file %s: %w", path, err)
+	}
+	az.configFileReloader = &configFileReloader{
+		path:     path,
+		checksum: sha256.Sum256(data),
+		config:   config,
+	}
+
+	go wait.Until(func() {
+		if err := az.reloadConfigFile(); err != nil {
+			klog.Errorf("reloadConfigFile: failed to reload cloud config file %s: %v", path, err)
+		}
+	}, period, stopCh)
+	return nil
+}
+
+// reloadConfigFile reloads the credentials, the rate limits and the backoff if the content of the cloud config file
+// is changed. The authorizers and the rate limiters are swapped and the backoff is updated for the new requests, the
+// changes of the other configs are logged and would take effect after restarts since they are used in constructing
+// the clients and caches. If the new credentials could not be built, the old ones are kept and the reloading would be
+// retried in the next period.
+func (az *Cloud) reloadConfigFile() error {
+	reloader := az.configFileReloader
+	reloader.lock.Lock()
+	defer reloader.lock.Unlock()
+
+	data, err := ioutil.ReadFile(reloader.path)
+	if err != nil {
+		return fmt.Errorf("failed to read cloud config file: %w", err)
+	}
+
+	checksum := sha256.Sum256(data)
+	if checksum == reloader.checksum {
+		return nil
+	}
+
+	config, err := ParseConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to parse cloud config file: %w", err)
+	}
+
+	var credentialChanges, rateLimitChanges, backoffChanges, otherChanges []string
+	for _, field := range diffConfigFields(reloader.config, config) {
+		switch {
+		case credentialConfigFields.Has(field):
+			credentialChanges = append(credentialChanges, field)
+		case rateLimitConfigFields.Has(field):
+			rateLimitChanges = append(rateLimitChanges, field)
+		case backoffConfigFields.Has(field):
+			backoffChanges = append(backoffChanges, field)
+		default:
+			otherChanges = append(otherChanges, field)
+		}
+	}
+	klog.Infof("reloadConfigFile: cloud config file %s is changed, changed credential fields: %v, changed rate limit fields: %v, changed backoff fields: %v, changed other fields: %v",
+		reloader.path, credentialChanges, rateLimitChanges, backoffChanges, otherChanges)
+
+	if len(credentialChanges) > 0 {
+		if err := az.reloadCredentials(config); err != nil {
+			return err
+		}
+		klog.Infof("reloadConfigFile: reloaded the credentials from cloud config file %s", reloader.path)
+	}
+	if len(rateLimitChanges) > 0 || len(backoffChanges) > 0 {
+		// the defaults are applied to a new copy of the config, so that the config loaded last time is kept intact for diffing
+		effectiveConfig, err := ParseConfig(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("failed to parse cloud config file: %w", err)
+		}
+		if len(rateLimitChanges) > 0 {
+			az.reloadRateLimits(effectiveConfig)
+		}
+		if len(backoffChanges) > 0 {
+			az.reloadBackoff(effectiveConfig)
+		}
+	}
+	if len(otherChanges) > 0 {
+		klog.Warningf("reloadConfigFile: the changes of %v in cloud config file %s would take effect after restart", otherChanges, reloader.path)
+	}
+
+	reloader.checksum = checksum
+	reloader.config = config
+	return nil
+}
+
+// reloadCredentials rebuilds the authorizers with the credentials in the config and swaps them. The other
+// auth configs, e.g. the cloud and the network resource tenant, are kept since the clients depend on them.
+func (az *Cloud) reloadCredentials(config *Config) error {
+	authConfig := az.Config.AzureAuthConfig
+	authConfig.TenantID = config.TenantID
+	authConfig.AADClientID = config.AADClientID
+	authConfig.AADClientSecret = config.AADClientSecret
+	authConfig.AADClientCertPath = config.AADClientCertPath
+	authConfig.AADClientCertPassword = config.AADClientCertPassword
+	authConfig.AADClientCertKeyVaultURI = config.AADClientCertKeyVaultURI
+	authConfig.UseManagedIdentityExtension = config.UseManagedIdentityExtension
+	authConfig.UserAssignedIdentityID = config.UserAssignedIdentityID
+	authConfig.UseFederatedWorkloadIdentityExtension = config.UseFederatedWorkloadIdentityExtension
+	authConfig.AADFederatedTokenFile = config.AADFederatedTokenFile
+
+	servicePrincipalToken, err := auth.GetServicePrincipalToken(&authConfig, &az.Environment, az.Environment.ServiceManagementEndpoint)
+	if err != nil {
+		return fmt.Errorf("failed to get service principal token: %w", err)
+	}
+
+	var multiTenantAuthorizer, networkResourceAuthorizer autorest.Authorizer
+	var multiTenantServicePrincipalToken *adal.MultiTenantServicePrincipalToken
+	var networkResourceServicePrincipalToken *adal.ServicePrincipalToken
+	if az.multiTenantAuthorizer != nil {
+		multiTenantServicePrincipalToken, err = auth.GetMultiTenantServicePrincipalToken(&authConfig, &az.Environment)
+		if err != nil {
+			return fmt.Errorf("failed to get multi-tenant service principal token: %w", err)
+		}
+		multiTenantAuthorizer = autorest.NewMultiTenantServicePrincipalTokenAuthorizer(multiTenantServicePrincipalToken)
+	}
+	if az.networkResourceAuthorizer != nil {
+		networkResourceServicePrincipalToken, err = auth.GetNetworkResourceServicePrincipalToken(&authConfig, &az.Environment)
+		if err != nil {
+			return fmt.Errorf("failed to get network resource service principal token: %w", err)
+		}
+		networkResourceAuthorizer = autorest.NewBearerAuthorizer(networkResourceServicePrincipalToken)
+	}
+
+	// swap the authorizers after all of them are built, so that the credentials are not partially rotated
+	az.authorizer.set(autorest.NewBearerAuthorizer(servicePrincipalToken))
+	if multiTenantAuthorizer != nil {
+		az.multiTenantAuthorizer.set(multiTenantAuthorizer)
+	}
+	if networkResourceAuthorizer != nil {
+		az.networkResourceAuthorizer.set(networkResourceAuthorizer)
+	}
+	az.startTokenRefreshers(servicePrincipalToken, multiTenantServicePrincipalToken, networkResourceServicePrincipalToken)
+	return nil
+}
+
+// rateLimitedClient is a rate limit config with its name and the Azure clients which are limited by it.
+type rateLimitedClient struct {
+	name      string
+	clients   []interface{}
+	rateLimit *azclients.RateLimitConfig
+}
+
+// rateLimitedClients returns the rate limit configs in config with the Azure clients they apply to, which are paired
+// in the same way as configAzureClients.
+func (az *Cloud) rateLimitedClients(config *CloudProviderRateLimitConfig) []rateLimitedClient {
+	return []rateLimitedClient{
+		{"interfaceRateLimit", []interface{}{az.InterfacesClient}, config.InterfaceRateLimit},
+		{"virtualMachineSizesRateLimit", []interface{}{az.VirtualMachineSizesClient}, config.VirtualMachineSizeRateLimit},
+		{"snapshotRateLimit", []interface{}{az.SnapshotsClient}, config.SnapshotRateLimit},
+		{"storageAccountRateLimit", []interface{}{az.StorageAccountClient}, config.StorageAccountRateLimit},
+		{"diskRateLimit", []interface{}{az.DisksClient}, config.DiskRateLimit},
+		{"virtualMachineRateLimit", []interface{}{az.VirtualMachinesClient}, config.VirtualMachineRateLimit},
+		{"virtualMachineScaleSetRateLimit", []interface{}{az.VirtualMachineScaleSetsClient, az.VirtualMachineScaleSetVMsClient}, config.VirtualMachineScaleSetRateLimit},
+		{"routeRateLimit", []interface{}{az.RoutesClient}, config.RouteRateLimit},
+		{"subnetsRateLimit", []interface{}{az.SubnetsClient}, config.SubnetsRateLimit},
+		{"routeTableRateLimit", []interface{}{az.RouteTablesClient}, config.RouteTableRateLimit},
+		{"loadBalancerRateLimit", []interface{}{az.LoadBalancerClient}, config.LoadBalancerRateLimit},
+		{"securityGroupRateLimit", []interface{}{az.SecurityGroupsClient}, config.SecurityGroupRateLimit},
+		{"publicIPAddressRateLimit", []interface{}{az.PublicIPAddressesClient}, config.PublicIPAddressRateLimit},
+		{"availabilitySetRateLimit", []interface{}{az.AvailabilitySetsClient}, config.AvailabilitySetRateLimit},
+		{"privateEndpointRateLimit", []interface{}{az.privateendpointclient}, config.PrivateEndpointRateLimit},
+		{"privateDNSRateLimit", []interface{}{az.privatednsclient}, config.PrivateDNSRateLimit},
+		{"privateDNSZoneGroupRateLimit", []interface{}{az.privatednszonegroupclient}, config.PrivateDNSZoneGroupRateLimit},
+		{"virtualNetworkRateLimit", []interface{}{az.virtualNetworkLinksClient}, config.VirtualNetworkRateLimit},
+		{"privateLinkServiceRateLimit", []interface{}{az.PrivateLinkServiceClient}, config.PrivateLinkServiceRateLimit},
+		{"containerServiceRateLimit", []interface{}{az.containerServiceClient}, config.ContainerServiceRateLimit},
+		{"deploymentRateLimit", []interface{}{az.deploymentClient}, config.DeploymentRateLimit},
+	}
+}
+
+// getRateLimitConfig returns a copy of the current rate limit config.
+func (az *Cloud) getRateLimitConfig() CloudProviderRateLimitConfig {
+	az.rateLimitLock.RLock()
+	defer az.rateLimitLock.RUnlock()
+	return az.Config.CloudProviderRateLimitConfig
+}
+
+// reloadRateLimits replaces the rate limiters of the Azure clients and the attach/detach disk operations with the
+// ones created from the rate limit configs in config. The requests waiting for the old rate limiters are not affected.
+func (az *Cloud) reloadRateLimits(config *Config) {
+	InitializeCloudProviderRateLimitConfig(&config.CloudProviderRateLimitConfig)
+
+	oldConfig := az.getRateLimitConfig()
+	oldClients := az.rateLimitedClients(&oldConfig)
+	var changes []string
+	for i, c := range az.rateLimitedClients(&config.CloudProviderRateLimitConfig) {
+		updated := false
+		for _, client := range c.clients {
+			if setter, ok := client.(azclients.RateLimitsSetter); ok {
+				setter.SetRateLimits(c.rateLimit)
+				updated = true
+			}
+		}
+		if oldRateLimit := oldClients[i].rateLimit; updated && !reflect.DeepEqual(oldRateLimit, c.rateLimit) {
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", c.name, formatRateLimitConfig(oldRateLimit), formatRateLimitConfig(c.rateLimit)))
+		}
+	}
+
+	if az.controllerCommon != nil {
+		if limiter, ok := az.controllerCommon.diskOpRateLimiter.(*azclients.ReloadableRateLimiter); ok {
+			limiter.Set(newDiskOpRateLimiter(&config.CloudProviderRateLimitConfig))
+		}
+		if oldRateLimit := oldConfig.AttachDetachDiskRateLimit; !reflect.DeepEqual(oldRateLimit, config.AttachDetachDiskRateLimit) {
+			changes = append(changes, fmt.Sprintf("attachDetachDiskRateLimit: %s -> %s",
+				formatRateLimitConfig(oldRateLimit), formatRateLimitConfig(config.AttachDetachDiskRateLimit)))
+		}
+	}
+
+	az.rateLimitLock.Lock()
+	az.Config.CloudProviderRateLimitConfig = config.CloudProviderRateLimitConfig
+	az.rateLimitLock.Unlock()
+	klog.Infof("reloadRateLimits: reloaded the rate limits, changes: %v", changes)
+}
+
+// reloadBackoff updates the backoff of the Cloud and the Azure clients with the backoff configs in config,
+// the requests in flight keep retrying with the old backoff.
+func (az *Cloud) reloadBackoff(config *Config) {
+	resourceRequestBackoff := az.setCloudProviderBackoffDefaults(config)
+	clientBackoff := &retry.Backoff{Steps: 1}
+	if config.CloudProviderBackoff {
+		clientBackoff = newClientBackoff(config)
+	}
+
+	az.backoffLock.Lock()
+	oldBackoff, oldCap := az.ResourceRequestBackoff, time.Duration(az.CloudProviderBackoffCap)*time.Second
+	az.CloudProviderBackoff = config.CloudProviderBackoff
+	az.CloudProviderBackoffRetries = config.CloudProviderBackoffRetries
+	az.CloudProviderBackoffExponent = config.CloudProviderBackoffExponent
+	az.CloudProviderBackoffDuration = config.CloudProviderBackoffDuration
+	az.CloudProviderBackoffJitter = config.CloudProviderBackoffJitter
+	az.CloudProviderBackoffCap = config.CloudProviderBackoffCap
+	az.ResourceRequestBackoff = resourceRequestBackoff
+	az.backoffLock.Unlock()
+
+	rateLimitConfig := az.getRateLimitConfig()
+	for _, c := range az.rateLimitedClients(&rateLimitConfig) {
+		for _, client := range c.clients {
+			if setter, ok := client.(azclients.BackoffSetter); ok {
+				setter.SetBackoff(clientBackoff)
+			}
+		}
+	}
+	klog.Infof("reloadBackoff: reloaded the backoff, steps: %d -> %d, duration: %v -> %v, factor: %g -> %g, jitter: %g -> %g, cap: %v -> %v",
+		oldBackoff.Steps, resourceRequestBackoff.Steps, oldBackoff.Duration, resourceRequestBackoff.Duration,
+		oldBackoff.Factor, resourceRequestBackoff.Factor, oldBackoff.Jitter, resourceRequestBackoff.Jitter,
+		oldCap, clientBackoff.Cap)
+}
+
+// formatRateLimitConfig formats the rate limit config for logging.
+func formatRateLimitConfig(config *azclients.RateLimitConfig) string {
+	if !azclients.RateLimitEnabled(config) {
+		return "disabled"
+	}
+	return fmt.Sprintf("{read QPS=%g, bucket=%d; write QPS=%g, bucket=%d}", config.CloudProviderRateLimitQPS, config.CloudProviderRateLimitBucket,
+		config.CloudProviderRateLimitQPSWrite, config.CloudProviderRateLimitBucketWrite)
+}
+
+// configFieldName returns the json name of the config field.
+func configFieldName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" {
+		name = field.Name
+	}
+	return name
+}
+
+// configFieldNames returns the json names of the fields of the config struct type, including the fields of the
+// embedded structs.
+func configFieldNames(t reflect.Type) sets.String {
+	names := sets.NewString()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			names.Insert(configFieldNames(field.Type).List()...)
+			continue
+		}
+		if field.IsExported() {
+			names.Insert(configFieldName(field))
+		}
+	}
+	return names
+}
+
+// diffConfigFields returns the json names of the fields which are different in the two configs.
+// The fields of the embedded structs, e.g. AzureAuthConfig, are compared one by one.
+func diffConfigFields(oldConfig, newConfig *Config) []string {
+	var fields []string
+	var diff func(oldValue, newValue reflect.Value)
+	diff = func(oldValue, newValue reflect.Value) {
+		for i := 0; i < oldValue.NumField(); i++ {
+			field := oldValue.Type().Field(i)
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				diff(oldValue.Field(i), newValue.Field(i))
+				continue
+			}
+			if !field.IsExported() || reflect.DeepEqual(oldValue.Field(i).Interface(), newValue.Field(i).Interface()) {
+				continue
+			}
+
+			fields = append(fields, configFieldName(field))
+		}
+	}
+	diff(reflect.ValueOf(*oldConfig), reflect.ValueOf(*newConfig))
+	return fields
+}
@@ -0,0 +1,250 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/auth"
+	azclients "sigs.k8s.io/cloud-provider-azure/pkg/azureclients"
+	"sigs.k8s.io/cloud-provider-azure/pkg/retry"
+)
+
+// fakeSTSHandler issues the access tokens derived from the client secrets in the token requests.
+func fakeSTSHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	expiresOn := time.Now().Add(time.Hour).Unix()
+	_, _ = w.Write([]byte(fmt.Sprintf(`{"access_token":"token-%s","expires_in":"3600","expires_on":"%d","not_before":"%d","resource":"%s","token_type":"Bearer"}`,
+		r.PostForm.Get("client_secret"), expiresOn, expiresOn-3600, r.PostForm.Get("resource"))))
+}
+
+// fakeARM records the authorization headers of the requests, and blocks the first request until it is released.
+type fakeARM struct {
+	lock           sync.Mutex
+	authorizations []string
+	received       chan struct{}
+	release        chan struct{}
+}
+
+func (f *fakeARM) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	f.authorizations = append(f.authorizations, r.Header.Get("Authorization"))
+	first := len(f.authorizations) == 1
+	f.lock.Unlock()
+
+	if first {
+		close(f.received)
+		<-f.release
+	}
+	_, _ = w.Write([]byte(`{"name":"lb"}`))
+}
+
+func writeCloudConfigFile(t *testing.T, path, clientSecret, location string) []byte {
+	data := []byte(fmt.Sprintf(`{"tenantId":"tenant","aadClientId":"client","aadClientSecret":"%s","subscriptionId":"subscription","resourceGroup":"rg","location":"%s"}`,
+		clientSecret, location))
+	assert.NoError(t, ioutil.WriteFile(path, data, 0600))
+	return data
+}
+
+func newCloudWithFakeEndpoints(t *testing.T, configFile string, data []byte, sts, arm *httptest.Server) *Cloud {
+	config, err := ParseConfig(bytes.NewReader(data))
+	assert.NoError(t, err)
+
+	az := &Cloud{
+		Config: *config,
+		Environment: azure.Environment{
+			ActiveDirectoryEndpoint:   sts.URL + "/",
+			ResourceManagerEndpoint:   arm.URL + "/",
+			ServiceManagementEndpoint: "https://management.core.windows.net/",
+		},
+	}
+	servicePrincipalToken, err := auth.GetServicePrincipalToken(&az.Config.AzureAuthConfig, &az.Environment, az.Environment.ServiceManagementEndpoint)
+	assert.NoError(t, err)
+	assert.NoError(t, az.configureMultiTenantClients(servicePrincipalToken))
+	assert.NoError(t, az.startConfigFileReloader(configFile, data, time.Hour, make(chan struct{})))
+	return az
+}
+
+func TestReloadConfigFileRotatesCredentials(t *testing.T) {
+	sts := httptest.NewServer(http.HandlerFunc(fakeSTSHandler))
+	defer sts.Close()
+	arm := &fakeARM{received: make(chan struct{}), release: make(chan struct{})}
+	armServer := httptest.NewServer(arm)
+	defer armServer.Close()
+
+	configFile := filepath.Join(t.TempDir(), "azure.json")
+	data := writeCloudConfigFile(t, configFile, "old", "westus")
+	az := newCloudWithFakeEndpoints(t, configFile, data, sts, armServer)
+
+	// start a request and rotate the credentials while it is in flight
+	var inFlightErr *retry.Error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, inFlightErr = az.LoadBalancerClient.Get(context.Background(), "rg", "lb", "")
+	}()
+	<-arm.received
+
+	oldRefreshers := az.getTokenRefreshers()
+	writeCloudConfigFile(t, configFile, "new", "westus")
+	assert.NoError(t, az.reloadConfigFile())
+	assert.Len(t, oldRefreshers, 1)
+	assert.Len(t, az.getTokenRefreshers(), 1)
+	assert.NotSame(t, oldRefreshers[0], az.getTokenRefreshers()[0], "the tokens of the new credentials should be refreshed")
+
+	close(arm.release)
+	<-done
+	assert.Nil(t, inFlightErr)
+
+	_, rerr := az.LoadBalancerClient.Get(context.Background(), "rg", "lb", "")
+	assert.Nil(t, rerr)
+	assert.Equal(t, []string{"Bearer token-old", "Bearer token-new"}, arm.authorizations)
+
+	// the credentials should not be rebuilt if the file is not changed
+	assert.NoError(t, az.reloadConfigFile())
+	_, rerr = az.LoadBalancerClient.Get(context.Background(), "rg", "lb", "")
+	assert.Nil(t, rerr)
+	assert.Equal(t, "Bearer token-new", arm.authorizations[2])
+}
+
+func TestReloadConfigFileKeepsCredentialsOnError(t *testing.T) {
+	sts := httptest.NewServer(http.HandlerFunc(fakeSTSHandler))
+	defer sts.Close()
+	arm := &fakeARM{received: make(chan struct{}), release: make(chan struct{})}
+	close(arm.release)
+	armServer := httptest.NewServer(arm)
+	defer armServer.Close()
+
+	configFile := filepath.Join(t.TempDir(), "azure.json")
+	data := writeCloudConfigFile(t, configFile, "old", "westus")
+	az := newCloudWithFakeEndpoints(t, configFile, data, sts, armServer)
+
+	// the malformed file should be retried without touching the credentials
+	assert.NoError(t, ioutil.WriteFile(configFile, []byte("{"), 0600))
+	assert.Error(t, az.reloadConfigFile())
+	assert.Equal(t, sha256.Sum256(data), az.configFileReloader.checksum)
+
+	// the non-credential changes should not rotate the credentials
+	writeCloudConfigFile(t, configFile, "old", "eastus")
+	assert.NoError(t, az.reloadConfigFile())
+	assert.Equal(t, "eastus", az.configFileReloader.config.Location)
+	assert.Equal(t, "westus", az.Config.Location)
+
+	_, rerr := az.LoadBalancerClient.Get(context.Background(), "rg", "lb", "")
+	assert.Nil(t, rerr)
+	assert.Equal(t, []string{"Bearer token-old"}, arm.authorizations)
+}
+
+func TestReloadConfigFileReloadsRateLimitsAndBackoff(t *testing.T) {
+	sts := httptest.NewServer(http.HandlerFunc(fakeSTSHandler))
+	defer sts.Close()
+	arm := &fakeARM{received: make(chan struct{}), release: make(chan struct{})}
+	close(arm.release)
+	armServer := httptest.NewServer(arm)
+	defer armServer.Close()
+
+	configFile := filepath.Join(t.TempDir(), "azure.json")
+	data := writeCloudConfigFile(t, configFile, "old", "westus")
+	az := newCloudWithFakeEndpoints(t, configFile, data, sts, armServer)
+	for i := 0; i < 3; i++ {
+		_, rerr := az.LoadBalancerClient.Get(context.Background(), "rg", "lb", "")
+		assert.Nil(t, rerr)
+	}
+	assert.Equal(t, 1, az.RequestBackoff().Steps)
+
+	assert.NoError(t, ioutil.WriteFile(configFile, []byte(`{"tenantId":"tenant","aadClientId":"client","aadClientSecret":"old","subscriptionId":"subscription","resourceGroup":"rg","location":"westus",`+
+		`"cloudProviderRateLimit":true,"cloudProviderRateLimitQPS":0.001,"cloudProviderRateLimitBucket":1,"cloudProviderBackoff":true,"cloudProviderBackoffRetries":4}`), 0600))
+	assert.NoError(t, az.reloadConfigFile())
+
+	// the new requests should be limited by the new rate limits
+	_, rerr := az.LoadBalancerClient.Get(context.Background(), "rg", "lb", "")
+	assert.Nil(t, rerr)
+	_, rerr = az.LoadBalancerClient.Get(context.Background(), "rg", "lb", "")
+	assert.Equal(t, retry.GetRateLimitError(false, "LBGet"), rerr)
+	assert.Equal(t, float32(0.001), az.getRateLimitConfig().LoadBalancerRateLimit.CloudProviderRateLimitQPS)
+
+	// the new requests should be retried with the new backoff
+	assert.True(t, az.CloudProviderBackoff)
+	assert.Equal(t, 4, az.RequestBackoff().Steps)
+
+	// the credentials should not be rotated, and the config loaded from the file should not be defaulted
+	assert.Equal(t, []string{"Bearer token-old", "Bearer token-old", "Bearer token-old", "Bearer token-old"}, arm.authorizations)
+	assert.Nil(t, az.configFileReloader.config.LoadBalancerRateLimit)
+}
+
+func TestRateLimitedClients(t *testing.T) {
+	az := &Cloud{}
+	names := sets.NewString()
+	for _, c := range az.rateLimitedClients(&CloudProviderRateLimitConfig{}) {
+		assert.False(t, names.Has(c.name), "duplicate rate limit config %s", c.name)
+		names.Insert(c.name)
+	}
+	assert.True(t, configFieldNames(reflect.TypeOf(CloudProviderRateLimitConfig{})).IsSuperset(names))
+}
+
+func TestFormatRateLimitConfig(t *testing.T) {
+	assert.Equal(t, "disabled", formatRateLimitConfig(nil))
+	assert.Equal(t, "disabled", formatRateLimitConfig(&azclients.RateLimitConfig{CloudProviderRateLimitQPS: 1}))
+	assert.Equal(t, "{read QPS=1.5, bucket=5; write QPS=1, bucket=10}", formatRateLimitConfig(&azclients.RateLimitConfig{
+		CloudProviderRateLimit:            true,
+		CloudProviderRateLimitQPS:         1.5,
+		CloudProviderRateLimitBucket:      5,
+		CloudProviderRateLimitQPSWrite:    1,
+		CloudProviderRateLimitBucketWrite: 10,
+	}))
+}
+
+func TestConfigFieldNames(t *testing.T) {
+	names := configFieldNames(reflect.TypeOf(CloudProviderRateLimitConfig{}))
+	assert.True(t, names.HasAll("cloudProviderRateLimit", "cloudProviderRateLimitQPSWrite", "loadBalancerRateLimit", "attachDetachDiskRateLimit"))
+	assert.False(t, names.Has("RateLimitConfig"))
+}
+
+func TestDiffConfigFields(t *testing.T) {
+	oldConfig := &Config{}
+	oldConfig.AADClientSecret = "old"
+	oldConfig.Location = "westus"
+	oldConfig.CacheTTLs = map[string]int{lbCacheName: 60}
+
+	newConfig := &Config{}
+	newConfig.AADClientSecret = "new"
+	newConfig.Location = "westus"
+	newConfig.CacheTTLs = map[string]int{lbCacheName: 120}
+	newConfig.LoadBalancerSku = "standard"
+
+	assert.Equal(t, []string{"aadClientSecret", "loadBalancerSku", "cacheTTLs"}, diffConfigFields(oldConfig, newConfig))
+	assert.Empty(t, diffConfigFields(oldConfig, oldConfig))
+}
@@ -33,14 +33,15 @@ import (
 	fakeclient "k8s.io/client-go/kubernetes/fake"
 
 	"sigs.k8s.io/cloud-provider-azure/pkg/auth"
+	azclients "sigs.k8s.io/cloud-provider-azure/pkg/azureclients"
 	"sigs.k8s.io/yaml"
 )
 
 func getTestConfig() *Config {
 	return &Config{
 		AzureAuthConfig: auth.AzureAuthConfig{
-			TenantID:        "TenantID",
-			SubscriptionID:  "SubscriptionID",
+			TenantID:        "00000000-0000-0000-0000-000000000001",
+			SubscriptionID:  "00000000-0000-0000-0000-000000000002",
 			AADClientID:     "AADClientID",
 			AADClientSecret: "AADClientSecret",
 		},
@@ -52,7 +53,7 @@ func getTestConfig() *Config {
 		VnetName:                    "VnetName",
 		PrimaryAvailabilitySetName:  "PrimaryAvailabilitySetName",
 		PrimaryScaleSetName:         "PrimaryScaleSetName",
-		LoadBalancerSku:             "LoadBalancerSku",
+		LoadBalancerSku:             "standard",
 		ExcludeMasterFromStandardLB: to.BoolPtr(true),
 	}
 }
@@ -60,8 +61,8 @@ func getTestConfig() *Config {
 func getTestCloudConfigTypeSecretConfig() *Config {
 	return &Config{
 		AzureAuthConfig: auth.AzureAuthConfig{
-			TenantID:       "TenantID",
-			SubscriptionID: "SubscriptionID",
+			TenantID:       "00000000-0000-0000-0000-000000000001",
+			SubscriptionID: "00000000-0000-0000-0000-000000000002",
 		},
 		ResourceGroup:           "ResourceGroup",
 		RouteTableName:          "RouteTableName",
@@ -74,8 +75,8 @@ func getTestCloudConfigTypeSecretConfig() *Config {
 func getTestCloudConfigTypeMergeConfig() *Config {
 	return &Config{
 		AzureAuthConfig: auth.AzureAuthConfig{
-			TenantID:       "TenantID",
-			SubscriptionID: "SubscriptionID",
+			TenantID:       "00000000-0000-0000-0000-000000000001",
+			SubscriptionID: "00000000-0000-0000-0000-000000000002",
 		},
 		ResourceGroup:           "ResourceGroup",
 		RouteTableName:          "RouteTableName",
@@ -321,3 +322,147 @@ func TestConfigSecretMetadata(t *testing.T) {
 		})
 	}
 }
+
+func TestMergeConfig(t *testing.T) {
+	base := getTestConfig()
+	base.TagsMap = map[string]string{"a": "1", "b": "2"}
+	base.CloudProviderRateLimit = true
+	base.CloudProviderRateLimitQPS = 10
+	base.LoadBalancerRateLimit = &azclients.RateLimitConfig{
+		CloudProviderRateLimit:    true,
+		CloudProviderRateLimitQPS: 5,
+	}
+	base.MaximumLoadBalancerRuleCount = 250
+
+	secret := `
+resourceGroup: SecretResourceGroup
+location: ""
+vnetName: <empty>
+aadClientSecret: SecretAADClientSecret
+loadBalancerRateLimit:
+  cloudProviderRateLimitBucket: 20
+tagsMap:
+  b: "3"
+  c: "4"
+cloudProviderRateLimitQPSWrite: 1000000
+AADCLIENTID: SecretAADClientID
+`
+	config, err := mergeConfig(base, []byte(secret))
+	assert.NoError(t, err)
+
+	// the secret overrides the file values field by field
+	assert.Equal(t, "SecretResourceGroup", config.ResourceGroup)
+	assert.Equal(t, "SecretAADClientSecret", config.AADClientSecret)
+	assert.Equal(t, "SecretAADClientID", config.AADClientID)
+	assert.Equal(t, float32(1000000), config.CloudProviderRateLimitQPSWrite)
+	assert.Equal(t, float32(10), config.CloudProviderRateLimitQPS)
+	assert.Equal(t, 250, config.MaximumLoadBalancerRuleCount)
+	assert.Equal(t, to.BoolPtr(true), config.ExcludeMasterFromStandardLB)
+	// the empty strings do not erase the file values unless the marker is used
+	assert.Equal(t, "Location", config.Location)
+	assert.Empty(t, config.VnetName)
+	// the nested objects are merged
+	assert.Equal(t, &azclients.RateLimitConfig{
+		CloudProviderRateLimit:       true,
+		CloudProviderRateLimitQPS:    5,
+		CloudProviderRateLimitBucket: 20,
+	}, config.LoadBalancerRateLimit)
+	assert.Equal(t, map[string]string{"a": "1", "b": "3", "c": "4"}, config.TagsMap)
+
+	// the base config is not changed
+	assert.Equal(t, "ResourceGroup", base.ResourceGroup)
+	assert.Equal(t, "VnetName", base.VnetName)
+	assert.Equal(t, 0, base.LoadBalancerRateLimit.CloudProviderRateLimitBucket)
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, base.TagsMap)
+
+	_, err = mergeConfig(base, []byte("resourceGroup: ["))
+	assert.Error(t, err)
+}
+
+func TestApplyConfigEnvOverrides(t *testing.T) {
+	env := map[string]string{
+		"CLOUD_PROVIDER_AZURE_RESOURCE_GROUP":                   "EnvResourceGroup",
+		"CLOUD_PROVIDER_AZURE_LOCATION":                         "EnvLocation",
+		"CLOUD_PROVIDER_AZURE_USE_INSTANCE_METADATA":            "true",
+		"CLOUD_PROVIDER_AZURE_MAXIMUM_LOAD_BALANCER_RULE_COUNT": "100",
+		"CLOUD_PROVIDER_AZURE_CLOUD_PROVIDER_RATE_LIMIT_QPS":    "2.5",
+		"CLOUD_PROVIDER_AZURE_DISABLE_OUTBOUND_SNAT":            "true",
+		"CLOUD_PROVIDER_AZURE_AAD_CLIENT_SECRET":                "EnvAADClientSecret",
+		// the generic variables set by other tools should not override the cloud config
+		"AZURE_TENANT_ID":       "AzureTenantID",
+		"ARM_SUBSCRIPTION_ID":   "ArmSubscriptionID",
+		"AZURE_VNET_NAME":       "AzureVnetName",
+		"ARM_SUBNET_NAME":       "ArmSubnetName",
+		"AZURE_RESOURCE_GROUP":  "AzureResourceGroup",
+		"ARM_AAD_CLIENT_SECRET": "ArmAADClientSecret",
+	}
+	lookupEnv := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	config := getTestConfig()
+	assert.NoError(t, applyConfigEnvOverrides(config, lookupEnv))
+	assert.Equal(t, "EnvResourceGroup", config.ResourceGroup)
+	assert.Equal(t, "EnvLocation", config.Location)
+	assert.True(t, config.UseInstanceMetadata)
+	assert.Equal(t, 100, config.MaximumLoadBalancerRuleCount)
+	assert.Equal(t, float32(2.5), config.CloudProviderRateLimitQPS)
+	assert.Equal(t, to.BoolPtr(true), config.DisableOutboundSNAT)
+	assert.Equal(t, "EnvAADClientSecret", config.AADClientSecret)
+	assert.Equal(t, getTestConfig().TenantID, config.TenantID)
+	assert.Equal(t, getTestConfig().SubscriptionID, config.SubscriptionID)
+	assert.Equal(t, "VnetName", config.VnetName)
+	assert.Equal(t, getTestConfig().SubnetName, config.SubnetName)
+
+	env["CLOUD_PROVIDER_AZURE_USE_INSTANCE_METADATA"] = "yes"
+	err := applyConfigEnvOverrides(getTestConfig(), lookupEnv)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "CLOUD_PROVIDER_AZURE_USE_INSTANCE_METADATA")
+}
+
+func TestGetConfigFromSecretWithEnvOverrides(t *testing.T) {
+	t.Setenv("CLOUD_PROVIDER_AZURE_LOCATION", "EnvLocation")
+
+	az := &Cloud{
+		KubeClient: fakeclient.NewSimpleClientset(&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "azure-cloud-provider", Namespace: "kube-system"},
+			Data:       map[string][]byte{"cloud-config": []byte(`{"resourceGroup":"SecretResourceGroup","location":"SecretLocation"}`)},
+		}),
+		InitSecretConfig: InitSecretConfig{
+			SecretName:      "azure-cloud-provider",
+			SecretNamespace: "kube-system",
+			CloudConfigKey:  "cloud-config",
+		},
+		Config: *getTestCloudConfigTypeMergeConfig(),
+	}
+
+	config, err := az.GetConfigFromSecret()
+	assert.NoError(t, err)
+	assert.Equal(t, "SecretResourceGroup", config.ResourceGroup)
+	assert.Equal(t, "EnvLocation", config.Location)
+	assert.Equal(t, "RouteTableName", config.RouteTableName)
+}
+
+func TestToUpperSnakeCase(t *testing.T) {
+	for name, expected := range map[string]string{
+		"resourceGroup":             "RESOURCE_GROUP",
+		"aadClientId":               "AAD_CLIENT_ID",
+		"networkResourceTenantID":   "NETWORK_RESOURCE_TENANT_ID",
+		"cloudProviderRateLimitQPS": "CLOUD_PROVIDER_RATE_LIMIT_QPS",
+		"vmType":                    "VM_TYPE",
+	} {
+		assert.Equal(t, expected, toUpperSnakeCase(name))
+	}
+}
+
+func TestRedactedConfig(t *testing.T) {
+	config := getTestConfig()
+	config.AADClientCertPassword = "password"
+	redacted := redactedConfig(config)
+	assert.NotContains(t, redacted, "AADClientSecret")
+	assert.NotContains(t, redacted, `"password"`)
+	assert.Contains(t, redacted, `"aadClientSecret":"REDACTED"`)
+	assert.Contains(t, redacted, `"aadClientCertPassword":"REDACTED"`)
+	assert.Contains(t, redacted, `"resourceGroup":"ResourceGroup"`)
+}
@@ -0,0 +1,212 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	azclients "sigs.k8s.io/cloud-provider-azure/pkg/azureclients"
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+)
+
+var guidRE = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// ConfigError is a problem of a field in the cloud config.
+type ConfigError struct {
+	// Field is the name of the field in azure.json.
+	Field string
+	// Message describes the problem.
+	Message string
+}
+
+// Error implements the error interface.
+func (e *ConfigError) Error() string {
+	return e.Message
+}
+
+func newConfigError(field, format string, args ...interface{}) error {
+	return &ConfigError{Field: field, Message: fmt.Sprintf(format, args...)}
+}
+
+// Validate checks the cloud config before the defaults are applied, and returns a utilerrors.Aggregate of
+// ConfigErrors listing all the problems found, or nil if the config is valid.
+func (config *Config) Validate() error {
+	var errs []error
+
+	ids := []struct{ field, value string }{
+		{"subscriptionId", config.SubscriptionID},
+		{"networkResourceSubscriptionID", config.NetworkResourceSubscriptionID},
+	}
+	// The tenant IDs are overridden by ADFS on Azure Stack
+	if !strings.EqualFold(config.IdentitySystem, consts.ADFSIdentitySystem) {
+		ids = append(ids, []struct{ field, value string }{
+			{"tenantId", config.TenantID},
+			{"networkResourceTenantID", config.NetworkResourceTenantID},
+		}...)
+	}
+	for _, id := range ids {
+		if id.value != "" && !guidRE.MatchString(id.value) {
+			errs = append(errs, newConfigError(id.field, "%s %s is invalid, it should be a GUID", id.field, id.value))
+		}
+	}
+
+	if config.VMType != "" && !strings.EqualFold(config.VMType, consts.VMTypeStandard) && !strings.EqualFold(config.VMType, consts.VMTypeVMSS) {
+		errs = append(errs, newConfigError("vmType", "vmType %s is not supported, supported values are %s and %s", config.VMType, consts.VMTypeStandard, consts.VMTypeVMSS))
+	}
+	if config.DisableAvailabilitySetNodes && !strings.EqualFold(config.VMType, consts.VMTypeVMSS) {
+		errs = append(errs, newConfigError("disableAvailabilitySetNodes", "disableAvailabilitySetNodes %v is only supported when vmType is 'vmss'", config.DisableAvailabilitySetNodes))
+	}
+
+	errs = append(errs, config.validateLoadBalancerConfig()...)
+
+	if config.PrimaryIPFamily != "" &&
+		!strings.EqualFold(config.PrimaryIPFamily, string(v1.IPv4Protocol)) &&
+		!strings.EqualFold(config.PrimaryIPFamily, string(v1.IPv6Protocol)) {
+		errs = append(errs, newConfigError("primaryIPFamily", "primaryIPFamily %s is not supported, supported values are %s and %s", config.PrimaryIPFamily, v1.IPv4Protocol, v1.IPv6Protocol))
+	}
+
+	if config.DiskLunStartIndex < 0 || config.DiskLunStartIndex >= maxLUN {
+		errs = append(errs, newConfigError("diskLunStartIndex", "diskLunStartIndex %d is invalid, it should be in the range [0, %d)", config.DiskLunStartIndex, maxLUN))
+	}
+
+	if config.DiskOpBatchWindowInMilliseconds != nil && *config.DiskOpBatchWindowInMilliseconds < 0 {
+		errs = append(errs, newConfigError("diskOpBatchWindowInMilliseconds", "diskOpBatchWindowInMilliseconds %d is invalid, it should not be negative", *config.DiskOpBatchWindowInMilliseconds))
+	}
+
+	if config.ArmRateLimitRemainingWarningThreshold < 0 {
+		errs = append(errs, newConfigError("armRateLimitRemainingWarningThreshold", "armRateLimitRemainingWarningThreshold %d is invalid, it should not be negative", config.ArmRateLimitRemainingWarningThreshold))
+	}
+
+	if err := validateCacheTTLs(config.CacheTTLs); err != nil {
+		errs = append(errs, newConfigError("cacheTTLs", "%s", err.Error()))
+	}
+
+	if config.CloudConfigType != "" {
+		supportedCloudConfigTypes := sets.NewString(
+			string(cloudConfigTypeMerge),
+			string(cloudConfigTypeFile),
+			string(cloudConfigTypeSecret))
+		if !supportedCloudConfigTypes.Has(string(config.CloudConfigType)) {
+			errs = append(errs, newConfigError("cloudConfigType", "cloudConfigType %v is not supported, supported values are %v", config.CloudConfigType, supportedCloudConfigTypes.List()))
+		}
+	}
+
+	errs = append(errs, validateRateLimitConfigs(&config.CloudProviderRateLimitConfig)...)
+
+	if !config.UseManagedIdentityExtension && config.UserAssignedIdentityID != "" {
+		errs = append(errs, newConfigError("userAssignedIdentityID", "userAssignedIdentityID %s is only used by the managed identity, but useManagedIdentityExtension is false", config.UserAssignedIdentityID))
+	}
+
+	// The network resources in another tenant are authorized by the auxiliary token of that tenant, which could
+	// only be acquired by service principals, and which is only attached to the requests to networkResourceSubscriptionID.
+	if config.NetworkResourceTenantID != "" && !strings.EqualFold(config.NetworkResourceTenantID, config.TenantID) {
+		if config.UseManagedIdentityExtension {
+			errs = append(errs, newConfigError("useManagedIdentityExtension", "useManagedIdentityExtension is not supported when networkResourceTenantID %s is different from tenantId", config.NetworkResourceTenantID))
+		}
+		if config.NetworkResourceSubscriptionID == "" || strings.EqualFold(config.NetworkResourceSubscriptionID, config.SubscriptionID) {
+			errs = append(errs, newConfigError("networkResourceSubscriptionID", "networkResourceSubscriptionID should be set to a subscription other than subscriptionId when networkResourceTenantID %s is different from tenantId", config.NetworkResourceTenantID))
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// validateLoadBalancerConfig checks the SKU of the load balancers and the configs depending on it.
+func (config *Config) validateLoadBalancerConfig() []error {
+	var errs []error
+
+	isStandardSku := strings.EqualFold(config.LoadBalancerSku, consts.LoadBalancerSkuStandard)
+	if config.LoadBalancerSku != "" && !isStandardSku && !strings.EqualFold(config.LoadBalancerSku, consts.LoadBalancerSkuBasic) {
+		errs = append(errs, newConfigError("loadBalancerSku", "loadBalancerSku %s is not supported, supported values are %s and %s", config.LoadBalancerSku, consts.LoadBalancerSkuBasic, consts.LoadBalancerSkuStandard))
+	}
+	if !isStandardSku {
+		if config.ExcludeMasterFromStandardLB != nil {
+			errs = append(errs, newConfigError("excludeMasterFromStandardLB", "excludeMasterFromStandardLB should only be set when loadBalancerSku is standard"))
+		}
+		if config.DisableOutboundSNAT != nil && *config.DisableOutboundSNAT {
+			errs = append(errs, newConfigError("disableOutboundSNAT", "disableOutboundSNAT should only set when loadBalancerSku is standard"))
+		}
+		if config.EnableMultipleStandardLoadBalancers {
+			errs = append(errs, newConfigError("enableMultipleStandardLoadBalancers", "enableMultipleStandardLoadBalancers should only be set when loadBalancerSku is standard"))
+		}
+	}
+	if config.NodePoolsWithoutDedicatedSLB != "" && !config.EnableMultipleStandardLoadBalancers {
+		errs = append(errs, newConfigError("nodePoolsWithoutDedicatedSLB", "nodePoolsWithoutDedicatedSLB is only used when enableMultipleStandardLoadBalancers is true"))
+	}
+	if config.MaximumLoadBalancerRuleCount < 0 {
+		errs = append(errs, newConfigError("maximumLoadBalancerRuleCount", "maximumLoadBalancerRuleCount %d is invalid, it should not be negative", config.MaximumLoadBalancerRuleCount))
+	}
+
+	if config.LoadBalancerBackendPoolConfigurationType != "" {
+		supportedLoadBalancerBackendPoolConfigurationTypes := sets.NewString(
+			strings.ToLower(consts.LoadBalancerBackendPoolConfigurationTypeNodeIPConfiguration),
+			strings.ToLower(consts.LoadBalancerBackendPoolConfigurationTypeNodeIP),
+			strings.ToLower(consts.LoadBalancerBackendPoolConfigurationTypePODIP))
+		if !supportedLoadBalancerBackendPoolConfigurationTypes.Has(strings.ToLower(config.LoadBalancerBackendPoolConfigurationType)) {
+			errs = append(errs, newConfigError("loadBalancerBackendPoolConfigurationType", "loadBalancerBackendPoolConfigurationType %s is not supported, supported values are %v", config.LoadBalancerBackendPoolConfigurationType, supportedLoadBalancerBackendPoolConfigurationTypes.List()))
+		}
+	}
+	return errs
+}
+
+// validateRateLimitConfigs checks the default rate limit config and the rate limit configs of the clients, the QPS
+// and the buckets of the enabled ones should not be negative. The zero values are replaced by the defaults.
+func validateRateLimitConfigs(config *CloudProviderRateLimitConfig) []error {
+	errs := validateRateLimitConfig("cloudProviderRateLimit", &config.RateLimitConfig)
+
+	value := reflect.ValueOf(config).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		clientConfig, ok := value.Field(i).Interface().(*azclients.RateLimitConfig)
+		if !ok || clientConfig == nil {
+			continue
+		}
+		field := strings.Split(value.Type().Field(i).Tag.Get("json"), ",")[0]
+		errs = append(errs, validateRateLimitConfig(field, clientConfig)...)
+	}
+	return errs
+}
+
+func validateRateLimitConfig(field string, config *azclients.RateLimitConfig) []error {
+	if !config.CloudProviderRateLimit {
+		return nil
+	}
+
+	var errs []error
+	for _, limit := range []struct {
+		name   string
+		qps    float32
+		bucket int
+	}{
+		{"cloudProviderRateLimitQPS", config.CloudProviderRateLimitQPS, config.CloudProviderRateLimitBucket},
+		{"cloudProviderRateLimitQPSWrite", config.CloudProviderRateLimitQPSWrite, config.CloudProviderRateLimitBucketWrite},
+	} {
+		if limit.qps < 0 {
+			errs = append(errs, newConfigError(field, "%s: %s %v is invalid, it should not be negative", field, limit.name, limit.qps))
+		}
+		if limit.bucket < 0 {
+			errs = append(errs, newConfigError(field, "%s: the bucket %d of %s is invalid, it should not be negative", field, limit.bucket, limit.name))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,234 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/stretchr/testify/assert"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	azclients "sigs.k8s.io/cloud-provider-azure/pkg/azureclients"
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+)
+
+func TestConfigValidate(t *testing.T) {
+	for _, tc := range []struct {
+		description    string
+		mutate         func(config *Config)
+		expectedFields []string
+	}{
+		{
+			description: "valid config",
+			mutate:      func(config *Config) {},
+		},
+		{
+			description:    "invalid subscription ID",
+			mutate:         func(config *Config) { config.SubscriptionID = "subscription" },
+			expectedFields: []string{"subscriptionId"},
+		},
+		{
+			description:    "invalid tenant IDs",
+			mutate:         func(config *Config) { config.TenantID = "tenant"; config.NetworkResourceTenantID = "tenant" },
+			expectedFields: []string{"tenantId", "networkResourceTenantID"},
+		},
+		{
+			description: "tenant ID overridden by ADFS",
+			mutate:      func(config *Config) { config.TenantID = "adfs"; config.IdentitySystem = consts.ADFSIdentitySystem },
+		},
+		{
+			description:    "invalid network resource subscription ID",
+			mutate:         func(config *Config) { config.NetworkResourceSubscriptionID = "subscription" },
+			expectedFields: []string{"networkResourceSubscriptionID"},
+		},
+		{
+			description:    "unsupported vmType",
+			mutate:         func(config *Config) { config.VMType = "vmssflex" },
+			expectedFields: []string{"vmType"},
+		},
+		{
+			description:    "disableAvailabilitySetNodes with standard vmType",
+			mutate:         func(config *Config) { config.DisableAvailabilitySetNodes = true },
+			expectedFields: []string{"disableAvailabilitySetNodes"},
+		},
+		{
+			description:    "unsupported loadBalancerSku",
+			mutate:         func(config *Config) { config.LoadBalancerSku = "premium" },
+			expectedFields: []string{"loadBalancerSku", "excludeMasterFromStandardLB"},
+		},
+		{
+			description: "excludeMasterFromStandardLB with basic load balancers",
+			mutate: func(config *Config) {
+				config.LoadBalancerSku = consts.LoadBalancerSkuBasic
+				config.ExcludeMasterFromStandardLB = to.BoolPtr(false)
+			},
+			expectedFields: []string{"excludeMasterFromStandardLB"},
+		},
+		{
+			description: "standard load balancer configs with basic load balancers",
+			mutate: func(config *Config) {
+				config.LoadBalancerSku = ""
+				config.ExcludeMasterFromStandardLB = nil
+				config.DisableOutboundSNAT = to.BoolPtr(true)
+				config.EnableMultipleStandardLoadBalancers = true
+			},
+			expectedFields: []string{"disableOutboundSNAT", "enableMultipleStandardLoadBalancers"},
+		},
+		{
+			description:    "nodePoolsWithoutDedicatedSLB without multiple standard load balancers",
+			mutate:         func(config *Config) { config.NodePoolsWithoutDedicatedSLB = "pool1" },
+			expectedFields: []string{"nodePoolsWithoutDedicatedSLB"},
+		},
+		{
+			description:    "negative maximumLoadBalancerRuleCount",
+			mutate:         func(config *Config) { config.MaximumLoadBalancerRuleCount = -1 },
+			expectedFields: []string{"maximumLoadBalancerRuleCount"},
+		},
+		{
+			description:    "unsupported loadBalancerBackendPoolConfigurationType",
+			mutate:         func(config *Config) { config.LoadBalancerBackendPoolConfigurationType = "invalid" },
+			expectedFields: []string{"loadBalancerBackendPoolConfigurationType"},
+		},
+		{
+			description:    "unsupported primaryIPFamily",
+			mutate:         func(config *Config) { config.PrimaryIPFamily = "IPv5" },
+			expectedFields: []string{"primaryIPFamily"},
+		},
+		{
+			description:    "invalid diskLunStartIndex",
+			mutate:         func(config *Config) { config.DiskLunStartIndex = maxLUN },
+			expectedFields: []string{"diskLunStartIndex"},
+		},
+		{
+			description:    "negative diskOpBatchWindowInMilliseconds",
+			mutate:         func(config *Config) { config.DiskOpBatchWindowInMilliseconds = to.IntPtr(-1) },
+			expectedFields: []string{"diskOpBatchWindowInMilliseconds"},
+		},
+		{
+			description:    "negative armRateLimitRemainingWarningThreshold",
+			mutate:         func(config *Config) { config.ArmRateLimitRemainingWarningThreshold = -1 },
+			expectedFields: []string{"armRateLimitRemainingWarningThreshold"},
+		},
+		{
+			description:    "unsupported cache",
+			mutate:         func(config *Config) { config.CacheTTLs = map[string]int{"unknown": 60} },
+			expectedFields: []string{"cacheTTLs"},
+		},
+		{
+			description:    "unsupported cloudConfigType",
+			mutate:         func(config *Config) { config.CloudConfigType = "unknown" },
+			expectedFields: []string{"cloudConfigType"},
+		},
+		{
+			description: "negative default rate limits",
+			mutate: func(config *Config) {
+				config.CloudProviderRateLimit = true
+				config.CloudProviderRateLimitQPS = -1
+				config.CloudProviderRateLimitBucketWrite = -1
+			},
+			expectedFields: []string{"cloudProviderRateLimit", "cloudProviderRateLimit"},
+		},
+		{
+			description: "negative rate limits of a client",
+			mutate: func(config *Config) {
+				config.LoadBalancerRateLimit = &azclients.RateLimitConfig{CloudProviderRateLimit: true, CloudProviderRateLimitBucket: -1}
+			},
+			expectedFields: []string{"loadBalancerRateLimit"},
+		},
+		{
+			description: "negative rate limits of a disabled client",
+			mutate: func(config *Config) {
+				config.LoadBalancerRateLimit = &azclients.RateLimitConfig{CloudProviderRateLimitBucket: -1}
+			},
+		},
+		{
+			description:    "userAssignedIdentityID without managed identity",
+			mutate:         func(config *Config) { config.UserAssignedIdentityID = "00000000-0000-0000-0000-000000000003" },
+			expectedFields: []string{"userAssignedIdentityID"},
+		},
+		{
+			description: "managed identity with network resources in another tenant",
+			mutate: func(config *Config) {
+				config.UseManagedIdentityExtension = true
+				config.NetworkResourceTenantID = "00000000-0000-0000-0000-000000000003"
+				config.NetworkResourceSubscriptionID = "00000000-0000-0000-0000-000000000004"
+			},
+			expectedFields: []string{"useManagedIdentityExtension"},
+		},
+		{
+			description: "network resources in another tenant",
+			mutate: func(config *Config) {
+				config.NetworkResourceTenantID = "00000000-0000-0000-0000-000000000003"
+				config.NetworkResourceSubscriptionID = "00000000-0000-0000-0000-000000000004"
+			},
+		},
+		{
+			description:    "network resource tenant without network resource subscription",
+			mutate:         func(config *Config) { config.NetworkResourceTenantID = "00000000-0000-0000-0000-000000000003" },
+			expectedFields: []string{"networkResourceSubscriptionID"},
+		},
+		{
+			description: "network resource tenant with the same subscription",
+			mutate: func(config *Config) {
+				config.NetworkResourceTenantID = "00000000-0000-0000-0000-000000000003"
+				config.NetworkResourceSubscriptionID = config.SubscriptionID
+			},
+			expectedFields: []string{"networkResourceSubscriptionID"},
+		},
+		{
+			description: "network resource subscription in the same tenant",
+			mutate:      func(config *Config) { config.NetworkResourceSubscriptionID = "00000000-0000-0000-0000-000000000004" },
+		},
+		{
+			description: "multiple problems",
+			mutate: func(config *Config) {
+				config.SubscriptionID = "subscription"
+				config.VMType = "unknown"
+				config.LoadBalancerSku = "unknown"
+			},
+			expectedFields: []string{"subscriptionId", "vmType", "loadBalancerSku", "excludeMasterFromStandardLB"},
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			config := getTestConfig()
+			tc.mutate(config)
+
+			err := config.Validate()
+			if len(tc.expectedFields) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			agg, ok := err.(utilerrors.Aggregate)
+			assert.True(t, ok, "the error should be an aggregate")
+			if !ok {
+				return
+			}
+			var fields []string
+			for _, err := range agg.Errors() {
+				configErr, ok := err.(*ConfigError)
+				assert.True(t, ok, "the error %v should be a ConfigError", err)
+				if ok {
+					fields = append(fields, configErr.Field)
+				}
+			}
+			assert.Equal(t, tc.expectedFields, fields)
+		})
+	}
+}
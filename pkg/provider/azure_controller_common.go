@@ -23,13 +23,16 @@ import (
 	"net/http"
 	"path"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2021-07-01/compute"
+	"github.com/Azure/go-autorest/autorest/azure"
 
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	kwait "k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/util/flowcontrol"
 	cloudprovider "k8s.io/cloud-provider"
@@ -53,6 +56,15 @@ const (
 	sourceVolume           = "volume"
 	attachDiskMapKeySuffix = "attachdiskmap"
 	detachDiskMapKeySuffix = "detachdiskmap"
+	sharedDiskMapKeySuffix = "shareddiskmap"
+
+	// maxDiskLunConflictRetries is the max number of attempts to attach the disks with the next free luns
+	// when the VM update is rejected because of a lun conflict
+	maxDiskLunConflictRetries = 3
+
+	// defaultDiskOpBatchWindow is the default duration to wait for the concurrent attach/detach
+	// requests on a node to be batched into one VM update
+	defaultDiskOpBatchWindow = 200 * time.Millisecond
 
 	// WriteAcceleratorEnabled support for Azure Write Accelerator on Azure Disks
 	// https://docs.microsoft.com/azure/virtual-machines/windows/how-to-enable-write-accelerator
@@ -73,8 +85,12 @@ var defaultBackOff = kwait.Backoff{
 }
 
 var (
+	// ErrDiskMaxSharesExceeded is returned if a shared disk is attached to more VMs than its maxShares.
+	ErrDiskMaxSharesExceeded = errors.New("the shared disk is attached to the maximum number of VMs")
+
 	managedDiskPathRE  = regexp.MustCompile(`.*/subscriptions/(?:.*)/resourceGroups/(?:.*)/providers/Microsoft.Compute/disks/(.+)`)
 	diskSnapshotPathRE = regexp.MustCompile(`.*/subscriptions/(?:.*)/resourceGroups/(?:.*)/providers/Microsoft.Compute/snapshots/(.+)`)
+	diskLunConflictRE  = regexp.MustCompile(`(?i)disk at lun (\d+) already exists`)
 )
 
 type controllerCommon struct {
@@ -87,11 +103,20 @@ type controllerCommon struct {
 	lockMap               *lockMap
 	cloud                 *Cloud
 	// disk queue that is waiting for attach or detach on specific node
-	// <nodeName, map<diskURI, *AttachDiskOptions/DetachDiskOptions>>
+	// <nodeName, map<diskURI, *AttachDiskOptions/detachDiskOptions>>
 	attachDiskMap sync.Map
 	detachDiskMap sync.Map
+	// nodes which the shared disk is being attached to
+	// <diskURI, sets.String>
+	sharedDiskAttachMap sync.Map
 	// attach/detach disk rate limiter
 	diskOpRateLimiter flowcontrol.RateLimiter
+	// the duration to wait for the concurrent attach/detach requests on a node to be batched
+	diskOpBatchWindow time.Duration
+	// cache of the max data disk count of the VM sizes, keyed by the location
+	// <location, map<lower case VM size, max data disk count>>
+	vmSizeCache     *azcache.TimedCache
+	vmSizeCacheLock sync.Mutex
 }
 
 // AttachDiskOptions attach disk options
@@ -101,6 +126,15 @@ type AttachDiskOptions struct {
 	diskEncryptionSetID     string
 	writeAcceleratorEnabled bool
 	lun                     int32
+	// result is the result of the VM update attaching the disk
+	result *diskOperationResult
+}
+
+// detachDiskOptions is a detach disk request queued on a node.
+type detachDiskOptions struct {
+	diskName string
+	// result is the result of the VM update detaching the disk
+	result *diskOperationResult
 }
 
 // ExtendedLocation contains additional info about the location of resources.
@@ -149,7 +183,32 @@ func (c *controllerCommon) AttachDisk(ctx context.Context, async bool, diskName,
 	// there is possibility that disk is nil when GetDisk is throttled
 	// don't check disk state when GetDisk is throttled
 	if disk != nil {
-		if disk.ManagedBy != nil && (disk.MaxShares == nil || *disk.MaxShares <= 1) {
+		if isSharedDisk(disk) {
+			vmset, err := c.getNodeVMSet(nodeName, azcache.CacheReadTypeUnsafe)
+			if err != nil {
+				return -1, err
+			}
+			attachedNodes, err := getSharedDiskAttachedNodes(vmset, disk)
+			if err != nil {
+				return -1, err
+			}
+			if attachedNodes.Has(strings.ToLower(string(nodeName))) {
+				klog.Warningf("shared volume %s is actually attached to current node %s, invalidate vm cache and return error", diskURI, nodeName)
+				// update VM(invalidate vm cache)
+				if errUpdate := c.UpdateVM(ctx, nodeName); errUpdate != nil {
+					return -1, errUpdate
+				}
+				lun, _, err := c.GetDiskLun(diskName, diskURI, nodeName)
+				return lun, err
+			}
+
+			// the other attached nodes are legitimate holders of the shared disk, so it's not a dangling volume
+			diskuri, node := strings.ToLower(diskURI), strings.ToLower(string(nodeName))
+			if err := c.reserveSharedDiskAttach(diskuri, node, *disk.MaxShares, attachedNodes); err != nil {
+				return -1, err
+			}
+			defer c.releaseSharedDiskAttach(diskuri, node)
+		} else if disk.ManagedBy != nil {
 			vmset, err := c.getNodeVMSet(nodeName, azcache.CacheReadTypeUnsafe)
 			if err != nil {
 				return -1, err
@@ -207,10 +266,21 @@ func (c *controllerCommon) AttachDisk(ctx context.Context, async bool, diskName,
 		cachingMode:             cachingMode,
 		diskEncryptionSetID:     diskEncryptionSetID,
 		writeAcceleratorEnabled: writeAcceleratorEnabled,
+		result:                  newDiskOperationResult(),
 	}
 	node := strings.ToLower(string(nodeName))
 	diskuri := strings.ToLower(diskURI)
-	if err := c.insertAttachDiskRequest(diskuri, node, &options); err != nil {
+	queued, queueLen, err := c.insertAttachDiskRequest(diskuri, node, &options)
+	if err != nil {
+		return -1, err
+	}
+
+	// wait for the concurrent attach requests on the node to be coalesced into one VM update
+	if err := c.waitDiskOpBatchWindow(ctx, queueLen); err != nil {
+		// the request queued by another call is left to it, only the one queued by this call is withdrawn
+		if queued == &options && c.removeAttachDiskRequest(diskuri, node, queued) {
+			queued.result.complete(err)
+		}
 		return -1, err
 	}
 
@@ -227,31 +297,65 @@ func (c *controllerCommon) AttachDisk(ctx context.Context, async bool, diskName,
 		return -1, err
 	}
 
-	lun, err := c.SetDiskLun(nodeName, diskuri, diskMap)
-	if err != nil {
-		return -1, err
+	// the requests queued by the other calls are attached together, the result is reported to each of them
+	if len(diskMap) > 0 {
+		err := c.attachDiskBatch(ctx, async, nodeName, diskMap, &unlock)
+		for _, opt := range diskMap {
+			opt.result.complete(err)
+		}
+	}
+	if !unlock {
+		unlock = true
+		c.lockMap.UnlockEntry(node)
 	}
 
-	klog.V(2).Infof("Trying to attach volume %s lun %d to node %s, diskMap: %s", diskURI, lun, nodeName, diskMap)
-	if len(diskMap) == 0 {
-		return lun, nil
+	if err := queued.result.wait(ctx); err != nil {
+		return -1, err
 	}
+	return queued.lun, nil
+}
 
+// attachDiskBatch allocates the luns of the disks in diskMap and attaches them to the node in one VM update.
+// The node lock is released before waiting for the VM update if async is true, and unlock is set.
+func (c *controllerCommon) attachDiskBatch(ctx context.Context, async bool, nodeName types.NodeName, diskMap map[string]*AttachDiskOptions, unlock *bool) error {
+	var diskURI string
+	for uri := range diskMap {
+		diskURI = uri
+		break
+	}
 	vmset, err := c.getNodeVMSet(nodeName, azcache.CacheReadTypeUnsafe)
 	if err != nil {
-		return -1, err
+		return err
 	}
-	c.diskStateMap.Store(disk, "attaching")
-	defer c.diskStateMap.Delete(disk)
-	future, err := vmset.AttachDisk(ctx, nodeName, diskMap)
-	if err != nil {
-		return -1, err
+	for uri := range diskMap {
+		c.diskStateMap.Store(uri, "attaching")
+		defer c.diskStateMap.Delete(uri)
+	}
+
+	var future *azure.Future
+	conflictLuns := sets.NewInt()
+	for i := 0; ; i++ {
+		if _, err := c.setDiskLun(nodeName, diskURI, diskMap, conflictLuns); err != nil {
+			return err
+		}
+
+		klog.V(2).Infof("Trying to attach volumes to node %s, diskMap: %s", nodeName, diskMap)
+		future, err = vmset.AttachDisk(ctx, nodeName, diskMap)
+		if err == nil {
+			break
+		}
+		lun, isConflict := getConflictDiskLun(err)
+		if !isConflict || i >= maxDiskLunConflictRetries-1 {
+			return err
+		}
+		klog.Warningf("azureDisk - lun %d is already used on node %s, retry attaching volumes with the next free lun", lun, nodeName)
+		conflictLuns.Insert(int(lun))
 	}
 
 	if async && c.diskOpRateLimiter.TryAccept() {
 		// unlock and wait for attach disk complete
-		unlock = true
-		c.lockMap.UnlockEntry(node)
+		*unlock = true
+		c.lockMap.UnlockEntry(strings.ToLower(string(nodeName)))
 	} else {
 		if async {
 			klog.Warningf("azureDisk - switch to batch operation due to rate limited, QPS: %f", c.diskOpRateLimiter.QPS())
@@ -259,12 +363,118 @@ func (c *controllerCommon) AttachDisk(ctx context.Context, async bool, diskName,
 	}
 	resourceGroup, _, err := getInfoFromDiskURI(diskURI)
 	if err != nil {
-		return -1, err
+		return err
+	}
+	return vmset.WaitForUpdateResult(ctx, future, resourceGroup, "attach_disk")
+}
+
+// diskOperationResult is the result of the VM update which attaches or detaches a batch of disks.
+type diskOperationResult struct {
+	done chan struct{}
+	err  error
+}
+
+func newDiskOperationResult() *diskOperationResult {
+	return &diskOperationResult{done: make(chan struct{})}
+}
+
+// complete records the result of the VM update and wakes up the callers waiting for it.
+func (r *diskOperationResult) complete(err error) {
+	r.err = err
+	close(r.done)
+}
+
+// wait waits for the VM update carrying the disk operation to complete.
+func (r *diskOperationResult) wait(ctx context.Context) error {
+	select {
+	case <-r.done:
+		return r.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// waitDiskOpBatchWindow waits for the concurrent disk requests on a node to be queued, so that they are coalesced
+// into one VM update. The wait is skipped if the request is the only one queued on the node.
+func (c *controllerCommon) waitDiskOpBatchWindow(ctx context.Context, queueLen int) error {
+	if queueLen <= 1 || c.diskOpBatchWindow <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(c.diskOpBatchWindow)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// isSharedDisk returns true if the disk could be attached to multiple VMs.
+func isSharedDisk(disk *compute.Disk) bool {
+	return disk.DiskProperties != nil && disk.MaxShares != nil && *disk.MaxShares > 1
+}
+
+// getSharedDiskAttachedNodes returns the lower-cased names of the nodes which the shared disk is attached to.
+func getSharedDiskAttachedNodes(vmset VMSet, disk *compute.Disk) (sets.String, error) {
+	attachedNodes := sets.NewString()
+	if disk.ManagedByExtended == nil {
+		return attachedNodes, nil
+	}
+
+	for _, vmID := range *disk.ManagedByExtended {
+		attachedNode, err := vmset.GetNodeNameByProviderID(vmID)
+		if err != nil {
+			return nil, err
+		}
+		attachedNodes.Insert(strings.ToLower(string(attachedNode)))
+	}
+	return attachedNodes, nil
+}
+
+// reserveSharedDiskAttach records the node which the shared disk is being attached to. It returns
+// ErrDiskMaxSharesExceeded if the disk is attached or being attached to maxShares nodes already.
+func (c *controllerCommon) reserveSharedDiskAttach(diskURI, nodeName string, maxShares int32, attachedNodes sets.String) error {
+	sharedDiskMapKey := diskURI + sharedDiskMapKeySuffix
+	c.lockMap.LockEntry(sharedDiskMapKey)
+	defer c.lockMap.UnlockEntry(sharedDiskMapKey)
+
+	attachingNodes := sets.NewString()
+	if v, ok := c.sharedDiskAttachMap.Load(diskURI); ok {
+		attachingNodes = v.(sets.String)
 	}
-	return lun, vmset.WaitForUpdateResult(ctx, future, resourceGroup, "attach_disk")
+
+	nodes := attachedNodes.Union(attachingNodes)
+	if !nodes.Has(nodeName) && nodes.Len() >= int(maxShares) {
+		return fmt.Errorf("%w: disk(%s) with maxShares(%d) is attached or being attached to nodes %v, could not be attached to node(%s)",
+			ErrDiskMaxSharesExceeded, diskURI, maxShares, nodes.List(), nodeName)
+	}
+
+	attachingNodes.Insert(nodeName)
+	c.sharedDiskAttachMap.Store(diskURI, attachingNodes)
+	return nil
 }
 
-func (c *controllerCommon) insertAttachDiskRequest(diskURI, nodeName string, options *AttachDiskOptions) error {
+// releaseSharedDiskAttach removes the node which the shared disk has been attached to.
+func (c *controllerCommon) releaseSharedDiskAttach(diskURI, nodeName string) {
+	sharedDiskMapKey := diskURI + sharedDiskMapKeySuffix
+	c.lockMap.LockEntry(sharedDiskMapKey)
+	defer c.lockMap.UnlockEntry(sharedDiskMapKey)
+
+	v, ok := c.sharedDiskAttachMap.Load(diskURI)
+	if !ok {
+		return
+	}
+	attachingNodes := v.(sets.String)
+	attachingNodes.Delete(nodeName)
+	if attachingNodes.Len() == 0 {
+		c.sharedDiskAttachMap.Delete(diskURI)
+	}
+}
+
+// insertAttachDiskRequest queues the attach disk request on the node and returns the queued request,
+// which is the existing one if the disk is already in the queue.
+func (c *controllerCommon) insertAttachDiskRequest(diskURI, nodeName string, options *AttachDiskOptions) (*AttachDiskOptions, int, error) {
 	var diskMap map[string]*AttachDiskOptions
 	attachDiskMapKey := nodeName + attachDiskMapKeySuffix
 	c.lockMap.LockEntry(attachDiskMapKey)
@@ -272,20 +482,36 @@ func (c *controllerCommon) insertAttachDiskRequest(diskURI, nodeName string, opt
 	v, ok := c.attachDiskMap.Load(nodeName)
 	if ok {
 		if diskMap, ok = v.(map[string]*AttachDiskOptions); !ok {
-			return fmt.Errorf("convert attachDiskMap failure on node(%s)", nodeName)
+			return nil, 0, fmt.Errorf("convert attachDiskMap failure on node(%s)", nodeName)
 		}
 	} else {
 		diskMap = make(map[string]*AttachDiskOptions)
 		c.attachDiskMap.Store(nodeName, diskMap)
 	}
 	// insert attach disk request to queue
-	_, ok = diskMap[diskURI]
-	if ok {
+	if queued, ok := diskMap[diskURI]; ok {
 		klog.V(2).Infof("azureDisk - duplicated attach disk(%s) request on node(%s)", diskURI, nodeName)
-	} else {
-		diskMap[diskURI] = options
+		return queued, len(diskMap), nil
 	}
-	return nil
+	diskMap[diskURI] = options
+	return options, len(diskMap), nil
+}
+
+// removeAttachDiskRequest removes the attach disk request from the queue if it has not been taken by a VM update yet.
+func (c *controllerCommon) removeAttachDiskRequest(diskURI, nodeName string, options *AttachDiskOptions) bool {
+	attachDiskMapKey := nodeName + attachDiskMapKeySuffix
+	c.lockMap.LockEntry(attachDiskMapKey)
+	defer c.lockMap.UnlockEntry(attachDiskMapKey)
+	v, ok := c.attachDiskMap.Load(nodeName)
+	if !ok {
+		return false
+	}
+	diskMap, ok := v.(map[string]*AttachDiskOptions)
+	if !ok || diskMap[diskURI] != options {
+		return false
+	}
+	delete(diskMap, diskURI)
+	return true
 }
 
 // clean up attach disk requests
@@ -327,37 +553,38 @@ func (c *controllerCommon) DetachDisk(ctx context.Context, diskName, diskURI str
 
 	node := strings.ToLower(string(nodeName))
 	disk := strings.ToLower(diskURI)
-	if err := c.insertDetachDiskRequest(diskName, disk, node); err != nil {
+	options := &detachDiskOptions{diskName: diskName, result: newDiskOperationResult()}
+	queued, queueLen, err := c.insertDetachDiskRequest(disk, node, options)
+	if err != nil {
+		return err
+	}
+
+	// wait for the concurrent detach requests on the node to be coalesced into one VM update
+	if err := c.waitDiskOpBatchWindow(ctx, queueLen); err != nil {
+		// the request queued by another call is left to it, only the one queued by this call is withdrawn
+		if queued == options && c.removeDetachDiskRequest(disk, node, queued) {
+			queued.result.complete(err)
+		}
 		return err
 	}
 
 	c.lockMap.LockEntry(node)
-	defer c.lockMap.UnlockEntry(node)
 	diskMap, err := c.cleanDetachDiskRequests(node)
 	if err != nil {
+		c.lockMap.UnlockEntry(node)
 		return err
 	}
 
-	klog.V(2).Infof("Trying to detach volume %s from node %s, diskMap: %s", diskURI, nodeName, diskMap)
+	// the requests queued by the other calls are detached together, the result is reported to each of them
 	if len(diskMap) > 0 {
-		c.diskStateMap.Store(disk, "detaching")
-		defer c.diskStateMap.Delete(disk)
-		if err = vmset.DetachDisk(ctx, nodeName, diskMap); err != nil {
-			if isInstanceNotFoundError(err) {
-				// if host doesn't exist, no need to detach
-				klog.Warningf("azureDisk - got InstanceNotFoundError(%v), DetachDisk(%s) will assume disk is already detached",
-					err, diskURI)
-				return nil
-			}
-		}
-	} else {
-		lun, _, errGetLun := c.GetDiskLun(diskName, diskURI, nodeName)
-		if errGetLun == nil || !strings.Contains(errGetLun.Error(), consts.CannotFindDiskLUN) {
-			return fmt.Errorf("disk(%s) is still attatched to node(%s) on lun(%d), error: %v", diskURI, nodeName, lun, errGetLun)
+		err := c.detachDiskBatch(ctx, vmset, nodeName, diskMap)
+		for _, opt := range diskMap {
+			opt.result.complete(err)
 		}
 	}
+	c.lockMap.UnlockEntry(node)
 
-	if err != nil {
+	if err := queued.result.wait(ctx); err != nil {
 		klog.Errorf("azureDisk - detach disk(%s, %s) failed, err: %v", diskName, diskURI, err)
 		return err
 	}
@@ -366,6 +593,28 @@ func (c *controllerCommon) DetachDisk(ctx context.Context, diskName, diskURI str
 	return nil
 }
 
+// detachDiskBatch detaches the disks in diskMap from the node in one VM update.
+func (c *controllerCommon) detachDiskBatch(ctx context.Context, vmset VMSet, nodeName types.NodeName, diskMap map[string]*detachDiskOptions) error {
+	diskNames := make(map[string]string, len(diskMap))
+	for uri, opt := range diskMap {
+		diskNames[uri] = opt.diskName
+		c.diskStateMap.Store(uri, "detaching")
+		defer c.diskStateMap.Delete(uri)
+	}
+
+	klog.V(2).Infof("Trying to detach volumes from node %s, diskMap: %s", nodeName, diskNames)
+	if err := vmset.DetachDisk(ctx, nodeName, diskNames); err != nil {
+		if isInstanceNotFoundError(err) {
+			// if host doesn't exist, no need to detach
+			klog.Warningf("azureDisk - got InstanceNotFoundError(%v), DetachDisk(%v) will assume disks are already detached",
+				err, diskNames)
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
 // UpdateVM updates a vm
 func (c *controllerCommon) UpdateVM(ctx context.Context, nodeName types.NodeName) error {
 	vmset, err := c.getNodeVMSet(nodeName, azcache.CacheReadTypeUnsafe)
@@ -378,34 +627,52 @@ func (c *controllerCommon) UpdateVM(ctx context.Context, nodeName types.NodeName
 	return vmset.UpdateVM(ctx, nodeName)
 }
 
-func (c *controllerCommon) insertDetachDiskRequest(diskName, diskURI, nodeName string) error {
-	var diskMap map[string]string
+// insertDetachDiskRequest queues the detach disk request on the node and returns the queued request,
+// which is the existing one if the disk is already in the queue.
+func (c *controllerCommon) insertDetachDiskRequest(diskURI, nodeName string, options *detachDiskOptions) (*detachDiskOptions, int, error) {
+	var diskMap map[string]*detachDiskOptions
 	detachDiskMapKey := nodeName + detachDiskMapKeySuffix
 	c.lockMap.LockEntry(detachDiskMapKey)
 	defer c.lockMap.UnlockEntry(detachDiskMapKey)
 	v, ok := c.detachDiskMap.Load(nodeName)
 	if ok {
-		if diskMap, ok = v.(map[string]string); !ok {
-			return fmt.Errorf("convert detachDiskMap failure on node(%s)", nodeName)
+		if diskMap, ok = v.(map[string]*detachDiskOptions); !ok {
+			return nil, 0, fmt.Errorf("convert detachDiskMap failure on node(%s)", nodeName)
 		}
 	} else {
-		diskMap = make(map[string]string)
+		diskMap = make(map[string]*detachDiskOptions)
 		c.detachDiskMap.Store(nodeName, diskMap)
 	}
 	// insert detach disk request to queue
-	_, ok = diskMap[diskURI]
-	if ok {
+	if queued, ok := diskMap[diskURI]; ok {
 		klog.V(2).Infof("azureDisk - duplicated detach disk(%s) request on node(%s)", diskURI, nodeName)
-	} else {
-		diskMap[diskURI] = diskName
+		return queued, len(diskMap), nil
 	}
-	return nil
+	diskMap[diskURI] = options
+	return options, len(diskMap), nil
+}
+
+// removeDetachDiskRequest removes the detach disk request from the queue if it has not been taken by a VM update yet.
+func (c *controllerCommon) removeDetachDiskRequest(diskURI, nodeName string, options *detachDiskOptions) bool {
+	detachDiskMapKey := nodeName + detachDiskMapKeySuffix
+	c.lockMap.LockEntry(detachDiskMapKey)
+	defer c.lockMap.UnlockEntry(detachDiskMapKey)
+	v, ok := c.detachDiskMap.Load(nodeName)
+	if !ok {
+		return false
+	}
+	diskMap, ok := v.(map[string]*detachDiskOptions)
+	if !ok || diskMap[diskURI] != options {
+		return false
+	}
+	delete(diskMap, diskURI)
+	return true
 }
 
 // clean up detach disk requests
 // return original detach disk requests
-func (c *controllerCommon) cleanDetachDiskRequests(nodeName string) (map[string]string, error) {
-	var diskMap map[string]string
+func (c *controllerCommon) cleanDetachDiskRequests(nodeName string) (map[string]*detachDiskOptions, error) {
+	var diskMap map[string]*detachDiskOptions
 
 	detachDiskMapKey := nodeName + detachDiskMapKeySuffix
 	c.lockMap.LockEntry(detachDiskMapKey)
@@ -414,11 +681,11 @@ func (c *controllerCommon) cleanDetachDiskRequests(nodeName string) (map[string]
 	if !ok {
 		return diskMap, nil
 	}
-	if diskMap, ok = v.(map[string]string); !ok {
+	if diskMap, ok = v.(map[string]*detachDiskOptions); !ok {
 		return diskMap, fmt.Errorf("convert detachDiskMap failure on node(%s)", nodeName)
 	}
 	// clean up original requests in disk map
-	c.detachDiskMap.Store(nodeName, make(map[string]string))
+	c.detachDiskMap.Store(nodeName, make(map[string]*detachDiskOptions))
 	return diskMap, nil
 }
 
@@ -461,6 +728,11 @@ func (c *controllerCommon) GetDiskLun(diskName, diskURI string, nodeName types.N
 // SetDiskLun find unused luns and allocate lun for every disk in diskMap.
 // Return lun of diskURI, -1 if all luns are used.
 func (c *controllerCommon) SetDiskLun(nodeName types.NodeName, diskURI string, diskMap map[string]*AttachDiskOptions) (int32, error) {
+	return c.setDiskLun(nodeName, diskURI, diskMap, nil)
+}
+
+// setDiskLun allocates lun for every disk in diskMap, skipping the luns below DiskLunStartIndex and the excluded luns.
+func (c *controllerCommon) setDiskLun(nodeName types.NodeName, diskURI string, diskMap map[string]*AttachDiskOptions, excludedLuns sets.Int) (int32, error) {
 	disks, _, err := c.getNodeDataDisks(nodeName, azcache.CacheReadTypeDefault)
 	if err != nil {
 		klog.Errorf("error of getting data disks for node %s: %v", nodeName, err)
@@ -470,6 +742,14 @@ func (c *controllerCommon) SetDiskLun(nodeName types.NodeName, diskURI string, d
 	lun := int32(-1)
 	_, isDiskInMap := diskMap[diskURI]
 	used := make([]bool, maxLUN)
+	for i := 0; i < int(c.cloud.DiskLunStartIndex) && i < maxLUN; i++ {
+		used[i] = true
+	}
+	for lun := range excludedLuns {
+		if lun >= 0 && lun < maxLUN {
+			used[lun] = true
+		}
+	}
 	for _, disk := range disks {
 		if disk.Lun != nil {
 			used[*disk.Lun] = true
@@ -503,8 +783,8 @@ func (c *controllerCommon) SetDiskLun(nodeName types.NodeName, diskURI string, d
 	}
 
 	if len(diskLuns) != len(diskMap) {
-		return -1, fmt.Errorf("could not find enough disk luns(current: %d) for diskMap(%v, len=%d), diskURI(%s)",
-			len(diskLuns), diskMap, len(diskMap), diskURI)
+		return -1, fmt.Errorf("could not find enough disk luns(current: %d) for diskMap(%v, len=%d), diskURI(%s)%s",
+			len(diskLuns), diskMap, len(diskMap), diskURI, c.getDiskLunExhaustedDetail(nodeName, len(disks)))
 	}
 
 	count = 0
@@ -524,6 +804,100 @@ func (c *controllerCommon) SetDiskLun(nodeName types.NodeName, diskURI string, d
 	return lun, nil
 }
 
+// getDiskLunExhaustedDetail describes how many data disks the VM size of the node supports, it returns an empty
+// string if the VM size could not be determined.
+func (c *controllerCommon) getDiskLunExhaustedDetail(nodeName types.NodeName, attachedDiskCount int) string {
+	vmset, err := c.getNodeVMSet(nodeName, azcache.CacheReadTypeUnsafe)
+	if err != nil {
+		klog.Warningf("azureDisk - failed to get vmset of node %s: %v", nodeName, err)
+		return ""
+	}
+	vmSize, err := vmset.GetInstanceTypeByNodeName(string(nodeName))
+	if err != nil {
+		klog.Warningf("azureDisk - failed to get VM size of node %s: %v", nodeName, err)
+		return ""
+	}
+	maxDataDiskCount, err := c.getMaxDataDiskCount(vmSize)
+	if err != nil {
+		klog.Warningf("azureDisk - failed to get max data disk count of VM size %s: %v", vmSize, err)
+		return ""
+	}
+	return fmt.Sprintf(", node(%s) has %d data disks attached, VM size %s supports at most %d data disks, lun start index is %d",
+		nodeName, attachedDiskCount, vmSize, maxDataDiskCount, c.cloud.DiskLunStartIndex)
+}
+
+// getMaxDataDiskCount gets the max data disk count of the VM size. The VM sizes in the location are listed
+// and cached for the TTL of the vm_size cache, so that the new VM sizes are picked up after it expires.
+func (c *controllerCommon) getMaxDataDiskCount(vmSize string) (int32, error) {
+	vmSizeCache, err := c.getVMSizeCache()
+	if err != nil {
+		return -1, err
+	}
+	cached, err := vmSizeCache.Get(c.location, azcache.CacheReadTypeDefault)
+	if err != nil {
+		return -1, err
+	}
+
+	count, ok := cached.(map[string]int32)[strings.ToLower(vmSize)]
+	if !ok {
+		return -1, fmt.Errorf("VM size %s is not found in location %s", vmSize, c.location)
+	}
+	return count, nil
+}
+
+// getVMSizeCache returns the cache of the max data disk count of the VM sizes, which is created on the first call.
+func (c *controllerCommon) getVMSizeCache() (*azcache.TimedCache, error) {
+	c.vmSizeCacheLock.Lock()
+	defer c.vmSizeCacheLock.Unlock()
+	if c.vmSizeCache != nil {
+		return c.vmSizeCache, nil
+	}
+
+	getter := func(location string) (interface{}, error) {
+		ctx, cancel := getContextWithCancel()
+		defer cancel()
+		result, rerr := c.cloud.VirtualMachineSizesClient.List(ctx, location)
+		if rerr != nil {
+			return nil, rerr.Error()
+		}
+		vmSizes := make(map[string]int32)
+		if result.Value != nil {
+			for _, size := range *result.Value {
+				if size.Name != nil && size.MaxDataDiskCount != nil {
+					vmSizes[strings.ToLower(*size.Name)] = *size.MaxDataDiskCount
+				}
+			}
+		}
+		// Don't cache the empty results, which are likely transient failures.
+		if len(vmSizes) == 0 {
+			return nil, fmt.Errorf("no VM sizes are listed in location %s", location)
+		}
+		return vmSizes, nil
+	}
+	vmSizeCache, err := azcache.NewTimedcacheWithName(vmSizeCacheName, c.cloud.Config.getCacheTTL(vmSizeCacheName, vmSizeCacheTTLDefaultInSeconds), getter)
+	if err != nil {
+		return nil, err
+	}
+	c.vmSizeCache = vmSizeCache
+	return c.vmSizeCache, nil
+}
+
+// getConflictDiskLun returns the lun and true if the error is caused by a disk already attached at the lun.
+func getConflictDiskLun(err error) (int32, bool) {
+	if err == nil {
+		return -1, false
+	}
+	matches := diskLunConflictRE.FindStringSubmatch(err.Error())
+	if len(matches) != 2 {
+		return -1, false
+	}
+	lun, parseErr := strconv.ParseInt(matches[1], 10, 32)
+	if parseErr != nil {
+		return -1, false
+	}
+	return int32(lun), true
+}
+
 // DisksAreAttached checks if a list of volumes are attached to the node with the specified NodeName.
 func (c *controllerCommon) DisksAreAttached(diskNames []string, nodeName types.NodeName) (map[string]bool, error) {
 	attached := make(map[string]bool)
@@ -17,11 +17,15 @@ limitations under the License.
 package provider
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"reflect"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2021-07-01/compute"
 	"github.com/Azure/go-autorest/autorest/azure"
@@ -30,6 +34,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/util/flowcontrol"
 	cloudprovider "k8s.io/cloud-provider"
@@ -37,6 +42,7 @@ import (
 
 	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/diskclient/mockdiskclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/vmclient/mockvmclient"
+	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/vmsizeclient/mockvmsizeclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/vmssclient/mockvmssclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/vmssvmclient/mockvmssvmclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
@@ -176,6 +182,8 @@ func TestCommonAttachDisk(t *testing.T) {
 		mockVMsClient.EXPECT().Update(gomock.Any(), testCloud.ResourceGroup, gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 		mockVMsClient.EXPECT().UpdateAsync(gomock.Any(), testCloud.ResourceGroup, gomock.Any(), gomock.Any(), gomock.Any()).Return(&azure.Future{}, nil).AnyTimes()
 		mockVMsClient.EXPECT().WaitForUpdateResult(gomock.Any(), gomock.Any(), testCloud.ResourceGroup, gomock.Any()).Return(nil).AnyTimes()
+		mockVMSizesClient := testCloud.VirtualMachineSizesClient.(*mockvmsizeclient.MockInterface)
+		mockVMSizesClient.EXPECT().List(gomock.Any(), testCloud.Location).Return(compute.VirtualMachineSizeListResult{}, nil).AnyTimes()
 
 		lun, err := common.AttachDisk(ctx, true, "", diskURI, test.nodeName, compute.CachingTypesReadOnly, test.existedDisk)
 		assert.Equal(t, test.expectedLun, lun, "TestCase[%d]: %s", i, test.desc)
@@ -183,6 +191,281 @@ func TestCommonAttachDisk(t *testing.T) {
 	}
 }
 
+func TestCommonAttachDiskLunConflict(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := getContextWithCancel()
+	defer cancel()
+
+	testCloud := GetTestCloud(ctrl)
+	common := &controllerCommon{
+		location:              testCloud.Location,
+		storageEndpointSuffix: testCloud.Environment.StorageEndpointSuffix,
+		resourceGroup:         testCloud.ResourceGroup,
+		subscriptionID:        testCloud.SubscriptionID,
+		cloud:                 testCloud,
+		lockMap:               newLockMap(),
+		diskOpRateLimiter:     flowcontrol.NewTokenBucketRateLimiter(10, 20),
+	}
+	diskURI := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/disks/%s",
+		testCloud.SubscriptionID, testCloud.ResourceGroup, "disk-name")
+	expectedVMs := setTestVirtualMachines(testCloud, map[string]string{"vm1": "PowerState/Running"}, false)
+	mockVMsClient := testCloud.VirtualMachinesClient.(*mockvmclient.MockInterface)
+	mockVMsClient.EXPECT().Get(gomock.Any(), testCloud.ResourceGroup, "vm1", gomock.Any()).Return(expectedVMs[0], nil).AnyTimes()
+	gomock.InOrder(
+		mockVMsClient.EXPECT().UpdateAsync(gomock.Any(), testCloud.ResourceGroup, "vm1", gomock.Any(), gomock.Any()).Return(nil, &retry.Error{
+			HTTPStatusCode: http.StatusConflict,
+			RawError:       fmt.Errorf("A disk at LUN 3 already exists."),
+		}),
+		mockVMsClient.EXPECT().UpdateAsync(gomock.Any(), testCloud.ResourceGroup, "vm1", gomock.Any(), gomock.Any()).Return(&azure.Future{}, nil),
+	)
+	mockVMsClient.EXPECT().WaitForUpdateResult(gomock.Any(), gomock.Any(), testCloud.ResourceGroup, gomock.Any()).Return(nil)
+
+	lun, err := common.AttachDisk(ctx, true, "", diskURI, "vm1", compute.CachingTypesReadOnly, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(4), lun)
+}
+
+func TestCommonAttachSharedDisk(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := getContextWithCancel()
+	defer cancel()
+
+	testCloud := GetTestCloud(ctrl)
+	common := &controllerCommon{
+		location:              testCloud.Location,
+		storageEndpointSuffix: testCloud.Environment.StorageEndpointSuffix,
+		resourceGroup:         testCloud.ResourceGroup,
+		subscriptionID:        testCloud.SubscriptionID,
+		cloud:                 testCloud,
+		lockMap:               newLockMap(),
+		diskOpRateLimiter:     flowcontrol.NewTokenBucketRateLimiter(10, 20),
+	}
+	diskURI := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/disks/%s",
+		testCloud.SubscriptionID, testCloud.ResourceGroup, "disk-name")
+	vmID := func(name string) string {
+		return fmt.Sprintf("/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/%s", name)
+	}
+	newSharedDisk := func(attachedVMs ...string) *compute.Disk {
+		managedByExtended := make([]string, 0)
+		for _, vm := range attachedVMs {
+			managedByExtended = append(managedByExtended, vmID(vm))
+		}
+		return &compute.Disk{
+			Name:              to.StringPtr("disk-name"),
+			ManagedByExtended: &managedByExtended,
+			DiskProperties: &compute.DiskProperties{
+				MaxShares: to.Int32Ptr(2),
+				DiskState: compute.DiskStateAttached,
+			},
+		}
+	}
+
+	mockVMsClient := testCloud.VirtualMachinesClient.(*mockvmclient.MockInterface)
+	for _, node := range []string{"vm1", "vm2", "vm3"} {
+		vm := setTestVirtualMachines(testCloud, map[string]string{node: "PowerState/Running"}, false)[0]
+		mockVMsClient.EXPECT().Get(gomock.Any(), testCloud.ResourceGroup, node, gomock.Any()).Return(vm, nil).AnyTimes()
+	}
+	mockVMsClient.EXPECT().UpdateAsync(gomock.Any(), testCloud.ResourceGroup, gomock.Any(), gomock.Any(), gomock.Any()).Return(&azure.Future{}, nil).AnyTimes()
+	attaching := make(chan struct{}, 2)
+	finishAttach := make(chan struct{})
+	mockVMsClient.EXPECT().WaitForUpdateResult(gomock.Any(), gomock.Any(), testCloud.ResourceGroup, gomock.Any()).DoAndReturn(
+		func(ctx context.Context, future *azure.Future, resourceGroupName, source string) *retry.Error {
+			attaching <- struct{}{}
+			<-finishAttach
+			return nil
+		}).AnyTimes()
+
+	// the shared disk could be attached to two nodes concurrently
+	errs := make(chan error, 2)
+	for _, node := range []string{"vm1", "vm2"} {
+		go func(node string) {
+			_, err := common.AttachDisk(ctx, true, "disk-name", diskURI, types.NodeName(node), compute.CachingTypesReadOnly, newSharedDisk())
+			errs <- err
+		}(node)
+	}
+	<-attaching
+	<-attaching
+
+	// the third node exceeds maxShares while the two attaches are in progress
+	lun, err := common.AttachDisk(ctx, true, "disk-name", diskURI, "vm3", compute.CachingTypesReadOnly, newSharedDisk())
+	assert.Equal(t, int32(-1), lun)
+	assert.True(t, errors.Is(err, ErrDiskMaxSharesExceeded), "unexpected error: %v", err)
+
+	close(finishAttach)
+	assert.NoError(t, <-errs)
+	assert.NoError(t, <-errs)
+	_, ok := common.sharedDiskAttachMap.Load(strings.ToLower(diskURI))
+	assert.False(t, ok)
+
+	// the disk attached to the other nodes up to maxShares is not a dangling volume, but it's over-subscribed
+	lun, err = common.AttachDisk(ctx, true, "disk-name", diskURI, "vm3", compute.CachingTypesReadOnly, newSharedDisk("vm1", "vm2"))
+	assert.Equal(t, int32(-1), lun)
+	assert.True(t, errors.Is(err, ErrDiskMaxSharesExceeded), "unexpected error: %v", err)
+
+	// the disk attached to another node could be attached to the current node
+	go func() {
+		<-attaching
+	}()
+	_, err = common.AttachDisk(ctx, true, "disk-name", diskURI, "vm3", compute.CachingTypesReadOnly, newSharedDisk("vm1"))
+	assert.NoError(t, err)
+}
+
+func TestCommonAttachDetachDiskBatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := getContextWithCancel()
+	defer cancel()
+
+	testCloud := GetTestCloud(ctrl)
+	common := &controllerCommon{
+		location:              testCloud.Location,
+		storageEndpointSuffix: testCloud.Environment.StorageEndpointSuffix,
+		resourceGroup:         testCloud.ResourceGroup,
+		subscriptionID:        testCloud.SubscriptionID,
+		cloud:                 testCloud,
+		lockMap:               newLockMap(),
+		diskOpRateLimiter:     flowcontrol.NewTokenBucketRateLimiter(10, 20),
+		diskOpBatchWindow:     100 * time.Millisecond,
+	}
+	diskURI := func(i int) string {
+		return fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/disks/disk%d",
+			testCloud.SubscriptionID, testCloud.ResourceGroup, i)
+	}
+	const diskNum = 8
+
+	vm := setTestVirtualMachines(testCloud, map[string]string{"vm1": "PowerState/Running"}, false)[0]
+	mockVMsClient := testCloud.VirtualMachinesClient.(*mockvmclient.MockInterface)
+	mockVMsClient.EXPECT().Get(gomock.Any(), testCloud.ResourceGroup, "vm1", gomock.Any()).Return(vm, nil).AnyTimes()
+	var attachUpdates, detachUpdates int32
+	mockVMsClient.EXPECT().UpdateAsync(gomock.Any(), testCloud.ResourceGroup, "vm1", gomock.Any(), "attach_disk").DoAndReturn(
+		func(ctx context.Context, resourceGroupName, vmName string, parameters compute.VirtualMachineUpdate, source string) (*azure.Future, *retry.Error) {
+			atomic.AddInt32(&attachUpdates, 1)
+			// the requests arriving during the VM update are queued and coalesced into the next one
+			time.Sleep(50 * time.Millisecond)
+			luns := sets.NewInt32()
+			for _, disk := range *parameters.StorageProfile.DataDisks {
+				assert.False(t, luns.Has(*disk.Lun), "lun %d is assigned to multiple disks", *disk.Lun)
+				luns.Insert(*disk.Lun)
+			}
+			return &azure.Future{}, nil
+		}).AnyTimes()
+	mockVMsClient.EXPECT().WaitForUpdateResult(gomock.Any(), gomock.Any(), testCloud.ResourceGroup, gomock.Any()).Return(nil).AnyTimes()
+	mockVMsClient.EXPECT().Update(gomock.Any(), testCloud.ResourceGroup, "vm1", gomock.Any(), "detach_disk").DoAndReturn(
+		func(ctx context.Context, resourceGroupName, vmName string, parameters compute.VirtualMachineUpdate, source string) *retry.Error {
+			atomic.AddInt32(&detachUpdates, 1)
+			time.Sleep(50 * time.Millisecond)
+			return nil
+		}).AnyTimes()
+
+	// the concurrent attach requests are coalesced, and the invalid disk fails without failing the others.
+	// The first request is not delayed since it is the only one queued, the others are coalesced into the next update.
+	type attachResult struct {
+		lun int32
+		err error
+	}
+	results := make(chan attachResult, diskNum+1)
+	for i := 0; i <= diskNum; i++ {
+		disk := &compute.Disk{Name: to.StringPtr(fmt.Sprintf("disk%d", i)), DiskProperties: &compute.DiskProperties{DiskState: compute.DiskStateUnattached}}
+		if i == diskNum {
+			disk.DiskProperties.DiskState = compute.DiskStateAttached
+		}
+		go func(i int, disk *compute.Disk) {
+			lun, err := common.AttachDisk(ctx, false, *disk.Name, diskURI(i), "vm1", compute.CachingTypesReadOnly, disk)
+			results <- attachResult{lun: lun, err: err}
+		}(i, disk)
+	}
+	failed := 0
+	for i := 0; i <= diskNum; i++ {
+		result := <-results
+		if result.err != nil {
+			failed++
+			assert.Contains(t, result.err.Error(), "not in expected Unattached state")
+			continue
+		}
+		assert.GreaterOrEqual(t, result.lun, int32(0))
+	}
+	assert.Equal(t, 1, failed)
+	assert.GreaterOrEqual(t, atomic.LoadInt32(&attachUpdates), int32(1))
+	assert.LessOrEqual(t, atomic.LoadInt32(&attachUpdates), int32(2))
+
+	// the concurrent detach requests are coalesced as well
+	errs := make(chan error, diskNum)
+	for i := 0; i < diskNum; i++ {
+		go func(i int) {
+			errs <- common.DetachDisk(ctx, fmt.Sprintf("disk%d", i), diskURI(i), "vm1")
+		}(i)
+	}
+	for i := 0; i < diskNum; i++ {
+		assert.NoError(t, <-errs)
+	}
+	assert.GreaterOrEqual(t, atomic.LoadInt32(&detachUpdates), int32(1))
+	assert.LessOrEqual(t, atomic.LoadInt32(&detachUpdates), int32(2))
+}
+
+func TestCommonAttachDetachDiskDuplicateCanceled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := getContextWithCancel()
+	defer cancel()
+
+	testCloud := GetTestCloud(ctrl)
+	common := &controllerCommon{
+		location:              testCloud.Location,
+		storageEndpointSuffix: testCloud.Environment.StorageEndpointSuffix,
+		resourceGroup:         testCloud.ResourceGroup,
+		subscriptionID:        testCloud.SubscriptionID,
+		cloud:                 testCloud,
+		lockMap:               newLockMap(),
+		diskOpRateLimiter:     flowcontrol.NewTokenBucketRateLimiter(10, 20),
+		diskOpBatchWindow:     200 * time.Millisecond,
+	}
+	diskURI := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/disks/disk1",
+		testCloud.SubscriptionID, testCloud.ResourceGroup)
+	otherDiskURI := strings.ToLower(fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/disks/disk2",
+		testCloud.SubscriptionID, testCloud.ResourceGroup))
+
+	vm := setTestVirtualMachines(testCloud, map[string]string{"vm1": "PowerState/Running"}, false)[0]
+	mockVMsClient := testCloud.VirtualMachinesClient.(*mockvmclient.MockInterface)
+	mockVMsClient.EXPECT().Get(gomock.Any(), testCloud.ResourceGroup, "vm1", gomock.Any()).Return(vm, nil).AnyTimes()
+	mockVMsClient.EXPECT().UpdateAsync(gomock.Any(), testCloud.ResourceGroup, "vm1", gomock.Any(), "attach_disk").Return(&azure.Future{}, nil).Times(1)
+	mockVMsClient.EXPECT().WaitForUpdateResult(gomock.Any(), gomock.Any(), testCloud.ResourceGroup, gomock.Any()).Return(nil).AnyTimes()
+	mockVMsClient.EXPECT().Update(gomock.Any(), testCloud.ResourceGroup, "vm1", gomock.Any(), "detach_disk").Return(nil).Times(1)
+
+	// another disk is queued on the node, so that the requests wait for the batch window
+	_, _, err := common.insertAttachDiskRequest(otherDiskURI, "vm1", &AttachDiskOptions{lun: -1, diskName: "disk2", result: newDiskOperationResult()})
+	assert.NoError(t, err)
+	disk := &compute.Disk{Name: to.StringPtr("disk1"), DiskProperties: &compute.DiskProperties{DiskState: compute.DiskStateUnattached}}
+	attachErr := make(chan error)
+	go func() {
+		_, err := common.AttachDisk(ctx, false, "disk1", diskURI, "vm1", compute.CachingTypesReadOnly, disk)
+		attachErr <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	duplicateCtx, duplicateCancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer duplicateCancel()
+	_, err = common.AttachDisk(duplicateCtx, false, "disk1", diskURI, "vm1", compute.CachingTypesReadOnly, disk)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.NoError(t, <-attachErr, "the canceled duplicate should not fail the original attach request")
+
+	_, _, err = common.insertDetachDiskRequest(otherDiskURI, "vm1", &detachDiskOptions{diskName: "disk2", result: newDiskOperationResult()})
+	assert.NoError(t, err)
+	detachErr := make(chan error)
+	go func() {
+		detachErr <- common.DetachDisk(ctx, "disk1", diskURI, "vm1")
+	}()
+	time.Sleep(50 * time.Millisecond)
+	duplicateCtx, duplicateCancel = context.WithTimeout(ctx, 10*time.Millisecond)
+	defer duplicateCancel()
+	assert.Equal(t, context.DeadlineExceeded, common.DetachDisk(duplicateCtx, "disk1", diskURI, "vm1"))
+	assert.NoError(t, <-detachErr, "the canceled duplicate should not fail the original detach request")
+}
+
 func TestCommonAttachDiskWithVMSS(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -235,11 +518,11 @@ func TestCommonAttachDiskWithVMSS(t *testing.T) {
 				testCloud.DisableAvailabilitySetNodes = false
 				expectedVMSS := compute.VirtualMachineScaleSet{Name: to.StringPtr(testVMSSName)}
 				mockVMSSClient := testCloud.VirtualMachineScaleSetsClient.(*mockvmssclient.MockInterface)
-				mockVMSSClient.EXPECT().List(gomock.Any(), testCloud.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
+				mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), testCloud.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
 
 				expectedVMSSVMs, _, _ := buildTestVirtualMachineEnv(testCloud, testVMSSName, "", 0, test.vmssList, "", false)
 				mockVMSSVMClient := testCloud.VirtualMachineScaleSetVMsClient.(*mockvmssvmclient.MockInterface)
-				mockVMSSVMClient.EXPECT().List(gomock.Any(), testCloud.ResourceGroup, testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
+				mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), testCloud.ResourceGroup, testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
 
 				mockVMsClient := testCloud.VirtualMachinesClient.(*mockvmclient.MockInterface)
 				mockVMsClient.EXPECT().List(gomock.Any(), gomock.Any()).Return([]compute.VirtualMachine{}, nil).AnyTimes()
@@ -476,7 +759,9 @@ func TestSetDiskLun(t *testing.T) {
 		diskURI         string
 		diskMap         map[string]*AttachDiskOptions
 		isDataDisksFull bool
+		lunStartIndex   int32
 		expectedErr     bool
+		expectedErrMsg  string
 		expectedLun     int32
 	}{
 		{
@@ -495,6 +780,26 @@ func TestSetDiskLun(t *testing.T) {
 			isDataDisksFull: true,
 			expectedLun:     -1,
 			expectedErr:     true,
+			expectedErrMsg:  "VM size Standard_A0 supports at most 4 data disks",
+		},
+		{
+			desc:          "the luns below lunStartIndex shall be skipped",
+			nodeName:      "nodeName",
+			diskURI:       "diskURI",
+			diskMap:       map[string]*AttachDiskOptions{"diskURI": {}},
+			lunStartIndex: 10,
+			expectedLun:   10,
+			expectedErr:   false,
+		},
+		{
+			desc:           "LUN -1 and error shall be returned if all the luns above lunStartIndex are used",
+			nodeName:       "nodeName",
+			diskURI:        "diskURI",
+			diskMap:        map[string]*AttachDiskOptions{"diskURI": {}, "diskURI2": {}},
+			lunStartIndex:  maxLUN - 1,
+			expectedLun:    -1,
+			expectedErr:    true,
+			expectedErrMsg: "lun start index is 63",
 		},
 		{
 			desc:        "diskURI1 is not in VM data disk list nor in diskMap",
@@ -517,15 +822,100 @@ func TestSetDiskLun(t *testing.T) {
 			lockMap:               newLockMap(),
 			diskOpRateLimiter:     flowcontrol.NewTokenBucketRateLimiter(10, 20),
 		}
+		testCloud.DiskLunStartIndex = test.lunStartIndex
 		expectedVMs := setTestVirtualMachines(testCloud, map[string]string{test.nodeName: "PowerState/Running"}, test.isDataDisksFull)
 		mockVMsClient := testCloud.VirtualMachinesClient.(*mockvmclient.MockInterface)
 		for _, vm := range expectedVMs {
 			mockVMsClient.EXPECT().Get(gomock.Any(), testCloud.ResourceGroup, *vm.Name, gomock.Any()).Return(vm, nil).AnyTimes()
 		}
+		mockVMSizesClient := testCloud.VirtualMachineSizesClient.(*mockvmsizeclient.MockInterface)
+		mockVMSizesClient.EXPECT().List(gomock.Any(), testCloud.Location).Return(compute.VirtualMachineSizeListResult{
+			Value: &[]compute.VirtualMachineSize{
+				{Name: to.StringPtr("Standard_A0"), MaxDataDiskCount: to.Int32Ptr(4)},
+			},
+		}, nil).MaxTimes(1)
 
 		lun, err := common.SetDiskLun(types.NodeName(test.nodeName), test.diskURI, test.diskMap)
 		assert.Equal(t, test.expectedLun, lun, "TestCase[%d]: %s", i, test.desc)
 		assert.Equal(t, test.expectedErr, err != nil, "TestCase[%d]: %s", i, test.desc)
+		if test.expectedErrMsg != "" {
+			assert.Contains(t, err.Error(), test.expectedErrMsg, "TestCase[%d]: %s", i, test.desc)
+		}
+	}
+}
+
+func TestGetMaxDataDiskCount(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testCloud := GetTestCloud(ctrl)
+	common := testCloud.controllerCommon
+	mockVMSizesClient := testCloud.VirtualMachineSizesClient.(*mockvmsizeclient.MockInterface)
+
+	// the empty results are not cached
+	mockVMSizesClient.EXPECT().List(gomock.Any(), testCloud.Location).Return(compute.VirtualMachineSizeListResult{}, nil).Times(1)
+	_, err := common.getMaxDataDiskCount("Standard_D2s_v3")
+	assert.Error(t, err)
+
+	mockVMSizesClient.EXPECT().List(gomock.Any(), testCloud.Location).Return(compute.VirtualMachineSizeListResult{
+		Value: &[]compute.VirtualMachineSize{
+			{Name: to.StringPtr("Standard_D2s_v3"), MaxDataDiskCount: to.Int32Ptr(4)},
+			{Name: to.StringPtr("Standard_D4s_v3"), MaxDataDiskCount: to.Int32Ptr(8)},
+		},
+	}, nil).Times(1)
+
+	count, err := common.getMaxDataDiskCount("standard_d4s_v3")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(8), count)
+
+	// the VM sizes are cached after the first call
+	count, err = common.getMaxDataDiskCount("Standard_D2s_v3")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(4), count)
+
+	_, err = common.getMaxDataDiskCount("Standard_A0")
+	assert.Error(t, err)
+
+	// the new VM sizes are listed after the cache expires
+	common.vmSizeCache.TTL = 0
+	mockVMSizesClient.EXPECT().List(gomock.Any(), testCloud.Location).Return(compute.VirtualMachineSizeListResult{
+		Value: &[]compute.VirtualMachineSize{
+			{Name: to.StringPtr("Standard_A0"), MaxDataDiskCount: to.Int32Ptr(1)},
+		},
+	}, nil).Times(1)
+	count, err = common.getMaxDataDiskCount("Standard_A0")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), count)
+}
+
+func TestGetConflictDiskLun(t *testing.T) {
+	testCases := []struct {
+		desc               string
+		err                error
+		expectedLun        int32
+		expectedIsConflict bool
+	}{
+		{
+			desc:        "nil error",
+			expectedLun: -1,
+		},
+		{
+			desc:        "not a lun conflict error",
+			err:         fmt.Errorf("Retriable: false, RetryAfter: 0s, HTTPStatusCode: 500, RawError: internal error"),
+			expectedLun: -1,
+		},
+		{
+			desc:               "lun conflict error",
+			err:                fmt.Errorf("Retriable: false, RetryAfter: 0s, HTTPStatusCode: 409, RawError: A disk at LUN 1 already exists."),
+			expectedLun:        1,
+			expectedIsConflict: true,
+		},
+	}
+
+	for i, test := range testCases {
+		lun, isConflict := getConflictDiskLun(test.err)
+		assert.Equal(t, test.expectedLun, lun, "TestCase[%d]: %s", i, test.desc)
+		assert.Equal(t, test.expectedIsConflict, isConflict, "TestCase[%d]: %s", i, test.desc)
 	}
 }
 
@@ -988,11 +1378,15 @@ func TestAttachDiskRequestFuncs(t *testing.T) {
 			diskURI := fmt.Sprintf("%s%d", test.diskURI, i)
 			diskName := fmt.Sprintf("%s%d", test.diskName, i)
 			attachDiskOptions := &AttachDiskOptions{diskName: diskName}
-			err := common.insertAttachDiskRequest(diskURI, test.nodeName, attachDiskOptions)
+			queued, queueLen, err := common.insertAttachDiskRequest(diskURI, test.nodeName, attachDiskOptions)
 			assert.Equal(t, test.expectedErr, err != nil, "TestCase[%d]: %s", i, test.desc)
+			assert.Equal(t, attachDiskOptions, queued, "TestCase[%d]: %s", i, test.desc)
+			assert.Equal(t, i, queueLen, "TestCase[%d]: %s", i, test.desc)
 			if test.duplicateDiskRequest {
-				err := common.insertAttachDiskRequest(diskURI, test.nodeName, attachDiskOptions)
+				duplicated, queueLen, err := common.insertAttachDiskRequest(diskURI, test.nodeName, &AttachDiskOptions{diskName: diskName})
 				assert.Equal(t, test.expectedErr, err != nil, "TestCase[%d]: %s", i, test.desc)
+				assert.Same(t, attachDiskOptions, duplicated, "TestCase[%d]: %s", i, test.desc)
+				assert.Equal(t, i, queueLen, "TestCase[%d]: %s", i, test.desc)
 			}
 		}
 
@@ -1006,6 +1400,53 @@ func TestAttachDiskRequestFuncs(t *testing.T) {
 	}
 }
 
+func TestRemoveDiskRequest(t *testing.T) {
+	common := &controllerCommon{lockMap: newLockMap()}
+
+	attachOptions := &AttachDiskOptions{diskName: "disk1", result: newDiskOperationResult()}
+	_, _, err := common.insertAttachDiskRequest("diskuri1", "node1", attachOptions)
+	assert.NoError(t, err)
+	assert.False(t, common.removeAttachDiskRequest("diskuri1", "node1", &AttachDiskOptions{diskName: "disk1"}), "the request queued by another call should not be removed")
+	assert.True(t, common.removeAttachDiskRequest("diskuri1", "node1", attachOptions))
+	assert.False(t, common.removeAttachDiskRequest("diskuri1", "node1", attachOptions))
+	diskMap, err := common.cleanAttachDiskRequests("node1")
+	assert.NoError(t, err)
+	assert.Empty(t, diskMap)
+
+	detachOptions, _, err := common.insertDetachDiskRequest("diskuri1", "node1", &detachDiskOptions{diskName: "disk1", result: newDiskOperationResult()})
+	assert.NoError(t, err)
+	_, err = common.cleanDetachDiskRequests("node1")
+	assert.NoError(t, err)
+	assert.False(t, common.removeDetachDiskRequest("diskuri1", "node1", detachOptions), "the request taken by a VM update should not be removed")
+	assert.False(t, common.removeDetachDiskRequest("diskuri1", "node2", detachOptions))
+}
+
+func TestWaitDiskOpBatchWindow(t *testing.T) {
+	common := &controllerCommon{diskOpBatchWindow: time.Hour}
+
+	assert.NoError(t, common.waitDiskOpBatchWindow(context.Background(), 1), "the wait should be skipped for the only request on the node")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, common.waitDiskOpBatchWindow(ctx, 2))
+
+	common.diskOpBatchWindow = 0
+	assert.NoError(t, common.waitDiskOpBatchWindow(context.Background(), 2))
+
+	common.diskOpBatchWindow = 10 * time.Millisecond
+	assert.NoError(t, common.waitDiskOpBatchWindow(context.Background(), 2))
+}
+
+func TestInitDiskControllersBatchWindow(t *testing.T) {
+	az := &Cloud{}
+	assert.NoError(t, initDiskControllers(az))
+	assert.Equal(t, defaultDiskOpBatchWindow, az.ManagedDiskController.common.diskOpBatchWindow)
+
+	az.DiskOpBatchWindowInMilliseconds = to.IntPtr(0)
+	assert.NoError(t, initDiskControllers(az))
+	assert.Equal(t, time.Duration(0), az.ManagedDiskController.common.diskOpBatchWindow)
+}
+
 func TestDetachDiskRequestFuncs(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -1068,20 +1509,23 @@ func TestDetachDiskRequestFuncs(t *testing.T) {
 		for i := 1; i <= test.diskNum; i++ {
 			diskURI := fmt.Sprintf("%s%d", test.diskURI, i)
 			diskName := fmt.Sprintf("%s%d", test.diskName, i)
-			err := common.insertDetachDiskRequest(diskName, diskURI, test.nodeName)
+			queued, queueLen, err := common.insertDetachDiskRequest(diskURI, test.nodeName, &detachDiskOptions{diskName: diskName})
 			assert.Equal(t, test.expectedErr, err != nil, "TestCase[%d]: %s", i, test.desc)
+			assert.Equal(t, i, queueLen, "TestCase[%d]: %s", i, test.desc)
 			if test.duplicateDiskRequest {
-				err := common.insertDetachDiskRequest(diskName, diskURI, test.nodeName)
+				duplicated, queueLen, err := common.insertDetachDiskRequest(diskURI, test.nodeName, &detachDiskOptions{diskName: diskName})
 				assert.Equal(t, test.expectedErr, err != nil, "TestCase[%d]: %s", i, test.desc)
+				assert.Same(t, queued, duplicated, "TestCase[%d]: %s", i, test.desc)
+				assert.Equal(t, i, queueLen, "TestCase[%d]: %s", i, test.desc)
 			}
 		}
 
 		diskMap, err := common.cleanDetachDiskRequests(test.nodeName)
 		assert.Equal(t, test.expectedErr, err != nil, "TestCase[%d]: %s", i, test.desc)
 		assert.Equal(t, test.diskNum, len(diskMap), "TestCase[%d]: %s", i, test.desc)
-		for diskURI, diskName := range diskMap {
+		for diskURI, opt := range diskMap {
 			assert.Equal(t, strings.Contains(diskURI, test.diskURI), true, "TestCase[%d]: %s", i, test.desc)
-			assert.Equal(t, strings.Contains(diskName, test.diskName), true, "TestCase[%d]: %s", i, test.desc)
+			assert.Equal(t, strings.Contains(opt.diskName, test.diskName), true, "TestCase[%d]: %s", i, test.desc)
 		}
 	}
 }
@@ -0,0 +1,222 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/armclient"
+	azcache "sigs.k8s.io/cloud-provider-azure/pkg/cache"
+	"sigs.k8s.io/cloud-provider-azure/pkg/retry"
+)
+
+const (
+	// dependencyHealthCheckTTL is the duration during which the results of the dependency checks are reused,
+	// so that the probes don't send more requests to Azure than one round of the checks every 30 seconds.
+	dependencyHealthCheckTTL = 30 * time.Second
+	// dependencyHealthCheckTimeout is the timeout of each dependency check.
+	dependencyHealthCheckTimeout = 10 * time.Second
+
+	dependencyCheckToken         = "token"
+	dependencyCheckResourceGroup = "resource_group"
+	dependencyCheckIMDS          = "imds"
+)
+
+// DependencyStatus is the status of an Azure dependency reported by the DependencyHealthChecker.
+type DependencyStatus struct {
+	// Name is the name of the dependency check, e.g. token, resource_group or imds.
+	Name string `json:"name"`
+	// Healthy is true if the last check succeeded.
+	Healthy bool `json:"healthy"`
+	// LastError is the error of the last check, it is empty if the check succeeded.
+	LastError string `json:"lastError,omitempty"`
+	// LastChecked is the time of the last check.
+	LastChecked time.Time `json:"lastChecked"`
+}
+
+// dependencyCheck checks whether an Azure dependency is available.
+type dependencyCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// DependencyHealthChecker checks whether the cloud provider could actually work with its Azure dependencies, i.e.
+// the access tokens could be acquired, the resource group of the cluster could be got from ARM, and the IMDS is
+// reachable if useInstanceMetadata is enabled. The results are cached for 30 seconds. It implements the
+// healthz.HealthChecker interface for the readiness probe, and serves the report of each check over HTTP.
+type DependencyHealthChecker struct {
+	checks []dependencyCheck
+
+	// lock guards the cached statuses.
+	lock      sync.Mutex
+	statuses  []DependencyStatus
+	checkedAt time.Time
+	now       func() time.Time
+	// group deduplicates the concurrent rounds of the checks.
+	group singleflight.Group
+}
+
+// newDependencyHealthChecker creates a DependencyHealthChecker running the checks.
+func newDependencyHealthChecker(checks ...dependencyCheck) *DependencyHealthChecker {
+	return &DependencyHealthChecker{
+		checks: checks,
+		now:    time.Now,
+	}
+}
+
+// NewDependencyHealthChecker creates a DependencyHealthChecker of the Azure dependencies of the cloud provider.
+func (az *Cloud) NewDependencyHealthChecker() *DependencyHealthChecker {
+	checks := []dependencyCheck{{name: dependencyCheckToken, check: az.checkTokens}}
+
+	if az.authorizer != nil {
+		azClientConfig := az.getAzureClientConfig(nil)
+		azClientConfig.Authorizer = az.authorizer
+		azClientConfig.Backoff = &retry.Backoff{Steps: 1}
+		armClient := armclient.New(azClientConfig.Authorizer, *azClientConfig, az.Environment.ResourceManagerEndpoint, resourceGroupsAPIVersion)
+		checks = append(checks, dependencyCheck{
+			name: dependencyCheckResourceGroup,
+			check: func(ctx context.Context) error {
+				return az.checkResourceGroup(ctx, armClient)
+			},
+		})
+	}
+
+	if az.UseInstanceMetadata && az.Metadata != nil {
+		checks = append(checks, dependencyCheck{name: dependencyCheckIMDS, check: az.checkIMDS})
+	}
+
+	return newDependencyHealthChecker(checks...)
+}
+
+// Name implements the healthz.HealthChecker interface.
+func (checker *DependencyHealthChecker) Name() string {
+	return "azure"
+}
+
+// Check implements the healthz.HealthChecker interface. It returns the errors of the failed dependency checks.
+func (checker *DependencyHealthChecker) Check(_ *http.Request) error {
+	var errs []error
+	for _, status := range checker.Statuses() {
+		if !status.Healthy {
+			errs = append(errs, fmt.Errorf("%s: %s", status.Name, status.LastError))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// ServeHTTP reports the status of each dependency check in JSON. The status code is 503 if any check fails.
+func (checker *DependencyHealthChecker) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	statuses := checker.Statuses()
+	statusCode := http.StatusOK
+	for _, status := range statuses {
+		if !status.Healthy {
+			statusCode = http.StatusServiceUnavailable
+			break
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(statuses); err != nil {
+		klog.Errorf("DependencyHealthChecker: failed to write the statuses: %v", err)
+	}
+}
+
+// Statuses returns the statuses of the dependency checks. The checks are run only if the last results
+// are older than 30 seconds, and the concurrent callers wait for the same round of the checks, which
+// is run without holding the lock so that the callers reading the cached results are not blocked.
+func (checker *DependencyHealthChecker) Statuses() []DependencyStatus {
+	checker.lock.Lock()
+	if checker.statuses != nil && checker.now().Sub(checker.checkedAt) < dependencyHealthCheckTTL {
+		statuses := append([]DependencyStatus{}, checker.statuses...)
+		checker.lock.Unlock()
+		return statuses
+	}
+	checker.lock.Unlock()
+
+	result, _, _ := checker.group.Do("", func() (interface{}, error) {
+		return checker.runChecks(), nil
+	})
+	return append([]DependencyStatus{}, result.([]DependencyStatus)...)
+}
+
+// runChecks runs the dependency checks in parallel and caches their statuses.
+func (checker *DependencyHealthChecker) runChecks() []DependencyStatus {
+	now := checker.now()
+	statuses := make([]DependencyStatus, len(checker.checks))
+	var wg sync.WaitGroup
+	for i, check := range checker.checks {
+		wg.Add(1)
+		go func(i int, check dependencyCheck) {
+			defer wg.Done()
+			status := DependencyStatus{Name: check.name, Healthy: true, LastChecked: now}
+			ctx, cancel := context.WithTimeout(context.Background(), dependencyHealthCheckTimeout)
+			defer cancel()
+			if err := check.check(ctx); err != nil {
+				klog.Warningf("DependencyHealthChecker: check %s failed: %v", check.name, err)
+				status.Healthy = false
+				status.LastError = err.Error()
+			}
+			statuses[i] = status
+		}(i, check)
+	}
+	wg.Wait()
+
+	checker.lock.Lock()
+	defer checker.lock.Unlock()
+	checker.statuses = statuses
+	checker.checkedAt = now
+	return statuses
+}
+
+// checkTokens checks whether the access tokens of the clients could be acquired.
+func (az *Cloud) checkTokens(ctx context.Context) error {
+	for _, refresher := range az.getTokenRefreshers() {
+		if err := refresher.EnsureFresh(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkResourceGroup checks whether the resource group of the cluster could be got from ARM.
+func (az *Cloud) checkResourceGroup(ctx context.Context, armClient armclient.Interface) error {
+	resourceGroupID := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s", az.SubscriptionID, az.ResourceGroup)
+	response, rerr := armClient.GetResource(ctx, resourceGroupID)
+	defer armClient.CloseResponse(ctx, response)
+	if rerr != nil {
+		return fmt.Errorf("failed to get resource group %s: %w", az.ResourceGroup, rerr.Error())
+	}
+	return nil
+}
+
+// checkIMDS checks whether the instance metadata service is reachable.
+func (az *Cloud) checkIMDS(_ context.Context) error {
+	_, err := az.Metadata.GetMetadata(azcache.CacheReadTypeForceRefresh)
+	return err
+}
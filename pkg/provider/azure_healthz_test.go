@@ -0,0 +1,209 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/armclient/mockarmclient"
+	"sigs.k8s.io/cloud-provider-azure/pkg/retry"
+)
+
+func TestDependencyHealthChecker(t *testing.T) {
+	for _, failing := range []string{dependencyCheckToken, dependencyCheckResourceGroup, dependencyCheckIMDS} {
+		t.Run(fmt.Sprintf("failing %s", failing), func(t *testing.T) {
+			checks := make([]dependencyCheck, 0)
+			for _, name := range []string{dependencyCheckToken, dependencyCheckResourceGroup, dependencyCheckIMDS} {
+				name := name
+				checks = append(checks, dependencyCheck{name: name, check: func(context.Context) error {
+					if name == failing {
+						return fmt.Errorf("%s is unavailable", name)
+					}
+					return nil
+				}})
+			}
+			checker := newDependencyHealthChecker(checks...)
+
+			err := checker.Check(nil)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), fmt.Sprintf("%s is unavailable", failing))
+
+			for _, status := range checker.Statuses() {
+				assert.Equal(t, status.Name != failing, status.Healthy, status.Name)
+				if status.Name == failing {
+					assert.Equal(t, fmt.Sprintf("%s is unavailable", failing), status.LastError)
+				} else {
+					assert.Empty(t, status.LastError)
+				}
+			}
+
+			recorder := httptest.NewRecorder()
+			checker.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz/azure", nil))
+			assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+			var statuses []DependencyStatus
+			assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &statuses))
+			assert.Len(t, statuses, 3)
+		})
+	}
+
+	t.Run("healthy", func(t *testing.T) {
+		checker := newDependencyHealthChecker(dependencyCheck{name: dependencyCheckToken, check: func(context.Context) error { return nil }})
+		assert.NoError(t, checker.Check(nil))
+
+		recorder := httptest.NewRecorder()
+		checker.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz/azure", nil))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	})
+}
+
+func TestDependencyHealthCheckerCache(t *testing.T) {
+	calls := 0
+	var checkErr error
+	checker := newDependencyHealthChecker(dependencyCheck{name: dependencyCheckToken, check: func(context.Context) error {
+		calls++
+		return checkErr
+	}})
+	now := time.Now()
+	checker.now = func() time.Time { return now }
+
+	assert.NoError(t, checker.Check(nil))
+	checkErr = fmt.Errorf("failed to acquire the token")
+	now = now.Add(dependencyHealthCheckTTL - time.Second)
+	assert.NoError(t, checker.Check(nil), "the cached result should be returned within the TTL")
+	assert.Equal(t, 1, calls)
+
+	now = now.Add(2 * time.Second)
+	assert.Error(t, checker.Check(nil))
+	assert.Equal(t, 2, calls)
+}
+
+func TestDependencyHealthCheckerConcurrentStatuses(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	blockingCheck := func(context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return nil
+	}
+	checker := newDependencyHealthChecker(
+		dependencyCheck{name: dependencyCheckToken, check: blockingCheck},
+		dependencyCheck{name: dependencyCheckResourceGroup, check: blockingCheck},
+	)
+
+	const callers = 10
+	results := make(chan []DependencyStatus, callers)
+	for i := 0; i < callers; i++ {
+		go func() {
+			results <- checker.Statuses()
+		}()
+	}
+	// the checks of a round run in parallel, and the concurrent callers share the round
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&calls) == 2 }, time.Second, 10*time.Millisecond)
+	close(release)
+	for i := 0; i < callers; i++ {
+		statuses := <-results
+		assert.Len(t, statuses, 2)
+		assert.True(t, statuses[0].Healthy && statuses[1].Healthy)
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestCheckTokensWhileReloading(t *testing.T) {
+	az := &Cloud{}
+	az.startTokenRefreshers(newManagedIdentityToken(t), nil, nil)
+	defer func() {
+		for _, refresher := range az.getTokenRefreshers() {
+			refresher.Stop()
+		}
+	}()
+
+	// the refreshers replaced by the reloads should be read consistently by the probes
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10; i++ {
+			az.startTokenRefreshers(newManagedIdentityToken(t), nil, nil)
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, az.checkTokens(context.Background()))
+	}
+	<-done
+	assert.Len(t, az.getTokenRefreshers(), 1)
+}
+
+func TestCheckResourceGroup(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	az := GetTestCloud(ctrl)
+	armClient := mockarmclient.NewMockInterface(ctrl)
+	resourceGroupID := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s", az.SubscriptionID, az.ResourceGroup)
+	armClient.EXPECT().CloseResponse(gomock.Any(), gomock.Any()).AnyTimes()
+
+	armClient.EXPECT().GetResource(gomock.Any(), resourceGroupID).Return(&http.Response{StatusCode: http.StatusOK}, nil)
+	assert.NoError(t, az.checkResourceGroup(context.Background(), armClient))
+
+	armClient.EXPECT().GetResource(gomock.Any(), resourceGroupID).Return(nil, &retry.Error{HTTPStatusCode: http.StatusForbidden, RawError: fmt.Errorf("forbidden")})
+	err := az.checkResourceGroup(context.Background(), armClient)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "forbidden")
+}
+
+func TestCheckIMDS(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	az := GetTestCloud(ctrl)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	var err error
+	az.Metadata, err = NewInstanceMetadataService(server.URL + "/")
+	assert.NoError(t, err)
+
+	assert.Error(t, az.checkIMDS(context.Background()))
+}
+
+func TestNewDependencyHealthChecker(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	az := GetTestCloud(ctrl)
+
+	az.UseInstanceMetadata = false
+	checker := az.NewDependencyHealthChecker()
+	assert.Len(t, checker.checks, 1, "only the token is checked without the authorizer and IMDS")
+	assert.Equal(t, dependencyCheckToken, checker.checks[0].name)
+	assert.NoError(t, checker.Check(nil))
+
+	var err error
+	az.UseInstanceMetadata = true
+	az.Metadata, err = NewInstanceMetadataService("http://localhost/")
+	assert.NoError(t, err)
+	checker = az.NewDependencyHealthChecker()
+	assert.Equal(t, dependencyCheckIMDS, checker.checks[len(checker.checks)-1].name)
+}
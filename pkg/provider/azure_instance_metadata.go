@@ -21,12 +21,22 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"time"
 
+	kwait "k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/klog/v2"
+
 	azcache "sigs.k8s.io/cloud-provider-azure/pkg/cache"
 	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
 )
 
+// imdsBackoff is the backoff to retry the transient failures of the instance metadata service.
+var imdsBackoff = kwait.Backoff{
+	Steps:    4,
+	Duration: 500 * time.Millisecond,
+	Factor:   2.0,
+}
+
 // NetworkMetadata contains metadata about an instance's network
 type NetworkMetadata struct {
 	Interface []NetworkInterface `json:"interface"`
@@ -49,6 +59,24 @@ type NetworkData struct {
 type IPAddress struct {
 	PrivateIP string `json:"privateIpAddress"`
 	PublicIP  string `json:"publicIpAddress"`
+	// Primary indicates whether the IP address belongs to the primary IP configuration of the interface.
+	Primary bool `json:"primary,omitempty"`
+}
+
+// primaryIPAddress returns the IP address of the primary IP configuration, or nil if there isn't any.
+// The secondary IP configurations, e.g. the ones allocated to pods by Azure CNI, are usually listed
+// after the primary one, but the order is not guaranteed on all platforms (e.g. Windows), so the
+// primary flag is honored if it is set. The first IP address is used otherwise.
+func (nd *NetworkData) primaryIPAddress() *IPAddress {
+	if len(nd.IPAddress) == 0 {
+		return nil
+	}
+	for i := range nd.IPAddress {
+		if nd.IPAddress[i].Primary {
+			return &nd.IPAddress[i]
+		}
+	}
+	return &nd.IPAddress[0]
 }
 
 // Subnet represents subnet information.
@@ -72,6 +100,14 @@ type ComputeMetadata struct {
 	ResourceGroup          string `json:"resourceGroupName,omitempty"`
 	VMScaleSetName         string `json:"vmScaleSetName,omitempty"`
 	SubscriptionID         string `json:"subscriptionId,omitempty"`
+
+	ExtendedLocation *ExtendedLocationMetadata `json:"extendedLocation,omitempty"`
+}
+
+// ExtendedLocationMetadata represents the extended location of the instance, e.g. the edge zone.
+type ExtendedLocationMetadata struct {
+	Name string `json:"name,omitempty"`
+	Type string `json:"type,omitempty"`
 }
 
 // InstanceMetadata represents instance information.
@@ -96,6 +132,25 @@ type LoadBalancerMetadata struct {
 	LoadBalancer *LoadbalancerProfile `json:"loadbalancer,omitempty"`
 }
 
+// ScheduledEvent represents a scheduled event of the instance, e.g. an upcoming reboot or preemption.
+type ScheduledEvent struct {
+	EventID           string   `json:"EventId,omitempty"`
+	EventType         string   `json:"EventType,omitempty"`
+	ResourceType      string   `json:"ResourceType,omitempty"`
+	Resources         []string `json:"Resources,omitempty"`
+	EventStatus       string   `json:"EventStatus,omitempty"`
+	NotBefore         string   `json:"NotBefore,omitempty"`
+	Description       string   `json:"Description,omitempty"`
+	EventSource       string   `json:"EventSource,omitempty"`
+	DurationInSeconds int      `json:"DurationInSeconds,omitempty"`
+}
+
+// ScheduledEvents represents the scheduled events of the instance.
+type ScheduledEvents struct {
+	DocumentIncarnation int              `json:"DocumentIncarnation,omitempty"`
+	Events              []ScheduledEvent `json:"Events,omitempty"`
+}
+
 // InstanceMetadataService knows how to query the Azure instance metadata server.
 type InstanceMetadataService struct {
 	imdsServer string
@@ -108,7 +163,7 @@ func NewInstanceMetadataService(imdsServer string) (*InstanceMetadataService, er
 		imdsServer: imdsServer,
 	}
 
-	imsCache, err := azcache.NewTimedcache(consts.MetadataCacheTTL, ims.getMetadata)
+	imsCache, err := azcache.NewTimedcacheWithName("instance_metadata", consts.MetadataCacheTTL, ims.getMetadata)
 	if err != nil {
 		return nil, err
 	}
@@ -125,8 +180,10 @@ func (ims *InstanceMetadataService) getMetadata(key string) (interface{}, error)
 
 	if instanceMetadata.Network != nil && len(instanceMetadata.Network.Interface) > 0 {
 		netInterface := instanceMetadata.Network.Interface[0]
-		if (len(netInterface.IPV4.IPAddress) > 0 && len(netInterface.IPV4.IPAddress[0].PublicIP) > 0) ||
-			(len(netInterface.IPV6.IPAddress) > 0 && len(netInterface.IPV6.IPAddress[0].PublicIP) > 0) {
+		ipv4Address := netInterface.IPV4.primaryIPAddress()
+		ipv6Address := netInterface.IPV6.primaryIPAddress()
+		if (ipv4Address != nil && len(ipv4Address.PublicIP) > 0) ||
+			(ipv6Address != nil && len(ipv6Address.PublicIP) > 0) {
 			// Return if public IP address has already part of instance metadata.
 			return instanceMetadata, nil
 		}
@@ -140,18 +197,13 @@ func (ims *InstanceMetadataService) getMetadata(key string) (interface{}, error)
 		}
 
 		publicIPs := loadBalancerMetadata.LoadBalancer.PublicIPAddresses
-		if len(netInterface.IPV4.IPAddress) > 0 && len(netInterface.IPV4.IPAddress[0].PrivateIP) > 0 {
-			for _, pip := range publicIPs {
-				if pip.PrivateIPAddress == netInterface.IPV4.IPAddress[0].PrivateIP {
-					netInterface.IPV4.IPAddress[0].PublicIP = pip.FrontendIPAddress
-					break
-				}
+		for _, address := range []*IPAddress{ipv4Address, ipv6Address} {
+			if address == nil || len(address.PrivateIP) == 0 {
+				continue
 			}
-		}
-		if len(netInterface.IPV6.IPAddress) > 0 && len(netInterface.IPV6.IPAddress[0].PrivateIP) > 0 {
 			for _, pip := range publicIPs {
-				if pip.PrivateIPAddress == netInterface.IPV6.IPAddress[0].PrivateIP {
-					netInterface.IPV6.IPAddress[0].PublicIP = pip.FrontendIPAddress
+				if pip.PrivateIPAddress == address.PrivateIP {
+					address.PublicIP = pip.FrontendIPAddress
 					break
 				}
 			}
@@ -174,8 +226,7 @@ func (ims *InstanceMetadataService) getInstanceMetadata(key string) (*InstanceMe
 	q.Add("api-version", consts.ImdsInstanceAPIVersion)
 	req.URL.RawQuery = q.Encode()
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := ims.doIMDSRequest(req)
 	if err != nil {
 		return nil, err
 	}
@@ -212,8 +263,7 @@ func (ims *InstanceMetadataService) getLoadBalancerMetadata() (*LoadBalancerMeta
 	q.Add("api-version", consts.ImdsLoadBalancerAPIVersion)
 	req.URL.RawQuery = q.Encode()
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := ims.doIMDSRequest(req)
 	if err != nil {
 		return nil, err
 	}
@@ -237,6 +287,72 @@ func (ims *InstanceMetadataService) getLoadBalancerMetadata() (*LoadBalancerMeta
 	return &obj, nil
 }
 
+// GetScheduledEvents gets the scheduled events of the instance. They are not cached since they
+// are changed (e.g. scheduled, started or cancelled) frequently.
+func (ims *InstanceMetadataService) GetScheduledEvents() (*ScheduledEvents, error) {
+	req, err := http.NewRequest("GET", ims.imdsServer+consts.ImdsScheduledEventsURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Metadata", "True")
+	req.Header.Add("User-Agent", "golang/kubernetes-cloud-provider")
+
+	q := req.URL.Query()
+	q.Add("api-version", consts.ImdsScheduledEventsAPIVersion)
+	req.URL.RawQuery = q.Encode()
+
+	resp, err := ims.doIMDSRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failure of getting scheduled events with response %q", resp.Status)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := ScheduledEvents{}
+	err = json.Unmarshal(data, &obj)
+	if err != nil {
+		return nil, err
+	}
+
+	return &obj, nil
+}
+
+// doIMDSRequest sends the request to the instance metadata service, and retries with backoff on
+// the transient failures, i.e. connection errors and 5xx responses.
+func (ims *InstanceMetadataService) doIMDSRequest(req *http.Request) (*http.Response, error) {
+	client := &http.Client{}
+	var resp *http.Response
+	var lastErr error
+	err := kwait.ExponentialBackoff(imdsBackoff, func() (bool, error) {
+		var err error
+		resp, err = client.Do(req)
+		if err != nil {
+			klog.V(3).Infof("failed to send request %s to instance metadata service: %v, will retry", req.URL.Path, err)
+			lastErr = err
+			return false, nil
+		}
+		if resp.StatusCode >= http.StatusInternalServerError {
+			klog.V(3).Infof("instance metadata service returned %q for request %s, will retry", resp.Status, req.URL.Path)
+			lastErr = fmt.Errorf("failure of request %s with response %q", req.URL.Path, resp.Status)
+			resp.Body.Close()
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("instance metadata service %s is unreachable after %d attempts: %w", ims.imdsServer, imdsBackoff.Steps, lastErr)
+	}
+	return resp, nil
+}
+
 // GetMetadata gets instance metadata from cache.
 // crt determines if we can get data from stalled cache/need fresh if cache expired.
 func (ims *InstanceMetadataService) GetMetadata(crt azcache.AzureCacheReadType) (*InstanceMetadata, error) {
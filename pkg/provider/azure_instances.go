@@ -21,16 +21,21 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	cloudprovider "k8s.io/cloud-provider"
 	"k8s.io/klog/v2"
+	utilnet "k8s.io/utils/net"
 
 	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2021-07-01/compute"
 	azcache "sigs.k8s.io/cloud-provider-azure/pkg/cache"
 	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+	"sigs.k8s.io/cloud-provider-azure/pkg/metrics"
 )
 
 const (
@@ -38,14 +43,28 @@ const (
 	vmPowerStateStopped      = "stopped"
 	vmPowerStateDeallocated  = "deallocated"
 	vmPowerStateDeallocating = "deallocating"
+	vmPowerStateStopping     = "stopping"
+
+	scheduledEventTypePreempt   = "Preempt"
+	scheduledEventTypeReboot    = "Reboot"
+	scheduledEventTypeRedeploy  = "Redeploy"
+	scheduledEventTypeTerminate = "Terminate"
 
 	// nodeNameEnvironmentName is the environment variable name for getting node name.
 	// It is only used for out-of-tree cloud provider.
 	nodeNameEnvironmentName = "NODE_NAME"
+
+	// localIPv6AddressesGracePeriod is the duration to keep reporting the IPv6 addresses of the local
+	// instance if IMDS omits them, to avoid flapping the node addresses
+	localIPv6AddressesGracePeriod = 5 * time.Minute
 )
 
 var (
 	errNodeNotInitialized = fmt.Errorf("providerID is empty, the node is not initialized yet")
+
+	// shutdownScheduledEventTypes are the types of the scheduled events shutting down the instance.
+	// Freeze is not included since the instance is only paused for a few seconds.
+	shutdownScheduledEventTypes = sets.NewString(scheduledEventTypePreempt, scheduledEventTypeReboot, scheduledEventTypeRedeploy, scheduledEventTypeTerminate)
 )
 
 func (az *Cloud) addressGetter(nodeName types.NodeName) ([]v1.NodeAddress, error) {
@@ -65,11 +84,81 @@ func (az *Cloud) addressGetter(nodeName types.NodeName) ([]v1.NodeAddress, error
 			Address: publicIP,
 		})
 	}
+
+	// add the private IP of the other IP family in the ipConfigurations of the NIC on dual-stack clusters
+	if az.ipv6DualStackEnabled {
+		privateIPs, err := az.VMSet.GetPrivateIPsByNodeName(string(nodeName))
+		if err != nil {
+			klog.Warningf("NodeAddresses(%s): failed to get the private IPs of the node: %v", nodeName, err)
+		}
+		for _, privateIP := range privateIPs {
+			if utilnet.IsIPv6String(privateIP) != utilnet.IsIPv6String(ip) {
+				addresses = append(addresses, v1.NodeAddress{
+					Type:    v1.NodeInternalIP,
+					Address: privateIP,
+				})
+				break
+			}
+		}
+	}
+
+	az.sortNodeAddressesByIPFamily(addresses)
 	return addresses, nil
 }
 
+// isIPv6Primary returns true if the IPv6 node addresses should be reported before the IPv4 ones.
+func (az *Cloud) isIPv6Primary() bool {
+	return strings.EqualFold(az.PrimaryIPFamily, string(v1.IPv6Protocol))
+}
+
+// sortNodeAddressesByIPFamily moves the IP addresses of the primary IP family before the ones of the other
+// IP family, the order of the addresses in the same IP family and the hostname is kept.
+func (az *Cloud) sortNodeAddressesByIPFamily(addresses []v1.NodeAddress) {
+	ipv6Primary := az.isIPv6Primary()
+	isPrimary := func(address v1.NodeAddress) bool {
+		if address.Type == v1.NodeHostName || address.Type == v1.NodeInternalDNS || address.Type == v1.NodeExternalDNS {
+			return true
+		}
+		return utilnet.IsIPv6String(address.Address) == ipv6Primary
+	}
+	sort.SliceStable(addresses, func(i, j int) bool {
+		return isPrimary(addresses[i]) && !isPrimary(addresses[j])
+	})
+}
+
+// keepLocalIPv6Addresses records the IPv6 addresses of the local instance got from IMDS. If they are
+// missing, the ones got last time are returned if they are got within the grace period.
+func (az *Cloud) keepLocalIPv6Addresses(addresses []v1.NodeAddress) []v1.NodeAddress {
+	az.localIPv6AddressesLock.Lock()
+	defer az.localIPv6AddressesLock.Unlock()
+
+	var ipv6Addresses []v1.NodeAddress
+	for _, address := range addresses {
+		if address.Type != v1.NodeHostName && utilnet.IsIPv6String(address.Address) {
+			ipv6Addresses = append(ipv6Addresses, address)
+		}
+	}
+	if len(ipv6Addresses) > 0 {
+		az.localIPv6Addresses = ipv6Addresses
+		az.localIPv6AddressesTime = time.Now()
+		return addresses
+	}
+
+	if len(az.localIPv6Addresses) > 0 && time.Since(az.localIPv6AddressesTime) < localIPv6AddressesGracePeriod {
+		klog.Warningf("IPv6 addresses are missing in instance metadata, keep reporting the last ones %v", az.localIPv6Addresses)
+		return append(addresses, az.localIPv6Addresses...)
+	}
+	return addresses
+}
+
 // NodeAddresses returns the addresses of the specified instance.
-func (az *Cloud) NodeAddresses(ctx context.Context, name types.NodeName) ([]v1.NodeAddress, error) {
+func (az *Cloud) NodeAddresses(ctx context.Context, name types.NodeName) (addresses []v1.NodeAddress, err error) {
+	ctx = metrics.WithSource(ctx, metrics.SourceNodeController)
+	mc := metrics.NewMetricContext("instances", "node_addresses", az.ResourceGroup, az.SubscriptionID, string(name)).WithContext(ctx)
+	defer func() {
+		mc.ObserveOperationWithResult(err == nil)
+	}()
+
 	// Returns nil for unmanaged nodes because azure cloud provider couldn't fetch information for them.
 	unmanaged, err := az.IsNodeUnmanaged(string(name))
 	if err != nil {
@@ -121,21 +210,12 @@ func (az *Cloud) getLocalInstanceNodeAddresses(netInterfaces []NetworkInterface,
 	addresses := []v1.NodeAddress{
 		{Type: v1.NodeHostName, Address: nodeName},
 	}
-	if len(netInterface.IPV4.IPAddress) > 0 && len(netInterface.IPV4.IPAddress[0].PrivateIP) > 0 {
-		address := netInterface.IPV4.IPAddress[0]
-		addresses = append(addresses, v1.NodeAddress{
-			Type:    v1.NodeInternalIP,
-			Address: address.PrivateIP,
-		})
-		if len(address.PublicIP) > 0 {
-			addresses = append(addresses, v1.NodeAddress{
-				Type:    v1.NodeExternalIP,
-				Address: address.PublicIP,
-			})
+	// Only the addresses of the primary IP configurations are reported, the secondary ones,
+	// e.g. the ones allocated to pods by Azure CNI, are excluded.
+	for _, address := range []*IPAddress{netInterface.IPV4.primaryIPAddress(), netInterface.IPV6.primaryIPAddress()} {
+		if address == nil || len(address.PrivateIP) == 0 {
+			continue
 		}
-	}
-	if len(netInterface.IPV6.IPAddress) > 0 && len(netInterface.IPV6.IPAddress[0].PrivateIP) > 0 {
-		address := netInterface.IPV6.IPAddress[0]
 		addresses = append(addresses, v1.NodeAddress{
 			Type:    v1.NodeInternalIP,
 			Address: address.PrivateIP,
@@ -153,6 +233,9 @@ func (az *Cloud) getLocalInstanceNodeAddresses(netInterfaces []NetworkInterface,
 		_ = az.Metadata.imsCache.Delete(consts.MetadataCacheKey)
 		return nil, fmt.Errorf("get empty IP addresses from instance metadata service")
 	}
+
+	addresses = az.keepLocalIPv6Addresses(addresses)
+	az.sortNodeAddressesByIPFamily(addresses)
 	return addresses, nil
 }
 
@@ -287,7 +370,7 @@ func (az *Cloud) InstanceShutdownByProviderID(ctx context.Context, providerID st
 
 	status := strings.ToLower(powerStatus)
 	provisioningSucceeded := strings.EqualFold(strings.ToLower(provisioningState), strings.ToLower(string(compute.ProvisioningStateSucceeded)))
-	return provisioningSucceeded && (status == vmPowerStateStopped || status == vmPowerStateDeallocated || status == vmPowerStateDeallocating), nil
+	return provisioningSucceeded && (status == vmPowerStateStopped || status == vmPowerStateStopping || status == vmPowerStateDeallocated || status == vmPowerStateDeallocating), nil
 }
 
 // InstanceShutdown returns true if the instance is shutdown according to the cloud provider.
@@ -374,28 +457,34 @@ func (az *Cloud) InstanceID(ctx context.Context, name types.NodeName) (string, e
 			// vmSet == nil indicates credentials are not provided.
 			return "", fmt.Errorf("no credentials provided for Azure cloud provider")
 		}
-		return az.getLocalInstanceProviderID(metadata, nodeName)
+		return az.getLocalInstanceProviderID(metadata)
 	}
 
 	return az.VMSet.GetInstanceIDByNodeName(nodeName)
 }
 
-func (az *Cloud) getLocalInstanceProviderID(metadata *InstanceMetadata, nodeName string) (string, error) {
+// getLocalInstanceProviderID composes the provider ID of the local instance from the instance metadata.
+// It is the only place normalizing the casing of the provider IDs got from the instance metadata: the
+// subscription ID and resource group are lower-cased, while the VM name in the metadata is used as is, the
+// same as the provider IDs got from ARM. The node name isn't used since it is lower-cased (and could be
+// truncated) from the hostname of Windows nodes, which doesn't match the VM name.
+func (az *Cloud) getLocalInstanceProviderID(metadata *InstanceMetadata) (string, error) {
 	// Get resource group name and subscription ID.
 	resourceGroup := strings.ToLower(metadata.Compute.ResourceGroup)
 	subscriptionID := strings.ToLower(metadata.Compute.SubscriptionID)
+	vmName := metadata.Compute.Name
 
-	// Compose instanceID based on nodeName for standard instance.
+	// Compose instanceID based on the VM name for standard instance.
 	if metadata.Compute.VMScaleSetName == "" {
-		return az.getStandardMachineID(subscriptionID, resourceGroup, nodeName), nil
+		return az.getStandardMachineID(subscriptionID, resourceGroup, vmName), nil
 	}
 
 	// Get scale set name and instanceID from vmName for vmss.
-	ssName, instanceID, err := extractVmssVMName(metadata.Compute.Name)
+	ssName, instanceID, err := extractVmssVMName(vmName)
 	if err != nil {
 		if errors.Is(err, ErrorNotVmssInstance) {
 			// Compose machineID for standard Node.
-			return az.getStandardMachineID(subscriptionID, resourceGroup, nodeName), nil
+			return az.getStandardMachineID(subscriptionID, resourceGroup, vmName), nil
 		}
 		return "", err
 	}
@@ -403,6 +492,90 @@ func (az *Cloud) getLocalInstanceProviderID(metadata *InstanceMetadata, nodeName
 	return az.getVmssMachineID(subscriptionID, resourceGroup, ssName, instanceID), nil
 }
 
+// getLocalInstanceMetadata gets the instance metadata of the local instance, which must contain the compute metadata.
+func (az *Cloud) getLocalInstanceMetadata() (*InstanceMetadata, error) {
+	metadata, err := az.Metadata.GetMetadata(azcache.CacheReadTypeDefault)
+	if err != nil {
+		return nil, err
+	}
+	if metadata.Compute == nil {
+		_ = az.Metadata.imsCache.Delete(consts.MetadataCacheKey)
+		return nil, fmt.Errorf("failure of getting compute information from instance metadata")
+	}
+	return metadata, nil
+}
+
+// LocalInstanceNodeAddresses returns the addresses of the local instance from the instance metadata only.
+// It is used by cloud-node-manager, which always runs on the node to initialize and may run without credentials.
+func (az *Cloud) LocalInstanceNodeAddresses(name types.NodeName) ([]v1.NodeAddress, error) {
+	metadata, err := az.getLocalInstanceMetadata()
+	if err != nil {
+		return nil, err
+	}
+	if metadata.Network == nil {
+		_ = az.Metadata.imsCache.Delete(consts.MetadataCacheKey)
+		return nil, fmt.Errorf("failure of getting network information from instance metadata")
+	}
+
+	return az.getLocalInstanceNodeAddresses(metadata.Network.Interface, string(name))
+}
+
+// LocalInstanceID returns the cloud provider ID of the local instance from the instance metadata only.
+// The VM name in the instance metadata is used since the hostname of Windows nodes could be truncated.
+func (az *Cloud) LocalInstanceID() (string, error) {
+	metadata, err := az.getLocalInstanceMetadata()
+	if err != nil {
+		return "", err
+	}
+	if metadata.Compute.Name == "" || metadata.Compute.SubscriptionID == "" || metadata.Compute.ResourceGroup == "" {
+		return "", fmt.Errorf("failure of getting the VM name, subscription ID and resource group from instance metadata")
+	}
+
+	return az.getLocalInstanceProviderID(metadata)
+}
+
+// LocalInstanceType returns the type of the local instance from the instance metadata only.
+func (az *Cloud) LocalInstanceType() (string, error) {
+	metadata, err := az.getLocalInstanceMetadata()
+	if err != nil {
+		return "", err
+	}
+	if metadata.Compute.VMSize == "" {
+		return "", fmt.Errorf("failure of getting the VM size from instance metadata")
+	}
+
+	return metadata.Compute.VMSize, nil
+}
+
+// LocalInstanceScheduledShutdownEvent returns the type of the scheduled event which would shut down
+// the local instance, e.g. Preempt, Reboot or Redeploy. Empty string is returned if there isn't any.
+func (az *Cloud) LocalInstanceScheduledShutdownEvent() (string, error) {
+	metadata, err := az.getLocalInstanceMetadata()
+	if err != nil {
+		return "", err
+	}
+
+	scheduledEvents, err := az.Metadata.GetScheduledEvents()
+	if err != nil {
+		return "", err
+	}
+
+	for _, event := range scheduledEvents.Events {
+		if !shutdownScheduledEventTypes.Has(event.EventType) {
+			continue
+		}
+		for _, resource := range event.Resources {
+			if strings.EqualFold(resource, metadata.Compute.Name) {
+				klog.V(2).Infof("LocalInstanceScheduledShutdownEvent: got scheduled event %s(%s) in status %s not before %q for instance %s",
+					event.EventType, event.EventID, event.EventStatus, event.NotBefore, resource)
+				return event.EventType, nil
+			}
+		}
+	}
+
+	return "", nil
+}
+
 // InstanceTypeByProviderID returns the cloudprovider instance type of the node with the specified unique providerID
 // This method will not be called from the node that is requesting this ID. i.e. metadata service
 // and other local methods cannot be used here
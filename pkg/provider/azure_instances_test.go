@@ -18,11 +18,14 @@ package provider
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2021-07-01/compute"
 	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2021-08-01/network"
@@ -42,6 +45,7 @@ import (
 	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/vmssvmclient/mockvmssvmclient"
 	azcache "sigs.k8s.io/cloud-provider-azure/pkg/cache"
 	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+	"sigs.k8s.io/cloud-provider-azure/pkg/metrics"
 	"sigs.k8s.io/cloud-provider-azure/pkg/retry"
 )
 
@@ -163,6 +167,15 @@ func TestInstanceID(t *testing.T) {
 			useInstanceMetadata: true,
 			expectedID:          "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/vm2",
 		},
+		{
+			name:                "InstanceID should use the VM name in the metadata for Windows nodes whose hostname is lower-cased",
+			vmList:              []string{"WIN-VM1"},
+			nodeName:            "win-vm1",
+			metadataName:        "WIN-VM1",
+			vmType:              consts.VMTypeStandard,
+			useInstanceMetadata: true,
+			expectedID:          "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/WIN-VM1",
+		},
 		{
 			name:         "InstanceID should get instanceID from Azure API if cloud.UseInstanceMetadata is false",
 			vmList:       []string{"vm2"},
@@ -317,11 +330,11 @@ func TestInstanceShutdownByProviderID(t *testing.T) {
 			expected:          false,
 		},
 		{
-			name:       "InstanceShutdownByProviderID should return false if the vm is in PowerState/Stopping status",
+			name:       "InstanceShutdownByProviderID should return true if the vm is in PowerState/Stopping status",
 			vmList:     map[string]string{"vm6": "PowerState/Stopping"},
 			nodeName:   "vm6",
 			providerID: "azure:///subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/vm6",
-			expected:   false,
+			expected:   true,
 		},
 		{
 			name:       "InstanceShutdownByProviderID should return false if the vm is in PowerState/Unknown status",
@@ -758,10 +771,10 @@ func TestInstanceExistsByProviderID(t *testing.T) {
 		ss.cloud.VirtualMachineScaleSetVMsClient = mockVMSSVMClient
 
 		expectedScaleSet := buildTestVMSS(test.scaleSet, test.scaleSet)
-		mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any()).Return([]compute.VirtualMachineScaleSet{expectedScaleSet}, test.rerr).AnyTimes()
+		mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).Return([]compute.VirtualMachineScaleSet{expectedScaleSet}, test.rerr).AnyTimes()
 
 		expectedVMs, _, _ := buildTestVirtualMachineEnv(ss.cloud, test.scaleSet, "", 0, test.vmList, "succeeded", false)
-		mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(expectedVMs, test.rerr).AnyTimes()
+		mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(expectedVMs, test.rerr).AnyTimes()
 
 		mockVMsClient := ss.cloud.VirtualMachinesClient.(*mockvmclient.MockInterface)
 		mockVMsClient.EXPECT().List(gomock.Any(), gomock.Any()).Return([]compute.VirtualMachine{}, nil).AnyTimes()
@@ -771,6 +784,20 @@ func TestInstanceExistsByProviderID(t *testing.T) {
 	}
 }
 
+func TestNodeAddressesMetricSource(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cloud := GetTestCloud(ctrl)
+	cloud.unmanagedNodes.Insert("node1")
+
+	before := getOperationCount("instances_node_addresses", metrics.SourceNodeController)
+	addresses, err := cloud.NodeAddresses(context.TODO(), "node1")
+	assert.NoError(t, err)
+	assert.Nil(t, addresses)
+	assert.Equal(t, before+1, getOperationCount("instances_node_addresses", metrics.SourceNodeController))
+	assert.Zero(t, getOperationCount("instances_node_addresses", metrics.SourceServiceController))
+}
+
 func TestNodeAddressesByProviderID(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -967,3 +994,293 @@ func TestCloud_InstanceExists(t *testing.T) {
 		assert.False(t, exist)
 	})
 }
+
+func TestGetLocalInstanceNodeAddressesIPFamilies(t *testing.T) {
+	ipv4Interface := NetworkData{IPAddress: []IPAddress{{PrivateIP: "10.0.0.4", PublicIP: "1.2.3.4"}}}
+	ipv6Interface := NetworkData{IPAddress: []IPAddress{{PrivateIP: "fd00::4", PublicIP: "2001::4"}}}
+
+	testcases := []struct {
+		desc            string
+		primaryIPFamily string
+		netInterface    NetworkInterface
+		expected        []v1.NodeAddress
+	}{
+		{
+			desc:         "getLocalInstanceNodeAddresses should return IPv4 addresses for IPv4 only instance",
+			netInterface: NetworkInterface{IPV4: ipv4Interface},
+			expected: []v1.NodeAddress{
+				{Type: v1.NodeHostName, Address: "vm1"},
+				{Type: v1.NodeInternalIP, Address: "10.0.0.4"},
+				{Type: v1.NodeExternalIP, Address: "1.2.3.4"},
+			},
+		},
+		{
+			desc:         "getLocalInstanceNodeAddresses should return IPv6 addresses for IPv6 only instance",
+			netInterface: NetworkInterface{IPV6: ipv6Interface},
+			expected: []v1.NodeAddress{
+				{Type: v1.NodeHostName, Address: "vm1"},
+				{Type: v1.NodeInternalIP, Address: "fd00::4"},
+				{Type: v1.NodeExternalIP, Address: "2001::4"},
+			},
+		},
+		{
+			desc:         "getLocalInstanceNodeAddresses should return IPv4 addresses first for dual-stack instance by default",
+			netInterface: NetworkInterface{IPV4: ipv4Interface, IPV6: ipv6Interface},
+			expected: []v1.NodeAddress{
+				{Type: v1.NodeHostName, Address: "vm1"},
+				{Type: v1.NodeInternalIP, Address: "10.0.0.4"},
+				{Type: v1.NodeExternalIP, Address: "1.2.3.4"},
+				{Type: v1.NodeInternalIP, Address: "fd00::4"},
+				{Type: v1.NodeExternalIP, Address: "2001::4"},
+			},
+		},
+		{
+			desc:            "getLocalInstanceNodeAddresses should return IPv6 addresses first for dual-stack instance if IPv6 is primary",
+			primaryIPFamily: "IPv6",
+			netInterface:    NetworkInterface{IPV4: ipv4Interface, IPV6: ipv6Interface},
+			expected: []v1.NodeAddress{
+				{Type: v1.NodeHostName, Address: "vm1"},
+				{Type: v1.NodeInternalIP, Address: "fd00::4"},
+				{Type: v1.NodeExternalIP, Address: "2001::4"},
+				{Type: v1.NodeInternalIP, Address: "10.0.0.4"},
+				{Type: v1.NodeExternalIP, Address: "1.2.3.4"},
+			},
+		},
+	}
+
+	for _, test := range testcases {
+		t.Run(test.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			cloud := GetTestCloud(ctrl)
+			cloud.PrimaryIPFamily = test.primaryIPFamily
+
+			addresses, err := cloud.getLocalInstanceNodeAddresses([]NetworkInterface{test.netInterface}, "vm1")
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, addresses)
+		})
+	}
+}
+
+func TestGetLocalInstanceNodeAddressesSecondaryIPConfigs(t *testing.T) {
+	testcases := []struct {
+		desc     string
+		network  string
+		expected []v1.NodeAddress
+	}{
+		{
+			desc: "getLocalInstanceNodeAddresses should exclude the secondary IP configurations allocated by Azure CNI",
+			network: `{"interface":[{"ipv4":{"ipAddress":[{"privateIpAddress":"10.240.0.4","publicIpAddress":"20.1.2.3"},` +
+				`{"privateIpAddress":"10.240.0.5","publicIpAddress":""},{"privateIpAddress":"10.240.0.6","publicIpAddress":""}],` +
+				`"subnet":[{"address":"10.240.0.0","prefix":"16"}]},"ipv6":{"ipAddress":[]},"macAddress":"000D3A6E1B2C"}]}`,
+			expected: []v1.NodeAddress{
+				{Type: v1.NodeHostName, Address: "akswin000000"},
+				{Type: v1.NodeInternalIP, Address: "10.240.0.4"},
+				{Type: v1.NodeExternalIP, Address: "20.1.2.3"},
+			},
+		},
+		{
+			desc: "getLocalInstanceNodeAddresses should honor the primary flag of the IP configurations on Windows",
+			network: `{"interface":[{"ipv4":{"ipAddress":[{"privateIpAddress":"10.240.0.36","publicIpAddress":""},` +
+				`{"privateIpAddress":"10.240.0.35","publicIpAddress":"","primary":true},{"privateIpAddress":"10.240.0.37","publicIpAddress":""}],` +
+				`"subnet":[{"address":"10.240.0.0","prefix":"16"}]},"ipv6":{"ipAddress":[]},"macAddress":"000D3A6E1B2D"}]}`,
+			expected: []v1.NodeAddress{
+				{Type: v1.NodeHostName, Address: "akswin000000"},
+				{Type: v1.NodeInternalIP, Address: "10.240.0.35"},
+			},
+		},
+		{
+			desc: "getLocalInstanceNodeAddresses should only return the addresses of the primary interface",
+			network: `{"interface":[{"ipv4":{"ipAddress":[{"privateIpAddress":"10.240.0.35","publicIpAddress":""}],` +
+				`"subnet":[{"address":"10.240.0.0","prefix":"16"}]},"ipv6":{"ipAddress":[]},"macAddress":"000D3A6E1B2D"},` +
+				`{"ipv4":{"ipAddress":[{"privateIpAddress":"10.241.0.4","publicIpAddress":""}],` +
+				`"subnet":[{"address":"10.241.0.0","prefix":"16"}]},"ipv6":{"ipAddress":[]},"macAddress":"000D3A6E1B2E"}]}`,
+			expected: []v1.NodeAddress{
+				{Type: v1.NodeHostName, Address: "akswin000000"},
+				{Type: v1.NodeInternalIP, Address: "10.240.0.35"},
+			},
+		},
+	}
+
+	for _, test := range testcases {
+		t.Run(test.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			cloud := GetTestCloud(ctrl)
+
+			network := NetworkMetadata{}
+			assert.NoError(t, json.Unmarshal([]byte(test.network), &network))
+			addresses, err := cloud.getLocalInstanceNodeAddresses(network.Interface, "akswin000000")
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, addresses)
+		})
+	}
+}
+
+func TestGetLocalInstanceNodeAddressesIPv6Missing(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cloud := GetTestCloud(ctrl)
+
+	dualStack := NetworkInterface{
+		IPV4: NetworkData{IPAddress: []IPAddress{{PrivateIP: "10.0.0.4"}}},
+		IPV6: NetworkData{IPAddress: []IPAddress{{PrivateIP: "fd00::4"}}},
+	}
+	ipv4Only := NetworkInterface{
+		IPV4: NetworkData{IPAddress: []IPAddress{{PrivateIP: "10.0.0.4"}}},
+	}
+	expectedDualStack := []v1.NodeAddress{
+		{Type: v1.NodeHostName, Address: "vm1"},
+		{Type: v1.NodeInternalIP, Address: "10.0.0.4"},
+		{Type: v1.NodeInternalIP, Address: "fd00::4"},
+	}
+
+	addresses, err := cloud.getLocalInstanceNodeAddresses([]NetworkInterface{dualStack}, "vm1")
+	assert.NoError(t, err)
+	assert.Equal(t, expectedDualStack, addresses)
+
+	// the IPv6 address should be kept if IMDS omits it momentarily
+	addresses, err = cloud.getLocalInstanceNodeAddresses([]NetworkInterface{ipv4Only}, "vm1")
+	assert.NoError(t, err)
+	assert.Equal(t, expectedDualStack, addresses)
+
+	// the IPv6 address should be dropped after the grace period
+	cloud.localIPv6AddressesTime = time.Now().Add(-localIPv6AddressesGracePeriod)
+	addresses, err = cloud.getLocalInstanceNodeAddresses([]NetworkInterface{ipv4Only}, "vm1")
+	assert.NoError(t, err)
+	assert.Equal(t, expectedDualStack[:2], addresses)
+}
+
+func TestNodeAddressesDualStack(t *testing.T) {
+	testcases := []struct {
+		desc            string
+		primaryIPFamily string
+		privateIPs      []string
+		privateIPsErr   error
+		expected        []v1.NodeAddress
+	}{
+		{
+			desc:       "NodeAddresses should return the private IPs of both IP families",
+			privateIPs: []string{"10.0.0.4", "fd00::4"},
+			expected: []v1.NodeAddress{
+				{Type: v1.NodeInternalIP, Address: "10.0.0.4"},
+				{Type: v1.NodeHostName, Address: "vm1"},
+				{Type: v1.NodeExternalIP, Address: "1.2.3.4"},
+				{Type: v1.NodeInternalIP, Address: "fd00::4"},
+			},
+		},
+		{
+			desc:            "NodeAddresses should return the IPv6 private IP first if IPv6 is primary",
+			primaryIPFamily: "IPv6",
+			privateIPs:      []string{"10.0.0.4", "fd00::4"},
+			expected: []v1.NodeAddress{
+				{Type: v1.NodeHostName, Address: "vm1"},
+				{Type: v1.NodeInternalIP, Address: "fd00::4"},
+				{Type: v1.NodeInternalIP, Address: "10.0.0.4"},
+				{Type: v1.NodeExternalIP, Address: "1.2.3.4"},
+			},
+		},
+		{
+			desc:          "NodeAddresses should return the primary IP family if failed to get the private IPs",
+			privateIPsErr: fmt.Errorf("error"),
+			expected: []v1.NodeAddress{
+				{Type: v1.NodeInternalIP, Address: "10.0.0.4"},
+				{Type: v1.NodeHostName, Address: "vm1"},
+				{Type: v1.NodeExternalIP, Address: "1.2.3.4"},
+			},
+		},
+	}
+
+	for _, test := range testcases {
+		t.Run(test.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			cloud := GetTestCloud(ctrl)
+			cloud.ipv6DualStackEnabled = true
+			cloud.PrimaryIPFamily = test.primaryIPFamily
+			mockVMSet := NewMockVMSet(ctrl)
+			mockVMSet.EXPECT().GetIPByNodeName("vm1").Return("10.0.0.4", "1.2.3.4", nil)
+			mockVMSet.EXPECT().GetPrivateIPsByNodeName("vm1").Return(test.privateIPs, test.privateIPsErr)
+			cloud.VMSet = mockVMSet
+
+			addresses, err := cloud.addressGetter("vm1")
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, addresses)
+		})
+	}
+}
+
+func TestLocalInstanceScheduledShutdownEvent(t *testing.T) {
+	scheduledEventsTemplate := `{"DocumentIncarnation": 1, "Events": [{"EventId": "event", "EventType": "%s", "ResourceType": "VirtualMachine", "Resources": ["%s"], "EventStatus": "Scheduled", "NotBefore": "Mon, 19 Sep 2022 18:29:47 GMT", "EventSource": "Platform"}]}`
+	testcases := []struct {
+		desc              string
+		scheduledEvents   string
+		statusCode        int
+		expectedEventType string
+		expectedErr       bool
+	}{
+		{
+			desc:              "Preempt event of the instance should be returned",
+			scheduledEvents:   fmt.Sprintf(scheduledEventsTemplate, "Preempt", "vm1"),
+			expectedEventType: "Preempt",
+		},
+		{
+			desc:              "Reboot event of the instance should be returned",
+			scheduledEvents:   fmt.Sprintf(scheduledEventsTemplate, "Reboot", "vm1"),
+			expectedEventType: "Reboot",
+		},
+		{
+			desc:              "Redeploy event of the instance should be returned",
+			scheduledEvents:   fmt.Sprintf(scheduledEventsTemplate, "Redeploy", "VM1"),
+			expectedEventType: "Redeploy",
+		},
+		{
+			desc:            "Freeze event shouldn't be returned since the instance is not shut down",
+			scheduledEvents: fmt.Sprintf(scheduledEventsTemplate, "Freeze", "vm1"),
+		},
+		{
+			desc:            "events of other instances shouldn't be returned",
+			scheduledEvents: fmt.Sprintf(scheduledEventsTemplate, "Preempt", "vm2"),
+		},
+		{
+			desc:            "empty string should be returned if there is no scheduled events",
+			scheduledEvents: `{"DocumentIncarnation": 2, "Events": []}`,
+		},
+		{
+			desc:        "error should be returned if failed to get the scheduled events",
+			statusCode:  http.StatusNotFound,
+			expectedErr: true,
+		},
+	}
+
+	for _, test := range testcases {
+		t.Run(test.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			cloud := GetTestCloud(ctrl)
+
+			mux := http.NewServeMux()
+			mux.HandleFunc(consts.ImdsInstanceURI, func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, `{"compute":{"name":"vm1"}}`)
+			})
+			mux.HandleFunc(consts.ImdsScheduledEventsURI, func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, consts.ImdsScheduledEventsAPIVersion, r.URL.Query().Get("api-version"))
+				if test.statusCode != 0 {
+					w.WriteHeader(test.statusCode)
+					return
+				}
+				fmt.Fprint(w, test.scheduledEvents)
+			})
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			var err error
+			cloud.Metadata, err = NewInstanceMetadataService(server.URL)
+			assert.NoError(t, err)
+
+			eventType, err := cloud.LocalInstanceScheduledShutdownEvent()
+			assert.Equal(t, test.expectedErr, err != nil, "unexpected error: %v", err)
+			assert.Equal(t, test.expectedEventType, eventType)
+		})
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2021-08-01/network"
 	"github.com/Azure/go-autorest/autorest/to"
@@ -40,6 +41,7 @@ import (
 
 	azcache "sigs.k8s.io/cloud-provider-azure/pkg/cache"
 	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+	"sigs.k8s.io/cloud-provider-azure/pkg/log"
 	"sigs.k8s.io/cloud-provider-azure/pkg/metrics"
 	"sigs.k8s.io/cloud-provider-azure/pkg/retry"
 )
@@ -47,11 +49,13 @@ import (
 // GetLoadBalancer returns whether the specified load balancer and its components exist, and
 // if so, what its status is.
 func (az *Cloud) GetLoadBalancer(ctx context.Context, clusterName string, service *v1.Service) (status *v1.LoadBalancerStatus, exists bool, err error) {
+	ctx = metrics.WithSource(ctx, metrics.SourceServiceController)
+	ctx = log.NewOperationContext(ctx, getServiceName(service))
 	// Since public IP is not a part of the load balancer on Azure,
 	// there is a chance that we could orphan public IP resources while we delete the load balancer (kubernetes/kubernetes#80571).
 	// We need to make sure the existence of the load balancer depends on the load balancer resource and public IP resource on Azure.
 	existsPip := func() bool {
-		pipName, _, err := az.determinePublicIPName(clusterName, service, nil)
+		pipName, _, err := az.determinePublicIPName(ctx, clusterName, service, nil)
 		if err != nil {
 			return false
 		}
@@ -63,15 +67,14 @@ func (az *Cloud) GetLoadBalancer(ctx context.Context, clusterName string, servic
 		return existsPip
 	}()
 
-	_, status, existsLb, err := az.getServiceLoadBalancer(service, clusterName, nil, false, []network.LoadBalancer{})
+	_, status, existsLb, err := az.getServiceLoadBalancer(ctx, service, clusterName, nil, false, []network.LoadBalancer{})
 	if err != nil {
 		return nil, existsPip, err
 	}
 
 	// Return exists = false only if the load balancer and the public IP are not found on Azure
 	if !existsLb && !existsPip {
-		serviceName := getServiceName(service)
-		klog.V(5).Infof("getloadbalancer (cluster:%s) (service:%s) - doesn't exist", clusterName, serviceName)
+		klog.V(5).InfoS("GetLoadBalancer: load balancer doesn't exist", log.KeysAndValues(ctx, "cluster", clusterName)...)
 		return nil, false, nil
 	}
 
@@ -88,16 +91,15 @@ func getPublicIPDomainNameLabel(service *v1.Service) (string, bool) {
 
 // reconcileService reconcile the LoadBalancer service. It returns LoadBalancerStatus on success.
 func (az *Cloud) reconcileService(ctx context.Context, clusterName string, service *v1.Service, nodes []*v1.Node) (*v1.LoadBalancerStatus, error) {
-	serviceName := getServiceName(service)
-	lb, err := az.reconcileLoadBalancer(clusterName, service, nodes, true /* wantLb */)
+	lb, err := az.reconcileLoadBalancer(ctx, clusterName, service, nodes, true /* wantLb */)
 	if err != nil {
-		klog.Errorf("reconcileLoadBalancer(%s) failed: %v", serviceName, err)
+		klog.ErrorS(err, "reconcileLoadBalancer failed", log.KeysAndValues(ctx)...)
 		return nil, err
 	}
 
-	lbStatus, fipConfig, err := az.getServiceLoadBalancerStatus(service, lb, nil)
+	lbStatus, fipConfig, err := az.getServiceLoadBalancerStatus(ctx, service, lb, nil)
 	if err != nil {
-		klog.Errorf("getServiceLoadBalancerStatus(%s) failed: %v", serviceName, err)
+		klog.ErrorS(err, "getServiceLoadBalancerStatus failed", log.KeysAndValues(ctx)...)
 		return nil, err
 	}
 
@@ -105,30 +107,30 @@ func (az *Cloud) reconcileService(ctx context.Context, clusterName string, servi
 	if lbStatus != nil && len(lbStatus.Ingress) > 0 {
 		serviceIP = &lbStatus.Ingress[0].IP
 	}
-	klog.V(2).Infof("reconcileService: reconciling security group for service %q with IP %q, wantLb = true", serviceName, logSafe(serviceIP))
-	if _, err := az.reconcileSecurityGroup(clusterName, service, serviceIP, true /* wantLb */); err != nil {
-		klog.Errorf("reconcileSecurityGroup(%s) failed: %#v", serviceName, err)
+	klog.V(2).InfoS("reconcileService: reconciling security group", log.KeysAndValues(ctx, "serviceIP", logSafe(serviceIP), "wantLb", true)...)
+	if _, err := az.reconcileSecurityGroup(ctx, clusterName, service, serviceIP, true /* wantLb */); err != nil {
+		klog.ErrorS(err, "reconcileSecurityGroup failed", log.KeysAndValues(ctx)...)
 		return nil, err
 	}
 
 	if fipConfig != nil {
-		if err := az.reconcilePrivateLinkService(clusterName, service, fipConfig, true /* wantPLS */); err != nil {
-			klog.Errorf("reconcilePrivateLinkService(%s) failed: %#v", serviceName, err)
+		if err := az.reconcilePrivateLinkService(ctx, clusterName, service, fipConfig, true /* wantPLS */); err != nil {
+			klog.ErrorS(err, "reconcilePrivateLinkService failed", log.KeysAndValues(ctx)...)
 			return nil, err
 		}
 	}
 
 	updateService := updateServiceLoadBalancerIP(service, to.String(serviceIP))
 	flippedService := flipServiceInternalAnnotation(updateService)
-	if _, err := az.reconcileLoadBalancer(clusterName, flippedService, nil, false /* wantLb */); err != nil {
-		klog.Errorf("reconcileLoadBalancer(%s) failed: %#v", serviceName, err)
+	if _, err := az.reconcileLoadBalancer(ctx, clusterName, flippedService, nil, false /* wantLb */); err != nil {
+		klog.ErrorS(err, "reconcileLoadBalancer failed", log.KeysAndValues(ctx)...)
 		return nil, err
 	}
 
 	// lb is not reused here because the ETAG may be changed in above operations, hence reconcilePublicIP() would get lb again from cache.
-	klog.V(2).Infof("reconcileService: reconciling pip")
-	if _, err := az.reconcilePublicIP(clusterName, updateService, to.String(lb.Name), true /* wantLb */); err != nil {
-		klog.Errorf("reconcilePublicIP(%s) failed: %#v", serviceName, err)
+	klog.V(2).InfoS("reconcileService: reconciling pip", log.KeysAndValues(ctx)...)
+	if _, err := az.reconcilePublicIP(ctx, clusterName, updateService, to.String(lb.Name), true /* wantLb */); err != nil {
+		klog.ErrorS(err, "reconcilePublicIP failed", log.KeysAndValues(ctx)...)
 		return nil, err
 	}
 
@@ -142,13 +144,17 @@ func (az *Cloud) EnsureLoadBalancer(ctx context.Context, clusterName string, ser
 	// Here we'll firstly ensure service do not lie in the opposite LB.
 	var err error
 	serviceName := getServiceName(service)
-	mc := metrics.NewMetricContext("services", "ensure_loadbalancer", az.ResourceGroup, az.SubscriptionID, serviceName)
-	klog.V(5).InfoS("EnsureLoadBalancer Start", "service", serviceName, "cluster", clusterName, "service_spec", service)
+	ctx = metrics.WithSource(ctx, metrics.SourceServiceController)
+	start := time.Now()
+	mc := metrics.NewMetricContext("services", "ensure_loadbalancer", az.ResourceGroup, az.SubscriptionID, serviceName).WithContext(ctx)
+	ctx = log.NewOperationContext(ctx, serviceName)
+	klog.V(5).InfoS("EnsureLoadBalancer Start", log.KeysAndValues(ctx, "cluster", clusterName, "service_spec", service)...)
 
 	isOperationSucceeded := false
 	defer func() {
 		mc.ObserveOperationWithResult(isOperationSucceeded)
-		klog.V(5).InfoS("EnsureLoadBalancer Finish", "service", serviceName, "cluster", clusterName, "service_spec", service, "error", err)
+		metrics.ObserveServiceReconcile("ensure_loadbalancer", serviceName, requiresInternalLoadBalancer(service), start, isOperationSucceeded)
+		klog.V(5).InfoS("EnsureLoadBalancer Finish", log.KeysAndValues(ctx, "cluster", clusterName, "service_spec", service, "error", err, log.DurationMsKey, log.DurationMs(start))...)
 	}()
 
 	lbStatus, err := az.reconcileService(ctx, clusterName, service, nodes)
@@ -164,22 +170,26 @@ func (az *Cloud) EnsureLoadBalancer(ctx context.Context, clusterName string, ser
 func (az *Cloud) UpdateLoadBalancer(ctx context.Context, clusterName string, service *v1.Service, nodes []*v1.Node) error {
 	var err error
 	serviceName := getServiceName(service)
-	mc := metrics.NewMetricContext("services", "update_loadbalancer", az.ResourceGroup, az.SubscriptionID, serviceName)
-	klog.V(5).InfoS("UpdateLoadBalancer Start", "service", serviceName, "cluster", clusterName, "service_spec", service)
+	ctx = metrics.WithSource(ctx, metrics.SourceServiceController)
+	start := time.Now()
+	mc := metrics.NewMetricContext("services", "update_loadbalancer", az.ResourceGroup, az.SubscriptionID, serviceName).WithContext(ctx)
+	ctx = log.NewOperationContext(ctx, serviceName)
+	klog.V(5).InfoS("UpdateLoadBalancer Start", log.KeysAndValues(ctx, "cluster", clusterName, "service_spec", service)...)
 	isOperationSucceeded := false
 	defer func() {
 		mc.ObserveOperationWithResult(isOperationSucceeded)
-		klog.V(5).InfoS("UpdateLoadBalancer Finish", "service", serviceName, "cluster", clusterName, "service_spec", service, "error", err)
+		metrics.ObserveServiceReconcile("update_loadbalancer", serviceName, requiresInternalLoadBalancer(service), start, isOperationSucceeded)
+		klog.V(5).InfoS("UpdateLoadBalancer Finish", log.KeysAndValues(ctx, "cluster", clusterName, "service_spec", service, "error", err, log.DurationMsKey, log.DurationMs(start))...)
 	}()
 
-	shouldUpdateLB, err := az.shouldUpdateLoadBalancer(clusterName, service, nodes)
+	shouldUpdateLB, err := az.shouldUpdateLoadBalancer(ctx, clusterName, service, nodes)
 	if err != nil {
 		return err
 	}
 
 	if !shouldUpdateLB {
 		isOperationSucceeded = true
-		klog.V(2).Infof("UpdateLoadBalancer: skipping service %s because it is either being deleted or does not exist anymore", service.Name)
+		klog.V(2).InfoS("UpdateLoadBalancer: skipping service because it is either being deleted or does not exist anymore", log.KeysAndValues(ctx)...)
 		return nil
 	}
 
@@ -202,12 +212,16 @@ func (az *Cloud) EnsureLoadBalancerDeleted(ctx context.Context, clusterName stri
 	var err error
 	isInternal := requiresInternalLoadBalancer(service)
 	serviceName := getServiceName(service)
-	mc := metrics.NewMetricContext("services", "ensure_loadbalancer_deleted", az.ResourceGroup, az.SubscriptionID, serviceName)
-	klog.V(5).InfoS("EnsureLoadBalancerDeleted Start", "service", serviceName, "cluster", clusterName, "service_spec", service)
+	ctx = metrics.WithSource(ctx, metrics.SourceServiceController)
+	start := time.Now()
+	mc := metrics.NewMetricContext("services", "ensure_loadbalancer_deleted", az.ResourceGroup, az.SubscriptionID, serviceName).WithContext(ctx)
+	ctx = log.NewOperationContext(ctx, serviceName)
+	klog.V(5).InfoS("EnsureLoadBalancerDeleted Start", log.KeysAndValues(ctx, "cluster", clusterName, "service_spec", service)...)
 	isOperationSucceeded := false
 	defer func() {
 		mc.ObserveOperationWithResult(isOperationSucceeded)
-		klog.V(5).InfoS("EnsureLoadBalancerDeleted Finish", "service", serviceName, "cluster", clusterName, "service_spec", service, "error", err)
+		metrics.ObserveServiceReconcile("ensure_loadbalancer_deleted", serviceName, requiresInternalLoadBalancer(service), start, isOperationSucceeded)
+		klog.V(5).InfoS("EnsureLoadBalancerDeleted Finish", log.KeysAndValues(ctx, "cluster", clusterName, "service_spec", service, "error", err, log.DurationMsKey, log.DurationMs(start))...)
 	}()
 
 	serviceIPToCleanup, err := az.findServiceIPAddress(ctx, clusterName, service, isInternal)
@@ -215,23 +229,23 @@ func (az *Cloud) EnsureLoadBalancerDeleted(ctx context.Context, clusterName stri
 		return err
 	}
 
-	klog.V(2).Infof("EnsureLoadBalancerDeleted: reconciling security group for service %q with IP %q, wantLb = false", serviceName, serviceIPToCleanup)
-	_, err = az.reconcileSecurityGroup(clusterName, service, &serviceIPToCleanup, false /* wantLb */)
+	klog.V(2).InfoS("EnsureLoadBalancerDeleted: reconciling security group", log.KeysAndValues(ctx, "serviceIP", serviceIPToCleanup, "wantLb", false)...)
+	_, err = az.reconcileSecurityGroup(ctx, clusterName, service, &serviceIPToCleanup, false /* wantLb */)
 	if err != nil {
 		return err
 	}
 
-	_, err = az.reconcileLoadBalancer(clusterName, service, nil, false /* wantLb */)
+	_, err = az.reconcileLoadBalancer(ctx, clusterName, service, nil, false /* wantLb */)
 	if err != nil && !retry.HasStatusForbiddenOrIgnoredError(err) {
 		return err
 	}
 
-	_, err = az.reconcilePublicIP(clusterName, service, "", false /* wantLb */)
+	_, err = az.reconcilePublicIP(ctx, clusterName, service, "", false /* wantLb */)
 	if err != nil {
 		return err
 	}
 
-	klog.V(2).Infof("Delete service (%s): FINISH", serviceName)
+	klog.V(2).InfoS("Delete service: FINISH", log.KeysAndValues(ctx)...)
 	isOperationSucceeded = true
 
 	return nil
@@ -296,7 +310,7 @@ func (az *Cloud) shouldChangeLoadBalancer(service *v1.Service, currLBName, clust
 	return true
 }
 
-func (az *Cloud) removeFrontendIPConfigurationFromLoadBalancer(lb *network.LoadBalancer, existingLBs []network.LoadBalancer, fip *network.FrontendIPConfiguration, clusterName string, service *v1.Service) error {
+func (az *Cloud) removeFrontendIPConfigurationFromLoadBalancer(ctx context.Context, lb *network.LoadBalancer, existingLBs []network.LoadBalancer, fip *network.FrontendIPConfiguration, clusterName string, service *v1.Service) error {
 	if lb == nil || lb.LoadBalancerPropertiesFormat == nil || lb.FrontendIPConfigurations == nil {
 		return nil
 	}
@@ -330,7 +344,7 @@ func (az *Cloud) removeFrontendIPConfigurationFromLoadBalancer(lb *network.LoadB
 	}
 
 	// clean up any private link service associated with the frontEndIPConfig
-	err := az.reconcilePrivateLinkService(clusterName, service, fip, false /* wantPLS */)
+	err := az.reconcilePrivateLinkService(ctx, clusterName, service, fip, false /* wantPLS */)
 	if err != nil {
 		klog.Errorf("removeFrontendIPConfigurationFromLoadBalancer(%s, %s, %s, %s): failed to clean up PLS: %v", to.String(lb.Name), to.String(fip.Name), clusterName, service.Name, err)
 		return err
@@ -338,24 +352,23 @@ func (az *Cloud) removeFrontendIPConfigurationFromLoadBalancer(lb *network.LoadB
 
 	if len(fipConfigs) == 0 {
 		klog.V(2).Infof("removeFrontendIPConfigurationFromLoadBalancer(%s, %s, %s, %s): deleting load balancer because there is no remaining frontend IP configurations", to.String(lb.Name), to.String(fip.Name), clusterName, service.Name)
-		err := az.cleanOrphanedLoadBalancer(lb, existingLBs, service, clusterName)
+		err := az.cleanOrphanedLoadBalancer(ctx, lb, existingLBs, service, clusterName)
 		if err != nil {
 			klog.Errorf("removeFrontendIPConfigurationFromLoadBalancer(%s, %s, %s, %s): failed to cleanupOrphanedLoadBalancer: %v", to.String(lb.Name), to.String(fip.Name), clusterName, service.Name, err)
 			return err
 		}
 	} else {
 		klog.V(2).Infof("removeFrontendIPConfigurationFromLoadBalancer(%s, %s, %s, %s): updating the load balancer", to.String(lb.Name), to.String(fip.Name), clusterName, service.Name)
-		err := az.CreateOrUpdateLB(service, *lb)
+		err := az.CreateOrUpdateLB(ctx, service, *lb)
 		if err != nil {
 			klog.Errorf("removeFrontendIPConfigurationFromLoadBalancer(%s, %s, %s, %s): failed to CreateOrUpdateLB: %v", to.String(lb.Name), to.String(fip.Name), clusterName, service.Name, err)
 			return err
 		}
-		_ = az.lbCache.Delete(to.String(lb.Name))
 	}
 	return nil
 }
 
-func (az *Cloud) cleanOrphanedLoadBalancer(lb *network.LoadBalancer, existingLBs []network.LoadBalancer, service *v1.Service, clusterName string) error {
+func (az *Cloud) cleanOrphanedLoadBalancer(ctx context.Context, lb *network.LoadBalancer, existingLBs []network.LoadBalancer, service *v1.Service, clusterName string) error {
 	lbName := to.String(lb.Name)
 	serviceName := getServiceName(service)
 	isBackendPoolPreConfigured := az.isBackendPoolPreConfigured(service)
@@ -388,7 +401,7 @@ func (az *Cloud) cleanOrphanedLoadBalancer(lb *network.LoadBalancer, existingLBs
 			lb.BackendAddressPools = nil
 		}
 
-		deleteErr := az.safeDeleteLoadBalancer(*lb, clusterName, vmSetName, service)
+		deleteErr := az.safeDeleteLoadBalancer(ctx, *lb, clusterName, vmSetName, service)
 		if deleteErr != nil {
 			klog.Warningf("cleanOrphanedLoadBalancer(%s, %s, %s): failed to DeleteLB: %v", lbName, serviceName, clusterName, deleteErr)
 
@@ -413,13 +426,13 @@ func (az *Cloud) cleanOrphanedLoadBalancer(lb *network.LoadBalancer, existingLBs
 			}
 
 			vmssNamesMap := map[string]bool{vmssName: true}
-			err := az.VMSet.EnsureBackendPoolDeletedFromVMSets(vmssNamesMap, lbBackendPoolID)
+			err := az.VMSet.EnsureBackendPoolDeletedFromVMSets(ctx, vmssNamesMap, lbBackendPoolID)
 			if err != nil {
 				klog.Errorf("cleanOrphanedLoadBalancer(%s, %s, %s): failed to EnsureBackendPoolDeletedFromVMSets: %v", lbName, serviceName, clusterName, err)
 				return err
 			}
 
-			deleteErr := az.DeleteLB(service, lbName)
+			deleteErr := az.DeleteLB(ctx, service, lbName)
 			if deleteErr != nil {
 				klog.Errorf("cleanOrphanedLoadBalancer(%s, %s, %s): failed delete lb for the second time, stop retrying: %v", lbName, serviceName, clusterName, deleteErr)
 				return deleteErr.Error()
@@ -431,21 +444,20 @@ func (az *Cloud) cleanOrphanedLoadBalancer(lb *network.LoadBalancer, existingLBs
 }
 
 // safeDeleteLoadBalancer deletes the load balancer after decoupling it from the vmSet
-func (az *Cloud) safeDeleteLoadBalancer(lb network.LoadBalancer, clusterName, vmSetName string, service *v1.Service) *retry.Error {
+func (az *Cloud) safeDeleteLoadBalancer(ctx context.Context, lb network.LoadBalancer, clusterName, vmSetName string, service *v1.Service) *retry.Error {
 	if isLBBackendPoolTypeIPConfig(service, &lb, clusterName) {
 		lbBackendPoolID := az.getBackendPoolID(to.String(lb.Name), az.getLoadBalancerResourceGroup(), getBackendPoolName(clusterName, service))
-		err := az.VMSet.EnsureBackendPoolDeleted(service, lbBackendPoolID, vmSetName, lb.BackendAddressPools, true)
+		err := az.VMSet.EnsureBackendPoolDeleted(ctx, service, lbBackendPoolID, vmSetName, lb.BackendAddressPools, true)
 		if err != nil {
 			return retry.NewError(false, fmt.Errorf("safeDeleteLoadBalancer: failed to EnsureBackendPoolDeleted: %w", err))
 		}
 	}
 
 	klog.V(2).Infof("safeDeleteLoadBalancer: deleting LB %s", to.String(lb.Name))
-	rerr := az.DeleteLB(service, to.String(lb.Name))
+	rerr := az.DeleteLB(ctx, service, to.String(lb.Name))
 	if rerr != nil {
 		return rerr
 	}
-	_ = az.lbCache.Delete(to.String(lb.Name))
 
 	return nil
 }
@@ -456,13 +468,13 @@ func (az *Cloud) safeDeleteLoadBalancer(lb network.LoadBalancer, clusterName, vm
 // 2. When migrating from multiple slbs to single slb mode.
 // It also ensures those vmSets are joint the backend pools of the primary SLBs.
 // It runs only once everytime the cloud controller manager restarts.
-func (az *Cloud) reconcileSharedLoadBalancer(service *v1.Service, clusterName string, nodes []*v1.Node) ([]network.LoadBalancer, error) {
+func (az *Cloud) reconcileSharedLoadBalancer(ctx context.Context, service *v1.Service, clusterName string, nodes []*v1.Node) ([]network.LoadBalancer, error) {
 	var (
 		existingLBs []network.LoadBalancer
 		err         error
 	)
 
-	existingLBs, err = az.ListManagedLBs(service, nodes, clusterName)
+	existingLBs, err = az.ListManagedLBs(ctx, service, nodes, clusterName)
 	if err != nil {
 		return nil, fmt.Errorf("reconcileSharedLoadBalancer: failed to list managed LB: %w", err)
 	}
@@ -513,7 +525,7 @@ func (az *Cloud) reconcileSharedLoadBalancer(service *v1.Service, clusterName st
 		// decouple the VMSet from the lb and delete the lb. Then adding the VMSet
 		// to the backend pool of the primary slb.
 		klog.V(2).Infof("reconcileSharedLoadBalancer: deleting LB %s because the corresponding vmSet is supposed to be in the primary SLB", to.String(lb.Name))
-		rerr := az.safeDeleteLoadBalancer(lb, clusterName, vmSetName, service)
+		rerr := az.safeDeleteLoadBalancer(ctx, lb, clusterName, vmSetName, service)
 		if rerr != nil {
 			return nil, rerr.Error()
 		}
@@ -540,7 +552,7 @@ func (az *Cloud) reconcileSharedLoadBalancer(service *v1.Service, clusterName st
 // In case the selected load balancer does not exist it returns network.LoadBalancer struct
 // with added metadata (such as name, location) and existsLB set to FALSE.
 // By default - cluster default LB is returned.
-func (az *Cloud) getServiceLoadBalancer(service *v1.Service, clusterName string, nodes []*v1.Node, wantLb bool, existingLBs []network.LoadBalancer) (lb *network.LoadBalancer, status *v1.LoadBalancerStatus, exists bool, err error) {
+func (az *Cloud) getServiceLoadBalancer(ctx context.Context, service *v1.Service, clusterName string, nodes []*v1.Node, wantLb bool, existingLBs []network.LoadBalancer) (lb *network.LoadBalancer, status *v1.LoadBalancerStatus, exists bool, err error) {
 	isInternal := requiresInternalLoadBalancer(service)
 	var defaultLB *network.LoadBalancer
 	primaryVMSetName := az.VMSet.GetPrimaryVMSetName()
@@ -549,7 +561,7 @@ func (az *Cloud) getServiceLoadBalancer(service *v1.Service, clusterName string,
 
 	// reuse the lb list from reconcileSharedLoadBalancer to reduce the api call
 	if len(existingLBs) == 0 {
-		existingLBs, err = az.ListLB(service)
+		existingLBs, err = az.ListLB(ctx, service)
 		if err != nil {
 			return nil, nil, false, err
 		}
@@ -580,7 +592,7 @@ func (az *Cloud) getServiceLoadBalancer(service *v1.Service, clusterName string,
 				// need to remove the specific vmSet from the primary SLB.
 				return !strings.EqualFold(vmSetName, primaryVMSetName) && vmSetName != ""
 			}
-			cleanedLB, err := az.LoadBalancerBackendPool.CleanupVMSetFromBackendPoolByCondition(&existingLB, service, nodes, clusterName, shouldRemoveVMSetFromSLB)
+			cleanedLB, err := az.LoadBalancerBackendPool.CleanupVMSetFromBackendPoolByCondition(ctx, &existingLB, service, nodes, clusterName, shouldRemoveVMSetFromSLB)
 			if err != nil {
 				return nil, nil, false, err
 			}
@@ -594,7 +606,7 @@ func (az *Cloud) getServiceLoadBalancer(service *v1.Service, clusterName string,
 			continue
 		}
 		var fipConfig *network.FrontendIPConfiguration
-		status, fipConfig, err = az.getServiceLoadBalancerStatus(service, &existingLB, pips)
+		status, fipConfig, err = az.getServiceLoadBalancerStatus(ctx, service, &existingLB, pips)
 		if err != nil {
 			return nil, nil, false, err
 		}
@@ -607,7 +619,7 @@ func (az *Cloud) getServiceLoadBalancer(service *v1.Service, clusterName string,
 		// select another load balancer instead of returning
 		// the current one if the change is needed
 		if wantLb && az.shouldChangeLoadBalancer(service, to.String(existingLB.Name), clusterName) {
-			if err := az.removeFrontendIPConfigurationFromLoadBalancer(&existingLB, existingLBs, fipConfig, clusterName, service); err != nil {
+			if err := az.removeFrontendIPConfigurationFromLoadBalancer(ctx, &existingLB, existingLBs, fipConfig, clusterName, service); err != nil {
 				klog.Errorf("getServiceLoadBalancer(%s, %s, %v): failed to remove frontend IP configuration from load balancer: %v", service.Name, clusterName, wantLb, err)
 				return nil, nil, false, err
 			}
@@ -728,7 +740,7 @@ func (az *Cloud) selectLoadBalancer(clusterName string, service *v1.Service, exi
 	return selectedLB, existsLb, nil
 }
 
-func (az *Cloud) getServiceLoadBalancerStatus(service *v1.Service, lb *network.LoadBalancer, pips *[]network.PublicIPAddress) (status *v1.LoadBalancerStatus, fipConfig *network.FrontendIPConfiguration, err error) {
+func (az *Cloud) getServiceLoadBalancerStatus(ctx context.Context, service *v1.Service, lb *network.LoadBalancer, pips *[]network.PublicIPAddress) (status *v1.LoadBalancerStatus, fipConfig *network.FrontendIPConfiguration, err error) {
 	if lb == nil {
 		klog.V(10).Info("getServiceLoadBalancerStatus: lb is nil")
 		return nil, nil, nil
@@ -740,7 +752,7 @@ func (az *Cloud) getServiceLoadBalancerStatus(service *v1.Service, lb *network.L
 	isInternal := requiresInternalLoadBalancer(service)
 	serviceName := getServiceName(service)
 	for _, ipConfiguration := range *lb.FrontendIPConfigurations {
-		owns, isPrimaryService, err := az.serviceOwnsFrontendIP(ipConfiguration, service, pips)
+		owns, isPrimaryService, err := az.serviceOwnsFrontendIP(ctx, ipConfiguration, service, pips)
 		if err != nil {
 			return nil, nil, fmt.Errorf("get(%s): lb(%s) - failed to filter frontend IP configs with error: %w", serviceName, to.String(lb.Name), err)
 		}
@@ -794,7 +806,7 @@ func (az *Cloud) getServiceLoadBalancerStatus(service *v1.Service, lb *network.L
 	return nil, nil, nil
 }
 
-func (az *Cloud) determinePublicIPName(clusterName string, service *v1.Service, pips *[]network.PublicIPAddress) (string, bool, error) {
+func (az *Cloud) determinePublicIPName(ctx context.Context, clusterName string, service *v1.Service, pips *[]network.PublicIPAddress) (string, bool, error) {
 	var shouldPIPExisted bool
 
 	if name, found := service.Annotations[consts.ServiceAnnotationPIPName]; found && name != "" {
@@ -815,7 +827,7 @@ func (az *Cloud) determinePublicIPName(clusterName string, service *v1.Service,
 
 	// For the services with loadBalancerIP set, an existing public IP is required, primary
 	// or secondary, or a public IP not found error would be reported.
-	pip, err := az.findMatchedPIPByLoadBalancerIP(service, loadBalancerIP, pipResourceGroup, pips)
+	pip, err := az.findMatchedPIPByLoadBalancerIP(ctx, service, loadBalancerIP, pipResourceGroup, pips)
 	if err != nil {
 		return "", shouldPIPExisted, err
 	}
@@ -827,9 +839,9 @@ func (az *Cloud) determinePublicIPName(clusterName string, service *v1.Service,
 	return "", shouldPIPExisted, fmt.Errorf("user supplied IP Address %s was not found in resource group %s", loadBalancerIP, pipResourceGroup)
 }
 
-func (az *Cloud) findMatchedPIPByLoadBalancerIP(service *v1.Service, loadBalancerIP, pipResourceGroup string, pips *[]network.PublicIPAddress) (*network.PublicIPAddress, error) {
+func (az *Cloud) findMatchedPIPByLoadBalancerIP(ctx context.Context, service *v1.Service, loadBalancerIP, pipResourceGroup string, pips *[]network.PublicIPAddress) (*network.PublicIPAddress, error) {
 	if pips == nil {
-		pipList, err := az.ListPIP(service, pipResourceGroup)
+		pipList, err := az.ListPIP(ctx, service, pipResourceGroup)
 		if err != nil {
 			return nil, err
 		}
@@ -877,23 +889,23 @@ func (az *Cloud) findServiceIPAddress(ctx context.Context, clusterName string, s
 		return service.Status.LoadBalancer.Ingress[0].IP, nil
 	}
 
-	_, lbStatus, existsLb, err := az.getServiceLoadBalancer(service, clusterName, nil, false, []network.LoadBalancer{})
+	_, lbStatus, existsLb, err := az.getServiceLoadBalancer(ctx, service, clusterName, nil, false, []network.LoadBalancer{})
 	if err != nil {
 		return "", err
 	}
 	if !existsLb {
-		klog.V(2).Infof("Expected to find an IP address for service %s but did not. Assuming it has been removed", service.Name)
+		klog.V(2).InfoS("Expected to find an IP address for service but did not. Assuming it has been removed", log.KeysAndValues(ctx)...)
 		return "", nil
 	}
 	if len(lbStatus.Ingress) < 1 {
-		klog.V(2).Infof("Expected to find an IP address for service %s but it had no ingresses. Assuming it has been removed", service.Name)
+		klog.V(2).InfoS("Expected to find an IP address for service but it had no ingresses. Assuming it has been removed", log.KeysAndValues(ctx)...)
 		return "", nil
 	}
 
 	return lbStatus.Ingress[0].IP, nil
 }
 
-func (az *Cloud) ensurePublicIPExists(service *v1.Service, pipName string, domainNameLabel, clusterName string, shouldPIPExisted, foundDNSLabelAnnotation bool) (*network.PublicIPAddress, error) {
+func (az *Cloud) ensurePublicIPExists(ctx context.Context, service *v1.Service, pipName string, domainNameLabel, clusterName string, shouldPIPExisted, foundDNSLabelAnnotation bool) (*network.PublicIPAddress, error) {
 	pipResourceGroup := az.getPublicIPAddressResourceGroup(service)
 	pip, existsPip, err := az.getPublicIPAddress(pipResourceGroup, pipName, azcache.CacheReadTypeDefault)
 	if err != nil {
@@ -926,13 +938,11 @@ func (az *Cloud) ensurePublicIPExists(service *v1.Service, pipName string, domai
 				var rerr *retry.Error
 				if changed {
 					klog.V(2).Infof("ensurePublicIPExists: updating the PIP %s for the incoming service %s", pipName, serviceName)
-					err = az.CreateOrUpdatePIP(service, pipResourceGroup, pip)
+					err = az.CreateOrUpdatePIP(ctx, service, pipResourceGroup, pip)
 					if err != nil {
 						return nil, err
 					}
 
-					ctx, cancel := getContextWithCancel()
-					defer cancel()
 					pip, rerr = az.PublicIPAddressesClient.Get(ctx, pipResourceGroup, *pip.Name, "")
 					if rerr != nil {
 						return nil, rerr.Error()
@@ -1021,7 +1031,7 @@ func (az *Cloud) ensurePublicIPExists(service *v1.Service, pipName string, domai
 
 	if changed {
 		klog.V(2).Infof("CreateOrUpdatePIP(%s, %q): start", pipResourceGroup, *pip.Name)
-		err = az.CreateOrUpdatePIP(service, pipResourceGroup, pip)
+		err = az.CreateOrUpdatePIP(ctx, service, pipResourceGroup, pip)
 		if err != nil {
 			klog.V(2).Infof("ensure(%s) abort backoff: pip(%s)", serviceName, *pip.Name)
 			return nil, err
@@ -1030,8 +1040,6 @@ func (az *Cloud) ensurePublicIPExists(service *v1.Service, pipName string, domai
 		klog.V(10).Infof("CreateOrUpdatePIP(%s, %q): end", pipResourceGroup, *pip.Name)
 	}
 
-	ctx, cancel := getContextWithCancel()
-	defer cancel()
 	pip, rerr := az.PublicIPAddressesClient.Get(ctx, pipResourceGroup, *pip.Name, "")
 	if rerr != nil {
 		return nil, rerr.Error()
@@ -1246,8 +1254,8 @@ func getDomainNameLabel(pip *network.PublicIPAddress) string {
 	return to.String(pip.PublicIPAddressPropertiesFormat.DNSSettings.DomainNameLabel)
 }
 
-func (az *Cloud) isFrontendIPChanged(clusterName string, config network.FrontendIPConfiguration, service *v1.Service, lbFrontendIPConfigName string, pips *[]network.PublicIPAddress) (bool, error) {
-	isServiceOwnsFrontendIP, isPrimaryService, err := az.serviceOwnsFrontendIP(config, service, pips)
+func (az *Cloud) isFrontendIPChanged(ctx context.Context, clusterName string, config network.FrontendIPConfiguration, service *v1.Service, lbFrontendIPConfigName string, pips *[]network.PublicIPAddress) (bool, error) {
+	isServiceOwnsFrontendIP, isPrimaryService, err := az.serviceOwnsFrontendIP(ctx, config, service, pips)
 	if err != nil {
 		return false, err
 	}
@@ -1279,7 +1287,7 @@ func (az *Cloud) isFrontendIPChanged(clusterName string, config network.Frontend
 		}
 		return config.PrivateIPAllocationMethod != network.IPAllocationMethodStatic || !strings.EqualFold(loadBalancerIP, to.String(config.PrivateIPAddress)), nil
 	}
-	pipName, _, err := az.determinePublicIPName(clusterName, service, pips)
+	pipName, _, err := az.determinePublicIPName(ctx, clusterName, service, pips)
 	if err != nil {
 		return false, err
 	}
@@ -1406,12 +1414,13 @@ func findMatchedOutboundRuleFIPConfig(fipConfigID *string, outboundRuleFIPConfig
 }
 
 func (az *Cloud) findFrontendIPConfigOfService(
+	ctx context.Context,
 	fipConfigs *[]network.FrontendIPConfiguration,
 	service *v1.Service,
 	pips *[]network.PublicIPAddress,
 ) (*network.FrontendIPConfiguration, bool, error) {
 	for _, config := range *fipConfigs {
-		owns, isPrimaryService, err := az.serviceOwnsFrontendIP(config, service, pips)
+		owns, isPrimaryService, err := az.serviceOwnsFrontendIP(ctx, config, service, pips)
 		if err != nil {
 			return nil, false, err
 		}
@@ -1427,34 +1436,34 @@ func (az *Cloud) findFrontendIPConfigOfService(
 // This also reconciles the Service's Ports  with the LoadBalancer config.
 // This entails adding rules/probes for expected Ports and removing stale rules/ports.
 // nodes only used if wantLb is true
-func (az *Cloud) reconcileLoadBalancer(clusterName string, service *v1.Service, nodes []*v1.Node, wantLb bool) (*network.LoadBalancer, error) {
+func (az *Cloud) reconcileLoadBalancer(ctx context.Context, clusterName string, service *v1.Service, nodes []*v1.Node, wantLb bool) (*network.LoadBalancer, error) {
 	isBackendPoolPreConfigured := az.isBackendPoolPreConfigured(service)
 	serviceName := getServiceName(service)
-	klog.V(2).Infof("reconcileLoadBalancer for service(%s) - wantLb(%t): started", serviceName, wantLb)
+	klog.V(2).InfoS("reconcileLoadBalancer: started", log.KeysAndValues(ctx, "wantLb", wantLb)...)
 
-	existingLBs, err := az.reconcileSharedLoadBalancer(service, clusterName, nodes)
+	existingLBs, err := az.reconcileSharedLoadBalancer(ctx, service, clusterName, nodes)
 	if err != nil {
-		klog.Errorf("reconcileLoadBalancer: failed to reconcile shared load balancer: %v", err)
+		klog.ErrorS(err, "reconcileLoadBalancer: failed to reconcile shared load balancer", log.KeysAndValues(ctx)...)
 		return nil, err
 	}
 
-	lb, lbStatus, _, err := az.getServiceLoadBalancer(service, clusterName, nodes, wantLb, existingLBs)
+	lb, lbStatus, _, err := az.getServiceLoadBalancer(ctx, service, clusterName, nodes, wantLb, existingLBs)
 	if err != nil {
-		klog.Errorf("reconcileLoadBalancer: failed to get load balancer for service %q, error: %v", serviceName, err)
+		klog.ErrorS(err, "reconcileLoadBalancer: failed to get load balancer", log.KeysAndValues(ctx)...)
 		return nil, err
 	}
 
 	lbName := *lb.Name
 	lbResourceGroup := az.getLoadBalancerResourceGroup()
 	lbBackendPoolID := az.getBackendPoolID(lbName, az.getLoadBalancerResourceGroup(), getBackendPoolName(clusterName, service))
-	klog.V(2).Infof("reconcileLoadBalancer for service(%s): lb(%s/%s) wantLb(%t) resolved load balancer name", serviceName, lbResourceGroup, lbName, wantLb)
+	klog.V(2).InfoS("reconcileLoadBalancer: resolved load balancer name", log.KeysAndValues(ctx, "resourceGroup", lbResourceGroup, "lb", lbName, "wantLb", wantLb)...)
 	defaultLBFrontendIPConfigName := az.getDefaultFrontendIPConfigName(service)
 	defaultLBFrontendIPConfigID := az.getFrontendIPConfigID(lbName, lbResourceGroup, defaultLBFrontendIPConfigName)
 	dirtyLb := false
 
 	// reconcile the load balancer's backend pool configuration.
 	if wantLb {
-		preConfig, changed, err := az.LoadBalancerBackendPool.ReconcileBackendPools(clusterName, service, lb)
+		preConfig, changed, err := az.LoadBalancerBackendPool.ReconcileBackendPools(ctx, clusterName, service, lb)
 		if err != nil {
 			return lb, err
 		}
@@ -1465,7 +1474,7 @@ func (az *Cloud) reconcileLoadBalancer(clusterName string, service *v1.Service,
 	}
 
 	// reconcile the load balancer's frontend IP configurations.
-	ownedFIPConfig, toDeleteConfigs, changed, err := az.reconcileFrontendIPConfigs(clusterName, service, lb, lbStatus, wantLb, defaultLBFrontendIPConfigName)
+	ownedFIPConfig, toDeleteConfigs, changed, err := az.reconcileFrontendIPConfigs(ctx, clusterName, service, lb, lbStatus, wantLb, defaultLBFrontendIPConfigName)
 	if err != nil {
 		return lb, err
 	}
@@ -1517,37 +1526,31 @@ func (az *Cloud) reconcileLoadBalancer(clusterName string, service *v1.Service,
 		if len(toDeleteConfigs) > 0 {
 			for i := range toDeleteConfigs {
 				fipConfigToDel := toDeleteConfigs[i]
-				err := az.reconcilePrivateLinkService(clusterName, service, &fipConfigToDel, false /* wantPLS */)
+				err := az.reconcilePrivateLinkService(ctx, clusterName, service, &fipConfigToDel, false /* wantPLS */)
 				if err != nil {
-					klog.Errorf(
-						"reconcileLoadBalancer for service(%s): lb(%s) - failed to clean up PrivateLinkService for frontEnd(%s): %v",
-						serviceName,
-						lbName,
-						to.String(fipConfigToDel.Name),
-						err,
-					)
+					klog.ErrorS(err, "reconcileLoadBalancer: failed to clean up PrivateLinkService", log.KeysAndValues(ctx, "lb", lbName, "frontendIPConfig", to.String(fipConfigToDel.Name))...)
 				}
 			}
 		}
 
 		if lb.FrontendIPConfigurations == nil || len(*lb.FrontendIPConfigurations) == 0 {
-			err := az.cleanOrphanedLoadBalancer(lb, existingLBs, service, clusterName)
+			err := az.cleanOrphanedLoadBalancer(ctx, lb, existingLBs, service, clusterName)
 			if err != nil {
-				klog.Errorf("reconcileLoadBalancer for service(%s): lb(%s) - failed to cleanOrphanedLoadBalancer: %v", serviceName, lbName, err)
+				klog.ErrorS(err, "reconcileLoadBalancer: failed to clean orphaned load balancer", log.KeysAndValues(ctx, "lb", lbName)...)
 				return nil, err
 			}
 		} else {
-			klog.V(2).Infof("reconcileLoadBalancer: reconcileLoadBalancer for service(%s): lb(%s) - updating", serviceName, lbName)
-			err := az.CreateOrUpdateLB(service, *lb)
+			klog.V(2).InfoS("reconcileLoadBalancer: updating load balancer", log.KeysAndValues(ctx, "lb", lbName)...)
+			err := az.CreateOrUpdateLB(ctx, service, *lb)
 			if err != nil {
-				klog.Errorf("reconcileLoadBalancer for service(%s) abort backoff: lb(%s) - updating: %s", serviceName, lbName, err.Error())
+				klog.ErrorS(err, "reconcileLoadBalancer: failed to update load balancer", log.KeysAndValues(ctx, "lb", lbName)...)
 				return nil, err
 			}
 
 			// Refresh updated lb which will be used later in other places.
 			newLB, exist, err := az.getAzureLoadBalancer(lbName, azcache.CacheReadTypeDefault)
 			if err != nil {
-				klog.Errorf("reconcileLoadBalancer for service(%s): getAzureLoadBalancer(%s) failed: %v", serviceName, lbName, err)
+				klog.ErrorS(err, "reconcileLoadBalancer: getAzureLoadBalancer failed", log.KeysAndValues(ctx, "lb", lbName)...)
 				return nil, err
 			}
 			if !exist {
@@ -1569,7 +1572,7 @@ func (az *Cloud) reconcileLoadBalancer(clusterName string, service *v1.Service,
 			backendPools := *lb.BackendAddressPools
 			for _, backendPool := range backendPools {
 				if strings.EqualFold(to.String(backendPool.Name), getBackendPoolName(clusterName, service)) {
-					if err := az.LoadBalancerBackendPool.EnsureHostsInPool(service, nodes, lbBackendPoolID, vmSetName, clusterName, lbName, backendPool); err != nil {
+					if err := az.LoadBalancerBackendPool.EnsureHostsInPool(ctx, service, nodes, lbBackendPoolID, vmSetName, clusterName, lbName, backendPool); err != nil {
 						return nil, err
 					}
 				}
@@ -1577,7 +1580,7 @@ func (az *Cloud) reconcileLoadBalancer(clusterName string, service *v1.Service,
 		}
 	}
 
-	klog.V(2).Infof("reconcileLoadBalancer for service(%s): lb(%s) finished", serviceName, lbName)
+	klog.V(2).InfoS("reconcileLoadBalancer: finished", log.KeysAndValues(ctx, "lb", lbName)...)
 	return lb, nil
 }
 
@@ -1671,7 +1674,7 @@ func (az *Cloud) reconcileLBRules(lb *network.LoadBalancer, service *v1.Service,
 	return dirtyRules
 }
 
-func (az *Cloud) reconcileFrontendIPConfigs(clusterName string, service *v1.Service, lb *network.LoadBalancer, status *v1.LoadBalancerStatus, wantLb bool, defaultLBFrontendIPConfigName string) (*network.FrontendIPConfiguration, []network.FrontendIPConfiguration, bool, error) {
+func (az *Cloud) reconcileFrontendIPConfigs(ctx context.Context, clusterName string, service *v1.Service, lb *network.LoadBalancer, status *v1.LoadBalancerStatus, wantLb bool, defaultLBFrontendIPConfigName string) (*network.FrontendIPConfiguration, []network.FrontendIPConfiguration, bool, error) {
 	var err error
 	lbName := *lb.Name
 	serviceName := getServiceName(service)
@@ -1689,7 +1692,7 @@ func (az *Cloud) reconcileFrontendIPConfigs(clusterName string, service *v1.Serv
 	if !wantLb {
 		for i := len(newConfigs) - 1; i >= 0; i-- {
 			config := newConfigs[i]
-			isServiceOwnsFrontendIP, _, err := az.serviceOwnsFrontendIP(config, service, pips)
+			isServiceOwnsFrontendIP, _, err := az.serviceOwnsFrontendIP(ctx, config, service, pips)
 			if err != nil {
 				return nil, toDeleteConfigs, false, err
 			}
@@ -1727,13 +1730,13 @@ func (az *Cloud) reconcileFrontendIPConfigs(clusterName string, service *v1.Serv
 		)
 		for i := len(newConfigs) - 1; i >= 0; i-- {
 			config := newConfigs[i]
-			isServiceOwnsFrontendIP, _, _ := az.serviceOwnsFrontendIP(config, service, pips)
+			isServiceOwnsFrontendIP, _, _ := az.serviceOwnsFrontendIP(ctx, config, service, pips)
 			if !isServiceOwnsFrontendIP {
 				klog.V(4).Infof("reconcileFrontendIPConfigs for service (%s): the frontend IP configuration %s does not belong to the service", serviceName, to.String(config.Name))
 				continue
 			}
 			klog.V(4).Infof("reconcileFrontendIPConfigs for service (%s): checking owned frontend IP cofiguration %s", serviceName, to.String(config.Name))
-			isFipChanged, err = az.isFrontendIPChanged(clusterName, config, service, defaultLBFrontendIPConfigName, pips)
+			isFipChanged, err = az.isFrontendIPChanged(ctx, clusterName, config, service, defaultLBFrontendIPConfigName, pips)
 			if err != nil {
 				return nil, toDeleteConfigs, false, err
 			}
@@ -1747,7 +1750,7 @@ func (az *Cloud) reconcileFrontendIPConfigs(clusterName string, service *v1.Serv
 			break
 		}
 
-		ownedFIPConfig, _, err = az.findFrontendIPConfigOfService(&newConfigs, service, pips)
+		ownedFIPConfig, _, err = az.findFrontendIPConfigOfService(ctx, &newConfigs, service, pips)
 		if err != nil {
 			return nil, toDeleteConfigs, false, err
 		}
@@ -1795,12 +1798,12 @@ func (az *Cloud) reconcileFrontendIPConfigs(clusterName string, service *v1.Serv
 
 				fipConfigurationProperties = &configProperties
 			} else {
-				pipName, shouldPIPExisted, err := az.determinePublicIPName(clusterName, service, pips)
+				pipName, shouldPIPExisted, err := az.determinePublicIPName(ctx, clusterName, service, pips)
 				if err != nil {
 					return nil, toDeleteConfigs, false, err
 				}
 				domainNameLabel, found := getPublicIPDomainNameLabel(service)
-				pip, err := az.ensurePublicIPExists(service, pipName, domainNameLabel, clusterName, shouldPIPExisted, found)
+				pip, err := az.ensurePublicIPExists(ctx, service, pipName, domainNameLabel, clusterName, shouldPIPExisted, found)
 				if err != nil {
 					return nil, toDeleteConfigs, false, err
 				}
@@ -2081,6 +2084,62 @@ func (az *Cloud) buildHealthProbeRulesForPort(annotations map[string]string, por
 	return probe, nil
 }
 
+// getNodeEndpointHealthProbeTarget returns the port, protocol and request path of the health probe of the service
+// with the external traffic policy Local. The probe targets the health check node port over HTTP by default, which
+// could be overridden by the health probe annotations. The request path is nil for the TCP probe.
+func (az *Cloud) getNodeEndpointHealthProbeTarget(service *v1.Service, podPresencePath string, podPresencePort int32) (int32, network.ProbeProtocol, *string, error) {
+	port, err := consts.Getint32ValueFromK8sSvcAnnotation(service.Annotations, consts.ServiceAnnotationHealthProbePort, func(val *int32) error {
+		if *val < 1 || *val > 65535 {
+			return fmt.Errorf("port %d is out of range", *val)
+		}
+		for _, servicePort := range service.Spec.Ports {
+			if servicePort.NodePort == *val {
+				return fmt.Errorf("port %d is allocated to the service as the node port of port %d", *val, servicePort.Port)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, "", nil, fmt.Errorf("failed to parse annotation %s: %w", consts.ServiceAnnotationHealthProbePort, err)
+	}
+	if port == nil {
+		port = to.Int32Ptr(podPresencePort)
+	}
+
+	protocol := network.ProbeProtocolHTTP
+	protocolValue, err := consts.GetAttributeValueInSvcAnnotation(service.Annotations, consts.ServiceAnnotationHealthProbeProtocol)
+	if err != nil {
+		return 0, "", nil, fmt.Errorf("failed to parse annotation %s: %w", consts.ServiceAnnotationHealthProbeProtocol, err)
+	}
+	if protocolValue != nil {
+		switch value := strings.TrimSpace(*protocolValue); {
+		case strings.EqualFold(value, string(network.ProbeProtocolHTTP)):
+			protocol = network.ProbeProtocolHTTP
+		case strings.EqualFold(value, string(network.ProbeProtocolHTTPS)):
+			if !az.useStandardLoadBalancer() {
+				return 0, "", nil, fmt.Errorf("annotation %s: Https health probe is only supported on the standard load balancer", consts.ServiceAnnotationHealthProbeProtocol)
+			}
+			protocol = network.ProbeProtocolHTTPS
+		case strings.EqualFold(value, string(network.ProbeProtocolTCP)):
+			protocol = network.ProbeProtocolTCP
+		default:
+			return 0, "", nil, fmt.Errorf("annotation %s: unsupported health probe protocol %q", consts.ServiceAnnotationHealthProbeProtocol, value)
+		}
+	}
+	if protocol == network.ProbeProtocolTCP {
+		return *port, protocol, nil, nil
+	}
+
+	path, err := consts.GetAttributeValueInSvcAnnotation(service.Annotations, consts.ServiceAnnotationHealthProbeRequestPath)
+	if err != nil {
+		return 0, "", nil, fmt.Errorf("failed to parse annotation %s: %w", consts.ServiceAnnotationHealthProbeRequestPath, err)
+	}
+	if path == nil {
+		path = to.StringPtr(podPresencePath)
+	}
+	return *port, protocol, path, nil
+}
+
 // buildLBRules
 // for following sku: basic loadbalancer vs standard load balancer
 // for following scenario: internal vs external
@@ -2100,14 +2159,20 @@ func (az *Cloud) getExpectedLBRules(
 	var nodeEndpointHealthprobe *network.Probe
 	if servicehelpers.NeedsHealthCheck(service) {
 		podPresencePath, podPresencePort := servicehelpers.GetServiceHealthCheckPathPort(service)
+		// the probe is named after the health check node port even if the target is overridden,
+		// so that it is updated in place when the annotations change
 		lbRuleName := az.getLoadBalancerRuleName(service, v1.ProtocolTCP, podPresencePort)
 
+		probePort, probeProtocol, probePath, err := az.getNodeEndpointHealthProbeTarget(service, podPresencePath, podPresencePort)
+		if err != nil {
+			return nil, nil, err
+		}
 		nodeEndpointHealthprobe = &network.Probe{
 			Name: &lbRuleName,
 			ProbePropertiesFormat: &network.ProbePropertiesFormat{
-				RequestPath:       to.StringPtr(podPresencePath),
-				Protocol:          network.ProbeProtocolHTTP,
-				Port:              to.Int32Ptr(podPresencePort),
+				RequestPath:       probePath,
+				Protocol:          probeProtocol,
+				Port:              to.Int32Ptr(probePort),
 				IntervalInSeconds: to.Int32Ptr(consts.HealthProbeDefaultProbeInterval),
 				NumberOfProbes:    to.Int32Ptr(consts.HealthProbeDefaultNumOfProbe),
 			},
@@ -2277,9 +2342,9 @@ func (az *Cloud) getExpectedHAModeLoadBalancingRuleProperties(
 
 // This reconciles the Network Security Group similar to how the LB is reconciled.
 // This entails adding required, missing SecurityRules and removing stale rules.
-func (az *Cloud) reconcileSecurityGroup(clusterName string, service *v1.Service, lbIP *string, wantLb bool) (*network.SecurityGroup, error) {
+func (az *Cloud) reconcileSecurityGroup(ctx context.Context, clusterName string, service *v1.Service, lbIP *string, wantLb bool) (*network.SecurityGroup, error) {
 	serviceName := getServiceName(service)
-	klog.V(5).Infof("reconcileSecurityGroup(%s): START clusterName=%q", serviceName, clusterName)
+	klog.V(5).InfoS("reconcileSecurityGroup: started", log.KeysAndValues(ctx, "cluster", clusterName, "wantLb", wantLb)...)
 
 	ports := service.Spec.Ports
 	if ports == nil {
@@ -2356,15 +2421,14 @@ func (az *Cloud) reconcileSecurityGroup(clusterName string, service *v1.Service,
 
 	if dirtySg {
 		sg.SecurityRules = &updatedRules
-		klog.V(2).Infof("reconcileSecurityGroup for service(%s): sg(%s) - updating", serviceName, *sg.Name)
+		klog.V(2).InfoS("reconcileSecurityGroup: updating security group", log.KeysAndValues(ctx, "sg", *sg.Name)...)
 		klog.V(10).Infof("CreateOrUpdateSecurityGroup(%q): start", *sg.Name)
-		err := az.CreateOrUpdateSecurityGroup(sg)
+		err := az.CreateOrUpdateSecurityGroup(ctx, sg)
 		if err != nil {
-			klog.V(2).Infof("ensure(%s) abort backoff: sg(%s) - updating", serviceName, *sg.Name)
+			klog.ErrorS(err, "reconcileSecurityGroup: failed to update security group", log.KeysAndValues(ctx, "sg", *sg.Name)...)
 			return nil, err
 		}
 		klog.V(10).Infof("CreateOrUpdateSecurityGroup(%q): end", *sg.Name)
-		_ = az.nsgCache.Delete(to.String(sg.Name))
 	}
 	return &sg, nil
 }
@@ -2554,13 +2618,13 @@ func (az *Cloud) getExpectedSecurityRules(wantLb bool, ports []v1.ServicePort, s
 	return expectedSecurityRules, nil
 }
 
-func (az *Cloud) shouldUpdateLoadBalancer(clusterName string, service *v1.Service, nodes []*v1.Node) (bool, error) {
-	existingManagedLBs, err := az.ListManagedLBs(service, nodes, clusterName)
+func (az *Cloud) shouldUpdateLoadBalancer(ctx context.Context, clusterName string, service *v1.Service, nodes []*v1.Node) (bool, error) {
+	existingManagedLBs, err := az.ListManagedLBs(ctx, service, nodes, clusterName)
 	if err != nil {
 		return false, fmt.Errorf("shouldUpdateLoadBalancer: failed to list managed load balancers: %w", err)
 	}
 
-	_, _, existsLb, _ := az.getServiceLoadBalancer(service, clusterName, nodes, false, existingManagedLBs)
+	_, _, existsLb, _ := az.getServiceLoadBalancer(ctx, service, clusterName, nodes, false, existingManagedLBs)
 	return existsLb && service.ObjectMeta.DeletionTimestamp == nil, nil
 }
 
@@ -2783,7 +2847,7 @@ func (az *Cloud) ensurePIPTagged(service *v1.Service, pip *network.PublicIPAddre
 }
 
 // This reconciles the PublicIP resources similar to how the LB is reconciled.
-func (az *Cloud) reconcilePublicIP(clusterName string, service *v1.Service, lbName string, wantLb bool) (*network.PublicIPAddress, error) {
+func (az *Cloud) reconcilePublicIP(ctx context.Context, clusterName string, service *v1.Service, lbName string, wantLb bool) (*network.PublicIPAddress, error) {
 	isInternal := requiresInternalLoadBalancer(service)
 	serviceName := getServiceName(service)
 	serviceIPTagRequest := getServiceIPTagRequestForPublicIP(service)
@@ -2797,13 +2861,13 @@ func (az *Cloud) reconcilePublicIP(clusterName string, service *v1.Service, lbNa
 
 	pipResourceGroup := az.getPublicIPAddressResourceGroup(service)
 
-	pips, err := az.ListPIP(service, pipResourceGroup)
+	pips, err := az.ListPIP(ctx, service, pipResourceGroup)
 	if err != nil {
 		return nil, err
 	}
 
 	if !isInternal && wantLb {
-		desiredPipName, shouldPIPExisted, err = az.determinePublicIPName(clusterName, service, &pips)
+		desiredPipName, shouldPIPExisted, err = az.determinePublicIPName(ctx, clusterName, service, &pips)
 		if err != nil {
 			return nil, err
 		}
@@ -2828,7 +2892,7 @@ func (az *Cloud) reconcilePublicIP(clusterName string, service *v1.Service, lbNa
 		pipCopy := *pip
 		updateFuncs = append(updateFuncs, func() error {
 			klog.V(2).Infof("reconcilePublicIP for service(%s): pip(%s) - updating", serviceName, *pip.Name)
-			return az.CreateOrUpdatePIP(service, pipResourceGroup, pipCopy)
+			return az.CreateOrUpdatePIP(ctx, service, pipResourceGroup, pipCopy)
 		})
 	}
 	errs := utilerrors.AggregateGoroutines(updateFuncs...)
@@ -2840,7 +2904,7 @@ func (az *Cloud) reconcilePublicIP(clusterName string, service *v1.Service, lbNa
 		pipCopy := *pip
 		deleteFuncs = append(deleteFuncs, func() error {
 			klog.V(2).Infof("reconcilePublicIP for service(%s): pip(%s) - deleting", serviceName, *pip.Name)
-			return az.safeDeletePublicIP(service, pipResourceGroup, &pipCopy, lb)
+			return az.safeDeletePublicIP(ctx, service, pipResourceGroup, &pipCopy, lb)
 		})
 	}
 	errs = utilerrors.AggregateGoroutines(deleteFuncs...)
@@ -2853,7 +2917,7 @@ func (az *Cloud) reconcilePublicIP(clusterName string, service *v1.Service, lbNa
 		var pip *network.PublicIPAddress
 		domainNameLabel, found := getPublicIPDomainNameLabel(service)
 		errorIfPublicIPDoesNotExist := shouldPIPExisted && discoveredDesiredPublicIP && !deletedDesiredPublicIP
-		if pip, err = az.ensurePublicIPExists(service, desiredPipName, domainNameLabel, clusterName, errorIfPublicIPDoesNotExist, found); err != nil {
+		if pip, err = az.ensurePublicIPExists(ctx, service, desiredPipName, domainNameLabel, clusterName, errorIfPublicIPDoesNotExist, found); err != nil {
 			return nil, err
 		}
 		return pip, nil
@@ -2935,7 +2999,7 @@ func (az *Cloud) getPublicIPUpdates(
 }
 
 // safeDeletePublicIP deletes public IP by removing its reference first.
-func (az *Cloud) safeDeletePublicIP(service *v1.Service, pipResourceGroup string, pip *network.PublicIPAddress, lb *network.LoadBalancer) error {
+func (az *Cloud) safeDeletePublicIP(ctx context.Context, service *v1.Service, pipResourceGroup string, pip *network.PublicIPAddress, lb *network.LoadBalancer) error {
 	// Remove references if pip.IPConfiguration is not nil.
 	if pip.PublicIPAddressPropertiesFormat != nil &&
 		pip.PublicIPAddressPropertiesFormat.IPConfiguration != nil &&
@@ -2993,7 +3057,7 @@ func (az *Cloud) safeDeletePublicIP(service *v1.Service, pipResourceGroup string
 
 		// Update load balancer when frontendIPConfigUpdated or loadBalancerRuleUpdated.
 		if frontendIPConfigUpdated || loadBalancerRuleUpdated {
-			err := az.CreateOrUpdateLB(service, *lb)
+			err := az.CreateOrUpdateLB(ctx, service, *lb)
 			if err != nil {
 				klog.Errorf("safeDeletePublicIP for service(%s) failed with error: %v", getServiceName(service), err)
 				return err
@@ -3003,7 +3067,7 @@ func (az *Cloud) safeDeletePublicIP(service *v1.Service, pipResourceGroup string
 
 	pipName := to.String(pip.Name)
 	klog.V(10).Infof("DeletePublicIP(%s, %q): start", pipResourceGroup, pipName)
-	err := az.DeletePublicIP(service, pipResourceGroup, pipName)
+	err := az.DeletePublicIP(ctx, service, pipResourceGroup, pipName)
 	if err != nil {
 		return err
 	}
@@ -38,7 +38,10 @@ import (
 
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/component-base/metrics/testutil"
 
+	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/loadbalancerclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/loadbalancerclient/mockloadbalancerclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/privatelinkserviceclient/mockprivatelinkserviceclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/publicipclient/mockpublicipclient"
@@ -47,6 +50,7 @@ import (
 	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/vmssclient/mockvmssclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/zoneclient/mockzoneclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+	"sigs.k8s.io/cloud-provider-azure/pkg/metrics"
 	"sigs.k8s.io/cloud-provider-azure/pkg/retry"
 )
 
@@ -462,8 +466,8 @@ func TestEnsureLoadBalancerDeleted(t *testing.T) {
 	defer ctrl.Finish()
 	az := GetTestCloud(ctrl)
 	mockLBBackendPool := az.LoadBalancerBackendPool.(*MockBackendPool)
-	mockLBBackendPool.EXPECT().ReconcileBackendPools(gomock.Any(), gomock.Any(), gomock.Any()).Return(false, false, nil).AnyTimes()
-	mockLBBackendPool.EXPECT().EnsureHostsInPool(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	mockLBBackendPool.EXPECT().ReconcileBackendPools(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(false, false, nil).AnyTimes()
+	mockLBBackendPool.EXPECT().EnsureHostsInPool(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 
 	clusterResources, expectedInterfaces, expectedVirtualMachines := getClusterResources(az, vmCount, availabilitySetCount)
 	setMockEnv(az, ctrl, expectedInterfaces, expectedVirtualMachines, 4)
@@ -514,6 +518,73 @@ func TestEnsureLoadBalancerDeleted(t *testing.T) {
 	}
 }
 
+// getOperationCount returns the number of the observed operations of the request from the source controller,
+// it is zero if no operations are observed yet.
+func getOperationCount(request, source string) uint64 {
+	vec, err := testutil.GetHistogramVecFromGatherer(legacyregistry.DefaultGatherer, consts.AzureMetricsNamespace+"_op_duration_seconds",
+		map[string]string{"request": request, "source_controller": source})
+	if err != nil {
+		return 0
+	}
+	return vec.GetAggregatedSampleCount()
+}
+
+// getServiceReconcileCount returns the number of the observed reconciliations of the LoadBalancer services
+// with the operation, result and LB type, it is zero if no reconciliations are observed yet.
+func getServiceReconcileCount(operation, result, lbType string) uint64 {
+	vec, err := testutil.GetHistogramVecFromGatherer(legacyregistry.DefaultGatherer, consts.AzureMetricsNamespace+"_service_reconcile_duration_seconds",
+		map[string]string{"operation": operation, "result": result, "lb_type": lbType})
+	if err != nil {
+		return 0
+	}
+	return vec.GetAggregatedSampleCount()
+}
+
+func TestEnsureLoadBalancerMetricSource(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	az := GetTestCloud(ctrl)
+	mockLBBackendPool := az.LoadBalancerBackendPool.(*MockBackendPool)
+	mockLBBackendPool.EXPECT().ReconcileBackendPools(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(false, false, nil).AnyTimes()
+	mockLBBackendPool.EXPECT().EnsureHostsInPool(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	clusterResources, expectedInterfaces, expectedVirtualMachines := getClusterResources(az, 1, 1)
+	setMockEnv(az, ctrl, expectedInterfaces, expectedVirtualMachines, 1)
+	expectedLBs := make([]network.LoadBalancer, 0)
+	setMockLBs(az, ctrl, &expectedLBs, "service", 1, 1, false)
+	lbClient := &sourceRecordingLBClient{Interface: az.LoadBalancerClient}
+	az.LoadBalancerClient = lbClient
+
+	before := getOperationCount("services_ensure_loadbalancer", metrics.SourceServiceController)
+	reconcileBefore := getServiceReconcileCount("ensure_loadbalancer", metrics.ServiceReconcileResultSucceeded, "external")
+	service := getTestService("service1", v1.ProtocolTCP, nil, false, 80)
+	_, err := az.EnsureLoadBalancer(context.TODO(), testClusterName, &service, clusterResources.nodes)
+	assert.NoError(t, err)
+	assert.Equal(t, before+1, getOperationCount("services_ensure_loadbalancer", metrics.SourceServiceController))
+	assert.Equal(t, reconcileBefore+1, getServiceReconcileCount("ensure_loadbalancer", metrics.ServiceReconcileResultSucceeded, "external"))
+	// The source should be carried down to the API calls of the load balancer client.
+	assert.NotEmpty(t, lbClient.sources)
+	for _, source := range lbClient.sources {
+		assert.Equal(t, metrics.SourceServiceController, source)
+	}
+}
+
+// sourceRecordingLBClient records the metric sources of the contexts passed to the load balancer client.
+type sourceRecordingLBClient struct {
+	loadbalancerclient.Interface
+	sources []string
+}
+
+func (c *sourceRecordingLBClient) List(ctx context.Context, resourceGroupName string) ([]network.LoadBalancer, *retry.Error) {
+	c.sources = append(c.sources, metrics.SourceFromContext(ctx))
+	return c.Interface.List(ctx, resourceGroupName)
+}
+
+func (c *sourceRecordingLBClient) CreateOrUpdate(ctx context.Context, resourceGroupName string, loadBalancerName string, parameters network.LoadBalancer, etag string) *retry.Error {
+	c.sources = append(c.sources, metrics.SourceFromContext(ctx))
+	return c.Interface.CreateOrUpdate(ctx, resourceGroupName, loadBalancerName, parameters, etag)
+}
+
 func TestServiceOwnsPublicIP(t *testing.T) {
 	tests := []struct {
 		desc                    string
@@ -1468,7 +1539,7 @@ func TestGetServiceLoadBalancer(t *testing.T) {
 			test.service.Annotations = test.annotations
 		}
 		az.LoadBalancerSku = test.sku
-		lb, status, exists, err := az.getServiceLoadBalancer(&test.service, testClusterName,
+		lb, status, exists, err := az.getServiceLoadBalancer(context.TODO(), &test.service, testClusterName,
 			clusterResources.nodes, test.wantLB, []network.LoadBalancer{})
 		assert.Equal(t, test.expectedLB, lb, "TestCase[%d]: %s", i, test.desc)
 		assert.Equal(t, test.expectedStatus, status, "TestCase[%d]: %s", i, test.desc)
@@ -1500,7 +1571,7 @@ func TestGetServiceLoadBalancerWithExtendedLocation(t *testing.T) {
 	mockLBsClient.EXPECT().List(gomock.Any(), "rg").Return(nil, nil)
 	az.LoadBalancerClient = mockLBsClient
 
-	lb, status, exists, err := az.getServiceLoadBalancer(&service, testClusterName,
+	lb, status, exists, err := az.getServiceLoadBalancer(context.TODO(), &service, testClusterName,
 		clusterResources.nodes, false, []network.LoadBalancer{})
 	assert.Equal(t, expectedLB, lb, "GetServiceLoadBalancer shall return a default LB with expected location.")
 	assert.Nil(t, status, "GetServiceLoadBalancer: Status should be nil for default LB.")
@@ -1525,7 +1596,7 @@ func TestGetServiceLoadBalancerWithExtendedLocation(t *testing.T) {
 	mockLBsClient.EXPECT().List(gomock.Any(), "rg").Return(nil, nil)
 	az.LoadBalancerClient = mockLBsClient
 
-	lb, status, exists, err = az.getServiceLoadBalancer(&service, testClusterName,
+	lb, status, exists, err = az.getServiceLoadBalancer(context.TODO(), &service, testClusterName,
 		clusterResources.nodes, true, []network.LoadBalancer{})
 	assert.Equal(t, expectedLB, lb, "GetServiceLoadBalancer shall return a new LB with expected location.")
 	assert.Nil(t, status, "GetServiceLoadBalancer: Status should be nil for new LB.")
@@ -1741,7 +1812,7 @@ func TestIsFrontendIPChanged(t *testing.T) {
 		}
 		test.service.Spec.LoadBalancerIP = test.loadBalancerIP
 		test.service.Annotations[consts.ServiceAnnotationLoadBalancerInternalSubnet] = test.annotations
-		flag, rerr := az.isFrontendIPChanged("testCluster", test.config,
+		flag, rerr := az.isFrontendIPChanged(context.TODO(), "testCluster", test.config,
 			&test.service, test.lbFrontendIPConfigName, &test.existingPIPs)
 		if rerr != nil {
 			fmt.Println(rerr.Error())
@@ -1806,7 +1877,7 @@ func TestDeterminePublicIPName(t *testing.T) {
 				t.Fatalf("TestCase[%d] meets unexpected error: %v", i, err)
 			}
 		}
-		ip, _, err := az.determinePublicIPName("testCluster", &service, nil)
+		ip, _, err := az.determinePublicIPName(context.TODO(), "testCluster", &service, nil)
 		assert.Equal(t, test.expectedIP, ip, "TestCase[%d]: %s", i, test.desc)
 		assert.Equal(t, test.expectedError, err != nil, "TestCase[%d]: %s", i, test.desc)
 	}
@@ -2172,6 +2243,122 @@ func TestReconcileLoadBalancerRule(t *testing.T) {
 		}
 	}
 }
+func TestGetExpectedLBRulesWithHealthProbeOverride(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	probeID := "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/lbname/probes/atest1-TCP-34567"
+	for _, test := range []struct {
+		desc            string
+		annotations     map[string]string
+		loadBalancerSku string
+		expectedPort    int32
+		expectedProto   network.ProbeProtocol
+		expectedPath    *string
+		expectedErr     bool
+	}{
+		{
+			desc:          "should probe the health check node port over HTTP by default",
+			expectedPort:  34567,
+			expectedProto: network.ProbeProtocolHTTP,
+			expectedPath:  to.StringPtr("/healthz"),
+		},
+		{
+			desc: "should probe the overridden port by TCP",
+			annotations: map[string]string{
+				consts.ServiceAnnotationHealthProbePort:     "10256",
+				consts.ServiceAnnotationHealthProbeProtocol: "tcp",
+			},
+			expectedPort:  10256,
+			expectedProto: network.ProbeProtocolTCP,
+		},
+		{
+			desc: "should probe the overridden port and path over HTTPS",
+			annotations: map[string]string{
+				consts.ServiceAnnotationHealthProbePort:        "10256",
+				consts.ServiceAnnotationHealthProbeProtocol:    "Https",
+				consts.ServiceAnnotationHealthProbeRequestPath: "/healthz/cilium",
+			},
+			loadBalancerSku: "standard",
+			expectedPort:    10256,
+			expectedProto:   network.ProbeProtocolHTTPS,
+			expectedPath:    to.StringPtr("/healthz/cilium"),
+		},
+		{
+			desc:        "should return an error if the port is not a number",
+			annotations: map[string]string{consts.ServiceAnnotationHealthProbePort: "healthz"},
+			expectedErr: true,
+		},
+		{
+			desc:        "should return an error if the port is out of range",
+			annotations: map[string]string{consts.ServiceAnnotationHealthProbePort: "65536"},
+			expectedErr: true,
+		},
+		{
+			desc:        "should return an error if the port is a node port of the service",
+			annotations: map[string]string{consts.ServiceAnnotationHealthProbePort: "10080"},
+			expectedErr: true,
+		},
+		{
+			desc:        "should return an error if the protocol is not supported",
+			annotations: map[string]string{consts.ServiceAnnotationHealthProbeProtocol: "Udp"},
+			expectedErr: true,
+		},
+		{
+			desc:        "should return an error if the HTTPS probe is used on the basic load balancer",
+			annotations: map[string]string{consts.ServiceAnnotationHealthProbeProtocol: "Https"},
+			expectedErr: true,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			az := GetTestCloud(ctrl)
+			az.Config.LoadBalancerSku = test.loadBalancerSku
+			svc := getTestService("test1", v1.ProtocolTCP, test.annotations, false, 80)
+			svc.Spec.ExternalTrafficPolicy = v1.ServiceExternalTrafficPolicyTypeLocal
+			svc.Spec.HealthCheckNodePort = 34567
+
+			probes, rules, err := az.getExpectedLBRules(&svc, "frontendIPConfigID", "backendPoolID", "lbname")
+			if test.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, []network.Probe{{
+				Name: to.StringPtr("atest1-TCP-34567"),
+				ProbePropertiesFormat: &network.ProbePropertiesFormat{
+					Protocol:          test.expectedProto,
+					Port:              to.Int32Ptr(test.expectedPort),
+					RequestPath:       test.expectedPath,
+					IntervalInSeconds: to.Int32Ptr(consts.HealthProbeDefaultProbeInterval),
+					NumberOfProbes:    to.Int32Ptr(consts.HealthProbeDefaultNumOfProbe),
+				},
+			}}, probes)
+			assert.Len(t, rules, 1)
+			assert.Equal(t, probeID, to.String(rules[0].Probe.ID))
+		})
+	}
+
+	t.Run("should update the probe in place when the override changes", func(t *testing.T) {
+		az := GetTestCloud(ctrl)
+		svc := getTestService("test1", v1.ProtocolTCP, nil, false, 80)
+		svc.Spec.ExternalTrafficPolicy = v1.ServiceExternalTrafficPolicyTypeLocal
+		svc.Spec.HealthCheckNodePort = 34567
+		existingProbes, _, err := az.getExpectedLBRules(&svc, "frontendIPConfigID", "backendPoolID", "lbname")
+		assert.NoError(t, err)
+		lb := &network.LoadBalancer{
+			LoadBalancerPropertiesFormat: &network.LoadBalancerPropertiesFormat{Probes: &existingProbes},
+		}
+
+		svc.Annotations[consts.ServiceAnnotationHealthProbePort] = "10256"
+		expectedProbes, _, err := az.getExpectedLBRules(&svc, "frontendIPConfigID", "backendPoolID", "lbname")
+		assert.NoError(t, err)
+		assert.True(t, az.reconcileLBProbes(lb, &svc, "default/test1", true, expectedProbes))
+		assert.Len(t, *lb.Probes, 1)
+		assert.Equal(t, "atest1-TCP-34567", to.String((*lb.Probes)[0].Name))
+		assert.Equal(t, int32(10256), to.Int32((*lb.Probes)[0].Port))
+	})
+}
+
 func getTestProbes(protocol, path string, interval, port, numOfProbe *int32) []network.Probe {
 	return []network.Probe{
 		getTestProbe(protocol, path, interval, port, numOfProbe),
@@ -2740,10 +2927,10 @@ func TestReconcileLoadBalancer(t *testing.T) {
 		}
 
 		mockLBBackendPool := az.LoadBalancerBackendPool.(*MockBackendPool)
-		mockLBBackendPool.EXPECT().ReconcileBackendPools(gomock.Any(), gomock.Any(), gomock.Any()).Return(false, false, nil).AnyTimes()
-		mockLBBackendPool.EXPECT().EnsureHostsInPool(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+		mockLBBackendPool.EXPECT().ReconcileBackendPools(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(false, false, nil).AnyTimes()
+		mockLBBackendPool.EXPECT().EnsureHostsInPool(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 
-		lb, rerr := az.reconcileLoadBalancer("testCluster", &test.service, clusterResources.nodes, test.wantLb)
+		lb, rerr := az.reconcileLoadBalancer(context.TODO(), "testCluster", &test.service, clusterResources.nodes, test.wantLb)
 		assert.Equal(t, test.expectedError, rerr, "TestCase[%d]: %s", i, test.desc)
 
 		if test.expectedError == nil {
@@ -2879,7 +3066,7 @@ func TestGetServiceLoadBalancerStatus(t *testing.T) {
 	}
 
 	for i, test := range testCases {
-		status, _, err := az.getServiceLoadBalancerStatus(test.service, test.lb, nil)
+		status, _, err := az.getServiceLoadBalancerStatus(context.TODO(), test.service, test.lb, nil)
 		assert.Equal(t, test.expectedStatus, status, "TestCase[%d]: %s", i, test.desc)
 		assert.Equal(t, test.expectedError, err != nil, "TestCase[%d]: %s", i, test.desc)
 	}
@@ -3117,7 +3304,7 @@ func TestReconcileSecurityGroup(t *testing.T) {
 				t.Fatalf("TestCase[%d] meets unexpected error: %v", i, err)
 			}
 		}
-		sg, err := az.reconcileSecurityGroup("testCluster", &test.service, test.lbIP, test.wantLb)
+		sg, err := az.reconcileSecurityGroup(context.TODO(), "testCluster", &test.service, test.lbIP, test.wantLb)
 		assert.Equal(t, test.expectedSg, sg, "TestCase[%d]: %s", i, test.desc)
 		assert.Equal(t, test.expectedError, err != nil, "TestCase[%d]: %s", i, test.desc)
 	}
@@ -3173,7 +3360,7 @@ func TestReconcileSecurityGroupLoadBalancerSourceRanges(t *testing.T) {
 	mockSGClient := az.SecurityGroupsClient.(*mocksecuritygroupclient.MockInterface)
 	mockSGClient.EXPECT().Get(gomock.Any(), az.ResourceGroup, gomock.Any(), gomock.Any()).Return(existingSg, nil)
 	mockSGClient.EXPECT().CreateOrUpdate(gomock.Any(), az.ResourceGroup, gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
-	sg, err := az.reconcileSecurityGroup("testCluster", &service, lbIP, true)
+	sg, err := az.reconcileSecurityGroup(context.TODO(), "testCluster", &service, lbIP, true)
 	assert.NoError(t, err)
 	assert.Equal(t, expectedSg, *sg)
 }
@@ -3235,7 +3422,7 @@ func TestSafeDeletePublicIP(t *testing.T) {
 		mockLBsClient := mockloadbalancerclient.NewMockInterface(ctrl)
 		mockLBsClient.EXPECT().CreateOrUpdate(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
 		az.LoadBalancerClient = mockLBsClient
-		rerr := az.safeDeletePublicIP(&service, "rg", test.pip, test.lb)
+		rerr := az.safeDeletePublicIP(context.TODO(), &service, "rg", test.pip, test.lb)
 		assert.Equal(t, 0, len(*test.lb.FrontendIPConfigurations), "TestCase[%d]: %s", i, test.desc)
 		assert.Equal(t, 0, len(*test.lb.LoadBalancingRules), "TestCase[%d]: %s", i, test.desc)
 		assert.Equal(t, test.expectedError, rerr != nil, "TestCase[%d]: %s", i, test.desc)
@@ -3587,7 +3774,7 @@ func TestReconcilePublicIP(t *testing.T) {
 				// Clear create or update count to prepare for main execution
 				createOrUpdateCount = 0
 			}
-			pip, err := az.reconcilePublicIP("testCluster", &service, "", test.wantLb)
+			pip, err := az.reconcilePublicIP(context.TODO(), "testCluster", &service, "", test.wantLb)
 			if !test.expectedError {
 				assert.Equal(t, nil, err, "TestCase[%d]: %s", i, test.desc)
 			}
@@ -3891,7 +4078,7 @@ func TestEnsurePublicIPExists(t *testing.T) {
 				return basicPIP, nil
 			}).AnyTimes()
 
-			pip, err := az.ensurePublicIPExists(&service, "pip1", test.inputDNSLabel, "", false, test.foundDNSLabelAnnotation)
+			pip, err := az.ensurePublicIPExists(context.TODO(), &service, "pip1", test.inputDNSLabel, "", false, test.foundDNSLabelAnnotation)
 			assert.Equal(t, test.expectedError, err != nil, "TestCase[%d]: %s, encountered unexpected error: %v", i, test.desc, err)
 			if test.expectedID != "" {
 				assert.Equal(t, test.expectedID, to.String(pip.ID), "TestCase[%d]: %s", i, test.desc)
@@ -3943,7 +4130,7 @@ func TestEnsurePublicIPExistsWithExtendedLocation(t *testing.T) {
 			assert.Nil(t, publicIPAddressParameters.Zones)
 			return nil
 		}).Times(1)
-	pip, err := az.ensurePublicIPExists(&service, "pip1", "", "", false, false)
+	pip, err := az.ensurePublicIPExists(context.TODO(), &service, "pip1", "", "", false, false)
 	assert.NotNil(t, pip, "ensurePublicIPExists shall create a new pip"+
 		"with extendedLocation if there is no existed pip")
 	assert.Nil(t, err, "ensurePublicIPExists should create a new pip without errors.")
@@ -4035,7 +4222,7 @@ func TestShouldUpdateLoadBalancer(t *testing.T) {
 		mockVMSet.EXPECT().GetPrimaryVMSetName().Return(az.Config.PrimaryAvailabilitySetName).Times(2)
 		az.VMSet = mockVMSet
 
-		shouldUpdateLoadBalancer, err := az.shouldUpdateLoadBalancer(testClusterName, &service, existingNodes)
+		shouldUpdateLoadBalancer, err := az.shouldUpdateLoadBalancer(context.TODO(), testClusterName, &service, existingNodes)
 		assert.NoError(t, err)
 		assert.Equal(t, test.expectedOutput, shouldUpdateLoadBalancer, "TestCase[%d]: %s", i, test.desc)
 	}
@@ -4658,7 +4845,7 @@ func TestRemoveFrontendIPConfigurationFromLoadBalancerDelete(t *testing.T) {
 		mockPLSClient := cloud.PrivateLinkServiceClient.(*mockprivatelinkserviceclient.MockInterface)
 		mockPLSClient.EXPECT().List(gomock.Any(), "rg").Return(expectedPLS, nil).MinTimes(1).MaxTimes(1)
 		existingLBs := []network.LoadBalancer{{Name: to.StringPtr("lb")}}
-		err := cloud.removeFrontendIPConfigurationFromLoadBalancer(&lb, existingLBs, fip, "testCluster", &service)
+		err := cloud.removeFrontendIPConfigurationFromLoadBalancer(context.TODO(), &lb, existingLBs, fip, "testCluster", &service)
 		assert.NoError(t, err)
 	})
 }
@@ -4680,7 +4867,7 @@ func TestRemoveFrontendIPConfigurationFromLoadBalancerUpdate(t *testing.T) {
 		expectedPLS := make([]network.PrivateLinkService, 0)
 		mockPLSClient := cloud.PrivateLinkServiceClient.(*mockprivatelinkserviceclient.MockInterface)
 		mockPLSClient.EXPECT().List(gomock.Any(), "rg").Return(expectedPLS, nil).MinTimes(1).MaxTimes(1)
-		err := cloud.removeFrontendIPConfigurationFromLoadBalancer(&lb, []network.LoadBalancer{}, fip, "testCluster", &service)
+		err := cloud.removeFrontendIPConfigurationFromLoadBalancer(context.TODO(), &lb, []network.LoadBalancer{}, fip, "testCluster", &service)
 		assert.NoError(t, err)
 	})
 }
@@ -4702,7 +4889,7 @@ func TestCleanOrphanedLoadBalancerLBInUseByVMSS(t *testing.T) {
 
 		expectedVMSS := buildTestVMSSWithLB(testVMSSName, "vmss-vm-", []string{testLBBackendpoolID0}, false)
 		mockVMSSClient := cloud.VirtualMachineScaleSetsClient.(*mockvmssclient.MockInterface)
-		mockVMSSClient.EXPECT().List(gomock.Any(), "rg").Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil)
+		mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), "rg").Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil)
 
 		service := getTestService("test", v1.ProtocolTCP, nil, false, 80)
 		lb := getTestLoadBalancer(to.StringPtr("test"), to.StringPtr("rg"), to.StringPtr("test"), to.StringPtr("test"), service, consts.LoadBalancerSkuStandard)
@@ -4710,7 +4897,7 @@ func TestCleanOrphanedLoadBalancerLBInUseByVMSS(t *testing.T) {
 
 		existingLBs := []network.LoadBalancer{{Name: to.StringPtr("test")}}
 
-		err = cloud.cleanOrphanedLoadBalancer(&lb, existingLBs, &service, "test")
+		err = cloud.cleanOrphanedLoadBalancer(context.TODO(), &lb, existingLBs, &service, "test")
 		assert.NoError(t, err)
 	})
 
@@ -4727,7 +4914,7 @@ func TestCleanOrphanedLoadBalancerLBInUseByVMSS(t *testing.T) {
 
 		existingLBs := []network.LoadBalancer{}
 
-		err = cloud.cleanOrphanedLoadBalancer(&lb, existingLBs, &service, "test")
+		err = cloud.cleanOrphanedLoadBalancer(context.TODO(), &lb, existingLBs, &service, "test")
 		assert.NoError(t, err)
 	})
 }
@@ -4853,7 +5040,7 @@ func TestReconcileZonesForFrontendIPConfigs(t *testing.T) {
 			cloud.ZoneClient = zoneClient
 
 			defaultLBFrontendIPConfigName := cloud.getDefaultFrontendIPConfigName(&tc.service)
-			_, _, dirty, err := cloud.reconcileFrontendIPConfigs("testCluster", &tc.service, &lb, tc.status, true, defaultLBFrontendIPConfigName)
+			_, _, dirty, err := cloud.reconcileFrontendIPConfigs(context.TODO(), "testCluster", &tc.service, &lb, tc.status, true, defaultLBFrontendIPConfigName)
 			if tc.expectedErr == nil {
 				assert.NoError(t, err)
 			} else {
@@ -5076,14 +5263,14 @@ func TestReconcileSharedLoadBalancer(t *testing.T) {
 			}
 
 			mockVMSet := NewMockVMSet(ctrl)
-			mockVMSet.EXPECT().EnsureBackendPoolDeleted(gomock.Any(), "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/vmss1/backendAddressPools/kubernetes", "vmss1", gomock.Any(), gomock.Any()).Return(nil).Times(tc.expectedDeleteCount)
-			mockVMSet.EXPECT().EnsureBackendPoolDeleted(gomock.Any(), "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/vmss1-internal/backendAddressPools/kubernetes", "vmss1", gomock.Any(), gomock.Any()).Return(nil).Times(tc.expectedDeleteCount)
+			mockVMSet.EXPECT().EnsureBackendPoolDeleted(gomock.Any(), gomock.Any(), "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/vmss1/backendAddressPools/kubernetes", "vmss1", gomock.Any(), gomock.Any()).Return(nil).Times(tc.expectedDeleteCount)
+			mockVMSet.EXPECT().EnsureBackendPoolDeleted(gomock.Any(), gomock.Any(), "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/vmss1-internal/backendAddressPools/kubernetes", "vmss1", gomock.Any(), gomock.Any()).Return(nil).Times(tc.expectedDeleteCount)
 			mockVMSet.EXPECT().GetAgentPoolVMSetNames(gomock.Any()).Return(&[]string{"vmss1", "vmss2"}, nil).MaxTimes(tc.expectedGetNamesCount)
 			mockVMSet.EXPECT().GetPrimaryVMSetName().Return("vmss2").AnyTimes()
 			cloud.VMSet = mockVMSet
 
 			service := getTestService("test", v1.ProtocolTCP, nil, false, 80)
-			lbs, err := cloud.reconcileSharedLoadBalancer(&service, "kubernetes", tc.nodes)
+			lbs, err := cloud.reconcileSharedLoadBalancer(context.TODO(), &service, "kubernetes", tc.nodes)
 			if tc.expectedErr != nil {
 				assert.Equal(t, tc.expectedErr.Error(), err.Error())
 			}
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"net/http"
 	"path"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -35,8 +36,201 @@ import (
 	"k8s.io/klog/v2"
 
 	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+	"sigs.k8s.io/cloud-provider-azure/pkg/retry"
 )
 
+// DiskStorageAccountTypesPremiumV2LRS is the Premium SSD v2 disk type, which is not defined in the compute API version of the SDK.
+const DiskStorageAccountTypesPremiumV2LRS compute.DiskStorageAccountTypes = "PremiumV2_LRS"
+
+// onlineResizeSizeLimitGiB is the size a disk could not be expanded across while it is attached.
+const onlineResizeSizeLimitGiB = 4096
+
+// diskEncryptionSetIDRE matches the resource ID of a disk encryption set.
+var diskEncryptionSetIDRE = regexp.MustCompile(`(?i)^/subscriptions/[^/]+/resourceGroups/[^/]+/providers/Microsoft\.Compute/diskEncryptionSets/[^/]+$`)
+
+// diskPerformanceLimits is the range of the provisioned IOPS and throughput of a disk type.
+type diskPerformanceLimits struct {
+	minIOPS       int64
+	maxIOPS       int64
+	maxIOPSPerGiB int64
+	minMBps       int64
+	maxMBps       int64
+	// iopsPerMBps caps the throughput by the provisioned IOPS if it's positive, e.g. 4 means at most 0.25 MBps per IOPS.
+	iopsPerMBps int64
+}
+
+// diskPerformanceLimitsBySku are the limits of the disk types whose IOPS and throughput could be provisioned,
+// refer to https://docs.microsoft.com/en-us/azure/virtual-machines/disks-types.
+var diskPerformanceLimitsBySku = map[compute.DiskStorageAccountTypes]diskPerformanceLimits{
+	compute.DiskStorageAccountTypesUltraSSDLRS: {
+		minIOPS:       100,
+		maxIOPS:       160000,
+		maxIOPSPerGiB: 300,
+		minMBps:       1,
+		maxMBps:       4000,
+	},
+	DiskStorageAccountTypesPremiumV2LRS: {
+		minIOPS:       3000,
+		maxIOPS:       80000,
+		maxIOPSPerGiB: 500,
+		minMBps:       125,
+		maxMBps:       1200,
+		iopsPerMBps:   4,
+	},
+}
+
+// validate checks the IOPS and throughput against the limits of the disk type and the disk size.
+// The nil values are not provisioned explicitly, and the baseline IOPS is used to cap the throughput.
+func (l diskPerformanceLimits) validate(sku compute.DiskStorageAccountTypes, sizeGB int32, iops, mbps *int64) error {
+	provisionedIOPS := l.minIOPS
+	if iops != nil {
+		maxIOPS := l.maxIOPS
+		if sizeGB > 0 {
+			maxIOPS = int64Min(maxIOPS, int64Max(l.minIOPS, l.maxIOPSPerGiB*int64(sizeGB)))
+		}
+		if *iops < l.minIOPS || *iops > maxIOPS {
+			return fmt.Errorf("AzureDisk - DiskIOPSReadWrite(%d) is out of range [%d, %d] for %s disk of %d GiB", *iops, l.minIOPS, maxIOPS, sku, sizeGB)
+		}
+		provisionedIOPS = *iops
+	}
+
+	if mbps != nil {
+		maxMBps := l.maxMBps
+		if l.iopsPerMBps > 0 {
+			maxMBps = int64Min(maxMBps, int64Max(l.minMBps, provisionedIOPS/l.iopsPerMBps))
+		}
+		if *mbps < l.minMBps || *mbps > maxMBps {
+			return fmt.Errorf("AzureDisk - DiskMBpsReadWrite(%d) is out of range [%d, %d] for %s disk with %d IOPS", *mbps, l.minMBps, maxMBps, sku, provisionedIOPS)
+		}
+	}
+	return nil
+}
+
+// parseDiskPerformance parses the IOPS and throughput of the disk, and the empty value is returned as nil.
+func parseDiskPerformance(diskIOPSReadWrite, diskMBpsReadWrite string) (*int64, *int64, error) {
+	var iops, mbps *int64
+	if diskIOPSReadWrite != "" {
+		v, err := strconv.Atoi(diskIOPSReadWrite)
+		if err != nil {
+			return nil, nil, fmt.Errorf("AzureDisk - failed to parse DiskIOPSReadWrite: %w", err)
+		}
+		iops = to.Int64Ptr(int64(v))
+	}
+	if diskMBpsReadWrite != "" {
+		v, err := strconv.Atoi(diskMBpsReadWrite)
+		if err != nil {
+			return nil, nil, fmt.Errorf("AzureDisk - failed to parse DiskMBpsReadWrite: %w", err)
+		}
+		mbps = to.Int64Ptr(int64(v))
+	}
+	return iops, mbps, nil
+}
+
+// validateMaxShares checks maxShares against the limit of the disk type and the disk size,
+// refer to https://docs.microsoft.com/en-us/azure/virtual-machines/disks-shared#disk-sizes.
+func validateMaxShares(sku compute.DiskStorageAccountTypes, sizeGB, maxShares int32) error {
+	var limit int32
+	switch sku {
+	case compute.DiskStorageAccountTypesUltraSSDLRS, DiskStorageAccountTypesPremiumV2LRS:
+		limit = 15
+	case compute.DiskStorageAccountTypesPremiumLRS, compute.DiskStorageAccountTypesPremiumZRS,
+		compute.DiskStorageAccountTypesStandardSSDLRS, compute.DiskStorageAccountTypesStandardSSDZRS:
+		// only P15/E15 (256 GiB) and larger disks could be shared
+		switch {
+		case sizeGB <= 128:
+			limit = 0
+		case sizeGB <= 512:
+			limit = 2
+		case sizeGB <= 4096:
+			limit = 5
+		default:
+			limit = 10
+		}
+	}
+
+	if limit == 0 {
+		return fmt.Errorf("AzureDisk - MaxShares(%d) is not supported by %s disk of %d GiB", maxShares, sku, sizeGB)
+	}
+	if maxShares > limit {
+		return fmt.Errorf("AzureDisk - MaxShares(%d) exceeds the limit %d of %s disk of %d GiB", maxShares, limit, sku, sizeGB)
+	}
+	return nil
+}
+
+// premiumDiskTiers are the performance tiers of Premium SSD disks in ascending order with the maximum disk size of each tier,
+// refer to https://docs.microsoft.com/en-us/azure/virtual-machines/disks-change-performance.
+var premiumDiskTiers = []struct {
+	name       string
+	maxSizeGiB int32
+}{
+	{"P1", 4}, {"P2", 8}, {"P3", 16}, {"P4", 32}, {"P6", 64}, {"P10", 128}, {"P15", 256},
+	{"P20", 512}, {"P30", 1024}, {"P40", 2048}, {"P50", 4096}, {"P60", 8192}, {"P70", 16384}, {"P80", 32767},
+}
+
+// onDemandBurstingMinSizeGiB is the size a Premium SSD disk must be larger than to enable on-demand bursting.
+const onDemandBurstingMinSizeGiB = 512
+
+// isPremiumSSDDiskSku returns true if the disk type is Premium SSD, which supports performance tiers and on-demand bursting.
+func isPremiumSSDDiskSku(sku compute.DiskStorageAccountTypes) bool {
+	return sku == compute.DiskStorageAccountTypesPremiumLRS || sku == compute.DiskStorageAccountTypesPremiumZRS
+}
+
+// validateDiskTier checks the performance tier is valid and not lower than the baseline tier of the disk size.
+func validateDiskTier(sku compute.DiskStorageAccountTypes, sizeGB int32, tier string) error {
+	if !isPremiumSSDDiskSku(sku) {
+		return fmt.Errorf("AzureDisk - PerformanceTier parameter is only applicable in Premium_LRS and Premium_ZRS disk types, current disk type: %s", sku)
+	}
+
+	tierIndex, baselineIndex := -1, -1
+	validTiers := make([]string, 0, len(premiumDiskTiers))
+	for i, t := range premiumDiskTiers {
+		if strings.EqualFold(t.name, tier) {
+			tierIndex = i
+		}
+		if baselineIndex < 0 && sizeGB <= t.maxSizeGiB {
+			baselineIndex = i
+		}
+		validTiers = append(validTiers, t.name)
+	}
+	if tierIndex < 0 {
+		return fmt.Errorf("AzureDisk - PerformanceTier(%s) is invalid, valid tiers: %s", tier, strings.Join(validTiers, ", "))
+	}
+	if tierIndex < baselineIndex {
+		return fmt.Errorf("AzureDisk - PerformanceTier(%s) must not be lower than the baseline tier %s of disk of %d GiB", tier, premiumDiskTiers[baselineIndex].name, sizeGB)
+	}
+	return nil
+}
+
+// validateBurstingEnabled checks on-demand bursting could be enabled on the disk.
+func validateBurstingEnabled(sku compute.DiskStorageAccountTypes, sizeGB int32) error {
+	if !isPremiumSSDDiskSku(sku) {
+		return fmt.Errorf("AzureDisk - on-demand bursting is only applicable in Premium_LRS and Premium_ZRS disk types, current disk type: %s", sku)
+	}
+	if sizeGB <= onDemandBurstingMinSizeGiB {
+		return fmt.Errorf("AzureDisk - on-demand bursting is only applicable in disks larger than %d GiB, current disk size: %d GiB", onDemandBurstingMinSizeGiB, sizeGB)
+	}
+	return nil
+}
+
+// isZRSDiskSku returns true if the disk type is zone-redundant storage.
+func isZRSDiskSku(sku compute.DiskStorageAccountTypes) bool {
+	return sku == compute.DiskStorageAccountTypesPremiumZRS || sku == compute.DiskStorageAccountTypesStandardSSDZRS
+}
+
+func int64Min(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func int64Max(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
 //ManagedDiskController : managed disk controller struct
 type ManagedDiskController struct {
 	common *controllerCommon
@@ -52,13 +246,13 @@ type ManagedDiskOptions struct {
 	PVCName string
 	// The name of resource group.
 	ResourceGroup string
-	// The AvailabilityZone to create the disk.
+	// The AvailabilityZone to create the disk. It must be empty for ZRS disks.
 	AvailabilityZone string
 	// The tags of the disk.
 	Tags map[string]string
-	// IOPS Caps for UltraSSD disk
+	// IOPS Caps for UltraSSD and PremiumV2 disk
 	DiskIOPSReadWrite string
-	// Throughput Cap (MBps) for UltraSSD disk
+	// Throughput Cap (MBps) for UltraSSD and PremiumV2 disk
 	DiskMBpsReadWrite string
 	// if SourceResourceID is not empty, then it's a disk copy operation(for snapshot)
 	SourceResourceID string
@@ -72,7 +266,7 @@ type ManagedDiskOptions struct {
 	SizeGB int
 	// The maximum number of VMs that can attach to the disk at the same time. Value greater than one indicates a disk that can be mounted on multiple VMs at the same time.
 	MaxShares int32
-	// Logical sector size in bytes for Ultra disks
+	// Logical sector size in bytes for UltraSSD and PremiumV2 disks
 	LogicalSectorSize int32
 	// SkipGetDiskOperation indicates whether skip GetDisk operation(mainly due to throttling)
 	SkipGetDiskOperation bool
@@ -82,6 +276,8 @@ type ManagedDiskOptions struct {
 	DiskAccessID *string
 	// BurstingEnabled - Set to true to enable bursting beyond the provisioned performance target of the disk.
 	BurstingEnabled *bool
+	// PerformanceTier - Performance tier of Premium SSD disk, e.g. P30. It must not be lower than the baseline tier of the disk size.
+	PerformanceTier string
 	// SubscriptionID - specify a different SubscriptionID
 	SubscriptionID string
 }
@@ -95,6 +291,10 @@ func (c *ManagedDiskController) CreateManagedDisk(ctx context.Context, options *
 	if len(options.AvailabilityZone) > 0 {
 		requestedZone := c.common.cloud.GetZoneID(options.AvailabilityZone)
 		if requestedZone != "" {
+			// ZRS disks are replicated across the zones of the region, so they could not be pinned to a zone.
+			if isZRSDiskSku(options.StorageAccountType) {
+				return "", fmt.Errorf("AzureDisk - AvailabilityZone(%s) must be empty for zone-redundant disk type %s", options.AvailabilityZone, options.StorageAccountType)
+			}
 			createZones = append(createZones, requestedZone)
 		}
 	}
@@ -131,46 +331,55 @@ func (c *ManagedDiskController) CreateManagedDisk(ctx context.Context, options *
 	if err != nil {
 		return "", err
 	}
+	var source *diskSource
+	if creationData.SourceResourceID != nil {
+		if source, err = c.getDiskSource(ctx, options.SourceType, *creationData.SourceResourceID); err != nil {
+			return "", err
+		}
+		if source.sizeGB > diskSizeGB {
+			klog.V(2).Infof("azureDisk - requested size(%dGiB) of disk(%s) is smaller than the size(%dGiB) of source %s, use the size of source",
+				diskSizeGB, options.DiskName, source.sizeGB, *creationData.SourceResourceID)
+			diskSizeGB = source.sizeGB
+		}
+	}
 	diskProperties := compute.DiskProperties{
 		DiskSizeGB:      &diskSizeGB,
 		CreationData:    &creationData,
 		BurstingEnabled: options.BurstingEnabled,
 	}
+	if source != nil {
+		diskProperties.HyperVGeneration = source.hyperVGeneration
+		diskProperties.SupportsHibernation = source.supportsHibernation
+	}
 
-	if options.NetworkAccessPolicy != "" {
-		diskProperties.NetworkAccessPolicy = options.NetworkAccessPolicy
-		if options.NetworkAccessPolicy == compute.NetworkAccessPolicyAllowPrivate {
-			if options.DiskAccessID == nil {
-				return "", fmt.Errorf("DiskAccessID should not be empty when NetworkAccessPolicy is AllowPrivate")
-			}
-			diskProperties.DiskAccessID = options.DiskAccessID
-		} else {
-			if options.DiskAccessID != nil {
-				return "", fmt.Errorf("DiskAccessID(%s) must be empty when NetworkAccessPolicy(%s) is not AllowPrivate", *options.DiskAccessID, options.NetworkAccessPolicy)
-			}
+	networkAccessPolicy, diskAccessID := c.getDiskNetworkAccessPolicy(options)
+	if networkAccessPolicy != "" {
+		if err := validateDiskNetworkAccessPolicy(networkAccessPolicy, diskAccessID); err != nil {
+			return "", err
 		}
+		diskProperties.NetworkAccessPolicy = networkAccessPolicy
+		diskProperties.DiskAccessID = diskAccessID
 	}
 
-	if diskSku == compute.DiskStorageAccountTypesUltraSSDLRS {
-		diskIOPSReadWrite := int64(consts.DefaultDiskIOPSReadWrite)
-		if options.DiskIOPSReadWrite != "" {
-			v, err := strconv.Atoi(options.DiskIOPSReadWrite)
-			if err != nil {
-				return "", fmt.Errorf("AzureDisk - failed to parse DiskIOPSReadWrite: %w", err)
-			}
-			diskIOPSReadWrite = int64(v)
+	if limits, ok := diskPerformanceLimitsBySku[diskSku]; ok {
+		diskIOPSReadWrite, diskMBpsReadWrite, err := parseDiskPerformance(options.DiskIOPSReadWrite, options.DiskMBpsReadWrite)
+		if err != nil {
+			return "", err
+		}
+		if err := limits.validate(diskSku, diskSizeGB, diskIOPSReadWrite, diskMBpsReadWrite); err != nil {
+			return "", err
 		}
-		diskProperties.DiskIOPSReadWrite = to.Int64Ptr(diskIOPSReadWrite)
 
-		diskMBpsReadWrite := int64(consts.DefaultDiskMBpsReadWrite)
-		if options.DiskMBpsReadWrite != "" {
-			v, err := strconv.Atoi(options.DiskMBpsReadWrite)
-			if err != nil {
-				return "", fmt.Errorf("AzureDisk - failed to parse DiskMBpsReadWrite: %w", err)
+		if diskSku == compute.DiskStorageAccountTypesUltraSSDLRS {
+			if diskIOPSReadWrite == nil {
+				diskIOPSReadWrite = to.Int64Ptr(consts.DefaultDiskIOPSReadWrite)
+			}
+			if diskMBpsReadWrite == nil {
+				diskMBpsReadWrite = to.Int64Ptr(consts.DefaultDiskMBpsReadWrite)
 			}
-			diskMBpsReadWrite = int64(v)
 		}
-		diskProperties.DiskMBpsReadWrite = to.Int64Ptr(diskMBpsReadWrite)
+		diskProperties.DiskIOPSReadWrite = diskIOPSReadWrite
+		diskProperties.DiskMBpsReadWrite = diskMBpsReadWrite
 
 		if options.LogicalSectorSize != 0 {
 			klog.V(2).Infof("AzureDisk - requested LogicalSectorSize: %v", options.LogicalSectorSize)
@@ -178,27 +387,28 @@ func (c *ManagedDiskController) CreateManagedDisk(ctx context.Context, options *
 		}
 	} else {
 		if options.DiskIOPSReadWrite != "" {
-			return "", fmt.Errorf("AzureDisk - DiskIOPSReadWrite parameter is only applicable in UltraSSD_LRS disk type")
+			return "", fmt.Errorf("AzureDisk - DiskIOPSReadWrite parameter is only applicable in UltraSSD_LRS and PremiumV2_LRS disk types, current disk type: %s", diskSku)
 		}
 		if options.DiskMBpsReadWrite != "" {
-			return "", fmt.Errorf("AzureDisk - DiskMBpsReadWrite parameter is only applicable in UltraSSD_LRS disk type")
+			return "", fmt.Errorf("AzureDisk - DiskMBpsReadWrite parameter is only applicable in UltraSSD_LRS and PremiumV2_LRS disk types, current disk type: %s", diskSku)
 		}
 		if options.LogicalSectorSize != 0 {
-			return "", fmt.Errorf("AzureDisk - LogicalSectorSize parameter is only applicable in UltraSSD_LRS disk type")
+			return "", fmt.Errorf("AzureDisk - LogicalSectorSize parameter is only applicable in UltraSSD_LRS and PremiumV2_LRS disk types, current disk type: %s", diskSku)
 		}
 	}
 
-	if options.DiskEncryptionSetID != "" {
-		if strings.Index(strings.ToLower(options.DiskEncryptionSetID), "/subscriptions/") != 0 {
-			return "", fmt.Errorf("AzureDisk - format of DiskEncryptionSetID(%s) is incorrect, correct format: %s", options.DiskEncryptionSetID, consts.DiskEncryptionSetIDFormat)
+	diskEncryptionSetID, diskEncryptionType := c.getDiskEncryptionSetID(options, source)
+	if diskEncryptionSetID != "" {
+		if !diskEncryptionSetIDRE.MatchString(diskEncryptionSetID) {
+			return "", fmt.Errorf("AzureDisk - format of DiskEncryptionSetID(%s) is incorrect, correct format: %s", diskEncryptionSetID, consts.DiskEncryptionSetIDFormat)
 		}
 		encryptionType := compute.EncryptionTypeEncryptionAtRestWithCustomerKey
-		if options.DiskEncryptionType != "" {
-			encryptionType = compute.EncryptionType(options.DiskEncryptionType)
-			klog.V(4).Infof("azureDisk - DiskEncryptionType: %s, DiskEncryptionSetID: %s", options.DiskEncryptionType, options.DiskEncryptionSetID)
+		if diskEncryptionType != "" {
+			encryptionType = compute.EncryptionType(diskEncryptionType)
 		}
+		klog.V(4).Infof("azureDisk - DiskEncryptionType: %s, DiskEncryptionSetID: %s", encryptionType, diskEncryptionSetID)
 		diskProperties.Encryption = &compute.Encryption{
-			DiskEncryptionSetID: &options.DiskEncryptionSetID,
+			DiskEncryptionSetID: &diskEncryptionSetID,
 			Type:                encryptionType,
 		}
 	} else {
@@ -207,7 +417,22 @@ func (c *ManagedDiskController) CreateManagedDisk(ctx context.Context, options *
 		}
 	}
 
+	if to.Bool(options.BurstingEnabled) {
+		if err := validateBurstingEnabled(diskSku, diskSizeGB); err != nil {
+			return "", err
+		}
+	}
+	if options.PerformanceTier != "" {
+		if err := validateDiskTier(diskSku, diskSizeGB, options.PerformanceTier); err != nil {
+			return "", err
+		}
+		diskProperties.Tier = &options.PerformanceTier
+	}
+
 	if options.MaxShares > 1 {
+		if err := validateMaxShares(diskSku, diskSizeGB, options.MaxShares); err != nil {
+			return "", err
+		}
 		diskProperties.MaxShares = &options.MaxShares
 	}
 
@@ -232,6 +457,9 @@ func (c *ManagedDiskController) CreateManagedDisk(ctx context.Context, options *
 	}
 
 	if rerr := c.common.cloud.DisksClient.CreateOrUpdate(ctx, subsID, rg, options.DiskName, model); rerr != nil {
+		if diskEncryptionSetID != "" && isKeyVaultAccessError(rerr) {
+			return "", fmt.Errorf("AzureDisk - failed to create disk(%s) with DiskEncryptionSetID(%s), make sure the identity of the disk encryption set has get, wrapKey and unwrapKey permissions on the key vault: %w", options.DiskName, diskEncryptionSetID, rerr.Error())
+		}
 		return "", rerr.Error()
 	}
 
@@ -260,6 +488,10 @@ func (c *ManagedDiskController) CreateManagedDisk(ctx context.Context, options *
 
 		if err != nil {
 			klog.Warningf("azureDisk - created new MD Name:%s StorageAccountType:%s Size:%v but was unable to confirm provisioningState in poll process", options.DiskName, options.StorageAccountType, options.SizeGB)
+		} else if networkAccessPolicy != "" {
+			if err := c.reconcileDiskNetworkAccessPolicy(ctx, subsID, rg, options.DiskName, networkAccessPolicy, diskAccessID); err != nil {
+				return "", err
+			}
 		}
 	}
 
@@ -318,7 +550,7 @@ func (c *ManagedDiskController) GetDisk(ctx context.Context, subsID, resourceGro
 	return "", "", nil
 }
 
-// ResizeDisk Expand the disk to new size
+// ResizeDisk Expand the disk to new size. An attached disk is expanded online if supportOnlineResize is set or its disk type supports that.
 func (c *ManagedDiskController) ResizeDisk(ctx context.Context, diskURI string, oldSize resource.Quantity, newSize resource.Quantity, supportOnlineResize bool) (resource.Quantity, error) {
 	diskName := path.Base(diskURI)
 	resourceGroup, subsID, err := getInfoFromDiskURI(diskURI)
@@ -349,7 +581,7 @@ func (c *ManagedDiskController) ResizeDisk(ctx context.Context, diskURI string,
 		return newSizeQuant, nil
 	}
 
-	if !supportOnlineResize && result.DiskProperties.DiskState != compute.DiskStateUnattached {
+	if result.DiskProperties.DiskState != compute.DiskStateUnattached && !supportOnlineResize && !isOnlineResizeSupported(result, requestGiB) {
 		return oldSize, fmt.Errorf("azureDisk - disk resize is only supported on Unattached disk, current disk state: %s, already attached to %s", result.DiskProperties.DiskState, to.String(result.ManagedBy))
 	}
 
@@ -367,6 +599,294 @@ func (c *ManagedDiskController) ResizeDisk(ctx context.Context, diskURI string,
 	return newSizeQuant, nil
 }
 
+// isOnlineResizeSupported returns true if the disk could be expanded to requestGiB while it is attached to a VM,
+// refer to https://docs.microsoft.com/en-us/azure/virtual-machines/linux/expand-disks#expand-without-downtime.
+func isOnlineResizeSupported(disk compute.Disk, requestGiB int32) bool {
+	if disk.Sku == nil || disk.DiskProperties == nil || disk.DiskProperties.DiskSizeGB == nil {
+		return false
+	}
+	switch disk.Sku.Name {
+	case compute.DiskStorageAccountTypesStandardLRS,
+		compute.DiskStorageAccountTypesStandardSSDLRS,
+		compute.DiskStorageAccountTypesStandardSSDZRS,
+		compute.DiskStorageAccountTypesPremiumLRS,
+		compute.DiskStorageAccountTypesPremiumZRS:
+	default:
+		return false
+	}
+	// shared disks could not be expanded while attached
+	if disk.DiskProperties.MaxShares != nil && *disk.DiskProperties.MaxShares > 1 {
+		return false
+	}
+	// disks of 4 TiB or smaller could not be expanded beyond 4 TiB while attached
+	return *disk.DiskProperties.DiskSizeGB > onlineResizeSizeLimitGiB || requestGiB <= onlineResizeSizeLimitGiB
+}
+
+// ModifyDiskPerformance updates the provisioned IOPS and throughput (MBps) of an UltraSSD_LRS or PremiumV2_LRS disk.
+// The empty value keeps the current setting of the disk.
+func (c *ManagedDiskController) ModifyDiskPerformance(ctx context.Context, diskURI string, diskIOPSReadWrite, diskMBpsReadWrite string) error {
+	diskName := path.Base(diskURI)
+	resourceGroup, subsID, err := getInfoFromDiskURI(diskURI)
+	if err != nil {
+		return err
+	}
+
+	iops, mbps, err := parseDiskPerformance(diskIOPSReadWrite, diskMBpsReadWrite)
+	if err != nil {
+		return err
+	}
+	if iops == nil && mbps == nil {
+		return nil
+	}
+
+	result, rerr := c.common.cloud.DisksClient.Get(ctx, subsID, resourceGroup, diskName)
+	if rerr != nil {
+		return rerr.Error()
+	}
+	if result.Sku == nil || result.DiskProperties == nil {
+		return fmt.Errorf("sku or DiskProperties of disk(%s) is nil", diskName)
+	}
+
+	limits, ok := diskPerformanceLimitsBySku[result.Sku.Name]
+	if !ok {
+		return fmt.Errorf("AzureDisk - IOPS and throughput could only be modified on UltraSSD_LRS and PremiumV2_LRS disk types, current disk type: %s", result.Sku.Name)
+	}
+	// the throughput is capped by the current IOPS of the disk if the IOPS is not changed
+	validateIOPS := iops
+	if validateIOPS == nil {
+		validateIOPS = result.DiskProperties.DiskIOPSReadWrite
+	}
+	if err := limits.validate(result.Sku.Name, to.Int32(result.DiskProperties.DiskSizeGB), validateIOPS, mbps); err != nil {
+		return err
+	}
+
+	diskParameter := compute.DiskUpdate{
+		DiskUpdateProperties: &compute.DiskUpdateProperties{
+			DiskIOPSReadWrite: iops,
+			DiskMBpsReadWrite: mbps,
+		},
+	}
+
+	klog.V(2).Infof("azureDisk - begin to modify disk(%s) with IOPS(%s) and MBps(%s)", diskName, diskIOPSReadWrite, diskMBpsReadWrite)
+	if rerr := c.common.cloud.DisksClient.Update(ctx, subsID, resourceGroup, diskName, diskParameter); rerr != nil {
+		return rerr.Error()
+	}
+
+	klog.V(2).Infof("azureDisk - modify disk(%s) with IOPS(%s) and MBps(%s) completed", diskName, diskIOPSReadWrite, diskMBpsReadWrite)
+	return nil
+}
+
+// ModifyDiskTier updates the performance tier and on-demand bursting of a Premium SSD disk.
+// The empty tier or nil burstingEnabled keeps the current setting of the disk.
+func (c *ManagedDiskController) ModifyDiskTier(ctx context.Context, diskURI string, performanceTier string, burstingEnabled *bool) error {
+	if performanceTier == "" && burstingEnabled == nil {
+		return nil
+	}
+
+	diskName := path.Base(diskURI)
+	resourceGroup, subsID, err := getInfoFromDiskURI(diskURI)
+	if err != nil {
+		return err
+	}
+
+	result, rerr := c.common.cloud.DisksClient.Get(ctx, subsID, resourceGroup, diskName)
+	if rerr != nil {
+		return rerr.Error()
+	}
+	if result.Sku == nil || result.DiskProperties == nil {
+		return fmt.Errorf("sku or DiskProperties of disk(%s) is nil", diskName)
+	}
+
+	sizeGB := to.Int32(result.DiskProperties.DiskSizeGB)
+	diskParameter := compute.DiskUpdate{
+		DiskUpdateProperties: &compute.DiskUpdateProperties{
+			BurstingEnabled: burstingEnabled,
+		},
+	}
+	if performanceTier != "" {
+		if err := validateDiskTier(result.Sku.Name, sizeGB, performanceTier); err != nil {
+			return err
+		}
+		diskParameter.DiskUpdateProperties.Tier = &performanceTier
+	}
+	if to.Bool(burstingEnabled) {
+		if err := validateBurstingEnabled(result.Sku.Name, sizeGB); err != nil {
+			return err
+		}
+	}
+
+	klog.V(2).Infof("azureDisk - begin to modify disk(%s) with PerformanceTier(%s) and BurstingEnabled(%v)", diskName, performanceTier, to.Bool(burstingEnabled))
+	if rerr := c.common.cloud.DisksClient.Update(ctx, subsID, resourceGroup, diskName, diskParameter); rerr != nil {
+		return rerr.Error()
+	}
+
+	klog.V(2).Infof("azureDisk - modify disk(%s) with PerformanceTier(%s) and BurstingEnabled(%v) completed", diskName, performanceTier, to.Bool(burstingEnabled))
+	return nil
+}
+
+// getDiskNetworkAccessPolicy returns the network access policy and the disk access ID of the disk to be created.
+// The NetworkAccessPolicy in the options takes precedence over the DefaultDiskNetworkAccessPolicy in the cloud config.
+func (c *ManagedDiskController) getDiskNetworkAccessPolicy(options *ManagedDiskOptions) (compute.NetworkAccessPolicy, *string) {
+	if options.NetworkAccessPolicy != "" {
+		return options.NetworkAccessPolicy, options.DiskAccessID
+	}
+	if c.common.cloud.DefaultDiskNetworkAccessPolicy == "" {
+		return "", options.DiskAccessID
+	}
+
+	diskAccessID := options.DiskAccessID
+	if diskAccessID == nil && c.common.cloud.DefaultDiskAccessID != "" {
+		diskAccessID = to.StringPtr(c.common.cloud.DefaultDiskAccessID)
+	}
+	return compute.NetworkAccessPolicy(c.common.cloud.DefaultDiskNetworkAccessPolicy), diskAccessID
+}
+
+// validateDiskNetworkAccessPolicy checks the network access policy is valid and the disk access ID is only set with AllowPrivate.
+func validateDiskNetworkAccessPolicy(networkAccessPolicy compute.NetworkAccessPolicy, diskAccessID *string) error {
+	switch networkAccessPolicy {
+	case compute.NetworkAccessPolicyAllowPrivate:
+		if diskAccessID == nil || *diskAccessID == "" {
+			return fmt.Errorf("DiskAccessID should not be empty when NetworkAccessPolicy is AllowPrivate")
+		}
+	case compute.NetworkAccessPolicyAllowAll, compute.NetworkAccessPolicyDenyAll:
+		if diskAccessID != nil {
+			return fmt.Errorf("DiskAccessID(%s) must be empty when NetworkAccessPolicy(%s) is not AllowPrivate", *diskAccessID, networkAccessPolicy)
+		}
+	default:
+		return fmt.Errorf("NetworkAccessPolicy(%s) is invalid, supported values: %s, %s, %s", networkAccessPolicy,
+			compute.NetworkAccessPolicyAllowAll, compute.NetworkAccessPolicyAllowPrivate, compute.NetworkAccessPolicyDenyAll)
+	}
+	return nil
+}
+
+// reconcileDiskNetworkAccessPolicy checks the network access policy of an existing disk. The difference is only
+// logged unless EnforceDiskNetworkAccessPolicy is set in the cloud config, in which case the disk is patched.
+func (c *ManagedDiskController) reconcileDiskNetworkAccessPolicy(ctx context.Context, subsID, resourceGroup, diskName string, networkAccessPolicy compute.NetworkAccessPolicy, diskAccessID *string) error {
+	result, rerr := c.common.cloud.DisksClient.Get(ctx, subsID, resourceGroup, diskName)
+	if rerr != nil {
+		return rerr.Error()
+	}
+	if result.DiskProperties == nil {
+		return fmt.Errorf("DiskProperties of disk(%s) is nil", diskName)
+	}
+
+	if result.DiskProperties.NetworkAccessPolicy == networkAccessPolicy &&
+		strings.EqualFold(to.String(result.DiskProperties.DiskAccessID), to.String(diskAccessID)) {
+		return nil
+	}
+	if !c.common.cloud.EnforceDiskNetworkAccessPolicy {
+		klog.Warningf("azureDisk - disk(%s) has NetworkAccessPolicy(%s) and DiskAccessID(%s), expected NetworkAccessPolicy(%s) and DiskAccessID(%s)",
+			diskName, result.DiskProperties.NetworkAccessPolicy, to.String(result.DiskProperties.DiskAccessID), networkAccessPolicy, to.String(diskAccessID))
+		return nil
+	}
+
+	diskParameter := compute.DiskUpdate{
+		DiskUpdateProperties: &compute.DiskUpdateProperties{
+			NetworkAccessPolicy: networkAccessPolicy,
+			DiskAccessID:        diskAccessID,
+		},
+	}
+	klog.V(2).Infof("azureDisk - begin to update disk(%s) with NetworkAccessPolicy(%s) and DiskAccessID(%s)", diskName, networkAccessPolicy, to.String(diskAccessID))
+	if rerr := c.common.cloud.DisksClient.Update(ctx, subsID, resourceGroup, diskName, diskParameter); rerr != nil {
+		return rerr.Error()
+	}
+	return nil
+}
+
+// getDiskEncryptionSetID returns the disk encryption set ID and the encryption type of the disk to be created.
+// The DiskEncryptionSetID in the options takes precedence, then the one of the source snapshot,
+// and the DefaultDiskEncryptionSetID in the cloud config at last.
+func (c *ManagedDiskController) getDiskEncryptionSetID(options *ManagedDiskOptions, source *diskSource) (string, string) {
+	if options.DiskEncryptionSetID != "" {
+		return options.DiskEncryptionSetID, options.DiskEncryptionType
+	}
+
+	if options.SourceType == sourceSnapshot && source != nil && source.encryption != nil &&
+		source.encryption.DiskEncryptionSetID != nil && *source.encryption.DiskEncryptionSetID != "" {
+		diskEncryptionType := options.DiskEncryptionType
+		if diskEncryptionType == "" {
+			diskEncryptionType = string(source.encryption.Type)
+		}
+		return *source.encryption.DiskEncryptionSetID, diskEncryptionType
+	}
+
+	return c.common.cloud.DefaultDiskEncryptionSetID, options.DiskEncryptionType
+}
+
+// diskSource is the properties of the snapshot or disk which a disk is copied from.
+type diskSource struct {
+	location            string
+	sizeGB              int32
+	hyperVGeneration    compute.HyperVGeneration
+	supportsHibernation *bool
+	encryption          *compute.Encryption
+}
+
+// getDiskSource gets the source snapshot or disk by its resource ID, which could be in another subscription,
+// and makes sure it is in the same region as the disk to create.
+func (c *ManagedDiskController) getDiskSource(ctx context.Context, sourceType, sourceResourceID string) (*diskSource, error) {
+	resourceGroup, subsID, err := getInfoFromDiskURI(sourceResourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	source := &diskSource{}
+	var rerr *retry.Error
+	switch sourceType {
+	case sourceSnapshot:
+		var snapshot compute.Snapshot
+		snapshot, rerr = c.common.cloud.SnapshotsClient.Get(ctx, subsID, resourceGroup, path.Base(sourceResourceID))
+		if rerr == nil {
+			source.location = to.String(snapshot.Location)
+			if snapshot.SnapshotProperties != nil {
+				source.sizeGB = to.Int32(snapshot.DiskSizeGB)
+				source.hyperVGeneration = snapshot.HyperVGeneration
+				source.supportsHibernation = snapshot.SnapshotProperties.SupportsHibernation
+				source.encryption = snapshot.Encryption
+			}
+		}
+	case sourceVolume:
+		var disk compute.Disk
+		disk, rerr = c.common.cloud.DisksClient.Get(ctx, subsID, resourceGroup, path.Base(sourceResourceID))
+		if rerr == nil {
+			source.location = to.String(disk.Location)
+			if disk.DiskProperties != nil {
+				source.sizeGB = to.Int32(disk.DiskSizeGB)
+				source.hyperVGeneration = disk.HyperVGeneration
+				source.supportsHibernation = disk.DiskProperties.SupportsHibernation
+				source.encryption = disk.Encryption
+			}
+		}
+	default:
+		return nil, fmt.Errorf("AzureDisk - source type(%s) is not supported, supported values: %s, %s", sourceType, sourceSnapshot, sourceVolume)
+	}
+	if rerr != nil {
+		if rerr.HTTPStatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("AzureDisk - source %s(%s) is not found", sourceType, sourceResourceID)
+		}
+		return nil, fmt.Errorf("AzureDisk - failed to get source %s(%s): %w", sourceType, sourceResourceID, rerr.Error())
+	}
+
+	if source.location != "" && !strings.EqualFold(normalizeLocation(source.location), normalizeLocation(c.common.location)) {
+		return nil, fmt.Errorf("AzureDisk - source %s(%s) is in region %s, which is different from the region %s of the disk to create",
+			sourceType, sourceResourceID, source.location, c.common.location)
+	}
+	return source, nil
+}
+
+// normalizeLocation converts the display name of a location, e.g. "West US", to its name, e.g. "westus".
+func normalizeLocation(location string) string {
+	return strings.ToLower(strings.ReplaceAll(location, " ", ""))
+}
+
+// isKeyVaultAccessError returns true if ARM fails to access the key vault of the disk encryption set.
+func isKeyVaultAccessError(rerr *retry.Error) bool {
+	if strings.Contains(strings.ToLower(rerr.ServiceErrorCode()), "keyvault") {
+		return true
+	}
+	return rerr.RawError != nil && strings.Contains(strings.ToLower(rerr.RawError.Error()), "keyvault")
+}
+
 // get resource group name, subs id from a managed disk URI, e.g. return {group-name}, {sub-id} according to
 // /subscriptions/{sub-id}/resourcegroups/{group-name}/providers/microsoft.compute/disks/{disk-id}
 // according to https://docs.microsoft.com/en-us/rest/api/compute/disks/get
@@ -416,6 +936,12 @@ func (c *Cloud) GetAzureDiskLabels(ctx context.Context, diskURI string) (map[str
 		return nil, rerr.Error()
 	}
 
+	// ZRS disks are not in a single zone, so the zone label is omitted.
+	if disk.Sku != nil && isZRSDiskSku(disk.Sku.Name) {
+		klog.V(4).Infof("Azure disk %s is zone-redundant", diskName)
+		return labels, nil
+	}
+
 	// Check whether availability zone is specified.
 	if disk.Zones == nil || len(*disk.Zones) == 0 {
 		klog.V(4).Infof("Azure disk %s is not zoned", diskName)
@@ -19,6 +19,7 @@ package provider
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"strings"
 	"testing"
 
@@ -32,6 +33,7 @@ import (
 	cloudvolume "k8s.io/cloud-provider/volume"
 
 	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/diskclient/mockdiskclient"
+	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/snapshotclient/mocksnapshotclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
 	"sigs.k8s.io/cloud-provider-azure/pkg/retry"
 )
@@ -49,7 +51,6 @@ func TestCreateManagedDisk(t *testing.T) {
 	ctx, cancel := getContextWithCancel()
 	defer cancel()
 
-	maxShare := int32(2)
 	goodDiskEncryptionSetID := fmt.Sprintf("/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/diskEncryptionSets/%s", "diskEncryptionSet-name")
 	badDiskEncryptionSetID := "badDiskEncryptionSetID"
 	testTags := make(map[string]*string)
@@ -135,6 +136,82 @@ func TestCreateManagedDisk(t *testing.T) {
 			expectedErr:         true,
 			expectedErrMsg:      fmt.Errorf("AzureDisk - format of DiskEncryptionSetID(%s) is incorrect, correct format: %s", badDiskEncryptionSetID, consts.DiskEncryptionSetIDFormat),
 		},
+		{
+			desc:                "disk Id and no error shall be returned if everything is good with PremiumV2LRS storage account",
+			diskID:              disk1ID,
+			diskName:            disk1Name,
+			storageAccountType:  DiskStorageAccountTypesPremiumV2LRS,
+			diskIOPSReadWrite:   "3000",
+			diskMBPSReadWrite:   "125",
+			diskEncryptionSetID: goodDiskEncryptionSetID,
+			expectedDiskID:      disk1ID,
+			existedDisk:         compute.Disk{ID: to.StringPtr(disk1ID), Name: to.StringPtr(disk1Name), DiskProperties: &compute.DiskProperties{Encryption: &compute.Encryption{DiskEncryptionSetID: &goodDiskEncryptionSetID, Type: compute.EncryptionTypeEncryptionAtRestWithCustomerKey}, ProvisioningState: to.StringPtr("Succeeded")}, Tags: testTags},
+			expectedErr:         false,
+		},
+		{
+			desc:                "disk Id and no error shall be returned with PremiumV2LRS storage account and default performance",
+			diskID:              disk1ID,
+			diskName:            disk1Name,
+			storageAccountType:  DiskStorageAccountTypesPremiumV2LRS,
+			diskIOPSReadWrite:   "",
+			diskMBPSReadWrite:   "",
+			diskEncryptionSetID: goodDiskEncryptionSetID,
+			expectedDiskID:      disk1ID,
+			existedDisk:         compute.Disk{ID: to.StringPtr(disk1ID), Name: to.StringPtr(disk1Name), DiskProperties: &compute.DiskProperties{Encryption: &compute.Encryption{DiskEncryptionSetID: &goodDiskEncryptionSetID, Type: compute.EncryptionTypeEncryptionAtRestWithCustomerKey}, ProvisioningState: to.StringPtr("Succeeded")}, Tags: testTags},
+			expectedErr:         false,
+		},
+		{
+			desc:                "an error shall be returned if DiskIOPSReadWrite exceeds the limit of the UltraSSDLRS disk size",
+			diskID:              disk1ID,
+			diskName:            disk1Name,
+			storageAccountType:  compute.DiskStorageAccountTypesUltraSSDLRS,
+			diskIOPSReadWrite:   "301",
+			diskMBPSReadWrite:   "100",
+			diskEncryptionSetID: goodDiskEncryptionSetID,
+			expectedDiskID:      "",
+			existedDisk:         compute.Disk{ID: to.StringPtr(disk1ID), Name: to.StringPtr(disk1Name), DiskProperties: &compute.DiskProperties{Encryption: &compute.Encryption{DiskEncryptionSetID: &goodDiskEncryptionSetID, Type: compute.EncryptionTypeEncryptionAtRestWithCustomerKey}, ProvisioningState: to.StringPtr("Succeeded")}, Tags: testTags},
+			expectedErr:         true,
+			expectedErrMsg:      fmt.Errorf("AzureDisk - DiskIOPSReadWrite(301) is out of range [100, 300] for UltraSSD_LRS disk of 1 GiB"),
+		},
+		{
+			desc:                "an error shall be returned if DiskMBpsReadWrite is below the limit of the UltraSSDLRS disk",
+			diskID:              disk1ID,
+			diskName:            disk1Name,
+			storageAccountType:  compute.DiskStorageAccountTypesUltraSSDLRS,
+			diskIOPSReadWrite:   "300",
+			diskMBPSReadWrite:   "0",
+			diskEncryptionSetID: goodDiskEncryptionSetID,
+			expectedDiskID:      "",
+			existedDisk:         compute.Disk{ID: to.StringPtr(disk1ID), Name: to.StringPtr(disk1Name), DiskProperties: &compute.DiskProperties{Encryption: &compute.Encryption{DiskEncryptionSetID: &goodDiskEncryptionSetID, Type: compute.EncryptionTypeEncryptionAtRestWithCustomerKey}, ProvisioningState: to.StringPtr("Succeeded")}, Tags: testTags},
+			expectedErr:         true,
+			expectedErrMsg:      fmt.Errorf("AzureDisk - DiskMBpsReadWrite(0) is out of range [1, 4000] for UltraSSD_LRS disk with 300 IOPS"),
+		},
+		{
+			desc:                "an error shall be returned if DiskIOPSReadWrite is below the baseline of the PremiumV2LRS disk",
+			diskID:              disk1ID,
+			diskName:            disk1Name,
+			storageAccountType:  DiskStorageAccountTypesPremiumV2LRS,
+			diskIOPSReadWrite:   "2999",
+			diskMBPSReadWrite:   "",
+			diskEncryptionSetID: goodDiskEncryptionSetID,
+			expectedDiskID:      "",
+			existedDisk:         compute.Disk{ID: to.StringPtr(disk1ID), Name: to.StringPtr(disk1Name), DiskProperties: &compute.DiskProperties{Encryption: &compute.Encryption{DiskEncryptionSetID: &goodDiskEncryptionSetID, Type: compute.EncryptionTypeEncryptionAtRestWithCustomerKey}, ProvisioningState: to.StringPtr("Succeeded")}, Tags: testTags},
+			expectedErr:         true,
+			expectedErrMsg:      fmt.Errorf("AzureDisk - DiskIOPSReadWrite(2999) is out of range [3000, 3000] for PremiumV2_LRS disk of 1 GiB"),
+		},
+		{
+			desc:                "an error shall be returned if DiskMBpsReadWrite exceeds the limit of the PremiumV2LRS disk IOPS",
+			diskID:              disk1ID,
+			diskName:            disk1Name,
+			storageAccountType:  DiskStorageAccountTypesPremiumV2LRS,
+			diskIOPSReadWrite:   "",
+			diskMBPSReadWrite:   "751",
+			diskEncryptionSetID: goodDiskEncryptionSetID,
+			expectedDiskID:      "",
+			existedDisk:         compute.Disk{ID: to.StringPtr(disk1ID), Name: to.StringPtr(disk1Name), DiskProperties: &compute.DiskProperties{Encryption: &compute.Encryption{DiskEncryptionSetID: &goodDiskEncryptionSetID, Type: compute.EncryptionTypeEncryptionAtRestWithCustomerKey}, ProvisioningState: to.StringPtr("Succeeded")}, Tags: testTags},
+			expectedErr:         true,
+			expectedErrMsg:      fmt.Errorf("AzureDisk - DiskMBpsReadWrite(751) is out of range [125, 750] for PremiumV2_LRS disk with 3000 IOPS"),
+		},
 		{
 			desc:                "DiskEncryptionType should be empty when DiskEncryptionSetID is not set",
 			diskID:              disk1ID,
@@ -158,7 +235,7 @@ func TestCreateManagedDisk(t *testing.T) {
 			expectedDiskID:      "",
 			existedDisk:         compute.Disk{ID: to.StringPtr(disk1ID), Name: to.StringPtr(disk1Name), DiskProperties: &compute.DiskProperties{Encryption: &compute.Encryption{DiskEncryptionSetID: &goodDiskEncryptionSetID, Type: compute.EncryptionTypeEncryptionAtRestWithCustomerKey}, ProvisioningState: to.StringPtr("Succeeded")}, Tags: testTags},
 			expectedErr:         true,
-			expectedErrMsg:      fmt.Errorf("AzureDisk - DiskIOPSReadWrite parameter is only applicable in UltraSSD_LRS disk type"),
+			expectedErrMsg:      fmt.Errorf("AzureDisk - DiskIOPSReadWrite parameter is only applicable in UltraSSD_LRS and PremiumV2_LRS disk types, current disk type: Standard_LRS"),
 		},
 		{
 			desc:                "disk Id and no error shall be returned if everything is good with StandardLRS storage account with not empty diskMBPSReadWrite",
@@ -171,7 +248,7 @@ func TestCreateManagedDisk(t *testing.T) {
 			expectedDiskID:      "",
 			existedDisk:         compute.Disk{ID: to.StringPtr(disk1ID), Name: to.StringPtr(disk1Name), DiskProperties: &compute.DiskProperties{Encryption: &compute.Encryption{DiskEncryptionSetID: &goodDiskEncryptionSetID, Type: compute.EncryptionTypeEncryptionAtRestWithCustomerKey}, ProvisioningState: to.StringPtr("Succeeded")}, Tags: testTags},
 			expectedErr:         true,
-			expectedErrMsg:      fmt.Errorf("AzureDisk - DiskMBpsReadWrite parameter is only applicable in UltraSSD_LRS disk type"),
+			expectedErrMsg:      fmt.Errorf("AzureDisk - DiskMBpsReadWrite parameter is only applicable in UltraSSD_LRS and PremiumV2_LRS disk types, current disk type: Standard_LRS"),
 		},
 		{
 			desc:                "correct NetworkAccessPolicy(DenyAll) setting",
@@ -248,7 +325,6 @@ func TestCreateManagedDisk(t *testing.T) {
 			DiskMBpsReadWrite:   test.diskMBPSReadWrite,
 			DiskEncryptionSetID: test.diskEncryptionSetID,
 			DiskEncryptionType:  test.diskEncryptionType,
-			MaxShares:           maxShare,
 			NetworkAccessPolicy: test.networkAccessPolicy,
 			DiskAccessID:        test.diskAccessID,
 			SubscriptionID:      test.subscriptionID,
@@ -315,6 +391,722 @@ func TestCreateManagedDiskWithExtendedLocation(t *testing.T) {
 	assert.Nil(t, err, "There should not be an error.")
 }
 
+func TestCreateManagedDiskZones(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := getContextWithCancel()
+	defer cancel()
+
+	testCases := []struct {
+		desc               string
+		storageAccountType compute.DiskStorageAccountTypes
+		availabilityZone   string
+		expectedZones      *[]string
+		expectedErrMsg     error
+	}{
+		{
+			desc:               "zonal LRS disk shall be created in the requested zone",
+			storageAccountType: compute.DiskStorageAccountTypesPremiumLRS,
+			availabilityZone:   "westus-1",
+			expectedZones:      &[]string{"1"},
+		},
+		{
+			desc:               "regional LRS disk shall be created without zone",
+			storageAccountType: compute.DiskStorageAccountTypesPremiumLRS,
+		},
+		{
+			desc:               "LRS disk shall be created without zone if the zone is a fault domain",
+			storageAccountType: compute.DiskStorageAccountTypesStandardSSDLRS,
+			availabilityZone:   "0",
+		},
+		{
+			desc:               "Premium_ZRS disk shall be created without zone",
+			storageAccountType: compute.DiskStorageAccountTypesPremiumZRS,
+		},
+		{
+			desc:               "StandardSSD_ZRS disk shall be created without zone",
+			storageAccountType: compute.DiskStorageAccountTypesStandardSSDZRS,
+		},
+		{
+			desc:               "ZRS disk shall ignore the zone if it is a fault domain",
+			storageAccountType: compute.DiskStorageAccountTypesStandardSSDZRS,
+			availabilityZone:   "0",
+		},
+		{
+			desc:               "an error shall be returned if Premium_ZRS disk is requested in a zone",
+			storageAccountType: compute.DiskStorageAccountTypesPremiumZRS,
+			availabilityZone:   "westus-2",
+			expectedErrMsg:     fmt.Errorf("AzureDisk - AvailabilityZone(westus-2) must be empty for zone-redundant disk type Premium_ZRS"),
+		},
+		{
+			desc:               "an error shall be returned if StandardSSD_ZRS disk is requested in a zone",
+			storageAccountType: compute.DiskStorageAccountTypesStandardSSDZRS,
+			availabilityZone:   "westus-3",
+			expectedErrMsg:     fmt.Errorf("AzureDisk - AvailabilityZone(westus-3) must be empty for zone-redundant disk type StandardSSD_ZRS"),
+		},
+	}
+
+	for i, test := range testCases {
+		testCloud := GetTestCloud(ctrl)
+		managedDiskController := testCloud.ManagedDiskController
+		volumeOptions := &ManagedDiskOptions{
+			DiskName:             disk1Name,
+			StorageAccountType:   test.storageAccountType,
+			SizeGB:               1,
+			AvailabilityZone:     test.availabilityZone,
+			SkipGetDiskOperation: true,
+		}
+
+		mockDisksClient := testCloud.DisksClient.(*mockdiskclient.MockInterface)
+		var createdDisk compute.Disk
+		mockDisksClient.EXPECT().CreateOrUpdate(gomock.Any(), gomock.Any(), gomock.Any(), disk1Name, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, subsID, resourceGroupName, diskName string, diskParameter compute.Disk) *retry.Error {
+				createdDisk = diskParameter
+				return nil
+			}).MaxTimes(1)
+
+		_, err := managedDiskController.CreateManagedDisk(ctx, volumeOptions)
+		if test.expectedErrMsg != nil {
+			assert.EqualError(t, err, test.expectedErrMsg.Error(), "TestCase[%d]: %s", i, test.desc)
+			continue
+		}
+		assert.NoError(t, err, "TestCase[%d]: %s", i, test.desc)
+		assert.Equal(t, test.expectedZones, createdDisk.Zones, "TestCase[%d]: %s", i, test.desc)
+		assert.Equal(t, test.storageAccountType, createdDisk.Sku.Name, "TestCase[%d]: %s", i, test.desc)
+	}
+}
+
+func TestCreateManagedDiskEncryption(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := getContextWithCancel()
+	defer cancel()
+
+	optionDESID := "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/diskEncryptionSets/option-des"
+	defaultDESID := "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/diskEncryptionSets/default-des"
+	snapshotDESID := "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/diskEncryptionSets/snapshot-des"
+	snapshotID := "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/snapshots/snapshot1"
+	keyVaultErr := &retry.Error{
+		HTTPStatusCode: http.StatusBadRequest,
+		RawError:       fmt.Errorf("Code=\"KeyVaultAccessForbidden\" Message=\"Unable to access key vault resource\""),
+	}
+
+	testCases := []struct {
+		desc                 string
+		diskEncryptionSetID  string
+		diskEncryptionType   string
+		defaultDESID         string
+		sourceSnapshot       string
+		snapshotEncryption   *compute.Encryption
+		getSnapshotErr       *retry.Error
+		createErr            *retry.Error
+		expectedEncryption   *compute.Encryption
+		expectedErrMsg       error
+		expectedErrSubstring string
+	}{
+		{
+			desc: "disk shall not be encrypted with customer-managed key if no DiskEncryptionSetID is set",
+		},
+		{
+			desc:                "DiskEncryptionSetID in the options shall take precedence over the default one",
+			diskEncryptionSetID: optionDESID,
+			defaultDESID:        defaultDESID,
+			expectedEncryption:  &compute.Encryption{DiskEncryptionSetID: &optionDESID, Type: compute.EncryptionTypeEncryptionAtRestWithCustomerKey},
+		},
+		{
+			desc:               "default DiskEncryptionSetID shall be used if it is not set in the options",
+			diskEncryptionType: string(compute.EncryptionTypeEncryptionAtRestWithPlatformAndCustomerKeys),
+			defaultDESID:       defaultDESID,
+			expectedEncryption: &compute.Encryption{DiskEncryptionSetID: &defaultDESID, Type: compute.EncryptionTypeEncryptionAtRestWithPlatformAndCustomerKeys},
+		},
+		{
+			desc:           "an error shall be returned if the default DiskEncryptionSetID is invalid",
+			defaultDESID:   "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/disks/disk1",
+			expectedErrMsg: fmt.Errorf("AzureDisk - format of DiskEncryptionSetID(/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/disks/disk1) is incorrect, correct format: %s", consts.DiskEncryptionSetIDFormat),
+		},
+		{
+			desc:               "disk created from a snapshot shall inherit the DiskEncryptionSetID of the snapshot",
+			defaultDESID:       defaultDESID,
+			sourceSnapshot:     snapshotID,
+			snapshotEncryption: &compute.Encryption{DiskEncryptionSetID: &snapshotDESID, Type: compute.EncryptionTypeEncryptionAtRestWithPlatformAndCustomerKeys},
+			expectedEncryption: &compute.Encryption{DiskEncryptionSetID: &snapshotDESID, Type: compute.EncryptionTypeEncryptionAtRestWithPlatformAndCustomerKeys},
+		},
+		{
+			desc:                "DiskEncryptionSetID in the options shall override the one of the source snapshot",
+			diskEncryptionSetID: optionDESID,
+			sourceSnapshot:      snapshotID,
+			expectedEncryption:  &compute.Encryption{DiskEncryptionSetID: &optionDESID, Type: compute.EncryptionTypeEncryptionAtRestWithCustomerKey},
+		},
+		{
+			desc:               "default DiskEncryptionSetID shall be used if the source snapshot is not encrypted with customer-managed key",
+			defaultDESID:       defaultDESID,
+			sourceSnapshot:     snapshotID,
+			snapshotEncryption: &compute.Encryption{Type: compute.EncryptionTypeEncryptionAtRestWithPlatformKey},
+			expectedEncryption: &compute.Encryption{DiskEncryptionSetID: &defaultDESID, Type: compute.EncryptionTypeEncryptionAtRestWithCustomerKey},
+		},
+		{
+			desc:           "an error shall be returned if it fails to get the source snapshot",
+			sourceSnapshot: snapshotID,
+			getSnapshotErr: &retry.Error{HTTPStatusCode: http.StatusNotFound, RawError: fmt.Errorf("not found")},
+			expectedErrMsg: fmt.Errorf("AzureDisk - source snapshot(%s) is not found", snapshotID),
+		},
+		{
+			desc:                 "key vault access error shall be returned with guidance",
+			diskEncryptionSetID:  optionDESID,
+			createErr:            keyVaultErr,
+			expectedErrSubstring: "make sure the identity of the disk encryption set has get, wrapKey and unwrapKey permissions on the key vault",
+		},
+		{
+			desc:           "key vault access error shall be returned as is if the disk is not encrypted with customer-managed key",
+			createErr:      keyVaultErr,
+			expectedErrMsg: keyVaultErr.Error(),
+		},
+	}
+
+	for i, test := range testCases {
+		testCloud := GetTestCloud(ctrl)
+		testCloud.DefaultDiskEncryptionSetID = test.defaultDESID
+		managedDiskController := testCloud.ManagedDiskController
+		volumeOptions := &ManagedDiskOptions{
+			DiskName:             disk1Name,
+			StorageAccountType:   compute.DiskStorageAccountTypesPremiumLRS,
+			SizeGB:               1,
+			DiskEncryptionSetID:  test.diskEncryptionSetID,
+			DiskEncryptionType:   test.diskEncryptionType,
+			SkipGetDiskOperation: true,
+		}
+		if test.sourceSnapshot != "" {
+			volumeOptions.SourceResourceID = test.sourceSnapshot
+			volumeOptions.SourceType = sourceSnapshot
+		}
+
+		mockSnapshotsClient := testCloud.SnapshotsClient.(*mocksnapshotclient.MockInterface)
+		snapshot := compute.Snapshot{SnapshotProperties: &compute.SnapshotProperties{Encryption: test.snapshotEncryption}}
+		mockSnapshotsClient.EXPECT().Get(gomock.Any(), "subscription", "rg", "snapshot1").Return(snapshot, test.getSnapshotErr).MaxTimes(1)
+
+		mockDisksClient := testCloud.DisksClient.(*mockdiskclient.MockInterface)
+		var createdDisk compute.Disk
+		mockDisksClient.EXPECT().CreateOrUpdate(gomock.Any(), gomock.Any(), gomock.Any(), disk1Name, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, subsID, resourceGroupName, diskName string, diskParameter compute.Disk) *retry.Error {
+				createdDisk = diskParameter
+				return test.createErr
+			}).MaxTimes(1)
+
+		_, err := managedDiskController.CreateManagedDisk(ctx, volumeOptions)
+		if test.expectedErrMsg != nil {
+			assert.EqualError(t, err, test.expectedErrMsg.Error(), "TestCase[%d]: %s", i, test.desc)
+			continue
+		}
+		if test.expectedErrSubstring != "" {
+			assert.Error(t, err, "TestCase[%d]: %s", i, test.desc)
+			assert.Contains(t, err.Error(), test.expectedErrSubstring, "TestCase[%d]: %s", i, test.desc)
+			continue
+		}
+		assert.NoError(t, err, "TestCase[%d]: %s", i, test.desc)
+		assert.Equal(t, test.expectedEncryption, createdDisk.Encryption, "TestCase[%d]: %s", i, test.desc)
+	}
+}
+
+func TestCreateManagedDiskFromSource(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := getContextWithCancel()
+	defer cancel()
+
+	snapshotID := "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/snapshots/snapshot1"
+	crossSubSnapshotID := "/subscriptions/subscription2/resourceGroups/rg2/providers/Microsoft.Compute/snapshots/snapshot1"
+	diskID := "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/disks/disk2"
+	testCases := []struct {
+		desc                        string
+		sourceType                  string
+		sourceResourceID            string
+		sizeGB                      int
+		sourceLocation              string
+		sourceSizeGB                int32
+		getSourceErr                *retry.Error
+		expectedSizeGB              int32
+		expectedHyperVGeneration    compute.HyperVGeneration
+		expectedSupportsHibernation *bool
+		expectedErrMsg              string
+	}{
+		{
+			desc:                        "disk created from a snapshot shall inherit the properties of the snapshot",
+			sourceType:                  sourceSnapshot,
+			sourceResourceID:            snapshotID,
+			sizeGB:                      20,
+			sourceLocation:              "westus",
+			sourceSizeGB:                10,
+			expectedSizeGB:              20,
+			expectedHyperVGeneration:    compute.HyperVGenerationV2,
+			expectedSupportsHibernation: to.BoolPtr(true),
+		},
+		{
+			desc:                        "requested size shall be grown to the size of the source snapshot",
+			sourceType:                  sourceSnapshot,
+			sourceResourceID:            snapshotID,
+			sizeGB:                      1,
+			sourceLocation:              "West US",
+			sourceSizeGB:                10,
+			expectedSizeGB:              10,
+			expectedHyperVGeneration:    compute.HyperVGenerationV2,
+			expectedSupportsHibernation: to.BoolPtr(true),
+		},
+		{
+			desc:                        "disk could be created from a snapshot in another subscription",
+			sourceType:                  sourceSnapshot,
+			sourceResourceID:            crossSubSnapshotID,
+			sizeGB:                      10,
+			sourceLocation:              "westus",
+			sourceSizeGB:                10,
+			expectedSizeGB:              10,
+			expectedHyperVGeneration:    compute.HyperVGenerationV2,
+			expectedSupportsHibernation: to.BoolPtr(true),
+		},
+		{
+			desc:                        "requested size shall be grown to the size of the source disk",
+			sourceType:                  sourceVolume,
+			sourceResourceID:            diskID,
+			sizeGB:                      1,
+			sourceLocation:              "westus",
+			sourceSizeGB:                32,
+			expectedSizeGB:              32,
+			expectedHyperVGeneration:    compute.HyperVGenerationV2,
+			expectedSupportsHibernation: to.BoolPtr(true),
+		},
+		{
+			desc:             "an error shall be returned if the source snapshot is in another region",
+			sourceType:       sourceSnapshot,
+			sourceResourceID: snapshotID,
+			sizeGB:           10,
+			sourceLocation:   "eastus",
+			expectedErrMsg:   fmt.Sprintf("AzureDisk - source snapshot(%s) is in region eastus, which is different from the region westus of the disk to create", snapshotID),
+		},
+		{
+			desc:             "an error shall be returned if the source disk is not found",
+			sourceType:       sourceVolume,
+			sourceResourceID: diskID,
+			sizeGB:           10,
+			getSourceErr:     &retry.Error{HTTPStatusCode: http.StatusNotFound, RawError: fmt.Errorf("not found")},
+			expectedErrMsg:   fmt.Sprintf("AzureDisk - source volume(%s) is not found", diskID),
+		},
+		{
+			desc:             "an error shall be returned if it fails to get the source snapshot",
+			sourceType:       sourceSnapshot,
+			sourceResourceID: snapshotID,
+			sizeGB:           10,
+			getSourceErr:     &retry.Error{HTTPStatusCode: http.StatusInternalServerError, RawError: fmt.Errorf("internal error")},
+			expectedErrMsg:   fmt.Sprintf("AzureDisk - failed to get source snapshot(%s): Retriable: false, RetryAfter: 0s, HTTPStatusCode: 500, RawError: internal error", snapshotID),
+		},
+	}
+
+	for i, test := range testCases {
+		testCloud := GetTestCloud(ctrl)
+		managedDiskController := testCloud.ManagedDiskController
+		volumeOptions := &ManagedDiskOptions{
+			DiskName:             disk1Name,
+			StorageAccountType:   compute.DiskStorageAccountTypesStandardLRS,
+			SizeGB:               test.sizeGB,
+			SourceResourceID:     test.sourceResourceID,
+			SourceType:           test.sourceType,
+			SkipGetDiskOperation: true,
+		}
+
+		mockSnapshotsClient := testCloud.SnapshotsClient.(*mocksnapshotclient.MockInterface)
+		mockDisksClient := testCloud.DisksClient.(*mockdiskclient.MockInterface)
+		if test.sourceType == sourceSnapshot {
+			resourceGroup, subsID, _ := getInfoFromDiskURI(test.sourceResourceID)
+			snapshot := compute.Snapshot{
+				Location: &test.sourceLocation,
+				SnapshotProperties: &compute.SnapshotProperties{
+					DiskSizeGB:          &test.sourceSizeGB,
+					HyperVGeneration:    compute.HyperVGenerationV2,
+					SupportsHibernation: to.BoolPtr(true),
+				},
+			}
+			mockSnapshotsClient.EXPECT().Get(gomock.Any(), subsID, resourceGroup, "snapshot1").Return(snapshot, test.getSourceErr).Times(1)
+		} else {
+			disk := compute.Disk{
+				Location: &test.sourceLocation,
+				DiskProperties: &compute.DiskProperties{
+					DiskSizeGB:          &test.sourceSizeGB,
+					HyperVGeneration:    compute.HyperVGenerationV2,
+					SupportsHibernation: to.BoolPtr(true),
+				},
+			}
+			mockDisksClient.EXPECT().Get(gomock.Any(), "subscription", "rg", "disk2").Return(disk, test.getSourceErr).Times(1)
+		}
+
+		var createdDisk compute.Disk
+		mockDisksClient.EXPECT().CreateOrUpdate(gomock.Any(), gomock.Any(), gomock.Any(), disk1Name, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, subsID, resourceGroupName, diskName string, diskParameter compute.Disk) *retry.Error {
+				createdDisk = diskParameter
+				return nil
+			}).MaxTimes(1)
+
+		_, err := managedDiskController.CreateManagedDisk(ctx, volumeOptions)
+		if test.expectedErrMsg != "" {
+			assert.EqualError(t, err, test.expectedErrMsg, "TestCase[%d]: %s", i, test.desc)
+			continue
+		}
+		assert.NoError(t, err, "TestCase[%d]: %s", i, test.desc)
+		assert.Equal(t, test.expectedSizeGB, *createdDisk.DiskSizeGB, "TestCase[%d]: %s", i, test.desc)
+		assert.Equal(t, test.expectedHyperVGeneration, createdDisk.HyperVGeneration, "TestCase[%d]: %s", i, test.desc)
+		assert.Equal(t, test.expectedSupportsHibernation, createdDisk.SupportsHibernation, "TestCase[%d]: %s", i, test.desc)
+		assert.Equal(t, test.sourceResourceID, *createdDisk.CreationData.SourceResourceID, "TestCase[%d]: %s", i, test.desc)
+	}
+}
+
+func TestCreateManagedDiskPerformanceTier(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := getContextWithCancel()
+	defer cancel()
+
+	testCases := []struct {
+		desc               string
+		storageAccountType compute.DiskStorageAccountTypes
+		sizeGB             int
+		performanceTier    string
+		burstingEnabled    *bool
+		expectedTier       *string
+		expectedBursting   *bool
+		expectedErrMsg     error
+	}{
+		{
+			desc:               "performance tier higher than the baseline tier shall be set",
+			storageAccountType: compute.DiskStorageAccountTypesPremiumLRS,
+			sizeGB:             128,
+			performanceTier:    "P30",
+			expectedTier:       to.StringPtr("P30"),
+		},
+		{
+			desc:               "on-demand bursting shall be enabled on Premium SSD disk larger than 512 GiB",
+			storageAccountType: compute.DiskStorageAccountTypesPremiumZRS,
+			sizeGB:             1024,
+			burstingEnabled:    to.BoolPtr(true),
+			expectedBursting:   to.BoolPtr(true),
+		},
+		{
+			desc:               "bursting disabled shall be passed through on any disk type",
+			storageAccountType: compute.DiskStorageAccountTypesStandardLRS,
+			sizeGB:             1,
+			burstingEnabled:    to.BoolPtr(false),
+			expectedBursting:   to.BoolPtr(false),
+		},
+		{
+			desc:               "an error shall be returned if the performance tier is invalid",
+			storageAccountType: compute.DiskStorageAccountTypesPremiumLRS,
+			sizeGB:             128,
+			performanceTier:    "P5",
+			expectedErrMsg:     fmt.Errorf("AzureDisk - PerformanceTier(P5) is invalid, valid tiers: P1, P2, P3, P4, P6, P10, P15, P20, P30, P40, P50, P60, P70, P80"),
+		},
+		{
+			desc:               "an error shall be returned if the performance tier is lower than the baseline tier",
+			storageAccountType: compute.DiskStorageAccountTypesPremiumLRS,
+			sizeGB:             1024,
+			performanceTier:    "P20",
+			expectedErrMsg:     fmt.Errorf("AzureDisk - PerformanceTier(P20) must not be lower than the baseline tier P30 of disk of 1024 GiB"),
+		},
+		{
+			desc:               "an error shall be returned if the performance tier is set on non Premium SSD disk",
+			storageAccountType: compute.DiskStorageAccountTypesStandardSSDLRS,
+			sizeGB:             128,
+			performanceTier:    "P30",
+			expectedErrMsg:     fmt.Errorf("AzureDisk - PerformanceTier parameter is only applicable in Premium_LRS and Premium_ZRS disk types, current disk type: StandardSSD_LRS"),
+		},
+		{
+			desc:               "an error shall be returned if on-demand bursting is enabled on disk of 512 GiB",
+			storageAccountType: compute.DiskStorageAccountTypesPremiumLRS,
+			sizeGB:             512,
+			burstingEnabled:    to.BoolPtr(true),
+			expectedErrMsg:     fmt.Errorf("AzureDisk - on-demand bursting is only applicable in disks larger than 512 GiB, current disk size: 512 GiB"),
+		},
+		{
+			desc:               "an error shall be returned if on-demand bursting is enabled on non Premium SSD disk",
+			storageAccountType: compute.DiskStorageAccountTypesStandardSSDLRS,
+			sizeGB:             1024,
+			burstingEnabled:    to.BoolPtr(true),
+			expectedErrMsg:     fmt.Errorf("AzureDisk - on-demand bursting is only applicable in Premium_LRS and Premium_ZRS disk types, current disk type: StandardSSD_LRS"),
+		},
+	}
+
+	for i, test := range testCases {
+		testCloud := GetTestCloud(ctrl)
+		managedDiskController := testCloud.ManagedDiskController
+		volumeOptions := &ManagedDiskOptions{
+			DiskName:             disk1Name,
+			StorageAccountType:   test.storageAccountType,
+			SizeGB:               test.sizeGB,
+			PerformanceTier:      test.performanceTier,
+			BurstingEnabled:      test.burstingEnabled,
+			SkipGetDiskOperation: true,
+		}
+
+		mockDisksClient := testCloud.DisksClient.(*mockdiskclient.MockInterface)
+		var createdDisk compute.Disk
+		mockDisksClient.EXPECT().CreateOrUpdate(gomock.Any(), gomock.Any(), gomock.Any(), disk1Name, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, subsID, resourceGroupName, diskName string, diskParameter compute.Disk) *retry.Error {
+				createdDisk = diskParameter
+				return nil
+			}).MaxTimes(1)
+
+		_, err := managedDiskController.CreateManagedDisk(ctx, volumeOptions)
+		if test.expectedErrMsg != nil {
+			assert.EqualError(t, err, test.expectedErrMsg.Error(), "TestCase[%d]: %s", i, test.desc)
+			continue
+		}
+		assert.NoError(t, err, "TestCase[%d]: %s", i, test.desc)
+		assert.Equal(t, test.expectedTier, createdDisk.Tier, "TestCase[%d]: %s", i, test.desc)
+		assert.Equal(t, test.expectedBursting, createdDisk.BurstingEnabled, "TestCase[%d]: %s", i, test.desc)
+	}
+}
+
+func TestCreateManagedDiskNetworkAccessPolicy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := getContextWithCancel()
+	defer cancel()
+
+	diskAccessID := "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/diskAccesses/diskaccess1"
+	defaultDiskAccessID := "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/diskAccesses/default"
+	testCases := []struct {
+		desc                 string
+		networkAccessPolicy  compute.NetworkAccessPolicy
+		diskAccessID         *string
+		defaultPolicy        string
+		defaultDiskAccessID  string
+		enforce              bool
+		existedPolicy        compute.NetworkAccessPolicy
+		expectedPolicy       compute.NetworkAccessPolicy
+		expectedDiskAccessID *string
+		expectedUpdate       *compute.DiskUpdate
+		expectedErrMsg       error
+	}{
+		{
+			desc:                "AllowAll shall be set on the disk",
+			networkAccessPolicy: compute.NetworkAccessPolicyAllowAll,
+			existedPolicy:       compute.NetworkAccessPolicyAllowAll,
+			expectedPolicy:      compute.NetworkAccessPolicyAllowAll,
+		},
+		{
+			desc:                 "AllowPrivate shall be set on the disk with DiskAccessID",
+			networkAccessPolicy:  compute.NetworkAccessPolicyAllowPrivate,
+			diskAccessID:         &diskAccessID,
+			existedPolicy:        compute.NetworkAccessPolicyAllowPrivate,
+			expectedPolicy:       compute.NetworkAccessPolicyAllowPrivate,
+			expectedDiskAccessID: &diskAccessID,
+		},
+		{
+			desc:                "DenyAll shall be set on the disk",
+			networkAccessPolicy: compute.NetworkAccessPolicyDenyAll,
+			existedPolicy:       compute.NetworkAccessPolicyDenyAll,
+			expectedPolicy:      compute.NetworkAccessPolicyDenyAll,
+		},
+		{
+			desc:                "NetworkAccessPolicy in the options shall take precedence over the default one",
+			networkAccessPolicy: compute.NetworkAccessPolicyDenyAll,
+			defaultPolicy:       string(compute.NetworkAccessPolicyAllowPrivate),
+			defaultDiskAccessID: defaultDiskAccessID,
+			existedPolicy:       compute.NetworkAccessPolicyDenyAll,
+			expectedPolicy:      compute.NetworkAccessPolicyDenyAll,
+		},
+		{
+			desc:                 "default NetworkAccessPolicy and DiskAccessID shall be used if they are not set in the options",
+			defaultPolicy:        string(compute.NetworkAccessPolicyAllowPrivate),
+			defaultDiskAccessID:  defaultDiskAccessID,
+			existedPolicy:        compute.NetworkAccessPolicyAllowPrivate,
+			expectedPolicy:       compute.NetworkAccessPolicyAllowPrivate,
+			expectedDiskAccessID: &defaultDiskAccessID,
+		},
+		{
+			desc:                "the different NetworkAccessPolicy of the existing disk shall only be logged by default",
+			networkAccessPolicy: compute.NetworkAccessPolicyDenyAll,
+			existedPolicy:       compute.NetworkAccessPolicyAllowAll,
+			expectedPolicy:      compute.NetworkAccessPolicyDenyAll,
+		},
+		{
+			desc:                "the different NetworkAccessPolicy of the existing disk shall be updated if it is enforced",
+			networkAccessPolicy: compute.NetworkAccessPolicyDenyAll,
+			enforce:             true,
+			existedPolicy:       compute.NetworkAccessPolicyAllowAll,
+			expectedPolicy:      compute.NetworkAccessPolicyDenyAll,
+			expectedUpdate: &compute.DiskUpdate{
+				DiskUpdateProperties: &compute.DiskUpdateProperties{
+					NetworkAccessPolicy: compute.NetworkAccessPolicyDenyAll,
+				},
+			},
+		},
+		{
+			desc:                "an error shall be returned if DiskAccessID is not set with AllowPrivate",
+			networkAccessPolicy: compute.NetworkAccessPolicyAllowPrivate,
+			expectedErrMsg:      fmt.Errorf("DiskAccessID should not be empty when NetworkAccessPolicy is AllowPrivate"),
+		},
+		{
+			desc:           "an error shall be returned if the default NetworkAccessPolicy is invalid",
+			defaultPolicy:  "DenyPublic",
+			expectedErrMsg: fmt.Errorf("NetworkAccessPolicy(DenyPublic) is invalid, supported values: AllowAll, AllowPrivate, DenyAll"),
+		},
+	}
+
+	for i, test := range testCases {
+		testCloud := GetTestCloud(ctrl)
+		testCloud.DefaultDiskNetworkAccessPolicy = test.defaultPolicy
+		testCloud.DefaultDiskAccessID = test.defaultDiskAccessID
+		testCloud.EnforceDiskNetworkAccessPolicy = test.enforce
+		managedDiskController := testCloud.ManagedDiskController
+		volumeOptions := &ManagedDiskOptions{
+			DiskName:            disk1Name,
+			StorageAccountType:  compute.DiskStorageAccountTypesPremiumLRS,
+			SizeGB:              1,
+			NetworkAccessPolicy: test.networkAccessPolicy,
+			DiskAccessID:        test.diskAccessID,
+		}
+
+		mockDisksClient := testCloud.DisksClient.(*mockdiskclient.MockInterface)
+		var createdDisk compute.Disk
+		mockDisksClient.EXPECT().CreateOrUpdate(gomock.Any(), gomock.Any(), gomock.Any(), disk1Name, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, subsID, resourceGroupName, diskName string, diskParameter compute.Disk) *retry.Error {
+				createdDisk = diskParameter
+				return nil
+			}).MaxTimes(1)
+		existedDisk := compute.Disk{
+			ID:   to.StringPtr(disk1ID),
+			Name: to.StringPtr(disk1Name),
+			DiskProperties: &compute.DiskProperties{
+				ProvisioningState:   to.StringPtr("Succeeded"),
+				NetworkAccessPolicy: test.existedPolicy,
+				DiskAccessID:        test.expectedDiskAccessID,
+			},
+		}
+		mockDisksClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any(), disk1Name).Return(existedDisk, nil).AnyTimes()
+		if test.expectedUpdate != nil {
+			mockDisksClient.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any(), disk1Name, *test.expectedUpdate).Return(nil).Times(1)
+		}
+
+		_, err := managedDiskController.CreateManagedDisk(ctx, volumeOptions)
+		if test.expectedErrMsg != nil {
+			assert.EqualError(t, err, test.expectedErrMsg.Error(), "TestCase[%d]: %s", i, test.desc)
+			continue
+		}
+		assert.NoError(t, err, "TestCase[%d]: %s", i, test.desc)
+		assert.Equal(t, test.expectedPolicy, createdDisk.NetworkAccessPolicy, "TestCase[%d]: %s", i, test.desc)
+		assert.Equal(t, test.expectedDiskAccessID, createdDisk.DiskAccessID, "TestCase[%d]: %s", i, test.desc)
+	}
+}
+
+func TestCreateManagedDiskMaxShares(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := getContextWithCancel()
+	defer cancel()
+
+	testCases := []struct {
+		desc               string
+		storageAccountType compute.DiskStorageAccountTypes
+		sizeGB             int
+		maxShares          int32
+		expectedMaxShares  *int32
+		expectedErrMsg     error
+	}{
+		{
+			desc:               "maxShares shall not be set if it is not greater than one",
+			storageAccountType: compute.DiskStorageAccountTypesStandardLRS,
+			sizeGB:             1,
+			maxShares:          1,
+		},
+		{
+			desc:               "maxShares shall be set on UltraSSD_LRS disk",
+			storageAccountType: compute.DiskStorageAccountTypesUltraSSDLRS,
+			sizeGB:             1,
+			maxShares:          15,
+			expectedMaxShares:  to.Int32Ptr(15),
+		},
+		{
+			desc:               "maxShares shall be set on Premium_LRS disk of P15",
+			storageAccountType: compute.DiskStorageAccountTypesPremiumLRS,
+			sizeGB:             256,
+			maxShares:          2,
+			expectedMaxShares:  to.Int32Ptr(2),
+		},
+		{
+			desc:               "maxShares shall be set on StandardSSD_ZRS disk of E50",
+			storageAccountType: compute.DiskStorageAccountTypesStandardSSDZRS,
+			sizeGB:             4096,
+			maxShares:          5,
+			expectedMaxShares:  to.Int32Ptr(5),
+		},
+		{
+			desc:               "maxShares shall be set on Premium_LRS disk of P60",
+			storageAccountType: compute.DiskStorageAccountTypesPremiumLRS,
+			sizeGB:             8192,
+			maxShares:          10,
+			expectedMaxShares:  to.Int32Ptr(10),
+		},
+		{
+			desc:               "an error shall be returned if maxShares exceeds the limit of Premium_LRS disk of P20",
+			storageAccountType: compute.DiskStorageAccountTypesPremiumLRS,
+			sizeGB:             512,
+			maxShares:          3,
+			expectedErrMsg:     fmt.Errorf("AzureDisk - MaxShares(3) exceeds the limit 2 of Premium_LRS disk of 512 GiB"),
+		},
+		{
+			desc:               "an error shall be returned if maxShares exceeds the limit of UltraSSD_LRS disk",
+			storageAccountType: compute.DiskStorageAccountTypesUltraSSDLRS,
+			sizeGB:             1,
+			maxShares:          16,
+			expectedErrMsg:     fmt.Errorf("AzureDisk - MaxShares(16) exceeds the limit 15 of UltraSSD_LRS disk of 1 GiB"),
+		},
+		{
+			desc:               "an error shall be returned if Premium_LRS disk smaller than P15 is shared",
+			storageAccountType: compute.DiskStorageAccountTypesPremiumLRS,
+			sizeGB:             128,
+			maxShares:          2,
+			expectedErrMsg:     fmt.Errorf("AzureDisk - MaxShares(2) is not supported by Premium_LRS disk of 128 GiB"),
+		},
+		{
+			desc:               "an error shall be returned if Standard_LRS disk is shared",
+			storageAccountType: compute.DiskStorageAccountTypesStandardLRS,
+			sizeGB:             1024,
+			maxShares:          2,
+			expectedErrMsg:     fmt.Errorf("AzureDisk - MaxShares(2) is not supported by Standard_LRS disk of 1024 GiB"),
+		},
+	}
+
+	for i, test := range testCases {
+		testCloud := GetTestCloud(ctrl)
+		managedDiskController := testCloud.ManagedDiskController
+		volumeOptions := &ManagedDiskOptions{
+			DiskName:             disk1Name,
+			StorageAccountType:   test.storageAccountType,
+			SizeGB:               test.sizeGB,
+			MaxShares:            test.maxShares,
+			SkipGetDiskOperation: true,
+		}
+
+		mockDisksClient := testCloud.DisksClient.(*mockdiskclient.MockInterface)
+		var createdDisk compute.Disk
+		mockDisksClient.EXPECT().CreateOrUpdate(gomock.Any(), gomock.Any(), gomock.Any(), disk1Name, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, subsID, resourceGroupName, diskName string, diskParameter compute.Disk) *retry.Error {
+				createdDisk = diskParameter
+				return nil
+			}).MaxTimes(1)
+
+		_, err := managedDiskController.CreateManagedDisk(ctx, volumeOptions)
+		if test.expectedErrMsg != nil {
+			assert.EqualError(t, err, test.expectedErrMsg.Error(), "TestCase[%d]: %s", i, test.desc)
+			continue
+		}
+		assert.NoError(t, err, "TestCase[%d]: %s", i, test.desc)
+		assert.Equal(t, test.expectedMaxShares, createdDisk.MaxShares, "TestCase[%d]: %s", i, test.desc)
+	}
+}
+
 func TestDeleteManagedDisk(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -511,6 +1303,60 @@ func TestResizeDisk(t *testing.T) {
 			expectedErr:      true,
 			expectedErrMsg:   fmt.Errorf("azureDisk - disk resize is only supported on Unattached disk, current disk state: Attached, already attached to "),
 		},
+		{
+			desc:             "new quantity and no error shall be returned if the attached disk supports online resize",
+			diskName:         diskName,
+			oldSize:          *resource.NewQuantity(2*(1024*1024*1024), resource.BinarySI),
+			newSize:          *resource.NewQuantity(3*(1024*1024*1024), resource.BinarySI),
+			existedDisk:      compute.Disk{Name: to.StringPtr(disk1Name), Sku: &compute.DiskSku{Name: compute.DiskStorageAccountTypesPremiumLRS}, ManagedBy: to.StringPtr("vm1"), DiskProperties: &compute.DiskProperties{DiskSizeGB: &diskSizeGB, DiskState: compute.DiskStateAttached}},
+			expectedQuantity: *resource.NewQuantity(3*(1024*1024*1024), resource.BinarySI),
+		},
+		{
+			desc:             "requested size shall be rounded up to GiB when the attached disk is resized online",
+			diskName:         diskName,
+			oldSize:          *resource.NewQuantity(2*(1024*1024*1024), resource.BinarySI),
+			newSize:          *resource.NewQuantity(2*(1024*1024*1024)+1, resource.BinarySI),
+			existedDisk:      compute.Disk{Name: to.StringPtr(disk1Name), Sku: &compute.DiskSku{Name: compute.DiskStorageAccountTypesStandardSSDZRS}, ManagedBy: to.StringPtr("vm1"), DiskProperties: &compute.DiskProperties{DiskSizeGB: &diskSizeGB, DiskState: compute.DiskStateAttached}},
+			expectedQuantity: *resource.NewQuantity(3*(1024*1024*1024), resource.BinarySI),
+		},
+		{
+			desc:             "an error shall be returned if the disk type of the attached disk does not support online resize",
+			diskName:         diskName,
+			oldSize:          *resource.NewQuantity(2*(1024*1024*1024), resource.BinarySI),
+			newSize:          *resource.NewQuantity(3*(1024*1024*1024), resource.BinarySI),
+			existedDisk:      compute.Disk{Name: to.StringPtr(disk1Name), Sku: &compute.DiskSku{Name: compute.DiskStorageAccountTypesUltraSSDLRS}, ManagedBy: to.StringPtr("vm1"), DiskProperties: &compute.DiskProperties{DiskSizeGB: &diskSizeGB, DiskState: compute.DiskStateAttached}},
+			expectedQuantity: *resource.NewQuantity(2*(1024*1024*1024), resource.BinarySI),
+			expectedErr:      true,
+			expectedErrMsg:   fmt.Errorf("azureDisk - disk resize is only supported on Unattached disk, current disk state: Attached, already attached to vm1"),
+		},
+		{
+			desc:             "an error shall be returned if the attached disk is a shared disk",
+			diskName:         diskName,
+			oldSize:          *resource.NewQuantity(2*(1024*1024*1024), resource.BinarySI),
+			newSize:          *resource.NewQuantity(3*(1024*1024*1024), resource.BinarySI),
+			existedDisk:      compute.Disk{Name: to.StringPtr(disk1Name), Sku: &compute.DiskSku{Name: compute.DiskStorageAccountTypesPremiumLRS}, ManagedBy: to.StringPtr("vm1"), DiskProperties: &compute.DiskProperties{DiskSizeGB: &diskSizeGB, MaxShares: to.Int32Ptr(2), DiskState: compute.DiskStateAttached}},
+			expectedQuantity: *resource.NewQuantity(2*(1024*1024*1024), resource.BinarySI),
+			expectedErr:      true,
+			expectedErrMsg:   fmt.Errorf("azureDisk - disk resize is only supported on Unattached disk, current disk state: Attached, already attached to vm1"),
+		},
+		{
+			desc:             "an error shall be returned if the attached disk is expanded beyond 4 TiB",
+			diskName:         diskName,
+			oldSize:          *resource.NewQuantity(4096*(1024*1024*1024), resource.BinarySI),
+			newSize:          *resource.NewQuantity(4097*(1024*1024*1024), resource.BinarySI),
+			existedDisk:      compute.Disk{Name: to.StringPtr(disk1Name), Sku: &compute.DiskSku{Name: compute.DiskStorageAccountTypesPremiumLRS}, ManagedBy: to.StringPtr("vm1"), DiskProperties: &compute.DiskProperties{DiskSizeGB: to.Int32Ptr(4096), DiskState: compute.DiskStateAttached}},
+			expectedQuantity: *resource.NewQuantity(4096*(1024*1024*1024), resource.BinarySI),
+			expectedErr:      true,
+			expectedErrMsg:   fmt.Errorf("azureDisk - disk resize is only supported on Unattached disk, current disk state: Attached, already attached to vm1"),
+		},
+		{
+			desc:             "new quantity and no error shall be returned if the attached disk larger than 4 TiB is expanded",
+			diskName:         diskName,
+			oldSize:          *resource.NewQuantity(5000*(1024*1024*1024), resource.BinarySI),
+			newSize:          *resource.NewQuantity(6000*(1024*1024*1024), resource.BinarySI),
+			existedDisk:      compute.Disk{Name: to.StringPtr(disk1Name), Sku: &compute.DiskSku{Name: compute.DiskStorageAccountTypesPremiumLRS}, ManagedBy: to.StringPtr("vm1"), DiskProperties: &compute.DiskProperties{DiskSizeGB: to.Int32Ptr(5000), DiskState: compute.DiskStateAttached}},
+			expectedQuantity: *resource.NewQuantity(6000*(1024*1024*1024), resource.BinarySI),
+		},
 	}
 
 	for i, test := range testCases {
@@ -540,6 +1386,166 @@ func TestResizeDisk(t *testing.T) {
 	}
 }
 
+func TestModifyDiskPerformance(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := getContextWithCancel()
+	defer cancel()
+
+	diskSizeGB := int32(100)
+	testCases := []struct {
+		desc              string
+		diskIOPSReadWrite string
+		diskMBpsReadWrite string
+		existedDisk       compute.Disk
+		expectedUpdate    *compute.DiskUpdate
+		expectedErrMsg    error
+	}{
+		{
+			desc:              "IOPS and throughput shall be patched on PremiumV2LRS disk",
+			diskIOPSReadWrite: "10000",
+			diskMBpsReadWrite: "500",
+			existedDisk:       compute.Disk{Sku: &compute.DiskSku{Name: DiskStorageAccountTypesPremiumV2LRS}, DiskProperties: &compute.DiskProperties{DiskSizeGB: &diskSizeGB}},
+			expectedUpdate: &compute.DiskUpdate{
+				DiskUpdateProperties: &compute.DiskUpdateProperties{
+					DiskIOPSReadWrite: to.Int64Ptr(10000),
+					DiskMBpsReadWrite: to.Int64Ptr(500),
+				},
+			},
+		},
+		{
+			desc:              "only throughput shall be patched and validated against the current IOPS of the disk",
+			diskMBpsReadWrite: "2000",
+			existedDisk:       compute.Disk{Sku: &compute.DiskSku{Name: compute.DiskStorageAccountTypesUltraSSDLRS}, DiskProperties: &compute.DiskProperties{DiskSizeGB: &diskSizeGB, DiskIOPSReadWrite: to.Int64Ptr(20000)}},
+			expectedUpdate: &compute.DiskUpdate{
+				DiskUpdateProperties: &compute.DiskUpdateProperties{
+					DiskMBpsReadWrite: to.Int64Ptr(2000),
+				},
+			},
+		},
+		{
+			desc:              "an error shall be returned if throughput exceeds the limit of the current IOPS of the disk",
+			diskMBpsReadWrite: "1000",
+			existedDisk:       compute.Disk{Sku: &compute.DiskSku{Name: DiskStorageAccountTypesPremiumV2LRS}, DiskProperties: &compute.DiskProperties{DiskSizeGB: &diskSizeGB, DiskIOPSReadWrite: to.Int64Ptr(3000)}},
+			expectedErrMsg:    fmt.Errorf("AzureDisk - DiskMBpsReadWrite(1000) is out of range [125, 750] for PremiumV2_LRS disk with 3000 IOPS"),
+		},
+		{
+			desc:              "an error shall be returned if IOPS exceeds the limit of the disk size",
+			diskIOPSReadWrite: "50001",
+			existedDisk:       compute.Disk{Sku: &compute.DiskSku{Name: DiskStorageAccountTypesPremiumV2LRS}, DiskProperties: &compute.DiskProperties{DiskSizeGB: &diskSizeGB}},
+			expectedErrMsg:    fmt.Errorf("AzureDisk - DiskIOPSReadWrite(50001) is out of range [3000, 50000] for PremiumV2_LRS disk of 100 GiB"),
+		},
+		{
+			desc:              "an error shall be returned if the disk type doesn't support provisioned performance",
+			diskIOPSReadWrite: "5000",
+			existedDisk:       compute.Disk{Sku: &compute.DiskSku{Name: compute.DiskStorageAccountTypesPremiumLRS}, DiskProperties: &compute.DiskProperties{DiskSizeGB: &diskSizeGB}},
+			expectedErrMsg:    fmt.Errorf("AzureDisk - IOPS and throughput could only be modified on UltraSSD_LRS and PremiumV2_LRS disk types, current disk type: Premium_LRS"),
+		},
+		{
+			desc:              "an error shall be returned if IOPS is invalid",
+			diskIOPSReadWrite: "invalid",
+			expectedErrMsg:    fmt.Errorf("AzureDisk - failed to parse DiskIOPSReadWrite: strconv.Atoi: parsing \"invalid\": invalid syntax"),
+		},
+	}
+
+	for i, test := range testCases {
+		testCloud := GetTestCloud(ctrl)
+		managedDiskController := testCloud.ManagedDiskController
+		diskURI := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/disks/%s",
+			testCloud.SubscriptionID, testCloud.ResourceGroup, disk1Name)
+
+		mockDisksClient := testCloud.DisksClient.(*mockdiskclient.MockInterface)
+		mockDisksClient.EXPECT().Get(gomock.Any(), testCloud.SubscriptionID, testCloud.ResourceGroup, disk1Name).Return(test.existedDisk, nil).AnyTimes()
+		if test.expectedUpdate != nil {
+			mockDisksClient.EXPECT().Update(gomock.Any(), testCloud.SubscriptionID, testCloud.ResourceGroup, disk1Name, *test.expectedUpdate).Return(nil).Times(1)
+		}
+
+		err := managedDiskController.ModifyDiskPerformance(ctx, diskURI, test.diskIOPSReadWrite, test.diskMBpsReadWrite)
+		if test.expectedErrMsg != nil {
+			assert.EqualError(t, err, test.expectedErrMsg.Error(), "TestCase[%d]: %s", i, test.desc)
+		} else {
+			assert.NoError(t, err, "TestCase[%d]: %s", i, test.desc)
+		}
+	}
+}
+
+func TestModifyDiskTier(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := getContextWithCancel()
+	defer cancel()
+
+	diskSizeGB := int32(1024)
+	testCases := []struct {
+		desc            string
+		performanceTier string
+		burstingEnabled *bool
+		existedDisk     compute.Disk
+		expectedUpdate  *compute.DiskUpdate
+		expectedErrMsg  error
+	}{
+		{
+			desc:            "performance tier and on-demand bursting shall be patched on Premium SSD disk",
+			performanceTier: "P40",
+			burstingEnabled: to.BoolPtr(true),
+			existedDisk:     compute.Disk{Sku: &compute.DiskSku{Name: compute.DiskStorageAccountTypesPremiumLRS}, DiskProperties: &compute.DiskProperties{DiskSizeGB: &diskSizeGB}},
+			expectedUpdate: &compute.DiskUpdate{
+				DiskUpdateProperties: &compute.DiskUpdateProperties{
+					Tier:            to.StringPtr("P40"),
+					BurstingEnabled: to.BoolPtr(true),
+				},
+			},
+		},
+		{
+			desc:            "only on-demand bursting shall be patched if the performance tier is empty",
+			burstingEnabled: to.BoolPtr(false),
+			existedDisk:     compute.Disk{Sku: &compute.DiskSku{Name: compute.DiskStorageAccountTypesPremiumLRS}, DiskProperties: &compute.DiskProperties{DiskSizeGB: &diskSizeGB}},
+			expectedUpdate: &compute.DiskUpdate{
+				DiskUpdateProperties: &compute.DiskUpdateProperties{
+					BurstingEnabled: to.BoolPtr(false),
+				},
+			},
+		},
+		{
+			desc: "nothing shall be patched if neither performance tier nor on-demand bursting is set",
+		},
+		{
+			desc:            "an error shall be returned if the performance tier is lower than the baseline tier",
+			performanceTier: "P10",
+			existedDisk:     compute.Disk{Sku: &compute.DiskSku{Name: compute.DiskStorageAccountTypesPremiumLRS}, DiskProperties: &compute.DiskProperties{DiskSizeGB: &diskSizeGB}},
+			expectedErrMsg:  fmt.Errorf("AzureDisk - PerformanceTier(P10) must not be lower than the baseline tier P30 of disk of 1024 GiB"),
+		},
+		{
+			desc:            "an error shall be returned if on-demand bursting is enabled on non Premium SSD disk",
+			burstingEnabled: to.BoolPtr(true),
+			existedDisk:     compute.Disk{Sku: &compute.DiskSku{Name: compute.DiskStorageAccountTypesStandardLRS}, DiskProperties: &compute.DiskProperties{DiskSizeGB: &diskSizeGB}},
+			expectedErrMsg:  fmt.Errorf("AzureDisk - on-demand bursting is only applicable in Premium_LRS and Premium_ZRS disk types, current disk type: Standard_LRS"),
+		},
+	}
+
+	for i, test := range testCases {
+		testCloud := GetTestCloud(ctrl)
+		managedDiskController := testCloud.ManagedDiskController
+		diskURI := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/disks/%s",
+			testCloud.SubscriptionID, testCloud.ResourceGroup, disk1Name)
+
+		mockDisksClient := testCloud.DisksClient.(*mockdiskclient.MockInterface)
+		mockDisksClient.EXPECT().Get(gomock.Any(), testCloud.SubscriptionID, testCloud.ResourceGroup, disk1Name).Return(test.existedDisk, nil).AnyTimes()
+		if test.expectedUpdate != nil {
+			mockDisksClient.EXPECT().Update(gomock.Any(), testCloud.SubscriptionID, testCloud.ResourceGroup, disk1Name, *test.expectedUpdate).Return(nil).Times(1)
+		}
+
+		err := managedDiskController.ModifyDiskTier(ctx, diskURI, test.performanceTier, test.burstingEnabled)
+		if test.expectedErrMsg != nil {
+			assert.EqualError(t, err, test.expectedErrMsg.Error(), "TestCase[%d]: %s", i, test.desc)
+		} else {
+			assert.NoError(t, err, "TestCase[%d]: %s", i, test.desc)
+		}
+	}
+}
+
 func TestGetLabelsForVolume(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -580,6 +1586,25 @@ func TestGetLabelsForVolume(t *testing.T) {
 			},
 			expectedErr: false,
 		},
+		{
+			desc:     "zone label shall be omitted for ZRS disk",
+			diskName: diskName,
+			pv: &v1.PersistentVolume{
+				Spec: v1.PersistentVolumeSpec{
+					PersistentVolumeSource: v1.PersistentVolumeSource{
+						AzureDisk: &v1.AzureDiskVolumeSource{
+							DiskName:    diskName,
+							DataDiskURI: diskURI,
+						},
+					},
+				},
+			},
+			existedDisk: compute.Disk{Name: to.StringPtr(diskName), Sku: &compute.DiskSku{Name: compute.DiskStorageAccountTypesPremiumZRS}, DiskProperties: &compute.DiskProperties{DiskSizeGB: &diskSizeGB}, Zones: &[]string{"1", "2", "3"}},
+			expected: map[string]string{
+				consts.LabelFailureDomainBetaRegion: testCloud0.Location,
+			},
+			expectedErr: false,
+		},
 		{
 			desc:     "an error shall be returned if everything is good with invalid zone",
 			diskName: diskName,
@@ -0,0 +1,82 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Azure/go-autorest/autorest/adal"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/armclient"
+)
+
+const (
+	// resourceGroupsAPIVersion is the API version of the resource groups API.
+	resourceGroupsAPIVersion = "2021-04-01"
+)
+
+// validateManagedIdentity checks whether the managed identity is authorized to access the resource group of the cluster
+// by getting it. On VMs with multiple user-assigned identities, the one specified by userAssignedIdentityID may not be
+// the one with the role assignments, and the error returned on 403 reports the identity actually used with the hints.
+func (az *Cloud) validateManagedIdentity(ctx context.Context, servicePrincipalToken *adal.ServicePrincipalToken) error {
+	azClientConfig := az.getAzureClientConfig(servicePrincipalToken)
+	armClient := armclient.New(azClientConfig.Authorizer, *azClientConfig, az.Environment.ResourceManagerEndpoint, resourceGroupsAPIVersion)
+
+	resourceGroupID := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s", az.SubscriptionID, az.ResourceGroup)
+	response, rerr := armClient.GetResource(ctx, resourceGroupID)
+	defer armClient.CloseResponse(ctx, response)
+	if rerr == nil {
+		return nil
+	}
+	if rerr.HTTPStatusCode != http.StatusForbidden {
+		return fmt.Errorf("failed to validate the managed identity by getting resource group %s: %w", az.ResourceGroup, rerr.Error())
+	}
+
+	return fmt.Errorf("the managed identity (%s) is not authorized to get resource group %s. "+
+		"Please make sure that userAssignedIdentityID %q selects the identity assigned to the VM with the required roles, "+
+		"e.g. the Contributor role of resource group %s, or a custom role with the permissions of the virtual machines, "+
+		"the load balancers, the public IPs, the network security groups and the route tables: %w",
+		describeTokenIdentity(servicePrincipalToken.OAuthToken()), az.ResourceGroup, az.UserAssignedIdentityID, az.ResourceGroup, rerr.Error())
+}
+
+// describeTokenIdentity returns the identity which the access token is issued to by its claims,
+// i.e. the client ID, the object ID and the resource ID of the managed identity.
+func describeTokenIdentity(accessToken string) string {
+	parts := strings.Split(accessToken, ".")
+	if len(parts) != 3 {
+		return "unknown identity"
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "unknown identity"
+	}
+
+	claims := struct {
+		AppID      string `json:"appid"`
+		ObjectID   string `json:"oid"`
+		ResourceID string `json:"xms_mirid"`
+	}{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return "unknown identity"
+	}
+	return fmt.Sprintf("client ID %s, object ID %s, resource ID %s", claims.AppID, claims.ObjectID, claims.ResourceID)
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/stretchr/testify/assert"
+)
+
+func newManagedIdentityToken(t *testing.T) *adal.ServicePrincipalToken {
+	claims := `{"appid":"client","oid":"object","xms_mirid":"/subscriptions/subscription/resourcegroups/rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/ua"}`
+	accessToken := fmt.Sprintf("header.%s.signature", base64.RawURLEncoding.EncodeToString([]byte(claims)))
+	expiresOn := time.Now().Add(time.Hour).Unix()
+	oauthConfig, err := adal.NewOAuthConfig("https://login.microsoftonline.com/", "tenant")
+	assert.NoError(t, err)
+	spt, err := adal.NewServicePrincipalTokenFromManualToken(*oauthConfig, "client", "https://management.azure.com/", adal.Token{
+		AccessToken: accessToken,
+		ExpiresIn:   "3600",
+		ExpiresOn:   json.Number(fmt.Sprint(expiresOn)),
+		NotBefore:   json.Number(fmt.Sprint(expiresOn - 3600)),
+		Type:        "Bearer",
+	})
+	assert.NoError(t, err)
+	return spt
+}
+
+func TestValidateManagedIdentity(t *testing.T) {
+	for _, tc := range []struct {
+		description  string
+		statusCode   int
+		expectedErrs []string
+	}{
+		{
+			description: "authorized identity",
+			statusCode:  http.StatusOK,
+		},
+		{
+			description:  "unauthorized identity",
+			statusCode:   http.StatusForbidden,
+			expectedErrs: []string{"client ID client, object ID object", "userAssignedIdentityID \"ua-client\"", "Contributor role of resource group rg"},
+		},
+		{
+			description:  "unknown error",
+			statusCode:   http.StatusNotFound,
+			expectedErrs: []string{"failed to validate the managed identity by getting resource group rg"},
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			arm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/subscriptions/subscription/resourceGroups/rg", r.URL.Path)
+				assert.Equal(t, resourceGroupsAPIVersion, r.URL.Query().Get("api-version"))
+				w.WriteHeader(tc.statusCode)
+				_, _ = w.Write([]byte(`{}`))
+			}))
+			defer arm.Close()
+
+			az := &Cloud{Environment: azure.Environment{ResourceManagerEndpoint: arm.URL + "/"}}
+			az.SubscriptionID = "subscription"
+			az.ResourceGroup = "rg"
+			az.UseManagedIdentityExtension = true
+			az.UserAssignedIdentityID = "ua-client"
+
+			err := az.validateManagedIdentity(context.Background(), newManagedIdentityToken(t))
+			if len(tc.expectedErrs) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Error(t, err)
+			for _, expectedErr := range tc.expectedErrs {
+				assert.Contains(t, err.Error(), expectedErr)
+			}
+		})
+	}
+}
+
+func TestDescribeTokenIdentity(t *testing.T) {
+	assert.Equal(t, "unknown identity", describeTokenIdentity("token"))
+	assert.Equal(t, "unknown identity", describeTokenIdentity("header.!.signature"))
+	assert.Equal(t, "client ID client, object ID object, resource ID /subscriptions/subscription/resourcegroups/rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/ua",
+		describeTokenIdentity(newManagedIdentityToken(t).OAuthToken()))
+}
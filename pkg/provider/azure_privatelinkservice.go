@@ -17,6 +17,7 @@ limitations under the License.
 package provider
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"strings"
@@ -35,6 +36,7 @@ import (
 // reconcilePrivateLinkService() function makes sure a PLS is created or deleted on
 // a Load Balancer frontend IP Configuration according to service spec and cluster operation
 func (az *Cloud) reconcilePrivateLinkService(
+	ctx context.Context,
 	clusterName string,
 	service *v1.Service,
 	fipConfig *network.FrontendIPConfiguration,
@@ -113,13 +115,13 @@ func (az *Cloud) reconcilePrivateLinkService(
 
 		if dirtyPLS {
 			klog.V(2).Infof("reconcilePrivateLinkService for service(%s): pls(%s) - updating", serviceName, plsName)
-			err := az.disablePLSNetworkPolicy(service)
+			err := az.disablePLSNetworkPolicy(ctx, service)
 			if err != nil {
 				klog.Errorf("reconcilePrivateLinkService for service(%s) disable PLS network policy failed for pls(%s): %v", serviceName, plsName, err.Error())
 				return err
 			}
 			existingPLS.Etag = to.StringPtr("")
-			err = az.CreateOrUpdatePLS(service, existingPLS)
+			err = az.CreateOrUpdatePLS(ctx, service, existingPLS)
 			if err != nil {
 				klog.Errorf("reconcilePrivateLinkService for service(%s) abort backoff: pls(%s) - updating: %s", serviceName, plsName, err.Error())
 				return err
@@ -134,7 +136,17 @@ func (az *Cloud) reconcilePrivateLinkService(
 
 		exists := !strings.EqualFold(to.String(existingPLS.ID), consts.PrivateLinkServiceNotExistID)
 		if exists {
-			deleteErr := az.safeDeletePLS(&existingPLS, service)
+			// Only the private link service created by the cloud provider can be deleted, the frontend could not be
+			// removed until the unmanaged one is detached by its owner.
+			if !isManagedPrivateLinkSerivce(&existingPLS, clusterName) {
+				return fmt.Errorf(
+					"reconcilePrivateLinkService for service(%s) failed: LB frontend(%s) has unmanaged private link service(%s) which would not be deleted",
+					serviceName,
+					to.String(fipConfigID),
+					to.String(existingPLS.ID),
+				)
+			}
+			deleteErr := az.safeDeletePLS(ctx, &existingPLS, service)
 			if deleteErr != nil {
 				klog.Errorf("reconcilePrivateLinkService for service(%s): deletePLS for frontEnd(%s) failed: %v", serviceName, to.String(fipConfigID), err)
 				return deleteErr.Error()
@@ -146,7 +158,7 @@ func (az *Cloud) reconcilePrivateLinkService(
 	return nil
 }
 
-func (az *Cloud) disablePLSNetworkPolicy(service *v1.Service) error {
+func (az *Cloud) disablePLSNetworkPolicy(ctx context.Context, service *v1.Service) error {
 	serviceName := getServiceName(service)
 	subnetName := getPLSSubnetName(service)
 	if subnetName == nil {
@@ -167,14 +179,14 @@ func (az *Cloud) disablePLSNetworkPolicy(service *v1.Service) error {
 	}
 
 	subnet.PrivateLinkServiceNetworkPolicies = network.VirtualNetworkPrivateLinkServiceNetworkPoliciesDisabled
-	err = az.CreateOrUpdateSubnet(service, subnet)
+	err = az.CreateOrUpdateSubnet(ctx, service, subnet)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (az *Cloud) safeDeletePLS(pls *network.PrivateLinkService, service *v1.Service) *retry.Error {
+func (az *Cloud) safeDeletePLS(ctx context.Context, pls *network.PrivateLinkService, service *v1.Service) *retry.Error {
 	if pls == nil {
 		return nil
 	}
@@ -183,14 +195,14 @@ func (az *Cloud) safeDeletePLS(pls *network.PrivateLinkService, service *v1.Serv
 	if peConns != nil {
 		for _, peConn := range *peConns {
 			klog.V(2).Infof("deletePLS: deleting PEConnection %s", to.String(peConn.Name))
-			rerr := az.DeletePEConn(service, to.String(pls.Name), to.String(peConn.Name))
+			rerr := az.DeletePEConn(ctx, service, to.String(pls.Name), to.String(peConn.Name))
 			if rerr != nil {
 				return rerr
 			}
 		}
 	}
 
-	rerr := az.DeletePLS(service, to.String(pls.Name), to.String((*pls.LoadBalancerFrontendIPConfigurations)[0].ID))
+	rerr := az.DeletePLS(ctx, service, to.String(pls.Name), to.String((*pls.LoadBalancerFrontendIPConfigurations)[0].ID))
 	if rerr != nil {
 		return rerr
 	}
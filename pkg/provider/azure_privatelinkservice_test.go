@@ -17,6 +17,7 @@ limitations under the License.
 package provider
 
 import (
+	"context"
 	"net/http"
 	"testing"
 
@@ -261,6 +262,51 @@ func TestReconcilePrivateLinkService(t *testing.T) {
 			expectedPLSCreate: true,
 			expectedPLS:       &network.PrivateLinkService{Name: to.StringPtr("testpls")},
 		},
+		{
+			desc: "reconcilePrivateLinkService should update an existing PLS if proxy protocol, visibility or fqdns get changed",
+			annotations: map[string]string{
+				consts.ServiceAnnotationPLSCreation:          "true",
+				consts.ServiceAnnotationLoadBalancerInternal: "true",
+				consts.ServiceAnnotationPLSName:              "testpls",
+				consts.ServiceAnnotationPLSProxyProtocol:     "true",
+				consts.ServiceAnnotationPLSVisibility:        "*",
+				consts.ServiceAnnotationPLSFqdns:             "pls.example.com",
+			},
+			wantPLS:           true,
+			expectedSubnetGet: true,
+			existingSubnet: &network.Subnet{
+				Name: to.StringPtr("subnet"),
+				ID:   to.StringPtr("subnetID"),
+				SubnetPropertiesFormat: &network.SubnetPropertiesFormat{
+					PrivateLinkServiceNetworkPolicies: network.VirtualNetworkPrivateLinkServiceNetworkPoliciesDisabled,
+				},
+			},
+			expectedPLSList: true,
+			existingPLSList: []network.PrivateLinkService{
+				{
+					Name: to.StringPtr("testpls"),
+					PrivateLinkServiceProperties: &network.PrivateLinkServiceProperties{
+						LoadBalancerFrontendIPConfigurations: &[]network.FrontendIPConfiguration{{ID: to.StringPtr("fipConfigID")}},
+						IPConfigurations: &[]network.PrivateLinkServiceIPConfiguration{
+							{
+								PrivateLinkServiceIPConfigurationProperties: &network.PrivateLinkServiceIPConfigurationProperties{
+									PrivateIPAllocationMethod: network.IPAllocationMethodDynamic,
+									Subnet:                    &network.Subnet{ID: to.StringPtr("subnetID")},
+									Primary:                   to.BoolPtr(true),
+									PrivateIPAddressVersion:   network.IPVersionIPv4,
+								},
+							},
+						},
+					},
+					Tags: map[string]*string{
+						consts.ClusterNameTagKey:  to.StringPtr(testClusterName),
+						consts.OwnerServiceTagKey: to.StringPtr("default/test"),
+					},
+				},
+			},
+			expectedPLSCreate: true,
+			expectedPLS:       &network.PrivateLinkService{Name: to.StringPtr("testpls")},
+		},
 		{
 			desc: "reconcilePrivateLinkService should not do anything if no existing PLS attached to the LB frontend when deleting",
 			annotations: map[string]string{
@@ -292,10 +338,35 @@ func TestReconcilePrivateLinkService(t *testing.T) {
 					PrivateLinkServiceProperties: &network.PrivateLinkServiceProperties{
 						LoadBalancerFrontendIPConfigurations: &[]network.FrontendIPConfiguration{{ID: to.StringPtr("fipConfigID")}},
 					},
+					Tags: map[string]*string{
+						consts.ClusterNameTagKey:  to.StringPtr(testClusterName),
+						consts.OwnerServiceTagKey: to.StringPtr("default/test"),
+					},
 				},
 			},
 			expectedPLSDelete: true,
 		},
+		{
+			desc: "reconcilePrivateLinkService should report error and not delete unmanaged pls when frontend is deleted",
+			annotations: map[string]string{
+				consts.ServiceAnnotationPLSCreation:          "true",
+				consts.ServiceAnnotationLoadBalancerInternal: "true",
+				consts.ServiceAnnotationPLSName:              "testpls",
+			},
+			expectedPLSList: true,
+			existingPLSList: []network.PrivateLinkService{
+				{
+					Name: to.StringPtr("testpls"),
+					PrivateLinkServiceProperties: &network.PrivateLinkServiceProperties{
+						LoadBalancerFrontendIPConfigurations: &[]network.FrontendIPConfiguration{{ID: to.StringPtr("fipConfigID")}},
+					},
+					Tags: map[string]*string{
+						consts.ClusterNameTagKey: to.StringPtr("other-cluster"),
+					},
+				},
+			},
+			expectedError: true,
+		},
 	}
 	for i, test := range testCases {
 		az := GetTestCloud(ctrl)
@@ -317,7 +388,7 @@ func TestReconcilePrivateLinkService(t *testing.T) {
 		if test.expectedPLSDelete {
 			mockPLSsClient.EXPECT().Delete(gomock.Any(), "rg", "testpls").Return(nil).Times(1)
 		}
-		err := az.reconcilePrivateLinkService(clusterName, &service, fipConfig, test.wantPLS)
+		err := az.reconcilePrivateLinkService(context.TODO(), clusterName, &service, fipConfig, test.wantPLS)
 		assert.Equal(t, test.expectedError, err != nil, "TestCase[%d]: %s", i, test.desc)
 	}
 }
@@ -370,7 +441,7 @@ func TestDisablePLSNetworkPolicy(t *testing.T) {
 				},
 			}).Return(nil).Times(1)
 		}
-		err := az.disablePLSNetworkPolicy(service)
+		err := az.disablePLSNetworkPolicy(context.TODO(), service)
 		assert.Equal(t, test.expectedError, err != nil, "TestCase[%d]: %s", i, test.desc)
 	}
 }
@@ -406,7 +477,7 @@ func TestSafeDeletePLS(t *testing.T) {
 		mockPLSsClient.EXPECT().DeletePEConnection(gomock.Any(), "rg", "testpls", "pe2").Return(nil).Times(1)
 		mockPLSsClient.EXPECT().Delete(gomock.Any(), "rg", "testpls").Return(nil).Times(1)
 		service := getTestService("test1", v1.ProtocolTCP, nil, false, 80)
-		rerr := az.safeDeletePLS(test.pls, &service)
+		rerr := az.safeDeletePLS(context.TODO(), test.pls, &service)
 		assert.Equal(t, test.expectedError, rerr != nil, "TestCase[%d]: %s", i, test.desc)
 	}
 }
@@ -34,6 +34,7 @@ import (
 
 	azcache "sigs.k8s.io/cloud-provider-azure/pkg/cache"
 	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+	"sigs.k8s.io/cloud-provider-azure/pkg/log"
 	"sigs.k8s.io/cloud-provider-azure/pkg/metrics"
 )
 
@@ -102,6 +103,8 @@ func (d *delayedRouteUpdater) run() {
 
 // updateRoutes invokes route table client to update all routes.
 func (d *delayedRouteUpdater) updateRoutes() {
+	// The routes are only updated on behalf of the route controller.
+	ctx := metrics.WithSource(context.Background(), metrics.SourceRouteController)
 	d.lock.Lock()
 	defer d.lock.Unlock()
 
@@ -133,7 +136,7 @@ func (d *delayedRouteUpdater) updateRoutes() {
 
 	// create route table if it doesn't exists yet.
 	if !existsRouteTable {
-		err = d.az.createRouteTable()
+		err = d.az.createRouteTable(ctx)
 		if err != nil {
 			klog.Errorf("createRouteTable() failed with error: %v", err)
 			return
@@ -202,7 +205,7 @@ func (d *delayedRouteUpdater) updateRoutes() {
 			klog.V(2).Infof("updateRoutes: updating routes")
 			routeTable.Routes = &routes
 		}
-		err = d.az.CreateOrUpdateRouteTable(routeTable)
+		err = d.az.CreateOrUpdateRouteTable(ctx, routeTable)
 		if err != nil {
 			klog.Errorf("CreateOrUpdateRouteTable() failed with error: %v", err)
 			return
@@ -347,7 +350,7 @@ func processRoutes(ipv6DualStackEnabled bool, routeTable network.RouteTable, exi
 	return kubeRoutes, nil
 }
 
-func (az *Cloud) createRouteTable() error {
+func (az *Cloud) createRouteTable(ctx context.Context) error {
 	routeTable := network.RouteTable{
 		Name:                       to.StringPtr(az.RouteTableName),
 		Location:                   to.StringPtr(az.Location),
@@ -355,7 +358,7 @@ func (az *Cloud) createRouteTable() error {
 	}
 
 	klog.V(3).Infof("createRouteTableIfNotExists: creating routetable. routeTableName=%q", az.RouteTableName)
-	err := az.CreateOrUpdateRouteTable(routeTable)
+	err := az.CreateOrUpdateRouteTable(ctx, routeTable)
 	if err != nil {
 		return err
 	}
@@ -369,7 +372,10 @@ func (az *Cloud) createRouteTable() error {
 // route.Name will be ignored, although the cloud-provider may use nameHint
 // to create a more user-meaningful name.
 func (az *Cloud) CreateRoute(ctx context.Context, clusterName string, nameHint string, kubeRoute *cloudprovider.Route) error {
-	mc := metrics.NewMetricContext("routes", "create_route", az.ResourceGroup, az.SubscriptionID, string(kubeRoute.TargetNode))
+	ctx = metrics.WithSource(ctx, metrics.SourceRouteController)
+	ctx = log.NewOperationContext(ctx, "")
+	start := time.Now()
+	mc := metrics.NewMetricContext("routes", "create_route", az.ResourceGroup, az.SubscriptionID, string(kubeRoute.TargetNode)).WithContext(ctx)
 	isOperationSucceeded := false
 	defer func() {
 		mc.ObserveOperationWithResult(isOperationSucceeded)
@@ -383,7 +389,7 @@ func (az *Cloud) CreateRoute(ctx context.Context, clusterName string, nameHint s
 		return err
 	}
 	if unmanaged {
-		klog.V(2).Infof("CreateRoute: omitting unmanaged node %q", kubeRoute.TargetNode)
+		klog.V(2).InfoS("CreateRoute: omitting unmanaged node", log.KeysAndValues(ctx, "node", kubeRoute.TargetNode)...)
 		az.routeCIDRsLock.Lock()
 		defer az.routeCIDRsLock.Unlock()
 		az.routeCIDRs[nodeName] = kubeRoute.DestinationCIDR
@@ -402,16 +408,16 @@ func (az *Cloud) CreateRoute(ctx context.Context, clusterName string, nameHint s
 	} else {
 		// for dual stack and single stack IPv6 we need to select
 		// a private ip that matches family of the cidr
-		klog.V(4).Infof("CreateRoute: create route instance=%q cidr=%q is in dual stack mode", kubeRoute.TargetNode, kubeRoute.DestinationCIDR)
+		klog.V(4).InfoS("CreateRoute: create route in dual stack mode", log.KeysAndValues(ctx, "node", kubeRoute.TargetNode, "cidr", kubeRoute.DestinationCIDR)...)
 		nodePrivateIPs, err := az.getPrivateIPsForMachine(kubeRoute.TargetNode)
 		if nil != err {
-			klog.V(3).Infof("CreateRoute: create route: failed(GetPrivateIPsByNodeName) instance=%q cidr=%q with error=%v", kubeRoute.TargetNode, kubeRoute.DestinationCIDR, err)
+			klog.V(3).InfoS("CreateRoute: create route: failed(GetPrivateIPsByNodeName)", log.KeysAndValues(ctx, "node", kubeRoute.TargetNode, "cidr", kubeRoute.DestinationCIDR, "error", err)...)
 			return err
 		}
 
 		targetIP, err = findFirstIPByFamily(nodePrivateIPs, CIDRv6)
 		if nil != err {
-			klog.V(3).Infof("CreateRoute: create route: failed(findFirstIpByFamily) instance=%q cidr=%q with error=%v", kubeRoute.TargetNode, kubeRoute.DestinationCIDR, err)
+			klog.V(3).InfoS("CreateRoute: create route: failed(findFirstIpByFamily)", log.KeysAndValues(ctx, "node", kubeRoute.TargetNode, "cidr", kubeRoute.DestinationCIDR, "error", err)...)
 			return err
 		}
 	}
@@ -425,21 +431,21 @@ func (az *Cloud) CreateRoute(ctx context.Context, clusterName string, nameHint s
 		},
 	}
 
-	klog.V(2).Infof("CreateRoute: creating route for clusterName=%q instance=%q cidr=%q", clusterName, kubeRoute.TargetNode, kubeRoute.DestinationCIDR)
+	klog.V(2).InfoS("CreateRoute: creating route", log.KeysAndValues(ctx, "cluster", clusterName, "node", kubeRoute.TargetNode, "cidr", kubeRoute.DestinationCIDR)...)
 	op, err := az.routeUpdater.addRouteOperation(routeOperationAdd, route)
 	if err != nil {
-		klog.Errorf("CreateRoute failed for node %q with error: %v", kubeRoute.TargetNode, err)
+		klog.ErrorS(err, "CreateRoute failed", log.KeysAndValues(ctx, "node", kubeRoute.TargetNode)...)
 		return err
 	}
 
 	// Wait for operation complete.
 	err = op.wait()
 	if err != nil {
-		klog.Errorf("CreateRoute failed for node %q with error: %v", kubeRoute.TargetNode, err)
+		klog.ErrorS(err, "CreateRoute failed", log.KeysAndValues(ctx, "node", kubeRoute.TargetNode)...)
 		return err
 	}
 
-	klog.V(2).Infof("CreateRoute: route created. clusterName=%q instance=%q cidr=%q", clusterName, kubeRoute.TargetNode, kubeRoute.DestinationCIDR)
+	klog.V(2).InfoS("CreateRoute: route created", log.KeysAndValues(ctx, "cluster", clusterName, "node", kubeRoute.TargetNode, "cidr", kubeRoute.DestinationCIDR, log.DurationMsKey, log.DurationMs(start))...)
 	isOperationSucceeded = true
 
 	return nil
@@ -448,7 +454,10 @@ func (az *Cloud) CreateRoute(ctx context.Context, clusterName string, nameHint s
 // DeleteRoute deletes the specified managed route
 // Route should be as returned by ListRoutes
 func (az *Cloud) DeleteRoute(ctx context.Context, clusterName string, kubeRoute *cloudprovider.Route) error {
-	mc := metrics.NewMetricContext("routes", "delete_route", az.ResourceGroup, az.SubscriptionID, string(kubeRoute.TargetNode))
+	ctx = metrics.WithSource(ctx, metrics.SourceRouteController)
+	ctx = log.NewOperationContext(ctx, "")
+	start := time.Now()
+	mc := metrics.NewMetricContext("routes", "delete_route", az.ResourceGroup, az.SubscriptionID, string(kubeRoute.TargetNode)).WithContext(ctx)
 	isOperationSucceeded := false
 	defer func() {
 		mc.ObserveOperationWithResult(isOperationSucceeded)
@@ -461,7 +470,7 @@ func (az *Cloud) DeleteRoute(ctx context.Context, clusterName string, kubeRoute
 		return err
 	}
 	if unmanaged {
-		klog.V(2).Infof("DeleteRoute: omitting unmanaged node %q", kubeRoute.TargetNode)
+		klog.V(2).InfoS("DeleteRoute: omitting unmanaged node", log.KeysAndValues(ctx, "node", kubeRoute.TargetNode)...)
 		az.routeCIDRsLock.Lock()
 		defer az.routeCIDRsLock.Unlock()
 		delete(az.routeCIDRs, nodeName)
@@ -469,47 +478,47 @@ func (az *Cloud) DeleteRoute(ctx context.Context, clusterName string, kubeRoute
 	}
 
 	routeName := mapNodeNameToRouteName(az.ipv6DualStackEnabled, kubeRoute.TargetNode, kubeRoute.DestinationCIDR)
-	klog.V(2).Infof("DeleteRoute: deleting route. clusterName=%q instance=%q cidr=%q routeName=%q", clusterName, kubeRoute.TargetNode, kubeRoute.DestinationCIDR, routeName)
+	klog.V(2).InfoS("DeleteRoute: deleting route", log.KeysAndValues(ctx, "cluster", clusterName, "node", kubeRoute.TargetNode, "cidr", kubeRoute.DestinationCIDR, "route", routeName)...)
 	route := network.Route{
 		Name:                  to.StringPtr(routeName),
 		RoutePropertiesFormat: &network.RoutePropertiesFormat{},
 	}
 	op, err := az.routeUpdater.addRouteOperation(routeOperationDelete, route)
 	if err != nil {
-		klog.Errorf("DeleteRoute failed for node %q with error: %v", kubeRoute.TargetNode, err)
+		klog.ErrorS(err, "DeleteRoute failed", log.KeysAndValues(ctx, "node", kubeRoute.TargetNode)...)
 		return err
 	}
 
 	// Wait for operation complete.
 	err = op.wait()
 	if err != nil {
-		klog.Errorf("DeleteRoute failed for node %q with error: %v", kubeRoute.TargetNode, err)
+		klog.ErrorS(err, "DeleteRoute failed", log.KeysAndValues(ctx, "node", kubeRoute.TargetNode)...)
 		return err
 	}
 
 	// Remove outdated ipv4 routes as well
 	if az.ipv6DualStackEnabled {
 		routeNameWithoutIPV6Suffix := strings.Split(routeName, consts.RouteNameSeparator)[0]
-		klog.V(2).Infof("DeleteRoute: deleting route. clusterName=%q instance=%q cidr=%q routeName=%q", clusterName, kubeRoute.TargetNode, kubeRoute.DestinationCIDR, routeNameWithoutIPV6Suffix)
+		klog.V(2).InfoS("DeleteRoute: deleting route", log.KeysAndValues(ctx, "cluster", clusterName, "node", kubeRoute.TargetNode, "cidr", kubeRoute.DestinationCIDR, "route", routeNameWithoutIPV6Suffix)...)
 		route := network.Route{
 			Name:                  to.StringPtr(routeNameWithoutIPV6Suffix),
 			RoutePropertiesFormat: &network.RoutePropertiesFormat{},
 		}
 		op, err := az.routeUpdater.addRouteOperation(routeOperationDelete, route)
 		if err != nil {
-			klog.Errorf("DeleteRoute failed for node %q with error: %v", kubeRoute.TargetNode, err)
+			klog.ErrorS(err, "DeleteRoute failed", log.KeysAndValues(ctx, "node", kubeRoute.TargetNode)...)
 			return err
 		}
 
 		// Wait for operation complete.
 		err = op.wait()
 		if err != nil {
-			klog.Errorf("DeleteRoute failed for node %q with error: %v", kubeRoute.TargetNode, err)
+			klog.ErrorS(err, "DeleteRoute failed", log.KeysAndValues(ctx, "node", kubeRoute.TargetNode)...)
 			return err
 		}
 	}
 
-	klog.V(2).Infof("DeleteRoute: route deleted. clusterName=%q instance=%q cidr=%q", clusterName, kubeRoute.TargetNode, kubeRoute.DestinationCIDR)
+	klog.V(2).InfoS("DeleteRoute: route deleted", log.KeysAndValues(ctx, "cluster", clusterName, "node", kubeRoute.TargetNode, "cidr", kubeRoute.DestinationCIDR, log.DurationMsKey, log.DurationMs(start))...)
 	isOperationSucceeded = true
 
 	return nil
@@ -34,6 +34,7 @@ import (
 	cloudprovider "k8s.io/cloud-provider"
 
 	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/routetableclient/mockroutetableclient"
+	"sigs.k8s.io/cloud-provider-azure/pkg/metrics"
 	"sigs.k8s.io/cloud-provider-azure/pkg/retry"
 )
 
@@ -109,6 +110,63 @@ func TestDeleteRoute(t *testing.T) {
 	}
 }
 
+func TestDeleteRouteMetricSource(t *testing.T) {
+	cloud := &Cloud{
+		unmanagedNodes:     sets.NewString("node1"),
+		nodeInformerSynced: func() bool { return true },
+		routeCIDRs:         map[string]string{"node1": "4.3.2.1/24"},
+	}
+	route := cloudprovider.Route{
+		TargetNode:      MapRouteNameToNodeName(false, "node1"),
+		DestinationCIDR: "4.3.2.1/24",
+	}
+
+	before := getOperationCount("routes_delete_route", metrics.SourceRouteController)
+	assert.NoError(t, cloud.DeleteRoute(context.TODO(), "cluster", &route))
+	assert.Equal(t, before+1, getOperationCount("routes_delete_route", metrics.SourceRouteController))
+	assert.Zero(t, getOperationCount("routes_delete_route", metrics.SourceServiceController))
+}
+
+func TestUpdateRoutesMetricSource(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	routeTableClient := mockroutetableclient.NewMockInterface(ctrl)
+
+	cloud := &Cloud{
+		RouteTablesClient: routeTableClient,
+		Config: Config{
+			RouteTableResourceGroup: "foo",
+			RouteTableName:          "bar",
+			Location:                "location",
+		},
+	}
+	cache, _ := cloud.newRouteTableCache()
+	cloud.rtCache = cache
+	cloud.routeUpdater = newDelayedRouteUpdater(cloud, 100*time.Millisecond)
+
+	routeTable := network.RouteTable{
+		Name:                       &cloud.RouteTableName,
+		Location:                   &cloud.Location,
+		RouteTablePropertiesFormat: &network.RouteTablePropertiesFormat{},
+	}
+	var source string
+	routeTableClient.EXPECT().Get(gomock.Any(), cloud.RouteTableResourceGroup, cloud.RouteTableName, "").Return(routeTable, nil)
+	routeTableClient.EXPECT().CreateOrUpdate(gomock.Any(), cloud.RouteTableResourceGroup, cloud.RouteTableName, gomock.Any(), "").DoAndReturn(
+		func(ctx context.Context, resourceGroupName, routeTableName string, parameters network.RouteTable, etag string) *retry.Error {
+			source = metrics.SourceFromContext(ctx)
+			return nil
+		})
+
+	op, err := cloud.routeUpdater.addRouteOperation(routeOperationAdd, network.Route{
+		Name:                  to.StringPtr("route"),
+		RoutePropertiesFormat: &network.RoutePropertiesFormat{AddressPrefix: to.StringPtr("1.2.3.4/24")},
+	})
+	assert.NoError(t, err)
+	go cloud.routeUpdater.updateRoutes()
+	assert.NoError(t, op.wait())
+	assert.Equal(t, metrics.SourceRouteController, source)
+}
+
 func TestDeleteRouteDualStack(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -399,7 +457,7 @@ func TestCreateRouteTable(t *testing.T) {
 		RouteTablePropertiesFormat: &network.RouteTablePropertiesFormat{},
 	}
 	routeTableClient.EXPECT().CreateOrUpdate(gomock.Any(), cloud.RouteTableResourceGroup, cloud.RouteTableName, expectedTable, "").Return(nil)
-	err := cloud.createRouteTable()
+	err := cloud.createRouteTable(context.TODO())
 	if err != nil {
 		t.Errorf("unexpected error in creating route table: %v", err)
 		t.FailNow()
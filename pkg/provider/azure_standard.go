@@ -25,7 +25,6 @@ import (
 	"strconv"
 	"strings"
 	"sync"
-	"time"
 	"unicode"
 
 	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
@@ -343,7 +342,7 @@ func (az *Cloud) serviceOwnsRule(service *v1.Service, rule string) bool {
 // This means the name of the config can be tracked by the service UID.
 // 2. The secondary services must have their loadBalancer IP set if they want to share the same config as the primary
 // service. Hence, it can be tracked by the loadBalancer IP.
-func (az *Cloud) serviceOwnsFrontendIP(fip network.FrontendIPConfiguration, service *v1.Service, pips *[]network.PublicIPAddress) (bool, bool, error) {
+func (az *Cloud) serviceOwnsFrontendIP(ctx context.Context, fip network.FrontendIPConfiguration, service *v1.Service, pips *[]network.PublicIPAddress) (bool, bool, error) {
 	var isPrimaryService bool
 	baseName := az.GetLoadBalancerName(context.TODO(), "", service)
 	if strings.HasPrefix(to.String(fip.Name), baseName) {
@@ -361,7 +360,7 @@ func (az *Cloud) serviceOwnsFrontendIP(fip network.FrontendIPConfiguration, serv
 	// for external secondary service the public IP address should be checked
 	if !requiresInternalLoadBalancer(service) {
 		pipResourceGroup := az.getPublicIPAddressResourceGroup(service)
-		pip, err := az.findMatchedPIPByLoadBalancerIP(service, loadBalancerIP, pipResourceGroup, pips)
+		pip, err := az.findMatchedPIPByLoadBalancerIP(ctx, service, loadBalancerIP, pipResourceGroup, pips)
 		if err != nil {
 			klog.Warningf("serviceOwnsFrontendIP: unexpected error when finding match public IP of the service %s with loadBalancerLP %s: %v", service.Name, loadBalancerIP, err)
 			return false, isPrimaryService, nil
@@ -447,6 +446,8 @@ type availabilitySet struct {
 	*Cloud
 
 	vmasCache *azcache.TimedCache
+	// vmasNICCache is a short-lived cache of the VMs and their primary NICs keyed by the resource group.
+	vmasNICCache *azcache.TimedCache
 }
 
 type availabilitySetEntry struct {
@@ -490,7 +491,120 @@ func (as *availabilitySet) newVMASCache() (*azcache.TimedCache, error) {
 		as.Config.AvailabilitySetsCacheTTLInSeconds = consts.VMASCacheTTLDefaultInSeconds
 	}
 
-	return azcache.NewTimedcache(time.Duration(as.Config.AvailabilitySetsCacheTTLInSeconds)*time.Second, getter)
+	return azcache.NewTimedcacheWithName(availabilitySetsCacheName, as.Config.getCacheTTL(availabilitySetsCacheName, as.Config.AvailabilitySetsCacheTTLInSeconds), getter)
+}
+
+// vmasNICEntry is a VM and its primary NIC joined from the lists of the resource group.
+type vmasNICEntry struct {
+	vm  *compute.VirtualMachine
+	nic *network.Interface
+}
+
+// newVMASNICCache creates the cache of the VMs and their primary NICs. The VMs and NICs in a
+// resource group are listed once and joined by the NIC ID, so that reconciling the backend pools
+// doesn't need to get the VM and the NIC of each node one by one.
+func (as *availabilitySet) newVMASNICCache() (*azcache.TimedCache, error) {
+	getter := func(key string) (interface{}, error) {
+		localCache := &sync.Map{} // [vmName]*vmasNICEntry
+
+		vms, err := as.ListVirtualMachines(key)
+		if err != nil {
+			return nil, err
+		}
+
+		ctx, cancel := getContextWithCancel()
+		defer cancel()
+		nics, rerr := as.InterfacesClient.List(ctx, key)
+		if rerr != nil {
+			klog.Errorf("InterfacesClient.List(%s) failed: %v", key, rerr)
+			return nil, rerr.Error()
+		}
+
+		nicsByID := make(map[string]*network.Interface, len(nics))
+		for i := range nics {
+			nicsByID[strings.ToLower(to.String(nics[i].ID))] = &nics[i]
+		}
+
+		for i := range vms {
+			vm := vms[i]
+			primaryNicID, err := getPrimaryInterfaceID(vm)
+			if err != nil {
+				klog.V(4).Infof("newVMASNICCache: failed to get the primary NIC of VM %s: %v", to.String(vm.Name), err)
+				continue
+			}
+
+			// The NIC in another resource group is not cached, and it would be got by a separate request.
+			nic, ok := nicsByID[strings.ToLower(primaryNicID)]
+			if !ok {
+				continue
+			}
+			localCache.Store(strings.ToLower(to.String(vm.Name)), &vmasNICEntry{
+				vm:  &vm,
+				nic: nic,
+			})
+		}
+
+		return localCache, nil
+	}
+
+	return azcache.NewTimedcacheWithName(vmasNICCacheName, as.Config.getCacheTTL(vmasNICCacheName, consts.VMASNICCacheTTLDefaultInSeconds), getter)
+}
+
+// getVMASNICEntry gets the VM and its primary NIC from the cache. It returns nil if they are not cached.
+func (as *availabilitySet) getVMASNICEntry(resourceGroup, vmName string) *vmasNICEntry {
+	cached, err := as.vmasNICCache.Get(strings.ToLower(resourceGroup), azcache.CacheReadTypeDefault)
+	if err != nil {
+		klog.Warningf("getVMASNICEntry: failed to list the VMs and NICs in the resource group %s: %v", resourceGroup, err)
+		return nil
+	}
+
+	entry, ok := cached.(*sync.Map).Load(strings.ToLower(vmName))
+	if !ok {
+		return nil
+	}
+	return entry.(*vmasNICEntry)
+}
+
+// deleteVMASNICCacheForNode invalidates the cached VM and NIC of the node after its NIC is updated.
+func (as *availabilitySet) deleteVMASNICCacheForNode(nodeName string) {
+	resourceGroup, err := as.GetNodeResourceGroup(nodeName)
+	if err != nil {
+		klog.Warningf("deleteVMASNICCacheForNode: failed to get the resource group of node %s: %v", nodeName, err)
+		return
+	}
+
+	entry, exists, err := as.vmasNICCache.Store.GetByKey(strings.ToLower(resourceGroup))
+	if err != nil || !exists {
+		return
+	}
+	if cached := entry.(*azcache.AzureCacheEntry).Data; cached != nil {
+		cached.(*sync.Map).Delete(strings.ToLower(nodeName))
+	}
+}
+
+// copyInterface returns a copy of the NIC whose IP configurations could be updated without changing the cached NIC.
+func copyInterface(nic *network.Interface) network.Interface {
+	result := *nic
+	if nic.InterfacePropertiesFormat == nil || nic.IPConfigurations == nil {
+		return result
+	}
+
+	properties := *nic.InterfacePropertiesFormat
+	ipConfigs := make([]network.InterfaceIPConfiguration, len(*nic.IPConfigurations))
+	for i, ipConfig := range *nic.IPConfigurations {
+		if ipConfig.InterfaceIPConfigurationPropertiesFormat != nil {
+			ipConfigProperties := *ipConfig.InterfaceIPConfigurationPropertiesFormat
+			if ipConfigProperties.LoadBalancerBackendAddressPools != nil {
+				pools := append([]network.BackendAddressPool{}, *ipConfigProperties.LoadBalancerBackendAddressPools...)
+				ipConfigProperties.LoadBalancerBackendAddressPools = &pools
+			}
+			ipConfig.InterfaceIPConfigurationPropertiesFormat = &ipConfigProperties
+		}
+		ipConfigs[i] = ipConfig
+	}
+	properties.IPConfigurations = &ipConfigs
+	result.InterfacePropertiesFormat = &properties
+	return result
 }
 
 // newStandardSet creates a new availabilitySet.
@@ -504,6 +618,10 @@ func newAvailabilitySet(az *Cloud) (VMSet, error) {
 	if err != nil {
 		return nil, err
 	}
+	as.vmasNICCache, err = as.newVMASNICCache()
+	if err != nil {
+		return nil, err
+	}
 
 	return as, nil
 }
@@ -825,7 +943,7 @@ func (as *availabilitySet) GetNodeVMSetName(node *v1.Node) (string, error) {
 
 // GetPrimaryInterface gets machine primary network interface by node name.
 func (as *availabilitySet) GetPrimaryInterface(nodeName string) (network.Interface, error) {
-	nic, _, err := as.getPrimaryInterfaceWithVMSet(nodeName, "")
+	nic, _, err := as.getPrimaryInterfaceWithVMSet(nodeName, "", false)
 	return nic, err
 }
 
@@ -840,13 +958,30 @@ func extractResourceGroupByNicID(nicID string) (string, error) {
 }
 
 // getPrimaryInterfaceWithVMSet gets machine primary network interface by node name and vmSet.
-func (as *availabilitySet) getPrimaryInterfaceWithVMSet(nodeName, vmSetName string) (network.Interface, string, error) {
+// If useNICCache is true, the VM and NIC are read from vmasNICCache first, and they are only
+// got separately if they are not cached.
+func (as *availabilitySet) getPrimaryInterfaceWithVMSet(nodeName, vmSetName string, useNICCache bool) (network.Interface, string, error) {
 	var machine compute.VirtualMachine
+	var cachedNIC *network.Interface
+	var err error
 
-	machine, err := as.GetVirtualMachineWithRetry(types.NodeName(nodeName), azcache.CacheReadTypeDefault)
-	if err != nil {
-		klog.V(2).Infof("GetPrimaryInterface(%s, %s) abort backoff", nodeName, vmSetName)
-		return network.Interface{}, "", err
+	if useNICCache {
+		nodeResourceGroup, err := as.GetNodeResourceGroup(nodeName)
+		if err != nil {
+			return network.Interface{}, "", err
+		}
+		if entry := as.getVMASNICEntry(nodeResourceGroup, nodeName); entry != nil {
+			machine = *entry.vm
+			cachedNIC = entry.nic
+		}
+	}
+
+	if cachedNIC == nil {
+		machine, err = as.GetVirtualMachineWithRetry(types.NodeName(nodeName), azcache.CacheReadTypeDefault)
+		if err != nil {
+			klog.V(2).Infof("GetPrimaryInterface(%s, %s) abort backoff", nodeName, vmSetName)
+			return network.Interface{}, "", err
+		}
 	}
 
 	primaryNicID, err := getPrimaryInterfaceID(machine)
@@ -897,6 +1032,15 @@ func (as *availabilitySet) getPrimaryInterfaceWithVMSet(nodeName, vmSetName stri
 		}
 	}
 
+	var availabilitySetID string
+	if machine.VirtualMachineProperties != nil && machine.AvailabilitySet != nil {
+		availabilitySetID = to.String(machine.AvailabilitySet.ID)
+	}
+
+	if cachedNIC != nil {
+		return copyInterface(cachedNIC), availabilitySetID, nil
+	}
+
 	nicResourceGroup, err := extractResourceGroupByNicID(primaryNicID)
 	if err != nil {
 		return network.Interface{}, "", err
@@ -909,19 +1053,21 @@ func (as *availabilitySet) getPrimaryInterfaceWithVMSet(nodeName, vmSetName stri
 		return network.Interface{}, "", rerr.Error()
 	}
 
-	var availabilitySetID string
-	if machine.VirtualMachineProperties != nil && machine.AvailabilitySet != nil {
-		availabilitySetID = to.String(machine.AvailabilitySet.ID)
-	}
 	return nic, availabilitySetID, nil
 }
 
 // EnsureHostInPool ensures the given VM's Primary NIC's Primary IP Configuration is
 // participating in the specified LoadBalancer Backend Pool.
-func (as *availabilitySet) EnsureHostInPool(service *v1.Service, nodeName types.NodeName, backendPoolID string, vmSetName string) (string, string, string, *compute.VirtualMachineScaleSetVM, error) {
+func (as *availabilitySet) EnsureHostInPool(ctx context.Context, service *v1.Service, nodeName types.NodeName, backendPoolID string, vmSetName string) (string, string, string, *compute.VirtualMachineScaleSetVM, error) {
+	return as.ensureHostInPool(ctx, service, nodeName, backendPoolID, vmSetName, false)
+}
+
+// ensureHostInPool ensures the given VM's Primary NIC's Primary IP Configuration is participating
+// in the specified LoadBalancer Backend Pool. The VM and NIC are read from vmasNICCache if useNICCache is true.
+func (as *availabilitySet) ensureHostInPool(ctx context.Context, service *v1.Service, nodeName types.NodeName, backendPoolID string, vmSetName string, useNICCache bool) (string, string, string, *compute.VirtualMachineScaleSetVM, error) {
 	vmName := mapNodeNameToVMName(nodeName)
 	serviceName := getServiceName(service)
-	nic, _, err := as.getPrimaryInterfaceWithVMSet(vmName, vmSetName)
+	nic, _, err := as.getPrimaryInterfaceWithVMSet(vmName, vmSetName, useNICCache)
 	if err != nil {
 		if errors.Is(err, errNotInVMSet) {
 			klog.V(3).Infof("EnsureHostInPool skips node %s because it is not in the vmSet %s", nodeName, vmSetName)
@@ -993,7 +1139,8 @@ func (as *availabilitySet) EnsureHostInPool(service *v1.Service, nodeName types.
 
 		nicName := *nic.Name
 		klog.V(3).Infof("nicupdate(%s): nic(%s) - updating", serviceName, nicName)
-		err := as.CreateOrUpdateInterface(service, nic)
+		err := as.CreateOrUpdateInterface(ctx, service, nic)
+		as.deleteVMASNICCacheForNode(vmName)
 		if err != nil {
 			return "", "", "", nil, err
 		}
@@ -1003,8 +1150,8 @@ func (as *availabilitySet) EnsureHostInPool(service *v1.Service, nodeName types.
 
 // EnsureHostsInPool ensures the given Node's primary IP configurations are
 // participating in the specified LoadBalancer Backend Pool.
-func (as *availabilitySet) EnsureHostsInPool(service *v1.Service, nodes []*v1.Node, backendPoolID string, vmSetName string) error {
-	mc := metrics.NewMetricContext("services", "vmas_ensure_hosts_in_pool", as.ResourceGroup, as.SubscriptionID, getServiceName(service))
+func (as *availabilitySet) EnsureHostsInPool(ctx context.Context, service *v1.Service, nodes []*v1.Node, backendPoolID string, vmSetName string) error {
+	mc := metrics.NewMetricContext("services", "vmas_ensure_hosts_in_pool", as.ResourceGroup, as.SubscriptionID, getServiceName(service)).WithContext(ctx)
 	isOperationSucceeded := false
 	defer func() {
 		mc.ObserveOperationWithResult(isOperationSucceeded)
@@ -1029,7 +1176,7 @@ func (as *availabilitySet) EnsureHostsInPool(service *v1.Service, nodes []*v1.No
 		}
 
 		f := func() error {
-			_, _, _, _, err := as.EnsureHostInPool(service, types.NodeName(localNodeName), backendPoolID, vmSetName)
+			_, _, _, _, err := as.ensureHostInPool(ctx, service, types.NodeName(localNodeName), backendPoolID, vmSetName, true)
 			if err != nil {
 				return fmt.Errorf("ensure(%s): backendPoolID(%s) - failed to ensure host in pool: %w", getServiceName(service), backendPoolID, err)
 			}
@@ -1048,13 +1195,13 @@ func (as *availabilitySet) EnsureHostsInPool(service *v1.Service, nodes []*v1.No
 }
 
 // EnsureBackendPoolDeleted ensures the loadBalancer backendAddressPools deleted from the specified nodes.
-func (as *availabilitySet) EnsureBackendPoolDeleted(service *v1.Service, backendPoolID, vmSetName string, backendAddressPools *[]network.BackendAddressPool, deleteFromVMSet bool) error {
+func (as *availabilitySet) EnsureBackendPoolDeleted(ctx context.Context, service *v1.Service, backendPoolID, vmSetName string, backendAddressPools *[]network.BackendAddressPool, deleteFromVMSet bool) error {
 	// Returns nil if backend address pools already deleted.
 	if backendAddressPools == nil {
 		return nil
 	}
 
-	mc := metrics.NewMetricContext("services", "vmas_ensure_backend_pool_deleted", as.ResourceGroup, as.SubscriptionID, getServiceName(service))
+	mc := metrics.NewMetricContext("services", "vmas_ensure_backend_pool_deleted", as.ResourceGroup, as.SubscriptionID, getServiceName(service)).WithContext(ctx)
 	isOperationSucceeded := false
 	defer func() {
 		mc.ObserveOperationWithResult(isOperationSucceeded)
@@ -1089,7 +1236,7 @@ func (as *availabilitySet) EnsureBackendPoolDeleted(service *v1.Service, backend
 		}
 
 		vmName := mapNodeNameToVMName(types.NodeName(nodeName))
-		nic, vmasID, err := as.getPrimaryInterfaceWithVMSet(vmName, vmSetName)
+		nic, vmasID, err := as.getPrimaryInterfaceWithVMSet(vmName, vmSetName, false)
 		if err != nil {
 			if errors.Is(err, errNotInVMSet) {
 				klog.V(3).Infof("EnsureBackendPoolDeleted skips node %s because it is not in the vmSet %s", nodeName, vmSetName)
@@ -1135,10 +1282,9 @@ func (as *availabilitySet) EnsureBackendPoolDeleted(service *v1.Service, backend
 			}
 			nic.IPConfigurations = &newIPConfigs
 			nicUpdaters = append(nicUpdaters, func() error {
-				ctx, cancel := getContextWithCancel()
-				defer cancel()
 				klog.V(2).Infof("EnsureBackendPoolDeleted begins to CreateOrUpdate for NIC(%s, %s) with backendPoolID %s", as.resourceGroup, to.String(nic.Name), backendPoolID)
 				rerr := as.InterfacesClient.CreateOrUpdate(ctx, as.ResourceGroup, to.String(nic.Name), nic)
+				as.deleteVMASNICCacheForNode(vmName)
 				if rerr != nil {
 					klog.Errorf("EnsureBackendPoolDeleted CreateOrUpdate for NIC(%s, %s) failed with error %v", as.resourceGroup, to.String(nic.Name), rerr.Error())
 					return rerr.Error()
@@ -1317,7 +1463,7 @@ func (as *availabilitySet) GetNodeCIDRMasksByProviderID(providerID string) (int,
 }
 
 //EnsureBackendPoolDeletedFromVMSets ensures the loadBalancer backendAddressPools deleted from the specified VMAS
-func (as *availabilitySet) EnsureBackendPoolDeletedFromVMSets(vmasNamesMap map[string]bool, backendPoolID string) error {
+func (as *availabilitySet) EnsureBackendPoolDeletedFromVMSets(ctx context.Context, vmasNamesMap map[string]bool, backendPoolID string) error {
 	return nil
 }
 
@@ -17,10 +17,12 @@ limitations under the License.
 package provider
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"sync/atomic"
 	"testing"
 
 	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2021-07-01/compute"
@@ -1466,7 +1468,7 @@ func TestStandardEnsureHostInPool(t *testing.T) {
 		mockInterfaceClient.EXPECT().Get(gomock.Any(), cloud.ResourceGroup, test.nicName, gomock.Any()).Return(testNIC, nil).AnyTimes()
 		mockInterfaceClient.EXPECT().CreateOrUpdate(gomock.Any(), cloud.ResourceGroup, gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 
-		_, _, _, vm, err := cloud.VMSet.EnsureHostInPool(test.service, test.nodeName, test.backendPoolID, test.vmSetName)
+		_, _, _, vm, err := cloud.VMSet.EnsureHostInPool(context.TODO(), test.service, test.nodeName, test.backendPoolID, test.vmSetName)
 		assert.Equal(t, test.expectedErrMsg, err, test.name)
 		assert.Nil(t, vm, test.name)
 	}
@@ -1601,12 +1603,14 @@ func TestStandardEnsureHostsInPool(t *testing.T) {
 
 		mockVMClient := cloud.VirtualMachinesClient.(*mockvmclient.MockInterface)
 		mockVMClient.EXPECT().Get(gomock.Any(), cloud.ResourceGroup, test.nodeName, gomock.Any()).Return(testVM, nil).AnyTimes()
+		mockVMClient.EXPECT().List(gomock.Any(), cloud.ResourceGroup).Return(nil, nil).AnyTimes()
 
 		mockInterfaceClient := cloud.InterfacesClient.(*mockinterfaceclient.MockInterface)
 		mockInterfaceClient.EXPECT().Get(gomock.Any(), cloud.ResourceGroup, test.nicName, gomock.Any()).Return(testNIC, nil).AnyTimes()
+		mockInterfaceClient.EXPECT().List(gomock.Any(), cloud.ResourceGroup).Return(nil, nil).AnyTimes()
 		mockInterfaceClient.EXPECT().CreateOrUpdate(gomock.Any(), cloud.ResourceGroup, gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 
-		err := cloud.VMSet.EnsureHostsInPool(test.service, test.nodes, test.backendPoolID, test.vmSetName)
+		err := cloud.VMSet.EnsureHostsInPool(context.TODO(), test.service, test.nodes, test.backendPoolID, test.vmSetName)
 		if test.expectedErr {
 			assert.EqualError(t, test.expectedErrMsg, err.Error(), test.name)
 		} else {
@@ -1615,6 +1619,100 @@ func TestStandardEnsureHostsInPool(t *testing.T) {
 	}
 }
 
+func TestStandardEnsureHostsInPoolWithNICCache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cloud := GetTestCloud(ctrl)
+	cloud.Config.LoadBalancerSku = consts.LoadBalancerSkuStandard
+
+	backendAddressPoolID := "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/lb1-internal/backendAddressPools/backendpool-1"
+	nodes, vms, nics := buildTestAvailabilitySetNodes(10)
+
+	mockVMClient := cloud.VirtualMachinesClient.(*mockvmclient.MockInterface)
+	mockVMClient.EXPECT().List(gomock.Any(), cloud.ResourceGroup).Return(vms, nil).Times(1)
+	mockVMClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	mockInterfaceClient := cloud.InterfacesClient.(*mockinterfaceclient.MockInterface)
+	mockInterfaceClient.EXPECT().List(gomock.Any(), cloud.ResourceGroup).Return(nics, nil).Times(1)
+	mockInterfaceClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+	mockInterfaceClient.EXPECT().CreateOrUpdate(gomock.Any(), cloud.ResourceGroup, gomock.Any(), gomock.Any()).Return(nil).Times(len(nodes))
+
+	err := cloud.VMSet.EnsureHostsInPool(context.TODO(), &v1.Service{}, nodes, backendAddressPoolID, "availabilityset-1")
+	assert.NoError(t, err)
+
+	// The updated NICs should not be served from the cache anymore.
+	as := cloud.VMSet.(*availabilitySet)
+	for _, node := range nodes {
+		assert.Nil(t, as.getVMASNICEntry(cloud.ResourceGroup, node.Name))
+	}
+	// The cached NICs should not be changed by the updates.
+	for _, nic := range nics {
+		assert.Empty(t, *(*nic.IPConfigurations)[0].LoadBalancerBackendAddressPools)
+	}
+}
+
+func BenchmarkStandardEnsureHostsInPool(b *testing.B) {
+	ctrl := gomock.NewController(b)
+	defer ctrl.Finish()
+	cloud := GetTestCloud(ctrl)
+	cloud.Config.LoadBalancerSku = consts.LoadBalancerSkuStandard
+
+	backendAddressPoolID := "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/lb1-internal/backendAddressPools/backendpool-1"
+	nodes, vms, nics := buildTestAvailabilitySetNodes(100)
+	var readCalls int64
+
+	mockVMClient := cloud.VirtualMachinesClient.(*mockvmclient.MockInterface)
+	mockVMClient.EXPECT().List(gomock.Any(), cloud.ResourceGroup).DoAndReturn(func(ctx context.Context, resourceGroupName string) ([]compute.VirtualMachine, *retry.Error) {
+		atomic.AddInt64(&readCalls, 1)
+		return vms, nil
+	}).AnyTimes()
+
+	mockInterfaceClient := cloud.InterfacesClient.(*mockinterfaceclient.MockInterface)
+	mockInterfaceClient.EXPECT().List(gomock.Any(), cloud.ResourceGroup).DoAndReturn(func(ctx context.Context, resourceGroupName string) ([]network.Interface, *retry.Error) {
+		atomic.AddInt64(&readCalls, 1)
+		return nics, nil
+	}).AnyTimes()
+	mockInterfaceClient.EXPECT().CreateOrUpdate(gomock.Any(), cloud.ResourceGroup, gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	as := cloud.VMSet.(*availabilitySet)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		as.vmasNICCache, _ = as.newVMASNICCache()
+		b.StartTimer()
+
+		err := as.EnsureHostsInPool(context.TODO(), &v1.Service{}, nodes, backendAddressPoolID, "availabilityset-1")
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(atomic.LoadInt64(&readCalls))/float64(b.N), "reads/op")
+}
+
+// buildTestAvailabilitySetNodes builds the nodes and their VMs and NICs in the availability set.
+func buildTestAvailabilitySetNodes(count int) ([]*v1.Node, []compute.VirtualMachine, []network.Interface) {
+	nodes := make([]*v1.Node, 0, count)
+	vms := make([]compute.VirtualMachine, 0, count)
+	nics := make([]network.Interface, 0, count)
+	for i := 0; i < count; i++ {
+		nodeName := fmt.Sprintf("vm%d", i)
+		nicName := fmt.Sprintf("nic%d", i)
+		nicID := "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/networkInterfaces/" + nicName
+
+		nodes = append(nodes, &v1.Node{ObjectMeta: meta.ObjectMeta{Name: nodeName}})
+
+		vm := buildDefaultTestVirtualMachine(asID, []string{nicID})
+		vm.Name = to.StringPtr(nodeName)
+		vms = append(vms, vm)
+
+		nic := buildDefaultTestInterface(true, []string{})
+		nic.Name = to.StringPtr(nicName)
+		nic.ID = to.StringPtr(nicID)
+		nics = append(nics, nic)
+	}
+	return nodes, vms, nics
+}
+
 func TestServiceOwnsFrontendIP(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -1786,7 +1884,7 @@ func TestServiceOwnsFrontendIP(t *testing.T) {
 	}
 
 	for _, test := range testCases {
-		isOwned, isPrimary, err := cloud.serviceOwnsFrontendIP(test.fip, test.service, &test.existingPIPs)
+		isOwned, isPrimary, err := cloud.serviceOwnsFrontendIP(context.TODO(), test.fip, test.service, &test.existingPIPs)
 		assert.Equal(t, test.expectedErr, err, test.desc)
 		assert.Equal(t, test.isOwned, isOwned, test.desc)
 		assert.Equal(t, test.isPrimary, isPrimary, test.desc)
@@ -1842,7 +1940,7 @@ func TestStandardEnsureBackendPoolDeleted(t *testing.T) {
 		mockNICClient.EXPECT().CreateOrUpdate(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
 		cloud.InterfacesClient = mockNICClient
 
-		err := cloud.VMSet.EnsureBackendPoolDeleted(&service, backendPoolID, vmSetName, test.backendAddressPools, true)
+		err := cloud.VMSet.EnsureBackendPoolDeleted(context.TODO(), &service, backendPoolID, vmSetName, test.backendAddressPools, true)
 		assert.NoError(t, err, test.desc)
 	}
 }
@@ -27,6 +27,7 @@ import (
 	"github.com/Azure/go-autorest/autorest/to"
 
 	v1 "k8s.io/api/core/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
 	utilnet "k8s.io/utils/net"
@@ -86,6 +87,38 @@ func (lm *lockMap) unlockEntry(entry string) {
 	lm.mutexMap[entry].Unlock()
 }
 
+// aggregateGoroutinesWithLimit runs the provided functions in parallel with at most limit
+// of them running at the same time, and aggregates all the non-nil errors. If limit is
+// smaller than or equal to zero, all the functions will be run at the same time.
+func aggregateGoroutinesWithLimit(limit int, funcs ...func() error) utilerrors.Aggregate {
+	if limit <= 0 || limit > len(funcs) {
+		limit = len(funcs)
+	}
+
+	errChan := make(chan error, len(funcs))
+	rateLimiter := make(chan struct{}, limit)
+	wg := sync.WaitGroup{}
+	for _, f := range funcs {
+		rateLimiter <- struct{}{}
+		wg.Add(1)
+		go func(f func() error) {
+			defer wg.Done()
+			defer func() { <-rateLimiter }()
+			errChan <- f()
+		}(f)
+	}
+	wg.Wait()
+	close(errChan)
+
+	errs := make([]error, 0)
+	for err := range errChan {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
 func getContextWithCancel() (context.Context, context.CancelFunc) {
 	return context.WithCancel(context.Background())
 }
@@ -18,6 +18,7 @@ package provider
 
 import (
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -89,6 +90,46 @@ func ensureNoCallback(t *testing.T, callbackChan <-chan interface{}) bool {
 	}
 }
 
+func TestAggregateGoroutinesWithLimit(t *testing.T) {
+	for _, limit := range []int{0, 1, 3, 10} {
+		var (
+			lock      sync.Mutex
+			active    int
+			maxActive int
+		)
+		funcs := make([]func() error, 0)
+		for i := 0; i < 5; i++ {
+			i := i
+			funcs = append(funcs, func() error {
+				lock.Lock()
+				active++
+				if active > maxActive {
+					maxActive = active
+				}
+				lock.Unlock()
+
+				time.Sleep(10 * time.Millisecond)
+
+				lock.Lock()
+				active--
+				lock.Unlock()
+				if i%2 == 0 {
+					return fmt.Errorf("error %d", i)
+				}
+				return nil
+			})
+		}
+
+		errs := aggregateGoroutinesWithLimit(limit, funcs...)
+		assert.Equal(t, 3, len(errs.Errors()))
+		if limit > 0 {
+			assert.LessOrEqual(t, maxActive, limit)
+		}
+	}
+
+	assert.Nil(t, aggregateGoroutinesWithLimit(2))
+}
+
 func TestReconcileTags(t *testing.T) {
 	for _, testCase := range []struct {
 		description, systemTags                      string
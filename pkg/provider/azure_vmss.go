@@ -17,6 +17,7 @@ limitations under the License.
 package provider
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"regexp"
@@ -44,25 +45,30 @@ import (
 var (
 	// ErrorNotVmssInstance indicates an instance is not belonging to any vmss.
 	ErrorNotVmssInstance = errors.New("not a vmss instance")
+	// errVMSSVMDeleting indicates the vmss instance is under deleting, it is also a cloudprovider.InstanceNotFound.
+	errVMSSVMDeleting = fmt.Errorf("%w: the vmss instance is under deleting", cloudprovider.InstanceNotFound)
 
 	scaleSetNameRE         = regexp.MustCompile(`.*/subscriptions/(?:.*)/Microsoft.Compute/virtualMachineScaleSets/(.+)/virtualMachines(?:.*)`)
 	resourceGroupRE        = regexp.MustCompile(`.*/subscriptions/(?:.*)/resourceGroups/(.+)/providers/Microsoft.Compute/virtualMachineScaleSets/(?:.*)/virtualMachines(?:.*)`)
 	vmssIPConfigurationRE  = regexp.MustCompile(`.*/subscriptions/(?:.*)/resourceGroups/(.+)/providers/Microsoft.Compute/virtualMachineScaleSets/(.+)/virtualMachines/(.+)/networkInterfaces(?:.*)`)
 	vmssPIPConfigurationRE = regexp.MustCompile(`.*/subscriptions/(?:.*)/resourceGroups/(.+)/providers/Microsoft.Compute/virtualMachineScaleSets/(.+)/virtualMachines/(.+)/networkInterfaces/(.+)/ipConfigurations/(.+)/publicIPAddresses/(.+)`)
 	vmssVMProviderIDRE     = regexp.MustCompile(`azure:///subscriptions/(?:.*)/resourceGroups/(.+)/providers/Microsoft.Compute/virtualMachineScaleSets/(.+)/virtualMachines/(?:\d+)`)
+	vmssVMResourceIDRE     = regexp.MustCompile(`(?i)^/subscriptions/([^/]+)/resourceGroups/([^/]+)/providers/Microsoft.Compute/virtualMachineScaleSets/([^/]+)/virtualMachines/([^/]+)$`)
 )
 
 // vmssMetaInfo contains the metadata for a VMSS.
 type vmssMetaInfo struct {
-	vmssName      string
-	resourceGroup string
+	vmssName       string
+	subscriptionID string
+	resourceGroup  string
 }
 
 // nodeIdentity identifies a node within a subscription.
 type nodeIdentity struct {
-	resourceGroup string
-	vmssName      string
-	nodeName      string
+	subscriptionID string
+	resourceGroup  string
+	vmssName       string
+	nodeName       string
 }
 
 // ScaleSet implements VMSet interface for Azure scale set.
@@ -75,7 +81,7 @@ type ScaleSet struct {
 	availabilitySet VMSet
 
 	vmssCache                 *azcache.TimedCache
-	vmssVMCache               *sync.Map // [resourcegroup/vmssname]*azcache.TimedCache
+	vmssVMCache               *sync.Map // [subscriptionid/resourcegroup/vmssname]*azcache.TimedCache
 	availabilitySetNodesCache *azcache.TimedCache
 	// lockMap in cache refresh
 	lockMap *lockMap
@@ -114,23 +120,27 @@ func newScaleSet(az *Cloud) (VMSet, error) {
 	return ss, nil
 }
 
-func (ss *ScaleSet) getVMSS(vmssName string, crt azcache.AzureCacheReadType) (*compute.VirtualMachineScaleSet, error) {
-	getter := func(vmssName string) (*compute.VirtualMachineScaleSet, error) {
+// getVMSS gets the VMSS in the subscription and the resource group from the cache, the subscription in the
+// cloud config is used if subscriptionID is empty.
+func (ss *ScaleSet) getVMSS(subscriptionID, resourceGroup, vmssName string, crt azcache.AzureCacheReadType) (*compute.VirtualMachineScaleSet, error) {
+	if subscriptionID == "" {
+		subscriptionID = ss.SubscriptionID
+	}
+	vmssResourceID := getVMSSResourceID(subscriptionID, resourceGroup, vmssName)
+	getter := func(crt azcache.AzureCacheReadType) (*compute.VirtualMachineScaleSet, error) {
 		cached, err := ss.vmssCache.Get(consts.VMSSKey, crt)
 		if err != nil {
 			return nil, err
 		}
 
 		vmsses := cached.(*sync.Map)
-		if vmss, ok := vmsses.Load(vmssName); ok {
-			result := vmss.(*vmssEntry)
-			return result.vmss, nil
+		if v, ok := vmsses.Load(vmssResourceID); ok {
+			return v.(*vmssEntry).vmss, nil
 		}
-
 		return nil, nil
 	}
 
-	vmss, err := getter(vmssName)
+	vmss, err := getter(crt)
 	if err != nil {
 		return nil, err
 	}
@@ -138,9 +148,9 @@ func (ss *ScaleSet) getVMSS(vmssName string, crt azcache.AzureCacheReadType) (*c
 		return vmss, nil
 	}
 
-	klog.V(2).Infof("Couldn't find VMSS with name %s, refreshing the cache", vmssName)
+	klog.V(2).Infof("Couldn't find VMSS %s in resource group %s of subscription %s, refreshing the cache", vmssName, resourceGroup, subscriptionID)
 	_ = ss.vmssCache.Delete(consts.VMSSKey)
-	vmss, err = getter(vmssName)
+	vmss, err = getter(crt)
 	if err != nil {
 		return nil, err
 	}
@@ -154,27 +164,38 @@ func (ss *ScaleSet) getVMSS(vmssName string, crt azcache.AzureCacheReadType) (*c
 // getVmssVMByNodeIdentity find virtualMachineScaleSetVM by nodeIdentity, using node's parent VMSS cache.
 // Returns cloudprovider.InstanceNotFound if the node does not belong to the scale set named in nodeIdentity.
 func (ss *ScaleSet) getVmssVMByNodeIdentity(node *nodeIdentity, crt azcache.AzureCacheReadType) (*virtualmachine.VirtualMachine, error) {
-	cacheKey, cache, err := ss.getVMSSVMCache(node.resourceGroup, node.vmssName)
+	cacheKey, cache, err := ss.getVMSSVMCache(node.subscriptionID, node.resourceGroup, node.vmssName)
 	if err != nil {
 		return nil, err
 	}
 
+	deleting := false
 	getter := func(nodeName string, crt azcache.AzureCacheReadType) (*virtualmachine.VirtualMachine, bool, error) {
 		var found bool
 		cached, err := cache.Get(cacheKey, crt)
 		if err != nil {
 			return nil, found, err
 		}
+		// the VMSS is cached as not found
+		if cached == nil {
+			return nil, found, cloudprovider.InstanceNotFound
+		}
 
 		virtualMachines := cached.(*sync.Map)
 		if entry, ok := virtualMachines.Load(nodeName); ok {
 			result := entry.(*vmssVirtualMachinesEntry)
 			if result.virtualMachine == nil {
+				// There is no need to refresh the cache for the VM under deleting.
+				if isVMSSVMDeleting(result.provisioningState) {
+					deleting = true
+					return nil, true, nil
+				}
 				klog.Warningf("failed to get VM with vmssVirtualMachinesEntry on Node %q", nodeName)
 				return nil, false, nil
 			}
 			found = true
-			return virtualmachine.FromVirtualMachineScaleSetVM(result.virtualMachine, virtualmachine.ByVMSS(result.vmssName)), found, nil
+			return virtualmachine.FromVirtualMachineScaleSetVM(result.virtualMachine, virtualmachine.ByVMSS(result.vmssName),
+				virtualmachine.InResourceGroup(result.subscriptionID, result.resourceGroup)), found, nil
 		}
 
 		return nil, found, nil
@@ -212,6 +233,10 @@ func (ss *ScaleSet) getVmssVMByNodeIdentity(node *nodeIdentity, crt azcache.Azur
 		return vm, nil
 	}
 
+	if deleting {
+		return nil, errVMSSVMDeleting
+	}
+
 	if !found || vm == nil {
 		klog.Warningf("Unable to find node %s: %v", node.nodeName, cloudprovider.InstanceNotFound)
 		return nil, cloudprovider.InstanceNotFound
@@ -248,15 +273,8 @@ func (ss *ScaleSet) GetPowerStatusByNodeName(name string) (powerState string, er
 	}
 
 	if vm.IsVirtualMachineScaleSetVM() {
-		v := vm.AsVirtualMachineScaleSetVM()
-		if v.InstanceView != nil && v.InstanceView.Statuses != nil {
-			statuses := *v.InstanceView.Statuses
-			for _, status := range statuses {
-				state := to.String(status.Code)
-				if strings.HasPrefix(state, vmPowerStatePrefix) {
-					return strings.TrimPrefix(state, vmPowerStatePrefix), nil
-				}
-			}
+		if powerState := getVMSSVMPowerState(vm.AsVirtualMachineScaleSetVM()); powerState != "" {
+			return powerState, nil
 		}
 	}
 
@@ -265,6 +283,77 @@ func (ss *ScaleSet) GetPowerStatusByNodeName(name string) (powerState string, er
 	return vmPowerStateStopped, nil
 }
 
+// getVMSSVMPowerState returns the power state of the VMSS VM from its instance view.
+// It returns an empty string if the instance view is not available.
+func getVMSSVMPowerState(vm *compute.VirtualMachineScaleSetVM) string {
+	if vm == nil || vm.VirtualMachineScaleSetVMProperties == nil ||
+		vm.InstanceView == nil || vm.InstanceView.Statuses == nil {
+		return ""
+	}
+
+	for _, status := range *vm.InstanceView.Statuses {
+		state := to.String(status.Code)
+		if strings.HasPrefix(state, vmPowerStatePrefix) {
+			return strings.TrimPrefix(state, vmPowerStatePrefix)
+		}
+	}
+	return ""
+}
+
+// isVMSSVMDeallocated returns true if the VMSS VM is deallocated or being deallocated.
+func isVMSSVMDeallocated(vm *compute.VirtualMachineScaleSetVM) bool {
+	powerState := strings.ToLower(getVMSSVMPowerState(vm))
+	return powerState == vmPowerStateDeallocated || powerState == vmPowerStateDeallocating
+}
+
+// isVMSSVMDeleting returns true if the provisioning state of the VMSS VM is Deleting.
+func isVMSSVMDeleting(provisioningState string) bool {
+	return strings.EqualFold(provisioningState, string(compute.ProvisioningStateDeleting))
+}
+
+// isVMSSVMProvisioningFailed returns true if the provisioning state of the VMSS VM is Failed.
+func isVMSSVMProvisioningFailed(vm *compute.VirtualMachineScaleSetVM) bool {
+	return vm != nil && vm.VirtualMachineScaleSetVMProperties != nil &&
+		strings.EqualFold(to.String(vm.VirtualMachineScaleSetVMProperties.ProvisioningState), string(compute.ProvisioningStateFailed))
+}
+
+// skipVMSSVM logs and counts the VMSS VM skipped by the operation because of its provisioning state.
+func skipVMSSVM(operation, name, provisioningState string) {
+	klog.V(3).Infof("%s skips vmss instance %s because its provisioning state is %s", operation, name, provisioningState)
+	metrics.CountSkippedVMSSVM(operation, provisioningState)
+}
+
+// isSpotVMSSVMEvicted returns true if the VMSS VM belongs to a Spot scale set and
+// has been deallocated, which is what happens when Azure evicts a Spot instance.
+// The model of an evicted instance can't be updated until it is restarted, so
+// callers should skip it instead of sending UpdateVMs requests.
+func (ss *ScaleSet) isSpotVMSSVMEvicted(vm *virtualmachine.VirtualMachine) bool {
+	if !isVMSSVMDeallocated(vm.AsVirtualMachineScaleSetVM()) {
+		return false
+	}
+
+	vmss, err := ss.getVMSS(vm.SubscriptionID, vm.ResourceGroup, vm.VMSSName, azcache.CacheReadTypeDefault)
+	if err != nil {
+		klog.Warningf("isSpotVMSSVMEvicted: failed to get VMSS %s: %v", vm.VMSSName, err)
+		return false
+	}
+	if vmss.VirtualMachineScaleSetProperties == nil || vmss.VirtualMachineProfile == nil {
+		return false
+	}
+
+	return strings.EqualFold(string(vmss.VirtualMachineProfile.Priority), string(compute.VirtualMachinePriorityTypesSpot))
+}
+
+// isSpotNodeEvicted returns true if the node is an evicted Spot VMSS instance.
+func (ss *ScaleSet) isSpotNodeEvicted(nodeName string) bool {
+	vm, err := ss.getVmssVM(nodeName, azcache.CacheReadTypeDefault)
+	if err != nil || !vm.IsVirtualMachineScaleSetVM() {
+		return false
+	}
+
+	return ss.isSpotVMSSVMEvicted(vm)
+}
+
 // GetProvisioningStateByNodeName returns the provisioningState for the specified node.
 func (ss *ScaleSet) GetProvisioningStateByNodeName(name string) (provisioningState string, err error) {
 	managedByAS, err := ss.isNodeManagedByAvailabilitySet(name, azcache.CacheReadTypeUnsafe)
@@ -290,18 +379,23 @@ func (ss *ScaleSet) GetProvisioningStateByNodeName(name string) (provisioningSta
 }
 
 // getCachedVirtualMachineByInstanceID gets scaleSetVMInfo from cache.
-// The node must belong to one of scale sets.
-func (ss *ScaleSet) getVmssVMByInstanceID(resourceGroup, scaleSetName, instanceID string, crt azcache.AzureCacheReadType) (*compute.VirtualMachineScaleSetVM, error) {
-	cacheKey, cache, err := ss.getVMSSVMCache(resourceGroup, scaleSetName)
+// The node must belong to one of scale sets. An empty subscriptionID refers to the subscription in the cloud config.
+func (ss *ScaleSet) getVmssVMByInstanceID(subscriptionID, resourceGroup, scaleSetName, instanceID string, crt azcache.AzureCacheReadType) (*compute.VirtualMachineScaleSetVM, error) {
+	cacheKey, cache, err := ss.getVMSSVMCache(subscriptionID, resourceGroup, scaleSetName)
 	if err != nil {
 		return nil, err
 	}
 
+	var provisioningState string
 	getter := func(crt azcache.AzureCacheReadType) (vm *compute.VirtualMachineScaleSetVM, found bool, err error) {
 		cached, err := cache.Get(cacheKey, crt)
 		if err != nil {
 			return nil, false, err
 		}
+		// the VMSS is cached as not found
+		if cached == nil {
+			return nil, false, cloudprovider.InstanceNotFound
+		}
 
 		virtualMachines := cached.(*sync.Map)
 		virtualMachines.Range(func(key, value interface{}) bool {
@@ -310,6 +404,7 @@ func (ss *ScaleSet) getVmssVMByInstanceID(resourceGroup, scaleSetName, instanceI
 				strings.EqualFold(vmEntry.vmssName, scaleSetName) &&
 				strings.EqualFold(vmEntry.instanceID, instanceID) {
 				vm = vmEntry.virtualMachine
+				provisioningState = vmEntry.provisioningState
 				found = true
 				return false
 			}
@@ -341,6 +436,9 @@ func (ss *ScaleSet) getVmssVMByInstanceID(resourceGroup, scaleSetName, instanceI
 			return nil, err
 		}
 	}
+	if found && vm == nil && isVMSSVMDeleting(provisioningState) {
+		return nil, errVMSSVMDeleting
+	}
 	if !found || vm == nil {
 		return nil, cloudprovider.InstanceNotFound
 	}
@@ -391,7 +489,7 @@ func (ss *ScaleSet) GetNodeNameByProviderID(providerID string) (types.NodeName,
 		return ss.availabilitySet.GetNodeNameByProviderID(providerID)
 	}
 
-	resourceGroup, err := extractResourceGroupByProviderID(providerID)
+	subscriptionID, resourceGroup, err := extractSubscriptionAndResourceGroupByProviderID(providerID)
 	if err != nil {
 		return "", fmt.Errorf("error of extracting resource group for node %q", providerID)
 	}
@@ -410,8 +508,13 @@ func (ss *ScaleSet) GetNodeNameByProviderID(providerID string) (types.NodeName,
 		}
 	}
 
-	vm, err := ss.getVmssVMByInstanceID(resourceGroup, scaleSetName, instanceID, azcache.CacheReadTypeUnsafe)
+	vm, err := ss.getVmssVMByInstanceID(subscriptionID, resourceGroup, scaleSetName, instanceID, azcache.CacheReadTypeUnsafe)
 	if err != nil {
+		// Return InstanceNotFound for the instance under deleting so that the node controller can proceed.
+		if errors.Is(err, errVMSSVMDeleting) {
+			skipVMSSVM("GetNodeNameByProviderID", providerID, string(compute.ProvisioningStateDeleting))
+			return "", cloudprovider.InstanceNotFound
+		}
 		klog.Errorf("Unable to find node by providerID %s: %v", providerID, err)
 		return "", err
 	}
@@ -659,7 +762,7 @@ func (ss *ScaleSet) listScaleSets(resourceGroup string) ([]string, error) {
 	ctx, cancel := getContextWithCancel()
 	defer cancel()
 
-	allScaleSets, rerr := ss.VirtualMachineScaleSetsClient.List(ctx, resourceGroup)
+	allScaleSets, rerr := ss.VirtualMachineScaleSetsClient.List(ctx, "", resourceGroup)
 	if rerr != nil {
 		klog.Errorf("VirtualMachineScaleSetsClient.List failed: %v", rerr)
 		return nil, rerr.Error()
@@ -712,6 +815,7 @@ func (ss *ScaleSet) getNodeIdentityByNodeName(nodeName string, crt azcache.Azure
 
 			if strings.EqualFold(vmssPrefix, nodeName[:len(nodeName)-6]) {
 				node.vmssName = *v.vmss.Name
+				node.subscriptionID = v.subscriptionID
 				node.resourceGroup = v.resourceGroup
 				return false
 			}
@@ -747,13 +851,13 @@ func (ss *ScaleSet) getNodeIdentityByNodeName(nodeName string, crt azcache.Azure
 }
 
 // listScaleSetVMs lists VMs belonging to the specified scale set.
-func (ss *ScaleSet) listScaleSetVMs(scaleSetName, resourceGroup string) ([]compute.VirtualMachineScaleSetVM, error) {
+func (ss *ScaleSet) listScaleSetVMs(subscriptionID, scaleSetName, resourceGroup string) ([]compute.VirtualMachineScaleSetVM, error) {
 	ctx, cancel := getContextWithCancel()
 	defer cancel()
 
-	allVMs, rerr := ss.VirtualMachineScaleSetVMsClient.List(ctx, resourceGroup, scaleSetName, string(compute.InstanceViewTypesInstanceView))
+	allVMs, rerr := ss.VirtualMachineScaleSetVMsClient.List(ctx, subscriptionID, resourceGroup, scaleSetName, string(compute.InstanceViewTypesInstanceView))
 	if rerr != nil {
-		klog.Errorf("VirtualMachineScaleSetVMsClient.List(%s, %s) failed: %v", resourceGroup, scaleSetName, rerr)
+		klog.Errorf("VirtualMachineScaleSetVMsClient.List(%s, %s, %s) failed: %v", subscriptionID, resourceGroup, scaleSetName, rerr)
 		if rerr.IsNotFound() {
 			return nil, cloudprovider.InstanceNotFound
 		}
@@ -984,10 +1088,18 @@ func (ss *ScaleSet) getConfigForScaleSetByIPFamily(config *compute.VirtualMachin
 
 // EnsureHostInPool ensures the given VM's Primary NIC's Primary IP Configuration is
 // participating in the specified LoadBalancer Backend Pool, which returns (resourceGroup, vmasName, instanceID, vmssVM, error).
-func (ss *ScaleSet) EnsureHostInPool(service *v1.Service, nodeName types.NodeName, backendPoolID string, vmSetNameOfLB string) (string, string, string, *compute.VirtualMachineScaleSetVM, error) {
+func (ss *ScaleSet) EnsureHostInPool(ctx context.Context, service *v1.Service, nodeName types.NodeName, backendPoolID string, vmSetNameOfLB string) (string, string, string, *compute.VirtualMachineScaleSetVM, error) {
 	vmName := mapNodeNameToVMName(nodeName)
-	vm, err := ss.getVmssVM(vmName, azcache.CacheReadTypeDefault)
+	var vm *virtualmachine.VirtualMachine
+	node, err := ss.getNodeIdentityByNodeName(vmName, azcache.CacheReadTypeDefault)
+	if err == nil {
+		vm, err = ss.getVmssVMByNodeIdentity(node, azcache.CacheReadTypeDefault)
+	}
 	if err != nil {
+		if errors.Is(err, errVMSSVMDeleting) {
+			skipVMSSVM("EnsureHostInPool", vmName, string(compute.ProvisioningStateDeleting))
+			return "", "", "", nil, nil
+		}
 		if errors.Is(err, cloudprovider.InstanceNotFound) {
 			klog.Infof("EnsureHostInPool: skipping node %s because it is not found", vmName)
 			return "", "", "", nil, nil
@@ -997,6 +1109,12 @@ func (ss *ScaleSet) EnsureHostInPool(service *v1.Service, nodeName types.NodeNam
 		return "", "", "", nil, err
 	}
 
+	// The VM in Failed provisioning state can't be updated until it is recovered.
+	if isVMSSVMProvisioningFailed(vm.AsVirtualMachineScaleSetVM()) {
+		skipVMSSVM("EnsureHostInPool", vmName, string(compute.ProvisioningStateFailed))
+		return "", "", "", nil, nil
+	}
+
 	klog.V(2).Infof("ensuring node %q of scaleset %q in LB backendpool %q", nodeName, vm.VMSSName, backendPoolID)
 
 	// Check scale set name:
@@ -1026,8 +1144,16 @@ func (ss *ScaleSet) EnsureHostInPool(service *v1.Service, nodeName types.NodeNam
 		return "", "", "", nil, nil
 	}
 
+	// The model of an evicted spot instance can't be updated. Its backend pools are kept
+	// in the model, so the instance rejoins the LB backend pool once it is restarted.
+	if ss.isSpotVMSSVMEvicted(vm) {
+		klog.V(3).Infof("EnsureHostInPool skips node %s because it is an evicted spot instance", vmName)
+		return "", "", "", nil, nil
+	}
+
 	// Find primary network interface configuration.
-	if vm.VirtualMachineScaleSetVMProperties.NetworkProfileConfiguration.NetworkInterfaceConfigurations == nil {
+	if vm.VirtualMachineScaleSetVMProperties.NetworkProfileConfiguration == nil ||
+		vm.VirtualMachineScaleSetVMProperties.NetworkProfileConfiguration.NetworkInterfaceConfigurations == nil {
 		klog.V(4).Infof("EnsureHostInPool: cannot obtain the primary network interface configuration, of vm %s, "+
 			"probably because the vm's being deleted", vmName)
 		return "", "", "", nil, nil
@@ -1113,13 +1239,7 @@ func (ss *ScaleSet) EnsureHostInPool(service *v1.Service, nodeName types.NodeNam
 		},
 	}
 
-	// Get the node resource group.
-	nodeResourceGroup, err := ss.GetNodeResourceGroup(vmName)
-	if err != nil {
-		return "", "", "", nil, err
-	}
-
-	return nodeResourceGroup, vm.VMSSName, vm.InstanceID, newVM, nil
+	return node.resourceGroup, vm.VMSSName, vm.InstanceID, newVM, nil
 }
 
 func getVmssAndResourceGroupNameByVMProviderID(providerID string) (string, string, error) {
@@ -1130,9 +1250,13 @@ func getVmssAndResourceGroupNameByVMProviderID(providerID string) (string, strin
 	return matches[1], matches[2], nil
 }
 
-func (ss *ScaleSet) ensureVMSSInPool(service *v1.Service, nodes []*v1.Node, backendPoolID string, vmSetNameOfLB string) error {
+func (ss *ScaleSet) ensureVMSSInPool(ctx context.Context, service *v1.Service, nodes []*v1.Node, backendPoolID string, vmSetNameOfLB string) error {
 	klog.V(2).Infof("ensureVMSSInPool: ensuring VMSS with backendPoolID %s", backendPoolID)
-	vmssNamesMap := make(map[string]bool)
+	// scaleSet is a VMSS in the resource group of the cloud config, which could be in another subscription
+	type scaleSet struct {
+		subscriptionID, name string
+	}
+	vmssNamesMap := make(map[scaleSet]bool)
 
 	// the single standard load balancer supports multiple vmss in its backend while
 	// multiple standard load balancers and the basic load balancer doesn't
@@ -1158,18 +1282,24 @@ func (ss *ScaleSet) ensureVMSSInPool(service *v1.Service, nodes []*v1.Node, back
 				klog.V(4).Infof("ensureVMSSInPool: found VMAS node %s, will skip checking and continue", node.Name)
 				continue
 			}
+			subscriptionID, _, err := extractSubscriptionAndResourceGroupByProviderID(node.Spec.ProviderID)
+			if err != nil {
+				klog.V(4).Infof("ensureVMSSInPool: failed to get the subscription of node %s, will skip checking and continue", node.Name)
+				continue
+			}
 			// only vmsses in the resource group same as it's in azure config are included
 			if strings.EqualFold(resourceGroupName, ss.ResourceGroup) {
-				vmssNamesMap[vmssName] = true
+				vmssNamesMap[scaleSet{subscriptionID: subscriptionID, name: vmssName}] = true
 			}
 		}
 	} else {
-		vmssNamesMap[vmSetNameOfLB] = true
+		vmssNamesMap[scaleSet{subscriptionID: ss.SubscriptionID, name: vmSetNameOfLB}] = true
 	}
 
 	klog.V(2).Infof("ensureVMSSInPool begins to update VMSS %v with backendPoolID %s", vmssNamesMap, backendPoolID)
-	for vmssName := range vmssNamesMap {
-		vmss, err := ss.getVMSS(vmssName, azcache.CacheReadTypeDefault)
+	for vmssKey := range vmssNamesMap {
+		subscriptionID, vmssName := vmssKey.subscriptionID, vmssKey.name
+		vmss, err := ss.getVMSS(subscriptionID, ss.ResourceGroup, vmssName, azcache.CacheReadTypeDefault)
 		if err != nil {
 			return err
 		}
@@ -1261,7 +1391,7 @@ func (ss *ScaleSet) ensureVMSSInPool(service *v1.Service, nodes []*v1.Node, back
 		}
 
 		klog.V(2).Infof("ensureVMSSInPool begins to update vmss(%s) with new backendPoolID %s", vmssName, backendPoolID)
-		rerr := ss.CreateOrUpdateVMSS(ss.ResourceGroup, vmssName, newVMSS)
+		rerr := ss.CreateOrUpdateVMSS(ctx, subscriptionID, ss.ResourceGroup, vmssName, newVMSS)
 		if rerr != nil {
 			klog.Errorf("ensureVMSSInPool CreateOrUpdateVMSS(%s) with new backendPoolID %s, err: %v", vmssName, backendPoolID, err)
 			return rerr.Error()
@@ -1272,8 +1402,8 @@ func (ss *ScaleSet) ensureVMSSInPool(service *v1.Service, nodes []*v1.Node, back
 
 // EnsureHostsInPool ensures the given Node's primary IP configurations are
 // participating in the specified LoadBalancer Backend Pool.
-func (ss *ScaleSet) EnsureHostsInPool(service *v1.Service, nodes []*v1.Node, backendPoolID string, vmSetNameOfLB string) error {
-	mc := metrics.NewMetricContext("services", "vmss_ensure_hosts_in_pool", ss.ResourceGroup, ss.SubscriptionID, getServiceName(service))
+func (ss *ScaleSet) EnsureHostsInPool(ctx context.Context, service *v1.Service, nodes []*v1.Node, backendPoolID string, vmSetNameOfLB string) error {
+	mc := metrics.NewMetricContext("services", "vmss_ensure_hosts_in_pool", ss.ResourceGroup, ss.SubscriptionID, getServiceName(service)).WithContext(ctx)
 	isOperationSucceeded := false
 	defer func() {
 		mc.ObserveOperationWithResult(isOperationSucceeded)
@@ -1312,7 +1442,7 @@ func (ss *ScaleSet) EnsureHostsInPool(service *v1.Service, nodes []*v1.Node, bac
 			// VMAS nodes should also be added to the SLB backends.
 			if ss.useStandardLoadBalancer() {
 				hostUpdates = append(hostUpdates, func() error {
-					_, _, _, _, err := ss.availabilitySet.EnsureHostInPool(service, types.NodeName(localNodeName), backendPoolID, vmSetNameOfLB)
+					_, _, _, _, err := ss.availabilitySet.EnsureHostInPool(ctx, service, types.NodeName(localNodeName), backendPoolID, vmSetNameOfLB)
 					return err
 				})
 				continue
@@ -1322,7 +1452,7 @@ func (ss *ScaleSet) EnsureHostsInPool(service *v1.Service, nodes []*v1.Node, bac
 			continue
 		}
 
-		nodeResourceGroup, nodeVMSS, nodeInstanceID, nodeVMSSVM, err := ss.EnsureHostInPool(service, types.NodeName(localNodeName), backendPoolID, vmSetNameOfLB)
+		nodeResourceGroup, nodeVMSS, nodeInstanceID, nodeVMSSVM, err := ss.EnsureHostInPool(ctx, service, types.NodeName(localNodeName), backendPoolID, vmSetNameOfLB)
 		if err != nil {
 			klog.Errorf("EnsureHostInPool(%s): backendPoolID(%s) - failed to ensure host in pool: %q", getServiceName(service), backendPoolID, err)
 			errors = append(errors, err)
@@ -1334,7 +1464,10 @@ func (ss *ScaleSet) EnsureHostsInPool(service *v1.Service, nodes []*v1.Node, bac
 			continue
 		}
 
-		nodeVMSSMetaInfo := vmssMetaInfo{vmssName: nodeVMSS, resourceGroup: nodeResourceGroup}
+		// The node pool could be in a different subscription from the cloud config
+		// (and from the backend pool), which is resolved from the node's providerID.
+		nodeSubscriptionID, _, _ := extractSubscriptionAndResourceGroupByProviderID(node.Spec.ProviderID)
+		nodeVMSSMetaInfo := vmssMetaInfo{vmssName: nodeVMSS, subscriptionID: nodeSubscriptionID, resourceGroup: nodeResourceGroup}
 		if v, ok := nodeUpdates[nodeVMSSMetaInfo]; ok {
 			v[nodeInstanceID] = *nodeVMSSVM
 		} else {
@@ -1350,36 +1483,41 @@ func (ss *ScaleSet) EnsureHostsInPool(service *v1.Service, nodes []*v1.Node, bac
 	}
 
 	// Update VMs with best effort that have already been added to nodeUpdates.
+	// The instances of each scale set are still updated in batches by UpdateVMs,
+	// while the scale sets are updated concurrently with a bounded concurrency.
+	vmssUpdates := make([]func() error, 0, len(nodeUpdates))
 	for meta, update := range nodeUpdates {
 		// create new instance of meta and update for passing to anonymous function
 		meta := meta
 		update := update
-		hostUpdates = append(hostUpdates, func() error {
-			ctx, cancel := getContextWithCancel()
-			defer cancel()
-			klog.V(2).Infof("EnsureHostInPool begins to UpdateVMs for VMSS(%s, %s) with new backendPoolID %s", meta.resourceGroup, meta.vmssName, backendPoolID)
-			rerr := ss.VirtualMachineScaleSetVMsClient.UpdateVMs(ctx, meta.resourceGroup, meta.vmssName, update, "network_update", ss.getPutVMSSVMBatchSize())
+		vmssUpdates = append(vmssUpdates, func() error {
+			klog.V(2).Infof("EnsureHostInPool begins to UpdateVMs for VMSS(%s, %s, %s) with new backendPoolID %s", meta.subscriptionID, meta.resourceGroup, meta.vmssName, backendPoolID)
+			rerr := ss.VirtualMachineScaleSetVMsClient.UpdateVMs(ctx, meta.subscriptionID, meta.resourceGroup, meta.vmssName, update, "network_update", ss.getPutVMSSVMBatchSize())
 			if rerr != nil {
-				klog.Errorf("EnsureHostInPool UpdateVMs for VMSS(%s, %s) failed with error %v", meta.resourceGroup, meta.vmssName, rerr.Error())
-				return rerr.Error()
+				klog.Errorf("EnsureHostInPool UpdateVMs for VMSS(%s, %s, %s) failed with error %v", meta.subscriptionID, meta.resourceGroup, meta.vmssName, rerr.Error())
+				return fmt.Errorf("vmss %s: %w", meta.vmssName, rerr.Error())
 			}
 
 			return nil
 		})
 	}
-	errs := utilerrors.AggregateGoroutines(hostUpdates...)
-	if errs != nil {
-		return utilerrors.Flatten(errs)
+	// The VMAS and VMSS nodes are updated independently, so a failure of one group
+	// should not block the other.
+	if errs := utilerrors.AggregateGoroutines(hostUpdates...); errs != nil {
+		errors = append(errors, utilerrors.Flatten(errs).Errors()...)
+	}
+	if errs := aggregateGoroutinesWithLimit(ss.getEnsureHostsInPoolConcurrency(), vmssUpdates...); errs != nil {
+		errors = append(errors, utilerrors.Flatten(errs).Errors()...)
 	}
 
-	// Fail if there are other errors.
+	// Fail if there are any errors.
 	if len(errors) > 0 {
 		return utilerrors.Flatten(utilerrors.NewAggregate(errors))
 	}
 
 	// Ensure the backendPoolID is also added on VMSS itself.
 	// Refer to issue kubernetes/kubernetes#80365 for detailed information
-	err := ss.ensureVMSSInPool(service, nodes, backendPoolID, vmSetNameOfLB)
+	err := ss.ensureVMSSInPool(ctx, service, nodes, backendPoolID, vmSetNameOfLB)
 	if err != nil {
 		return err
 	}
@@ -1391,7 +1529,11 @@ func (ss *ScaleSet) EnsureHostsInPool(service *v1.Service, nodes []*v1.Node, bac
 // ensureBackendPoolDeletedFromNode ensures the loadBalancer backendAddressPools deleted
 // from the specified node, which returns (resourceGroup, vmasName, instanceID, vmssVM, error).
 func (ss *ScaleSet) ensureBackendPoolDeletedFromNode(nodeName, backendPoolID string) (string, string, string, *compute.VirtualMachineScaleSetVM, error) {
-	vm, err := ss.getVmssVM(nodeName, azcache.CacheReadTypeDefault)
+	var vm *virtualmachine.VirtualMachine
+	node, err := ss.getNodeIdentityByNodeName(nodeName, azcache.CacheReadTypeDefault)
+	if err == nil {
+		vm, err = ss.getVmssVMByNodeIdentity(node, azcache.CacheReadTypeDefault)
+	}
 	if err != nil {
 		if errors.Is(err, cloudprovider.InstanceNotFound) {
 			klog.Infof("ensureBackendPoolDeletedFromNode: skipping node %s because it is not found", nodeName)
@@ -1401,8 +1543,15 @@ func (ss *ScaleSet) ensureBackendPoolDeletedFromNode(nodeName, backendPoolID str
 		return "", "", "", nil, err
 	}
 
+	// Skip evicted spot instances since their models can't be updated.
+	if ss.isSpotVMSSVMEvicted(vm) {
+		klog.V(3).Infof("ensureBackendPoolDeletedFromNode skips node %s because it is an evicted spot instance", nodeName)
+		return "", "", "", nil, nil
+	}
+
 	// Find primary network interface configuration.
-	if vm.VirtualMachineScaleSetVMProperties.NetworkProfileConfiguration.NetworkInterfaceConfigurations == nil {
+	if vm.VirtualMachineScaleSetVMProperties.NetworkProfileConfiguration == nil ||
+		vm.VirtualMachineScaleSetVMProperties.NetworkProfileConfiguration.NetworkInterfaceConfigurations == nil {
 		klog.V(4).Infof("EnsureHostInPool: cannot obtain the primary network interface configuration, of vm %s, "+
 			"probably because the vm's being deleted", nodeName)
 		return "", "", "", nil, nil
@@ -1452,13 +1601,7 @@ func (ss *ScaleSet) ensureBackendPoolDeletedFromNode(nodeName, backendPoolID str
 		},
 	}
 
-	// Get the node resource group.
-	nodeResourceGroup, err := ss.GetNodeResourceGroup(nodeName)
-	if err != nil {
-		return "", "", "", nil, err
-	}
-
-	return nodeResourceGroup, vm.VMSSName, vm.InstanceID, newVM, nil
+	return node.resourceGroup, vm.VMSSName, vm.InstanceID, newVM, nil
 }
 
 // GetNodeNameByIPConfigurationID gets the node name and the VMSS name by IP configuration ID.
@@ -1474,10 +1617,12 @@ func (ss *ScaleSet) GetNodeNameByIPConfigurationID(ipConfigurationID string) (st
 		return name, rg, nil
 	}
 
+	// The subscription of the VMSS could be different from the one in the cloud config.
+	subscriptionID, _, _ := extractSubscriptionAndResourceGroupByProviderID(ipConfigurationID)
 	resourceGroup := matches[1]
 	scaleSetName := matches[2]
 	instanceID := matches[3]
-	vm, err := ss.getVmssVMByInstanceID(resourceGroup, scaleSetName, instanceID, azcache.CacheReadTypeUnsafe)
+	vm, err := ss.getVmssVMByInstanceID(subscriptionID, resourceGroup, scaleSetName, instanceID, azcache.CacheReadTypeUnsafe)
 	if err != nil {
 		klog.Errorf("Unable to find node by ipConfigurationID %s: %v", ipConfigurationID, err)
 		return "", "", err
@@ -1502,7 +1647,7 @@ func getScaleSetAndResourceGroupNameByIPConfigurationID(ipConfigurationID string
 	return scaleSetName, resourceGroup, nil
 }
 
-func (ss *ScaleSet) ensureBackendPoolDeletedFromVMSS(service *v1.Service, backendPoolID, vmSetName string, ipConfigurationIDs []string) error {
+func (ss *ScaleSet) ensureBackendPoolDeletedFromVMSS(ctx context.Context, service *v1.Service, backendPoolID, vmSetName string, ipConfigurationIDs []string) error {
 	vmssNamesMap := make(map[string]bool)
 
 	// the standard load balancer supports multiple vmss in its backend while the basic sku doesn't
@@ -1523,17 +1668,17 @@ func (ss *ScaleSet) ensureBackendPoolDeletedFromVMSS(service *v1.Service, backen
 		vmssNamesMap[vmSetName] = true
 	}
 
-	return ss.EnsureBackendPoolDeletedFromVMSets(vmssNamesMap, backendPoolID)
+	return ss.EnsureBackendPoolDeletedFromVMSets(ctx, vmssNamesMap, backendPoolID)
 }
 
 // EnsureBackendPoolDeleted ensures the loadBalancer backendAddressPools deleted from the specified nodes.
-func (ss *ScaleSet) EnsureBackendPoolDeleted(service *v1.Service, backendPoolID, vmSetName string, backendAddressPools *[]network.BackendAddressPool, deleteFromVMSet bool) error {
+func (ss *ScaleSet) EnsureBackendPoolDeleted(ctx context.Context, service *v1.Service, backendPoolID, vmSetName string, backendAddressPools *[]network.BackendAddressPool, deleteFromVMSet bool) error {
 	// Returns nil if backend address pools already deleted.
 	if backendAddressPools == nil {
 		return nil
 	}
 
-	mc := metrics.NewMetricContext("services", "vmss_ensure_backend_pool_deleted", ss.ResourceGroup, ss.SubscriptionID, getServiceName(service))
+	mc := metrics.NewMetricContext("services", "vmss_ensure_backend_pool_deleted", ss.ResourceGroup, ss.SubscriptionID, getServiceName(service)).WithContext(ctx)
 	isOperationSucceeded := false
 	defer func() {
 		mc.ObserveOperationWithResult(isOperationSucceeded)
@@ -1598,7 +1743,8 @@ func (ss *ScaleSet) EnsureBackendPoolDeleted(service *v1.Service, backendPoolID,
 			continue
 		}
 
-		nodeVMSSMetaInfo := vmssMetaInfo{vmssName: nodeVMSS, resourceGroup: nodeResourceGroup}
+		nodeSubscriptionID, _, _ := extractSubscriptionAndResourceGroupByProviderID(ipConfigurationID)
+		nodeVMSSMetaInfo := vmssMetaInfo{vmssName: nodeVMSS, subscriptionID: nodeSubscriptionID, resourceGroup: nodeResourceGroup}
 		if v, ok := nodeUpdates[nodeVMSSMetaInfo]; ok {
 			v[nodeInstanceID] = *nodeVMSSVM
 		} else {
@@ -1619,12 +1765,10 @@ func (ss *ScaleSet) EnsureBackendPoolDeleted(service *v1.Service, backendPoolID,
 		meta := meta
 		update := update
 		hostUpdates = append(hostUpdates, func() error {
-			ctx, cancel := getContextWithCancel()
-			defer cancel()
-			klog.V(2).Infof("EnsureBackendPoolDeleted begins to UpdateVMs for VMSS(%s, %s) with backendPoolID %s", meta.resourceGroup, meta.vmssName, backendPoolID)
-			rerr := ss.VirtualMachineScaleSetVMsClient.UpdateVMs(ctx, meta.resourceGroup, meta.vmssName, update, "network_update", ss.getPutVMSSVMBatchSize())
+			klog.V(2).Infof("EnsureBackendPoolDeleted begins to UpdateVMs for VMSS(%s, %s, %s) with backendPoolID %s", meta.subscriptionID, meta.resourceGroup, meta.vmssName, backendPoolID)
+			rerr := ss.VirtualMachineScaleSetVMsClient.UpdateVMs(ctx, meta.subscriptionID, meta.resourceGroup, meta.vmssName, update, "network_update", ss.getPutVMSSVMBatchSize())
 			if rerr != nil {
-				klog.Errorf("EnsureBackendPoolDeleted UpdateVMs for VMSS(%s, %s) failed with error %v", meta.resourceGroup, meta.vmssName, rerr.Error())
+				klog.Errorf("EnsureBackendPoolDeleted UpdateVMs for VMSS(%s, %s, %s) failed with error %v", meta.subscriptionID, meta.resourceGroup, meta.vmssName, rerr.Error())
 				return rerr.Error()
 			}
 
@@ -1643,7 +1787,7 @@ func (ss *ScaleSet) EnsureBackendPoolDeleted(service *v1.Service, backendPoolID,
 
 	// Ensure the backendPoolID is also deleted on VMSS itself.
 	if deleteFromVMSet {
-		err := ss.ensureBackendPoolDeletedFromVMSS(service, backendPoolID, vmSetName, ipConfigurationIDs)
+		err := ss.ensureBackendPoolDeletedFromVMSS(ctx, service, backendPoolID, vmSetName, ipConfigurationIDs)
 		if err != nil {
 			return err
 		}
@@ -1655,12 +1799,16 @@ func (ss *ScaleSet) EnsureBackendPoolDeleted(service *v1.Service, backendPoolID,
 
 // GetNodeCIDRMaskByProviderID returns the node CIDR subnet mask by provider ID.
 func (ss *ScaleSet) GetNodeCIDRMasksByProviderID(providerID string) (int, int, error) {
-	_, vmssName, err := getVmssAndResourceGroupNameByVMProviderID(providerID)
+	resourceGroup, vmssName, err := getVmssAndResourceGroupNameByVMProviderID(providerID)
+	if err != nil {
+		return 0, 0, err
+	}
+	subscriptionID, _, err := extractSubscriptionAndResourceGroupByProviderID(providerID)
 	if err != nil {
 		return 0, 0, err
 	}
 
-	vmss, err := ss.getVMSS(vmssName, azcache.CacheReadTypeDefault)
+	vmss, err := ss.getVMSS(subscriptionID, resourceGroup, vmssName, azcache.CacheReadTypeDefault)
 	if err != nil {
 		return 0, 0, err
 	}
@@ -1683,12 +1831,12 @@ func (ss *ScaleSet) GetNodeCIDRMasksByProviderID(providerID string) (int, int, e
 }
 
 //EnsureBackendPoolDeletedFromVMSets ensures the loadBalancer backendAddressPools deleted from the specified VMSS
-func (ss *ScaleSet) EnsureBackendPoolDeletedFromVMSets(vmssNamesMap map[string]bool, backendPoolID string) error {
+func (ss *ScaleSet) EnsureBackendPoolDeletedFromVMSets(ctx context.Context, vmssNamesMap map[string]bool, backendPoolID string) error {
 	vmssUpdaters := make([]func() error, 0, len(vmssNamesMap))
 	errors := make([]error, 0, len(vmssNamesMap))
 	for vmssName := range vmssNamesMap {
 		vmssName := vmssName
-		vmss, err := ss.getVMSS(vmssName, azcache.CacheReadTypeDefault)
+		vmss, err := ss.getVMSS(ss.SubscriptionID, ss.ResourceGroup, vmssName, azcache.CacheReadTypeDefault)
 		if err != nil {
 			klog.Errorf("ensureBackendPoolDeletedFromVMSS: failed to get VMSS %s: %v", vmssName, err)
 			errors = append(errors, err)
@@ -1752,7 +1900,7 @@ func (ss *ScaleSet) EnsureBackendPoolDeletedFromVMSets(vmssNamesMap map[string]b
 			}
 
 			klog.V(2).Infof("ensureBackendPoolDeletedFromVMSS begins to update vmss(%s) with backendPoolID %s", vmssName, backendPoolID)
-			rerr := ss.CreateOrUpdateVMSS(ss.ResourceGroup, vmssName, newVMSS)
+			rerr := ss.CreateOrUpdateVMSS(ctx, ss.SubscriptionID, ss.ResourceGroup, vmssName, newVMSS)
 			if rerr != nil {
 				klog.Errorf("ensureBackendPoolDeletedFromVMSS CreateOrUpdateVMSS(%s) with new backendPoolID %s, err: %v", vmssName, backendPoolID, rerr)
 				return rerr.Error()
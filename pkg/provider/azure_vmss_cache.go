@@ -18,6 +18,7 @@ package provider
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -27,6 +28,7 @@ import (
 	"github.com/Azure/go-autorest/autorest/to"
 
 	"k8s.io/apimachinery/pkg/util/sets"
+	cloudprovider "k8s.io/cloud-provider"
 	"k8s.io/klog/v2"
 
 	azcache "sigs.k8s.io/cloud-provider-azure/pkg/cache"
@@ -34,17 +36,21 @@ import (
 )
 
 type vmssVirtualMachinesEntry struct {
+	subscriptionID string
 	resourceGroup  string
 	vmssName       string
 	instanceID     string
 	virtualMachine *compute.VirtualMachineScaleSetVM
 	lastUpdate     time.Time
+	// provisioningState is the provisioning state of the VM, which is kept even if virtualMachine is nil.
+	provisioningState string
 }
 
 type vmssEntry struct {
-	vmss          *compute.VirtualMachineScaleSet
-	resourceGroup string
-	lastUpdate    time.Time
+	vmss           *compute.VirtualMachineScaleSet
+	subscriptionID string
+	resourceGroup  string
+	lastUpdate     time.Time
 }
 
 type availabilitySetNodeEntry struct {
@@ -55,31 +61,34 @@ type availabilitySetNodeEntry struct {
 
 func (ss *ScaleSet) newVMSSCache() (*azcache.TimedCache, error) {
 	getter := func(key string) (interface{}, error) {
-		localCache := &sync.Map{} // [vmasName]*vmssEntry
+		localCache := &sync.Map{} // [vmssResourceID]*vmssEntry
 
-		allResourceGroups, err := ss.GetResourceGroups()
+		allSubscriptionResourceGroups, err := ss.GetSubscriptionResourceGroups()
 		if err != nil {
 			return nil, err
 		}
 
-		for _, resourceGroup := range allResourceGroups.List() {
-			allScaleSets, rerr := ss.VirtualMachineScaleSetsClient.List(context.Background(), resourceGroup)
-			if rerr != nil {
-				klog.Errorf("VirtualMachineScaleSetsClient.List failed: %v", rerr)
-				return nil, rerr.Error()
-			}
+		for subscriptionID, allResourceGroups := range allSubscriptionResourceGroups {
+			for _, resourceGroup := range allResourceGroups.List() {
+				allScaleSets, rerr := ss.VirtualMachineScaleSetsClient.List(context.Background(), subscriptionID, resourceGroup)
+				if rerr != nil {
+					klog.Errorf("VirtualMachineScaleSetsClient.List(%s, %s) failed: %v", subscriptionID, resourceGroup, rerr)
+					return nil, rerr.Error()
+				}
 
-			for i := range allScaleSets {
-				scaleSet := allScaleSets[i]
-				if scaleSet.Name == nil || *scaleSet.Name == "" {
-					klog.Warning("failed to get the name of VMSS")
-					continue
+				for i := range allScaleSets {
+					scaleSet := allScaleSets[i]
+					if scaleSet.Name == nil || *scaleSet.Name == "" {
+						klog.Warning("failed to get the name of VMSS")
+						continue
+					}
+					localCache.Store(getVMSSResourceID(subscriptionID, resourceGroup, *scaleSet.Name), &vmssEntry{
+						vmss:           &scaleSet,
+						subscriptionID: subscriptionID,
+						resourceGroup:  resourceGroup,
+						lastUpdate:     time.Now().UTC(),
+					})
 				}
-				localCache.Store(*scaleSet.Name, &vmssEntry{
-					vmss:          &scaleSet,
-					resourceGroup: resourceGroup,
-					lastUpdate:    time.Now().UTC(),
-				})
 			}
 		}
 
@@ -89,7 +98,7 @@ func (ss *ScaleSet) newVMSSCache() (*azcache.TimedCache, error) {
 	if ss.Config.VmssCacheTTLInSeconds == 0 {
 		ss.Config.VmssCacheTTLInSeconds = consts.VMSSCacheTTLDefaultInSeconds
 	}
-	return azcache.NewTimedcache(time.Duration(ss.Config.VmssCacheTTLInSeconds)*time.Second, getter)
+	return azcache.NewTimedcacheWithName(vmssCacheName, ss.Config.getCacheTTL(vmssCacheName, ss.Config.VmssCacheTTLInSeconds), getter)
 }
 
 func extractVmssVMName(name string) (string, string, error) {
@@ -106,20 +115,35 @@ func extractVmssVMName(name string) (string, string, error) {
 	return ssName, instanceID, nil
 }
 
+// getVMSSResourceID returns the lower-cased resource ID of a VMSS, which is used as the key of the VMSS cache.
+func getVMSSResourceID(subscriptionID, resourceGroup, vmssName string) string {
+	return strings.ToLower(fmt.Sprintf(consts.VMSSIDTemplate, subscriptionID, resourceGroup, vmssName))
+}
+
+// getVMSSVMCacheKey returns the key of the VMSS VMs cache for a VMSS.
+func getVMSSVMCacheKey(subscriptionID, resourceGroup, vmssName string) string {
+	return strings.ToLower(fmt.Sprintf("%s/%s/%s", subscriptionID, resourceGroup, vmssName))
+}
+
 // getVMSSVMCache returns an *azcache.TimedCache and cache key for a VMSS (creating that cache if new).
-func (ss *ScaleSet) getVMSSVMCache(resourceGroup, vmssName string) (string, *azcache.TimedCache, error) {
-	cacheKey := strings.ToLower(fmt.Sprintf("%s/%s", resourceGroup, vmssName))
+// An empty subscriptionID refers to the subscription in the cloud config.
+func (ss *ScaleSet) getVMSSVMCache(subscriptionID, resourceGroup, vmssName string) (string, *azcache.TimedCache, error) {
+	if subscriptionID == "" {
+		subscriptionID = ss.SubscriptionID
+	}
+	cacheKey := getVMSSVMCacheKey(subscriptionID, resourceGroup, vmssName)
 	if entry, ok := ss.vmssVMCache.Load(cacheKey); ok {
 		cache := entry.(*azcache.TimedCache)
 		return cacheKey, cache, nil
 	}
 
-	cache, err := ss.newVMSSVirtualMachinesCache(resourceGroup, vmssName, cacheKey)
+	cache, err := ss.newVMSSVirtualMachinesCache(subscriptionID, resourceGroup, vmssName, cacheKey)
 	if err != nil {
 		return "", nil, err
 	}
-	ss.vmssVMCache.Store(cacheKey, cache)
-	return cacheKey, cache, nil
+	// Another goroutine may have created the cache concurrently, so always use the stored one.
+	entry, _ := ss.vmssVMCache.LoadOrStore(cacheKey, cache)
+	return cacheKey, entry.(*azcache.TimedCache), nil
 }
 
 // gcVMSSVMCache delete stale VMSS VMs caches from deleted VMSSes.
@@ -130,11 +154,17 @@ func (ss *ScaleSet) gcVMSSVMCache() error {
 	}
 
 	vmsses := cached.(*sync.Map)
+	existing := sets.NewString()
+	vmsses.Range(func(key, value interface{}) bool {
+		v := value.(*vmssEntry)
+		existing.Insert(getVMSSVMCacheKey(v.subscriptionID, v.resourceGroup, to.String(v.vmss.Name)))
+		return true
+	})
+
 	removed := map[string]bool{}
 	ss.vmssVMCache.Range(func(key, value interface{}) bool {
 		cacheKey := key.(string)
-		vlistIdx := cacheKey[strings.LastIndex(cacheKey, "/")+1:]
-		if _, ok := vmsses.Load(vlistIdx); !ok {
+		if !existing.Has(cacheKey) {
 			removed[cacheKey] = true
 		}
 		return true
@@ -147,9 +177,44 @@ func (ss *ScaleSet) gcVMSSVMCache() error {
 	return nil
 }
 
+// invalidateVMSSVMCacheByResourceID deletes the cached VMSS VM of the resource ID, so that the VMs of
+// the VMSS would be refreshed the next time the VM is read.
+func (ss *ScaleSet) invalidateVMSSVMCacheByResourceID(resourceID string) {
+	matches := vmssVMResourceIDRE.FindStringSubmatch(resourceID)
+	if len(matches) != 5 {
+		klog.Warningf("invalidateVMSSVMCacheByResourceID: %q isn't a VMSS VM resource ID", resourceID)
+		return
+	}
+	subscriptionID, resourceGroup, vmssName, instanceID := matches[1], matches[2], matches[3], matches[4]
+
+	cacheKey := getVMSSVMCacheKey(subscriptionID, resourceGroup, vmssName)
+	vmssCache, ok := ss.vmssVMCache.Load(cacheKey)
+	if !ok {
+		return
+	}
+	// Read the store directly so that the invalidation would not trigger the refresh of the cache.
+	entry, exists, err := vmssCache.(*azcache.TimedCache).Store.GetByKey(cacheKey)
+	if err != nil || !exists {
+		return
+	}
+	// the cached data is not a map if the VMSS is cached as not found
+	virtualMachines, ok := entry.(*azcache.AzureCacheEntry).Data.(*sync.Map)
+	if !ok {
+		return
+	}
+	virtualMachines.Range(func(key, value interface{}) bool {
+		if strings.EqualFold(value.(*vmssVirtualMachinesEntry).instanceID, instanceID) {
+			klog.V(4).Infof("invalidateVMSSVMCacheByResourceID: deleting the cached VM of node %s after %s is mutated", key, resourceID)
+			virtualMachines.Delete(key)
+			return false
+		}
+		return true
+	})
+}
+
 // newVMSSVirtualMachinesCache instantiates a new VMs cache for VMs belonging to the provided VMSS.
-func (ss *ScaleSet) newVMSSVirtualMachinesCache(resourceGroupName, vmssName, cacheKey string) (*azcache.TimedCache, error) {
-	vmssVirtualMachinesCacheTTL := time.Duration(ss.Config.VmssVirtualMachinesCacheTTLInSeconds) * time.Second
+func (ss *ScaleSet) newVMSSVirtualMachinesCache(subscriptionID, resourceGroupName, vmssName, cacheKey string) (*azcache.TimedCache, error) {
+	vmssVirtualMachinesCacheTTL := ss.Config.getCacheTTL(vmssVirtualMachinesCacheName, ss.Config.VmssVirtualMachinesCacheTTLInSeconds)
 
 	getter := func(key string) (interface{}, error) {
 		localCache := &sync.Map{} // [nodeName]*vmssVirtualMachinesEntry
@@ -164,9 +229,8 @@ func (ss *ScaleSet) newVMSSVirtualMachinesCache(resourceGroupName, vmssName, cac
 				return nil, err
 			}
 			if exists {
-				cached := entry.(*azcache.AzureCacheEntry).Data
-				if cached != nil {
-					virtualMachines := cached.(*sync.Map)
+				// the cached data is not a map if the VMSS is cached as not found
+				if virtualMachines, ok := entry.(*azcache.AzureCacheEntry).Data.(*sync.Map); ok {
 					virtualMachines.Range(func(key, value interface{}) bool {
 						oldCache[key.(string)] = *value.(*vmssVirtualMachinesEntry)
 						return true
@@ -175,7 +239,12 @@ func (ss *ScaleSet) newVMSSVirtualMachinesCache(resourceGroupName, vmssName, cac
 			}
 		}
 
-		vms, err := ss.listScaleSetVMs(vmssName, resourceGroupName)
+		vms, err := ss.listScaleSetVMs(subscriptionID, vmssName, resourceGroupName)
+		if errors.Is(err, cloudprovider.InstanceNotFound) {
+			// the VMSS is not found, cache it as not found to avoid listing it again and again
+			klog.V(2).Infof("VMSS %q in rg %q not found", vmssName, resourceGroupName)
+			return nil, nil
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -188,21 +257,26 @@ func (ss *ScaleSet) newVMSSVirtualMachinesCache(resourceGroupName, vmssName, cac
 			}
 
 			computerName := strings.ToLower(*vm.OsProfile.ComputerName)
-			if vm.NetworkProfile == nil || vm.NetworkProfile.NetworkInterfaces == nil {
+			// Deallocated (e.g. evicted spot) instances still exist, so keep them in the
+			// cache even if their network profiles are not available.
+			if (vm.NetworkProfile == nil || vm.NetworkProfile.NetworkInterfaces == nil) && !isVMSSVMDeallocated(&vm) {
 				klog.Warningf("skip caching vmssVM %s since its network profile hasn't initialized yet (probably still under creating)", computerName)
 				continue
 			}
 
 			vmssVMCacheEntry := &vmssVirtualMachinesEntry{
+				subscriptionID: subscriptionID,
 				resourceGroup:  resourceGroupName,
 				vmssName:       vmssName,
 				instanceID:     to.String(vm.InstanceID),
 				virtualMachine: &vm,
 				lastUpdate:     time.Now().UTC(),
 			}
+			if vm.VirtualMachineScaleSetVMProperties != nil {
+				vmssVMCacheEntry.provisioningState = to.String(vm.VirtualMachineScaleSetVMProperties.ProvisioningState)
+			}
 			// set cache entry to nil when the VM is under deleting.
-			if vm.VirtualMachineScaleSetVMProperties != nil &&
-				strings.EqualFold(to.String(vm.VirtualMachineScaleSetVMProperties.ProvisioningState), string(compute.ProvisioningStateDeleting)) {
+			if isVMSSVMDeleting(vmssVMCacheEntry.provisioningState) {
 				klog.V(4).Infof("VMSS virtualMachine %q is under deleting, setting its cache to nil", computerName)
 				vmssVMCacheEntry.virtualMachine = nil
 			}
@@ -229,18 +303,26 @@ func (ss *ScaleSet) newVMSSVirtualMachinesCache(resourceGroupName, vmssName, cac
 
 			klog.V(5).Infof("adding old entries to new cache for %s", name)
 			localCache.Store(name, &vmssVirtualMachinesEntry{
+				subscriptionID: vmEntry.subscriptionID,
 				resourceGroup:  vmEntry.resourceGroup,
 				vmssName:       vmEntry.vmssName,
 				instanceID:     vmEntry.instanceID,
 				virtualMachine: nil,
 				lastUpdate:     lastUpdate,
+
+				provisioningState: vmEntry.provisioningState,
 			})
 		}
 
 		return localCache, nil
 	}
 
-	return azcache.NewTimedcache(vmssVirtualMachinesCacheTTL, getter)
+	vmssVMCache, err := azcache.NewTimedcache(vmssVirtualMachinesCacheTTL, getter)
+	if err != nil {
+		return nil, err
+	}
+	vmssVMCache.NegativeTTL = negativeCacheTTL(vmssVirtualMachinesCacheTTL)
+	return vmssVMCache, nil
 }
 
 func (ss *ScaleSet) deleteCacheForNode(nodeName string) error {
@@ -250,7 +332,7 @@ func (ss *ScaleSet) deleteCacheForNode(nodeName string) error {
 		return err
 	}
 
-	cacheKey, timedcache, err := ss.getVMSSVMCache(node.resourceGroup, node.vmssName)
+	cacheKey, timedcache, err := ss.getVMSSVMCache(node.subscriptionID, node.resourceGroup, node.vmssName)
 	if err != nil {
 		klog.Errorf("deleteCacheForNode(%s) failed with error: %v", nodeName, err)
 		return err
@@ -261,8 +343,9 @@ func (ss *ScaleSet) deleteCacheForNode(nodeName string) error {
 		klog.Errorf("deleteCacheForNode(%s) failed with error: %v", nodeName, err)
 		return err
 	}
-	virtualMachines := vmcache.(*sync.Map)
-	virtualMachines.Delete(nodeName)
+	if virtualMachines, ok := vmcache.(*sync.Map); ok {
+		virtualMachines.Delete(nodeName)
+	}
 
 	if err := ss.gcVMSSVMCache(); err != nil {
 		klog.Errorf("deleteCacheForNode(%s) failed to gc stale vmss caches: %v", nodeName, err)
@@ -311,7 +394,7 @@ func (ss *ScaleSet) newAvailabilitySetNodesCache() (*azcache.TimedCache, error)
 	if ss.Config.AvailabilitySetNodesCacheTTLInSeconds == 0 {
 		ss.Config.AvailabilitySetNodesCacheTTLInSeconds = consts.AvailabilitySetNodesCacheTTLDefaultInSeconds
 	}
-	return azcache.NewTimedcache(time.Duration(ss.Config.AvailabilitySetNodesCacheTTLInSeconds)*time.Second, getter)
+	return azcache.NewTimedcacheWithName(availabilitySetNodesCacheName, ss.Config.getCacheTTL(availabilitySetNodesCacheName, ss.Config.AvailabilitySetNodesCacheTTLInSeconds), getter)
 }
 
 func (ss *ScaleSet) isNodeManagedByAvailabilitySet(nodeName string, crt azcache.AzureCacheReadType) (bool, error) {
@@ -17,6 +17,8 @@ limitations under the License.
 package provider
 
 import (
+	"fmt"
+	"net/http"
 	"sync"
 	"testing"
 
@@ -27,9 +29,11 @@ import (
 
 	cloudprovider "k8s.io/cloud-provider"
 
+	azclients "sigs.k8s.io/cloud-provider-azure/pkg/azureclients"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/vmssclient/mockvmssclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/vmssvmclient/mockvmssvmclient"
 	azcache "sigs.k8s.io/cloud-provider-azure/pkg/cache"
+	"sigs.k8s.io/cloud-provider-azure/pkg/retry"
 )
 
 func TestExtractVmssVMName(t *testing.T) {
@@ -90,10 +94,10 @@ func TestVMSSVMCache(t *testing.T) {
 	ss.cloud.VirtualMachineScaleSetVMsClient = mockVMSSVMClient
 
 	expectedScaleSet := buildTestVMSS(testVMSSName, "vmssee6c2")
-	mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any()).Return([]compute.VirtualMachineScaleSet{expectedScaleSet}, nil).AnyTimes()
+	mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).Return([]compute.VirtualMachineScaleSet{expectedScaleSet}, nil).AnyTimes()
 
 	expectedVMs, _, _ := buildTestVirtualMachineEnv(ss.cloud, testVMSSName, "", 0, vmList, "", false)
-	mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(expectedVMs, nil).AnyTimes()
+	mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(expectedVMs, nil).AnyTimes()
 
 	// validate getting VMSS VM via cache.
 	for i := range expectedVMs {
@@ -113,7 +117,7 @@ func TestVMSSVMCache(t *testing.T) {
 	assert.NoError(t, err)
 
 	// the VM should be removed from cache after deleteCacheForNode().
-	cacheKey, cache, err := ss.getVMSSVMCache("rg", testVMSSName)
+	cacheKey, cache, err := ss.getVMSSVMCache("", "rg", testVMSSName)
 	assert.NoError(t, err)
 	cached, err := cache.Get(cacheKey, azcache.CacheReadTypeDefault)
 	assert.NoError(t, err)
@@ -146,10 +150,10 @@ func TestVMSSVMCacheWithDeletingNodes(t *testing.T) {
 		Name:                             to.StringPtr(testVMSSName),
 		VirtualMachineScaleSetProperties: &compute.VirtualMachineScaleSetProperties{},
 	}
-	mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any()).Return([]compute.VirtualMachineScaleSet{expectedScaleSet}, nil).AnyTimes()
+	mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).Return([]compute.VirtualMachineScaleSet{expectedScaleSet}, nil).AnyTimes()
 
 	expectedVMs, _, _ := buildTestVirtualMachineEnv(ss.cloud, testVMSSName, "", 0, vmList, string(compute.ProvisioningStateDeleting), false)
-	mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(expectedVMs, nil).AnyTimes()
+	mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(expectedVMs, nil).AnyTimes()
 
 	for i := range expectedVMs {
 		vm := expectedVMs[i]
@@ -161,3 +165,79 @@ func TestVMSSVMCacheWithDeletingNodes(t *testing.T) {
 		assert.Equal(t, cloudprovider.InstanceNotFound, err)
 	}
 }
+
+func TestVMSSVMCacheWithDeletedVMSS(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ss, err := NewTestScaleSet(ctrl)
+	assert.NoError(t, err)
+
+	mockVMSSClient := mockvmssclient.NewMockInterface(ctrl)
+	mockVMSSVMClient := mockvmssvmclient.NewMockInterface(ctrl)
+	ss.cloud.VirtualMachineScaleSetsClient = mockVMSSClient
+	ss.cloud.VirtualMachineScaleSetVMsClient = mockVMSSVMClient
+
+	expectedScaleSet := buildTestVMSS(testVMSSName, "vmssee6c2")
+	mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).Return([]compute.VirtualMachineScaleSet{expectedScaleSet}, nil).AnyTimes()
+	// the deleted VMSS should only be listed once.
+	mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, &retry.Error{HTTPStatusCode: http.StatusNotFound}).Times(1)
+
+	for i := 0; i < 2; i++ {
+		realVM, err := ss.getVmssVM("vmssee6c2000000", azcache.CacheReadTypeDefault)
+		assert.Nil(t, realVM)
+		assert.Equal(t, cloudprovider.InstanceNotFound, err)
+	}
+
+	// deleteCacheForNode should not fail for the deleted VMSS.
+	err = ss.deleteCacheForNode("vmssee6c2000000")
+	assert.NoError(t, err)
+}
+
+func TestInvalidateVMSSVMCacheByResourceID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	vmList := []string{"vmssee6c2000000", "vmssee6c2000001", "vmssee6c2000002"}
+	ss, err := NewTestScaleSet(ctrl)
+	assert.NoError(t, err)
+	ss.Cloud.VMSet = ss
+	ss.invalidationRegistry = azclients.NewInvalidationRegistry()
+	ss.registerCacheInvalidators()
+
+	mockVMSSClient := mockvmssclient.NewMockInterface(ctrl)
+	mockVMSSVMClient := mockvmssvmclient.NewMockInterface(ctrl)
+	ss.cloud.VirtualMachineScaleSetsClient = mockVMSSClient
+	ss.cloud.VirtualMachineScaleSetVMsClient = mockVMSSVMClient
+
+	expectedScaleSet := buildTestVMSS(testVMSSName, "vmssee6c2")
+	mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).Return([]compute.VirtualMachineScaleSet{expectedScaleSet}, nil).AnyTimes()
+	expectedVMs, _, _ := buildTestVirtualMachineEnv(ss.cloud, testVMSSName, "", 0, vmList, "", false)
+	mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(expectedVMs, nil).AnyTimes()
+
+	for _, vmName := range vmList {
+		_, err := ss.getVmssVM(vmName, azcache.CacheReadTypeDefault)
+		assert.NoError(t, err)
+	}
+
+	// the invalid resource IDs and the VMs not cached should be ignored.
+	ss.invalidationRegistry.Invalidate(vmssVMResourceType, "invalid")
+	ss.invalidationRegistry.Invalidate(vmssVMResourceType, fmt.Sprintf("/subscriptions/%s/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/other/virtualMachines/1", ss.SubscriptionID))
+
+	// only the mutated VM should be removed from the cache.
+	ss.invalidationRegistry.Invalidate(vmssVMResourceType, fmt.Sprintf("/subscriptions/%s/resourceGroups/RG/providers/Microsoft.Compute/virtualMachineScaleSets/%s/virtualMachines/1", ss.SubscriptionID, testVMSSName))
+	cacheKey, cache, err := ss.getVMSSVMCache("", "rg", testVMSSName)
+	assert.NoError(t, err)
+	cached, err := cache.Get(cacheKey, azcache.CacheReadTypeDefault)
+	assert.NoError(t, err)
+	cachedVirtualMachines := cached.(*sync.Map)
+	for i, vmName := range vmList {
+		_, ok := cachedVirtualMachines.Load(vmName)
+		assert.Equal(t, i != 1, ok, vmName)
+	}
+
+	// the VM should be back after another cache refresh.
+	realVM, err := ss.getVmssVM(vmList[1], azcache.CacheReadTypeDefault)
+	assert.NoError(t, err)
+	assert.Equal(t, "1", realVM.InstanceID)
+}
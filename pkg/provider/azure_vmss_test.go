@@ -17,10 +17,12 @@ limitations under the License.
 package provider
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2021-07-01/compute"
 	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2021-08-01/network"
@@ -285,7 +287,7 @@ func TestGetNodeIdentityByNodeName(t *testing.T) {
 			nodeName:     "vmssee6c2000001",
 			scaleSet:     "vmssee6c2",
 			computerName: "vmssee6c2",
-			expected:     &nodeIdentity{"rg", "vmssee6c2", "vmssee6c2000001"},
+			expected:     &nodeIdentity{"subscription", "rg", "vmssee6c2", "vmssee6c2000001"},
 		},
 		{
 			description:  "ScaleSet should get node identity when computerNamePrefix differs from vmss name",
@@ -293,7 +295,7 @@ func TestGetNodeIdentityByNodeName(t *testing.T) {
 			nodeName:     "vmssee6c2000001",
 			scaleSet:     "ss",
 			computerName: "vmssee6c2",
-			expected:     &nodeIdentity{"rg", "ss", "vmssee6c2000001"},
+			expected:     &nodeIdentity{"subscription", "rg", "ss", "vmssee6c2000001"},
 		},
 		{
 			description:  "ScaleSet should get node identity by node name with upper cases hostname",
@@ -301,7 +303,7 @@ func TestGetNodeIdentityByNodeName(t *testing.T) {
 			nodeName:     "vmssee6c2000001",
 			scaleSet:     "ss",
 			computerName: "vmssee6c2",
-			expected:     &nodeIdentity{"rg", "ss", "vmssee6c2000001"},
+			expected:     &nodeIdentity{"subscription", "rg", "ss", "vmssee6c2000001"},
 		},
 		{
 			description:  "ScaleSet should not get node identity for non-existing nodes",
@@ -323,10 +325,10 @@ func TestGetNodeIdentityByNodeName(t *testing.T) {
 		ss.cloud.VirtualMachineScaleSetVMsClient = mockVMSSVMClient
 
 		expectedScaleSet := buildTestVMSS(test.scaleSet, test.computerName)
-		mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any()).Return([]compute.VirtualMachineScaleSet{expectedScaleSet}, nil).AnyTimes()
+		mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).Return([]compute.VirtualMachineScaleSet{expectedScaleSet}, nil).AnyTimes()
 
 		expectedVMs, _, _ := buildTestVirtualMachineEnv(ss.cloud, test.scaleSet, "", 0, test.vmList, "", false)
-		mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(expectedVMs, nil).AnyTimes()
+		mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(expectedVMs, nil).AnyTimes()
 
 		mockVMsClient := ss.cloud.VirtualMachinesClient.(*mockvmclient.MockInterface)
 		mockVMsClient.EXPECT().List(gomock.Any(), gomock.Any()).Return([]compute.VirtualMachine{}, nil).AnyTimes()
@@ -387,10 +389,10 @@ func TestGetInstanceIDByNodeName(t *testing.T) {
 		ss.cloud.VirtualMachineScaleSetVMsClient = mockVMSSVMClient
 
 		expectedScaleSet := buildTestVMSS(test.scaleSet, "vmssee6c2")
-		mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any()).Return([]compute.VirtualMachineScaleSet{expectedScaleSet}, nil).AnyTimes()
+		mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).Return([]compute.VirtualMachineScaleSet{expectedScaleSet}, nil).AnyTimes()
 
 		expectedVMs, _, _ := buildTestVirtualMachineEnv(ss.cloud, test.scaleSet, "", 0, test.vmList, "", false)
-		mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(expectedVMs, nil).AnyTimes()
+		mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(expectedVMs, nil).AnyTimes()
 
 		mockVMsClient := ss.cloud.VirtualMachinesClient.(*mockvmclient.MockInterface)
 		mockVMsClient.EXPECT().List(gomock.Any(), gomock.Any()).Return([]compute.VirtualMachine{}, nil).AnyTimes()
@@ -472,10 +474,10 @@ func TestGetZoneByNodeName(t *testing.T) {
 		ss.cloud.VirtualMachineScaleSetVMsClient = mockVMSSVMClient
 
 		expectedScaleSet := buildTestVMSS(test.scaleSet, "vmssee6c2")
-		mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any()).Return([]compute.VirtualMachineScaleSet{expectedScaleSet}, nil).AnyTimes()
+		mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).Return([]compute.VirtualMachineScaleSet{expectedScaleSet}, nil).AnyTimes()
 
 		expectedVMs, _, _ := buildTestVirtualMachineEnv(ss.cloud, test.scaleSet, test.zone, test.faultDomain, test.vmList, "", false)
-		mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(expectedVMs, nil).AnyTimes()
+		mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(expectedVMs, nil).AnyTimes()
 
 		mockVMsClient := ss.cloud.VirtualMachinesClient.(*mockvmclient.MockInterface)
 		mockVMsClient.EXPECT().List(gomock.Any(), gomock.Any()).Return([]compute.VirtualMachine{}, nil).AnyTimes()
@@ -534,10 +536,10 @@ func TestGetIPByNodeName(t *testing.T) {
 		ss.cloud.PublicIPAddressesClient = mockPIPClient
 
 		expectedScaleSet := buildTestVMSS(test.scaleSet, "vmssee6c2")
-		mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any()).Return([]compute.VirtualMachineScaleSet{expectedScaleSet}, nil).AnyTimes()
+		mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).Return([]compute.VirtualMachineScaleSet{expectedScaleSet}, nil).AnyTimes()
 
 		expectedVMs, expectedInterface, expectedPIP := buildTestVirtualMachineEnv(ss.cloud, test.scaleSet, "", 0, test.vmList, "", false)
-		mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(expectedVMs, nil).AnyTimes()
+		mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(expectedVMs, nil).AnyTimes()
 		mockInterfaceClient.EXPECT().GetVirtualMachineScaleSetNetworkInterface(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(expectedInterface, nil).AnyTimes()
 		mockPIPClient.EXPECT().GetVirtualMachineScaleSetPublicIPAddress(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(expectedPIP, nil).AnyTimes()
 
@@ -604,10 +606,10 @@ func TestGetNodeNameByIPConfigurationID(t *testing.T) {
 		ss.cloud.VirtualMachineScaleSetVMsClient = mockVMSSVMClient
 
 		expectedScaleSet := buildTestVMSS(test.scaleSet, "vmssee6c2")
-		mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any()).Return([]compute.VirtualMachineScaleSet{expectedScaleSet}, nil).AnyTimes()
+		mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).Return([]compute.VirtualMachineScaleSet{expectedScaleSet}, nil).AnyTimes()
 
 		expectedVMs, _, _ := buildTestVirtualMachineEnv(ss.cloud, test.scaleSet, "", 0, test.vmList, "", false)
-		mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(expectedVMs, nil).AnyTimes()
+		mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(expectedVMs, nil).AnyTimes()
 
 		nodeName, scalesetName, err := ss.GetNodeNameByIPConfigurationID(test.ipConfigurationID)
 		if test.expectError {
@@ -703,9 +705,9 @@ func TestGetVMSS(t *testing.T) {
 				VirtualMachineProfile: &compute.VirtualMachineScaleSetVMProfile{},
 			},
 		}
-		mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any()).Return([]compute.VirtualMachineScaleSet{expected}, test.vmssListError).AnyTimes()
+		mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).Return([]compute.VirtualMachineScaleSet{expected}, test.vmssListError).AnyTimes()
 
-		actual, err := ss.getVMSS(test.vmssName, azcache.CacheReadTypeDefault)
+		actual, err := ss.getVMSS("", ss.ResourceGroup, test.vmssName, azcache.CacheReadTypeDefault)
 		if test.expectedErr != nil {
 			assert.EqualError(t, test.expectedErr, err.Error(), test.description)
 		}
@@ -747,7 +749,7 @@ func TestGetVmssVM(t *testing.T) {
 
 		expectedVMSS := buildTestVMSS(test.existedVMSSName, "vmss-vm-")
 		mockVMSSClient := ss.cloud.VirtualMachineScaleSetsClient.(*mockvmssclient.MockInterface)
-		mockVMSSClient.EXPECT().List(gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
+		mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
 
 		expectedVMSSVMs, _, _ := buildTestVirtualMachineEnv(ss.cloud, test.existedVMSSName, "", 0, test.existedNodeNames, "", false)
 		var expectedVMSSVM compute.VirtualMachineScaleSetVM
@@ -758,7 +760,7 @@ func TestGetVmssVM(t *testing.T) {
 		}
 
 		mockVMSSVMClient := ss.cloud.VirtualMachineScaleSetVMsClient.(*mockvmssvmclient.MockInterface)
-		mockVMSSVMClient.EXPECT().List(gomock.Any(), ss.ResourceGroup, test.existedVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
+		mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup, test.existedVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
 
 		vmssVM, err := ss.getVmssVM(test.nodeName, azcache.CacheReadTypeDefault)
 		if vmssVM != nil {
@@ -798,14 +800,14 @@ func TestGetPowerStatusByNodeName(t *testing.T) {
 
 		expectedVMSS := buildTestVMSS(testVMSSName, "vmss-vm-")
 		mockVMSSClient := ss.cloud.VirtualMachineScaleSetsClient.(*mockvmssclient.MockInterface)
-		mockVMSSClient.EXPECT().List(gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
+		mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
 
 		expectedVMSSVMs, _, _ := buildTestVirtualMachineEnv(ss.cloud, testVMSSName, "", 0, test.vmList, "", false)
 		mockVMSSVMClient := ss.cloud.VirtualMachineScaleSetVMsClient.(*mockvmssvmclient.MockInterface)
 		if test.nilStatus {
 			expectedVMSSVMs[0].InstanceView.Statuses = nil
 		}
-		mockVMSSVMClient.EXPECT().List(gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
+		mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
 
 		mockVMsClient := ss.cloud.VirtualMachinesClient.(*mockvmclient.MockInterface)
 		mockVMsClient.EXPECT().List(gomock.Any(), gomock.Any()).Return([]compute.VirtualMachine{}, nil).AnyTimes()
@@ -847,7 +849,7 @@ func TestGetProvisioningStateByNodeName(t *testing.T) {
 
 		expectedVMSS := buildTestVMSS(testVMSSName, "vmss-vm-")
 		mockVMSSClient := ss.cloud.VirtualMachineScaleSetsClient.(*mockvmssclient.MockInterface)
-		mockVMSSClient.EXPECT().List(gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
+		mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
 
 		expectedVMSSVMs, _, _ := buildTestVirtualMachineEnv(ss.cloud, testVMSSName, "", 0, test.vmList, "", false)
 		mockVMSSVMClient := ss.cloud.VirtualMachineScaleSetVMsClient.(*mockvmssvmclient.MockInterface)
@@ -856,7 +858,7 @@ func TestGetProvisioningStateByNodeName(t *testing.T) {
 		} else {
 			expectedVMSSVMs[0].ProvisioningState = nil
 		}
-		mockVMSSVMClient.EXPECT().List(gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
+		mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
 
 		mockVMsClient := ss.cloud.VirtualMachinesClient.(*mockvmclient.MockInterface)
 		mockVMsClient.EXPECT().List(gomock.Any(), gomock.Any()).Return([]compute.VirtualMachine{}, nil).AnyTimes()
@@ -895,13 +897,13 @@ func TestGetVmssVMByInstanceID(t *testing.T) {
 			},
 		}
 		mockVMSSClient := ss.cloud.VirtualMachineScaleSetsClient.(*mockvmssclient.MockInterface)
-		mockVMSSClient.EXPECT().List(gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
+		mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
 
 		expectedVMSSVMs, _, _ := buildTestVirtualMachineEnv(ss.cloud, testVMSSName, "", 0, test.vmList, "", false)
 		mockVMSSVMClient := ss.cloud.VirtualMachineScaleSetVMsClient.(*mockvmssvmclient.MockInterface)
-		mockVMSSVMClient.EXPECT().List(gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
+		mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
 
-		vm, err := ss.getVmssVMByInstanceID(ss.ResourceGroup, testVMSSName, test.instanceID, azcache.CacheReadTypeDefault)
+		vm, err := ss.getVmssVMByInstanceID("", ss.ResourceGroup, testVMSSName, test.instanceID, azcache.CacheReadTypeDefault)
 		assert.Equal(t, test.expectedErr, err, test.description+", but an error occurs")
 		assert.Equal(t, expectedVMSSVMs[0], *vm, test.description)
 	}
@@ -944,14 +946,14 @@ func TestGetVmssVMByNodeIdentity(t *testing.T) {
 				},
 			}
 			mockVMSSClient := ss.cloud.VirtualMachineScaleSetsClient.(*mockvmssclient.MockInterface)
-			mockVMSSClient.EXPECT().List(gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
+			mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
 
 			expectedVMSSVMs, _, _ := buildTestVirtualMachineEnv(ss.cloud, testVMSSName, "", 0, test.vmList, "", false)
 			mockVMSSVMClient := ss.cloud.VirtualMachineScaleSetVMsClient.(*mockvmssvmclient.MockInterface)
-			mockVMSSVMClient.EXPECT().List(gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
+			mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
 
 			// Make some nil VMSS VM in cache.
-			cacheKey, cache, err := ss.getVMSSVMCache(ss.ResourceGroup, testVMSSName)
+			cacheKey, cache, err := ss.getVMSSVMCache("", ss.ResourceGroup, testVMSSName)
 			assert.Nil(t, err)
 			cached, err := cache.Get(cacheKey, azcache.CacheReadTypeDefault)
 			assert.Nil(t, err)
@@ -965,18 +967,19 @@ func TestGetVmssVMByNodeIdentity(t *testing.T) {
 			}
 
 			for i := 0; i < len(test.vmList); i++ {
-				node := nodeIdentity{ss.ResourceGroup, testVMSSName, test.vmList[i]}
+				node := nodeIdentity{ss.SubscriptionID, ss.ResourceGroup, testVMSSName, test.vmList[i]}
 				vm, err := ss.getVmssVMByNodeIdentity(&node, azcache.CacheReadTypeDefault)
 				assert.Equal(t, test.expectedErr, err)
-				assert.Equal(t, *virtualmachine.FromVirtualMachineScaleSetVM(&expectedVMSSVMs[i], virtualmachine.ByVMSS(testVMSSName)), *vm)
+				assert.Equal(t, *virtualmachine.FromVirtualMachineScaleSetVM(&expectedVMSSVMs[i], virtualmachine.ByVMSS(testVMSSName),
+					virtualmachine.InResourceGroup(ss.SubscriptionID, ss.ResourceGroup)), *vm)
 			}
 			for i := 0; i < len(test.goneVMList); i++ {
-				node := nodeIdentity{ss.ResourceGroup, testVMSSName, test.goneVMList[i]}
+				node := nodeIdentity{ss.SubscriptionID, ss.ResourceGroup, testVMSSName, test.goneVMList[i]}
 				_, err := ss.getVmssVMByNodeIdentity(&node, azcache.CacheReadTypeDefault)
 				assert.Equal(t, test.goneVMExpectedErr, err)
 			}
 
-			cacheKey, cache, err = ss.getVMSSVMCache(ss.ResourceGroup, testVMSSName)
+			cacheKey, cache, err = ss.getVMSSVMCache("", ss.ResourceGroup, testVMSSName)
 			assert.Nil(t, err)
 			cached, err = cache.Get(cacheKey, azcache.CacheReadTypeDefault)
 			assert.Nil(t, err)
@@ -1020,11 +1023,11 @@ func TestGetInstanceTypeByNodeName(t *testing.T) {
 
 		expectedVMSS := buildTestVMSS(testVMSSName, "vmss-vm-")
 		mockVMSSClient := ss.cloud.VirtualMachineScaleSetsClient.(*mockvmssclient.MockInterface)
-		mockVMSSClient.EXPECT().List(gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
+		mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
 
 		expectedVMSSVMs, _, _ := buildTestVirtualMachineEnv(ss.cloud, testVMSSName, "", 0, test.vmList, "", false)
 		mockVMSSVMClient := ss.cloud.VirtualMachineScaleSetVMsClient.(*mockvmssvmclient.MockInterface)
-		mockVMSSVMClient.EXPECT().List(gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
+		mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
 
 		mockVMClient := ss.cloud.VirtualMachinesClient.(*mockvmclient.MockInterface)
 		mockVMClient.EXPECT().List(gomock.Any(), gomock.Any()).Return(nil, test.vmClientErr).AnyTimes()
@@ -1183,7 +1186,7 @@ func TestGetPrimaryInterface(t *testing.T) {
 
 		expectedVMSS := buildTestVMSS(testVMSSName, "vmss-vm-")
 		mockVMSSClient := ss.cloud.VirtualMachineScaleSetsClient.(*mockvmssclient.MockInterface)
-		mockVMSSClient.EXPECT().List(gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, test.vmssClientErr).AnyTimes()
+		mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, test.vmssClientErr).AnyTimes()
 
 		expectedVMSSVMs, expectedInterface, _ := buildTestVirtualMachineEnv(ss.cloud, testVMSSName, "", 0, test.vmList, "", false)
 		if !test.hasPrimaryInterface {
@@ -1200,7 +1203,7 @@ func TestGetPrimaryInterface(t *testing.T) {
 			expectedVMSSVMs[0].NetworkProfile.NetworkInterfaces = &networkInterfaces
 		}
 		mockVMSSVMClient := ss.cloud.VirtualMachineScaleSetVMsClient.(*mockvmssvmclient.MockInterface)
-		mockVMSSVMClient.EXPECT().List(gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
+		mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
 
 		mockVMClient := ss.cloud.VirtualMachinesClient.(*mockvmclient.MockInterface)
 		mockVMClient.EXPECT().List(gomock.Any(), gomock.Any()).Return(nil, test.vmClientErr).AnyTimes()
@@ -1310,12 +1313,12 @@ func TestGetPrivateIPsByNodeName(t *testing.T) {
 
 		expectedVMSS := buildTestVMSS(testVMSSName, "vmss-vm-")
 		mockVMSSClient := ss.cloud.VirtualMachineScaleSetsClient.(*mockvmssclient.MockInterface)
-		mockVMSSClient.EXPECT().List(gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
+		mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
 
 		expectedVMSSVMs, expectedInterface, _ := buildTestVirtualMachineEnv(ss.cloud, testVMSSName, "", 0, test.vmList, "", false)
 
 		mockVMSSVMClient := ss.cloud.VirtualMachineScaleSetVMsClient.(*mockvmssvmclient.MockInterface)
-		mockVMSSVMClient.EXPECT().List(gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
+		mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
 
 		mockVMClient := ss.cloud.VirtualMachinesClient.(*mockvmclient.MockInterface)
 		mockVMClient.EXPECT().List(gomock.Any(), gomock.Any()).Return(nil, test.vmClientErr).AnyTimes()
@@ -1423,7 +1426,7 @@ func TestListScaleSets(t *testing.T) {
 		assert.NoError(t, err, "unexpected error when creating test VMSS")
 
 		mockVMSSClient := ss.cloud.VirtualMachineScaleSetsClient.(*mockvmssclient.MockInterface)
-		mockVMSSClient.EXPECT().List(gomock.Any(), ss.ResourceGroup).Return(test.existedScaleSets, test.vmssClientErr).AnyTimes()
+		mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup).Return(test.existedScaleSets, test.vmssClientErr).AnyTimes()
 
 		vmssNames, err := ss.listScaleSets(ss.ResourceGroup)
 		if test.expectedErr != nil {
@@ -1462,11 +1465,11 @@ func TestListScaleSetVMs(t *testing.T) {
 		assert.NoError(t, err, "unexpected error when creating test VMSS")
 
 		mockVMSSVMClient := ss.cloud.VirtualMachineScaleSetVMsClient.(*mockvmssvmclient.MockInterface)
-		mockVMSSVMClient.EXPECT().List(gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(test.existedVMSSVMs, test.vmssVMClientErr).AnyTimes()
+		mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(test.existedVMSSVMs, test.vmssVMClientErr).AnyTimes()
 
 		expectedVMSSVMs := test.existedVMSSVMs
 
-		vmssVMs, err := ss.listScaleSetVMs(testVMSSName, ss.ResourceGroup)
+		vmssVMs, err := ss.listScaleSetVMs("", testVMSSName, ss.ResourceGroup)
 		if test.expectedErr != nil {
 			assert.EqualError(t, test.expectedErr, err.Error(), test.description+", but an error occurs")
 		}
@@ -1536,7 +1539,7 @@ func TestGetAgentPoolScaleSets(t *testing.T) {
 
 		expectedVMSS := buildTestVMSS(testVMSSName, "vmss-vm-")
 		mockVMSSClient := ss.cloud.VirtualMachineScaleSetsClient.(*mockvmssclient.MockInterface)
-		mockVMSSClient.EXPECT().List(gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
+		mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
 
 		expectedVMSSVMs := []compute.VirtualMachineScaleSetVM{
 			{
@@ -1565,7 +1568,7 @@ func TestGetAgentPoolScaleSets(t *testing.T) {
 			},
 		}
 		mockVMSSVMClient := ss.cloud.VirtualMachineScaleSetVMsClient.(*mockvmssvmclient.MockInterface)
-		mockVMSSVMClient.EXPECT().List(gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
+		mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
 
 		mockVMClient := ss.cloud.VirtualMachinesClient.(*mockvmclient.MockInterface)
 		mockVMClient.EXPECT().List(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
@@ -1670,7 +1673,7 @@ func TestGetVMSetNames(t *testing.T) {
 
 		expectedVMSS := buildTestVMSS(testVMSSName, "vmss-vm-")
 		mockVMSSClient := ss.cloud.VirtualMachineScaleSetsClient.(*mockvmssclient.MockInterface)
-		mockVMSSClient.EXPECT().List(gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
+		mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
 
 		expectedVMSSVMs := []compute.VirtualMachineScaleSetVM{
 			{
@@ -1704,7 +1707,7 @@ func TestGetVMSetNames(t *testing.T) {
 			},
 		}
 		mockVMSSVMClient := ss.cloud.VirtualMachineScaleSetVMsClient.(*mockvmssvmclient.MockInterface)
-		mockVMSSVMClient.EXPECT().List(gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
+		mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
 
 		mockVMClient := ss.cloud.VirtualMachinesClient.(*mockvmclient.MockInterface)
 		mockVMClient.EXPECT().List(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
@@ -1973,16 +1976,16 @@ func TestEnsureHostInPool(t *testing.T) {
 
 		expectedVMSS := buildTestVMSS(testVMSSName, "vmss-vm-")
 		mockVMSSClient := ss.cloud.VirtualMachineScaleSetsClient.(*mockvmssclient.MockInterface)
-		mockVMSSClient.EXPECT().List(gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
+		mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
 
 		expectedVMSSVMs, _, _ := buildTestVirtualMachineEnv(ss.cloud, testVMSSName, "", 0, []string{string(test.nodeName)}, "", false)
 		if test.isNilVMNetworkConfigs {
 			expectedVMSSVMs[0].NetworkProfileConfiguration.NetworkInterfaceConfigurations = nil
 		}
 		mockVMSSVMClient := ss.cloud.VirtualMachineScaleSetVMsClient.(*mockvmssvmclient.MockInterface)
-		mockVMSSVMClient.EXPECT().List(gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
+		mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
 
-		nodeResourceGroup, ssName, instanceID, vm, err := ss.EnsureHostInPool(test.service, test.nodeName, test.backendPoolID, test.vmSetName)
+		nodeResourceGroup, ssName, instanceID, vm, err := ss.EnsureHostInPool(context.TODO(), test.service, test.nodeName, test.backendPoolID, test.vmSetName)
 		assert.Equal(t, test.expectedErr, err, test.description+", but an error occurs")
 		assert.Equal(t, test.expectedNodeResourceGroup, nodeResourceGroup, test.description)
 		assert.Equal(t, test.expectedVMSSName, ssName, test.description)
@@ -2040,7 +2043,7 @@ func TestEnsureVMSSInPool(t *testing.T) {
 			nodes: []*v1.Node{
 				{
 					Spec: v1.NodeSpec{
-						ProviderID: "azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0",
+						ProviderID: "azure:///subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0",
 					},
 				},
 			},
@@ -2053,7 +2056,7 @@ func TestEnsureVMSSInPool(t *testing.T) {
 			nodes: []*v1.Node{
 				{
 					Spec: v1.NodeSpec{
-						ProviderID: "azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0",
+						ProviderID: "azure:///subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0",
 					},
 				},
 			},
@@ -2066,7 +2069,7 @@ func TestEnsureVMSSInPool(t *testing.T) {
 			nodes: []*v1.Node{
 				{
 					Spec: v1.NodeSpec{
-						ProviderID: "azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0",
+						ProviderID: "azure:///subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0",
 					},
 				},
 			},
@@ -2079,7 +2082,7 @@ func TestEnsureVMSSInPool(t *testing.T) {
 			nodes: []*v1.Node{
 				{
 					Spec: v1.NodeSpec{
-						ProviderID: "azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0",
+						ProviderID: "azure:///subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0",
 					},
 				},
 			},
@@ -2092,7 +2095,7 @@ func TestEnsureVMSSInPool(t *testing.T) {
 			nodes: []*v1.Node{
 				{
 					Spec: v1.NodeSpec{
-						ProviderID: "azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0",
+						ProviderID: "azure:///subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0",
 					},
 				},
 			},
@@ -2105,7 +2108,7 @@ func TestEnsureVMSSInPool(t *testing.T) {
 			nodes: []*v1.Node{
 				{
 					Spec: v1.NodeSpec{
-						ProviderID: "azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0",
+						ProviderID: "azure:///subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0",
 					},
 				},
 			},
@@ -2120,7 +2123,7 @@ func TestEnsureVMSSInPool(t *testing.T) {
 			nodes: []*v1.Node{
 				{
 					Spec: v1.NodeSpec{
-						ProviderID: "azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0",
+						ProviderID: "azure:///subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0",
 					},
 				},
 			},
@@ -2135,7 +2138,7 @@ func TestEnsureVMSSInPool(t *testing.T) {
 			nodes: []*v1.Node{
 				{
 					Spec: v1.NodeSpec{
-						ProviderID: "azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0",
+						ProviderID: "azure:///subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0",
 					},
 				},
 			},
@@ -2149,7 +2152,7 @@ func TestEnsureVMSSInPool(t *testing.T) {
 			nodes: []*v1.Node{
 				{
 					Spec: v1.NodeSpec{
-						ProviderID: "azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0",
+						ProviderID: "azure:///subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0",
 					},
 				},
 			},
@@ -2176,19 +2179,19 @@ func TestEnsureVMSSInPool(t *testing.T) {
 			expectedVMSS.VirtualMachineProfile.NetworkProfile.NetworkInterfaceConfigurations = nil
 		}
 		mockVMSSClient := ss.cloud.VirtualMachineScaleSetsClient.(*mockvmssclient.MockInterface)
-		mockVMSSClient.EXPECT().List(gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
+		mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
 		vmssPutTimes := 0
 		if test.expectedPutVMSS {
 			vmssPutTimes = 1
-			mockVMSSClient.EXPECT().Get(gomock.Any(), ss.ResourceGroup, testVMSSName).Return(expectedVMSS, nil)
+			mockVMSSClient.EXPECT().Get(gomock.Any(), gomock.Any(), ss.ResourceGroup, testVMSSName).Return(expectedVMSS, nil)
 		}
-		mockVMSSClient.EXPECT().CreateOrUpdate(gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(nil).Times(vmssPutTimes)
+		mockVMSSClient.EXPECT().CreateOrUpdate(gomock.Any(), gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(nil).Times(vmssPutTimes)
 
 		expectedVMSSVMs, _, _ := buildTestVirtualMachineEnv(ss.cloud, testVMSSName, "", 0, []string{"vmss-vm-000000"}, "", test.setIPv6Config)
 		mockVMSSVMClient := ss.cloud.VirtualMachineScaleSetVMsClient.(*mockvmssvmclient.MockInterface)
-		mockVMSSVMClient.EXPECT().List(gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
+		mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
 
-		err = ss.ensureVMSSInPool(&v1.Service{Spec: v1.ServiceSpec{ClusterIP: test.clusterIP}}, test.nodes, test.backendPoolID, test.vmSetName)
+		err = ss.ensureVMSSInPool(context.TODO(), &v1.Service{Spec: v1.ServiceSpec{ClusterIP: test.clusterIP}}, test.nodes, test.backendPoolID, test.vmSetName)
 		assert.Equal(t, test.expectedErr, err, test.description+", but an error occurs")
 	}
 }
@@ -2214,7 +2217,7 @@ func TestEnsureHostsInPool(t *testing.T) {
 						Labels: map[string]string{consts.NodeLabelRole: "master"},
 					},
 					Spec: v1.NodeSpec{
-						ProviderID: "azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0",
+						ProviderID: "azure:///subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0",
 					},
 				},
 				{
@@ -2223,7 +2226,7 @@ func TestEnsureHostsInPool(t *testing.T) {
 						Labels: map[string]string{consts.ManagedByAzureLabel: "false"},
 					},
 					Spec: v1.NodeSpec{
-						ProviderID: "azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/1",
+						ProviderID: "azure:///subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/1",
 					},
 				},
 				{
@@ -2231,7 +2234,7 @@ func TestEnsureHostsInPool(t *testing.T) {
 						Name: "vmss-vm-000002",
 					},
 					Spec: v1.NodeSpec{
-						ProviderID: "azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/2",
+						ProviderID: "azure:///subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/2",
 					},
 				},
 			},
@@ -2247,7 +2250,7 @@ func TestEnsureHostsInPool(t *testing.T) {
 						Name: "vmss-vm-000003",
 					},
 					Spec: v1.NodeSpec{
-						ProviderID: "azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/3",
+						ProviderID: "azure:///subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/3",
 					},
 				},
 			},
@@ -2267,23 +2270,382 @@ func TestEnsureHostsInPool(t *testing.T) {
 
 		expectedVMSS := buildTestVMSSWithLB(testVMSSName, "vmss-vm-", []string{testLBBackendpoolID0}, false)
 		mockVMSSClient := ss.cloud.VirtualMachineScaleSetsClient.(*mockvmssclient.MockInterface)
-		mockVMSSClient.EXPECT().List(gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
-		mockVMSSClient.EXPECT().Get(gomock.Any(), ss.ResourceGroup, testVMSSName).Return(expectedVMSS, nil).MaxTimes(1)
-		mockVMSSClient.EXPECT().CreateOrUpdate(gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(nil).MaxTimes(1)
+		mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
+		mockVMSSClient.EXPECT().Get(gomock.Any(), gomock.Any(), ss.ResourceGroup, testVMSSName).Return(expectedVMSS, nil).MaxTimes(1)
+		mockVMSSClient.EXPECT().CreateOrUpdate(gomock.Any(), gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(nil).MaxTimes(1)
 
 		expectedVMSSVMs, _, _ := buildTestVirtualMachineEnv(ss.cloud, testVMSSName, "", 0, []string{"vmss-vm-000000", "vmss-vm-000001", "vmss-vm-000002"}, "", false)
 		mockVMSSVMClient := ss.cloud.VirtualMachineScaleSetVMsClient.(*mockvmssvmclient.MockInterface)
-		mockVMSSVMClient.EXPECT().List(gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
-		mockVMSSVMClient.EXPECT().UpdateVMs(gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(test.expectedVMSSVMPutTimes)
+		mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
+		mockVMSSVMClient.EXPECT().UpdateVMs(gomock.Any(), gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(test.expectedVMSSVMPutTimes)
 
 		mockVMClient := ss.cloud.VirtualMachinesClient.(*mockvmclient.MockInterface)
 		mockVMClient.EXPECT().List(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
 
-		err = ss.EnsureHostsInPool(&v1.Service{}, test.nodes, test.backendpoolID, test.vmSetName)
+		err = ss.EnsureHostsInPool(context.TODO(), &v1.Service{}, test.nodes, test.backendpoolID, test.vmSetName)
 		assert.Equal(t, test.expectedErr, err != nil, test.description+", but an error occurs")
 	}
 }
 
+func TestEnsureHostsInPoolWithEvictedSpotInstance(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ss, err := NewTestScaleSet(ctrl)
+	assert.NoError(t, err)
+	ss.Cloud.VMSet = ss
+	ss.LoadBalancerSku = consts.LoadBalancerSkuStandard
+
+	providerID := "azure:///subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0"
+	nodes := []*v1.Node{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "vmss-vm-000000"},
+			Spec:       v1.NodeSpec{ProviderID: providerID},
+		},
+	}
+
+	expectedVMSS := buildTestVMSSWithLB(testVMSSName, "vmss-vm-", []string{testLBBackendpoolID0}, false)
+	expectedVMSS.VirtualMachineProfile.Priority = compute.VirtualMachinePriorityTypesSpot
+	mockVMSSClient := ss.cloud.VirtualMachineScaleSetsClient.(*mockvmssclient.MockInterface)
+	mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
+	mockVMSSClient.EXPECT().Get(gomock.Any(), gomock.Any(), ss.ResourceGroup, testVMSSName).Return(expectedVMSS, nil).AnyTimes()
+	mockVMSSClient.EXPECT().CreateOrUpdate(gomock.Any(), gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(nil).AnyTimes()
+
+	expectedVMSSVMs, _, _ := buildTestVirtualMachineEnv(ss.cloud, testVMSSName, "", 0, []string{"vmss-vm-000000"}, "", false)
+	powerState := "PowerState/deallocated"
+	(*expectedVMSSVMs[0].InstanceView.Statuses)[0].Code = &powerState
+	mockVMSSVMClient := ss.cloud.VirtualMachineScaleSetVMsClient.(*mockvmssvmclient.MockInterface)
+	mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
+
+	mockVMClient := ss.cloud.VirtualMachinesClient.(*mockvmclient.MockInterface)
+	mockVMClient.EXPECT().List(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+
+	// The evicted spot instance should be skipped without updating its model.
+	mockVMSSVMClient.EXPECT().UpdateVMs(gomock.Any(), gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(0)
+	err = ss.EnsureHostsInPool(context.TODO(), &v1.Service{}, nodes, testLBBackendpoolID1, testVMSSName)
+	assert.NoError(t, err)
+	_, _, _, vm, err := ss.ensureBackendPoolDeletedFromNode("vmss-vm-000000", testLBBackendpoolID0)
+	assert.NoError(t, err)
+	assert.Nil(t, vm)
+
+	// The evicted spot instance still exists.
+	exists, err := ss.InstanceExistsByProviderID(context.Background(), providerID)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	// Restart the same instance and it should be added back to the backend pool.
+	powerState = testVMPowerState
+	_ = ss.deleteCacheForNode("vmss-vm-000000")
+	mockVMSSVMClient.EXPECT().UpdateVMs(gomock.Any(), gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+	err = ss.EnsureHostsInPool(context.TODO(), &v1.Service{}, nodes, testLBBackendpoolID1, testVMSSName)
+	assert.NoError(t, err)
+}
+
+func TestDeallocatedVMSSVMWithoutNetworkProfile(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ss, err := NewTestScaleSet(ctrl)
+	assert.NoError(t, err)
+	ss.Cloud.VMSet = ss
+
+	expectedVMSS := buildTestVMSSWithLB(testVMSSName, "vmss-vm-", []string{testLBBackendpoolID0}, false)
+	mockVMSSClient := ss.cloud.VirtualMachineScaleSetsClient.(*mockvmssclient.MockInterface)
+	mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
+	mockVMSSClient.EXPECT().Get(gomock.Any(), gomock.Any(), ss.ResourceGroup, testVMSSName).Return(expectedVMSS, nil).AnyTimes()
+
+	// the deallocated instance is cached without its network profile
+	expectedVMSSVMs, _, _ := buildTestVirtualMachineEnv(ss.cloud, testVMSSName, "", 0, []string{"vmss-vm-000000"}, "", false)
+	(*expectedVMSSVMs[0].InstanceView.Statuses)[0].Code = to.StringPtr("PowerState/deallocated")
+	expectedVMSSVMs[0].NetworkProfile = nil
+	expectedVMSSVMs[0].NetworkProfileConfiguration = nil
+	mockVMSSVMClient := ss.cloud.VirtualMachineScaleSetVMsClient.(*mockvmssvmclient.MockInterface)
+	mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
+	mockVMSSVMClient.EXPECT().UpdateVMs(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+	mockVMClient := ss.cloud.VirtualMachinesClient.(*mockvmclient.MockInterface)
+	mockVMClient.EXPECT().List(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+
+	_, err = ss.GetPrimaryInterface("vmss-vm-000000")
+	assert.Error(t, err)
+	_, _, err = ss.GetIPByNodeName("vmss-vm-000000")
+	assert.Error(t, err)
+	_, _, _, vm, err := ss.EnsureHostInPool(context.TODO(), &v1.Service{}, "vmss-vm-000000", testLBBackendpoolID1, testVMSSName)
+	assert.NoError(t, err)
+	assert.Nil(t, vm)
+	_, _, _, vm, err = ss.ensureBackendPoolDeletedFromNode("vmss-vm-000000", testLBBackendpoolID0)
+	assert.NoError(t, err)
+	assert.Nil(t, vm)
+	_, _, err = ss.GetDataDisks("vmss-vm-000000", azcache.CacheReadTypeDefault)
+	assert.NoError(t, err)
+	powerState, err := ss.GetPowerStatusByNodeName("vmss-vm-000000")
+	assert.NoError(t, err)
+	assert.Equal(t, vmPowerStateDeallocated, powerState)
+}
+
+func TestEnsureHostsInPoolCrossSubscription(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ss, err := NewTestScaleSet(ctrl)
+	assert.NoError(t, err)
+	ss.LoadBalancerSku = consts.LoadBalancerSkuStandard
+
+	providerID := "azure:///subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0"
+	crossSubscriptionProviderID := "azure:///subscriptions/subscription2/resourceGroups/RG2/providers/Microsoft.Compute/virtualMachineScaleSets/vmss2/virtualMachines/0"
+	nodes := []*v1.Node{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "vmss-vm-000000"},
+			Spec:       v1.NodeSpec{ProviderID: providerID},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "vmss2-vm-000000"},
+			Spec:       v1.NodeSpec{ProviderID: crossSubscriptionProviderID},
+		},
+	}
+	ss.nodeProviderIDs = map[string]string{
+		"vmss-vm-000000":  providerID,
+		"vmss2-vm-000000": crossSubscriptionProviderID,
+	}
+
+	expectedVMSS := buildTestVMSSWithLB(testVMSSName, "vmss-vm-", []string{testLBBackendpoolID0}, false)
+	crossSubscriptionVMSS := buildTestVMSSWithLB("vmss2", "vmss2-vm-", []string{testLBBackendpoolID0}, false)
+	mockVMSSClient := ss.cloud.VirtualMachineScaleSetsClient.(*mockvmssclient.MockInterface)
+	mockVMSSClient.EXPECT().List(gomock.Any(), "subscription", "rg").Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
+	mockVMSSClient.EXPECT().List(gomock.Any(), "subscription2", "rg2").Return([]compute.VirtualMachineScaleSet{crossSubscriptionVMSS}, nil).AnyTimes()
+	mockVMSSClient.EXPECT().Get(gomock.Any(), gomock.Any(), ss.ResourceGroup, testVMSSName).Return(expectedVMSS, nil).AnyTimes()
+	mockVMSSClient.EXPECT().CreateOrUpdate(gomock.Any(), gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(nil).AnyTimes()
+
+	expectedVMSSVMs, _, _ := buildTestVirtualMachineEnv(ss.cloud, testVMSSName, "", 0, []string{"vmss-vm-000000"}, "", false)
+	crossSubscriptionVMSSVMs, _, _ := buildTestVirtualMachineEnv(ss.cloud, "vmss2", "", 0, []string{"vmss2-vm-000000"}, "", false)
+	mockVMSSVMClient := ss.cloud.VirtualMachineScaleSetVMsClient.(*mockvmssvmclient.MockInterface)
+	mockVMSSVMClient.EXPECT().List(gomock.Any(), "subscription", "rg", testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
+	mockVMSSVMClient.EXPECT().List(gomock.Any(), "subscription2", "rg2", "vmss2", gomock.Any()).Return(crossSubscriptionVMSSVMs, nil).AnyTimes()
+
+	mockVMClient := ss.cloud.VirtualMachinesClient.(*mockvmclient.MockInterface)
+	mockVMClient.EXPECT().List(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+
+	nodeName, err := ss.GetNodeNameByProviderID(crossSubscriptionProviderID)
+	assert.NoError(t, err)
+	assert.Equal(t, types.NodeName("vmss2-vm-000000"), nodeName)
+
+	// Each VMSS should be updated in its own subscription and resource group.
+	mockVMSSVMClient.EXPECT().UpdateVMs(gomock.Any(), "subscription", "rg", testVMSSName, gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+	mockVMSSVMClient.EXPECT().UpdateVMs(gomock.Any(), "subscription2", "rg2", "vmss2", gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+	err = ss.EnsureHostsInPool(context.TODO(), &v1.Service{}, nodes, testLBBackendpoolID1, "")
+	assert.NoError(t, err)
+}
+
+func TestEnsureHostsInPoolWithFailedVMASNode(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ss, err := NewTestScaleSet(ctrl)
+	assert.NoError(t, err)
+	ss.LoadBalancerSku = consts.LoadBalancerSkuStandard
+
+	nodes := []*v1.Node{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "vmss-vm-000000"},
+			Spec: v1.NodeSpec{
+				ProviderID: "azure:///subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0",
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "vm-0"},
+		},
+	}
+
+	expectedVMSS := buildTestVMSSWithLB(testVMSSName, "vmss-vm-", []string{testLBBackendpoolID0}, false)
+	mockVMSSClient := ss.cloud.VirtualMachineScaleSetsClient.(*mockvmssclient.MockInterface)
+	mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
+
+	expectedVMSSVMs, _, _ := buildTestVirtualMachineEnv(ss.cloud, testVMSSName, "", 0, []string{"vmss-vm-000000"}, "", false)
+	mockVMSSVMClient := ss.cloud.VirtualMachineScaleSetVMsClient.(*mockvmssvmclient.MockInterface)
+	mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
+
+	existingVMs := []compute.VirtualMachine{
+		{
+			Name: to.StringPtr("vm-0"),
+			VirtualMachineProperties: &compute.VirtualMachineProperties{
+				AvailabilitySet: &compute.SubResource{ID: to.StringPtr("vmas-0")},
+			},
+		},
+	}
+	mockVMClient := ss.cloud.VirtualMachinesClient.(*mockvmclient.MockInterface)
+	mockVMClient.EXPECT().List(gomock.Any(), gomock.Any()).Return(existingVMs, nil).AnyTimes()
+
+	mockVMSet := NewMockVMSet(ctrl)
+	mockVMSet.EXPECT().EnsureHostInPool(gomock.Any(), gomock.Any(), types.NodeName("vm-0"), testLBBackendpoolID1, "").Return("", "", "", nil, fmt.Errorf("vmas error")).Times(1)
+	ss.availabilitySet = mockVMSet
+
+	// The VMSS VMs should still be updated even if the VMAS node fails.
+	mockVMSSVMClient.EXPECT().UpdateVMs(gomock.Any(), gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any(), gomock.Any(), gomock.Any()).Return(&retry.Error{RawError: fmt.Errorf("vmss error")}).Times(1)
+	err = ss.EnsureHostsInPool(context.TODO(), &v1.Service{}, nodes, testLBBackendpoolID1, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "vmas error")
+	assert.Contains(t, err.Error(), "vmss vmss: ")
+}
+
+func TestEnsureHostsInPoolWithTransientProvisioningStates(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ss, err := NewTestScaleSet(ctrl)
+	assert.NoError(t, err)
+	ss.LoadBalancerSku = consts.LoadBalancerSkuStandard
+
+	vmList := []string{"vmss-vm-000000", "vmss-vm-000001", "vmss-vm-000002"}
+	nodes := make([]*v1.Node, 0)
+	ss.nodeProviderIDs = make(map[string]string)
+	for i, nodeName := range vmList {
+		providerID := fmt.Sprintf("azure:///subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/%d", i)
+		nodes = append(nodes, &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: nodeName},
+			Spec:       v1.NodeSpec{ProviderID: providerID},
+		})
+		ss.nodeProviderIDs[nodeName] = providerID
+	}
+
+	expectedVMSS := buildTestVMSSWithLB(testVMSSName, "vmss-vm-", []string{testLBBackendpoolID0}, false)
+	mockVMSSClient := ss.cloud.VirtualMachineScaleSetsClient.(*mockvmssclient.MockInterface)
+	mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
+	mockVMSSClient.EXPECT().Get(gomock.Any(), gomock.Any(), ss.ResourceGroup, testVMSSName).Return(expectedVMSS, nil).AnyTimes()
+	mockVMSSClient.EXPECT().CreateOrUpdate(gomock.Any(), gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(nil).AnyTimes()
+
+	// The instances in different provisioning states are returned in one page.
+	expectedVMSSVMs, _, _ := buildTestVirtualMachineEnv(ss.cloud, testVMSSName, "", 0, vmList, string(compute.ProvisioningStateSucceeded), false)
+	expectedVMSSVMs[1].ProvisioningState = to.StringPtr(string(compute.ProvisioningStateDeleting))
+	expectedVMSSVMs[2].ProvisioningState = to.StringPtr(string(compute.ProvisioningStateFailed))
+	mockVMSSVMClient := ss.cloud.VirtualMachineScaleSetVMsClient.(*mockvmssvmclient.MockInterface)
+	mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), "rg", testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
+	mockVMClient := ss.cloud.VirtualMachinesClient.(*mockvmclient.MockInterface)
+	mockVMClient.EXPECT().List(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+
+	// Only the instance in Succeeded state should be added to the backend pool.
+	mockVMSSVMClient.EXPECT().UpdateVMs(gomock.Any(), gomock.Any(), "rg", testVMSSName, gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, subsID, resourceGroupName, vmssName string, instances map[string]compute.VirtualMachineScaleSetVM, source string, batchSize int) *retry.Error {
+			assert.Equal(t, 1, len(instances))
+			_, ok := instances["0"]
+			assert.True(t, ok)
+			return nil
+		}).Times(1)
+	err = ss.EnsureHostsInPool(context.TODO(), &v1.Service{}, nodes, testLBBackendpoolID1, "")
+	assert.NoError(t, err)
+
+	// The instance under deleting should be reported as not found.
+	nodeName, err := ss.GetNodeNameByProviderID(ss.nodeProviderIDs["vmss-vm-000001"])
+	assert.Equal(t, cloudprovider.InstanceNotFound, err)
+	assert.Equal(t, types.NodeName(""), nodeName)
+
+	nodeName, err = ss.GetNodeNameByProviderID(ss.nodeProviderIDs["vmss-vm-000002"])
+	assert.NoError(t, err)
+	assert.Equal(t, types.NodeName("vmss-vm-000002"), nodeName)
+}
+
+func TestEnsureHostsInPoolBoundedConcurrency(t *testing.T) {
+	testCases := []struct {
+		description string
+		failedVMSS  string
+		expectedErr string
+	}{
+		{
+			description: "EnsureHostsInPool should update the scale sets concurrently with the bounded concurrency",
+		},
+		{
+			description: "EnsureHostsInPool should aggregate the errors with the scale set name prefixed",
+			failedVMSS:  "vmss3",
+			expectedErr: "vmss vmss3: Retriable: false, RetryAfter: 0s, HTTPStatusCode: 0, RawError: error",
+		},
+	}
+
+	for _, test := range testCases {
+		testEnsureHostsInPoolBoundedConcurrency(t, test.description, test.failedVMSS, test.expectedErr)
+	}
+}
+
+func testEnsureHostsInPoolBoundedConcurrency(t *testing.T, description, failedVMSS, expectedErr string) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ss, err := NewTestScaleSet(ctrl)
+	assert.NoError(t, err)
+	ss.LoadBalancerSku = consts.LoadBalancerSkuStandard
+	ss.EnsureHostsInPoolConcurrency = 2
+
+	vmssCount := 6
+	nodes := make([]*v1.Node, 0, vmssCount)
+	ss.nodeProviderIDs = make(map[string]string)
+	vmssList := make([]compute.VirtualMachineScaleSet, 0, vmssCount)
+	vmssByName := make(map[string]compute.VirtualMachineScaleSet)
+	mockVMSSVMClient := ss.cloud.VirtualMachineScaleSetVMsClient.(*mockvmssvmclient.MockInterface)
+	for i := 0; i < vmssCount; i++ {
+		vmssName := fmt.Sprintf("vmss%d", i)
+		nodeName := fmt.Sprintf("%s-vm-000000", vmssName)
+		providerID := fmt.Sprintf("azure:///subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/%s/virtualMachines/0", vmssName)
+		nodes = append(nodes, &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: nodeName},
+			Spec:       v1.NodeSpec{ProviderID: providerID},
+		})
+		ss.nodeProviderIDs[nodeName] = providerID
+
+		vmss := buildTestVMSSWithLB(vmssName, vmssName+"-vm-", []string{testLBBackendpoolID0}, false)
+		vmssList = append(vmssList, vmss)
+		vmssByName[vmssName] = vmss
+
+		vmssVMs, _, _ := buildTestVirtualMachineEnv(ss.cloud, vmssName, "", 0, []string{nodeName}, "", false)
+		mockVMSSVMClient.EXPECT().List(gomock.Any(), "subscription", "rg", vmssName, gomock.Any()).Return(vmssVMs, nil).AnyTimes()
+	}
+
+	mockVMSSClient := ss.cloud.VirtualMachineScaleSetsClient.(*mockvmssclient.MockInterface)
+	mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).Return(vmssList, nil).AnyTimes()
+	mockVMSSClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, subsID, resourceGroupName, vmssName string) (compute.VirtualMachineScaleSet, *retry.Error) {
+			return vmssByName[vmssName], nil
+		}).AnyTimes()
+	mockVMSSClient.EXPECT().CreateOrUpdate(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	mockVMClient := ss.cloud.VirtualMachinesClient.(*mockvmclient.MockInterface)
+	mockVMClient.EXPECT().List(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+
+	var (
+		lock           sync.Mutex
+		active         int
+		maxActive      int
+		updatedVMSSSet = sets.NewString()
+	)
+	mockVMSSVMClient.EXPECT().UpdateVMs(gomock.Any(), "subscription", "rg", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, subsID, resourceGroupName, vmssName string, instances map[string]compute.VirtualMachineScaleSetVM, source string, batchSize int) *retry.Error {
+			lock.Lock()
+			active++
+			if active > maxActive {
+				maxActive = active
+			}
+			updatedVMSSSet.Insert(vmssName)
+			lock.Unlock()
+
+			// inject latency so that the scale sets are updated concurrently.
+			time.Sleep(50 * time.Millisecond)
+
+			lock.Lock()
+			active--
+			lock.Unlock()
+			if vmssName == failedVMSS {
+				return &retry.Error{RawError: fmt.Errorf("error")}
+			}
+			return nil
+		}).Times(vmssCount)
+
+	err = ss.EnsureHostsInPool(context.TODO(), &v1.Service{}, nodes, testLBBackendpoolID1, "")
+	if expectedErr != "" {
+		assert.Error(t, err, description)
+		assert.Contains(t, err.Error(), expectedErr, description)
+	} else {
+		assert.NoError(t, err, description)
+	}
+	assert.Equal(t, vmssCount, updatedVMSSSet.Len(), description)
+	assert.LessOrEqual(t, maxActive, ss.EnsureHostsInPoolConcurrency, description)
+	assert.Greater(t, maxActive, 1, description)
+}
+
 func TestEnsureBackendPoolDeletedFromNode(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -2353,14 +2715,14 @@ func TestEnsureBackendPoolDeletedFromNode(t *testing.T) {
 
 		expectedVMSS := buildTestVMSS(testVMSSName, "vmss-vm-")
 		mockVMSSClient := ss.cloud.VirtualMachineScaleSetsClient.(*mockvmssclient.MockInterface)
-		mockVMSSClient.EXPECT().List(gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
+		mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
 
 		expectedVMSSVMs, _, _ := buildTestVirtualMachineEnv(ss.cloud, testVMSSName, "", 0, []string{"vmss-vm-000000"}, "", false)
 		if test.isNilVMNetworkConfigs {
 			expectedVMSSVMs[0].NetworkProfileConfiguration.NetworkInterfaceConfigurations = nil
 		}
 		mockVMSSVMClient := ss.cloud.VirtualMachineScaleSetVMsClient.(*mockvmssvmclient.MockInterface)
-		mockVMSSVMClient.EXPECT().List(gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
+		mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
 
 		nodeResourceGroup, ssName, instanceID, vm, err := ss.ensureBackendPoolDeletedFromNode(test.nodeName, test.backendpoolID)
 		assert.Equal(t, test.expectedErr, err, test.description+", but an error occurs")
@@ -2448,19 +2810,19 @@ func TestEnsureBackendPoolDeletedFromVMSS(t *testing.T) {
 			expectedVMSS.VirtualMachineProfile.NetworkProfile.NetworkInterfaceConfigurations = nil
 		}
 		mockVMSSClient := ss.cloud.VirtualMachineScaleSetsClient.(*mockvmssclient.MockInterface)
-		mockVMSSClient.EXPECT().List(gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
+		mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
 		vmssPutTimes := 0
 		if test.expectedPutVMSS {
 			vmssPutTimes = 1
-			mockVMSSClient.EXPECT().Get(gomock.Any(), ss.ResourceGroup, testVMSSName).Return(expectedVMSS, nil)
+			mockVMSSClient.EXPECT().Get(gomock.Any(), gomock.Any(), ss.ResourceGroup, testVMSSName).Return(expectedVMSS, nil)
 		}
-		mockVMSSClient.EXPECT().CreateOrUpdate(gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(test.vmssClientErr).Times(vmssPutTimes)
+		mockVMSSClient.EXPECT().CreateOrUpdate(gomock.Any(), gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(test.vmssClientErr).Times(vmssPutTimes)
 
 		expectedVMSSVMs, _, _ := buildTestVirtualMachineEnv(ss.cloud, testVMSSName, "", 0, []string{"vmss-vm-000000"}, "", false)
 		mockVMSSVMClient := ss.cloud.VirtualMachineScaleSetVMsClient.(*mockvmssvmclient.MockInterface)
-		mockVMSSVMClient.EXPECT().List(gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
+		mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
 
-		err = ss.ensureBackendPoolDeletedFromVMSS(&v1.Service{}, test.backendPoolID, testVMSSName, test.ipConfigurationIDs)
+		err = ss.ensureBackendPoolDeletedFromVMSS(context.TODO(), &v1.Service{}, test.backendPoolID, testVMSSName, test.ipConfigurationIDs)
 		if test.expectedErr != nil {
 			assert.EqualError(t, test.expectedErr, err.Error(), test.description+", but an error occurs")
 		}
@@ -2558,16 +2920,16 @@ func TestEnsureBackendPoolDeleted(t *testing.T) {
 
 		expectedVMSS := buildTestVMSSWithLB(testVMSSName, "vmss-vm-", []string{testLBBackendpoolID0}, false)
 		mockVMSSClient := ss.cloud.VirtualMachineScaleSetsClient.(*mockvmssclient.MockInterface)
-		mockVMSSClient.EXPECT().List(gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
-		mockVMSSClient.EXPECT().Get(gomock.Any(), ss.ResourceGroup, testVMSSName).Return(expectedVMSS, nil).MaxTimes(1)
-		mockVMSSClient.EXPECT().CreateOrUpdate(gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(nil).MaxTimes(1)
+		mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
+		mockVMSSClient.EXPECT().Get(gomock.Any(), gomock.Any(), ss.ResourceGroup, testVMSSName).Return(expectedVMSS, nil).MaxTimes(1)
+		mockVMSSClient.EXPECT().CreateOrUpdate(gomock.Any(), gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(nil).MaxTimes(1)
 
 		expectedVMSSVMs, _, _ := buildTestVirtualMachineEnv(ss.cloud, testVMSSName, "", 0, []string{"vmss-vm-000000", "vmss-vm-000001", "vmss-vm-000002"}, "", false)
 		mockVMSSVMClient := ss.cloud.VirtualMachineScaleSetVMsClient.(*mockvmssvmclient.MockInterface)
-		mockVMSSVMClient.EXPECT().List(gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
-		mockVMSSVMClient.EXPECT().UpdateVMs(gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any(), gomock.Any(), gomock.Any()).Return(test.vmClientErr).Times(test.expectedVMSSVMPutTimes)
+		mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
+		mockVMSSVMClient.EXPECT().UpdateVMs(gomock.Any(), gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any(), gomock.Any(), gomock.Any()).Return(test.vmClientErr).Times(test.expectedVMSSVMPutTimes)
 
-		err = ss.EnsureBackendPoolDeleted(&v1.Service{}, test.backendpoolID, testVMSSName, test.backendAddressPools, true)
+		err = ss.EnsureBackendPoolDeleted(context.TODO(), &v1.Service{}, test.backendpoolID, testVMSSName, test.backendAddressPools, true)
 		assert.Equal(t, test.expectedErr, err != nil, test.description+", but an error occurs")
 	}
 }
@@ -2628,17 +2990,17 @@ func TestEnsureBackendPoolDeletedConcurrently(t *testing.T) {
 	}
 
 	mockVMSSClient := ss.cloud.VirtualMachineScaleSetsClient.(*mockvmssclient.MockInterface)
-	mockVMSSClient.EXPECT().List(gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{vmss0, vmss1}, nil).AnyTimes()
-	mockVMSSClient.EXPECT().List(gomock.Any(), "rg1").Return(nil, nil).AnyTimes()
-	mockVMSSClient.EXPECT().Get(gomock.Any(), ss.ResourceGroup, "vmss-0").Return(vmss0, nil).MaxTimes(2)
-	mockVMSSClient.EXPECT().Get(gomock.Any(), ss.ResourceGroup, "vmss-1").Return(vmss1, nil).MaxTimes(2)
-	mockVMSSClient.EXPECT().CreateOrUpdate(gomock.Any(), ss.ResourceGroup, gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{vmss0, vmss1}, nil).AnyTimes()
+	mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), "rg1").Return(nil, nil).AnyTimes()
+	mockVMSSClient.EXPECT().Get(gomock.Any(), gomock.Any(), ss.ResourceGroup, "vmss-0").Return(vmss0, nil).MaxTimes(2)
+	mockVMSSClient.EXPECT().Get(gomock.Any(), gomock.Any(), ss.ResourceGroup, "vmss-1").Return(vmss1, nil).MaxTimes(2)
+	mockVMSSClient.EXPECT().CreateOrUpdate(gomock.Any(), gomock.Any(), ss.ResourceGroup, gomock.Any(), gomock.Any()).Return(nil).Times(2)
 
 	mockVMSSVMClient := ss.cloud.VirtualMachineScaleSetVMsClient.(*mockvmssvmclient.MockInterface)
-	mockVMSSVMClient.EXPECT().List(gomock.Any(), "rg1", "vmss-0", gomock.Any()).Return(nil, nil).AnyTimes()
-	mockVMSSVMClient.EXPECT().List(gomock.Any(), ss.ResourceGroup, "vmss-0", gomock.Any()).Return(expectedVMSSVMsOfVMSS0, nil).AnyTimes()
-	mockVMSSVMClient.EXPECT().List(gomock.Any(), ss.ResourceGroup, "vmss-1", gomock.Any()).Return(expectedVMSSVMsOfVMSS1, nil).AnyTimes()
-	mockVMSSVMClient.EXPECT().UpdateVMs(gomock.Any(), ss.ResourceGroup, gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), "rg1", "vmss-0", gomock.Any()).Return(nil, nil).AnyTimes()
+	mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup, "vmss-0", gomock.Any()).Return(expectedVMSSVMsOfVMSS0, nil).AnyTimes()
+	mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup, "vmss-1", gomock.Any()).Return(expectedVMSSVMsOfVMSS1, nil).AnyTimes()
+	mockVMSSVMClient.EXPECT().UpdateVMs(gomock.Any(), gomock.Any(), ss.ResourceGroup, gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(2)
 
 	backendpoolAddressIDs := []string{testLBBackendpoolID0, testLBBackendpoolID1, testLBBackendpoolID2}
 	testVMSSNames := []string{"vmss-0", "vmss-1", "vmss-2"}
@@ -2647,7 +3009,7 @@ func TestEnsureBackendPoolDeletedConcurrently(t *testing.T) {
 		i := i
 		id := id
 		testFunc = append(testFunc, func() error {
-			return ss.EnsureBackendPoolDeleted(&v1.Service{}, id, testVMSSNames[i], backendAddressPools, true)
+			return ss.EnsureBackendPoolDeleted(context.TODO(), &v1.Service{}, id, testVMSSNames[i], backendAddressPools, true)
 		})
 	}
 	errs := utilerrors.AggregateGoroutines(testFunc...)
@@ -2672,7 +3034,7 @@ func TestGetNodeCIDRMasksByProviderID(t *testing.T) {
 		},
 		{
 			description: "GetNodeCIDRMaksByProviderID should return the correct mask sizes",
-			providerID:  "azure:///subscriptions/sub/resourceGroups/rg1/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0",
+			providerID:  "azure:///subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0",
 			tags: map[string]*string{
 				consts.VMSetCIDRIPV4TagKey: to.StringPtr("24"),
 				consts.VMSetCIDRIPV6TagKey: to.StringPtr("64"),
@@ -2682,7 +3044,7 @@ func TestGetNodeCIDRMasksByProviderID(t *testing.T) {
 		},
 		{
 			description: "GetNodeCIDRMaksByProviderID should return the correct mask sizes even if some of the tags are not specified",
-			providerID:  "azure:///subscriptions/sub/resourceGroups/rg1/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0",
+			providerID:  "azure:///subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0",
 			tags: map[string]*string{
 				consts.VMSetCIDRIPV4TagKey: to.StringPtr("24"),
 			},
@@ -2690,13 +3052,21 @@ func TestGetNodeCIDRMasksByProviderID(t *testing.T) {
 		},
 		{
 			description: "GetNodeCIDRMaksByProviderID should not fail even if some of the tag is invalid",
-			providerID:  "azure:///subscriptions/sub/resourceGroups/rg1/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0",
+			providerID:  "azure:///subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0",
 			tags: map[string]*string{
 				consts.VMSetCIDRIPV4TagKey: to.StringPtr("abc"),
 				consts.VMSetCIDRIPV6TagKey: to.StringPtr("64"),
 			},
 			expectedIPV6MaskSize: 64,
 		},
+		{
+			description: "GetNodeCIDRMaksByProviderID should not match the VMSS with the same name in another resource group",
+			providerID:  "azure:///subscriptions/subscription/resourceGroups/rg1/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0",
+			tags: map[string]*string{
+				consts.VMSetCIDRIPV4TagKey: to.StringPtr("24"),
+			},
+			expectedErr: cloudprovider.InstanceNotFound,
+		},
 	} {
 		t.Run(tc.description, func(t *testing.T) {
 			ss, err := NewTestScaleSet(ctrl)
@@ -2707,7 +3077,7 @@ func TestGetNodeCIDRMasksByProviderID(t *testing.T) {
 				Tags: tc.tags,
 			}
 			mockVMSSClient := ss.cloud.VirtualMachineScaleSetsClient.(*mockvmssclient.MockInterface)
-			mockVMSSClient.EXPECT().List(gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).MaxTimes(1)
+			mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).MaxTimes(2)
 
 			ipv4MaskSize, ipv6MaskSize, err := ss.GetNodeCIDRMasksByProviderID(tc.providerID)
 			assert.Equal(t, tc.expectedErr, err)
@@ -2750,10 +3120,10 @@ func TestGetAgentPoolVMSetNamesMixedInstances(t *testing.T) {
 	mockVMClient.EXPECT().List(gomock.Any(), gomock.Any()).Return(existingVMs, nil).AnyTimes()
 
 	mockVMSSClient := ss.Cloud.VirtualMachineScaleSetsClient.(*mockvmssclient.MockInterface)
-	mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any()).Return([]compute.VirtualMachineScaleSet{expectedScaleSet}, nil)
+	mockVMSSClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).Return([]compute.VirtualMachineScaleSet{expectedScaleSet}, nil)
 
 	mockVMSSVMClient := ss.Cloud.VirtualMachineScaleSetVMsClient.(*mockvmssvmclient.MockInterface)
-	mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), testVMSSName, gomock.Any()).Return(existingVMSSVMs, nil)
+	mockVMSSVMClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any(), testVMSSName, gomock.Any()).Return(existingVMSSVMs, nil)
 
 	nodes := []*v1.Node{
 		{
@@ -28,6 +28,7 @@ import (
 	"github.com/Azure/go-autorest/autorest/to"
 
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	cloudprovider "k8s.io/cloud-provider"
 	"k8s.io/klog/v2"
 
@@ -36,6 +37,27 @@ import (
 	"sigs.k8s.io/cloud-provider-azure/pkg/retry"
 )
 
+const (
+	loadBalancerResourceType  = "Microsoft.Network/loadBalancers"
+	securityGroupResourceType = "Microsoft.Network/networkSecurityGroups"
+	vmssVMResourceType        = "Microsoft.Compute/virtualMachineScaleSets/virtualMachines"
+)
+
+const (
+	vmCacheName                   = "vm"
+	lbCacheName                   = "lb"
+	nsgCacheName                  = "nsg"
+	routeTableCacheName           = "rt"
+	pipCacheName                  = "pip"
+	plsCacheName                  = "pls"
+	vmssCacheName                 = "vmss"
+	vmssVirtualMachinesCacheName  = "vmss_virtual_machines"
+	availabilitySetNodesCacheName = "availability_set_nodes"
+	availabilitySetsCacheName     = "availability_sets"
+	vmasNICCacheName              = "vmas_nic"
+	vmSizeCacheName               = "vm_size"
+)
+
 var (
 	vmCacheTTLDefaultInSeconds           = 60
 	loadBalancerCacheTTLDefaultInSeconds = 120
@@ -43,9 +65,27 @@ var (
 	routeTableCacheTTLDefaultInSeconds   = 120
 	publicIPCacheTTLDefaultInSeconds     = 120
 	plsCacheTTLDefaultInSeconds          = 120
+	vmSizeCacheTTLDefaultInSeconds       = 3600
 
 	azureNodeProviderIDRE    = regexp.MustCompile(`^azure:///subscriptions/(?:.*)/resourceGroups/(?:.*)/providers/Microsoft.Compute/(?:.*)`)
 	azureResourceGroupNameRE = regexp.MustCompile(`.*/subscriptions/(?:.*)/resourceGroups/(.+)/providers/(?:.*)`)
+	azureResourceScopeRE     = regexp.MustCompile(`(?i)^(?:azure://)?/subscriptions/([^/]+)/resourceGroups/([^/]+)/providers/(?:.*)`)
+
+	// cacheNames are the names of the caches whose TTLs could be configured by cacheTTLs in the cloud config.
+	cacheNames = sets.NewString(
+		vmCacheName,
+		lbCacheName,
+		nsgCacheName,
+		routeTableCacheName,
+		pipCacheName,
+		plsCacheName,
+		vmssCacheName,
+		vmssVirtualMachinesCacheName,
+		availabilitySetNodesCacheName,
+		availabilitySetsCacheName,
+		vmasNICCacheName,
+		vmSizeCacheName,
+	)
 )
 
 // checkExistsFromError inspects an error and returns a true if err is nil,
@@ -223,7 +263,12 @@ func (az *Cloud) newVMCache() (*azcache.TimedCache, error) {
 	if az.VMCacheTTLInSeconds == 0 {
 		az.VMCacheTTLInSeconds = vmCacheTTLDefaultInSeconds
 	}
-	return azcache.NewTimedcache(time.Duration(az.VMCacheTTLInSeconds)*time.Second, getter)
+	vmCache, err := azcache.NewTimedcacheWithName(vmCacheName, az.getCacheTTL(vmCacheName, az.VMCacheTTLInSeconds), getter)
+	if err != nil {
+		return nil, err
+	}
+	vmCache.NegativeTTL = negativeCacheTTL(vmCache.TTL)
+	return vmCache, nil
 }
 
 func (az *Cloud) newLBCache() (*azcache.TimedCache, error) {
@@ -248,7 +293,7 @@ func (az *Cloud) newLBCache() (*azcache.TimedCache, error) {
 	if az.LoadBalancerCacheTTLInSeconds == 0 {
 		az.LoadBalancerCacheTTLInSeconds = loadBalancerCacheTTLDefaultInSeconds
 	}
-	return azcache.NewTimedcache(time.Duration(az.LoadBalancerCacheTTLInSeconds)*time.Second, getter)
+	return azcache.NewTimedcacheWithName(lbCacheName, az.getCacheTTL(lbCacheName, az.LoadBalancerCacheTTLInSeconds), getter)
 }
 
 func (az *Cloud) newNSGCache() (*azcache.TimedCache, error) {
@@ -272,7 +317,7 @@ func (az *Cloud) newNSGCache() (*azcache.TimedCache, error) {
 	if az.NsgCacheTTLInSeconds == 0 {
 		az.NsgCacheTTLInSeconds = nsgCacheTTLDefaultInSeconds
 	}
-	return azcache.NewTimedcache(time.Duration(az.NsgCacheTTLInSeconds)*time.Second, getter)
+	return azcache.NewTimedcacheWithName(nsgCacheName, az.getCacheTTL(nsgCacheName, az.NsgCacheTTLInSeconds), getter)
 }
 
 func (az *Cloud) newRouteTableCache() (*azcache.TimedCache, error) {
@@ -296,7 +341,7 @@ func (az *Cloud) newRouteTableCache() (*azcache.TimedCache, error) {
 	if az.RouteTableCacheTTLInSeconds == 0 {
 		az.RouteTableCacheTTLInSeconds = routeTableCacheTTLDefaultInSeconds
 	}
-	return azcache.NewTimedcache(time.Duration(az.RouteTableCacheTTLInSeconds)*time.Second, getter)
+	return azcache.NewTimedcacheWithName(routeTableCacheName, az.getCacheTTL(routeTableCacheName, az.RouteTableCacheTTLInSeconds), getter)
 }
 
 func (az *Cloud) newPIPCache() (*azcache.TimedCache, error) {
@@ -327,7 +372,84 @@ func (az *Cloud) newPIPCache() (*azcache.TimedCache, error) {
 	if az.PublicIPCacheTTLInSeconds == 0 {
 		az.PublicIPCacheTTLInSeconds = publicIPCacheTTLDefaultInSeconds
 	}
-	return azcache.NewTimedcache(time.Duration(az.PublicIPCacheTTLInSeconds)*time.Second, getter)
+	pipCache, err := azcache.NewTimedcacheWithName(pipCacheName, az.getCacheTTL(pipCacheName, az.PublicIPCacheTTLInSeconds), getter)
+	if err != nil {
+		return nil, err
+	}
+	pipCache.NegativeTTL = negativeCacheTTL(pipCache.TTL)
+	return pipCache, nil
+}
+
+// ListCacheNames returns the sorted names of the caches whose TTLs could be configured by cacheTTLs in the cloud config.
+func ListCacheNames() []string {
+	return cacheNames.List()
+}
+
+// validateCacheTTLs validates the cache names and the TTLs configured by cacheTTLs in the cloud config.
+func validateCacheTTLs(cacheTTLs map[string]int) error {
+	for name, ttl := range cacheTTLs {
+		if !cacheNames.Has(name) {
+			return fmt.Errorf("cacheTTLs: cache %q is not supported, supported caches are %v", name, ListCacheNames())
+		}
+		if ttl < 0 {
+			return fmt.Errorf("cacheTTLs: TTL %d of cache %q is invalid, it should not be negative", ttl, name)
+		}
+	}
+	return nil
+}
+
+// getCacheTTL returns the TTL of the named cache. The TTL configured by cacheTTLs takes precedence over ttlInSeconds.
+func (config *Config) getCacheTTL(name string, ttlInSeconds int) time.Duration {
+	if ttl, ok := config.CacheTTLs[name]; ok {
+		return time.Duration(ttl) * time.Second
+	}
+	return time.Duration(ttlInSeconds) * time.Second
+}
+
+// negativeCacheTTL returns the TTL of the cached not found results, which is not longer than the TTL of the cache.
+func negativeCacheTTL(ttl time.Duration) time.Duration {
+	negativeTTL := consts.NegativeCacheTTLDefaultInSeconds * time.Second
+	if ttl < negativeTTL {
+		return ttl
+	}
+	return negativeTTL
+}
+
+// registerCacheInvalidators registers the invalidators of the caches, so that the caches
+// would be invalidated right after the resources are mutated by the Azure clients.
+func (az *Cloud) registerCacheInvalidators() {
+	if az.invalidationRegistry == nil {
+		return
+	}
+
+	// lbCache and nsgCache are keyed by the resource names.
+	az.invalidationRegistry.Register(loadBalancerResourceType, func(resourceID string) {
+		az.invalidateCacheByResourceID(az.lbCache, resourceID)
+	})
+	az.invalidationRegistry.Register(securityGroupResourceType, func(resourceID string) {
+		az.invalidateCacheByResourceID(az.nsgCache, resourceID)
+	})
+
+	// The VMSS VMs are cached per VMSS by the ScaleSet, so only the mutated VM is invalidated.
+	// The LoadBalancers whose backend pools are changed by the VMs joining or leaving them are
+	// invalidated by their callers, which know the affected LoadBalancers.
+	if ss, ok := az.VMSet.(*ScaleSet); ok {
+		az.invalidationRegistry.Register(vmssVMResourceType, ss.invalidateVMSSVMCacheByResourceID)
+	}
+}
+
+// invalidateCacheByResourceID deletes the cache entry keyed by the name of the resource.
+func (az *Cloud) invalidateCacheByResourceID(cache *azcache.TimedCache, resourceID string) {
+	if cache == nil {
+		return
+	}
+
+	name, err := getLastSegment(resourceID, "/")
+	if err != nil {
+		klog.Warningf("invalidateCacheByResourceID: failed to get the resource name from %q: %v", resourceID, err)
+		return
+	}
+	_ = cache.Delete(name)
 }
 
 func (az *Cloud) newPLSCache() (*azcache.TimedCache, error) {
@@ -368,7 +490,7 @@ func (az *Cloud) newPLSCache() (*azcache.TimedCache, error) {
 	if az.PlsCacheTTLInSeconds == 0 {
 		az.PlsCacheTTLInSeconds = plsCacheTTLDefaultInSeconds
 	}
-	return azcache.NewTimedcache(time.Duration(az.PlsCacheTTLInSeconds)*time.Second, getter)
+	return azcache.NewTimedcacheWithName(plsCacheName, az.getCacheTTL(plsCacheName, az.PlsCacheTTLInSeconds), getter)
 }
 
 func (az *Cloud) useStandardLoadBalancer() bool {
@@ -405,6 +527,17 @@ func (az *Cloud) IsNodeUnmanagedByProviderID(providerID string) bool {
 	return !azureNodeProviderIDRE.Match([]byte(providerID))
 }
 
+// extractSubscriptionAndResourceGroupByProviderID extracts the lower-cased subscription ID and
+// resource group name from a node's providerID or an Azure resource ID.
+func extractSubscriptionAndResourceGroupByProviderID(providerID string) (string, string, error) {
+	matches := azureResourceScopeRE.FindStringSubmatch(providerID)
+	if len(matches) != 3 {
+		return "", "", fmt.Errorf("%q isn't in Azure resource ID format %q", providerID, azureResourceScopeRE.String())
+	}
+
+	return strings.ToLower(matches[1]), strings.ToLower(matches[2]), nil
+}
+
 // convertResourceGroupNameToLower converts the resource group name in the resource ID to be lowered.
 func convertResourceGroupNameToLower(resourceID string) (string, error) {
 	matches := azureResourceGroupNameRE.FindStringSubmatch(resourceID)
@@ -17,15 +17,26 @@ limitations under the License.
 package provider
 
 import (
+	"context"
+	"fmt"
 	"net/http"
 	"reflect"
+	"sort"
 	"testing"
+	"time"
 
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2021-08-01/network"
+	"github.com/Azure/go-autorest/autorest/to"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 
 	"k8s.io/apimachinery/pkg/util/sets"
 
+	azclients "sigs.k8s.io/cloud-provider-azure/pkg/azureclients"
+	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/interfaceclient/mockinterfaceclient"
+	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/loadbalancerclient/mockloadbalancerclient"
+	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/securitygroupclient/mocksecuritygroupclient"
+	azcache "sigs.k8s.io/cloud-provider-azure/pkg/cache"
 	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
 	"sigs.k8s.io/cloud-provider-azure/pkg/retry"
 )
@@ -290,3 +301,159 @@ func TestIsBackendPoolOnSameLB(t *testing.T) {
 		assert.Equal(t, test.expectedLBName, lbName)
 	}
 }
+
+func TestRegisterCacheInvalidators(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	az := GetTestCloud(ctrl)
+	az.invalidationRegistry = azclients.NewInvalidationRegistry()
+	az.registerCacheInvalidators()
+
+	lbID := "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/lb"
+	nsgID := "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/networkSecurityGroups/nsg"
+	nicID := "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/networkInterfaces/nic"
+	lbV1 := network.LoadBalancer{ID: to.StringPtr(lbID), Name: to.StringPtr("lb"), Etag: to.StringPtr("1")}
+	lbV2 := network.LoadBalancer{ID: to.StringPtr(lbID), Name: to.StringPtr("lb"), Etag: to.StringPtr("2")}
+	nsgV1 := network.SecurityGroup{ID: to.StringPtr(nsgID), Name: to.StringPtr("nsg"), Etag: to.StringPtr("1")}
+	nsgV2 := network.SecurityGroup{ID: to.StringPtr(nsgID), Name: to.StringPtr("nsg"), Etag: to.StringPtr("2")}
+
+	// The mocked clients notify the registry after the PUT requests as the real clients do.
+	mockLBClient := az.LoadBalancerClient.(*mockloadbalancerclient.MockInterface)
+	gomock.InOrder(
+		mockLBClient.EXPECT().Get(gomock.Any(), az.ResourceGroup, "lb", gomock.Any()).Return(lbV1, nil),
+		mockLBClient.EXPECT().Get(gomock.Any(), az.ResourceGroup, "lb", gomock.Any()).Return(lbV2, nil),
+	)
+	mockLBClient.EXPECT().CreateOrUpdate(gomock.Any(), az.ResourceGroup, "lb", gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, resourceGroupName, loadBalancerName string, parameters network.LoadBalancer, etag string) *retry.Error {
+			az.invalidationRegistry.Invalidate(loadBalancerResourceType, lbID)
+			return nil
+		})
+	mockNSGClient := az.SecurityGroupsClient.(*mocksecuritygroupclient.MockInterface)
+	gomock.InOrder(
+		mockNSGClient.EXPECT().Get(gomock.Any(), az.ResourceGroup, "nsg", gomock.Any()).Return(nsgV1, nil),
+		mockNSGClient.EXPECT().Get(gomock.Any(), az.ResourceGroup, "nsg", gomock.Any()).Return(nsgV2, nil),
+	)
+	mockNSGClient.EXPECT().CreateOrUpdate(gomock.Any(), az.ResourceGroup, "nsg", gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, resourceGroupName, networkSecurityGroupName string, parameters network.SecurityGroup, etag string) *retry.Error {
+			az.invalidationRegistry.Invalidate(securityGroupResourceType, nsgID)
+			return nil
+		})
+	mockInterfaceClient := az.InterfacesClient.(*mockinterfaceclient.MockInterface)
+	mockInterfaceClient.EXPECT().CreateOrUpdate(gomock.Any(), az.ResourceGroup, "nic", gomock.Any()).DoAndReturn(
+		func(ctx context.Context, resourceGroupName, networkInterfaceName string, parameters network.Interface) *retry.Error {
+			az.invalidationRegistry.Invalidate("Microsoft.Network/networkInterfaces", nicID)
+			return nil
+		})
+
+	lb, exists, err := az.getAzureLoadBalancer("lb", azcache.CacheReadTypeDefault)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, lbV1, lb)
+
+	// the LB PUT should invalidate the cached LB.
+	ctx, cancel := getContextWithCancel()
+	defer cancel()
+	rerr := az.LoadBalancerClient.CreateOrUpdate(ctx, az.ResourceGroup, "lb", lbV1, "1")
+	assert.Nil(t, rerr)
+	lb, _, err = az.getAzureLoadBalancer("lb", azcache.CacheReadTypeDefault)
+	assert.NoError(t, err)
+	assert.Equal(t, lbV2, lb)
+
+	// the NIC PUT should not invalidate the cached LBs, which are invalidated by the callers updating the backend pools.
+	rerr = az.InterfacesClient.CreateOrUpdate(ctx, az.ResourceGroup, "nic", network.Interface{ID: to.StringPtr(nicID)})
+	assert.Nil(t, rerr)
+	lb, _, err = az.getAzureLoadBalancer("lb", azcache.CacheReadTypeDefault)
+	assert.NoError(t, err)
+	assert.Equal(t, lbV2, lb)
+
+	// the NSG PUT should invalidate the cached NSG.
+	az.SecurityGroupName = "nsg"
+	nsg, err := az.getSecurityGroup(azcache.CacheReadTypeDefault)
+	assert.NoError(t, err)
+	assert.Equal(t, nsgV1, nsg)
+	rerr = az.SecurityGroupsClient.CreateOrUpdate(ctx, az.ResourceGroup, "nsg", nsgV1, "1")
+	assert.Nil(t, rerr)
+	nsg, err = az.getSecurityGroup(azcache.CacheReadTypeDefault)
+	assert.NoError(t, err)
+	assert.Equal(t, nsgV2, nsg)
+}
+
+func TestValidateCacheTTLs(t *testing.T) {
+	for _, tc := range []struct {
+		desc        string
+		cacheTTLs   map[string]int
+		expectedErr string
+	}{
+		{
+			desc: "nil cacheTTLs should be valid",
+		},
+		{
+			desc:      "non-negative TTLs of the known caches should be valid",
+			cacheTTLs: map[string]int{lbCacheName: 600, nsgCacheName: 0},
+		},
+		{
+			desc:        "unknown cache names should be invalid",
+			cacheTTLs:   map[string]int{"unknown": 60},
+			expectedErr: fmt.Sprintf("cacheTTLs: cache \"unknown\" is not supported, supported caches are %v", ListCacheNames()),
+		},
+		{
+			desc:        "negative TTLs should be invalid",
+			cacheTTLs:   map[string]int{pipCacheName: -1},
+			expectedErr: "cacheTTLs: TTL -1 of cache \"pip\" is invalid, it should not be negative",
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := validateCacheTTLs(tc.cacheTTLs)
+			if tc.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestListCacheNames(t *testing.T) {
+	names := ListCacheNames()
+	assert.True(t, sort.StringsAreSorted(names))
+	assert.Contains(t, names, lbCacheName)
+	assert.Contains(t, names, vmssVirtualMachinesCacheName)
+}
+
+func TestCacheTTLs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	az := GetTestCloud(ctrl)
+	az.CacheTTLs = map[string]int{
+		lbCacheName:  600,
+		nsgCacheName: 0,
+	}
+
+	// the TTL in cacheTTLs should take precedence over the one configured separately
+	az.LoadBalancerCacheTTLInSeconds = 60
+	lbCache, err := az.newLBCache()
+	assert.NoError(t, err)
+	assert.Equal(t, 600*time.Second, lbCache.TTL)
+
+	// the caches not in cacheTTLs should keep their defaults
+	az.RouteTableCacheTTLInSeconds = 0
+	rtCache, err := az.newRouteTableCache()
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(routeTableCacheTTLDefaultInSeconds)*time.Second, rtCache.TTL)
+
+	// the caching of NSG should be disabled by the zero TTL
+	az.SecurityGroupName = "nsg"
+	az.nsgCache, err = az.newNSGCache()
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), az.nsgCache.TTL)
+
+	nsg := network.SecurityGroup{Name: to.StringPtr("nsg")}
+	mockNSGClient := az.SecurityGroupsClient.(*mocksecuritygroupclient.MockInterface)
+	mockNSGClient.EXPECT().Get(gomock.Any(), az.ResourceGroup, "nsg", gomock.Any()).Return(nsg, nil).Times(2)
+	for i := 0; i < 2; i++ {
+		_, err = az.getSecurityGroup(azcache.CacheReadTypeDefault)
+		assert.NoError(t, err)
+	}
+}
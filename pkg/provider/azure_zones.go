@@ -234,3 +234,23 @@ func (az *Cloud) GetPlatformSubFaultDomain() (string, error) {
 	}
 	return "", nil
 }
+
+// GetExtendedLocation returns the name of the extended location (e.g. the edge zone) of the local instance.
+// It is got from IMDS if set, otherwise the extended location configured for the cluster is returned.
+func (az *Cloud) GetExtendedLocation() (string, error) {
+	if az.UseInstanceMetadata {
+		metadata, err := az.Metadata.GetMetadata(azcache.CacheReadTypeUnsafe)
+		if err != nil {
+			klog.Errorf("GetExtendedLocation: failed to GetMetadata: %s", err.Error())
+			return "", err
+		}
+		if metadata.Compute == nil {
+			_ = az.Metadata.imsCache.Delete(consts.MetadataCacheKey)
+			return "", errors.New("failure of getting compute information from instance metadata")
+		}
+		if metadata.Compute.ExtendedLocation != nil && metadata.Compute.ExtendedLocation.Name != "" {
+			return metadata.Compute.ExtendedLocation.Name, nil
+		}
+	}
+	return az.ExtendedLocationName, nil
+}
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/zoneclient/mockzoneclient"
@@ -247,6 +248,73 @@ func TestGetPlatformSubFaultDomain(t *testing.T) {
 	}
 }
 
+func TestGetExtendedLocation(t *testing.T) {
+	for _, testCase := range []struct {
+		description              string
+		useInstanceMetadata      bool
+		respString               string
+		extendedLocationName     string
+		expectedExtendedLocation string
+		expectedErr              error
+	}{
+		{
+			description:              "GetExtendedLocation should parse the extended location from IMDS",
+			useInstanceMetadata:      true,
+			respString:               `{"compute":{"location":"westus", "extendedLocation": {"type": "edgeZone", "name": "microsoftlosangeles1"}}}`,
+			expectedExtendedLocation: "microsoftlosangeles1",
+		},
+		{
+			description:              "GetExtendedLocation should return the configured extended location if it is not set in IMDS",
+			useInstanceMetadata:      true,
+			respString:               `{"compute":{"location":"westus"}}`,
+			extendedLocationName:     "microsoftlosangeles1",
+			expectedExtendedLocation: "microsoftlosangeles1",
+		},
+		{
+			description:         "GetExtendedLocation should return empty string for the instances not in extended locations",
+			useInstanceMetadata: true,
+			respString:          `{"compute":{"location":"westus"}}`,
+		},
+		{
+			description:              "GetExtendedLocation should return the configured extended location if IMDS is not used",
+			extendedLocationName:     "microsoftlosangeles1",
+			expectedExtendedLocation: "microsoftlosangeles1",
+		},
+		{
+			description:         "GetExtendedLocation should report an error if the compute is nil",
+			useInstanceMetadata: true,
+			respString:          "{}",
+			expectedErr:         errors.New("failure of getting compute information from instance metadata"),
+		},
+	} {
+		t.Run(testCase.description, func(t *testing.T) {
+			cloud := &Cloud{
+				Config: Config{
+					Location:             "westus",
+					UseInstanceMetadata:  testCase.useInstanceMetadata,
+					ExtendedLocationName: testCase.extendedLocationName,
+				},
+			}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, testCase.respString)
+			}))
+			defer server.Close()
+
+			var err error
+			cloud.Metadata, err = NewInstanceMetadataService(server.URL + "/")
+			assert.NoError(t, err)
+
+			extendedLocation, err := cloud.GetExtendedLocation()
+			if testCase.expectedErr != nil {
+				assert.Equal(t, testCase.expectedErr, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, testCase.expectedExtendedLocation, extendedLocation)
+			}
+		})
+	}
+}
+
 func TestMakeZone(t *testing.T) {
 	az := &Cloud{}
 	zone := az.makeZone("EASTUS", 2)
@@ -45,6 +45,14 @@ func ByVMSS(vmssName string) ManageOption {
 	}
 }
 
+// InResourceGroup specifies the subscription and the resource group of the virtual machine.
+func InResourceGroup(subscriptionID, resourceGroup string) ManageOption {
+	return func(vm *VirtualMachine) {
+		vm.SubscriptionID = subscriptionID
+		vm.ResourceGroup = resourceGroup
+	}
+}
+
 type VirtualMachine struct {
 	Variant Variant
 	vm      *compute.VirtualMachine
@@ -53,6 +61,11 @@ type VirtualMachine struct {
 	Manage   Manage
 	VMSSName string
 
+	// SubscriptionID and ResourceGroup are the subscription and the resource group of the virtual machine,
+	// they are empty if not specified by InResourceGroup.
+	SubscriptionID string
+	ResourceGroup  string
+
 	// re-export fields
 	// common fields
 	ID        string
@@ -99,7 +112,7 @@ func FromVirtualMachine(vm *compute.VirtualMachine, opt ...ManageOption) *Virtua
 	return v
 }
 
-func FromVirtualMachineScaleSetVM(vm *compute.VirtualMachineScaleSetVM, opt ManageOption) *VirtualMachine {
+func FromVirtualMachineScaleSetVM(vm *compute.VirtualMachineScaleSetVM, opt ...ManageOption) *VirtualMachine {
 	v := &VirtualMachine{
 		Variant: VariantVirtualMachineScaleSetVM,
 		vmssVM:  vm,
@@ -120,7 +133,9 @@ func FromVirtualMachineScaleSetVM(vm *compute.VirtualMachineScaleSetVM, opt Mana
 
 	// TODO: should validate manage option
 	// VirtualMachineScaleSetVM should always be managed by VMSS
-	opt(v)
+	for _, opt := range opt {
+		opt(v)
+	}
 
 	return v
 }
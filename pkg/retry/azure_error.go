@@ -18,7 +18,9 @@ package retry
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -41,6 +43,11 @@ var (
 	// The function to get current time.
 	now = time.Now
 
+	// ErrThrottled is the sentinel error matched by the Errors of the throttled requests, see Error.Is.
+	ErrThrottled = errors.New("request is throttled")
+	// ErrNotFound is the sentinel error matched by the Errors of the requests whose objects are not found, see Error.Is.
+	ErrNotFound = errors.New("object is not found")
+
 	// StatusCodesForRetry are a defined group of status code for which the client will retry.
 	StatusCodesForRetry = []int{
 		http.StatusRequestTimeout,      // 408
@@ -62,6 +69,13 @@ type Error struct {
 	RetryAfter time.Time
 	// RetryAfter indicates the raw error from API.
 	RawError error
+	// Retries indicates how many times the request has been retried.
+	Retries int
+	// TotalWait indicates the total backoff duration waited between the retries.
+	TotalWait time.Duration
+
+	// message is the context of the error prepended by WithMessage, e.g. the operation that failed.
+	message string
 }
 
 // RawErrorContainer is the container of the Error.RawError
@@ -85,10 +99,34 @@ func (err *Error) Error() error {
 		retryAfterSeconds = int(err.RetryAfter.Sub(curTime) / time.Second)
 	}
 
+	if err.message != "" {
+		return fmt.Errorf("%s: Retriable: %v, RetryAfter: %ds, HTTPStatusCode: %d, RawError: %w",
+			err.message, err.Retriable, retryAfterSeconds, err.HTTPStatusCode, err.RawError)
+	}
 	return fmt.Errorf("Retriable: %v, RetryAfter: %ds, HTTPStatusCode: %d, RawError: %w",
 		err.Retriable, retryAfterSeconds, err.HTTPStatusCode, err.RawError)
 }
 
+// WithMessage returns a copy of the Error with the message prepended to its context, e.g. the operation that
+// failed, so that the context is not lost when the Error is returned through several layers. The status code,
+// the retriable classification and the raw error are preserved. The calls could be chained, and the message
+// of the last call comes first.
+func (err *Error) WithMessage(msg string) *Error {
+	if err == nil {
+		return nil
+	}
+
+	wrapped := *err
+	if msg != "" {
+		if wrapped.message == "" {
+			wrapped.message = msg
+		} else {
+			wrapped.message = msg + ": " + wrapped.message
+		}
+	}
+	return &wrapped
+}
+
 // IsThrottled returns true the if the request is being throttled.
 func (err *Error) IsThrottled() bool {
 	if err == nil {
@@ -107,6 +145,43 @@ func (err *Error) IsNotFound() bool {
 	return err.HTTPStatusCode == http.StatusNotFound
 }
 
+// Is returns true if the Error matches the target. The sentinel errors ErrThrottled and ErrNotFound are matched
+// by the status codes and the throttling classification of the Error, and other targets are matched by the RawError.
+// Since Error doesn't implement the error interface, it should be called directly, e.g. rerr.Is(retry.ErrThrottled).
+func (err *Error) Is(target error) bool {
+	if err == nil {
+		return false
+	}
+
+	switch target {
+	case ErrThrottled:
+		return err.IsThrottled()
+	case ErrNotFound:
+		return err.IsNotFound()
+	}
+	return errors.Is(err.RawError, target)
+}
+
+// IsPreconditionFailed returns true the if the request is rejected because of the mismatched ETag in If-Match
+func (err *Error) IsPreconditionFailed() bool {
+	if err == nil {
+		return false
+	}
+
+	return err.HTTPStatusCode == http.StatusPreconditionFailed
+}
+
+// IsResourceLocked returns true if the request is rejected because the resource or its scope,
+// e.g. the resource group, is locked by a CanNotDelete or ReadOnly lock.
+func (err *Error) IsResourceLocked() bool {
+	if err == nil {
+		return false
+	}
+
+	code := err.ServiceErrorCode()
+	return strings.EqualFold(code, ScopeLocked) || strings.EqualFold(code, ResourceGroupLocked)
+}
+
 // NewError creates a new Error.
 func NewError(retriable bool, err error) *Error {
 	return &Error{
@@ -123,6 +198,15 @@ func NewErrorOrNil(retriable bool, err error) *Error {
 	return NewError(retriable, err)
 }
 
+// GetContextError returns a non-retriable Error if the context has been cancelled
+// or its deadline has been exceeded, otherwise it returns nil.
+func GetContextError(ctx context.Context) *Error {
+	if ctx.Err() == nil {
+		return nil
+	}
+	return NewError(false, ctx.Err())
+}
+
 // GetRetriableError gets new retriable Error.
 func GetRetriableError(err error) *Error {
 	return &Error{
@@ -165,12 +249,17 @@ func GetError(resp *http.Response, err error) *Error {
 	if retryAfterDuration := getRetryAfter(resp); retryAfterDuration != 0 {
 		retryAfter = now().Add(retryAfterDuration)
 	}
-	return &Error{
+	rerr := &Error{
 		RawError:       getRawError(resp, err),
 		RetryAfter:     retryAfter,
 		Retriable:      shouldRetryHTTPRequest(resp, err),
 		HTTPStatusCode: getHTTPStatusCode(resp),
 	}
+	// the request would keep failing until the lock is removed
+	if rerr.IsResourceLocked() {
+		rerr.Retriable = false
+	}
+	return rerr
 }
 
 // isSuccessHTTPResponse determines if the response from an HTTP request suggests success
@@ -270,6 +359,10 @@ func GetErrorWithRetriableHTTPStatusCodes(resp *http.Response, err error, retria
 		return nil
 	}
 
+	if rerr.IsResourceLocked() {
+		return rerr
+	}
+
 	for _, code := range retriableHTTPStatusCodes {
 		if rerr.HTTPStatusCode == code {
 			rerr.Retriable = true
@@ -356,6 +449,10 @@ const (
 	OperationNotAllowed string = "OperationNotAllowed"
 	// QuotaExceeded falls under OperationNotAllowed error code but we make it more specific here
 	QuotaExceeded string = "QuotaExceeded"
+	// ScopeLocked is returned if the resource or its parent scope is locked
+	ScopeLocked string = "ScopeLocked"
+	// ResourceGroupLocked is returned if the resource group is locked
+	ResourceGroupLocked string = "ResourceGroupLocked"
 )
 
 // ServiceRawError wraps the RawError field satisfying autorest.ServiceError
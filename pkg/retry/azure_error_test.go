@@ -18,15 +18,26 @@ package retry
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
+// ScopeLockedRawError is the ScopeLocked raw error of deleting a resource in a locked resource group
+const ScopeLockedRawError = `{
+	"error": {
+		"code": "ScopeLocked",
+		"message": "The scope '/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/publicIPAddresses/pip' cannot perform delete operation because following scope(s) are locked: '/subscriptions/sub/resourceGroups/rg'. Please remove the lock and try again."
+	}
+}`
+
 // LBInUseRawError is the LoadBalancerInUseByVirtualMachineScaleSet raw error
 const LBInUseRawError = `{
 	"error": {
@@ -50,6 +61,18 @@ func TestGetRetriableError(t *testing.T) {
 	assert.Equal(t, rawErr, newErr.RawError)
 }
 
+func TestGetContextError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	assert.Nil(t, GetContextError(ctx))
+
+	cancel()
+	rerr := GetContextError(ctx)
+	assert.NotNil(t, rerr)
+	assert.False(t, rerr.Retriable)
+	assert.Equal(t, context.Canceled, rerr.RawError)
+	assert.ErrorIs(t, rerr.Error(), context.Canceled)
+}
+
 func TestGetRateLimitError(t *testing.T) {
 	opType := "write"
 	opName := "opNameTest"
@@ -356,6 +379,161 @@ func TestIsThrottled(t *testing.T) {
 	}
 }
 
+func TestErrorIs(t *testing.T) {
+	tests := []struct {
+		desc     string
+		err      *Error
+		target   error
+		expected bool
+	}{
+		{
+			desc:     "nil Error should not match",
+			err:      nil,
+			target:   ErrThrottled,
+			expected: false,
+		},
+		{
+			desc:     "Error with 429 status code should match ErrThrottled",
+			err:      &Error{HTTPStatusCode: http.StatusTooManyRequests},
+			target:   ErrThrottled,
+			expected: true,
+		},
+		{
+			desc:     "Error with RetryAfter in the future should match ErrThrottled",
+			err:      GetThrottlingError("op", "reason", time.Now().Add(time.Hour)),
+			target:   ErrThrottled,
+			expected: true,
+		},
+		{
+			desc:     "Error with 404 status code should match ErrNotFound",
+			err:      &Error{HTTPStatusCode: http.StatusNotFound},
+			target:   ErrNotFound,
+			expected: true,
+		},
+		{
+			desc:     "Error with 404 status code should not match ErrThrottled",
+			err:      &Error{HTTPStatusCode: http.StatusNotFound},
+			target:   ErrThrottled,
+			expected: false,
+		},
+		{
+			desc:     "Error with 500 status code should not match ErrNotFound",
+			err:      &Error{HTTPStatusCode: http.StatusInternalServerError},
+			target:   ErrNotFound,
+			expected: false,
+		},
+		{
+			desc:     "Error should match the wrapped RawError",
+			err:      NewError(false, fmt.Errorf("request failed: %w", context.Canceled)),
+			target:   context.Canceled,
+			expected: true,
+		},
+		{
+			desc:     "Error should not match other errors",
+			err:      NewError(false, fmt.Errorf("request failed")),
+			target:   context.Canceled,
+			expected: false,
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, test.err.Is(test.target), test.desc)
+	}
+}
+
+func TestErrorWithMessage(t *testing.T) {
+	assert.Nil(t, (*Error)(nil).WithMessage("failed to get the load balancer"))
+
+	rawErr := fmt.Errorf(`{"error":{"code":"TooManyRequests","message":"throttled"}}`)
+	throttled := &Error{Retriable: true, HTTPStatusCode: http.StatusTooManyRequests, RawError: rawErr}
+	wrapped := throttled.WithMessage("failed to get the load balancer").WithMessage("failed to reconcile service default/svc")
+	assert.True(t, wrapped.IsThrottled())
+	assert.True(t, wrapped.Is(ErrThrottled))
+	assert.False(t, wrapped.IsNotFound())
+	assert.True(t, wrapped.Retriable)
+	assert.Equal(t, http.StatusTooManyRequests, wrapped.HTTPStatusCode)
+	assert.Equal(t, rawErr, wrapped.RawError)
+	assert.Equal(t, "TooManyRequests", wrapped.ServiceErrorCode())
+	assert.True(t, errors.Is(wrapped.Error(), rawErr))
+	assert.True(t, strings.HasPrefix(wrapped.Error().Error(), "failed to reconcile service default/svc: failed to get the load balancer: Retriable: true"))
+	assert.True(t, strings.HasPrefix(throttled.Error().Error(), "Retriable: true"), "the original error should not be changed")
+
+	notFound := (&Error{HTTPStatusCode: http.StatusNotFound, RawError: fmt.Errorf("not found")}).WithMessage("failed to get the public IP")
+	assert.True(t, notFound.IsNotFound())
+	assert.True(t, notFound.Is(ErrNotFound))
+	assert.False(t, notFound.IsThrottled())
+	assert.False(t, notFound.Retriable)
+	assert.Equal(t, "failed to get the public IP: Retriable: false, RetryAfter: 0s, HTTPStatusCode: 404, RawError: not found", notFound.Error().Error())
+}
+
+func TestIsPreconditionFailed(t *testing.T) {
+	tests := []struct {
+		err      *Error
+		expected bool
+	}{
+		{
+			err:      nil,
+			expected: false,
+		},
+		{
+			err: &Error{
+				HTTPStatusCode: http.StatusConflict,
+			},
+			expected: false,
+		},
+		{
+			err: &Error{
+				HTTPStatusCode: http.StatusPreconditionFailed,
+			},
+			expected: true,
+		},
+	}
+
+	for _, test := range tests {
+		real := test.err.IsPreconditionFailed()
+		assert.Equal(t, test.expected, real)
+	}
+}
+
+func TestIsResourceLocked(t *testing.T) {
+	lockedResp := &http.Response{
+		StatusCode: http.StatusConflict,
+		Body:       ioutil.NopCloser(bytes.NewReader([]byte(ScopeLockedRawError))),
+	}
+	rerr := GetError(lockedResp, nil)
+	assert.True(t, rerr.IsResourceLocked())
+	assert.False(t, rerr.Retriable)
+
+	tests := []struct {
+		err      *Error
+		expected bool
+	}{
+		{
+			err:      nil,
+			expected: false,
+		},
+		{
+			err: &Error{
+				HTTPStatusCode: http.StatusConflict,
+				RawError:       fmt.Errorf(`{"error":{"code":"Conflict","message":"conflict"}}`),
+			},
+			expected: false,
+		},
+		{
+			err: &Error{
+				HTTPStatusCode: http.StatusConflict,
+				RawError:       fmt.Errorf(`{"error":{"code":"ResourceGroupLocked","message":"locked"}}`),
+			},
+			expected: true,
+		},
+	}
+
+	for _, test := range tests {
+		real := test.err.IsResourceLocked()
+		assert.Equal(t, test.expected, real)
+	}
+}
+
 func TestIsErrorRetriable(t *testing.T) {
 	// false case
 	result := IsErrorRetriable(nil)
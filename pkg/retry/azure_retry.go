@@ -17,10 +17,15 @@ limitations under the License.
 package retry
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"html"
+	"io"
 	"math/rand"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Azure/go-autorest/autorest"
@@ -45,20 +50,103 @@ type Backoff struct {
 	// zero and `jitter*duration`.
 	Jitter float64
 	// The remaining number of iterations in which the duration
-	// parameter may change (but progress can be stopped earlier by
-	// hitting the cap). If not positive, the duration is not
+	// parameter may change. If not positive, the duration is not
 	// changed. Used for exponential backoff in combination with
 	// Factor and Cap.
 	Steps int
 	// A limit on revised values of the duration parameter. If a
 	// multiplication by the factor parameter would make the duration
-	// exceed the cap then the duration is set to the cap and the
-	// steps parameter is set to zero.
+	// exceed the cap then the duration is set to the cap, so that the
+	// following intervals plateau. The steps parameter is not changed
+	// since it is the remaining number of attempts of the requests.
+	// Not limited if it is not positive.
 	Cap time.Duration
 	// The errors indicate that the request shouldn't do more retrying.
 	NonRetriableErrors []string
 	// The RetriableHTTPStatusCodes indicates that the HTTPStatusCode should do more retrying.
 	RetriableHTTPStatusCodes []int
+	// PerTryTimeout bounds each attempt of the requests, the attempts exceeding it are cancelled
+	// and retried as the other retriable failures. Not limited if it is not positive.
+	PerTryTimeout time.Duration
+	// OverallTimeout bounds the whole retry sequence of the requests including the backoff delays,
+	// no more attempts are made once it is exceeded. Not limited if it is not positive.
+	OverallTimeout time.Duration
+}
+
+// Stats records the retries consumed by the requests sent with the context carrying it.
+// It is populated even if the requests succeed eventually.
+type Stats struct {
+	lock sync.Mutex
+	// Retries is the number of retries after the first attempts.
+	Retries int
+	// TotalWait is the total backoff duration waited between the attempts.
+	TotalWait time.Duration
+}
+
+// statsContextKey is the context key of the Stats.
+type statsContextKey struct{}
+
+// WithStats returns a new context carrying stats, which records the retries
+// of all the requests sent with the returned context.
+func WithStats(ctx context.Context, stats *Stats) context.Context {
+	return context.WithValue(ctx, statsContextKey{}, stats)
+}
+
+// StatsFromContext returns the Stats carried by the context, or nil if there isn't any.
+func StatsFromContext(ctx context.Context) *Stats {
+	if ctx == nil {
+		return nil
+	}
+	stats, _ := ctx.Value(statsContextKey{}).(*Stats)
+	return stats
+}
+
+// noRetryContextKey is the context key marking the requests that shouldn't be retried.
+type noRetryContextKey struct{}
+
+// WithNoRetry returns a new context with which the requests are sent only once, regardless of
+// the backoff configured on the client. It is used by the callers implementing their own retry loop.
+func WithNoRetry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noRetryContextKey{}, true)
+}
+
+// IsNoRetry returns true if the requests sent with the context shouldn't be retried.
+func IsNoRetry(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	noRetry, _ := ctx.Value(noRetryContextKey{}).(bool)
+	return noRetry
+}
+
+// record records a retry after waiting for the duration.
+func (s *Stats) record(wait time.Duration) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.Retries++
+	s.TotalWait += wait
+}
+
+// Get returns the number of retries and the total backoff duration recorded.
+func (s *Stats) Get() (int, time.Duration) {
+	if s == nil {
+		return 0, 0
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.Retries, s.TotalWait
+}
+
+// WithStats sets the retries recorded by stats to the Error.
+func (err *Error) WithStats(stats *Stats) *Error {
+	if err == nil {
+		return nil
+	}
+	err.Retries, err.TotalWait = stats.Get()
+	return err
 }
 
 // NewBackoff creates a new Backoff.
@@ -120,7 +208,6 @@ func (b *Backoff) Step() time.Duration {
 		b.Duration = time.Duration(float64(b.Duration) * b.Factor)
 		if b.Cap > 0 && b.Duration > b.Cap {
 			b.Duration = b.Cap
-			b.Steps = 0
 		}
 	}
 
@@ -156,35 +243,59 @@ func DoExponentialBackoffRetry(backoff *Backoff) autorest.SendDecorator {
 // backoff is a retry policy here we implicitly copy the backoff policy when args is passed to function.
 
 func doBackoffRetry(s autorest.Sender, r *http.Request, backoff Backoff) (resp *http.Response, err error) {
+	parent := r.Context()
+	if backoff.OverallTimeout > 0 {
+		ctx, cancel := context.WithTimeout(parent, backoff.OverallTimeout)
+		defer func() {
+			resp = CancelOnClose(resp, cancel)
+		}()
+		r = r.WithContext(ctx)
+	}
+
 	rr := autorest.NewRetriableRequest(r)
+	stats := StatsFromContext(r.Context())
+	if IsNoRetry(r.Context()) {
+		backoff.Steps = 1
+	}
 	// Increment to add the first call (attempts denotes number of retries)
 	for backoff.Steps > 0 {
+		// Stop retrying as soon as the request context is done.
+		if r.Context().Err() != nil {
+			return resp, getOverallContextError(parent, r.Context(), backoff.OverallTimeout)
+		}
 		err = rr.Prepare()
 		if err != nil {
 			return
 		}
-		resp, err = s.Do(rr.Request())
+		resp, err = doTry(s, rr.Request(), backoff.PerTryTimeout)
 		rerr := GetErrorWithRetriableHTTPStatusCodes(resp, err, backoff.RetriableHTTPStatusCodes)
 		// Abort retries in the following scenarios:
 		// 1) request succeed
 		// 2) request is not retriable
 		// 3) request has been throttled
 		// 4) request contains non-retriable errors
-		// 5) request has completed all the retry steps
+		// 5) resource is locked
+		// 6) request has completed all the retry steps
 		if rerr == nil {
 			return resp, nil
 		}
 
-		if !rerr.Retriable || rerr.IsThrottled() || backoff.isNonRetriableError(rerr) || backoff.Steps == 1 {
+		if !rerr.Retriable || rerr.IsThrottled() || backoff.isNonRetriableError(rerr) || rerr.IsResourceLocked() || backoff.Steps == 1 {
 			return resp, rerr.RawError
 		}
 
-		if !delayForBackOff(&backoff, r.Context().Done()) {
+		wait, ok := delayForBackOff(&backoff, r.Context().Done())
+		if !ok {
 			if r.Context().Err() != nil {
-				return resp, r.Context().Err()
+				return resp, getOverallContextError(parent, r.Context(), backoff.OverallTimeout)
 			}
 			return resp, rerr.RawError
 		}
+		stats.record(wait)
+		// The response of the failed attempt is replaced by that of the next one.
+		if resp != nil && resp.Body != nil {
+			resp.Body.Close()
+		}
 
 		klog.V(3).Infof("Backoff retrying %s %q with error %v", r.Method, html.EscapeString(r.URL.String()), rerr)
 	}
@@ -192,14 +303,63 @@ func doBackoffRetry(s autorest.Sender, r *http.Request, backoff Backoff) (resp *
 	return resp, err
 }
 
-// delayForBackOff invokes time.After for the supplied backoff duration.
+// doTry sends the request once. The attempt is cancelled if it exceeds perTryTimeout, in which case the
+// error of the attempt is retriable since the response is nil.
+func doTry(s autorest.Sender, r *http.Request, perTryTimeout time.Duration) (*http.Response, error) {
+	if perTryTimeout <= 0 {
+		return s.Do(r)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), perTryTimeout)
+	resp, err := s.Do(r.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return resp, err
+	}
+	return CancelOnClose(resp, cancel), nil
+}
+
+// CancelOnClose defers cancel until the body of the response is closed, so that the body could still be read
+// after the request returns. cancel is called immediately if there is no body.
+func CancelOnClose(resp *http.Response, cancel context.CancelFunc) *http.Response {
+	if resp == nil || resp.Body == nil {
+		cancel()
+		return resp
+	}
+	resp.Body = &cancelOnCloseBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp
+}
+
+// cancelOnCloseBody is a response body which cancels the context of the request when it is closed.
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close closes the body and cancels the context of the request.
+func (b *cancelOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// getOverallContextError returns the error of the context of the retries, which is annotated if the retries
+// exceed the overall timeout rather than the parent context is done.
+func getOverallContextError(parent, ctx context.Context, overallTimeout time.Duration) error {
+	if parent.Err() == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("the retries of the request exceed the overall timeout %v: %w", overallTimeout, ctx.Err())
+	}
+	return ctx.Err()
+}
+
+// delayForBackOff invokes time.After for the supplied backoff duration and returns the duration.
 // The delay may be canceled by closing the passed channel. If terminated early, returns false.
-func delayForBackOff(backoff *Backoff, cancel <-chan struct{}) bool {
+func delayForBackOff(backoff *Backoff, cancel <-chan struct{}) (time.Duration, bool) {
 	d := backoff.Step()
 	select {
 	case <-time.After(d):
-		return true
+		return d, true
 	case <-cancel:
-		return false
+		return d, false
 	}
 }
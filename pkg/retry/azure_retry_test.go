@@ -17,6 +17,7 @@ limitations under the License.
 package retry
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"net/http"
@@ -147,6 +148,26 @@ func TestStep(t *testing.T) {
 	}
 }
 
+func TestStepCap(t *testing.T) {
+	backoff := &Backoff{Duration: time.Second, Factor: 2, Steps: 100, Cap: 30 * time.Second}
+	var last time.Duration
+	for i := 0; i < 100; i++ {
+		got := backoff.Step()
+		assert.LessOrEqual(t, got, 30*time.Second, "Backoff.Step(%d) exceeds the cap", i)
+		assert.GreaterOrEqual(t, got, last, "Backoff.Step(%d) decreases", i)
+		last = got
+	}
+	assert.Equal(t, 30*time.Second, last)
+	assert.Equal(t, 0, backoff.Steps)
+
+	// the intervals are not limited if the cap is not set
+	backoff = &Backoff{Duration: time.Second, Factor: 2, Steps: 10}
+	for i := 0; i < 10; i++ {
+		last = backoff.Step()
+	}
+	assert.Equal(t, 512*time.Second, last)
+}
+
 func TestDoBackoffRetry(t *testing.T) {
 	fakeRequest := &http.Request{
 		URL: &url.URL{
@@ -257,3 +278,180 @@ func TestDoBackoffRetry(t *testing.T) {
 	assert.Equal(t, expectedErr.RawError, err)
 	assert.Equal(t, 3, client.Attempts())
 }
+
+func TestDoBackoffRetryStats(t *testing.T) {
+	fakeRequest := &http.Request{
+		URL: &url.URL{
+			Host: "localhost",
+			Path: "/api",
+		},
+	}
+
+	// the stats are populated when the request succeeds eventually
+	client := mocks.NewSender()
+	client.AppendAndRepeatResponse(mocks.NewResponseWithStatus("500 InternalServerError", http.StatusInternalServerError), 2)
+	client.AppendResponse(mocks.NewResponseWithStatus("200 OK", http.StatusOK))
+	stats := &Stats{}
+	resp, err := doBackoffRetry(client, fakeRequest.WithContext(WithStats(context.Background(), stats)), Backoff{Duration: 10 * time.Millisecond, Factor: 1.0, Steps: 5})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 3, client.Attempts())
+	retries, totalWait := stats.Get()
+	assert.Equal(t, 2, retries)
+	assert.Equal(t, 20*time.Millisecond, totalWait)
+
+	// the stats are populated when the retries are exhausted
+	client = mocks.NewSender()
+	client.AppendAndRepeatResponse(mocks.NewResponseWithStatus("500 InternalServerError", http.StatusInternalServerError), 3)
+	stats = &Stats{}
+	_, err = doBackoffRetry(client, fakeRequest.WithContext(WithStats(context.Background(), stats)), Backoff{Duration: 10 * time.Millisecond, Factor: 2.0, Steps: 3})
+	assert.Error(t, err)
+	assert.Equal(t, 3, client.Attempts())
+	rerr := (&Error{RawError: err}).WithStats(stats)
+	assert.Equal(t, 2, rerr.Retries)
+	assert.Equal(t, 30*time.Millisecond, rerr.TotalWait)
+
+	// the request without stats in its context is still retried
+	client = mocks.NewSender()
+	client.AppendAndRepeatResponse(mocks.NewResponseWithStatus("500 InternalServerError", http.StatusInternalServerError), 1)
+	client.AppendResponse(mocks.NewResponseWithStatus("200 OK", http.StatusOK))
+	_, err = doBackoffRetry(client, fakeRequest, Backoff{Factor: 1.0, Steps: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, client.Attempts())
+	assert.Nil(t, StatsFromContext(fakeRequest.Context()))
+}
+
+func TestDoBackoffRetryCap(t *testing.T) {
+	fakeRequest := &http.Request{
+		URL: &url.URL{
+			Host: "localhost",
+			Path: "/api",
+		},
+	}
+
+	// reaching the cap shouldn't stop the retries
+	client := mocks.NewSender()
+	client.AppendAndRepeatResponse(mocks.NewResponseWithStatus("500 InternalServerError", http.StatusInternalServerError), 5)
+	stats := &Stats{}
+	_, err := doBackoffRetry(client, fakeRequest.WithContext(WithStats(context.Background(), stats)), Backoff{Duration: time.Millisecond, Factor: 10, Steps: 5, Cap: 5 * time.Millisecond})
+	assert.Error(t, err)
+	assert.Equal(t, 5, client.Attempts())
+	retries, totalWait := stats.Get()
+	assert.Equal(t, 4, retries)
+	assert.Equal(t, 16*time.Millisecond, totalWait)
+}
+
+func TestDoBackoffRetryNoRetry(t *testing.T) {
+	fakeRequest := &http.Request{
+		URL: &url.URL{
+			Host: "localhost",
+			Path: "/api",
+		},
+	}
+
+	client := mocks.NewSender()
+	client.AppendAndRepeatResponse(mocks.NewResponseWithStatus("500 InternalServerError", http.StatusInternalServerError), 3)
+	ctx := WithNoRetry(context.Background())
+	assert.True(t, IsNoRetry(ctx))
+	resp, err := doBackoffRetry(client, fakeRequest.WithContext(ctx), Backoff{Factor: 1.0, Steps: 3})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	assert.Equal(t, 1, client.Attempts())
+	assert.False(t, IsNoRetry(context.Background()))
+}
+
+func TestDoBackoffRetryResourceLocked(t *testing.T) {
+	fakeRequest := &http.Request{
+		URL: &url.URL{
+			Host: "localhost",
+			Path: "/api",
+		},
+	}
+
+	client := mocks.NewSender()
+	client.AppendAndRepeatResponse(mocks.NewResponseWithBodyAndStatus(mocks.NewBody(ScopeLockedRawError), http.StatusConflict, "409 Conflict"), 3)
+	resp, err := doBackoffRetry(client, fakeRequest, Backoff{Factor: 1.0, Steps: 3, RetriableHTTPStatusCodes: []int{http.StatusConflict}})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Equal(t, 1, client.Attempts())
+}
+
+func TestDoBackoffRetryContextCanceled(t *testing.T) {
+	r := mocks.NewResponseWithStatus("500 InternalServerError", http.StatusInternalServerError)
+	client := mocks.NewSender()
+	client.AppendAndRepeatResponse(r, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	fakeRequest := (&http.Request{
+		URL: &url.URL{
+			Host: "localhost",
+			Path: "/api",
+		},
+	}).WithContext(ctx)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+
+	start := time.Now()
+	_, err := doBackoffRetry(client, fakeRequest, Backoff{Duration: time.Minute, Factor: 1.0, Steps: 10})
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, client.Attempts())
+
+	// a cancelled context doesn't send any request
+	client = mocks.NewSender()
+	client.AppendAndRepeatResponse(r, 10)
+	_, err = doBackoffRetry(client, fakeRequest, Backoff{Factor: 1.0, Steps: 3})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 0, client.Attempts())
+}
+
+func TestDoBackoffRetryPerTryTimeout(t *testing.T) {
+	fakeRequest := &http.Request{
+		URL: &url.URL{
+			Host: "localhost",
+			Path: "/api",
+		},
+	}
+	attempts := 0
+	slowSender := autorest.SenderFunc(func(r *http.Request) (*http.Response, error) {
+		attempts++
+		<-r.Context().Done()
+		return nil, r.Context().Err()
+	})
+
+	start := time.Now()
+	_, err := doBackoffRetry(slowSender, fakeRequest, Backoff{
+		Duration:       10 * time.Millisecond,
+		Factor:         1.0,
+		Steps:          100,
+		PerTryTimeout:  50 * time.Millisecond,
+		OverallTimeout: 300 * time.Millisecond,
+	})
+	assert.Less(t, time.Since(start), 2*time.Second)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "overall timeout 300ms")
+	assert.Greater(t, attempts, 1, "the attempts exceeding the per-try timeout should be retried")
+	assert.Less(t, attempts, 100, "the retries should stop at the overall timeout")
+}
+
+func TestDoBackoffRetryPerTryTimeoutResponseBody(t *testing.T) {
+	fakeRequest := &http.Request{
+		URL: &url.URL{
+			Host: "localhost",
+			Path: "/api",
+		},
+	}
+	var attemptCtx context.Context
+	sender := autorest.SenderFunc(func(r *http.Request) (*http.Response, error) {
+		attemptCtx = r.Context()
+		return mocks.NewResponse(), nil
+	})
+
+	resp, err := doBackoffRetry(sender, fakeRequest, Backoff{Steps: 1, PerTryTimeout: time.Minute, OverallTimeout: time.Minute})
+	assert.NoError(t, err)
+	assert.NoError(t, attemptCtx.Err(), "the attempt should not be cancelled before the body is closed")
+	assert.NoError(t, resp.Body.Close())
+	assert.ErrorIs(t, attemptCtx.Err(), context.Canceled)
+}
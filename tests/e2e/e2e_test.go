@@ -20,7 +20,9 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"strings"
 	"testing"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	"github.com/onsi/ginkgo/config"
@@ -32,14 +34,38 @@ import (
 	_ "sigs.k8s.io/cloud-provider-azure/tests/e2e/autoscaling"
 	_ "sigs.k8s.io/cloud-provider-azure/tests/e2e/network"
 	_ "sigs.k8s.io/cloud-provider-azure/tests/e2e/node"
+	"sigs.k8s.io/cloud-provider-azure/tests/e2e/utils"
 )
 
 const (
 	reportDirEnv     = "CCM_JUNIT_REPORT_DIR"
 	artifactsDirEnv  = "ARTIFACTS"
 	defaultReportDir = "_report/"
+
+	// If "CLEANUP_ORPHANED_RESOURCES" is "true", the orphaned resources of the deleted services are deleted
+	// after the suite, and if it is "dry-run", they are only logged.
+	cleanupOrphanedResourcesEnv = "CLEANUP_ORPHANED_RESOURCES"
+	clusterNameEnv              = "CLUSTER_NAME"
+	defaultClusterName          = "kubernetes"
+	orphanedResourcesOlderThan  = 10 * time.Minute
 )
 
+// The orphaned resources are cleaned up once on the first node after all the parallel nodes finish.
+var _ = SynchronizedAfterSuite(func() {}, func() {
+	cleanup := os.Getenv(cleanupOrphanedResourcesEnv)
+	if !strings.EqualFold(cleanup, "true") && !strings.EqualFold(cleanup, "dry-run") {
+		return
+	}
+	clusterName := os.Getenv(clusterNameEnv)
+	if clusterName == "" {
+		clusterName = defaultClusterName
+	}
+	tc, err := utils.CreateAzureTestClient()
+	Expect(err).NotTo(HaveOccurred())
+	err = utils.CleanupOrphanedResources(tc, clusterName, orphanedResourcesOlderThan, strings.EqualFold(cleanup, "dry-run"))
+	Expect(err).NotTo(HaveOccurred())
+})
+
 func TestAzureTest(t *testing.T) {
 	RegisterFailHandler(Fail)
 	reportDir := os.Getenv(reportDirEnv)
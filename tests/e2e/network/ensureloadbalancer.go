@@ -99,20 +99,10 @@ var _ = Describe("Ensure LoadBalancer", func() {
 
 	It("should support mixed protocol services", func() {
 		By("creating a mixed protocol service")
-		mixedProtocolPorts := []v1.ServicePort{
-			{
-				Name:       "tcp",
-				Port:       nginxPort,
-				TargetPort: intstr.FromInt(nginxPort),
-				Protocol:   v1.ProtocolTCP,
-			},
-			{
-				Name:       "udp",
-				Port:       testingPort,
-				TargetPort: intstr.FromInt(testingPort),
-				Protocol:   v1.ProtocolUDP,
-			},
-		}
+		mixedProtocolPorts := utils.MakeServicePorts(
+			utils.PortSpec{Name: "tcp", Port: nginxPort},
+			utils.PortSpec{Name: "udp", Port: testingPort, Protocol: v1.ProtocolUDP},
+		)
 		service := utils.CreateLoadBalancerServiceManifest(testServiceName, nil, labels, ns.Name, mixedProtocolPorts)
 		_, err := cs.CoreV1().Services(ns.Name).Create(context.TODO(), service, metav1.CreateOptions{})
 		Expect(err).NotTo(HaveOccurred())
@@ -476,7 +466,7 @@ var _ = Describe("Ensure LoadBalancer", func() {
 			ingressList := service.Status.LoadBalancer.Ingress
 			if len(ingressList) == 0 {
 				err = fmt.Errorf("Cannot find Ingress in limited time")
-				utils.Logf("Fail to get ingress, retry it in %s seconds", 10)
+				utils.Logf("Fail to get ingress, retry it in %d seconds", 10)
 				return false, nil
 			}
 			if targetIP == ingressList[0].IP {
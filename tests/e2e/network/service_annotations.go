@@ -32,7 +32,6 @@ import (
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
-	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/uuid"
 	"k8s.io/apimachinery/pkg/util/wait"
 	clientset "k8s.io/client-go/kubernetes"
@@ -46,7 +45,6 @@ import (
 )
 
 var (
-	scalesetRE               = regexp.MustCompile(`.*/subscriptions/(?:.*)/resourceGroups/(.+)/providers/Microsoft.Compute/virtualMachineScaleSets/(.+)/virtualMachines(?:.*)`)
 	lbNameRE                 = regexp.MustCompile(`^/subscriptions/(?:.*)/resourceGroups/(?:.*)/providers/Microsoft.Network/loadBalancers/(.+)/frontendIPConfigurations(?:.*)`)
 	backendIPConfigurationRE = regexp.MustCompile(`^/subscriptions/(?:.*)/resourceGroups/(?:.*)/providers/Microsoft.Compute/virtualMachineScaleSets/(.+)/virtualMachines(?:.*)`)
 )
@@ -244,18 +242,13 @@ var _ = Describe("Service with annotation", func() {
 		}
 
 		// create service with given annotation and wait it to expose
-		publicIP := createAndExposeDefaultServiceWithAnnotation(cs, serviceName, ns.Name, labels, annotation, ports)
-
-		// get lb from azure client
-		lb := getAzureLoadBalancerFromPIP(tc, publicIP, tc.GetResourceGroup(), "")
+		_ = createAndExposeDefaultServiceWithAnnotation(cs, serviceName, ns.Name, labels, annotation, ports)
+		service, err := cs.CoreV1().Services(ns.Name).Get(context.TODO(), serviceName, metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
 
-		var idleTimeout *int32
-		for _, rule := range *lb.LoadBalancingRules {
-			if rule.IdleTimeoutInMinutes != nil {
-				idleTimeout = rule.IdleTimeoutInMinutes
-			}
-		}
-		Expect(*idleTimeout).To(Equal(int32(5)))
+		idleTimeout, err := utils.GetServiceLBIdleTimeout(tc, service)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(idleTimeout).To(Equal(int32(5)))
 	})
 
 	// It("should support service annotation 'ServiceAnnotationLoadBalancerMixedProtocols'", func() {
@@ -572,16 +565,12 @@ var _ = Describe("Service with annotation", func() {
 			Expect(err).NotTo(HaveOccurred())
 		}()
 
-		var lb *network.LoadBalancer
-		//wait for backend update
-		err := wait.PollImmediate(5*time.Second, 60*time.Second, func() (bool, error) {
-			lb = getAzureLoadBalancerFromPIP(tc, publicIP, tc.GetResourceGroup(), "")
-			return len(*lb.LoadBalancerPropertiesFormat.Probes) == 1, nil
-		})
+		service, err := cs.CoreV1().Services(ns.Name).Get(context.TODO(), serviceName, metav1.GetOptions{})
 		Expect(err).NotTo(HaveOccurred())
-		// get lb from azure client
+
 		By("Validating health probe configs")
-		probes := *lb.Probes
+		probes, err := utils.GetServiceHealthProbes(tc, service)
+		Expect(err).NotTo(HaveOccurred())
 		Expect((len(probes))).To(Equal(1))
 		Expect(probes[0].Protocol).To(Equal(network.ProbeProtocolHTTP))
 	})
@@ -643,29 +632,17 @@ var _ = Describe("[[Multi-Nodepool]][VMSS]", func() {
 
 		// Get all the vmss names from all node's providerIDs
 		By("Get vmss names from node providerIDs")
-		vmssNames := sets.NewString()
-		var resourceGroupName string
-		for i, node := range nodes {
-			if utils.IsControlPlaneNode(&nodes[i]) {
-				continue
-			}
-			providerID := node.Spec.ProviderID
-			matches := scalesetRE.FindStringSubmatch(providerID)
-			if len(matches) != 3 {
-				Skip("azure-load-balancer-mode tests only works for vmss cluster")
-			}
-			resourceGroupName = matches[1]
-			vmssNames.Insert(matches[2])
-		}
-		Expect(resourceGroupName).NotTo(Equal(""))
-		utils.Logf("Got vmss names %v", vmssNames.List())
+		vmssNames := utils.SkipIfNotVMSSCluster(nodes)
+		utils.Logf("Got vmss names %v", vmssNames)
 
 		//Skip if there're less than two vmss
 		if len(vmssNames) < 2 {
 			Skip("azure-load-balancer-mode tests only works for cluster with multiple vmss agent pools")
 		}
+		resourceGroupName, _, err := utils.ParseVMSSProviderID(nodes[0].Spec.ProviderID)
+		Expect(err).NotTo(HaveOccurred())
 
-		vmssList := vmssNames.List()[:2]
+		vmssList := vmssNames[:2]
 		for _, vmss := range vmssList {
 			validateLoadBalancerBackendPools(tc, vmss, cs, serviceName, labels, ns.Name, ports, resourceGroupName)
 		}
@@ -72,7 +72,7 @@ var _ = Describe("Lifecycle of VMSS", func() {
 		if strings.EqualFold(os.Getenv(utils.CAPZTestCCM), "true") {
 			err = utils.ScaleMachinePool(*vmss.Name, numInstance-1)
 		} else {
-			err = utils.ScaleVMSS(azCli, *vmss.Name, azCli.GetResourceGroup(), numInstance-1)
+			err = utils.ScaleVMSS(azCli, *vmss.Name, numInstance-1)
 		}
 		Expect(err).NotTo(HaveOccurred())
 		expectedCap[*vmss.Name] = numInstance - 1
@@ -82,7 +82,7 @@ var _ = Describe("Lifecycle of VMSS", func() {
 			if strings.EqualFold(os.Getenv(utils.CAPZTestCCM), "true") {
 				err = utils.ScaleMachinePool(*vmss.Name, numInstance)
 			} else {
-				err = utils.ScaleVMSS(azCli, *vmss.Name, azCli.GetResourceGroup(), numInstance)
+				err = utils.ScaleVMSS(azCli, *vmss.Name, numInstance)
 			}
 			Expect(err).NotTo(HaveOccurred())
 			expectedCap[*vmss.Name] = numInstance
@@ -92,7 +92,7 @@ var _ = Describe("Lifecycle of VMSS", func() {
 
 			vmssAfterTest, err := utils.GetVMSS(azCli, *vmss.Name)
 			Expect(err).NotTo(HaveOccurred())
-			utils.Logf("VMSS %q sku capacity after the test: %d", &vmssAfterTest.Name, *vmssAfterTest.Sku.Capacity)
+			utils.Logf("VMSS %q sku capacity after the test: %d", *vmssAfterTest.Name, *vmssAfterTest.Sku.Capacity)
 		}()
 
 		err = utils.ValidateClusterNodesMatchVMSSInstances(azCli, expectedCap)
@@ -114,7 +114,7 @@ var _ = Describe("Lifecycle of VMSS", func() {
 		if strings.EqualFold(os.Getenv(utils.CAPZTestCCM), "true") {
 			err = utils.ScaleMachinePool(*vmss.Name, numInstance+1)
 		} else {
-			err = utils.ScaleVMSS(azCli, *vmss.Name, azCli.GetResourceGroup(), numInstance+1)
+			err = utils.ScaleVMSS(azCli, *vmss.Name, numInstance+1)
 		}
 		Expect(err).NotTo(HaveOccurred())
 		expectedCap[*vmss.Name] = numInstance + 1
@@ -124,7 +124,7 @@ var _ = Describe("Lifecycle of VMSS", func() {
 			if strings.EqualFold(os.Getenv(utils.CAPZTestCCM), "true") {
 				err = utils.ScaleMachinePool(*vmss.Name, numInstance)
 			} else {
-				err = utils.ScaleVMSS(azCli, *vmss.Name, azCli.GetResourceGroup(), numInstance)
+				err = utils.ScaleVMSS(azCli, *vmss.Name, numInstance)
 			}
 			Expect(err).NotTo(HaveOccurred())
 			expectedCap[*vmss.Name] = numInstance
@@ -134,7 +134,7 @@ var _ = Describe("Lifecycle of VMSS", func() {
 
 			vmssAfterTest, err := utils.GetVMSS(azCli, *vmss.Name)
 			Expect(err).NotTo(HaveOccurred())
-			utils.Logf("VMSS %q sku capacity after the test: %d", &vmssAfterTest.Name, *vmssAfterTest.Sku.Capacity)
+			utils.Logf("VMSS %q sku capacity after the test: %d", *vmssAfterTest.Name, *vmssAfterTest.Sku.Capacity)
 		}()
 
 		err = utils.ValidateClusterNodesMatchVMSSInstances(azCli, expectedCap)
@@ -168,6 +168,11 @@ func (tc *AzureTestClient) createResourceGroupClient() *azresources.GroupsClient
 	return &azresources.GroupsClient{BaseClient: tc.resourceClient}
 }
 
+// createResourcesClient generates generic resources client with the same baseclient as azure test client
+func (tc *AzureTestClient) createResourcesClient() *azresources.Client {
+	return &azresources.Client{BaseClient: tc.resourceClient}
+}
+
 // createACRClient generates ACR client with the same baseclient as azure test client
 func (tc *AzureTestClient) createACRClient() *acr.RegistriesClient {
 	return &acr.RegistriesClient{BaseClient: tc.acrClient}
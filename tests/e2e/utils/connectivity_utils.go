@@ -0,0 +1,256 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientset "k8s.io/client-go/kubernetes"
+)
+
+const (
+	// ConnectivityClientPod is the name of the agnhost pod the connectivity checks are run from.
+	ConnectivityClientPod = "connectivity-client-agnhost"
+
+	agnhostImage = "k8s.gcr.io/e2e-test-images/agnhost:2.36"
+
+	// connectivityCheckTimeout is the timeout of a single connection attempt.
+	connectivityCheckTimeout = 5 * time.Second
+	// connectivityCheckInterval and connectivityCheckDuration are the interval and the total duration of
+	// the retries of the connectivity checks.
+	connectivityCheckInterval = 10 * time.Second
+	connectivityCheckDuration = 2 * time.Minute
+)
+
+// CheckServiceConnectivity checks that the port of the service is reachable on the ip by the protocol. The
+// backends of the service are expected to run agnhost netexec, which echoes the client address on /clientip
+// for TCP and on the "clientip" command for UDP. The check is run from the ConnectivityClientPod, which is
+// created if it does not exist, if fromPod is true, and from the test runner otherwise. For the services with
+// externalTrafficPolicy=Local, the echoed client IP is validated to be preserved as well. The error contains
+// the output and the error of the last attempt.
+func CheckServiceConnectivity(cs clientset.Interface, ns, name, ip string, port int, protocol v1.Protocol, fromPod bool) error {
+	service, err := cs.CoreV1().Services(ns).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	preserveClientIP := service.Spec.ExternalTrafficPolicy == v1.ServiceExternalTrafficPolicyTypeLocal
+
+	var check func() (string, error)
+	var expectedClientIPs []string
+	if fromPod {
+		pod, err := EnsureConnectivityClientPod(cs, ns)
+		if err != nil {
+			return err
+		}
+		for _, podIP := range pod.Status.PodIPs {
+			expectedClientIPs = append(expectedClientIPs, podIP.IP)
+		}
+		cmd, err := connectivityCheckCommand(ip, port, protocol, connectivityCheckTimeout)
+		if err != nil {
+			return err
+		}
+		check = func() (string, error) {
+			return RunKubectl(ns, "exec", ConnectivityClientPod, "--", "/bin/sh", "-c", cmd)
+		}
+	} else {
+		check = func() (string, error) {
+			return checkConnectivityFromRunner(ip, port, protocol, connectivityCheckTimeout)
+		}
+	}
+
+	address := net.JoinHostPort(ip, strconv.Itoa(port))
+	var lastOutput string
+	var lastErr error
+	pollErr := wait.PollImmediate(connectivityCheckInterval, connectivityCheckDuration, func() (bool, error) {
+		lastOutput, lastErr = check()
+		if lastErr != nil {
+			Logf("Failed to connect to %s by %s: %v, will retry", address, protocol, lastErr)
+			return false, nil
+		}
+		if !preserveClientIP {
+			return true, nil
+		}
+		if lastErr = validateClientIP(lastOutput, expectedClientIPs); lastErr != nil {
+			Logf("Failed to validate the client IP of %s: %v, will retry", address, lastErr)
+			return false, nil
+		}
+		return true, nil
+	})
+	if pollErr != nil {
+		return fmt.Errorf("service %s/%s is not reachable on %s by %s, last output: %q, last error: %v: %w",
+			ns, name, address, protocol, lastOutput, lastErr, pollErr)
+	}
+	Logf("Service %s/%s is reachable on %s by %s", ns, name, address, protocol)
+	return nil
+}
+
+// EnsureConnectivityClientPod creates the ConnectivityClientPod in the namespace if it does not exist, and
+// returns the pod once it is running.
+func EnsureConnectivityClientPod(cs clientset.Interface, ns string) (*v1.Pod, error) {
+	pod, err := cs.CoreV1().Pods(ns).Get(context.TODO(), ConnectivityClientPod, metav1.GetOptions{})
+	if apierrs.IsNotFound(err) {
+		Logf("Creating the connectivity client pod %s in namespace %s", ConnectivityClientPod, ns)
+		pod, err = cs.CoreV1().Pods(ns).Create(context.TODO(), newConnectivityClientPod(ns), metav1.CreateOptions{})
+	}
+	if err != nil {
+		return nil, err
+	}
+	if pod.Status.Phase == v1.PodRunning {
+		return pod, nil
+	}
+
+	if _, err := WaitPodTo(v1.PodRunning, cs, pod, ns); err != nil {
+		return nil, err
+	}
+	return cs.CoreV1().Pods(ns).Get(context.TODO(), ConnectivityClientPod, metav1.GetOptions{})
+}
+
+// DeleteConnectivityClientPod deletes the ConnectivityClientPod in the namespace.
+func DeleteConnectivityClientPod(cs clientset.Interface, ns string) error {
+	return DeletePod(cs, ns, ConnectivityClientPod)
+}
+
+func newConnectivityClientPod(ns string) *v1.Pod {
+	immediate := int64(0)
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ConnectivityClientPod,
+			Namespace: ns,
+		},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{
+					Name:            "agnhost",
+					Image:           agnhostImage,
+					Args:            []string{"pause"},
+					ImagePullPolicy: v1.PullIfNotPresent,
+				},
+			},
+			NodeSelector: map[string]string{
+				v1.LabelOSStable: "linux",
+			},
+			TerminationGracePeriodSeconds: &immediate,
+		},
+	}
+}
+
+// connectivityCheckCommand returns the shell command run in the ConnectivityClientPod to get the client
+// address echoed by agnhost netexec.
+func connectivityCheckCommand(ip string, port int, protocol v1.Protocol, timeout time.Duration) (string, error) {
+	seconds := int(timeout.Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+	switch protocol {
+	case v1.ProtocolTCP, "":
+		return fmt.Sprintf("curl -sS -m %d http://%s/clientip", seconds, net.JoinHostPort(ip, strconv.Itoa(port))), nil
+	case v1.ProtocolUDP:
+		return fmt.Sprintf("echo clientip | nc -u -w %d %s %d", seconds, ip, port), nil
+	default:
+		return "", fmt.Errorf("connectivity check of protocol %s is not supported", protocol)
+	}
+}
+
+// checkConnectivityFromRunner gets the client address echoed by agnhost netexec from the test runner.
+func checkConnectivityFromRunner(ip string, port int, protocol v1.Protocol, timeout time.Duration) (string, error) {
+	address := net.JoinHostPort(ip, strconv.Itoa(port))
+	switch protocol {
+	case v1.ProtocolTCP, "":
+		client := http.Client{Timeout: timeout}
+		resp, err := client.Get(fmt.Sprintf("http://%s/clientip", address))
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return "", err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return string(body), fmt.Errorf("got status code %d", resp.StatusCode)
+		}
+		return string(body), nil
+	case v1.ProtocolUDP:
+		conn, err := net.DialTimeout("udp", address, timeout)
+		if err != nil {
+			return "", err
+		}
+		defer conn.Close()
+		if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+			return "", err
+		}
+		if _, err := conn.Write([]byte("clientip\n")); err != nil {
+			return "", err
+		}
+		buf := make([]byte, 1024)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return "", err
+		}
+		return string(buf[:n]), nil
+	default:
+		return "", fmt.Errorf("connectivity check of protocol %s is not supported", protocol)
+	}
+}
+
+// validateClientIP validates the client address echoed by agnhost netexec is one of the expected IPs. If no
+// IP is expected, e.g. the check is run from the test runner behind NAT, the client IP is only validated to
+// be a valid IP.
+func validateClientIP(output string, expectedIPs []string) error {
+	clientIP, err := parseClientIP(output)
+	if err != nil {
+		return err
+	}
+	if len(expectedIPs) == 0 {
+		return nil
+	}
+	for _, expectedIP := range expectedIPs {
+		if net.ParseIP(expectedIP).Equal(clientIP) {
+			return nil
+		}
+	}
+	return fmt.Errorf("client IP %s is not preserved, expected one of %v", clientIP, expectedIPs)
+}
+
+// parseClientIP parses the client IP from the "ip:port" echoed by agnhost netexec.
+func parseClientIP(output string) (net.IP, error) {
+	output = strings.TrimSpace(output)
+	if output == "" {
+		return nil, errors.New("no client address is echoed")
+	}
+	host, _, err := net.SplitHostPort(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the echoed client address %q: %w", output, err)
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid echoed client IP %q", host)
+	}
+	return ip, nil
+}
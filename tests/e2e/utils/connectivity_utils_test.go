@@ -0,0 +1,158 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestConnectivityCheckCommand(t *testing.T) {
+	for _, tc := range []struct {
+		description string
+		ip          string
+		protocol    v1.Protocol
+		timeout     time.Duration
+		expectedCmd string
+		expectedErr bool
+	}{
+		{
+			description: "should curl the clientip path for TCP",
+			ip:          "20.0.0.1",
+			protocol:    v1.ProtocolTCP,
+			timeout:     5 * time.Second,
+			expectedCmd: "curl -sS -m 5 http://20.0.0.1:80/clientip",
+		},
+		{
+			description: "should bracket the IPv6 addresses",
+			ip:          "2001:db8::1",
+			protocol:    v1.ProtocolTCP,
+			timeout:     5 * time.Second,
+			expectedCmd: "curl -sS -m 5 http://[2001:db8::1]:80/clientip",
+		},
+		{
+			description: "should send the clientip command by nc for UDP",
+			ip:          "20.0.0.1",
+			protocol:    v1.ProtocolUDP,
+			timeout:     100 * time.Millisecond,
+			expectedCmd: "echo clientip | nc -u -w 1 20.0.0.1 80",
+		},
+		{
+			description: "should return an error for SCTP",
+			ip:          "20.0.0.1",
+			protocol:    v1.ProtocolSCTP,
+			expectedErr: true,
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			cmd, err := connectivityCheckCommand(tc.ip, 80, tc.protocol, tc.timeout)
+			assert.Equal(t, tc.expectedErr, err != nil)
+			assert.Equal(t, tc.expectedCmd, cmd)
+		})
+	}
+}
+
+func TestValidateClientIP(t *testing.T) {
+	for _, tc := range []struct {
+		description string
+		output      string
+		expectedIPs []string
+		expectedErr bool
+	}{
+		{
+			description: "should accept the expected client IP",
+			output:      "10.244.0.5:43210\n",
+			expectedIPs: []string{"10.244.0.5"},
+		},
+		{
+			description: "should accept the expected IPv6 client IP",
+			output:      "[fd00::5]:43210",
+			expectedIPs: []string{"10.244.0.5", "fd00:0::5"},
+		},
+		{
+			description: "should reject the SNATed client IP",
+			output:      "10.240.0.4:43210",
+			expectedIPs: []string{"10.244.0.5"},
+			expectedErr: true,
+		},
+		{
+			description: "should accept any client IP if no IP is expected",
+			output:      "52.0.0.1:43210",
+		},
+		{
+			description: "should reject the output without the client address",
+			output:      "curl: (28) Connection timed out",
+			expectedErr: true,
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			err := validateClientIP(tc.output, tc.expectedIPs)
+			assert.Equal(t, tc.expectedErr, err != nil, "unexpected error: %v", err)
+		})
+	}
+}
+
+func TestCheckConnectivityFromRunner(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = conn.Read(make([]byte, 1024))
+		_, _ = conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: 15\r\n\r\n127.0.0.1:43210"))
+	}()
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	output, err := checkConnectivityFromRunner("127.0.0.1", port, v1.ProtocolTCP, time.Second)
+	assert.NoError(t, err)
+	assert.NoError(t, validateClientIP(output, []string{"127.0.0.1"}))
+}
+
+func TestEnsureConnectivityClientPod(t *testing.T) {
+	t.Run("should reuse the running client pod", func(t *testing.T) {
+		existing := newConnectivityClientPod("ns")
+		existing.Status = v1.PodStatus{Phase: v1.PodRunning, PodIPs: []v1.PodIP{{IP: "10.244.0.5"}}}
+		cs := fake.NewSimpleClientset(existing)
+
+		pod, err := EnsureConnectivityClientPod(cs, "ns")
+		assert.NoError(t, err)
+		assert.Equal(t, "10.244.0.5", pod.Status.PodIPs[0].IP)
+		for _, action := range cs.Actions() {
+			assert.NotEqual(t, "create", action.GetVerb())
+		}
+	})
+
+	t.Run("should delete the client pod", func(t *testing.T) {
+		cs := fake.NewSimpleClientset(newConnectivityClientPod("ns"))
+
+		assert.NoError(t, DeleteConnectivityClientPod(cs, "ns"))
+		_, err := cs.CoreV1().Pods("ns").Get(context.TODO(), ConnectivityClientPod, metav1.GetOptions{})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientset "k8s.io/client-go/kubernetes"
+)
+
+// endpointsPollInterval is the interval of polling the endpoints in ScaleDeploymentAndWaitEndpoints.
+var endpointsPollInterval = poll
+
+// ScaleDeploymentAndWaitEndpoints scales the deployment to the given replicas and waits until
+// the endpoints of the services selecting its pods have the same number of ready addresses.
+// The ready addresses are returned.
+func ScaleDeploymentAndWaitEndpoints(cs clientset.Interface, ns, deploy string, replicas int32, timeout time.Duration) ([]string, error) {
+	Logf("Scaling deployment %s/%s to %d replicas", ns, deploy, replicas)
+	patch := []byte(fmt.Sprintf(`{"spec":{"replicas":%d}}`, replicas))
+	deployment, err := cs.AppsV1().Deployments(ns).Patch(context.TODO(), deploy, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	serviceNames, err := getServicesSelectingPods(cs, ns, deployment.Spec.Template.Labels)
+	if err != nil {
+		return nil, err
+	}
+	if len(serviceNames) == 0 {
+		return nil, fmt.Errorf("no service selects the pods of deployment %s/%s", ns, deploy)
+	}
+
+	var addresses []string
+	err = wait.PollImmediate(endpointsPollInterval, timeout, func() (bool, error) {
+		for _, serviceName := range serviceNames {
+			readyAddresses, err := getReadyEndpointAddresses(cs, ns, serviceName)
+			if err != nil {
+				Logf("Failed to get the endpoints of service %s/%s: %v", ns, serviceName, err)
+				return false, nil
+			}
+			if len(readyAddresses) != int(replicas) {
+				Logf("Waiting for service %s/%s to have %d ready endpoints, got %d", ns, serviceName, replicas, len(readyAddresses))
+				return false, nil
+			}
+			addresses = readyAddresses
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to wait for the endpoints of deployment %s/%s to have %d ready addresses: %w", ns, deploy, replicas, err)
+	}
+	return addresses, nil
+}
+
+// getServicesSelectingPods returns the names of the services whose selector matches the pod labels.
+func getServicesSelectingPods(cs clientset.Interface, ns string, podLabels map[string]string) ([]string, error) {
+	services, err := cs.CoreV1().Services(ns).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, service := range services.Items {
+		if len(service.Spec.Selector) == 0 {
+			continue
+		}
+		if labels.SelectorFromSet(service.Spec.Selector).Matches(labels.Set(podLabels)) {
+			names = append(names, service.Name)
+		}
+	}
+	return names, nil
+}
+
+// getReadyEndpointAddresses returns the sorted ready addresses of the service from its
+// EndpointSlices, falling back to the Endpoints if there is no EndpointSlice.
+func getReadyEndpointAddresses(cs clientset.Interface, ns, serviceName string) ([]string, error) {
+	slices, err := cs.DiscoveryV1().EndpointSlices(ns).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{discoveryv1.LabelServiceName: serviceName}).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := sets.NewString()
+	if len(slices.Items) > 0 {
+		for _, slice := range slices.Items {
+			for _, endpoint := range slice.Endpoints {
+				if endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready {
+					continue
+				}
+				addresses.Insert(endpoint.Addresses...)
+			}
+		}
+		return addresses.List(), nil
+	}
+
+	endpoints, err := cs.CoreV1().Endpoints(ns).Get(context.TODO(), serviceName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, subset := range endpoints.Subsets {
+		addresses.Insert(getEndpointAddressIPs(subset.Addresses)...)
+	}
+	return addresses.List(), nil
+}
+
+func getEndpointAddressIPs(endpointAddresses []v1.EndpointAddress) []string {
+	ips := make([]string, 0, len(endpointAddresses))
+	for _, address := range endpointAddresses {
+		ips = append(ips, address.IP)
+	}
+	return ips
+}
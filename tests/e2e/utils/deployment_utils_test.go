@@ -0,0 +1,145 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/utils/pointer"
+)
+
+func setEndpointsPollInterval(t *testing.T) {
+	originalInterval := endpointsPollInterval
+	endpointsPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() {
+		endpointsPollInterval = originalInterval
+	})
+}
+
+func newTestDeploymentAndService(ns string) (*appsv1.Deployment, *v1.Service) {
+	podLabels := map[string]string{"app": "test"}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: ns},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: pointer.Int32(1),
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: podLabels},
+			},
+		},
+	}
+	service := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "svc", Namespace: ns},
+		Spec:       v1.ServiceSpec{Selector: podLabels},
+	}
+	return deployment, service
+}
+
+func TestScaleDeploymentAndWaitEndpoints(t *testing.T) {
+	setEndpointsPollInterval(t)
+	ns := "ns"
+	deployment, service := newTestDeploymentAndService(ns)
+	endpoints := &v1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Name: service.Name, Namespace: ns},
+		Subsets: []v1.EndpointSubset{{
+			Addresses: []v1.EndpointAddress{{IP: "10.0.0.1"}},
+		}},
+	}
+	cs := fake.NewSimpleClientset(deployment, service, endpoints)
+
+	// The endpoints are updated after a few polls.
+	getCount := 0
+	cs.PrependReactor("get", "endpoints", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		getCount++
+		if getCount < 3 {
+			return false, nil, nil
+		}
+		updated := endpoints.DeepCopy()
+		updated.Subsets = []v1.EndpointSubset{{
+			Addresses:         []v1.EndpointAddress{{IP: "10.0.0.3"}, {IP: "10.0.0.1"}},
+			NotReadyAddresses: []v1.EndpointAddress{{IP: "10.0.0.2"}},
+		}}
+		return true, updated, nil
+	})
+
+	addresses, err := ScaleDeploymentAndWaitEndpoints(cs, ns, deployment.Name, 2, time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.3"}, addresses)
+	assert.Equal(t, 3, getCount)
+
+	scaled, err := cs.AppsV1().Deployments(ns).Get(context.TODO(), deployment.Name, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), *scaled.Spec.Replicas)
+}
+
+func TestScaleDeploymentAndWaitEndpointSlices(t *testing.T) {
+	setEndpointsPollInterval(t)
+	ns := "ns"
+	deployment, service := newTestDeploymentAndService(ns)
+	slice := &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "svc-abc",
+			Namespace: ns,
+			Labels:    map[string]string{discoveryv1.LabelServiceName: service.Name},
+		},
+		Endpoints: []discoveryv1.Endpoint{
+			{Addresses: []string{"10.0.0.1"}, Conditions: discoveryv1.EndpointConditions{Ready: pointer.Bool(true)}},
+			{Addresses: []string{"10.0.0.2"}, Conditions: discoveryv1.EndpointConditions{Ready: pointer.Bool(false)}},
+		},
+	}
+	cs := fake.NewSimpleClientset(deployment, service, slice)
+
+	addresses, err := ScaleDeploymentAndWaitEndpoints(cs, ns, deployment.Name, 1, time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1"}, addresses)
+}
+
+func TestScaleDeploymentAndWaitEndpointsErrors(t *testing.T) {
+	setEndpointsPollInterval(t)
+	ns := "ns"
+
+	t.Run("deployment not found", func(t *testing.T) {
+		cs := fake.NewSimpleClientset()
+		_, err := ScaleDeploymentAndWaitEndpoints(cs, ns, "deploy", 1, time.Second)
+		assert.Error(t, err)
+	})
+
+	t.Run("no service selecting the pods", func(t *testing.T) {
+		deployment, _ := newTestDeploymentAndService(ns)
+		cs := fake.NewSimpleClientset(deployment)
+		_, err := ScaleDeploymentAndWaitEndpoints(cs, ns, deployment.Name, 1, time.Second)
+		assert.Equal(t, fmt.Errorf("no service selects the pods of deployment %s/%s", ns, deployment.Name), err)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		deployment, service := newTestDeploymentAndService(ns)
+		cs := fake.NewSimpleClientset(deployment, service)
+		_, err := ScaleDeploymentAndWaitEndpoints(cs, ns, deployment.Name, 1, 50*time.Millisecond)
+		assert.Error(t, err)
+	})
+}
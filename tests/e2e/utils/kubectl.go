@@ -193,7 +193,7 @@ func ScaleMachinePool(vmssName string, instanceCount int64) error {
 	}
 	defer func() {
 		if err := os.Setenv(clientcmd.RecommendedConfigPathEnvVar, kubeconfig); err != nil {
-			Logf("failed to export %s after scaling: %w", clientcmd.RecommendedConfigPathEnvVar, err)
+			Logf("failed to export %s after scaling: %v", clientcmd.RecommendedConfigPathEnvVar, err)
 		}
 	}()
 
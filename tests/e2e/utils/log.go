@@ -18,24 +18,106 @@ package utils
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
 	"runtime"
 	"time"
 
 	"github.com/onsi/ginkgo"
 )
 
+const (
+	// artifactsDirEnv is the directory of the artifacts of the test run. If it's set, the logs of each spec are
+	// also written to a file named after the spec under the specLogsDir of the directory.
+	artifactsDirEnv = "ARTIFACTS"
+	specLogsDir     = "spec-logs"
+
+	// maxSpecPrefixLength is the maximum length of the spec name in the prefix of the logs.
+	maxSpecPrefixLength = 40
+	// maxSpecLogFileNameLength is the maximum length of the log file name of a spec.
+	maxSpecLogFileNameLength = 200
+)
+
+var (
+	// currentSpec returns the description of the running spec, which is empty outside of the specs.
+	currentSpec = ginkgo.CurrentGinkgoTestDescription
+	// logWriter is where the logs are written to besides the spec log files.
+	logWriter io.Writer = ginkgo.GinkgoWriter
+
+	specLogFileNameRE = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+)
+
 func nowStamp() string {
 	return time.Now().Format(time.StampMilli)
 }
 
 func log(level string, format string, args ...interface{}) {
 	_, file, line, _ := runtime.Caller(2)
-	extra := fmt.Sprintf(" [%s:%d]", file, line)
+	spec := currentSpec()
+	message := formatLogMessage(nowStamp(), level, shortSpecName(spec.TestText), fmt.Sprintf(format, args...), file, line)
+
+	fmt.Fprint(logWriter, message)
+	if artifactsDir := os.Getenv(artifactsDirEnv); artifactsDir != "" && spec.FullTestText != "" {
+		if err := appendSpecLog(specLogFilePath(artifactsDir, spec.FullTestText), message); err != nil {
+			fmt.Fprintf(logWriter, "%s: WARNING: failed to write the spec log: %v\n", nowStamp(), err)
+		}
+	}
+}
+
+// formatLogMessage formats a log line as "<timestamp>: <level>: [<spec>] <message> [<file>:<line>]". The spec
+// part is omitted outside of the specs.
+func formatLogMessage(stamp, level, spec, message, file string, line int) string {
+	if spec != "" {
+		message = fmt.Sprintf("[%s] %s", spec, message)
+	}
+	return fmt.Sprintf("%s: %s: %s [%s:%d]\n", stamp, level, message, file, line)
+}
+
+// shortSpecName truncates the spec name to maxSpecPrefixLength characters.
+func shortSpecName(name string) string {
+	runes := []rune(name)
+	if len(runes) <= maxSpecPrefixLength {
+		return name
+	}
+	return string(runes[:maxSpecPrefixLength-3]) + "..."
+}
+
+// specLogFilePath returns the path of the log file of the spec under the artifacts directory, the characters
+// other than letters, digits, dots, dashes and underscores in the spec name are replaced by underscores.
+func specLogFilePath(artifactsDir, fullSpecName string) string {
+	name := specLogFileNameRE.ReplaceAllString(fullSpecName, "_")
+	if len(name) > maxSpecLogFileNameLength {
+		name = name[:maxSpecLogFileNameLength]
+	}
+	return filepath.Join(artifactsDir, specLogsDir, name+".log")
+}
 
-	fmt.Fprintf(ginkgo.GinkgoWriter, nowStamp()+": "+level+": "+format+extra+"\n", args...)
+func appendSpecLog(path, message string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(message)
+	return err
 }
 
 // Logf prints info logs
 func Logf(format string, args ...interface{}) {
 	log("INFO", format, args...)
 }
+
+// Warningf prints warning logs
+func Warningf(format string, args ...interface{}) {
+	log("WARNING", format, args...)
+}
+
+// Errorf prints error logs, it doesn't fail the spec.
+func Errorf(format string, args ...interface{}) {
+	log("ERROR", format, args...)
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/onsi/ginkgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatLogMessage(t *testing.T) {
+	for _, tc := range []struct {
+		description string
+		spec        string
+		expected    string
+	}{
+		{
+			description: "should prefix the message with the spec name",
+			spec:        "should support idle timeout",
+			expected:    "Oct 15 12:00:00.000: INFO: [should support idle timeout] hello [file.go:10]\n",
+		},
+		{
+			description: "should omit the spec name outside of the specs",
+			expected:    "Oct 15 12:00:00.000: INFO: hello [file.go:10]\n",
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			assert.Equal(t, tc.expected, formatLogMessage("Oct 15 12:00:00.000", "INFO", tc.spec, "hello", "file.go", 10))
+		})
+	}
+}
+
+func TestShortSpecName(t *testing.T) {
+	assert.Equal(t, "short", shortSpecName("short"))
+	long := strings.Repeat("a", maxSpecPrefixLength+1)
+	short := shortSpecName(long)
+	assert.Len(t, short, maxSpecPrefixLength)
+	assert.True(t, strings.HasSuffix(short, "..."))
+}
+
+func TestSpecLogFilePath(t *testing.T) {
+	assert.Equal(t, filepath.Join("/artifacts", specLogsDir, "Service_should_support_annotation_foo_bar_.log"),
+		specLogFilePath("/artifacts", "Service should support annotation 'foo/bar'"))
+	assert.Len(t, filepath.Base(specLogFilePath("/artifacts", strings.Repeat("a", 2*maxSpecLogFileNameLength))), maxSpecLogFileNameLength+len(".log"))
+}
+
+func TestLogToSpecFile(t *testing.T) {
+	artifactsDir := t.TempDir()
+	t.Setenv(artifactsDirEnv, artifactsDir)
+
+	originalSpec, originalWriter := currentSpec, logWriter
+	defer func() {
+		currentSpec, logWriter = originalSpec, originalWriter
+	}()
+	buf := &bytes.Buffer{}
+	logWriter = buf
+	currentSpec = func() ginkgo.GinkgoTestDescription {
+		return ginkgo.GinkgoTestDescription{FullTestText: "Network should work", TestText: "should work"}
+	}
+
+	Logf("info %d", 1)
+	Warningf("warning %d", 2)
+	Errorf("error %d", 3)
+
+	content, err := os.ReadFile(specLogFilePath(artifactsDir, "Network should work"))
+	assert.NoError(t, err)
+	assert.Equal(t, buf.String(), string(content))
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	assert.Len(t, lines, 3)
+	assert.Contains(t, lines[0], ": INFO: [should work] info 1 [")
+	assert.Contains(t, lines[1], ": WARNING: [should work] warning 2 [")
+	assert.Contains(t, lines[2], ": ERROR: [should work] error 3 [")
+	assert.Contains(t, lines[0], "log_test.go:")
+
+	t.Run("should not write the spec file outside of the specs", func(t *testing.T) {
+		currentSpec = func() ginkgo.GinkgoTestDescription {
+			return ginkgo.GinkgoTestDescription{}
+		}
+		Logf("outside")
+		entries, err := os.ReadDir(filepath.Join(artifactsDir, specLogsDir))
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+		assert.Contains(t, buf.String(), ": INFO: outside [")
+	})
+}
@@ -18,8 +18,11 @@ package utils
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
+	"regexp"
 	"strings"
 	"time"
 
@@ -30,6 +33,8 @@ import (
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/wait"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
 )
 
 // getVirtualNetworkList returns the list of virtual networks in the cluster resource group.
@@ -142,7 +147,7 @@ func (azureTestClient *AzureTestClient) getSecurityGroupList() (result aznetwork
 	err = wait.PollImmediate(poll, singleCallTimeout, func() (bool, error) {
 		result, err = securityGroupsClient.List(context.Background(), azureTestClient.GetResourceGroup())
 		if err != nil {
-			Logf("error when listing sgs: %w", err)
+			Logf("error when listing sgs: %v", err)
 			if !IsRetryableAPIError(err) {
 				return false, err
 			}
@@ -170,6 +175,524 @@ func (azureTestClient *AzureTestClient) GetClusterSecurityGroups() (ret []aznetw
 	return
 }
 
+// ClusterSecurityGroupsGetter gets the security groups of the cluster, it's implemented by AzureTestClient.
+type ClusterSecurityGroupsGetter interface {
+	GetClusterSecurityGroups() ([]aznetwork.SecurityGroup, error)
+}
+
+var _ ClusterSecurityGroupsGetter = &AzureTestClient{}
+
+// GetServiceNSGRules returns the security rules of the cluster security groups whose destination is the ingress IP of the service.
+func GetServiceNSGRules(azureClient ClusterSecurityGroupsGetter, svc *v1.Service) ([]aznetwork.SecurityRule, error) {
+	ingressIPs := make([]string, 0)
+	for _, ingress := range svc.Status.LoadBalancer.Ingress {
+		if ingress.IP != "" {
+			ingressIPs = append(ingressIPs, ingress.IP)
+		}
+	}
+	if len(ingressIPs) == 0 {
+		return nil, fmt.Errorf("service %s/%s doesn't have an ingress IP", svc.Namespace, svc.Name)
+	}
+
+	nsgs, err := azureClient.GetClusterSecurityGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]aznetwork.SecurityRule, 0)
+	for _, nsg := range nsgs {
+		if nsg.SecurityGroupPropertiesFormat == nil || nsg.SecurityRules == nil {
+			continue
+		}
+		for _, securityRule := range *nsg.SecurityRules {
+			if securityRule.SecurityRulePropertiesFormat == nil {
+				continue
+			}
+			destinations := make([]string, 0)
+			if securityRule.DestinationAddressPrefix != nil {
+				destinations = append(destinations, *securityRule.DestinationAddressPrefix)
+			}
+			if securityRule.DestinationAddressPrefixes != nil {
+				destinations = append(destinations, *securityRule.DestinationAddressPrefixes...)
+			}
+
+			for _, ip := range ingressIPs {
+				if StringInSlice(ip, destinations) {
+					rules = append(rules, securityRule)
+					break
+				}
+			}
+		}
+	}
+	Logf("Found %d security rules for service %s/%s", len(rules), svc.Namespace, svc.Name)
+	return rules, nil
+}
+
+// LoadBalancerGetter gets the load balancers in the cluster resource group.
+type LoadBalancerGetter interface {
+	GetResourceGroup() string
+	GetLoadBalancer(resourceGroupName, lbName string) (aznetwork.LoadBalancer, error)
+}
+
+var _ LoadBalancerGetter = &AzureTestClient{}
+
+// WaitForBackendPoolMembers waits until the backend pools of the load balancer have
+// expectedCount members in total and returns the IDs of the members. The members are
+// the NIC IP configurations of the VMs or VMSS instances, or the backend addresses of
+// the IP-based backend pools.
+func WaitForBackendPoolMembers(azureClient LoadBalancerGetter, lbName string, expectedCount int, timeout time.Duration) ([]string, error) {
+	var members []string
+	err := wait.PollImmediate(poll, timeout, func() (bool, error) {
+		lb, err := azureClient.GetLoadBalancer(azureClient.GetResourceGroup(), lbName)
+		if err != nil {
+			if IsRetryableAPIError(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		members = getBackendPoolMembers(&lb)
+		Logf("Load balancer %s has %d backend pool members, expected %d", lbName, len(members), expectedCount)
+		return len(members) == expectedCount, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to wait for %d backend pool members of load balancer %s: %w", expectedCount, lbName, err)
+	}
+	return members, nil
+}
+
+// getBackendPoolMembers returns the IDs of the members of all backend pools of the load balancer.
+func getBackendPoolMembers(lb *aznetwork.LoadBalancer) []string {
+	members := make([]string, 0)
+	if lb.LoadBalancerPropertiesFormat == nil || lb.BackendAddressPools == nil {
+		return members
+	}
+	for _, pool := range *lb.BackendAddressPools {
+		if pool.BackendAddressPoolPropertiesFormat == nil {
+			continue
+		}
+		if pool.BackendIPConfigurations != nil {
+			for _, ipConfig := range *pool.BackendIPConfigurations {
+				if ipConfig.ID != nil {
+					members = append(members, *ipConfig.ID)
+				}
+			}
+		}
+		if pool.LoadBalancerBackendAddresses != nil {
+			for _, address := range *pool.LoadBalancerBackendAddresses {
+				if address.LoadBalancerBackendAddressPropertiesFormat != nil && address.IPAddress != nil {
+					members = append(members, *address.IPAddress)
+				}
+			}
+		}
+	}
+	return members
+}
+
+// PublicIPsLister lists the public IPs in a resource group, it's implemented by AzureTestClient.
+type PublicIPsLister interface {
+	ListPublicIPs(resourceGroupName string) ([]aznetwork.PublicIPAddress, error)
+}
+
+var _ PublicIPsLister = &AzureTestClient{}
+
+// orphanedPublicIPGracePeriod is the time waited for the cloud provider to clean up the public IPs asynchronously.
+var orphanedPublicIPGracePeriod = 3 * time.Minute
+
+// AssertNoOrphanedPublicIPs checks that no public IP tagged for the cluster clusterTag is left unassociated in
+// the resource group, e.g. after the services are deleted. The public IPs are listed again until the grace
+// period expires, and an error listing the orphaned ones is returned if they still remain.
+func AssertNoOrphanedPublicIPs(azureClient PublicIPsLister, resourceGroup, clusterTag string) error {
+	var orphaned []string
+	err := wait.PollImmediate(poll, orphanedPublicIPGracePeriod, func() (bool, error) {
+		pips, err := azureClient.ListPublicIPs(resourceGroup)
+		if err != nil {
+			if IsRetryableAPIError(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		orphaned = getOrphanedPublicIPs(pips, clusterTag)
+		if len(orphaned) > 0 {
+			Logf("Found %d orphaned public IPs of cluster %s in resource group %s: %v", len(orphaned), clusterTag, resourceGroup, orphaned)
+		}
+		return len(orphaned) == 0, nil
+	})
+	if errors.Is(err, wait.ErrWaitTimeout) {
+		return fmt.Errorf("public IPs %v of cluster %s in resource group %s are not associated with any resource after %v", orphaned, clusterTag, resourceGroup, orphanedPublicIPGracePeriod)
+	}
+	return err
+}
+
+// getOrphanedPublicIPs returns the names of the public IPs tagged for the cluster which are not associated
+// with any IP configuration or NAT gateway.
+func getOrphanedPublicIPs(pips []aznetwork.PublicIPAddress, clusterTag string) []string {
+	orphaned := make([]string, 0)
+	for _, pip := range pips {
+		if pip.Tags == nil || !strings.EqualFold(to.String(pip.Tags[consts.ClusterNameKey]), clusterTag) {
+			continue
+		}
+		if pip.PublicIPAddressPropertiesFormat != nil && (pip.IPConfiguration != nil || pip.NatGateway != nil) {
+			continue
+		}
+		orphaned = append(orphaned, to.String(pip.Name))
+	}
+	return orphaned
+}
+
+// ServiceLoadBalancerInspector gets the load balancers and public IPs of the services, it's implemented by AzureTestClient.
+type ServiceLoadBalancerInspector interface {
+	LoadBalancerGetter
+	PublicIPsLister
+	ListLoadBalancers(resourceGroupName string) ([]aznetwork.LoadBalancer, error)
+}
+
+var _ ServiceLoadBalancerInspector = &AzureTestClient{}
+
+// serviceLoadBalancerInspectionTimeout is the time waited for the load balancer resources of a service to be
+// consistent with its ingress IPs.
+var serviceLoadBalancerInspectionTimeout = 2 * time.Minute
+
+// frontendIPConfigurationIDRE matches the resource group and the name of the load balancer in a frontend IP configuration ID.
+var frontendIPConfigurationIDRE = regexp.MustCompile(`(?i)/resourceGroups/([^/]+)/providers/Microsoft.Network/loadBalancers/([^/]+)/frontendIPConfigurations/[^/]+$`)
+
+// serviceFrontend is the load balancer frontend of a service resolved by its ingress IPs.
+type serviceFrontend struct {
+	lb          *aznetwork.LoadBalancer
+	frontendIDs []string
+	pips        []aznetwork.PublicIPAddress
+}
+
+// GetServiceLoadBalancerRules returns the load balancing rules of the frontends of the service.
+func GetServiceLoadBalancerRules(azureClient ServiceLoadBalancerInspector, svc *v1.Service) ([]aznetwork.LoadBalancingRule, error) {
+	var rules []aznetwork.LoadBalancingRule
+	_, err := waitServiceFrontend(azureClient, svc, func(frontend *serviceFrontend) bool {
+		rules = frontend.loadBalancingRules()
+		return len(rules) > 0
+	})
+	if err != nil {
+		return nil, err
+	}
+	Logf("Found %d load balancing rules for service %s/%s", len(rules), svc.Namespace, svc.Name)
+	return rules, nil
+}
+
+// GetServiceHealthProbes returns the health probes of the load balancing rules of the frontends of the service.
+func GetServiceHealthProbes(azureClient ServiceLoadBalancerInspector, svc *v1.Service) ([]aznetwork.Probe, error) {
+	var probes []aznetwork.Probe
+	_, err := waitServiceFrontend(azureClient, svc, func(frontend *serviceFrontend) bool {
+		probes = frontend.probes()
+		return len(probes) > 0
+	})
+	if err != nil {
+		return nil, err
+	}
+	Logf("Found %d health probes for service %s/%s", len(probes), svc.Namespace, svc.Name)
+	return probes, nil
+}
+
+// GetServiceLBDistributionMode returns the load distribution mode of the load balancing rules of the service,
+// e.g. "SourceIP" or "Default". An error is returned if the rules have different distribution modes.
+func GetServiceLBDistributionMode(azureClient ServiceLoadBalancerInspector, svc *v1.Service) (string, error) {
+	rules, err := GetServiceLoadBalancerRules(azureClient, svc)
+	if err != nil {
+		return "", err
+	}
+
+	mode := ""
+	for _, rule := range rules {
+		ruleMode := string(rule.LoadDistribution)
+		if ruleMode == "" {
+			ruleMode = string(aznetwork.LoadDistributionDefault)
+		}
+		if mode != "" && !strings.EqualFold(mode, ruleMode) {
+			return "", fmt.Errorf("load balancing rules of service %s/%s have different distribution modes %s and %s", svc.Namespace, svc.Name, mode, ruleMode)
+		}
+		mode = ruleMode
+	}
+	return mode, nil
+}
+
+// ExpectedLBDistributionMode returns the load distribution mode of the load balancing rules expected for the
+// session affinity of the service, which is "SourceIP" for ClientIP and "Default" for None.
+func ExpectedLBDistributionMode(svc *v1.Service) string {
+	if svc.Spec.SessionAffinity == v1.ServiceAffinityClientIP {
+		return string(aznetwork.LoadDistributionSourceIP)
+	}
+	return string(aznetwork.LoadDistributionDefault)
+}
+
+// defaultLBIdleTimeoutInMinutes is the TCP idle timeout of the load balancing rules if the idle timeout annotation is not set.
+const defaultLBIdleTimeoutInMinutes int32 = 4
+
+// GetServiceLBIdleTimeout returns the TCP idle timeout in minutes of the load balancing rules of the service. The
+// rules without the idle timeout are considered to have the default one. An error is returned if the rules have
+// different idle timeouts.
+func GetServiceLBIdleTimeout(azureClient ServiceLoadBalancerInspector, svc *v1.Service) (int32, error) {
+	rules, err := GetServiceLoadBalancerRules(azureClient, svc)
+	if err != nil {
+		return 0, err
+	}
+
+	var idleTimeout *int32
+	for _, rule := range rules {
+		ruleIdleTimeout := defaultLBIdleTimeoutInMinutes
+		if rule.IdleTimeoutInMinutes != nil {
+			ruleIdleTimeout = *rule.IdleTimeoutInMinutes
+		}
+		if idleTimeout != nil && *idleTimeout != ruleIdleTimeout {
+			return 0, fmt.Errorf("load balancing rules of service %s/%s have different idle timeouts %d and %d", svc.Namespace, svc.Name, *idleTimeout, ruleIdleTimeout)
+		}
+		idleTimeout = &ruleIdleTimeout
+	}
+	return *idleTimeout, nil
+}
+
+// ExpectedLBIdleTimeout returns the TCP idle timeout in minutes of the load balancing rules expected for the
+// idle timeout annotation of the service, which is the default one if the annotation is not set.
+func ExpectedLBIdleTimeout(svc *v1.Service) (int32, error) {
+	idleTimeout, err := consts.Getint32ValueFromK8sSvcAnnotation(svc.Annotations, consts.ServiceAnnotationLoadBalancerIdleTimeout)
+	if err != nil {
+		return 0, err
+	}
+	if idleTimeout == nil {
+		return defaultLBIdleTimeoutInMinutes, nil
+	}
+	return *idleTimeout, nil
+}
+
+// GetServiceOutboundRule returns the outbound rule of the load balancer which the outbound traffic of the service
+// backends is SNATed by. The outbound rule using the frontends of the service explicitly takes precedence over
+// the default one of the backend pool of the service load balancing rules. nil is returned if no outbound rule
+// applies, e.g. the backends use the default outbound access or the SNAT of the load balancing rules.
+func GetServiceOutboundRule(azureClient ServiceLoadBalancerInspector, svc *v1.Service) (*aznetwork.OutboundRule, error) {
+	var rules []aznetwork.LoadBalancingRule
+	frontend, err := waitServiceFrontend(azureClient, svc, func(frontend *serviceFrontend) bool {
+		rules = frontend.loadBalancingRules()
+		return len(rules) > 0
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	outboundRule := frontend.outboundRule(rules)
+	if outboundRule == nil {
+		Logf("Found no outbound rule for service %s/%s, the default outbound access is used", svc.Namespace, svc.Name)
+		return nil, nil
+	}
+	Logf("Found outbound rule %s with %d allocated ports for service %s/%s", to.String(outboundRule.Name), to.Int32(outboundRule.AllocatedOutboundPorts), svc.Namespace, svc.Name)
+	return outboundRule, nil
+}
+
+// GetServiceFrontendPIP returns the public IP of the frontend of the external service. For dual-stack
+// services, the public IP of the first ingress IP is returned.
+func GetServiceFrontendPIP(azureClient ServiceLoadBalancerInspector, svc *v1.Service) (aznetwork.PublicIPAddress, error) {
+	if isInternalService(svc) {
+		return aznetwork.PublicIPAddress{}, fmt.Errorf("service %s/%s is internal and has no frontend public IP", svc.Namespace, svc.Name)
+	}
+	frontend, err := waitServiceFrontend(azureClient, svc, nil)
+	if err != nil {
+		return aznetwork.PublicIPAddress{}, err
+	}
+	return frontend.pips[0], nil
+}
+
+// waitServiceFrontend resolves the load balancer frontend of the service by its ingress IPs until done returns
+// true for it, since the load balancer and public IPs are eventually consistent with the service status. The
+// error contains the raw JSON of the resources of the last attempt.
+func waitServiceFrontend(azureClient ServiceLoadBalancerInspector, svc *v1.Service, done func(*serviceFrontend) bool) (*serviceFrontend, error) {
+	var frontend *serviceFrontend
+	var lastErr error
+	err := wait.PollImmediate(poll, serviceLoadBalancerInspectionTimeout, func() (bool, error) {
+		frontend, lastErr = resolveServiceFrontend(azureClient, svc)
+		if lastErr != nil {
+			Logf("Failed to resolve the load balancer frontend of service %s/%s: %v, will retry", svc.Namespace, svc.Name, lastErr)
+			return false, nil
+		}
+		return done == nil || done(frontend), nil
+	})
+	if err == nil {
+		return frontend, nil
+	}
+
+	if lastErr != nil {
+		return nil, fmt.Errorf("failed to resolve the load balancer frontend of service %s/%s: %v: %w", svc.Namespace, svc.Name, lastErr, err)
+	}
+	raw, marshalErr := json.Marshal(frontend.lb)
+	if marshalErr != nil {
+		raw = []byte(marshalErr.Error())
+	}
+	return nil, fmt.Errorf("unexpected load balancer of the frontends %v of service %s/%s: %s: %w", frontend.frontendIDs, svc.Namespace, svc.Name, raw, err)
+}
+
+// resolveServiceFrontend resolves the load balancer frontend of the service by its ingress IPs. The frontend of
+// an internal service is looked up by the private IPs of the load balancers in the cluster resource group, and
+// that of an external service by the public IPs in the resource group of the load balancer annotation, which
+// defaults to the cluster resource group.
+func resolveServiceFrontend(azureClient ServiceLoadBalancerInspector, svc *v1.Service) (*serviceFrontend, error) {
+	ingressIPs := make([]string, 0)
+	for _, ingress := range svc.Status.LoadBalancer.Ingress {
+		if ingress.IP != "" {
+			ingressIPs = append(ingressIPs, ingress.IP)
+		}
+	}
+	if len(ingressIPs) == 0 {
+		return nil, fmt.Errorf("service %s/%s doesn't have an ingress IP", svc.Namespace, svc.Name)
+	}
+
+	if isInternalService(svc) {
+		return resolveInternalServiceFrontend(azureClient, ingressIPs)
+	}
+
+	pipResourceGroup := azureClient.GetResourceGroup()
+	if rg := strings.TrimSpace(svc.Annotations[consts.ServiceAnnotationLoadBalancerResourceGroup]); rg != "" {
+		pipResourceGroup = rg
+	}
+	pips, err := azureClient.ListPublicIPs(pipResourceGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	frontend := &serviceFrontend{}
+	for _, ip := range ingressIPs {
+		pip, found := findPublicIPByAddress(pips, ip)
+		if !found {
+			return nil, fmt.Errorf("found no public IP %s in resource group %s", ip, pipResourceGroup)
+		}
+		if pip.IPConfiguration == nil || pip.IPConfiguration.ID == nil {
+			raw, _ := json.Marshal(pip)
+			return nil, fmt.Errorf("public IP %s is not associated with a load balancer frontend: %s", ip, raw)
+		}
+		frontend.pips = append(frontend.pips, pip)
+		frontend.frontendIDs = append(frontend.frontendIDs, *pip.IPConfiguration.ID)
+	}
+
+	match := frontendIPConfigurationIDRE.FindStringSubmatch(frontend.frontendIDs[0])
+	if len(match) != 3 {
+		return nil, fmt.Errorf("public IP %s is associated with %s which is not a load balancer frontend", ingressIPs[0], frontend.frontendIDs[0])
+	}
+	lb, err := azureClient.GetLoadBalancer(match[1], match[2])
+	if err != nil {
+		return nil, err
+	}
+	frontend.lb = &lb
+	return frontend, nil
+}
+
+// resolveInternalServiceFrontend looks up the frontends whose private IPs are the ingress IPs in the load
+// balancers of the cluster resource group.
+func resolveInternalServiceFrontend(azureClient ServiceLoadBalancerInspector, ingressIPs []string) (*serviceFrontend, error) {
+	lbs, err := azureClient.ListLoadBalancers(azureClient.GetResourceGroup())
+	if err != nil {
+		return nil, err
+	}
+	for i := range lbs {
+		lb := lbs[i]
+		if lb.LoadBalancerPropertiesFormat == nil || lb.FrontendIPConfigurations == nil {
+			continue
+		}
+		frontend := &serviceFrontend{lb: &lb}
+		for _, fip := range *lb.FrontendIPConfigurations {
+			if fip.FrontendIPConfigurationPropertiesFormat != nil && fip.PrivateIPAddress != nil &&
+				StringInSlice(*fip.PrivateIPAddress, ingressIPs) && fip.ID != nil {
+				frontend.frontendIDs = append(frontend.frontendIDs, *fip.ID)
+			}
+		}
+		if len(frontend.frontendIDs) > 0 {
+			return frontend, nil
+		}
+	}
+	return nil, fmt.Errorf("found no load balancer frontend with private IPs %v in resource group %s", ingressIPs, azureClient.GetResourceGroup())
+}
+
+func findPublicIPByAddress(pips []aznetwork.PublicIPAddress, ip string) (aznetwork.PublicIPAddress, bool) {
+	for _, pip := range pips {
+		if pip.PublicIPAddressPropertiesFormat != nil && strings.EqualFold(to.String(pip.IPAddress), ip) {
+			return pip, true
+		}
+	}
+	return aznetwork.PublicIPAddress{}, false
+}
+
+// loadBalancingRules returns the load balancing rules of the frontends.
+func (f *serviceFrontend) loadBalancingRules() []aznetwork.LoadBalancingRule {
+	rules := make([]aznetwork.LoadBalancingRule, 0)
+	if f.lb.LoadBalancerPropertiesFormat == nil || f.lb.LoadBalancingRules == nil {
+		return rules
+	}
+	for _, rule := range *f.lb.LoadBalancingRules {
+		if rule.LoadBalancingRulePropertiesFormat == nil || rule.FrontendIPConfiguration == nil {
+			continue
+		}
+		if f.hasFrontend(to.String(rule.FrontendIPConfiguration.ID)) {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// probes returns the health probes of the load balancing rules of the frontends.
+func (f *serviceFrontend) probes() []aznetwork.Probe {
+	probes := make([]aznetwork.Probe, 0)
+	if f.lb.LoadBalancerPropertiesFormat == nil || f.lb.Probes == nil {
+		return probes
+	}
+	probeIDs := make([]string, 0)
+	for _, rule := range f.loadBalancingRules() {
+		if rule.Probe != nil && rule.Probe.ID != nil {
+			probeIDs = append(probeIDs, strings.ToLower(*rule.Probe.ID))
+		}
+	}
+	for _, probe := range *f.lb.Probes {
+		if StringInSlice(strings.ToLower(to.String(probe.ID)), probeIDs) {
+			probes = append(probes, probe)
+		}
+	}
+	return probes
+}
+
+// outboundRule returns the outbound rule using the frontends, or that of the backend pools of the load balancing
+// rules if there is none.
+func (f *serviceFrontend) outboundRule(rules []aznetwork.LoadBalancingRule) *aznetwork.OutboundRule {
+	if f.lb.LoadBalancerPropertiesFormat == nil || f.lb.OutboundRules == nil {
+		return nil
+	}
+	backendPoolIDs := make([]string, 0)
+	for _, rule := range rules {
+		if rule.BackendAddressPool != nil && rule.BackendAddressPool.ID != nil {
+			backendPoolIDs = append(backendPoolIDs, strings.ToLower(*rule.BackendAddressPool.ID))
+		}
+	}
+
+	var defaultRule *aznetwork.OutboundRule
+	for i := range *f.lb.OutboundRules {
+		outboundRule := &(*f.lb.OutboundRules)[i]
+		if outboundRule.OutboundRulePropertiesFormat == nil {
+			continue
+		}
+		if outboundRule.FrontendIPConfigurations != nil {
+			for _, fip := range *outboundRule.FrontendIPConfigurations {
+				if f.hasFrontend(to.String(fip.ID)) {
+					return outboundRule
+				}
+			}
+		}
+		if defaultRule == nil && outboundRule.BackendAddressPool != nil &&
+			StringInSlice(strings.ToLower(to.String(outboundRule.BackendAddressPool.ID)), backendPoolIDs) {
+			defaultRule = outboundRule
+		}
+	}
+	return defaultRule
+}
+
+func (f *serviceFrontend) hasFrontend(id string) bool {
+	for _, frontendID := range f.frontendIDs {
+		if strings.EqualFold(frontendID, id) {
+			return true
+		}
+	}
+	return false
+}
+
 // CreateLoadBalancerServiceManifest return the specific service to be created
 func CreateLoadBalancerServiceManifest(name string, annotation map[string]string, labels map[string]string, namespace string, ports []v1.ServicePort) *v1.Service {
 	return &v1.Service{
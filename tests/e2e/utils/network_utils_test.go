@@ -0,0 +1,621 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	aznetwork "github.com/Azure/azure-sdk-for-go/services/network/mgmt/2021-08-01/network"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/stretchr/testify/assert"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+)
+
+type fakeSecurityGroupsGetter struct {
+	nsgs []aznetwork.SecurityGroup
+}
+
+func (f *fakeSecurityGroupsGetter) GetClusterSecurityGroups() ([]aznetwork.SecurityGroup, error) {
+	return f.nsgs, nil
+}
+
+func TestGetServiceNSGRules(t *testing.T) {
+	getter := &fakeSecurityGroupsGetter{
+		nsgs: []aznetwork.SecurityGroup{
+			{
+				Name: to.StringPtr("nsg"),
+				SecurityGroupPropertiesFormat: &aznetwork.SecurityGroupPropertiesFormat{
+					SecurityRules: &[]aznetwork.SecurityRule{
+						{
+							Name: to.StringPtr("svc-TCP-80-Internet"),
+							SecurityRulePropertiesFormat: &aznetwork.SecurityRulePropertiesFormat{
+								DestinationAddressPrefix: to.StringPtr("1.2.3.4"),
+								DestinationPortRange:     to.StringPtr("80"),
+								SourceAddressPrefix:      to.StringPtr("Internet"),
+							},
+						},
+						{
+							Name: to.StringPtr("shared-TCP-443-Internet"),
+							SecurityRulePropertiesFormat: &aznetwork.SecurityRulePropertiesFormat{
+								DestinationAddressPrefixes: &[]string{"5.6.7.8", "1.2.3.4"},
+								DestinationPortRange:       to.StringPtr("443"),
+								SourceAddressPrefix:        to.StringPtr("Internet"),
+							},
+						},
+						{
+							Name: to.StringPtr("other-TCP-80-Internet"),
+							SecurityRulePropertiesFormat: &aznetwork.SecurityRulePropertiesFormat{
+								DestinationAddressPrefix: to.StringPtr("5.6.7.8"),
+								DestinationPortRange:     to.StringPtr("80"),
+								SourceAddressPrefix:      to.StringPtr("Internet"),
+							},
+						},
+					},
+				},
+			},
+			{
+				Name: to.StringPtr("empty-nsg"),
+			},
+		},
+	}
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "svc",
+			Namespace: "ns",
+		},
+		Status: v1.ServiceStatus{
+			LoadBalancer: v1.LoadBalancerStatus{
+				Ingress: []v1.LoadBalancerIngress{{IP: "1.2.3.4"}},
+			},
+		},
+	}
+
+	rules, err := GetServiceNSGRules(getter, svc)
+	assert.NoError(t, err)
+	assert.Len(t, rules, 2)
+	assert.Equal(t, "svc-TCP-80-Internet", to.String(rules[0].Name))
+	assert.Equal(t, "shared-TCP-443-Internet", to.String(rules[1].Name))
+
+	_, err = GetServiceNSGRules(getter, &v1.Service{})
+	assert.Error(t, err)
+}
+
+type fakeLoadBalancerGetter struct {
+	lbs   []aznetwork.LoadBalancer
+	calls int
+}
+
+func (f *fakeLoadBalancerGetter) GetResourceGroup() string {
+	return "rg"
+}
+
+func (f *fakeLoadBalancerGetter) GetLoadBalancer(resourceGroupName, lbName string) (aznetwork.LoadBalancer, error) {
+	lb := f.lbs[len(f.lbs)-1]
+	if f.calls < len(f.lbs) {
+		lb = f.lbs[f.calls]
+	}
+	f.calls++
+	return lb, nil
+}
+
+func TestWaitForBackendPoolMembers(t *testing.T) {
+	vmssIPConfigID := "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0/networkInterfaces/nic/ipConfigurations/ipconfig1"
+	vmIPConfigID := "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/networkInterfaces/vm-nic/ipConfigurations/ipconfig1"
+	lbWithPools := func(ipConfigIDs ...string) aznetwork.LoadBalancer {
+		ipConfigs := make([]aznetwork.InterfaceIPConfiguration, 0)
+		for _, id := range ipConfigIDs {
+			ipConfigs = append(ipConfigs, aznetwork.InterfaceIPConfiguration{ID: to.StringPtr(id)})
+		}
+		return aznetwork.LoadBalancer{
+			Name: to.StringPtr("lb"),
+			LoadBalancerPropertiesFormat: &aznetwork.LoadBalancerPropertiesFormat{
+				BackendAddressPools: &[]aznetwork.BackendAddressPool{
+					{
+						Name: to.StringPtr("lb"),
+						BackendAddressPoolPropertiesFormat: &aznetwork.BackendAddressPoolPropertiesFormat{
+							BackendIPConfigurations: &ipConfigs,
+						},
+					},
+				},
+			},
+		}
+	}
+	getter := &fakeLoadBalancerGetter{
+		lbs: []aznetwork.LoadBalancer{
+			{Name: to.StringPtr("lb")},
+			lbWithPools(vmssIPConfigID),
+			lbWithPools(vmssIPConfigID, vmIPConfigID),
+		},
+	}
+
+	members, err := WaitForBackendPoolMembers(getter, "lb", 2, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{vmssIPConfigID, vmIPConfigID}, members)
+	assert.Equal(t, 3, getter.calls)
+}
+
+func TestGetBackendPoolMembersIPBased(t *testing.T) {
+	lb := aznetwork.LoadBalancer{
+		LoadBalancerPropertiesFormat: &aznetwork.LoadBalancerPropertiesFormat{
+			BackendAddressPools: &[]aznetwork.BackendAddressPool{
+				{
+					BackendAddressPoolPropertiesFormat: &aznetwork.BackendAddressPoolPropertiesFormat{
+						LoadBalancerBackendAddresses: &[]aznetwork.LoadBalancerBackendAddress{
+							{
+								Name: to.StringPtr("node1"),
+								LoadBalancerBackendAddressPropertiesFormat: &aznetwork.LoadBalancerBackendAddressPropertiesFormat{
+									IPAddress: to.StringPtr("10.240.0.4"),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	assert.Equal(t, []string{"10.240.0.4"}, getBackendPoolMembers(&lb))
+}
+
+type fakePublicIPsLister struct {
+	pips []aznetwork.PublicIPAddress
+}
+
+func (f *fakePublicIPsLister) ListPublicIPs(resourceGroupName string) ([]aznetwork.PublicIPAddress, error) {
+	return f.pips, nil
+}
+
+func TestAssertNoOrphanedPublicIPs(t *testing.T) {
+	originalGracePeriod := orphanedPublicIPGracePeriod
+	orphanedPublicIPGracePeriod = 10 * time.Millisecond
+	defer func() {
+		orphanedPublicIPGracePeriod = originalGracePeriod
+	}()
+
+	clusterTags := map[string]*string{consts.ClusterNameKey: to.StringPtr("cluster")}
+	attached := aznetwork.PublicIPAddress{
+		Name: to.StringPtr("attached"),
+		Tags: clusterTags,
+		PublicIPAddressPropertiesFormat: &aznetwork.PublicIPAddressPropertiesFormat{
+			IPConfiguration: &aznetwork.IPConfiguration{ID: to.StringPtr("/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/lb/frontendIPConfigurations/fip")},
+		},
+	}
+	orphaned := aznetwork.PublicIPAddress{
+		Name:                            to.StringPtr("orphaned"),
+		Tags:                            clusterTags,
+		PublicIPAddressPropertiesFormat: &aznetwork.PublicIPAddressPropertiesFormat{},
+	}
+	otherCluster := aznetwork.PublicIPAddress{
+		Name:                            to.StringPtr("other-cluster"),
+		Tags:                            map[string]*string{consts.ClusterNameKey: to.StringPtr("other")},
+		PublicIPAddressPropertiesFormat: &aznetwork.PublicIPAddressPropertiesFormat{},
+	}
+
+	err := AssertNoOrphanedPublicIPs(&fakePublicIPsLister{pips: []aznetwork.PublicIPAddress{attached, orphaned, otherCluster}}, "rg", "cluster")
+	assert.EqualError(t, err, "public IPs [orphaned] of cluster cluster in resource group rg are not associated with any resource after 10ms")
+
+	err = AssertNoOrphanedPublicIPs(&fakePublicIPsLister{pips: []aznetwork.PublicIPAddress{attached, otherCluster}}, "rg", "cluster")
+	assert.NoError(t, err)
+}
+
+type fakeServiceLoadBalancerInspector struct {
+	lbs  []aznetwork.LoadBalancer
+	pips map[string][]aznetwork.PublicIPAddress
+}
+
+func (f *fakeServiceLoadBalancerInspector) GetResourceGroup() string {
+	return "rg"
+}
+
+func (f *fakeServiceLoadBalancerInspector) GetLoadBalancer(resourceGroupName, lbName string) (aznetwork.LoadBalancer, error) {
+	for _, lb := range f.lbs {
+		if strings.Contains(to.String(lb.ID), "/resourceGroups/"+resourceGroupName+"/") && to.String(lb.Name) == lbName {
+			return lb, nil
+		}
+	}
+	return aznetwork.LoadBalancer{}, fmt.Errorf("load balancer %s/%s is not found", resourceGroupName, lbName)
+}
+
+func (f *fakeServiceLoadBalancerInspector) ListLoadBalancers(resourceGroupName string) ([]aznetwork.LoadBalancer, error) {
+	return f.lbs, nil
+}
+
+func (f *fakeServiceLoadBalancerInspector) ListPublicIPs(resourceGroupName string) ([]aznetwork.PublicIPAddress, error) {
+	return f.pips[resourceGroupName], nil
+}
+
+func TestGetServiceLoadBalancerResources(t *testing.T) {
+	originalTimeout := serviceLoadBalancerInspectionTimeout
+	serviceLoadBalancerInspectionTimeout = 10 * time.Millisecond
+	defer func() {
+		serviceLoadBalancerInspectionTimeout = originalTimeout
+	}()
+
+	lbID := "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/kubernetes"
+	newLB := func(name string, frontends ...aznetwork.FrontendIPConfiguration) aznetwork.LoadBalancer {
+		id := "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/" + name
+		rules := make([]aznetwork.LoadBalancingRule, 0)
+		probes := make([]aznetwork.Probe, 0)
+		for i, fip := range frontends {
+			fip.ID = to.StringPtr(fmt.Sprintf("%s/frontendIPConfigurations/fip%d", id, i))
+			frontends[i] = fip
+			probeID := fmt.Sprintf("%s/probes/probe%d", id, i)
+			rules = append(rules, aznetwork.LoadBalancingRule{
+				Name: to.StringPtr(fmt.Sprintf("rule%d", i)),
+				LoadBalancingRulePropertiesFormat: &aznetwork.LoadBalancingRulePropertiesFormat{
+					FrontendIPConfiguration: &aznetwork.SubResource{ID: fip.ID},
+					Probe:                   &aznetwork.SubResource{ID: to.StringPtr(probeID)},
+					IdleTimeoutInMinutes:    to.Int32Ptr(5),
+				},
+			})
+			probes = append(probes, aznetwork.Probe{ID: to.StringPtr(probeID), Name: to.StringPtr(fmt.Sprintf("probe%d", i))})
+		}
+		return aznetwork.LoadBalancer{
+			ID:   to.StringPtr(id),
+			Name: to.StringPtr(name),
+			LoadBalancerPropertiesFormat: &aznetwork.LoadBalancerPropertiesFormat{
+				FrontendIPConfigurations: &frontends,
+				LoadBalancingRules:       &rules,
+				Probes:                   &probes,
+			},
+		}
+	}
+	newService := func(annotations map[string]string, ips ...string) *v1.Service {
+		svc := &v1.Service{ObjectMeta: metav1.ObjectMeta{Name: "svc", Namespace: "ns", Annotations: annotations}}
+		for _, ip := range ips {
+			svc.Status.LoadBalancer.Ingress = append(svc.Status.LoadBalancer.Ingress, v1.LoadBalancerIngress{IP: ip})
+		}
+		return svc
+	}
+
+	external := newLB("kubernetes", aznetwork.FrontendIPConfiguration{}, aznetwork.FrontendIPConfiguration{})
+	internal := newLB("kubernetes-internal", aznetwork.FrontendIPConfiguration{
+		FrontendIPConfigurationPropertiesFormat: &aznetwork.FrontendIPConfigurationPropertiesFormat{PrivateIPAddress: to.StringPtr("10.0.0.10")},
+	})
+	pip := func(ip, frontendID string) aznetwork.PublicIPAddress {
+		return aznetwork.PublicIPAddress{
+			Name: to.StringPtr("pip-" + ip),
+			PublicIPAddressPropertiesFormat: &aznetwork.PublicIPAddressPropertiesFormat{
+				IPAddress:       to.StringPtr(ip),
+				IPConfiguration: &aznetwork.IPConfiguration{ID: to.StringPtr(frontendID)},
+			},
+		}
+	}
+	inspector := &fakeServiceLoadBalancerInspector{
+		lbs: []aznetwork.LoadBalancer{external, internal},
+		pips: map[string][]aznetwork.PublicIPAddress{
+			"rg":       {pip("20.0.0.1", lbID+"/frontendIPConfigurations/fip0")},
+			"pip-rg":   {pip("20.0.0.2", lbID+"/frontendIPConfigurations/fip1")},
+			"empty-rg": {},
+		},
+	}
+
+	t.Run("should get the rules and probes of the external service", func(t *testing.T) {
+		svc := newService(nil, "20.0.0.1")
+		rules, err := GetServiceLoadBalancerRules(inspector, svc)
+		assert.NoError(t, err)
+		assert.Len(t, rules, 1)
+		assert.Equal(t, "rule0", to.String(rules[0].Name))
+
+		probes, err := GetServiceHealthProbes(inspector, svc)
+		assert.NoError(t, err)
+		assert.Len(t, probes, 1)
+		assert.Equal(t, "probe0", to.String(probes[0].Name))
+	})
+
+	t.Run("should get the frontend public IP in the load balancer resource group", func(t *testing.T) {
+		svc := newService(map[string]string{consts.ServiceAnnotationLoadBalancerResourceGroup: "pip-rg"}, "20.0.0.2")
+		pip, err := GetServiceFrontendPIP(inspector, svc)
+		assert.NoError(t, err)
+		assert.Equal(t, "pip-20.0.0.2", to.String(pip.Name))
+
+		rules, err := GetServiceLoadBalancerRules(inspector, svc)
+		assert.NoError(t, err)
+		assert.Len(t, rules, 1)
+		assert.Equal(t, "rule1", to.String(rules[0].Name))
+	})
+
+	t.Run("should get the rules of the internal service by the private IP", func(t *testing.T) {
+		svc := newService(map[string]string{consts.ServiceAnnotationLoadBalancerInternal: "true"}, "10.0.0.10")
+		rules, err := GetServiceLoadBalancerRules(inspector, svc)
+		assert.NoError(t, err)
+		assert.Len(t, rules, 1)
+		assert.Equal(t, int32(5), to.Int32(rules[0].IdleTimeoutInMinutes))
+
+		_, err = GetServiceFrontendPIP(inspector, svc)
+		assert.Error(t, err)
+	})
+
+	t.Run("should return an error if the public IP is not found", func(t *testing.T) {
+		svc := newService(map[string]string{consts.ServiceAnnotationLoadBalancerResourceGroup: "empty-rg"}, "20.0.0.1")
+		_, err := GetServiceLoadBalancerRules(inspector, svc)
+		assert.ErrorIs(t, err, wait.ErrWaitTimeout)
+		assert.Contains(t, err.Error(), "found no public IP 20.0.0.1 in resource group empty-rg")
+	})
+
+	t.Run("should attach the raw load balancer if the service has no rules", func(t *testing.T) {
+		noRules := newLB("kubernetes")
+		noRules.FrontendIPConfigurations = external.FrontendIPConfigurations
+		svc := newService(nil, "20.0.0.1")
+		_, err := GetServiceLoadBalancerRules(&fakeServiceLoadBalancerInspector{lbs: []aznetwork.LoadBalancer{noRules}, pips: inspector.pips}, svc)
+		assert.ErrorIs(t, err, wait.ErrWaitTimeout)
+		assert.Contains(t, err.Error(), `"loadBalancingRules":[]`)
+	})
+}
+
+func TestGetServiceLBDistributionMode(t *testing.T) {
+	originalTimeout := serviceLoadBalancerInspectionTimeout
+	serviceLoadBalancerInspectionTimeout = 10 * time.Millisecond
+	defer func() {
+		serviceLoadBalancerInspectionTimeout = originalTimeout
+	}()
+
+	lbID := "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/kubernetes"
+	frontendID := lbID + "/frontendIPConfigurations/fip"
+	newInspector := func(modes ...aznetwork.LoadDistribution) *fakeServiceLoadBalancerInspector {
+		rules := make([]aznetwork.LoadBalancingRule, 0)
+		for _, mode := range modes {
+			rules = append(rules, aznetwork.LoadBalancingRule{
+				LoadBalancingRulePropertiesFormat: &aznetwork.LoadBalancingRulePropertiesFormat{
+					FrontendIPConfiguration: &aznetwork.SubResource{ID: to.StringPtr(frontendID)},
+					LoadDistribution:        mode,
+				},
+			})
+		}
+		return &fakeServiceLoadBalancerInspector{
+			lbs: []aznetwork.LoadBalancer{{
+				ID:   to.StringPtr(lbID),
+				Name: to.StringPtr("kubernetes"),
+				LoadBalancerPropertiesFormat: &aznetwork.LoadBalancerPropertiesFormat{
+					FrontendIPConfigurations: &[]aznetwork.FrontendIPConfiguration{{ID: to.StringPtr(frontendID)}},
+					LoadBalancingRules:       &rules,
+				},
+			}},
+			pips: map[string][]aznetwork.PublicIPAddress{
+				"rg": {{
+					PublicIPAddressPropertiesFormat: &aznetwork.PublicIPAddressPropertiesFormat{
+						IPAddress:       to.StringPtr("20.0.0.1"),
+						IPConfiguration: &aznetwork.IPConfiguration{ID: to.StringPtr(frontendID)},
+					},
+				}},
+			},
+		}
+	}
+
+	for _, tc := range []struct {
+		description  string
+		affinity     v1.ServiceAffinity
+		modes        []aznetwork.LoadDistribution
+		expectedMode string
+		expectedErr  bool
+	}{
+		{
+			description:  "should return SourceIP for the ClientIP affinity",
+			affinity:     v1.ServiceAffinityClientIP,
+			modes:        []aznetwork.LoadDistribution{aznetwork.LoadDistributionSourceIP, aznetwork.LoadDistributionSourceIP},
+			expectedMode: "SourceIP",
+		},
+		{
+			description:  "should return Default for the None affinity",
+			affinity:     v1.ServiceAffinityNone,
+			modes:        []aznetwork.LoadDistribution{aznetwork.LoadDistributionDefault, ""},
+			expectedMode: "Default",
+		},
+		{
+			description: "should return an error if the rules have different distribution modes",
+			affinity:    v1.ServiceAffinityClientIP,
+			modes:       []aznetwork.LoadDistribution{aznetwork.LoadDistributionSourceIP, aznetwork.LoadDistributionDefault},
+			expectedErr: true,
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			svc := &v1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "svc", Namespace: "ns"},
+				Spec:       v1.ServiceSpec{SessionAffinity: tc.affinity},
+				Status: v1.ServiceStatus{
+					LoadBalancer: v1.LoadBalancerStatus{Ingress: []v1.LoadBalancerIngress{{IP: "20.0.0.1"}}},
+				},
+			}
+			mode, err := GetServiceLBDistributionMode(newInspector(tc.modes...), svc)
+			assert.Equal(t, tc.expectedErr, err != nil, "unexpected error: %v", err)
+			if !tc.expectedErr {
+				assert.Equal(t, tc.expectedMode, mode)
+				assert.Equal(t, ExpectedLBDistributionMode(svc), mode)
+			}
+		})
+	}
+}
+
+func TestGetServiceLBIdleTimeout(t *testing.T) {
+	originalTimeout := serviceLoadBalancerInspectionTimeout
+	serviceLoadBalancerInspectionTimeout = 10 * time.Millisecond
+	defer func() {
+		serviceLoadBalancerInspectionTimeout = originalTimeout
+	}()
+
+	lbID := "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/kubernetes"
+	frontendID := lbID + "/frontendIPConfigurations/fip"
+	newInspector := func(idleTimeouts ...*int32) *fakeServiceLoadBalancerInspector {
+		rules := make([]aznetwork.LoadBalancingRule, 0)
+		for _, idleTimeout := range idleTimeouts {
+			rules = append(rules, aznetwork.LoadBalancingRule{
+				LoadBalancingRulePropertiesFormat: &aznetwork.LoadBalancingRulePropertiesFormat{
+					FrontendIPConfiguration: &aznetwork.SubResource{ID: to.StringPtr(frontendID)},
+					IdleTimeoutInMinutes:    idleTimeout,
+				},
+			})
+		}
+		return &fakeServiceLoadBalancerInspector{
+			lbs: []aznetwork.LoadBalancer{{
+				ID:   to.StringPtr(lbID),
+				Name: to.StringPtr("kubernetes"),
+				LoadBalancerPropertiesFormat: &aznetwork.LoadBalancerPropertiesFormat{
+					FrontendIPConfigurations: &[]aznetwork.FrontendIPConfiguration{{ID: to.StringPtr(frontendID)}},
+					LoadBalancingRules:       &rules,
+				},
+			}},
+			pips: map[string][]aznetwork.PublicIPAddress{
+				"rg": {{
+					PublicIPAddressPropertiesFormat: &aznetwork.PublicIPAddressPropertiesFormat{
+						IPAddress:       to.StringPtr("20.0.0.1"),
+						IPConfiguration: &aznetwork.IPConfiguration{ID: to.StringPtr(frontendID)},
+					},
+				}},
+			},
+		}
+	}
+
+	for _, tc := range []struct {
+		description         string
+		annotations         map[string]string
+		idleTimeouts        []*int32
+		expectedIdleTimeout int32
+		expectedErr         bool
+	}{
+		{
+			description:         "should return the idle timeout of the annotation",
+			annotations:         map[string]string{consts.ServiceAnnotationLoadBalancerIdleTimeout: "15"},
+			idleTimeouts:        []*int32{to.Int32Ptr(15), to.Int32Ptr(15)},
+			expectedIdleTimeout: 15,
+		},
+		{
+			description:         "should return the default idle timeout without the annotation",
+			idleTimeouts:        []*int32{to.Int32Ptr(4), nil},
+			expectedIdleTimeout: 4,
+		},
+		{
+			description:  "should return an error if the rules have different idle timeouts",
+			idleTimeouts: []*int32{to.Int32Ptr(15), nil},
+			expectedErr:  true,
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			svc := &v1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "svc", Namespace: "ns", Annotations: tc.annotations},
+				Status: v1.ServiceStatus{
+					LoadBalancer: v1.LoadBalancerStatus{Ingress: []v1.LoadBalancerIngress{{IP: "20.0.0.1"}}},
+				},
+			}
+			idleTimeout, err := GetServiceLBIdleTimeout(newInspector(tc.idleTimeouts...), svc)
+			assert.Equal(t, tc.expectedErr, err != nil, "unexpected error: %v", err)
+			if !tc.expectedErr {
+				assert.Equal(t, tc.expectedIdleTimeout, idleTimeout)
+				expectedIdleTimeout, err := ExpectedLBIdleTimeout(svc)
+				assert.NoError(t, err)
+				assert.Equal(t, expectedIdleTimeout, idleTimeout)
+			}
+		})
+	}
+}
+
+func TestGetServiceOutboundRule(t *testing.T) {
+	lbID := "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/kubernetes"
+	frontendID := lbID + "/frontendIPConfigurations/fip"
+	backendPoolID := lbID + "/backendAddressPools/kubernetes"
+	newInspector := func(outboundRules ...aznetwork.OutboundRule) *fakeServiceLoadBalancerInspector {
+		return &fakeServiceLoadBalancerInspector{
+			lbs: []aznetwork.LoadBalancer{{
+				ID:   to.StringPtr(lbID),
+				Name: to.StringPtr("kubernetes"),
+				LoadBalancerPropertiesFormat: &aznetwork.LoadBalancerPropertiesFormat{
+					FrontendIPConfigurations: &[]aznetwork.FrontendIPConfiguration{{ID: to.StringPtr(frontendID)}},
+					LoadBalancingRules: &[]aznetwork.LoadBalancingRule{{
+						LoadBalancingRulePropertiesFormat: &aznetwork.LoadBalancingRulePropertiesFormat{
+							FrontendIPConfiguration: &aznetwork.SubResource{ID: to.StringPtr(frontendID)},
+							BackendAddressPool:      &aznetwork.SubResource{ID: to.StringPtr(backendPoolID)},
+						},
+					}},
+					OutboundRules: &outboundRules,
+				},
+			}},
+			pips: map[string][]aznetwork.PublicIPAddress{
+				"rg": {{
+					PublicIPAddressPropertiesFormat: &aznetwork.PublicIPAddressPropertiesFormat{
+						IPAddress:       to.StringPtr("20.0.0.1"),
+						IPConfiguration: &aznetwork.IPConfiguration{ID: to.StringPtr(frontendID)},
+					},
+				}},
+			},
+		}
+	}
+	newOutboundRule := func(name, frontendID, backendPoolID string, ports int32) aznetwork.OutboundRule {
+		return aznetwork.OutboundRule{
+			Name: to.StringPtr(name),
+			OutboundRulePropertiesFormat: &aznetwork.OutboundRulePropertiesFormat{
+				AllocatedOutboundPorts:   to.Int32Ptr(ports),
+				Protocol:                 aznetwork.LoadBalancerOutboundRuleProtocolAll,
+				FrontendIPConfigurations: &[]aznetwork.SubResource{{ID: to.StringPtr(frontendID)}},
+				BackendAddressPool:       &aznetwork.SubResource{ID: to.StringPtr(backendPoolID)},
+			},
+		}
+	}
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "svc", Namespace: "ns"},
+		Status: v1.ServiceStatus{
+			LoadBalancer: v1.LoadBalancerStatus{Ingress: []v1.LoadBalancerIngress{{IP: "20.0.0.1"}}},
+		},
+	}
+
+	for _, tc := range []struct {
+		description  string
+		inspector    *fakeServiceLoadBalancerInspector
+		expectedRule string
+		expectedPort int32
+	}{
+		{
+			description: "should return the outbound rule using the frontend of the service",
+			inspector: newInspector(
+				newOutboundRule("default", lbID+"/frontendIPConfigurations/outbound", backendPoolID, 1024),
+				newOutboundRule("explicit", frontendID, lbID+"/backendAddressPools/other", 2048),
+			),
+			expectedRule: "explicit",
+			expectedPort: 2048,
+		},
+		{
+			description: "should return the default outbound rule of the backend pool",
+			inspector: newInspector(
+				newOutboundRule("other", lbID+"/frontendIPConfigurations/outbound", lbID+"/backendAddressPools/other", 2048),
+				newOutboundRule("default", lbID+"/frontendIPConfigurations/outbound", strings.ToUpper(backendPoolID), 1024),
+			),
+			expectedRule: "default",
+			expectedPort: 1024,
+		},
+		{
+			description: "should return nil for the default outbound access",
+			inspector:   newInspector(),
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			rule, err := GetServiceOutboundRule(tc.inspector, svc)
+			assert.NoError(t, err)
+			if tc.expectedRule == "" {
+				assert.Nil(t, rule)
+				return
+			}
+			assert.Equal(t, tc.expectedRule, to.String(rule.Name))
+			assert.Equal(t, tc.expectedPort, to.Int32(rule.AllocatedOutboundPorts))
+			assert.Equal(t, aznetwork.LoadBalancerOutboundRuleProtocolAll, rule.Protocol)
+		})
+	}
+}
@@ -236,6 +236,45 @@ func WaitAutoScaleNodes(cs clientset.Interface, targetNodeCount int, isScaleDown
 	return err
 }
 
+// nodeCountPollInterval is the interval of listing the nodes in WaitForNodeCount.
+var nodeCountPollInterval = 30 * time.Second
+
+// WaitForNodeCount waits until there are expected ready agent nodes, e.g. after the VMSS is scaled by ScaleVMSS.
+func WaitForNodeCount(cs clientset.Interface, expected int, timeout time.Duration) error {
+	var readyCount int
+	err := wait.PollImmediate(nodeCountPollInterval, timeout, func() (bool, error) {
+		nodes, err := GetAgentNodes(cs)
+		if err != nil {
+			if IsRetryableAPIError(err) {
+				return false, nil
+			}
+			return false, err
+		}
+
+		readyCount = 0
+		for i := range nodes {
+			if isNodeReady(&nodes[i]) {
+				readyCount++
+			}
+		}
+		Logf("%d of %d agent nodes are ready, expected %d", readyCount, len(nodes), expected)
+		return readyCount == expected && len(nodes) == expected, nil
+	})
+	if errors.Is(err, wait.ErrWaitTimeout) {
+		return fmt.Errorf("%d agent nodes are ready after %v, expected %d: %w", readyCount, timeout, expected, err)
+	}
+	return err
+}
+
+func isNodeReady(node *v1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
 // IsControlPlaneNode returns true if the node has a control-plane role label.
 // The control-plane role is determined by looking for:
 // * a node-role.kubernetes.io/control-plane or node-role.kubernetes.io/master="" label
@@ -0,0 +1,60 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestWaitForNodeCount(t *testing.T) {
+	originalInterval := nodeCountPollInterval
+	nodeCountPollInterval = 10 * time.Millisecond
+	defer func() {
+		nodeCountPollInterval = originalInterval
+	}()
+
+	newNode := func(name string, ready v1.ConditionStatus, labels map[string]string) *v1.Node {
+		return &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+			Status: v1.NodeStatus{
+				Conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: ready}},
+			},
+		}
+	}
+	cs := fake.NewSimpleClientset(
+		newNode("master", v1.ConditionTrue, map[string]string{controlPlaneNodeRoleLabel: ""}),
+		newNode("node-0", v1.ConditionTrue, nil),
+		newNode("node-1", v1.ConditionFalse, nil),
+	)
+
+	err := WaitForNodeCount(cs, 2, 50*time.Millisecond)
+	assert.ErrorIs(t, err, wait.ErrWaitTimeout, "the not ready node should not be counted")
+	assert.Contains(t, err.Error(), "1 agent nodes are ready")
+
+	_, err = cs.CoreV1().Nodes().Update(context.TODO(), newNode("node-1", v1.ConditionTrue, nil), metav1.UpdateOptions{})
+	assert.NoError(t, err)
+	assert.NoError(t, WaitForNodeCount(cs, 2, 50*time.Millisecond))
+}
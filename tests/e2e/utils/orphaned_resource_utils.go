@@ -0,0 +1,407 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	aznetwork "github.com/Azure/azure-sdk-for-go/services/network/mgmt/2021-08-01/network"
+	"github.com/Azure/go-autorest/autorest/to"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	clientset "k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+)
+
+// serviceResourceNameRE matches the names of the load balancer frontends, rules and probes and the security
+// rules created for the services, which are prefixed by "a" and the service UID without dashes.
+var serviceResourceNameRE = regexp.MustCompile(`(?i)^a([0-9a-f]{32})`)
+
+// OrphanedResources are the Azure resources left behind by the services which no longer exist in the cluster.
+type OrphanedResources struct {
+	// PublicIPs are the names of the orphaned public IPs in the cluster resource group.
+	PublicIPs []string
+	// LoadBalancerFrontends maps the names of the load balancers to the names of their orphaned frontend IP configurations.
+	LoadBalancerFrontends map[string][]string
+	// SecurityRules maps the names of the security groups to the names of their orphaned security rules.
+	SecurityRules map[string][]string
+}
+
+// IsEmpty returns true if no orphaned resource is found.
+func (o *OrphanedResources) IsEmpty() bool {
+	return len(o.PublicIPs) == 0 && len(o.LoadBalancerFrontends) == 0 && len(o.SecurityRules) == 0
+}
+
+// OrphanedResourcesLister lists the resources in the cluster resource group, it's implemented by AzureTestClient.
+type OrphanedResourcesLister interface {
+	PublicIPsLister
+	ClusterSecurityGroupsGetter
+	GetResourceGroup() string
+	ListLoadBalancers(resourceGroupName string) ([]aznetwork.LoadBalancer, error)
+	ListResourceChangedTimes(resourceGroupName string) (map[string]time.Time, error)
+}
+
+var _ OrphanedResourcesLister = &AzureTestClient{}
+
+// ListResourceChangedTimes returns the last changed time of the resources in the resource group keyed by the
+// lower-cased resource IDs.
+func (azureTestClient *AzureTestClient) ListResourceChangedTimes(resourceGroupName string) (map[string]time.Time, error) {
+	resourcesClient := azureTestClient.createResourcesClient()
+
+	iterator, err := resourcesClient.ListByResourceGroupComplete(context.Background(), resourceGroupName, "", "changedTime", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]time.Time)
+	for ; iterator.NotDone(); err = iterator.Next() {
+		if err != nil {
+			return nil, err
+		}
+
+		resource := iterator.Value()
+		if resource.ID != nil && resource.ChangedTime != nil {
+			result[strings.ToLower(*resource.ID)] = resource.ChangedTime.Time
+		}
+	}
+
+	return result, nil
+}
+
+// CleanupOrphanedResources deletes the public IPs, load balancer frontends and security rules in the cluster
+// resource group which belong to the services no longer existing in the cluster clusterName. The resources
+// changed within olderThan are skipped since the cloud provider may be still cleaning them up. If dryRun is
+// true, the orphaned resources are only logged.
+func CleanupOrphanedResources(tc *AzureTestClient, clusterName string, olderThan time.Duration, dryRun bool) error {
+	cs, err := CreateKubeClientSet()
+	if err != nil {
+		return err
+	}
+	orphaned, err := FindOrphanedResources(tc, cs, clusterName, olderThan)
+	if err != nil {
+		return err
+	}
+	if orphaned.IsEmpty() {
+		Logf("No orphaned resources of cluster %s found in resource group %s", clusterName, tc.GetResourceGroup())
+		return nil
+	}
+	Logf("Found orphaned resources of cluster %s in resource group %s: public IPs %v, load balancer frontends %v, security rules %v",
+		clusterName, tc.GetResourceGroup(), orphaned.PublicIPs, orphaned.LoadBalancerFrontends, orphaned.SecurityRules)
+	if dryRun {
+		Logf("Dry run, skip deleting the orphaned resources")
+		return nil
+	}
+
+	var errs []error
+	for lbName, frontendNames := range orphaned.LoadBalancerFrontends {
+		if err := deleteLoadBalancerFrontends(tc, lbName, frontendNames); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete the frontends %v of load balancer %s: %w", frontendNames, lbName, err))
+		}
+	}
+	for nsgName, ruleNames := range orphaned.SecurityRules {
+		if err := deleteSecurityRules(tc, nsgName, ruleNames); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete the rules %v of security group %s: %w", ruleNames, nsgName, err))
+		}
+	}
+	// The public IPs are deleted after the frontends referencing them.
+	for _, pipName := range orphaned.PublicIPs {
+		if err := DeletePIPWithRetry(tc, pipName, tc.GetResourceGroup()); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete public IP %s: %w", pipName, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// FindOrphanedResources returns the resources in the cluster resource group which belong to the services no
+// longer existing in the cluster clusterName and are not changed within olderThan. The resources of which the
+// changed time is unknown are not considered orphaned.
+func FindOrphanedResources(azureClient OrphanedResourcesLister, cs clientset.Interface, clusterName string, olderThan time.Duration) (*OrphanedResources, error) {
+	services, err := cs.CoreV1().Services("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	serviceKeys, serviceUIDs := sets.NewString(), sets.NewString()
+	for _, svc := range services.Items {
+		serviceKeys.Insert(strings.ToLower(fmt.Sprintf("%s/%s", svc.Namespace, svc.Name)))
+		serviceUIDs.Insert(strings.ToLower(strings.ReplaceAll(string(svc.UID), "-", "")))
+	}
+
+	resourceGroup := azureClient.GetResourceGroup()
+	changedTimes, err := azureClient.ListResourceChangedTimes(resourceGroup)
+	if err != nil {
+		return nil, err
+	}
+	isOldEnough := func(id *string) bool {
+		changedTime, ok := changedTimes[strings.ToLower(to.String(id))]
+		return ok && time.Since(changedTime) >= olderThan
+	}
+
+	lbs, err := azureClient.ListLoadBalancers(resourceGroup)
+	if err != nil {
+		return nil, err
+	}
+	nsgs, err := azureClient.GetClusterSecurityGroups()
+	if err != nil {
+		return nil, err
+	}
+	pips, err := azureClient.ListPublicIPs(resourceGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	orphaned := &OrphanedResources{
+		LoadBalancerFrontends: make(map[string][]string),
+		SecurityRules:         make(map[string][]string),
+	}
+	orphanedFrontendIDs := sets.NewString()
+	for i := range lbs {
+		if !isOldEnough(lbs[i].ID) {
+			continue
+		}
+		for _, frontend := range getOrphanedLoadBalancerFrontends(&lbs[i], serviceUIDs) {
+			orphaned.LoadBalancerFrontends[to.String(lbs[i].Name)] = append(orphaned.LoadBalancerFrontends[to.String(lbs[i].Name)], to.String(frontend.Name))
+			orphanedFrontendIDs.Insert(strings.ToLower(to.String(frontend.ID)))
+		}
+	}
+	for i := range nsgs {
+		if !isOldEnough(nsgs[i].ID) {
+			continue
+		}
+		if rules := getOrphanedSecurityRules(&nsgs[i], serviceUIDs); len(rules) > 0 {
+			orphaned.SecurityRules[to.String(nsgs[i].Name)] = rules
+		}
+	}
+	for _, pip := range pips {
+		if !isOldEnough(pip.ID) || !isOrphanedServicePublicIP(pip, clusterName, serviceKeys, orphanedFrontendIDs) {
+			continue
+		}
+		orphaned.PublicIPs = append(orphaned.PublicIPs, to.String(pip.Name))
+	}
+	return orphaned, nil
+}
+
+// serviceUIDFromResourceName returns the lower-cased service UID without dashes in the name of the resource
+// created for the service.
+func serviceUIDFromResourceName(name string) (string, bool) {
+	matches := serviceResourceNameRE.FindStringSubmatch(name)
+	if len(matches) != 2 {
+		return "", false
+	}
+	return strings.ToLower(matches[1]), true
+}
+
+// isOrphanedServiceResource returns true if the resource is created for a service not in serviceUIDs.
+func isOrphanedServiceResource(name string, serviceUIDs sets.String) bool {
+	uid, ok := serviceUIDFromResourceName(name)
+	return ok && !serviceUIDs.Has(uid)
+}
+
+// getOrphanedLoadBalancerFrontends returns the frontends of the load balancer created for the services not in
+// serviceUIDs. A frontend shared with an existing service, which is referenced by the rules of the service, is
+// not orphaned.
+func getOrphanedLoadBalancerFrontends(lb *aznetwork.LoadBalancer, serviceUIDs sets.String) []aznetwork.FrontendIPConfiguration {
+	if lb.LoadBalancerPropertiesFormat == nil || lb.FrontendIPConfigurations == nil {
+		return nil
+	}
+	inUseFrontendIDs := sets.NewString()
+	if lb.LoadBalancingRules != nil {
+		for _, rule := range *lb.LoadBalancingRules {
+			if isOrphanedServiceResource(to.String(rule.Name), serviceUIDs) ||
+				rule.LoadBalancingRulePropertiesFormat == nil || rule.FrontendIPConfiguration == nil {
+				continue
+			}
+			inUseFrontendIDs.Insert(strings.ToLower(to.String(rule.FrontendIPConfiguration.ID)))
+		}
+	}
+
+	orphaned := make([]aznetwork.FrontendIPConfiguration, 0)
+	for _, frontend := range *lb.FrontendIPConfigurations {
+		if isOrphanedServiceResource(to.String(frontend.Name), serviceUIDs) && !inUseFrontendIDs.Has(strings.ToLower(to.String(frontend.ID))) {
+			orphaned = append(orphaned, frontend)
+		}
+	}
+	return orphaned
+}
+
+// getOrphanedSecurityRules returns the names of the rules of the security group created for the services not
+// in serviceUIDs.
+func getOrphanedSecurityRules(nsg *aznetwork.SecurityGroup, serviceUIDs sets.String) []string {
+	if nsg.SecurityGroupPropertiesFormat == nil || nsg.SecurityRules == nil {
+		return nil
+	}
+	orphaned := make([]string, 0)
+	for _, rule := range *nsg.SecurityRules {
+		if isOrphanedServiceResource(to.String(rule.Name), serviceUIDs) {
+			orphaned = append(orphaned, to.String(rule.Name))
+		}
+	}
+	return orphaned
+}
+
+// isOrphanedServicePublicIP returns true if the public IP is tagged for the cluster clusterName and the services
+// in the tag are not in serviceKeys. The public IP still associated with an IP configuration other than the
+// orphaned frontends is in use and not orphaned.
+func isOrphanedServicePublicIP(pip aznetwork.PublicIPAddress, clusterName string, serviceKeys, orphanedFrontendIDs sets.String) bool {
+	if pip.Tags == nil {
+		return false
+	}
+	clusterTag := to.String(pip.Tags[consts.ClusterNameKey])
+	if clusterTag == "" {
+		clusterTag = to.String(pip.Tags[consts.LegacyClusterNameKey])
+	}
+	if !strings.EqualFold(clusterTag, clusterName) {
+		return false
+	}
+	serviceTag := to.String(pip.Tags[consts.ServiceTagKey])
+	if serviceTag == "" {
+		serviceTag = to.String(pip.Tags[consts.LegacyServiceTagKey])
+	}
+	if strings.TrimSpace(serviceTag) == "" {
+		return false
+	}
+	for _, serviceKey := range strings.Split(serviceTag, ",") {
+		if serviceKeys.Has(strings.ToLower(strings.TrimSpace(serviceKey))) {
+			return false
+		}
+	}
+
+	if pip.PublicIPAddressPropertiesFormat != nil {
+		if pip.NatGateway != nil {
+			return false
+		}
+		if pip.IPConfiguration != nil && !orphanedFrontendIDs.Has(strings.ToLower(to.String(pip.IPConfiguration.ID))) {
+			return false
+		}
+	}
+	return true
+}
+
+// removeLoadBalancerFrontends removes the frontends from the load balancer together with the rules referencing
+// them, and the probes of the services left unreferenced.
+func removeLoadBalancerFrontends(lb *aznetwork.LoadBalancer, frontendNames []string) {
+	if lb.LoadBalancerPropertiesFormat == nil || lb.FrontendIPConfigurations == nil {
+		return
+	}
+	names := sets.NewString()
+	for _, name := range frontendNames {
+		names.Insert(strings.ToLower(name))
+	}
+	removedFrontendIDs := sets.NewString()
+	frontends := make([]aznetwork.FrontendIPConfiguration, 0)
+	for _, frontend := range *lb.FrontendIPConfigurations {
+		if names.Has(strings.ToLower(to.String(frontend.Name))) {
+			removedFrontendIDs.Insert(strings.ToLower(to.String(frontend.ID)))
+			continue
+		}
+		frontends = append(frontends, frontend)
+	}
+	lb.FrontendIPConfigurations = &frontends
+
+	referencedProbeIDs := sets.NewString()
+	if lb.LoadBalancingRules != nil {
+		rules := make([]aznetwork.LoadBalancingRule, 0)
+		for _, rule := range *lb.LoadBalancingRules {
+			if rule.LoadBalancingRulePropertiesFormat != nil && rule.FrontendIPConfiguration != nil &&
+				removedFrontendIDs.Has(strings.ToLower(to.String(rule.FrontendIPConfiguration.ID))) {
+				continue
+			}
+			if rule.LoadBalancingRulePropertiesFormat != nil && rule.Probe != nil {
+				referencedProbeIDs.Insert(strings.ToLower(to.String(rule.Probe.ID)))
+			}
+			rules = append(rules, rule)
+		}
+		lb.LoadBalancingRules = &rules
+	}
+	if lb.InboundNatRules != nil {
+		natRules := make([]aznetwork.InboundNatRule, 0)
+		for _, natRule := range *lb.InboundNatRules {
+			if natRule.InboundNatRulePropertiesFormat != nil && natRule.FrontendIPConfiguration != nil &&
+				removedFrontendIDs.Has(strings.ToLower(to.String(natRule.FrontendIPConfiguration.ID))) {
+				continue
+			}
+			natRules = append(natRules, natRule)
+		}
+		lb.InboundNatRules = &natRules
+	}
+	if lb.Probes != nil {
+		probes := make([]aznetwork.Probe, 0)
+		for _, probe := range *lb.Probes {
+			if _, ok := serviceUIDFromResourceName(to.String(probe.Name)); ok && !referencedProbeIDs.Has(strings.ToLower(to.String(probe.ID))) {
+				continue
+			}
+			probes = append(probes, probe)
+		}
+		lb.Probes = &probes
+	}
+}
+
+// removeSecurityRules removes the rules from the security group.
+func removeSecurityRules(nsg *aznetwork.SecurityGroup, ruleNames []string) {
+	if nsg.SecurityGroupPropertiesFormat == nil || nsg.SecurityRules == nil {
+		return
+	}
+	names := sets.NewString()
+	for _, name := range ruleNames {
+		names.Insert(strings.ToLower(name))
+	}
+	rules := make([]aznetwork.SecurityRule, 0)
+	for _, rule := range *nsg.SecurityRules {
+		if !names.Has(strings.ToLower(to.String(rule.Name))) {
+			rules = append(rules, rule)
+		}
+	}
+	nsg.SecurityRules = &rules
+}
+
+// deleteLoadBalancerFrontends deletes the frontends from the load balancer in the cluster resource group.
+func deleteLoadBalancerFrontends(tc *AzureTestClient, lbName string, frontendNames []string) error {
+	Logf("Deleting the frontends %v of load balancer %s", frontendNames, lbName)
+	lbClient := tc.createLoadBalancerClient()
+	lb, err := lbClient.Get(context.Background(), tc.GetResourceGroup(), lbName, "")
+	if err != nil {
+		return err
+	}
+	removeLoadBalancerFrontends(&lb, frontendNames)
+	future, err := lbClient.CreateOrUpdate(context.Background(), tc.GetResourceGroup(), lbName, lb)
+	if err != nil {
+		return err
+	}
+	return future.WaitForCompletionRef(context.Background(), lbClient.Client)
+}
+
+// deleteSecurityRules deletes the rules from the security group in the cluster resource group.
+func deleteSecurityRules(tc *AzureTestClient, nsgName string, ruleNames []string) error {
+	Logf("Deleting the rules %v of security group %s", ruleNames, nsgName)
+	nsgClient := tc.CreateSecurityGroupsClient()
+	nsg, err := nsgClient.Get(context.Background(), tc.GetResourceGroup(), nsgName, "")
+	if err != nil {
+		return err
+	}
+	removeSecurityRules(&nsg, ruleNames)
+	future, err := nsgClient.CreateOrUpdate(context.Background(), tc.GetResourceGroup(), nsgName, nsg)
+	if err != nil {
+		return err
+	}
+	return future.WaitForCompletionRef(context.Background(), nsgClient.Client)
+}
@@ -0,0 +1,256 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	aznetwork "github.com/Azure/azure-sdk-for-go/services/network/mgmt/2021-08-01/network"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/stretchr/testify/assert"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+)
+
+const (
+	existingServiceUID = "11111111-1111-1111-1111-111111111111"
+	deletedServiceUID  = "22222222-2222-2222-2222-222222222222"
+	orphanedLBID       = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/kubernetes"
+	orphanedNSGID      = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/networkSecurityGroups/nsg"
+)
+
+type fakeOrphanedResourcesLister struct {
+	lbs          []aznetwork.LoadBalancer
+	nsgs         []aznetwork.SecurityGroup
+	pips         []aznetwork.PublicIPAddress
+	changedTimes map[string]time.Time
+}
+
+func (f *fakeOrphanedResourcesLister) GetResourceGroup() string {
+	return "rg"
+}
+
+func (f *fakeOrphanedResourcesLister) ListLoadBalancers(resourceGroupName string) ([]aznetwork.LoadBalancer, error) {
+	return f.lbs, nil
+}
+
+func (f *fakeOrphanedResourcesLister) GetClusterSecurityGroups() ([]aznetwork.SecurityGroup, error) {
+	return f.nsgs, nil
+}
+
+func (f *fakeOrphanedResourcesLister) ListPublicIPs(resourceGroupName string) ([]aznetwork.PublicIPAddress, error) {
+	return f.pips, nil
+}
+
+func (f *fakeOrphanedResourcesLister) ListResourceChangedTimes(resourceGroupName string) (map[string]time.Time, error) {
+	return f.changedTimes, nil
+}
+
+func serviceResourceName(uid, suffix string) string {
+	return "a" + strings.ReplaceAll(uid, "-", "") + suffix
+}
+
+func newOrphanedTestLoadBalancer() aznetwork.LoadBalancer {
+	existingFrontendID := orphanedLBID + "/frontendIPConfigurations/" + serviceResourceName(existingServiceUID, "")
+	deletedFrontendID := orphanedLBID + "/frontendIPConfigurations/" + serviceResourceName(deletedServiceUID, "")
+	deletedProbeID := orphanedLBID + "/probes/" + serviceResourceName(deletedServiceUID, "-TCP-80")
+	return aznetwork.LoadBalancer{
+		ID:   to.StringPtr(orphanedLBID),
+		Name: to.StringPtr("kubernetes"),
+		LoadBalancerPropertiesFormat: &aznetwork.LoadBalancerPropertiesFormat{
+			FrontendIPConfigurations: &[]aznetwork.FrontendIPConfiguration{
+				{Name: to.StringPtr("kubernetes"), ID: to.StringPtr(orphanedLBID + "/frontendIPConfigurations/kubernetes")},
+				{Name: to.StringPtr(serviceResourceName(existingServiceUID, "")), ID: to.StringPtr(existingFrontendID)},
+				{Name: to.StringPtr(serviceResourceName(deletedServiceUID, "")), ID: to.StringPtr(deletedFrontendID)},
+			},
+			LoadBalancingRules: &[]aznetwork.LoadBalancingRule{
+				{
+					Name: to.StringPtr(serviceResourceName(existingServiceUID, "-TCP-80")),
+					LoadBalancingRulePropertiesFormat: &aznetwork.LoadBalancingRulePropertiesFormat{
+						FrontendIPConfiguration: &aznetwork.SubResource{ID: to.StringPtr(existingFrontendID)},
+					},
+				},
+				{
+					Name: to.StringPtr(serviceResourceName(deletedServiceUID, "-TCP-80")),
+					LoadBalancingRulePropertiesFormat: &aznetwork.LoadBalancingRulePropertiesFormat{
+						FrontendIPConfiguration: &aznetwork.SubResource{ID: to.StringPtr(deletedFrontendID)},
+						Probe:                   &aznetwork.SubResource{ID: to.StringPtr(deletedProbeID)},
+					},
+				},
+			},
+			Probes: &[]aznetwork.Probe{
+				{Name: to.StringPtr(serviceResourceName(deletedServiceUID, "-TCP-80")), ID: to.StringPtr(deletedProbeID)},
+			},
+		},
+	}
+}
+
+func newOrphanedTestSecurityGroup() aznetwork.SecurityGroup {
+	return aznetwork.SecurityGroup{
+		ID:   to.StringPtr(orphanedNSGID),
+		Name: to.StringPtr("nsg"),
+		SecurityGroupPropertiesFormat: &aznetwork.SecurityGroupPropertiesFormat{
+			SecurityRules: &[]aznetwork.SecurityRule{
+				{Name: to.StringPtr("allow-ssh")},
+				{Name: to.StringPtr(serviceResourceName(existingServiceUID, "-TCP-80-Internet"))},
+				{Name: to.StringPtr(serviceResourceName(deletedServiceUID, "-TCP-80-Internet"))},
+			},
+		},
+	}
+}
+
+func newOrphanedTestPublicIP(name, cluster, service string) aznetwork.PublicIPAddress {
+	return aznetwork.PublicIPAddress{
+		ID:   to.StringPtr("/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/publicIPAddresses/" + name),
+		Name: to.StringPtr(name),
+		Tags: map[string]*string{
+			consts.ClusterNameKey: to.StringPtr(cluster),
+			consts.ServiceTagKey:  to.StringPtr(service),
+		},
+		PublicIPAddressPropertiesFormat: &aznetwork.PublicIPAddressPropertiesFormat{},
+	}
+}
+
+func TestFindOrphanedResources(t *testing.T) {
+	cs := fake.NewSimpleClientset(&v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "existing", UID: types.UID(existingServiceUID)},
+	})
+
+	inUsePIP := newOrphanedTestPublicIP("in-use", "kubernetes", "ns/deleted")
+	inUsePIP.IPConfiguration = &aznetwork.IPConfiguration{ID: to.StringPtr("/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/networkInterfaces/nic/ipConfigurations/ipconfig")}
+	frontendPIP := newOrphanedTestPublicIP("frontend", "kubernetes", "ns/deleted")
+	frontendPIP.IPConfiguration = &aznetwork.IPConfiguration{ID: to.StringPtr(orphanedLBID + "/frontendIPConfigurations/" + serviceResourceName(deletedServiceUID, ""))}
+	pips := []aznetwork.PublicIPAddress{
+		newOrphanedTestPublicIP("orphaned", "kubernetes", "ns/deleted"),
+		newOrphanedTestPublicIP("shared", "kubernetes", "ns/deleted,ns/existing"),
+		newOrphanedTestPublicIP("other-cluster", "other", "ns/deleted"),
+		newOrphanedTestPublicIP("untagged", "kubernetes", ""),
+		newOrphanedTestPublicIP("recent", "kubernetes", "ns/deleted"),
+		newOrphanedTestPublicIP("unknown-age", "kubernetes", "ns/deleted"),
+		inUsePIP,
+		frontendPIP,
+	}
+
+	old, recent := time.Now().Add(-time.Hour), time.Now()
+	changedTimes := map[string]time.Time{
+		strings.ToLower(orphanedLBID):  old,
+		strings.ToLower(orphanedNSGID): old,
+	}
+	for _, pip := range pips {
+		if to.String(pip.Name) == "unknown-age" {
+			continue
+		}
+		changedTimes[strings.ToLower(to.String(pip.ID))] = old
+		if to.String(pip.Name) == "recent" {
+			changedTimes[strings.ToLower(to.String(pip.ID))] = recent
+		}
+	}
+
+	t.Run("should find the resources of the deleted services", func(t *testing.T) {
+		lister := &fakeOrphanedResourcesLister{
+			lbs:          []aznetwork.LoadBalancer{newOrphanedTestLoadBalancer()},
+			nsgs:         []aznetwork.SecurityGroup{newOrphanedTestSecurityGroup()},
+			pips:         pips,
+			changedTimes: changedTimes,
+		}
+		orphaned, err := FindOrphanedResources(lister, cs, "kubernetes", 10*time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"orphaned", "frontend"}, orphaned.PublicIPs)
+		assert.Equal(t, map[string][]string{"kubernetes": {serviceResourceName(deletedServiceUID, "")}}, orphaned.LoadBalancerFrontends)
+		assert.Equal(t, map[string][]string{"nsg": {serviceResourceName(deletedServiceUID, "-TCP-80-Internet")}}, orphaned.SecurityRules)
+	})
+
+	t.Run("should skip the load balancers and security groups changed recently", func(t *testing.T) {
+		recentChangedTimes := make(map[string]time.Time)
+		for id, changedTime := range changedTimes {
+			recentChangedTimes[id] = changedTime
+		}
+		recentChangedTimes[strings.ToLower(orphanedLBID)] = recent
+		recentChangedTimes[strings.ToLower(orphanedNSGID)] = recent
+		lister := &fakeOrphanedResourcesLister{
+			lbs:          []aznetwork.LoadBalancer{newOrphanedTestLoadBalancer()},
+			nsgs:         []aznetwork.SecurityGroup{newOrphanedTestSecurityGroup()},
+			pips:         pips,
+			changedTimes: recentChangedTimes,
+		}
+		orphaned, err := FindOrphanedResources(lister, cs, "kubernetes", 10*time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"orphaned"}, orphaned.PublicIPs)
+		assert.Empty(t, orphaned.LoadBalancerFrontends)
+		assert.Empty(t, orphaned.SecurityRules)
+	})
+
+	t.Run("should find nothing if all the services exist", func(t *testing.T) {
+		lister := &fakeOrphanedResourcesLister{
+			lbs:          []aznetwork.LoadBalancer{newOrphanedTestLoadBalancer()},
+			nsgs:         []aznetwork.SecurityGroup{newOrphanedTestSecurityGroup()},
+			changedTimes: changedTimes,
+		}
+		cs := fake.NewSimpleClientset(
+			&v1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "existing", UID: types.UID(existingServiceUID)}},
+			&v1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "deleted", UID: types.UID(deletedServiceUID)}},
+		)
+		orphaned, err := FindOrphanedResources(lister, cs, "kubernetes", 10*time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, orphaned.IsEmpty())
+	})
+}
+
+func TestGetOrphanedLoadBalancerFrontendsShared(t *testing.T) {
+	lb := newOrphanedTestLoadBalancer()
+	// The frontend named after the deleted service is reused by the existing one.
+	(*lb.LoadBalancingRules)[0].FrontendIPConfiguration.ID = (*lb.FrontendIPConfigurations)[2].ID
+	(*lb.FrontendIPConfigurations)[1].Name = to.StringPtr("kubernetes-2")
+
+	serviceUIDs := sets.NewString(strings.ReplaceAll(existingServiceUID, "-", ""))
+	for _, frontend := range getOrphanedLoadBalancerFrontends(&lb, serviceUIDs) {
+		t.Errorf("unexpected orphaned frontend %s", to.String(frontend.Name))
+	}
+}
+
+func TestRemoveLoadBalancerFrontends(t *testing.T) {
+	lb := newOrphanedTestLoadBalancer()
+	removeLoadBalancerFrontends(&lb, []string{serviceResourceName(deletedServiceUID, "")})
+
+	frontendNames := make([]string, 0)
+	for _, frontend := range *lb.FrontendIPConfigurations {
+		frontendNames = append(frontendNames, to.String(frontend.Name))
+	}
+	assert.Equal(t, []string{"kubernetes", serviceResourceName(existingServiceUID, "")}, frontendNames)
+	assert.Len(t, *lb.LoadBalancingRules, 1)
+	assert.Equal(t, serviceResourceName(existingServiceUID, "-TCP-80"), to.String((*lb.LoadBalancingRules)[0].Name))
+	assert.Empty(t, *lb.Probes)
+}
+
+func TestRemoveSecurityRules(t *testing.T) {
+	nsg := newOrphanedTestSecurityGroup()
+	removeSecurityRules(&nsg, []string{strings.ToUpper(serviceResourceName(deletedServiceUID, "-TCP-80-Internet"))})
+
+	ruleNames := make([]string, 0)
+	for _, rule := range *nsg.SecurityRules {
+		ruleNames = append(ruleNames, to.String(rule.Name))
+	}
+	assert.Equal(t, []string{"allow-ssh", serviceResourceName(existingServiceUID, "-TCP-80-Internet")}, ruleNames)
+}
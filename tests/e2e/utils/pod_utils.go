@@ -76,7 +76,7 @@ func WaitPodsToBeReady(cs clientset.Interface, ns string) error {
 	return wait.PollImmediate(pollInterval, pollTimeout, func() (done bool, err error) {
 		pendingPodCount, err := CountPendingPods(cs, ns)
 		if err != nil {
-			Logf("unexpected error: %w", err)
+			Logf("unexpected error: %v", err)
 			return false, err
 		}
 
@@ -18,20 +18,31 @@ package utils
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	aznetwork "github.com/Azure/azure-sdk-for-go/services/network/mgmt/2021-08-01/network"
 
 	v1 "k8s.io/api/core/v1"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
 	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+	cloudprovider "k8s.io/cloud-provider"
 
 	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
 )
@@ -41,10 +52,87 @@ const (
 	serviceTimeoutBasicLB = 10 * time.Minute
 	pullInterval          = 20 * time.Second
 	pullTimeout           = 1 * time.Minute
+	// stableIngressIPChecks is the number of polls in a row in which an unexpected ingress IP
+	// is observed before it is considered stable.
+	stableIngressIPChecks = 3
 
 	ExecAgnhostPod = "exec-agnhost-pod"
+
+	// ServiceAnnotationLoadBalancerSku is the annotation used to specify the load balancer SKU of the service
+	ServiceAnnotationLoadBalancerSku = "service.beta.kubernetes.io/azure-load-balancer-sku"
+)
+
+// loadBalancerEventReasons are the reasons of the events recorded by the service controller when it
+// reconciles the load balancer of a service.
+var loadBalancerEventReasons = sets.NewString(
+	"EnsuringLoadBalancer",
+	"EnsuredLoadBalancer",
+	"SyncLoadBalancerFailed",
+	"UpdatedLoadBalancer",
+	"UpdateLoadBalancerFailed",
+	"DeletingLoadBalancer",
+	"DeletedLoadBalancer",
+	"DeleteLoadBalancerFailed",
 )
 
+// serviceExposurePollInterval is the interval of polling the service in WaitServiceExposureWithTimeout.
+var serviceExposurePollInterval = 10 * time.Second
+
+// PortSpec describes a port of a service built by MakeServicePorts
+type PortSpec struct {
+	// Name of the port, default is "<protocol>-<port>", e.g. "tcp-80"
+	Name string
+	// Port exposed by the service
+	Port int32
+	// TargetPort on the pods, default is Port
+	TargetPort int32
+	// Protocol of the port, default is TCP
+	Protocol v1.Protocol
+}
+
+// MakeServicePorts builds the ports of a service from the specs
+func MakeServicePorts(specs ...PortSpec) []v1.ServicePort {
+	ports := make([]v1.ServicePort, 0, len(specs))
+	for _, spec := range specs {
+		protocol := spec.Protocol
+		if protocol == "" {
+			protocol = v1.ProtocolTCP
+		}
+		targetPort := spec.TargetPort
+		if targetPort == 0 {
+			targetPort = spec.Port
+		}
+		name := spec.Name
+		if name == "" {
+			name = fmt.Sprintf("%s-%d", strings.ToLower(string(protocol)), spec.Port)
+		}
+		ports = append(ports, v1.ServicePort{
+			Name:       name,
+			Port:       spec.Port,
+			TargetPort: intstr.FromInt(int(targetPort)),
+			Protocol:   protocol,
+		})
+	}
+	return ports
+}
+
+// CreateLoadBalancerService creates a LoadBalancer service with the given load balancer SKU
+func CreateLoadBalancerService(cs clientset.Interface, ns, name, sku string, annotation map[string]string, labels map[string]string, ports []v1.ServicePort) (*v1.Service, error) {
+	if !strings.EqualFold(sku, consts.LoadBalancerSkuBasic) && !strings.EqualFold(sku, consts.LoadBalancerSkuStandard) {
+		return nil, fmt.Errorf("invalid load balancer sku %q, supported values are %q and %q", sku, consts.LoadBalancerSkuBasic, consts.LoadBalancerSkuStandard)
+	}
+
+	annotations := make(map[string]string, len(annotation)+1)
+	for k, v := range annotation {
+		annotations[k] = v
+	}
+	annotations[ServiceAnnotationLoadBalancerSku] = strings.ToLower(sku)
+
+	Logf("Creating %s load balancer service %s in namespace %s", sku, name, ns)
+	service := CreateLoadBalancerServiceManifest(name, annotations, labels, ns, ports)
+	return cs.CoreV1().Services(ns).Create(context.TODO(), service, metav1.CreateOptions{})
+}
+
 // DeleteService deletes a service
 func DeleteService(cs clientset.Interface, ns string, serviceName string) error {
 	Logf("Deleting service %s in namespace %s", serviceName, ns)
@@ -70,6 +158,68 @@ func DeleteServiceIfExists(cs clientset.Interface, ns string, serviceName string
 	return err
 }
 
+// DeleteServicesByLabel deletes all services matching the label selector in the namespace immediately
+// and waits for them to disappear. The errors of deleting the services are aggregated.
+func DeleteServicesByLabel(cs clientset.Interface, ns string, selector map[string]string) error {
+	listOptions := metav1.ListOptions{LabelSelector: labels.SelectorFromSet(selector).String()}
+	services, err := cs.CoreV1().Services(ns).List(context.TODO(), listOptions)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	zero := int64(0)
+	for _, service := range services.Items {
+		Logf("Deleting service %s in namespace %s", service.Name, ns)
+		err := cs.CoreV1().Services(ns).Delete(context.TODO(), service.Name, metav1.DeleteOptions{GracePeriodSeconds: &zero})
+		if err != nil && !apierrs.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("failed to delete service %s: %w", service.Name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return utilerrors.NewAggregate(errs)
+	}
+
+	return wait.PollImmediate(poll, deletionTimeout, func() (bool, error) {
+		services, err := cs.CoreV1().Services(ns).List(context.TODO(), listOptions)
+		if err != nil {
+			return false, err
+		}
+		return len(services.Items) == 0, nil
+	})
+}
+
+// WaitFunc waits for the service to be reconciled after it is updated, e.g. by polling the Azure resources.
+type WaitFunc func(service *v1.Service) error
+
+// PatchServiceAnnotationAndWait sets the annotation of the service by a strategic merge patch, so that the
+// other fields updated by the controllers concurrently are kept, and then runs the wait function if provided.
+func PatchServiceAnnotationAndWait(cs clientset.Interface, ns, name, key, value string, wait WaitFunc) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{key: value},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	Logf("Patching annotation %s=%s of service %s in namespace %s", key, value, name, ns)
+	var service *v1.Service
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		service, err = cs.CoreV1().Services(ns).Patch(context.TODO(), name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to patch annotation %s of service %s: %w", key, name, err)
+	}
+
+	if wait == nil {
+		return nil
+	}
+	return wait(service)
+}
+
 // GetServiceDomainName cat prefix and azure suffix
 func GetServiceDomainName(prefix string) (ret string) {
 	suffix := extractSuffix()
@@ -95,10 +245,13 @@ func WaitServiceExposureAndValidateConnectivity(cs clientset.Interface, namespac
 	ip = service.Status.LoadBalancer.Ingress[0].IP
 
 	if !isInternalService(service) {
-		Logf("checking the connectivity of the public IP %s", ip)
-		for _, port := range service.Spec.Ports {
-			if err := ValidateExternalServiceConnectivity(ip, int(port.Port)); err != nil {
-				return ip, err
+		// Check all the frontends of the dual-stack services.
+		for _, address := range GetServiceIngressAddresses(service) {
+			Logf("checking the connectivity of the public IP %s", address)
+			for _, port := range service.Spec.Ports {
+				if err := ValidateExternalServiceConnectivity(address, int(port.Port)); err != nil {
+					return ip, err
+				}
 			}
 		}
 	} else if CheckPodExist(cs, namespace, ExecAgnhostPod) {
@@ -115,12 +268,15 @@ func WaitServiceExposureAndValidateConnectivity(cs clientset.Interface, namespac
 	return ip, nil
 }
 
+// WaitServiceExposureReturningService waits for the exposure of the external IP of the service
+// and returns the fully-populated service, e.g. with the annotations set by the controller.
+func WaitServiceExposureReturningService(cs clientset.Interface, namespace string, name string) (*v1.Service, error) {
+	return WaitServiceExposure(cs, namespace, name, "")
+}
+
 // WaitServiceExposure waits for the exposure of the external IP of the service
+// with the default timeout of the load balancer sku under test
 func WaitServiceExposure(cs clientset.Interface, namespace string, name string, targetIP string) (*v1.Service, error) {
-	var service *v1.Service
-	var err error
-	var ip string
-
 	timeout := serviceTimeout
 	if skuEnv := os.Getenv(LoadBalancerSkuEnv); skuEnv != "" {
 		if strings.EqualFold(skuEnv, string(aznetwork.LoadBalancerSkuNameBasic)) {
@@ -128,30 +284,57 @@ func WaitServiceExposure(cs clientset.Interface, namespace string, name string,
 		}
 	}
 
-	if err := wait.PollImmediate(10*time.Second, timeout, func() (bool, error) {
+	return WaitServiceExposureWithTimeout(cs, namespace, name, targetIP, timeout)
+}
+
+// WaitServiceExposureWithTimeout waits for the exposure of the external IP of the service
+// and gives up after the given timeout
+func WaitServiceExposureWithTimeout(cs clientset.Interface, namespace string, name string, targetIP string, timeout time.Duration) (*v1.Service, error) {
+	return WaitServiceExposureWithCallback(cs, namespace, name, targetIP, timeout, nil)
+}
+
+// WaitServiceExposureWithCallback is WaitServiceExposureWithTimeout which calls onPoll on each poll with the elapsed
+// time and the service got, so that the callers could report the progress, e.g. the events of the service.
+// The service is nil if it fails to be got. onPoll is optional. A dual-stack service is exposed once it gets
+// an ingress of each IP family. The timeout error reports the last observed ingresses and conditions.
+func WaitServiceExposureWithCallback(cs clientset.Interface, namespace string, name string, targetIP string, timeout time.Duration, onPoll func(elapsed time.Duration, svc *v1.Service)) (*v1.Service, error) {
+	var service, observed *v1.Service
+	var err error
+	var ip string
+
+	start := time.Now()
+	if err := wait.PollImmediate(serviceExposurePollInterval, timeout, func() (bool, error) {
 		service, err = cs.CoreV1().Services(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if onPoll != nil {
+			onPoll(time.Since(start), service)
+		}
 		if err != nil {
 			if IsRetryableAPIError(err) {
 				return false, nil
 			}
 			return false, err
 		}
+		observed = service
 
-		IngressList := service.Status.LoadBalancer.Ingress
-		if len(IngressList) == 0 {
-			err = fmt.Errorf("Cannot find Ingress in limited time")
-			Logf("Fail to find ingress, retry in 10 seconds")
+		addresses := GetServiceIngressAddresses(service)
+		expected := expectedIngressCount(service)
+		if len(addresses) < expected {
+			Logf("Found %d of %d ingresses of service %s/%s, retry in %v", len(addresses), expected, namespace, name, serviceExposurePollInterval)
 			return false, nil
 		}
 
 		ip = service.Status.LoadBalancer.Ingress[0].IP
 		if targetIP != "" && !strings.EqualFold(ip, targetIP) {
-			Logf("expected IP is %s, current IP is %s, retry in 10 seconds", targetIP, ip)
+			Logf("expected IP is %s, current IP is %s, retry in %v", targetIP, ip, serviceExposurePollInterval)
 			return false, nil
 		}
 
 		return true, nil
 	}); err != nil {
+		if errors.Is(err, wait.ErrWaitTimeout) && observed != nil {
+			return nil, fmt.Errorf("timed out waiting for the exposure of service %s/%s, the last observed ingresses are %v and conditions are %v: %w",
+				namespace, name, observed.Status.LoadBalancer.Ingress, observed.Status.Conditions, err)
+		}
 		return nil, err
 	}
 
@@ -159,6 +342,137 @@ func WaitServiceExposure(cs clientset.Interface, namespace string, name string,
 	return service, nil
 }
 
+// WaitServiceExposureAndGetIPs waits for the exposure of the service and returns the IPs or hostnames of all
+// its ingresses, e.g. both the IPv4 and the IPv6 frontends of a dual-stack service.
+func WaitServiceExposureAndGetIPs(cs clientset.Interface, namespace string, name string, timeout time.Duration) ([]string, error) {
+	service, err := WaitServiceExposureWithTimeout(cs, namespace, name, "", timeout)
+	if err != nil {
+		return nil, err
+	}
+	return GetServiceIngressAddresses(service), nil
+}
+
+// GetServiceIngressAddresses returns the IPs of the ingresses of the service, or the hostnames of the
+// ingresses without IPs.
+func GetServiceIngressAddresses(service *v1.Service) []string {
+	addresses := make([]string, 0, len(service.Status.LoadBalancer.Ingress))
+	for _, ingress := range service.Status.LoadBalancer.Ingress {
+		if ingress.IP != "" {
+			addresses = append(addresses, ingress.IP)
+		} else if ingress.Hostname != "" {
+			addresses = append(addresses, ingress.Hostname)
+		}
+	}
+	return addresses
+}
+
+// expectedIngressCount returns the number of the ingresses expected by the IP families of the service.
+func expectedIngressCount(service *v1.Service) int {
+	if len(service.Spec.IPFamilies) > 0 {
+		return len(service.Spec.IPFamilies)
+	}
+	return 1
+}
+
+// WaitServiceExposureForIP waits until the expected IP shows up in the ingress list of the service,
+// which is useful when the IP is pre-assigned. It fails without waiting for the timeout if a different
+// IP is assigned and stays unchanged for stableIngressIPChecks polls in a row.
+func WaitServiceExposureForIP(cs clientset.Interface, namespace string, name string, expectedIP string, timeout time.Duration) error {
+	var observedIP string
+	var stableChecks int
+
+	err := wait.PollImmediate(poll, timeout, func() (bool, error) {
+		service, err := cs.CoreV1().Services(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			if IsRetryableAPIError(err) {
+				return false, nil
+			}
+			return false, err
+		}
+
+		ips := make([]string, 0)
+		for _, ingress := range service.Status.LoadBalancer.Ingress {
+			if strings.EqualFold(ingress.IP, expectedIP) {
+				return true, nil
+			}
+			if ingress.IP != "" {
+				ips = append(ips, ingress.IP)
+			}
+		}
+		if len(ips) == 0 {
+			Logf("Fail to find ingress of service %s/%s, retry in %v", namespace, name, poll)
+			observedIP, stableChecks = "", 0
+			return false, nil
+		}
+
+		ip := strings.Join(ips, ",")
+		if ip == observedIP {
+			stableChecks++
+		} else {
+			observedIP, stableChecks = ip, 1
+		}
+		if stableChecks >= stableIngressIPChecks {
+			return false, fmt.Errorf("service %s/%s is assigned IP %s instead of the expected IP %s", namespace, name, observedIP, expectedIP)
+		}
+		Logf("expected IP is %s, current IP is %s, retry in %v", expectedIP, ip, poll)
+		return false, nil
+	})
+	if err != nil {
+		if errors.Is(err, wait.ErrWaitTimeout) && observedIP != "" {
+			return fmt.Errorf("timed out waiting for IP %s of service %s/%s, the observed IP is %s: %w", expectedIP, namespace, name, observedIP, err)
+		}
+		return err
+	}
+
+	Logf("Exposure successfully, get expected ip: %s", expectedIP)
+	return nil
+}
+
+// AssertServiceNotReconciled asserts that the service with a foreign loadBalancerClass is left to the other
+// load balancer implementation: it gets no ingress IP and no load balancer events of the service controller
+// within the given duration.
+func AssertServiceNotReconciled(cs clientset.Interface, ns, name string, within time.Duration) error {
+	service, err := cs.CoreV1().Services(ns).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if service.Spec.LoadBalancerClass == nil {
+		return fmt.Errorf("service %s/%s has no loadBalancerClass and is expected to be reconciled", ns, name)
+	}
+
+	Logf("Asserting service %s/%s with loadBalancerClass %s is not reconciled within %v", ns, name, *service.Spec.LoadBalancerClass, within)
+	err = wait.PollImmediate(poll, within, func() (bool, error) {
+		service, err := cs.CoreV1().Services(ns).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			if IsRetryableAPIError(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		if len(service.Status.LoadBalancer.Ingress) > 0 {
+			return false, fmt.Errorf("service %s/%s with loadBalancerClass %s is assigned ingress %v", ns, name, *service.Spec.LoadBalancerClass, service.Status.LoadBalancer.Ingress)
+		}
+
+		events, err := cs.CoreV1().Events(ns).List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			if IsRetryableAPIError(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		for _, event := range events.Items {
+			if event.InvolvedObject.Kind == "Service" && event.InvolvedObject.Name == name && loadBalancerEventReasons.Has(event.Reason) {
+				return false, fmt.Errorf("service %s/%s with loadBalancerClass %s is reconciled: %s %s", ns, name, *service.Spec.LoadBalancerClass, event.Reason, event.Message)
+			}
+		}
+		return false, nil
+	})
+	if errors.Is(err, wait.ErrWaitTimeout) {
+		return nil
+	}
+	return err
+}
+
 func isInternalService(service *v1.Service) bool {
 	var (
 		val string
@@ -171,6 +485,59 @@ func isInternalService(service *v1.Service) bool {
 	return strings.EqualFold(val, "true")
 }
 
+// serviceInternalSubnet returns the subnet annotated on the internal service, or nil if the service
+// is external or no subnet is annotated.
+func serviceInternalSubnet(service *v1.Service) *string {
+	if !isInternalService(service) {
+		return nil
+	}
+	if subnet, found := service.Annotations[consts.ServiceAnnotationLoadBalancerInternalSubnet]; found && strings.TrimSpace(subnet) != "" {
+		return &subnet
+	}
+	return nil
+}
+
+// ComputeServiceFrontendName returns the expected name of the frontend IP configuration created for the service.
+// lbName is the base name of the frontend, the default load balancer name of the service is used if it is empty.
+// It mirrors the naming logic of the cloud provider.
+func ComputeServiceFrontendName(svc *v1.Service, lbName string) string {
+	if lbName == "" {
+		lbName = cloudprovider.DefaultLoadBalancerName(svc)
+	}
+	subnet := serviceInternalSubnet(svc)
+	if subnet == nil {
+		return lbName
+	}
+
+	name := fmt.Sprintf("%s-%s", lbName, *subnet)
+	if len(name) > consts.FrontendIPConfigNameMaxLength {
+		name = name[:consts.FrontendIPConfigNameMaxLength]
+		// The name must end with a letter or '_'.
+		if last := name[len(name)-1]; !unicode.IsLetter(rune(last)) && last != '_' {
+			name = name[:len(name)-1] + "_"
+		}
+	}
+	return name
+}
+
+// ComputeServiceRuleName returns the expected name of the load balancing rule created for the service port.
+// It mirrors the naming logic of the cloud provider.
+func ComputeServiceRuleName(svc *v1.Service, port v1.ServicePort) string {
+	prefix := cloudprovider.DefaultLoadBalancerName(svc)
+	ruleName := fmt.Sprintf("%s-%s-%d", prefix, port.Protocol, port.Port)
+	subnet := serviceInternalSubnet(svc)
+	if subnet == nil {
+		return ruleName
+	}
+
+	// The rule name must not exceed 80 characters, so the subnet segment is truncated if needed.
+	subnetSegment := *subnet
+	if len(ruleName)+len(subnetSegment)+1 > consts.LoadBalancerRuleNameMaxLength {
+		subnetSegment = subnetSegment[:consts.LoadBalancerRuleNameMaxLength-len(ruleName)-1]
+	}
+	return fmt.Sprintf("%s-%s-%s-%d", prefix, subnetSegment, port.Protocol, port.Port)
+}
+
 // ValidateExternalServiceConnectivity validates the connectivity of the public service IP
 func ValidateExternalServiceConnectivity(serviceIP string, port int) error {
 	// the default nginx port is 80, skip other ports
@@ -179,7 +546,7 @@ func ValidateExternalServiceConnectivity(serviceIP string, port int) error {
 	}
 
 	err := wait.PollImmediate(pullInterval, pullTimeout, func() (done bool, err error) {
-		resp, err := http.Get(fmt.Sprintf("http://%s:%d", serviceIP, port))
+		resp, err := http.Get(fmt.Sprintf("http://%s", net.JoinHostPort(serviceIP, strconv.Itoa(port))))
 		if err != nil {
 			Logf("got error %v, will retry", err)
 			return false, nil
@@ -0,0 +1,457 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+)
+
+func TestWaitServiceExposureReturningService(t *testing.T) {
+	cs := fake.NewSimpleClientset(&v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "svc",
+			Namespace: "ns",
+			Annotations: map[string]string{
+				consts.ServiceAnnotationLoadBalancerInternal: "true",
+			},
+		},
+		Status: v1.ServiceStatus{
+			LoadBalancer: v1.LoadBalancerStatus{
+				Ingress: []v1.LoadBalancerIngress{{IP: "10.0.0.1"}},
+			},
+		},
+	})
+
+	service, err := WaitServiceExposureReturningService(cs, "ns", "svc")
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", service.Status.LoadBalancer.Ingress[0].IP)
+	assert.Equal(t, "true", service.Annotations[consts.ServiceAnnotationLoadBalancerInternal])
+}
+
+func TestWaitServiceExposureWithTimeout(t *testing.T) {
+	cs := fake.NewSimpleClientset(&v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "svc",
+			Namespace: "ns",
+		},
+	})
+
+	start := time.Now()
+	service, err := WaitServiceExposureWithTimeout(cs, "ns", "svc", "", 100*time.Millisecond)
+	assert.Error(t, err)
+	assert.Nil(t, service)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestWaitServiceExposureWithCallback(t *testing.T) {
+	originalInterval := serviceExposurePollInterval
+	serviceExposurePollInterval = 10 * time.Millisecond
+	defer func() {
+		serviceExposurePollInterval = originalInterval
+	}()
+
+	cs := fake.NewSimpleClientset(&v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "svc",
+			Namespace: "ns",
+		},
+	})
+	// the ingress IP is assigned on the third poll
+	gets := 0
+	cs.PrependReactor("get", "services", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		gets++
+		service := &v1.Service{ObjectMeta: metav1.ObjectMeta{Name: "svc", Namespace: "ns"}}
+		if gets >= 3 {
+			service.Status.LoadBalancer.Ingress = []v1.LoadBalancerIngress{{IP: "10.0.0.1"}}
+		}
+		return true, service, nil
+	})
+
+	var polls int
+	var lastElapsed time.Duration
+	service, err := WaitServiceExposureWithCallback(cs, "ns", "svc", "", time.Minute, func(elapsed time.Duration, svc *v1.Service) {
+		polls++
+		assert.GreaterOrEqual(t, elapsed, lastElapsed)
+		lastElapsed = elapsed
+		assert.Equal(t, "svc", svc.Name)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", service.Status.LoadBalancer.Ingress[0].IP)
+	assert.Equal(t, 3, polls)
+}
+
+func TestWaitServiceExposureAndGetIPs(t *testing.T) {
+	originalInterval := serviceExposurePollInterval
+	serviceExposurePollInterval = 10 * time.Millisecond
+	defer func() {
+		serviceExposurePollInterval = originalInterval
+	}()
+
+	// stagedService returns the service whose status is updated on each get by the stages in order,
+	// and stays at the last stage.
+	stagedService := func(ipFamilies []v1.IPFamily, stages ...v1.ServiceStatus) *fake.Clientset {
+		cs := fake.NewSimpleClientset()
+		gets := 0
+		cs.PrependReactor("get", "services", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			stage := stages[len(stages)-1]
+			if gets < len(stages) {
+				stage = stages[gets]
+			}
+			gets++
+			return true, &v1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "svc", Namespace: "ns"},
+				Spec:       v1.ServiceSpec{IPFamilies: ipFamilies},
+				Status:     stage,
+			}, nil
+		})
+		return cs
+	}
+	ingress := func(ingresses ...v1.LoadBalancerIngress) v1.ServiceStatus {
+		return v1.ServiceStatus{LoadBalancer: v1.LoadBalancerStatus{Ingress: ingresses}}
+	}
+
+	t.Run("should wait for the ingresses of both IP families of a dual-stack service", func(t *testing.T) {
+		cs := stagedService([]v1.IPFamily{v1.IPv4Protocol, v1.IPv6Protocol},
+			ingress(),
+			ingress(v1.LoadBalancerIngress{IP: "20.0.0.1"}),
+			ingress(v1.LoadBalancerIngress{IP: "20.0.0.1"}, v1.LoadBalancerIngress{IP: "2001:db8::1"}),
+		)
+		ips, err := WaitServiceExposureAndGetIPs(cs, "ns", "svc", time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"20.0.0.1", "2001:db8::1"}, ips)
+	})
+
+	t.Run("should return the hostnames of the ingresses without IPs", func(t *testing.T) {
+		cs := stagedService(nil, ingress(), ingress(v1.LoadBalancerIngress{Hostname: "svc.example.com"}))
+		ips, err := WaitServiceExposureAndGetIPs(cs, "ns", "svc", time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"svc.example.com"}, ips)
+	})
+
+	t.Run("should report the last observed conditions on timeout", func(t *testing.T) {
+		status := ingress(v1.LoadBalancerIngress{IP: "20.0.0.1"})
+		status.Conditions = []metav1.Condition{{Type: "LoadBalancerReady", Status: metav1.ConditionFalse, Reason: "SyncLoadBalancerFailed"}}
+		cs := stagedService([]v1.IPFamily{v1.IPv4Protocol, v1.IPv6Protocol}, status)
+		_, err := WaitServiceExposureAndGetIPs(cs, "ns", "svc", 100*time.Millisecond)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "SyncLoadBalancerFailed")
+		assert.Contains(t, err.Error(), "20.0.0.1")
+	})
+}
+
+func TestWaitServiceExposureForIP(t *testing.T) {
+	newService := func(ips ...string) *v1.Service {
+		service := &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "svc",
+				Namespace: "ns",
+			},
+		}
+		for _, ip := range ips {
+			service.Status.LoadBalancer.Ingress = append(service.Status.LoadBalancer.Ingress, v1.LoadBalancerIngress{IP: ip})
+		}
+		return service
+	}
+
+	t.Run("should succeed if the expected IP is in the ingress list", func(t *testing.T) {
+		cs := fake.NewSimpleClientset(newService("10.0.0.1", "10.0.0.2"))
+		assert.NoError(t, WaitServiceExposureForIP(cs, "ns", "svc", "10.0.0.2", time.Minute))
+	})
+
+	t.Run("should fail with the observed IP if a different IP is assigned", func(t *testing.T) {
+		cs := fake.NewSimpleClientset(newService("10.0.0.3"))
+		start := time.Now()
+		err := WaitServiceExposureForIP(cs, "ns", "svc", "10.0.0.2", time.Minute)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "10.0.0.3")
+		assert.Less(t, time.Since(start), time.Minute)
+	})
+
+	t.Run("should time out if no IP is assigned", func(t *testing.T) {
+		cs := fake.NewSimpleClientset(newService())
+		err := WaitServiceExposureForIP(cs, "ns", "svc", "10.0.0.2", 100*time.Millisecond)
+		assert.Error(t, err)
+	})
+}
+
+func TestAssertServiceNotReconciled(t *testing.T) {
+	newService := func(class *string, ips ...string) *v1.Service {
+		service := &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "svc",
+				Namespace: "ns",
+			},
+			Spec: v1.ServiceSpec{
+				Type:              v1.ServiceTypeLoadBalancer,
+				LoadBalancerClass: class,
+			},
+		}
+		for _, ip := range ips {
+			service.Status.LoadBalancer.Ingress = append(service.Status.LoadBalancer.Ingress, v1.LoadBalancerIngress{IP: ip})
+		}
+		return service
+	}
+	foreignClass := "example.com/foreign-lb"
+
+	t.Run("should succeed if the service with a foreign class is not reconciled", func(t *testing.T) {
+		cs := fake.NewSimpleClientset(newService(&foreignClass), &v1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: "other-event", Namespace: "ns"},
+			InvolvedObject: v1.ObjectReference{Kind: "Service", Name: "other-svc"},
+			Reason:         "EnsuredLoadBalancer",
+		})
+		assert.NoError(t, AssertServiceNotReconciled(cs, "ns", "svc", 100*time.Millisecond))
+	})
+
+	t.Run("should fail if the service is assigned an ingress IP", func(t *testing.T) {
+		cs := fake.NewSimpleClientset(newService(&foreignClass, "1.2.3.4"))
+		err := AssertServiceNotReconciled(cs, "ns", "svc", 100*time.Millisecond)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "1.2.3.4")
+	})
+
+	t.Run("should fail if the service controller records events of the service", func(t *testing.T) {
+		cs := fake.NewSimpleClientset(newService(&foreignClass), &v1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: "svc-event", Namespace: "ns"},
+			InvolvedObject: v1.ObjectReference{Kind: "Service", Name: "svc"},
+			Reason:         "EnsuringLoadBalancer",
+		})
+		err := AssertServiceNotReconciled(cs, "ns", "svc", 100*time.Millisecond)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "EnsuringLoadBalancer")
+	})
+
+	t.Run("should fail if the service has no loadBalancerClass", func(t *testing.T) {
+		cs := fake.NewSimpleClientset(newService(nil))
+		assert.Error(t, AssertServiceNotReconciled(cs, "ns", "svc", 100*time.Millisecond))
+	})
+}
+
+func TestMakeServicePorts(t *testing.T) {
+	ports := MakeServicePorts(
+		PortSpec{Port: 80},
+		PortSpec{Name: "https", Port: 443, TargetPort: 8443},
+		PortSpec{Port: 53, Protocol: v1.ProtocolUDP},
+	)
+
+	expected := []v1.ServicePort{
+		{Name: "tcp-80", Port: 80, TargetPort: intstr.FromInt(80), Protocol: v1.ProtocolTCP},
+		{Name: "https", Port: 443, TargetPort: intstr.FromInt(8443), Protocol: v1.ProtocolTCP},
+		{Name: "udp-53", Port: 53, TargetPort: intstr.FromInt(53), Protocol: v1.ProtocolUDP},
+	}
+	assert.Equal(t, expected, ports)
+	assert.Empty(t, MakeServicePorts())
+}
+
+func TestCreateLoadBalancerService(t *testing.T) {
+	ports := []v1.ServicePort{{Port: 80}}
+
+	t.Run("should reject the invalid sku before creating the service", func(t *testing.T) {
+		cs := fake.NewSimpleClientset()
+		service, err := CreateLoadBalancerService(cs, "ns", "svc", "standrad", nil, nil, ports)
+		assert.Error(t, err)
+		assert.Nil(t, service)
+		assert.Empty(t, cs.Actions())
+	})
+
+	t.Run("should create the service with the sku annotation", func(t *testing.T) {
+		cs := fake.NewSimpleClientset()
+		annotation := map[string]string{consts.ServiceAnnotationLoadBalancerInternal: "true"}
+		service, err := CreateLoadBalancerService(cs, "ns", "svc", "Standard", annotation, nil, ports)
+		assert.NoError(t, err)
+		assert.Equal(t, v1.ServiceTypeLoadBalancer, service.Spec.Type)
+		assert.Equal(t, consts.LoadBalancerSkuStandard, service.Annotations[ServiceAnnotationLoadBalancerSku])
+		assert.Equal(t, "true", service.Annotations[consts.ServiceAnnotationLoadBalancerInternal])
+		assert.NotContains(t, annotation, ServiceAnnotationLoadBalancerSku)
+	})
+}
+
+func TestDeleteServicesByLabel(t *testing.T) {
+	selector := map[string]string{"app": "e2e"}
+	newService := func(name string, labels map[string]string) *v1.Service {
+		return &v1.Service{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns", Labels: labels}}
+	}
+
+	t.Run("should delete the labeled services only", func(t *testing.T) {
+		cs := fake.NewSimpleClientset(newService("svc1", selector), newService("svc2", selector), newService("svc3", nil))
+		assert.NoError(t, DeleteServicesByLabel(cs, "ns", selector))
+
+		services, err := cs.CoreV1().Services("ns").List(context.TODO(), metav1.ListOptions{})
+		assert.NoError(t, err)
+		assert.Len(t, services.Items, 1)
+		assert.Equal(t, "svc3", services.Items[0].Name)
+	})
+
+	t.Run("should return the aggregated error if any deletion fails", func(t *testing.T) {
+		cs := fake.NewSimpleClientset(newService("svc1", selector), newService("svc2", selector))
+		cs.PrependReactor("delete", "services", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			if action.(k8stesting.DeleteAction).GetName() == "svc1" {
+				return true, nil, fmt.Errorf("delete failed")
+			}
+			return false, nil, nil
+		})
+		err := DeleteServicesByLabel(cs, "ns", selector)
+		assert.EqualError(t, err, "failed to delete service svc1: delete failed")
+
+		services, err := cs.CoreV1().Services("ns").List(context.TODO(), metav1.ListOptions{})
+		assert.NoError(t, err)
+		assert.Len(t, services.Items, 1)
+	})
+}
+
+func TestPatchServiceAnnotationAndWait(t *testing.T) {
+	newService := func() *v1.Service {
+		return &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "svc",
+				Namespace:   "ns",
+				Labels:      map[string]string{"app": "e2e"},
+				Annotations: map[string]string{consts.ServiceAnnotationLoadBalancerInternal: "true"},
+			},
+			Spec: v1.ServiceSpec{
+				Type:  v1.ServiceTypeLoadBalancer,
+				Ports: []v1.ServicePort{{Port: 80}},
+			},
+		}
+	}
+
+	t.Run("should set the annotation and keep the other fields", func(t *testing.T) {
+		cs := fake.NewSimpleClientset(newService())
+		conflicts := 0
+		cs.PrependReactor("patch", "services", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			patch := action.(k8stesting.PatchAction)
+			assert.Equal(t, types.StrategicMergePatchType, patch.GetPatchType())
+			assert.JSONEq(t, `{"metadata":{"annotations":{"key":"value"}}}`, string(patch.GetPatch()))
+			if conflicts == 0 {
+				conflicts++
+				return true, nil, apierrs.NewConflict(v1.Resource("services"), "svc", fmt.Errorf("updated by the controller"))
+			}
+			return false, nil, nil
+		})
+
+		var waited *v1.Service
+		err := PatchServiceAnnotationAndWait(cs, "ns", "svc", "key", "value", func(service *v1.Service) error {
+			waited = service
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, conflicts)
+		assert.Equal(t, "value", waited.Annotations["key"])
+
+		service, err := cs.CoreV1().Services("ns").Get(context.TODO(), "svc", metav1.GetOptions{})
+		assert.NoError(t, err)
+		expected := newService()
+		expected.Annotations["key"] = "value"
+		assert.Equal(t, expected.Annotations, service.Annotations)
+		assert.Equal(t, expected.Labels, service.Labels)
+		assert.Equal(t, expected.Spec, service.Spec)
+	})
+
+	t.Run("should return the error of the wait function", func(t *testing.T) {
+		cs := fake.NewSimpleClientset(newService())
+		err := PatchServiceAnnotationAndWait(cs, "ns", "svc", "key", "value", func(service *v1.Service) error {
+			return fmt.Errorf("not reconciled")
+		})
+		assert.EqualError(t, err, "not reconciled")
+	})
+
+	t.Run("should return the error if the service does not exist", func(t *testing.T) {
+		cs := fake.NewSimpleClientset()
+		err := PatchServiceAnnotationAndWait(cs, "ns", "svc", "key", "value", nil)
+		assert.Error(t, err)
+		assert.True(t, apierrs.IsNotFound(err))
+	})
+}
+
+func TestComputeServiceNames(t *testing.T) {
+	port := v1.ServicePort{Protocol: v1.ProtocolTCP, Port: 80}
+	longSubnet := "subnet-" + strings.Repeat("x", 80)
+	for _, tc := range []struct {
+		desc             string
+		annotations      map[string]string
+		lbName           string
+		expectedFrontend string
+		expectedRule     string
+	}{
+		{
+			desc:             "external service",
+			expectedFrontend: "a1234567890abcdef1234567890abcde",
+			expectedRule:     "a1234567890abcdef1234567890abcde-TCP-80",
+		},
+		{
+			desc:             "external service with base name",
+			lbName:           "frontend",
+			expectedFrontend: "frontend",
+			expectedRule:     "a1234567890abcdef1234567890abcde-TCP-80",
+		},
+		{
+			desc: "subnet should be ignored for external service",
+			annotations: map[string]string{
+				consts.ServiceAnnotationLoadBalancerInternalSubnet: "subnet",
+			},
+			expectedFrontend: "a1234567890abcdef1234567890abcde",
+			expectedRule:     "a1234567890abcdef1234567890abcde-TCP-80",
+		},
+		{
+			desc: "internal service with subnet",
+			annotations: map[string]string{
+				consts.ServiceAnnotationLoadBalancerInternal:       "true",
+				consts.ServiceAnnotationLoadBalancerInternalSubnet: "subnet",
+			},
+			expectedFrontend: "a1234567890abcdef1234567890abcde-subnet",
+			expectedRule:     "a1234567890abcdef1234567890abcde-subnet-TCP-80",
+		},
+		{
+			desc: "long subnet should be truncated",
+			annotations: map[string]string{
+				consts.ServiceAnnotationLoadBalancerInternal:       "true",
+				consts.ServiceAnnotationLoadBalancerInternalSubnet: longSubnet,
+			},
+			expectedFrontend: "a1234567890abcdef1234567890abcde-" + longSubnet[:47],
+			expectedRule:     "a1234567890abcdef1234567890abcde-" + longSubnet[:40] + "-TCP-80",
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			svc := &v1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "svc",
+					Namespace:   "ns",
+					UID:         "12345678-90ab-cdef-1234-567890abcdef",
+					Annotations: tc.annotations,
+				},
+			}
+			assert.Equal(t, tc.expectedFrontend, ComputeServiceFrontendName(svc, tc.lbName))
+			assert.Equal(t, tc.expectedRule, ComputeServiceRuleName(svc, port))
+		})
+	}
+}
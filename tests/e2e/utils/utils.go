@@ -142,7 +142,7 @@ func DeleteNamespace(cs clientset.Interface, namespace string) error {
 			if apierrs.IsNotFound(err) {
 				return true, nil
 			}
-			Logf("Error while waiting for namespace to be terminated: %w", err)
+			Logf("Error while waiting for namespace to be terminated: %v", err)
 			if !IsRetryableAPIError(err) {
 				return false, err
 			}
@@ -24,14 +24,16 @@ import (
 
 	azcompute "github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2021-07-01/compute"
 	"github.com/Azure/go-autorest/autorest/to"
+	. "github.com/onsi/ginkgo"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
 )
 
 var (
-	vmssVMRE        = regexp.MustCompile(`/subscriptions/(?:.*)/resourceGroups/(?:.+)/providers/Microsoft.Compute/virtualMachineScaleSets/(.+)/virtualMachines/(?:\d+)`)
-	errVMSSNotFound = fmt.Errorf("cannot find any VMSS")
+	vmssVMRE         = regexp.MustCompile(`/subscriptions/(?:.*)/resourceGroups/(?:.+)/providers/Microsoft.Compute/virtualMachineScaleSets/(.+)/virtualMachines/(?:\d+)`)
+	vmssProviderIDRE = regexp.MustCompile(`(?i)^azure:///subscriptions/[^/]+/resourceGroups/([^/]+)/providers/Microsoft.Compute/virtualMachineScaleSets/([^/]+)/virtualMachines/[^/]+$`)
+	errVMSSNotFound  = fmt.Errorf("cannot find any VMSS")
 )
 
 // FindTestVMSS returns the first VMSS in the resource group,
@@ -54,33 +56,78 @@ func FindTestVMSS(tc *AzureTestClient, rgName string) (*azcompute.VirtualMachine
 	return &vmssList[0], nil
 }
 
-// ScaleVMSS scales the given VMSS
-func ScaleVMSS(tc *AzureTestClient, vmssName, rgName string, instanceCount int64) (err error) {
+// ScaleVMSS scales the VMSS in the cluster resource group to newCapacity instances, and waits until the
+// number of the nodes of the VMSS equals newCapacity.
+func ScaleVMSS(tc *AzureTestClient, vmssName string, newCapacity int64) error {
 	Logf("ScaleVMSS: start")
 
 	vmssClient := tc.createVMSSClient()
 
-	vmss, err := vmssClient.Get(context.Background(), rgName, vmssName, azcompute.ExpandTypesForGetVMScaleSetsUserData)
+	vmss, err := vmssClient.Get(context.Background(), tc.GetResourceGroup(), vmssName, "")
 	if err != nil {
 		return err
 	}
-	parameters := azcompute.VirtualMachineScaleSet{
-		Location: to.StringPtr(tc.GetLocation()),
+	if vmss.Sku == nil {
+		return fmt.Errorf("VMSS %s has no sku", vmssName)
+	}
+	parameters := azcompute.VirtualMachineScaleSetUpdate{
 		Sku: &azcompute.Sku{
 			Name:     vmss.Sku.Name,
-			Capacity: to.Int64Ptr(instanceCount),
+			Tier:     vmss.Sku.Tier,
+			Capacity: to.Int64Ptr(newCapacity),
 		},
 	}
 
-	Logf("ScaleVMSS: scaling VMSS %s", vmssName)
-	_, err = vmssClient.CreateOrUpdate(context.Background(), rgName, vmssName, parameters)
+	Logf("ScaleVMSS: scaling VMSS %s from %d to %d instances", vmssName, to.Int64(vmss.Sku.Capacity), newCapacity)
+	future, err := vmssClient.Update(context.Background(), tc.GetResourceGroup(), vmssName, parameters)
 	if err != nil {
 		return err
 	}
+	if err := future.WaitForCompletionRef(context.Background(), vmssClient.Client); err != nil {
+		return err
+	}
 
 	Logf("ScaleVMSS: wait the scaling process to be over")
-	err = waitVMSSVMCountToEqual(tc, int(instanceCount), vmssName)
-	return err
+	return waitVMSSVMCountToEqual(tc, int(newCapacity), vmssName)
+}
+
+// ParseVMSSProviderID returns the resource group and the name of the VMSS of the provider ID of a VMSS
+// instance, e.g. "azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/0".
+// An error is returned if the provider ID is not of a VMSS instance, e.g. of an availability set VM.
+func ParseVMSSProviderID(providerID string) (resourceGroup, vmssName string, err error) {
+	matches := vmssProviderIDRE.FindStringSubmatch(providerID)
+	if len(matches) != 3 {
+		return "", "", fmt.Errorf("%q is not the provider ID of a VMSS instance", providerID)
+	}
+	return matches[1], matches[2], nil
+}
+
+// GetClusterVMSSNames returns the sorted names of the VMSSes of the agent nodes, one for each VMSS agent pool.
+// An error is returned if any agent node is not a VMSS instance, e.g. if the cluster mixes VMSS and availability
+// set agent pools.
+func GetClusterVMSSNames(nodes []v1.Node) ([]string, error) {
+	vmssNames := sets.NewString()
+	for i, node := range nodes {
+		if IsControlPlaneNode(&nodes[i]) || isVirtualKubeletNode(&nodes[i]) {
+			continue
+		}
+		_, vmssName, err := ParseVMSSProviderID(node.Spec.ProviderID)
+		if err != nil {
+			return nil, fmt.Errorf("node %s is not a VMSS instance: %w", node.Name, err)
+		}
+		vmssNames.Insert(vmssName)
+	}
+	return vmssNames.List(), nil
+}
+
+// SkipIfNotVMSSCluster skips the test if any agent node is not a VMSS instance, and returns the names of the
+// VMSSes of the agent nodes otherwise.
+func SkipIfNotVMSSCluster(nodes []v1.Node) []string {
+	vmssNames, err := GetClusterVMSSNames(nodes)
+	if err != nil {
+		Skip(fmt.Sprintf("the test only works for the clusters whose agent pools are all VMSSes: %v", err))
+	}
+	return vmssNames
 }
 
 // IsNodeInVMSS defines whether the node is the instance of the VMSS
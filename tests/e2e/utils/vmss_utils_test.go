@@ -0,0 +1,93 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseVMSSProviderID(t *testing.T) {
+	for _, tc := range []struct {
+		description           string
+		providerID            string
+		expectedResourceGroup string
+		expectedVMSSName      string
+		expectedErr           bool
+	}{
+		{
+			description:           "should parse the provider ID of a VMSS instance",
+			providerID:            "azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/aks-nodepool1-vmss/virtualMachines/0",
+			expectedResourceGroup: "rg",
+			expectedVMSSName:      "aks-nodepool1-vmss",
+		},
+		{
+			description:           "should parse the provider ID case-insensitively",
+			providerID:            "azure:///subscriptions/sub/resourcegroups/RG/providers/microsoft.compute/virtualmachinescalesets/vmss/virtualmachines/12",
+			expectedResourceGroup: "RG",
+			expectedVMSSName:      "vmss",
+		},
+		{
+			description: "should return an error for the availability set VM",
+			providerID:  "azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/vm-0",
+			expectedErr: true,
+		},
+		{
+			description: "should return an error for the empty provider ID",
+			expectedErr: true,
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			resourceGroup, vmssName, err := ParseVMSSProviderID(tc.providerID)
+			assert.Equal(t, tc.expectedErr, err != nil)
+			assert.Equal(t, tc.expectedResourceGroup, resourceGroup)
+			assert.Equal(t, tc.expectedVMSSName, vmssName)
+		})
+	}
+}
+
+func TestGetClusterVMSSNames(t *testing.T) {
+	newNode := func(name, providerID string, labels map[string]string) v1.Node {
+		return v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+			Spec:       v1.NodeSpec{ProviderID: providerID},
+		}
+	}
+	vmssProviderID := func(vmss, instance string) string {
+		return "azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/" + vmss + "/virtualMachines/" + instance
+	}
+
+	names, err := GetClusterVMSSNames([]v1.Node{
+		newNode("master", "azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/master-0", map[string]string{controlPlaneNodeRoleLabel: ""}),
+		newNode("pool2-0", vmssProviderID("pool2", "0"), nil),
+		newNode("pool1-0", vmssProviderID("pool1", "0"), nil),
+		newNode("pool1-1", vmssProviderID("pool1", "1"), nil),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"pool1", "pool2"}, names)
+
+	_, err = GetClusterVMSSNames([]v1.Node{
+		newNode("pool1-0", vmssProviderID("pool1", "0"), nil),
+		newNode("vm-0", "azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/vm-0", nil),
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "node vm-0 is not a VMSS instance")
+}